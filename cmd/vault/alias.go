@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/choplin/vault.md/internal/config"
+)
+
+// expandAliases applies the aliases from the config file to the command
+// line before cobra sees it. A config that cannot be loaded leaves args
+// unchanged, so that commands such as doctor can still report the problem.
+func expandAliases(args []string) ([]string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return args, nil
+	}
+	settings, err := config.Load()
+	if err != nil {
+		//nolint:nilerr // Commands report config errors themselves when they load it
+		return args, nil
+	}
+	return settings.ExpandAlias(args, isBuiltinCommand)
+}
+
+// isBuiltinCommand reports whether name selects one of vault's own commands,
+// including cobra's help, completion, and hidden __complete commands.
+func isBuiltinCommand(name string) bool {
+	if strings.HasPrefix(name, "__") {
+		return true
+	}
+	rootCmd.InitDefaultHelpCmd()
+	rootCmd.InitDefaultCompletionCmd()
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
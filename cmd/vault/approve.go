@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newApproveCmd() *cobra.Command {
+	var (
+		versionFlag int
+		scopeType   string
+		repoPath    string
+		branchName  string
+		worktreeID  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "approve <key>",
+		Short: "Mark a version of an entry as approved",
+		Long: "Mark a version of an entry (the latest unless --version is given) as approved. New versions " +
+			"start out as drafts; get --approved reads the newest approved version, so a reviewed document " +
+			"stays readable while edits to it are pending. The approver is recorded as the actor " +
+			"(VAULT_ACTOR or the OS user) and shown by history.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key := args[0]
+
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			var version *int
+			if cmd.Flags().Changed("version") {
+				version = &versionFlag
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			result, err := usecase.NewEntry(dbCtx).Approve(context.Background(), sc, key, version, "")
+			if err != nil {
+				return err
+			}
+			if result.AlreadyApproved {
+				_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s version %d was already approved\n", key, result.Version)
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Approved %s version %d\n", key, result.Version)
+			return err
+		},
+	}
+
+	cmd.Flags().IntVarP(&versionFlag, "version", "v", 0, "Version to approve (default: latest)")
+	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
+	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
+	cmd.Flags().StringVar(&worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+
+	return cmd
+}
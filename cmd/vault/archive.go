@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+// archiveColumns are the table columns of vault archive.
+const archiveColumns = "key,version,updated,size,description"
+
+func newArchiveCmd() *cobra.Command {
+	var (
+		prefix     string
+		olderThan  string
+		dryRun     bool
+		format     string
+		absolute   bool
+		scopeType  string
+		repoPath   string
+		branchName string
+		worktreeID string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "archive [key...]",
+		Short: "Archive the entries of a scope that match keys, a prefix, or an age",
+		Long: "Archive many entries in one transaction: those matching the keys or globs given as arguments " +
+			"('tmp/*'), --prefix, and --older-than (last written before the age or time). Every filter given " +
+			"must match, and at least one is required. Archived entries drop out of list and stale but keep " +
+			"their history; the summary ends with the vault restore command that brings them back. --dry-run " +
+			"lists what would be archived.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: table, json)", format)
+			}
+			now := time.Now()
+			before, err := parseTimeFlag(olderThan, now)
+			if err != nil {
+				return fmt.Errorf("--older-than: %w", err)
+			}
+			if len(args) == 0 && prefix == "" && before.IsZero() {
+				return fmt.Errorf("specify keys, --prefix, or --older-than")
+			}
+
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			entries, err := usecase.NewEntry(dbCtx).ArchiveMatching(cmd.Context(), sc, usecase.ArchiveFilter{
+				Patterns: args,
+				Prefix:   prefix,
+				Before:   before,
+				DryRun:   dryRun,
+			})
+			if err != nil {
+				return err
+			}
+			result := &usecase.ListResult{Entries: entries}
+
+			if format == "json" {
+				if err := outputJSON(cmd, result); err != nil {
+					return err
+				}
+			} else if len(entries) > 0 {
+				columns, err := parseListColumns(archiveColumns)
+				if err != nil {
+					return err
+				}
+				outputColumnsTable(cmd, result, columns, listTimeFormat{absolute: absolute || ciMode, now: now})
+			}
+			return outputArchiveSummary(cmd, sc, entries, dryRun)
+		},
+	}
+
+	cmd.Flags().StringVar(&prefix, "prefix", "", "Archive keys starting with this prefix, such as tmp/")
+	cmd.Flags().StringVar(&olderThan, "older-than", "", "Archive entries last written before this age or time (RFC3339, YYYY-MM-DD, or age like 30d)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the matching entries without archiving them")
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+	cmd.Flags().BoolVar(&absolute, "absolute", false, "Show dates and times instead of relative ages (\"2h ago\") in the table")
+	addScopeFlags(cmd, &scopeType, &repoPath, &branchName, &worktreeID)
+
+	return cmd
+}
+
+func newRestoreCmd() *cobra.Command {
+	var (
+		scopeType  string
+		repoPath   string
+		branchName string
+		worktreeID string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "restore <key...>",
+		Short: "Bring back archived entries",
+		Long:  "Unarchive keys so list and stale show them again. Writing an archived key also restores it.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			uc := usecase.NewEntry(dbCtx)
+			restored := 0
+			var skipped []string
+			err = uc.WithTransaction(cmd.Context(), func(tx *usecase.Entry) error {
+				for _, key := range args {
+					ok, err := tx.Restore(cmd.Context(), sc, key)
+					if err != nil {
+						return fmt.Errorf("restore %s: %w", key, err)
+					}
+					if ok {
+						restored++
+					} else {
+						skipped = append(skipped, key)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			if len(skipped) > 0 {
+				if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "note: not archived or not found: %s\n", strings.Join(skipped, ", ")); err != nil {
+					return err
+				}
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Restored %d entries in %s\n", restored, scope.FormatScopeShort(sc))
+			return err
+		},
+	}
+
+	addScopeFlags(cmd, &scopeType, &repoPath, &branchName, &worktreeID)
+
+	return cmd
+}
+
+// addScopeFlags adds the --scope, --repo, --branch, and --worktree flags.
+func addScopeFlags(cmd *cobra.Command, scopeType, repoPath, branchName, worktreeID *string) {
+	cmd.Flags().StringVar(scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
+	cmd.Flags().StringVar(repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	cmd.Flags().StringVar(branchName, "branch", "", "Branch name (requires --scope branch)")
+	cmd.Flags().StringVar(worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+}
+
+// outputArchiveSummary reports the archived count and how to undo it on
+// stderr, keeping stdout to the listing.
+func outputArchiveSummary(cmd *cobra.Command, sc scope.Scope, entries []usecase.ListEntry, dryRun bool) error {
+	out := cmd.ErrOrStderr()
+	if len(entries) == 0 {
+		_, err := fmt.Fprintf(out, "No matching entries in %s\n", scope.FormatScopeShort(sc))
+		return err
+	}
+	if dryRun {
+		_, err := fmt.Fprintf(out, "Would archive %d entries in %s\n", len(entries), scope.FormatScopeShort(sc))
+		return err
+	}
+
+	keys := make([]string, 0, len(entries))
+	args := append([]string{"vault", "restore"}, scopeFlagArgs(sc)...)
+	for _, e := range entries {
+		keys = append(keys, e.Record.Key)
+		args = append(args, shellQuote(e.Record.Key))
+	}
+	if _, err := fmt.Fprintf(out, "Archived %d entries in %s\nnote: undo with vault undo or: %s\n", len(entries), scope.FormatScopeShort(sc), strings.Join(args, " ")); err != nil {
+		return err
+	}
+	return recordUndo(cmd, usecase.NewArchiveUndo(sc, keys))
+}
+
+// scopeFlagArgs returns the command-line flags that select sc.
+func scopeFlagArgs(sc scope.Scope) []string {
+	args := []string{"--scope", string(sc.Type)}
+	if sc.Type != scope.ScopeGlobal {
+		args = append(args, "--repo", shellQuote(sc.PrimaryPath))
+	}
+	switch sc.Type {
+	case scope.ScopeBranch:
+		args = append(args, "--branch", shellQuote(sc.BranchName))
+	case scope.ScopeWorktree:
+		args = append(args, "--worktree", shellQuote(sc.WorktreeID))
+	}
+	return args
+}
+
+// shellQuote single-quotes s for a POSIX shell unless it is made of
+// characters that need no quoting.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:@=+,") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
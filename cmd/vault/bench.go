@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newBenchCmd() *cobra.Command {
+	var (
+		entries        int
+		versions       int
+		contentSize    int
+		listIterations int
+		format         string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Benchmark storage operations against a throwaway vault",
+		Long: "Populate a temporary vault with synthetic entries and report set/get/list latencies " +
+			"and on-disk sizes. The user's vault is never touched.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if entries <= 0 || versions <= 0 {
+				return fmt.Errorf("--entries and --versions must be positive")
+			}
+			if format != "table" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: table, json)", format)
+			}
+
+			restore, err := useTempVault("vault-bench-")
+			if err != nil {
+				return err
+			}
+			defer restore()
+
+			report, err := runBench(cmd, entries, versions, contentSize, listIterations)
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(report)
+			}
+			return outputBenchTable(cmd, report)
+		},
+	}
+
+	cmd.Flags().IntVar(&entries, "entries", 1000, "Number of distinct keys to create")
+	cmd.Flags().IntVar(&versions, "versions", 5, "Number of versions to write per key")
+	cmd.Flags().IntVar(&contentSize, "size", 1024, "Content size in bytes for each version")
+	cmd.Flags().IntVar(&listIterations, "list-iterations", 10, "Number of list calls to time")
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+
+	return cmd
+}
+
+// useTempVault points all storage helpers at a new temporary directory via
+// VAULT_DIR, and keeps its synthetic changes from reaching the configured
+// notifiers. The returned function restores the environment and removes the
+// directory.
+func useTempVault(prefix string) (func(), error) {
+	tempDir, err := os.MkdirTemp("", prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	env := map[string]string{"VAULT_DIR": tempDir, config.NoNotifyEnv: "1"}
+	previous := map[string]*string{}
+	restore := func() {
+		for name, value := range previous {
+			if value != nil {
+				_ = os.Setenv(name, *value)
+			} else {
+				_ = os.Unsetenv(name)
+			}
+		}
+		_ = os.RemoveAll(tempDir)
+	}
+	for name, value := range env {
+		if old, ok := os.LookupEnv(name); ok {
+			previous[name] = &old
+		} else {
+			previous[name] = nil
+		}
+		if err := os.Setenv(name, value); err != nil {
+			restore()
+			return nil, err
+		}
+	}
+	return restore, nil
+}
+
+type benchStats struct {
+	Operation string  `json:"operation"`
+	Count     int     `json:"count"`
+	TotalMs   float64 `json:"totalMs"`
+	AvgMs     float64 `json:"avgMs"`
+	P50Ms     float64 `json:"p50Ms"`
+	P95Ms     float64 `json:"p95Ms"`
+	MaxMs     float64 `json:"maxMs"`
+}
+
+type benchReport struct {
+	Entries      int          `json:"entries"`
+	Versions     int          `json:"versions"`
+	ContentSize  int          `json:"contentSize"`
+	Operations   []benchStats `json:"operations"`
+	DBBytes      int64        `json:"dbBytes"`
+	ObjectsBytes int64        `json:"objectsBytes"`
+}
+
+func runBench(cmd *cobra.Command, entries, versions, contentSize, listIterations int) (*benchReport, error) {
+	// Open the temporary vault's own file, so a configured databaseUrl
+	// never receives the synthetic entries.
+	dbCtx, err := database.CreateDatabase(config.GetDBPath())
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = database.CloseDatabase(dbCtx)
+	}()
+
+	ctx := cmd.Context()
+	uc := usecase.NewEntry(dbCtx)
+	sc := scope.NewGlobal()
+	content := strings.Repeat("x", contentSize)
+
+	keys := make([]string, entries)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("bench/key-%06d", i)
+	}
+
+	setDurations := make([]time.Duration, 0, entries*versions)
+	for v := 0; v < versions; v++ {
+		for _, key := range keys {
+			start := time.Now()
+			if _, err := uc.Set(ctx, sc, key, content, nil); err != nil {
+				return nil, fmt.Errorf("set %s failed: %w", key, err)
+			}
+			setDurations = append(setDurations, time.Since(start))
+		}
+		if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "Wrote version %d/%d\n", v+1, versions); err != nil {
+			return nil, err
+		}
+	}
+
+	getDurations := make([]time.Duration, 0, entries)
+	for _, key := range keys {
+		start := time.Now()
+		if _, err := uc.Get(ctx, sc, key, nil); err != nil {
+			return nil, fmt.Errorf("get %s failed: %w", key, err)
+		}
+		getDurations = append(getDurations, time.Since(start))
+	}
+
+	listDurations := make([]time.Duration, 0, listIterations)
+	for i := 0; i < listIterations; i++ {
+		start := time.Now()
+		if _, err := uc.List(ctx, sc, nil); err != nil {
+			return nil, fmt.Errorf("list failed: %w", err)
+		}
+		listDurations = append(listDurations, time.Since(start))
+	}
+
+	dbBytes, err := pathSize(config.GetDBPath())
+	if err != nil {
+		return nil, err
+	}
+	walBytes, err := pathSize(config.GetDBPath() + "-wal")
+	if err != nil {
+		return nil, err
+	}
+	objectsBytes, err := pathSize(config.GetObjectsDir())
+	if err != nil {
+		return nil, err
+	}
+
+	return &benchReport{
+		Entries:     entries,
+		Versions:    versions,
+		ContentSize: contentSize,
+		Operations: []benchStats{
+			summarizeDurations("set", setDurations),
+			summarizeDurations("get", getDurations),
+			summarizeDurations("list", listDurations),
+		},
+		DBBytes:      dbBytes + walBytes,
+		ObjectsBytes: objectsBytes,
+	}, nil
+}
+
+func summarizeDurations(operation string, durations []time.Duration) benchStats {
+	stats := benchStats{Operation: operation, Count: len(durations)}
+	if len(durations) == 0 {
+		return stats
+	}
+
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+
+	toMs := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	percentile := func(p float64) time.Duration {
+		idx := int(p * float64(len(sorted)-1))
+		return sorted[idx]
+	}
+
+	stats.TotalMs = toMs(total)
+	stats.AvgMs = toMs(total / time.Duration(len(sorted)))
+	stats.P50Ms = toMs(percentile(0.50))
+	stats.P95Ms = toMs(percentile(0.95))
+	stats.MaxMs = toMs(sorted[len(sorted)-1])
+	return stats
+}
+
+// pathSize returns the size of a file or the total size of all files under a
+// directory. Missing paths report zero.
+func pathSize(path string) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if !info.IsDir() {
+		return info.Size(), nil
+	}
+
+	var total int64
+	err = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += fi.Size()
+		return nil
+	})
+	return total, err
+}
+
+func outputBenchTable(cmd *cobra.Command, report *benchReport) error {
+	outputOperationsTable(cmd, report.Operations)
+
+	out := cmd.OutOrStdout()
+	if _, err := fmt.Fprintf(out, "Entries:       %d x %d versions (%d bytes each)\n", report.Entries, report.Versions, report.ContentSize); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(out, "Database size: %d bytes\n", report.DBBytes); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(out, "Objects size:  %d bytes\n", report.ObjectsBytes); err != nil {
+		return err
+	}
+	return nil
+}
+
+// outputOperationsTable renders latency statistics, one operation per row.
+func outputOperationsTable(cmd *cobra.Command, operations []benchStats) {
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(table.Row{"Operation", "Count", "Total (ms)", "Avg (ms)", "p50 (ms)", "p95 (ms)", "Max (ms)"})
+	for _, op := range operations {
+		t.AppendRow(table.Row{
+			op.Operation,
+			op.Count,
+			fmt.Sprintf("%.1f", op.TotalMs),
+			fmt.Sprintf("%.3f", op.AvgMs),
+			fmt.Sprintf("%.3f", op.P50Ms),
+			fmt.Sprintf("%.3f", op.P95Ms),
+			fmt.Sprintf("%.3f", op.MaxMs),
+		})
+	}
+	t.Render()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/choplin/vault.md/internal/database"
+)
+
+func TestBenchIgnoresRemoteDatabase(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"databaseUrl": "benchremote://index.example"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VAULT_DIR", dir)
+	t.Setenv("VAULT_CONFIG", configPath)
+	t.Setenv("CI", "")
+	t.Setenv("VAULT_EPHEMERAL", "")
+
+	opened := false
+	database.RegisterBackend("benchremote", func(string) (*sql.DB, error) {
+		opened = true
+		return nil, errors.New("the remote index must not be used")
+	})
+
+	out, err := runVault(t, "bench", "--entries", "2", "--versions", "2", "--list-iterations", "1", "--format", "json")
+	if err != nil {
+		t.Fatalf("vault bench failed: %v", err)
+	}
+	if opened {
+		t.Error("vault bench opened the configured remote index")
+	}
+	var report benchReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil || report.Entries != 2 || report.DBBytes == 0 {
+		t.Errorf("vault bench report = %s, %v", out, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "index.db")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("vault bench touched the user's vault: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newBlameCmd() *cobra.Command {
+	var (
+		format     string
+		actor      string
+		deviceID   string
+		scopeType  string
+		repoPath   string
+		branchName string
+		worktreeID string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "blame <key>",
+		Short: "Show who wrote each version of an entry",
+		Long: "Show, for each version of an entry, who wrote it, through which interface (cli or mcp), " +
+			"and its description. The actor is the MCP client name for MCP writes and the OS user otherwise; " +
+			"set VAULT_ACTOR to override it.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key := args[0]
+
+			if format != "table" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: table, json)", format)
+			}
+
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			uc := usecase.NewEntry(dbCtx)
+			history, err := uc.History(context.Background(), sc, key, &usecase.HistoryOptions{
+				Actor:    actor,
+				DeviceID: deviceID,
+			})
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				output := make([]blameOutputEntry, 0, len(history))
+				for _, record := range history {
+					output = append(output, newBlameOutputEntry(record))
+				}
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(output)
+			}
+
+			outputBlameTable(cmd, history)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+	cmd.Flags().StringVar(&actor, "actor", "", "Only show versions written by this actor")
+	cmd.Flags().StringVar(&deviceID, "device", "", "Only show versions written from this device (see vault devices)")
+	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
+	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
+	cmd.Flags().StringVar(&worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+
+	return cmd
+}
+
+type blameOutputEntry struct {
+	Version     int64   `json:"version"`
+	CreatedAt   string  `json:"createdAt"`
+	Actor       string  `json:"actor,omitempty"`
+	Tool        string  `json:"tool,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+func newBlameOutputEntry(record database.VersionHistoryRecord) blameOutputEntry {
+	entry := blameOutputEntry{
+		Version:     record.Version,
+		CreatedAt:   record.CreatedAt.Format(time.RFC3339),
+		Description: record.Description,
+	}
+	if p := record.Provenance; p != nil {
+		entry.Actor = p.Actor
+		entry.Tool = p.Tool
+	}
+	return entry
+}
+
+func outputBlameTable(cmd *cobra.Command, history []database.VersionHistoryRecord) {
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(table.Row{"Version", "Written", "Actor", "Via", "Description"})
+
+	for _, record := range history {
+		// Versions written before provenance was recorded show as unknown.
+		actor, via := "?", "?"
+		if p := record.Provenance; p != nil {
+			if p.Actor != "" {
+				actor = p.Actor
+			}
+			if p.Tool != "" {
+				via = p.Tool
+			}
+		}
+
+		description := ""
+		if record.Description != nil {
+			description = *record.Description
+		}
+
+		t.AppendRow(table.Row{
+			record.Version,
+			display.layout(record.CreatedAt, "2006-01-02 15:04"),
+			actor,
+			via,
+			description,
+		})
+	}
+
+	t.Render()
+}
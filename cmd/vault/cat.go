@@ -2,7 +2,6 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"os"
 
@@ -16,6 +15,7 @@ import (
 func newCatCmd() *cobra.Command {
 	var (
 		versionFlag int
+		noVerify    bool
 		scopeType   string
 		repoPath    string
 		branchName  string
@@ -29,7 +29,7 @@ func newCatCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			key := args[0]
 
-			sc, err := scope.ResolveScope(scope.ScopeOptions{
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
 				Type:     scopeType,
 				Repo:     repoPath,
 				Branch:   branchName,
@@ -39,12 +39,18 @@ func newCatCmd() *cobra.Command {
 				return err
 			}
 
-			var opts *usecase.GetOptions
+			opts := &usecase.GetOptions{}
 			if cmd.Flags().Changed("version") {
 				version := versionFlag
-				opts = &usecase.GetOptions{
-					Version: &version,
-				}
+				opts.Version = &version
+			}
+			opts.SkipVerify, err = resolveSkipVerify(cmd, noVerify)
+			if err != nil {
+				return err
+			}
+			opts.TrackRead, err = resolveTrackRead()
+			if err != nil {
+				return err
 			}
 
 			dbCtx, err := database.CreateDatabase("")
@@ -55,7 +61,7 @@ func newCatCmd() *cobra.Command {
 				_ = database.CloseDatabase(dbCtx)
 			}()
 
-			ctx := context.Background()
+			ctx := cmd.Context()
 			uc := usecase.NewEntry(dbCtx)
 			result, err := uc.Get(ctx, sc, key, opts)
 			if err != nil {
@@ -78,6 +84,7 @@ func newCatCmd() *cobra.Command {
 	}
 
 	cmd.Flags().IntVarP(&versionFlag, "version", "v", 0, "Specific version to retrieve")
+	cmd.Flags().BoolVar(&noVerify, "no-verify", false, "Skip the content hash check (default from verifyOnRead in config)")
 	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
 	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
 	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
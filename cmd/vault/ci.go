@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// ciTerminalWidth is the width tables are laid out for in CI mode, whatever
+// the terminal reports.
+const ciTerminalWidth = 120
+
+// ciMode makes output plain and repeatable for pipelines: no prompts,
+// pagers, editors, relative ages, or layout that depends on the terminal.
+// It is set before any command runs.
+var ciMode bool
+
+// loadCIMode enables CI mode with --ci, or when the CI environment variable
+// that CI services set is true. --ci=false turns it off.
+func loadCIMode(cmd *cobra.Command) error {
+	if cmd.Flags().Changed("ci") {
+		enabled, err := cmd.Flags().GetBool("ci")
+		if err != nil {
+			return err
+		}
+		ciMode = enabled
+	} else {
+		switch strings.ToLower(strings.TrimSpace(os.Getenv("CI"))) {
+		case "", "0", "false", "no":
+			ciMode = false
+		default:
+			ciMode = true
+		}
+	}
+	if ciMode {
+		// Errors are read from logs; the usage text only buries them.
+		cmd.SilenceUsage = true
+	}
+	return nil
+}
+
+// refuseInCI fails commands that would wait for a person in CI mode.
+func refuseInCI(what, alternative string) error {
+	if !ciMode {
+		return nil
+	}
+	return fmt.Errorf("%s needs a person at the terminal and is disabled in CI mode; %s", what, alternative)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/config"
+)
+
+// warnCloudSync tells the user when the vault directory is in a folder a
+// sync client such as Dropbox copies around, which is why shared storage
+// mode was switched on. Setting sharedStorage either way silences it.
+func warnCloudSync(cmd *cobra.Command) error {
+	settings, err := config.Load()
+	if err != nil || settings.SharedStorage != nil || settings.RemoteDatabaseURL() != "" {
+		return nil
+	}
+	service := config.CloudSyncService(config.GetVaultDir())
+	if service == "" {
+		return nil
+	}
+	_, err = fmt.Fprintf(cmd.ErrOrStderr(),
+		"note: the vault directory %s is in a %s folder, whose syncing can corrupt the database; using shared storage mode. "+
+			"Move it with VAULT_DIR, or set sharedStorage in the config to silence this.\n",
+		config.GetVaultDir(), service)
+	return err
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/choplin/vault.md/internal/services"
+	"github.com/choplin/vault.md/internal/services/memory"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+// useMemoryRepositories makes commands run on fresh in-memory repositories
+// in a temporary vault directory.
+func useMemoryRepositories(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("VAULT_DIR", dir)
+	t.Setenv("VAULT_CONFIG", filepath.Join(dir, "config.json"))
+	t.Setenv("CI", "")
+	t.Setenv("VAULT_EPHEMERAL", "")
+
+	scopes, entries := memory.NewScopeService(), memory.NewEntryService()
+	saved := openEntry
+	openEntry = func() (*usecase.Entry, func(), error) {
+		return usecase.NewEntryFromRepositories(scopes, entries), func() {}, nil
+	}
+	t.Cleanup(func() {
+		openEntry = saved
+	})
+	return dir
+}
+
+// runVault runs the vault command line with args and returns its stdout.
+func runVault(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	rootCmd.SetArgs(args)
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetErr(&stderr)
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+	})
+	err := rootCmd.Execute()
+	return stdout.String(), err
+}
+
+func TestSetGetListOverMemoryRepositories(t *testing.T) {
+	dir := useMemoryRepositories(t)
+
+	for _, content := range []string{"first\n", "second\n"} {
+		file := filepath.Join(dir, "content.md")
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := runVault(t, "set", "notes", "--scope", "global", "--file", file); err != nil {
+			t.Fatalf("vault set failed: %v", err)
+		}
+	}
+
+	got, err := runVault(t, "get", "notes", "--scope", "global")
+	if err != nil {
+		t.Fatalf("vault get failed: %v", err)
+	}
+	if got != "second\n" {
+		t.Errorf("vault get = %q, want the latest version", got)
+	}
+
+	listed, err := runVault(t, "list", "--scope", "global", "--all-versions", "--template", "{{.Key}} {{.Version}}")
+	if err != nil {
+		t.Fatalf("vault list failed: %v", err)
+	}
+	if strings.Count(listed, "notes ") != 2 {
+		t.Errorf("vault list =\n%s\nwant both versions of notes", listed)
+	}
+
+	if _, err := runVault(t, "get", "missing", "--scope", "global"); !errors.Is(err, services.ErrNotFound) {
+		t.Errorf("vault get of a missing key = %v, want a not-found error", err)
+	}
+}
+
+func TestCounterOverMemoryRepositories(t *testing.T) {
+	useMemoryRepositories(t)
+
+	for want := 1; want <= 2; want++ {
+		got, err := runVault(t, "counter", "incr", "builds", "--scope", "global")
+		if err != nil {
+			t.Fatalf("vault counter incr failed: %v", err)
+		}
+		if strings.TrimSpace(got) != strconv.Itoa(want) {
+			t.Errorf("vault counter incr = %q, want %d", got, want)
+		}
+	}
+
+	got, err := runVault(t, "counter", "get", "builds", "--scope", "global")
+	if err != nil {
+		t.Fatalf("vault counter get failed: %v", err)
+	}
+	if strings.TrimSpace(got) != "2" {
+		t.Errorf("vault counter get = %q, want 2", got)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newCounterCmd() *cobra.Command {
+	flags := &scopeFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "counter",
+		Short: "Keep integer counters that concurrent writers can share",
+		Long: "A counter is an entry holding an integer. counter incr adds to it in one step, retrying when " +
+			"another writer changed it in the meantime, so concurrent increments are never lost.",
+	}
+	flags.bind(cmd.PersistentFlags())
+
+	cmd.AddCommand(newCounterIncrCmd(flags))
+	cmd.AddCommand(newCounterGetCmd(flags))
+	return cmd
+}
+
+func newCounterIncrCmd(flags *scopeFlags) *cobra.Command {
+	var by int64
+
+	cmd := &cobra.Command{
+		Use:   "incr <key>",
+		Short: "Add to a counter, starting from 0, and print the new value",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			capture, err := resolveCaptureEnv(cmd, false)
+			if err != nil {
+				return err
+			}
+
+			return flags.run(cmd, func(ctx context.Context, uc *usecase.Entry, sc scope.Scope) error {
+				opts := &usecase.SetOptions{
+					Provenance: usecase.CaptureProvenance(ctx, usecase.ToolCLI, "", "", capture),
+				}
+				result, err := uc.Increment(ctx, sc, args[0], by, opts)
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintln(cmd.OutOrStdout(), result.Value)
+				return err
+			})
+		},
+	}
+
+	cmd.Flags().Int64Var(&by, "by", 1, "Amount to add; negative to decrement")
+	return cmd
+}
+
+func newCounterGetCmd(flags *scopeFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print a counter, or 0 if it was never incremented",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return flags.run(cmd, func(ctx context.Context, uc *usecase.Entry, sc scope.Scope) error {
+				value, err := uc.Counter(ctx, sc, args[0])
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintln(cmd.OutOrStdout(), value)
+				return err
+			})
+		},
+	}
+}
+
+func newLogAppendCmd() *cobra.Command {
+	flags := &scopeFlags{}
+	var filePath string
+
+	cmd := &cobra.Command{
+		Use:   "log-append <key> [line]",
+		Short: "Append a line to an entry",
+		Long: "Append a line to an entry, creating it if needed. The line is the argument, or read from --file " +
+			"or stdin. Appends by concurrent writers are retried rather than overwriting each other, so every " +
+			"line lands.",
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var line string
+			if len(args) == 2 {
+				line = args[1]
+			} else {
+				content, err := readContent(cmd, filePath)
+				if err != nil {
+					return err
+				}
+				line = content
+			}
+			capture, err := resolveCaptureEnv(cmd, false)
+			if err != nil {
+				return err
+			}
+
+			return flags.run(cmd, func(ctx context.Context, uc *usecase.Entry, sc scope.Scope) error {
+				opts := &usecase.SetOptions{
+					Provenance: usecase.CaptureProvenance(ctx, usecase.ToolCLI, "", "", capture),
+				}
+				result, err := uc.AppendLine(ctx, sc, args[0], line, opts)
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintf(cmd.OutOrStdout(), "Appended to %s (version %d)\n", args[0], result.Version)
+				return err
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&filePath, "file", "f", "", "Read the line from file instead of stdin")
+	flags.bind(cmd.Flags())
+	return cmd
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newDBCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Maintain the vault database",
+	}
+	cmd.AddCommand(newDBVerifyBackupCmd())
+	cmd.AddCommand(newDBRelayoutCmd())
+	cmd.AddCommand(newDBGCCmd())
+	return cmd
+}
+
+func newDBVerifyBackupCmd() *cobra.Command {
+	var (
+		format string
+		sample int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "verify-backup <path>",
+		Short: "Check that a backup of the vault can be restored",
+		Long: "Open a backup read-only and check it: the path is a copy of the vault directory, or a copy of " +
+			"its index.db with the objects directory next to it or not at all. The database must pass SQLite's " +
+			"integrity check and the doctor's consistency checks, its entry and version counts are compared " +
+			"with the live vault, and a random sample of versions is read back and checked against their " +
+			"hashes. Exits with a non-zero status when any check fails.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: table, json)", format)
+			}
+			if sample < 0 {
+				return fmt.Errorf("--sample must not be negative")
+			}
+
+			report := usecase.VerifyBackup(context.Background(), args[0], sample)
+
+			var err error
+			if format == "json" {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				err = encoder.Encode(report)
+			} else {
+				err = outputDoctorTable(cmd, report)
+			}
+			if err != nil {
+				return err
+			}
+
+			if report.Status == usecase.CheckFail {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("backup verification failed")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+	cmd.Flags().IntVar(&sample, "sample", usecase.DefaultBackupSample, "Number of versions to hash; 0 hashes every version")
+
+	return cmd
+}
+
+func newDBRelayoutCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "relayout <flat|sharded>",
+		Short: "Move the object files into another directory layout",
+		Long: "Arrange the object files of every scope in the given layout and write new ones in it from now on. " +
+			"flat keeps all files of a scope in one directory; sharded spreads them over up to 256 " +
+			"subdirectories by a hash of the key, like git's objects/ab/, for scopes with many thousand " +
+			"versions. The vault stays usable while files are moved, and an interrupted run can be repeated.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			layout, err := filesystem.ParseLayout(args[0])
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			result, err := usecase.RelayoutObjects(cmd.Context(), dbCtx, layout, nil)
+			if err != nil {
+				if result != nil && result.Moved > 0 {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Moved %d object(s) before the error; run it again to finish\n", result.Moved)
+				}
+				return err
+			}
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "Moved %d object(s) into the %s layout\n", result.Moved, result.Layout); err != nil {
+				return err
+			}
+			if result.Skipped > 0 {
+				_, err := fmt.Fprintf(cmd.ErrOrStderr(), "note: skipped %d version(s) whose object is missing or outside the objects directory (see vault doctor)\n", result.Skipped)
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+func newDBGCCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "gc",
+		Short: "Remove object files left behind by failed deletes",
+		Long: "Retry removing the object files of deleted versions that could not be removed at the time, " +
+			"for example because of a permission error or a crash between the database update and the file " +
+			"removal. Such files are queued and also retried before each delete. Exits with a non-zero " +
+			"status when files remain queued.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			result, err := usecase.NewEntry(dbCtx).ProcessPendingDeletions(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "Removed %d queued object(s)\n", result.Removed); err != nil {
+				return err
+			}
+			if len(result.Failed) == 0 {
+				return nil
+			}
+			for _, p := range result.Failed {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "  %s (%d attempt(s)): %s\n", p.FilePath, p.Attempts, p.LastError)
+			}
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d object(s) could not be removed and stay queued", len(result.Failed))
+		},
+	}
+}
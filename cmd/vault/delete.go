@@ -2,7 +2,6 @@ package main
 
 import (
 	"bufio"
-	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -31,7 +30,7 @@ func newDeleteCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			key := args[0]
 
-			sc, err := scope.ResolveScope(scope.ScopeOptions{
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
 				Type:     scopeType,
 				Repo:     repoPath,
 				Branch:   branchName,
@@ -43,6 +42,9 @@ func newDeleteCmd() *cobra.Command {
 
 			// Confirmation prompt
 			if !force {
+				if err := refuseInCI("confirming a deletion", "pass --force to delete"); err != nil {
+					return err
+				}
 				var message string
 				if cmd.Flags().Changed("version") {
 					message = fmt.Sprintf("Delete version %d of '%s'? (y/N) ", versionFlag, key)
@@ -76,9 +78,23 @@ func newDeleteCmd() *cobra.Command {
 				_ = database.CloseDatabase(dbCtx)
 			}()
 
-			ctx := context.Background()
+			ctx := cmd.Context()
 			uc := usecase.NewEntry(dbCtx)
 
+			var undoVersion int64
+			if cmd.Flags().Changed("version") {
+				undoVersion = int64(versionFlag)
+			}
+			undo, err := uc.CaptureDelete(ctx, sc, key, undoVersion)
+			if err != nil {
+				// A version whose file is missing can still be deleted, just
+				// not brought back.
+				if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "note: this delete cannot be undone: %v\n", err); err != nil {
+					return err
+				}
+				undo = nil
+			}
+
 			// Execute deletion
 			if cmd.Flags().Changed("version") {
 				deleted, err := uc.DeleteVersion(ctx, sc, key, versionFlag)
@@ -110,7 +126,7 @@ func newDeleteCmd() *cobra.Command {
 				}
 			}
 
-			return nil
+			return recordUndo(cmd, undo)
 		},
 	}
 
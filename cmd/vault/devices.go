@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newDevicesCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "devices",
+		Short: "List the devices that have written to the vault",
+		Long: "List the installations that have written to the vault, identified by the device ID each one " +
+			"records with its versions. The ID of this installation is stored next to the config file " +
+			"(override with VAULT_DEVICE_ID). Devices learned through sync-git or snapshot restores are listed too.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: table, json)", format)
+			}
+
+			self, err := config.DeviceID()
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			devices, err := usecase.NewEntry(dbCtx).Devices(context.Background())
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				output := make([]deviceOutputEntry, 0, len(devices))
+				for _, d := range devices {
+					output = append(output, newDeviceOutputEntry(d, self))
+				}
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(output)
+			}
+
+			outputDevicesTable(cmd, devices, self)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+	cmd.AddCommand(newDevicesRevokeCmd())
+
+	return cmd
+}
+
+func newDevicesRevokeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke <device-id>",
+		Short: "Stop a device from pushing to shared remotes",
+		Long: "Revoke a device. Its versions are no longer imported by sync-git --restore or snapshot --from, " +
+			"and once it sees the revocation it can no longer push. The revocation is published with the " +
+			"next sync-git push or snapshot.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			deviceID := args[0]
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			if err := usecase.NewEntry(dbCtx).RevokeDevice(context.Background(), deviceID); err != nil {
+				return err
+			}
+			if self, err := config.DeviceID(); err == nil && self == deviceID {
+				if _, err := fmt.Fprintln(cmd.ErrOrStderr(), "note: this is the current device; it can no longer push"); err != nil {
+					return err
+				}
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Revoked device %s\n", deviceID)
+			return err
+		},
+	}
+}
+
+type deviceOutputEntry struct {
+	DeviceID     string  `json:"deviceId"`
+	Hostname     string  `json:"hostname,omitempty"`
+	Current      bool    `json:"current"`
+	FirstSeenAt  string  `json:"firstSeenAt"`
+	LastWriteAt  *string `json:"lastWriteAt,omitempty"`
+	VersionCount int64   `json:"versionCount"`
+	RevokedAt    *string `json:"revokedAt,omitempty"`
+}
+
+func newDeviceOutputEntry(d database.DeviceRecord, self string) deviceOutputEntry {
+	entry := deviceOutputEntry{
+		DeviceID:     d.DeviceID,
+		Hostname:     d.Hostname,
+		Current:      d.DeviceID == self,
+		FirstSeenAt:  d.FirstSeenAt.Format(time.RFC3339),
+		VersionCount: d.VersionCount,
+	}
+	if !d.LastWriteAt.IsZero() {
+		lastWrite := d.LastWriteAt.Format(time.RFC3339)
+		entry.LastWriteAt = &lastWrite
+	}
+	if d.RevokedAt != nil {
+		revokedAt := d.RevokedAt.Format(time.RFC3339)
+		entry.RevokedAt = &revokedAt
+	}
+	return entry
+}
+
+func outputDevicesTable(cmd *cobra.Command, devices []database.DeviceRecord, self string) {
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(table.Row{"Device", "Host", "Versions", "Last Write", "Status"})
+
+	for _, d := range devices {
+		id := d.DeviceID
+		if id == self {
+			id += " (this device)"
+		}
+		lastWrite := display.timestamp(d.LastWriteAt)
+		status := "active"
+		if d.RevokedAt != nil {
+			status = "revoked " + display.date(*d.RevokedAt)
+		}
+		t.AppendRow(table.Row{id, d.Hostname, d.VersionCount, lastWrite, status})
+	}
+
+	t.Render()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"time"
+
+	"github.com/choplin/vault.md/internal/config"
+)
+
+// displayTime renders timestamps in tables and other human-readable output
+// with the timezone and layouts from the display section of the config
+// file. JSON output does not use it and stays RFC3339.
+type displayTime struct {
+	loc        *time.Location
+	timeLayout string
+	dateLayout string
+}
+
+// display is loaded from the config file before any command runs.
+var display = displayTime{
+	loc:        time.Local,
+	timeLayout: config.DefaultTimeFormat,
+	dateLayout: config.DefaultDateFormat,
+}
+
+// loadDisplayTime reads the display settings. A config that cannot be
+// loaded keeps the defaults, so that commands such as doctor can still
+// report the problem.
+func loadDisplayTime() {
+	settings, err := config.Load()
+	if err != nil {
+		return
+	}
+	display = displayTime{
+		loc:        settings.DisplayLocation(),
+		timeLayout: settings.DisplayTimeFormat(),
+		dateLayout: settings.DisplayDateFormat(),
+	}
+}
+
+// timestamp formats t with the configured time layout, or returns "" for
+// the zero time.
+func (d displayTime) timestamp(t time.Time) string {
+	return d.layout(t, d.timeLayout)
+}
+
+// date formats t with the configured date layout.
+func (d displayTime) date(t time.Time) string {
+	return d.layout(t, d.dateLayout)
+}
+
+// layout formats t in the configured timezone with a fixed layout, for
+// columns too narrow for the configured one.
+func (d displayTime) layout(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(d.loc).Format(layout)
+}
+
+// timestampWidth is the width of a formatted timestamp, measured on a date
+// with long month and weekday names.
+func (d displayTime) timestampWidth() int {
+	return len(d.timestamp(time.Date(2026, 9, 30, 23, 59, 59, 0, d.loc)))
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newDoctorCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose the vault environment",
+		Long: "Check the vault directory, config file, database schema and journal, git availability, " +
+			"disk space, and consistency between the index and stored objects. " +
+			"Exits with a non-zero status when any check fails.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: table, json)", format)
+			}
+
+			report := usecase.NewDoctor().Run(context.Background())
+
+			var err error
+			if format == "json" {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				err = encoder.Encode(report)
+			} else {
+				err = outputDoctorTable(cmd, report)
+			}
+			if err != nil {
+				return err
+			}
+
+			if report.Status == usecase.CheckFail {
+				// The findings are already printed; skip the usage text.
+				cmd.SilenceUsage = true
+				return fmt.Errorf("doctor found failing checks")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+
+	return cmd
+}
+
+func outputDoctorTable(cmd *cobra.Command, report *usecase.DoctorReport) error {
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(table.Row{"Check", "Status", "Details"})
+	for _, check := range report.Checks {
+		details := check.Message
+		if check.Hint != "" {
+			details += "\n→ " + check.Hint
+		}
+		t.AppendRow(table.Row{check.Name, strings.ToUpper(string(check.Status)), details})
+	}
+	t.Render()
+
+	if _, err := fmt.Fprintf(cmd.OutOrStdout(), "Overall: %s\n", strings.ToUpper(string(report.Status))); err != nil {
+		return err
+	}
+	return nil
+}
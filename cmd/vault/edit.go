@@ -1,15 +1,16 @@
 package main
 
 import (
-	"context"
 	"crypto/sha256"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 
 	"github.com/spf13/cobra"
 
+	"github.com/choplin/vault.md/internal/config"
 	"github.com/choplin/vault.md/internal/database"
 	"github.com/choplin/vault.md/internal/scope"
 	"github.com/choplin/vault.md/internal/usecase"
@@ -22,6 +23,8 @@ func newEditCmd() *cobra.Command {
 		repoPath    string
 		branchName  string
 		worktreeID  string
+		captureEnv  bool
+		force       bool
 	)
 
 	cmd := &cobra.Command{
@@ -29,9 +32,12 @@ func newEditCmd() *cobra.Command {
 		Short: "Edit entry with $EDITOR",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := refuseInCI("vault edit", "use vault set or vault patch"); err != nil {
+				return err
+			}
 			key := args[0]
 
-			sc, err := scope.ResolveScope(scope.ScopeOptions{
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
 				Type:     scopeType,
 				Repo:     repoPath,
 				Branch:   branchName,
@@ -57,7 +63,7 @@ func newEditCmd() *cobra.Command {
 				_ = database.CloseDatabase(dbCtx)
 			}()
 
-			ctx := context.Background()
+			ctx := cmd.Context()
 			uc := usecase.NewEntry(dbCtx)
 
 			// Get current entry
@@ -68,6 +74,12 @@ func newEditCmd() *cobra.Command {
 			if result == nil {
 				return fmt.Errorf("key not found: %s", key)
 			}
+			if !force {
+				// Refuse before the editor opens rather than losing the edit.
+				if err := uc.CheckLock(ctx, sc, key, usecase.ResolveActor("")); err != nil {
+					return err
+				}
+			}
 
 			// Read current content
 			currentContent, err := os.ReadFile(result.Record.FilePath)
@@ -82,7 +94,7 @@ func newEditCmd() *cobra.Command {
 			}
 			defer func() { _ = os.RemoveAll(tempDir) }()
 
-			tempFile := filepath.Join(tempDir, key+".md")
+			tempFile := filepath.Join(tempDir, openFileName(key, result.Record.Language))
 			if err := os.WriteFile(tempFile, currentContent, 0o600); err != nil {
 				return err
 			}
@@ -93,12 +105,17 @@ func newEditCmd() *cobra.Command {
 				editor = os.Getenv("VISUAL")
 			}
 			if editor == "" {
-				editor = "vi"
+				editor = defaultEditor()
+			}
+			// EDITOR may carry arguments, as in "code --wait".
+			editorArgs, err := config.SplitCommandLine(editor)
+			if err != nil {
+				return fmt.Errorf("invalid editor %q: %w", editor, err)
 			}
 
 			// Open editor
-			//nolint:gosec // G204: editor is from EDITOR env var or default vi
-			editorCmd := exec.Command(editor, tempFile)
+			//nolint:gosec // G204: editor is from EDITOR env var or the platform default
+			editorCmd := exec.Command(editorArgs[0], append(editorArgs[1:], tempFile)...)
 			editorCmd.Stdin = os.Stdin
 			editorCmd.Stdout = os.Stdout
 			editorCmd.Stderr = os.Stderr
@@ -125,14 +142,30 @@ func newEditCmd() *cobra.Command {
 				return nil
 			}
 
+			capture, err := resolveCaptureEnv(cmd, captureEnv)
+			if err != nil {
+				return err
+			}
+			summarize, err := loadSummarizer(false)
+			if err != nil {
+				return err
+			}
+
 			// Save as new version
 			description := fmt.Sprintf("Edited with %s", editor)
-			_, err = uc.Set(ctx, sc, key, string(editedContent), &usecase.SetOptions{
+			saved, err := uc.Set(ctx, sc, key, string(editedContent), &usecase.SetOptions{
 				Description: &description,
+				Provenance:  usecase.CaptureProvenance(cmd.Context(), usecase.ToolCLI, "", "", capture),
+				Language:    result.Record.Language,
+				Summarizer:  summarize,
+				IgnoreLock:  force,
 			})
 			if err != nil {
 				return err
 			}
+			if err := warnSummaryFailure(cmd, key, saved); err != nil {
+				return err
+			}
 
 			if _, err := fmt.Fprintln(cmd.OutOrStdout(), "Entry updated"); err != nil {
 				return err
@@ -142,6 +175,8 @@ func newEditCmd() *cobra.Command {
 	}
 
 	cmd.Flags().IntVarP(&versionFlag, "version", "v", 0, "Edit specific version")
+	cmd.Flags().BoolVar(&captureEnv, "capture-env", false, "Record hostname and git branch/commit/dirty state with the version (default from config)")
+	cmd.Flags().BoolVar(&force, "force", false, "Write even if another owner holds a lock on the key")
 	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
 	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
 	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
@@ -149,3 +184,11 @@ func newEditCmd() *cobra.Command {
 
 	return cmd
 }
+
+// defaultEditor is used when neither EDITOR nor VISUAL is set.
+func defaultEditor() string {
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+// openEntry opens the vault database and returns the entry use case on it,
+// with a function that closes the database. Tests replace it to run commands
+// on the in-memory repositories.
+var openEntry = func() (*usecase.Entry, func(), error) {
+	dbCtx, err := database.CreateDatabase("")
+	if err != nil {
+		return nil, nil, err
+	}
+	return usecase.NewEntry(dbCtx), func() {
+		_ = database.CloseDatabase(dbCtx)
+	}, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/config"
+)
+
+// applyEphemeral switches to a throwaway vault with --ephemeral, or when
+// VAULT_EPHEMERAL is true: an in-memory database and a temporary vault
+// directory that is removed when the command finishes. --ephemeral=false
+// turns it off.
+func applyEphemeral(cmd *cobra.Command) error {
+	enabled := config.IsEphemeral()
+	if cmd.Flags().Changed("ephemeral") {
+		var err error
+		if enabled, err = cmd.Flags().GetBool("ephemeral"); err != nil {
+			return err
+		}
+	}
+	if !enabled {
+		return nil
+	}
+
+	cleanup, err := config.StartEphemeral()
+	if err != nil {
+		return err
+	}
+	cobra.OnFinalize(cleanup)
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/snapshot"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newExportCmd() *cobra.Command {
+	var (
+		outputPath string
+		all        bool
+		encrypt    bool
+		format     int
+		keyGlob    string
+		since      string
+		onlyType   string
+		ghSummary  bool
+		scopeType  string
+		repoPath   string
+		branchName string
+		worktreeID string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export -o <file>",
+		Short: "Export a scope to a single bundle file",
+		Long: "Write every version of the current scope (or the one chosen with the scope flags), with its " +
+			"metadata, to one bundle file: a gzipped tar holding a snapshot in the layout of `vault snapshot`. " +
+			"--all exports every scope.\n\n" +
+			"--key-glob and --since narrow the bundle to matching keys or recent versions, and --scope-type " +
+			"exports every scope of one type, for focused bundles such as only the ADRs written in the last " +
+			"quarter.\n\n" +
+			"In GitHub Actions, --github-summary lists the exported keys in the job summary and sets step " +
+			"outputs (bundle, snapshot-id, keys, versions, exported-keys), for jobs that keep the agent context " +
+			"of each build as an artifact.\n\nRestore it elsewhere with `vault import <file>`; bundles restore into the " +
+			"scopes they were exported from and keep what the vault already has. Use -o - to write to stdout.\n\n" +
+			"--encrypt seals the bundle with a passphrase (scrypt and AES-256-GCM) so exports holding sensitive " +
+			"context can be mailed or uploaded safely; import asks for the passphrase. Set VAULT_PASSPHRASE to " +
+			"skip the prompt.\n\n" +
+			"--format-version 1 writes a bundle older releases can restore, leaving out version metadata, " +
+			"parent links, and scope information.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if outputPath == "" {
+				return fmt.Errorf("specify the bundle file with --output (- for stdout)")
+			}
+			acrossScopes := all || onlyType != ""
+			if acrossScopes && (scopeType != "" || repoPath != "" || branchName != "" || worktreeID != "") {
+				return fmt.Errorf("--all and --scope-type export across scopes; they cannot be combined with --scope, --repo, --branch, or --worktree")
+			}
+			if err := usecase.ValidateSnapshotFormatVersion(format); err != nil {
+				return err
+			}
+			if ghSummary {
+				if err := checkGitHubSummary(); err != nil {
+					return err
+				}
+			}
+			sinceTime, err := parseTimeFlag(since, time.Now())
+			if err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+
+			filter := &usecase.SnapshotFilter{
+				KeyGlob:   keyGlob,
+				Since:     sinceTime,
+				ScopeType: scope.ScopeType(onlyType),
+			}
+			if err := filter.Validate(); err != nil {
+				return err
+			}
+			if !acrossScopes {
+				sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+					Type:     scopeType,
+					Repo:     repoPath,
+					Branch:   branchName,
+					Worktree: worktreeID,
+				})
+				if err != nil {
+					return err
+				}
+				filter.Scope = &sc
+			}
+
+			var passphrase []byte
+			if encrypt {
+				var err error
+				if passphrase, err = readPassphrase(cmd.ErrOrStderr(), true); err != nil {
+					return err
+				}
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			uc := usecase.NewEntry(dbCtx)
+			bundle := snapshot.NewBundleStore()
+			result, err := uc.SnapshotTo(cmd.Context(), bundle, usecase.SnapshotToOptions{
+				Filter:        filter,
+				FormatVersion: format,
+			})
+			if err != nil {
+				return err
+			}
+			if result.Versions == 0 {
+				return fmt.Errorf("nothing to export")
+			}
+
+			data, err := bundle.Bytes()
+			if err != nil {
+				return err
+			}
+			if encrypt {
+				if data, err = snapshot.SealWithPassphrase(passphrase, data); err != nil {
+					return err
+				}
+			}
+
+			var out io.Writer = cmd.OutOrStdout()
+			report, target := cmd.OutOrStdout(), outputPath
+			if outputPath == "-" {
+				report, target = cmd.ErrOrStderr(), "stdout"
+			} else {
+				file, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600) //nolint:gosec // G304: output path is chosen by the user
+				if err != nil {
+					return err
+				}
+				defer func() {
+					_ = file.Close()
+				}()
+				out = file
+			}
+			if _, err := out.Write(data); err != nil {
+				return err
+			}
+
+			if _, err := fmt.Fprintf(report, "Exported %d key(s), %d version(s) to %s\n", result.Entries, result.Versions, target); err != nil {
+				return err
+			}
+			if ghSummary {
+				return writeExportGitHubSummary(target, result)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "Bundle file to write (- for stdout)")
+	cmd.Flags().BoolVar(&all, "all", false, "Export every scope instead of one")
+	cmd.Flags().StringVar(&keyGlob, "key-glob", "", `Only export keys matching this glob (e.g. "adr/*")`)
+	cmd.Flags().StringVar(&since, "since", "", "Only export versions written at or after this time (RFC3339, YYYY-MM-DD, or an age like 90d)")
+	cmd.Flags().StringVar(&onlyType, "scope-type", "", "Export every scope of this type: global, repository, branch, or worktree")
+	cmd.Flags().BoolVar(&ghSummary, "github-summary", false, "Add the exported keys to $GITHUB_STEP_SUMMARY and set step outputs in $GITHUB_OUTPUT")
+	cmd.Flags().BoolVar(&encrypt, "encrypt", false, "Encrypt the bundle with a passphrase")
+	cmd.Flags().IntVar(&format, "format-version", 0, fmt.Sprintf("Manifest format version to write, for older releases (1 to %d, default: current)", usecase.SnapshotFormatVersion))
+	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
+	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
+	cmd.Flags().StringVar(&worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+
+	return cmd
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/snapshot"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newExportKeyCmd() *cobra.Command {
+	var (
+		outputPath string
+		scopeType  string
+		repoPath   string
+		branchName string
+		worktreeID string
+		format     int
+		encrypt    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export-key <key>",
+		Short: "Export every version of a key to a JSON file",
+		Long: "Write a self-contained JSON document with the content and metadata of every version " +
+			"of a key. Restore it elsewhere with `vault import-key`.\n\n" +
+			"--format-version 1 writes a document older releases can import, leaving out version metadata " +
+			"such as provenance, approvals, and summaries.\n\n" +
+			"--encrypt seals the document with a passphrase (scrypt and AES-256-GCM) so it can be mailed or " +
+			"uploaded safely; import-key asks for the passphrase. Set VAULT_PASSPHRASE to skip the prompt.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key := args[0]
+			if err := usecase.ValidateKeyExportFormatVersion(format); err != nil {
+				return err
+			}
+
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			ctx := cmd.Context()
+			uc := usecase.NewEntry(dbCtx)
+			export, err := uc.ExportKey(ctx, sc, key)
+			if err != nil {
+				return err
+			}
+			if export, err = export.ConvertTo(format); err != nil {
+				return err
+			}
+
+			data, err := json.MarshalIndent(export, "", "  ")
+			if err != nil {
+				return err
+			}
+			data = append(data, '\n')
+			if encrypt {
+				passphrase, err := readPassphrase(cmd.ErrOrStderr(), true)
+				if err != nil {
+					return err
+				}
+				if data, err = snapshot.SealWithPassphrase(passphrase, data); err != nil {
+					return err
+				}
+			}
+
+			var out io.Writer = cmd.OutOrStdout()
+			if outputPath != "" && outputPath != "-" {
+				file, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600) //nolint:gosec // G304: output path is chosen by the user
+				if err != nil {
+					return err
+				}
+				defer func() {
+					_ = file.Close()
+				}()
+				out = file
+			}
+
+			_, err = out.Write(data)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "Write to this file instead of stdout")
+	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
+	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
+	cmd.Flags().StringVar(&worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+	cmd.Flags().BoolVar(&encrypt, "encrypt", false, "Encrypt the document with a passphrase")
+	cmd.Flags().IntVar(&format, "format-version", 0, fmt.Sprintf("Document format version to write, for older releases (1 to %d, default: current)", usecase.KeyExportFormatVersion))
+
+	return cmd
+}
@@ -2,19 +2,33 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
-	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/jsonquery"
 	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/textpatch"
 	"github.com/choplin/vault.md/internal/usecase"
 )
 
 func newGetCmd() *cobra.Command {
 	var (
 		versionFlag int
+		noVerify    bool
+		withInfo    bool
+		approved    bool
+		section     string
+		query       string
+		rawOutput   bool
+		children    bool
+		snapshot    string
+		asOf        string
 		scopeType   string
 		repoPath    string
 		branchName  string
@@ -28,7 +42,7 @@ func newGetCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			key := args[0]
 
-			sc, err := scope.ResolveScope(scope.ScopeOptions{
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
 				Type:     scopeType,
 				Repo:     repoPath,
 				Branch:   branchName,
@@ -38,24 +52,70 @@ func newGetCmd() *cobra.Command {
 				return err
 			}
 
-			var opts *usecase.GetOptions
+			var q *jsonquery.Query
+			if cmd.Flags().Changed("query") {
+				if section != "" || withInfo {
+					return fmt.Errorf("--query cannot be combined with --section or --info")
+				}
+				if q, err = jsonquery.Parse(query); err != nil {
+					return err
+				}
+			} else if rawOutput {
+				return fmt.Errorf("--raw-output requires --query")
+			}
+			if children && (section != "" || withInfo || q != nil) {
+				return fmt.Errorf("--with-children cannot be combined with --section, --info, or --query")
+			}
+
+			opts := &usecase.GetOptions{}
+			selectors := 0
+			for _, name := range []string{"version", "approved", "snapshot", "as-of"} {
+				if cmd.Flags().Changed(name) {
+					selectors++
+				}
+			}
+			if selectors > 1 {
+				return fmt.Errorf("specify only one of --version, --approved, --snapshot, or --as-of")
+			}
+			if opts.AsOf, err = parseTimeFlag(asOf, time.Now()); err != nil {
+				return fmt.Errorf("--as-of: %w", err)
+			}
 			if cmd.Flags().Changed("version") {
 				version := versionFlag
-				opts = &usecase.GetOptions{
-					Version: &version,
-				}
+				opts.Version = &version
 			}
+			opts.Approved = approved
+			opts.Snapshot = snapshot
+			opts.SkipVerify, err = resolveSkipVerify(cmd, noVerify)
+			if err != nil {
+				return err
+			}
+			opts.TrackRead, err = resolveTrackRead()
+			if err != nil {
+				return err
+			}
+
+			uc, closeEntry, err := openEntry()
+			if err != nil {
+				return err
+			}
+			defer closeEntry()
+
+			ctx := cmd.Context()
 
-			dbCtx, err := database.CreateDatabase("")
+			closePager, err := startPager(cmd)
 			if err != nil {
 				return err
 			}
-			defer func() {
-				_ = database.CloseDatabase(dbCtx)
-			}()
+			defer closePager()
+
+			if withInfo {
+				return outputGetWithInfo(ctx, cmd, uc, sc, key, section, opts)
+			}
+			if children {
+				return outputWithChildren(ctx, cmd, uc, sc, key, opts)
+			}
 
-			ctx := context.Background()
-			uc := usecase.NewEntry(dbCtx)
 			result, err := uc.Get(ctx, sc, key, opts)
 			if err != nil {
 				return err
@@ -64,12 +124,15 @@ func newGetCmd() *cobra.Command {
 				return fmt.Errorf("key not found: %s", key)
 			}
 
-			content, err := os.ReadFile(result.Record.FilePath)
+			content, err := readEntryContent(result.Record.FilePath, section)
 			if err != nil {
 				return err
 			}
 
-			if _, err := cmd.OutOrStdout().Write(content); err != nil {
+			if q != nil {
+				return outputQuery(cmd.OutOrStdout(), key, content, q, rawOutput)
+			}
+			if _, err := io.WriteString(cmd.OutOrStdout(), content); err != nil {
 				return err
 			}
 			return nil
@@ -77,6 +140,15 @@ func newGetCmd() *cobra.Command {
 	}
 
 	cmd.Flags().IntVarP(&versionFlag, "version", "v", 0, "Specific version to retrieve")
+	cmd.Flags().BoolVar(&noVerify, "no-verify", false, "Skip the content hash check (default from verifyOnRead in config)")
+	cmd.Flags().StringVar(&snapshot, "snapshot", "", "Get the version pinned by this scope snapshot (see vault snapshot-scope)")
+	cmd.Flags().StringVar(&asOf, "as-of", "", "Get the version that was latest at this time (RFC3339, YYYY-MM-DD, or age like 7d)")
+	cmd.Flags().BoolVar(&withInfo, "info", false, "Print content together with entry metadata as JSON")
+	cmd.Flags().BoolVar(&approved, "approved", false, "Get the newest approved version instead of the latest (see vault approve)")
+	cmd.Flags().StringVar(&section, "section", "", `Print only the content under this markdown heading (e.g. "## Decisions")`)
+	cmd.Flags().BoolVar(&children, "with-children", false, "Append the entry's children in order, each followed by its own (see vault parent)")
+	cmd.Flags().StringVar(&query, "query", "", `Print the values a jq-style path (e.g. ".tasks[0].status") selects from JSON content`)
+	cmd.Flags().BoolVarP(&rawOutput, "raw-output", "r", false, "With --query, print strings without JSON quotes")
 	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
 	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
 	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
@@ -84,3 +156,102 @@ func newGetCmd() *cobra.Command {
 
 	return cmd
 }
+
+type getInfoOutput struct {
+	infoOutputEntry
+	Content string `json:"content"`
+}
+
+// outputGetWithInfo prints content and metadata of the same version in one
+// JSON document, so callers can cite the version and hash of what they read.
+func outputGetWithInfo(ctx context.Context, cmd *cobra.Command, uc *usecase.Entry, sc scope.Scope, key, section string, opts *usecase.GetOptions) error {
+	result, err := uc.Info(ctx, sc, key, opts)
+	if err != nil {
+		return err
+	}
+
+	content, err := readEntryContent(result.Record.FilePath, section)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(getInfoOutput{
+		infoOutputEntry: newInfoOutputEntry(result),
+		Content:         content,
+	})
+}
+
+// outputWithChildren prints key assembled with its descendants.
+func outputWithChildren(ctx context.Context, cmd *cobra.Command, uc *usecase.Entry, sc scope.Scope, key string, opts *usecase.GetOptions) error {
+	doc, err := uc.Assemble(ctx, sc, key, opts)
+	if err != nil {
+		return err
+	}
+	for _, missing := range doc.Missing {
+		if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "note: child %s has no version; skipped\n", missing); err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(cmd.OutOrStdout(), doc.Content())
+	return err
+}
+
+// outputQuery prints each value q selects from content on its own line.
+func outputQuery(w io.Writer, key, content string, q *jsonquery.Query, raw bool) error {
+	doc, err := jsonquery.Decode(content)
+	if err != nil {
+		return fmt.Errorf("%s is not JSON: %w", key, err)
+	}
+	values, err := q.Run(doc)
+	if err != nil {
+		return err
+	}
+	for _, v := range values {
+		out, err := jsonquery.Format(v, raw)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readEntryContent reads a stored version, narrowed to one markdown section
+// when section is not empty.
+func readEntryContent(path, section string) (string, error) {
+	//nolint:gosec // G304: path is from database, controlled by application
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if section == "" {
+		return string(content), nil
+	}
+	return textpatch.ExtractSection(string(content), section)
+}
+
+// resolveSkipVerify decides whether a read skips hash verification: an
+// explicit --no-verify wins, otherwise verifyOnRead from the config file.
+func resolveSkipVerify(cmd *cobra.Command, noVerify bool) (bool, error) {
+	if cmd.Flags().Changed("no-verify") {
+		return noVerify, nil
+	}
+	settings, err := config.Load()
+	if err != nil {
+		return false, err
+	}
+	return !settings.ShouldVerifyOnRead(), nil
+}
+
+// resolveTrackRead reports whether reads are recorded (trackReads in config).
+func resolveTrackRead() (bool, error) {
+	settings, err := config.Load()
+	if err != nil {
+		return false, err
+	}
+	return settings.ShouldTrackReads(), nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+// maxSummaryRows caps the table of changed keys in a step summary, which
+// GitHub limits to 1 MiB.
+const maxSummaryRows = 200
+
+// writeSnapshotGitHubSummary appends a Markdown summary of a snapshot to the
+// file GitHub Actions names in GITHUB_STEP_SUMMARY, and step outputs to the
+// one in GITHUB_OUTPUT when it is set.
+func writeSnapshotGitHubSummary(target string, result *usecase.SnapshotResult) error {
+	return writeGitHubSummary(snapshotSummaryMarkdown(target, result), snapshotOutputs(result))
+}
+
+// writeExportGitHubSummary appends a Markdown summary of an export bundle to
+// GITHUB_STEP_SUMMARY, and step outputs to GITHUB_OUTPUT when it is set.
+func writeExportGitHubSummary(target string, result *usecase.SnapshotResult) error {
+	return writeGitHubSummary(exportSummaryMarkdown(target, result), exportOutputs(target, result))
+}
+
+func writeGitHubSummary(markdown, outputs string) error {
+	if err := checkGitHubSummary(); err != nil {
+		return err
+	}
+	if err := appendToFile(os.Getenv("GITHUB_STEP_SUMMARY"), markdown); err != nil {
+		return fmt.Errorf("failed to write step summary: %w", err)
+	}
+
+	outputPath := os.Getenv("GITHUB_OUTPUT")
+	if outputPath == "" {
+		return nil
+	}
+	if err := appendToFile(outputPath, outputs); err != nil {
+		return fmt.Errorf("failed to write step outputs: %w", err)
+	}
+	return nil
+}
+
+// checkGitHubSummary fails outside GitHub Actions, so that --github-summary
+// is rejected before any work is done.
+func checkGitHubSummary() error {
+	if os.Getenv("GITHUB_STEP_SUMMARY") == "" {
+		return fmt.Errorf("GITHUB_STEP_SUMMARY is not set; --github-summary only works in a GitHub Actions step")
+	}
+	return nil
+}
+
+func snapshotSummaryMarkdown(target string, result *usecase.SnapshotResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "### vault snapshot %s\n\n", result.ID)
+	fmt.Fprintf(&sb, "Wrote %d key(s) and %d version(s) to `%s`, %d new object(s).\n\n",
+		result.Entries, result.Versions, target, result.Uploaded)
+
+	switch {
+	case len(result.Changed) == 0:
+		fmt.Fprintf(&sb, "No changes since snapshot %s.\n\n", result.Previous)
+		return sb.String()
+	case result.Previous == "":
+		sb.WriteString("First snapshot in this directory; every key is new.\n\n")
+	default:
+		fmt.Fprintf(&sb, "%d key(s) changed since snapshot %s.\n\n", len(result.Changed), result.Previous)
+	}
+
+	writeChangesTable(&sb, "New versions", result.Changed)
+	return sb.String()
+}
+
+// exportSummaryMarkdown lists every key in an export bundle; a bundle has no
+// earlier snapshot, so all of its versions count as changed.
+func exportSummaryMarkdown(target string, result *usecase.SnapshotResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "### vault export %s\n\n", result.ID)
+	fmt.Fprintf(&sb, "Exported %d key(s) and %d version(s) to `%s`.\n\n", result.Entries, result.Versions, target)
+	writeChangesTable(&sb, "Versions", result.Changed)
+	return sb.String()
+}
+
+func writeChangesTable(sb *strings.Builder, versionsHeading string, changes []usecase.SnapshotChange) {
+	fmt.Fprintf(sb, "| Scope | Key | %s |\n|---|---|---|\n", versionsHeading)
+	for i, change := range changes {
+		if i == maxSummaryRows {
+			fmt.Fprintf(sb, "\n…and %d more.\n", len(changes)-maxSummaryRows)
+			break
+		}
+		versions := make([]string, len(change.Versions))
+		for j, v := range change.Versions {
+			versions[j] = fmt.Sprintf("v%d", v)
+		}
+		fmt.Fprintf(sb, "| %s | %s | %s |\n",
+			markdownCell(scope.FormatScope(change.Scope)), markdownCell(change.Key), strings.Join(versions, ", "))
+	}
+	sb.WriteString("\n")
+}
+
+// snapshotOutputs renders the step outputs of a snapshot: snapshot-id,
+// keys, versions, new-objects, changed, and changed-keys, one "scope key"
+// per line.
+func snapshotOutputs(result *usecase.SnapshotResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "snapshot-id=%s\n", result.ID)
+	fmt.Fprintf(&sb, "keys=%d\n", result.Entries)
+	fmt.Fprintf(&sb, "versions=%d\n", result.Versions)
+	fmt.Fprintf(&sb, "new-objects=%d\n", result.Uploaded)
+	fmt.Fprintf(&sb, "changed=%d\n", len(result.Changed))
+
+	writeKeysOutput(&sb, "changed-keys", result.Changed)
+	return sb.String()
+}
+
+// exportOutputs renders the step outputs of an export: bundle, snapshot-id,
+// keys, versions, and exported-keys, one "scope key" per line.
+func exportOutputs(target string, result *usecase.SnapshotResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "bundle=%s\n", target)
+	fmt.Fprintf(&sb, "snapshot-id=%s\n", result.ID)
+	fmt.Fprintf(&sb, "keys=%d\n", result.Entries)
+	fmt.Fprintf(&sb, "versions=%d\n", result.Versions)
+	writeKeysOutput(&sb, "exported-keys", result.Changed)
+	return sb.String()
+}
+
+// writeKeysOutput writes a multi-line step output listing the keys of
+// changes.
+func writeKeysOutput(sb *strings.Builder, name string, changes []usecase.SnapshotChange) {
+	delimiter := "VAULT_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_EOF"
+	fmt.Fprintf(sb, "%s<<%s\n", name, delimiter)
+	for _, change := range changes {
+		fmt.Fprintf(sb, "%s %s\n", scope.FormatScope(change.Scope), change.Key)
+	}
+	fmt.Fprintf(sb, "%s\n", delimiter)
+}
+
+// markdownCell escapes text for a Markdown table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+func appendToFile(path, text string) error {
+	//nolint:gosec // G302,G304: the path comes from the GitHub Actions runner
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(text); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+// shortCommitLength is how many characters of a commit SHA the table shows.
+const shortCommitLength = 7
+
+func newHistoryCmd() *cobra.Command {
+	var (
+		format     string
+		tmplText   string
+		actor      string
+		deviceID   string
+		scopeType  string
+		repoPath   string
+		branchName string
+		worktreeID string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "history <key>",
+		Short: "Show every version of an entry",
+		Long: "Show every version of an entry, newest first, with its write time, size, description, " +
+			"and the environment it was written from when that was captured (see --capture-env on set).",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key := args[0]
+
+			if format != "table" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: table, json)", format)
+			}
+			var tmpl *template.Template
+			if tmplText != "" {
+				var err error
+				if tmpl, err = parseOutputTemplate(cmd, tmplText); err != nil {
+					return err
+				}
+			}
+
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			uc := usecase.NewEntry(dbCtx)
+			history, err := uc.History(context.Background(), sc, key, &usecase.HistoryOptions{
+				Actor:    actor,
+				DeviceID: deviceID,
+			})
+			if err != nil {
+				return err
+			}
+
+			closePager, err := startPager(cmd)
+			if err != nil {
+				return err
+			}
+			defer closePager()
+
+			if tmpl != nil {
+				out := newTemplateWriter(cmd.OutOrStdout(), tmpl)
+				for _, record := range history {
+					if err := out.Write(newHistoryOutputEntry(record)); err != nil {
+						return err
+					}
+				}
+				return out.Flush()
+			}
+
+			if format == "json" {
+				output := make([]historyOutputEntry, 0, len(history))
+				for _, record := range history {
+					output = append(output, newHistoryOutputEntry(record))
+				}
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(output)
+			}
+
+			outputHistoryTable(cmd, history)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+	cmd.Flags().StringVar(&tmplText, "template", "", `Print each version with a Go template over the JSON output fields (e.g. '{{.Version}}\t{{.Actor}}')`)
+	cmd.Flags().StringVar(&actor, "actor", "", "Only show versions written by this actor")
+	cmd.Flags().StringVar(&deviceID, "device", "", "Only show versions written from this device (see vault devices)")
+	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
+	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
+	cmd.Flags().StringVar(&worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+
+	return cmd
+}
+
+type historyOutputEntry struct {
+	Version     int64   `json:"version"`
+	CreatedAt   string  `json:"createdAt"`
+	Size        int64   `json:"size"`
+	Hash        string  `json:"hash"`
+	Description *string `json:"description,omitempty"`
+	Language    string  `json:"language,omitempty"`
+	Summary     string  `json:"summary,omitempty"`
+	Actor       string  `json:"actor,omitempty"`
+	Tool        string  `json:"tool,omitempty"`
+	Hostname    string  `json:"hostname,omitempty"`
+	DeviceID    string  `json:"deviceId,omitempty"`
+	GitBranch   string  `json:"gitBranch,omitempty"`
+	GitCommit   string  `json:"gitCommit,omitempty"`
+	GitDirty    *bool   `json:"gitDirty,omitempty"`
+	Status      string  `json:"status"`
+	ApprovedBy  string  `json:"approvedBy,omitempty"`
+	ApprovedAt  *string `json:"approvedAt,omitempty"`
+}
+
+func newHistoryOutputEntry(record database.VersionHistoryRecord) historyOutputEntry {
+	entry := historyOutputEntry{
+		Version:     record.Version,
+		CreatedAt:   record.CreatedAt.Format(time.RFC3339),
+		Size:        record.Size,
+		Hash:        record.Hash,
+		Description: record.Description,
+		Language:    record.Language,
+		Summary:     record.Summary,
+	}
+	if p := record.Provenance; p != nil {
+		entry.Actor = p.Actor
+		entry.Tool = p.Tool
+		entry.Hostname = p.Hostname
+		entry.DeviceID = p.DeviceID
+		entry.GitBranch = p.GitBranch
+		entry.GitCommit = p.GitCommit
+		entry.GitDirty = p.GitDirty
+	}
+	entry.Status = versionStatus(record)
+	if a := record.Approval; a != nil {
+		approvedAt := a.ApprovedAt.Format(time.RFC3339)
+		entry.ApprovedBy = a.ApprovedBy
+		entry.ApprovedAt = &approvedAt
+	}
+	return entry
+}
+
+func outputHistoryTable(cmd *cobra.Command, history []database.VersionHistoryRecord) {
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(table.Row{"Version", "Status", "Written", "Size", "Description", "Tool", "Host", "Git"})
+
+	for _, record := range history {
+		description := ""
+		if record.Description != nil {
+			description = *record.Description
+		}
+
+		var tool, host, gitState string
+		if p := record.Provenance; p != nil {
+			tool = p.Tool
+			host = p.Hostname
+			gitState = formatGitState(p)
+		}
+
+		t.AppendRow(table.Row{
+			record.Version,
+			versionStatus(record),
+			display.timestamp(record.CreatedAt),
+			record.Size,
+			description,
+			tool,
+			host,
+			gitState,
+		})
+	}
+
+	t.Render()
+}
+
+// versionStatus names the review state of a version: approved once
+// someone ran vault approve on it, a draft until then.
+func versionStatus(record database.VersionHistoryRecord) string {
+	if record.Approval != nil {
+		return "approved"
+	}
+	return "draft"
+}
+
+// formatGitState renders branch@commit, marking uncommitted changes.
+func formatGitState(p *database.VersionProvenance) string {
+	if p.GitBranch == "" && p.GitCommit == "" {
+		return ""
+	}
+
+	commit := p.GitCommit
+	if len(commit) > shortCommitLength {
+		commit = commit[:shortCommitLength]
+	}
+
+	state := p.GitBranch
+	if commit != "" {
+		state += "@" + commit
+	}
+	if p.GitDirty != nil && *p.GitDirty {
+		state += " (dirty)"
+	}
+	return state
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/snapshot"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newImportCmd() *cobra.Command {
+	var (
+		ndjson     bool
+		batchSize  int
+		captureEnv bool
+		scopeType  string
+		repoPath   string
+		branchName string
+		worktreeID string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import [--ndjson] <file>",
+		Short: "Import an export bundle or a stream of entries",
+		Long: "Restore a bundle written by `vault export`, adding the versions the vault does not have yet to the " +
+			"scopes they were exported from. Bundles written with export --encrypt ask for their passphrase, or " +
+			"take it from VAULT_PASSPHRASE.\n\n" +
+			"With --ndjson, store each line of an NDJSON stream as the next version of its key. A record is an object with " +
+			`"key", "content", and optionally "description" and "scope" (in the form used by snapshot manifests); ` +
+			"records without a scope go to the scope selected by the scope flags. Use - to read from stdin.\n\n" +
+			"Records are committed in batches of --batch-size, one transaction each. Input is read only as fast as " +
+			"batches are committed. If a record is malformed, the import stops; batches committed before it are kept.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !ndjson {
+				for _, name := range []string{"batch-size", "capture-env", "scope", "repo", "branch", "worktree"} {
+					if cmd.Flags().Changed(name) {
+						return fmt.Errorf("--%s only applies to --ndjson; bundles restore into the scopes they were exported from", name)
+					}
+				}
+				return importBundle(cmd, args[0])
+			}
+			if batchSize < 1 {
+				return fmt.Errorf("--batch-size must be at least 1")
+			}
+
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			capture, err := resolveCaptureEnv(cmd, captureEnv)
+			if err != nil {
+				return err
+			}
+
+			var in io.Reader = cmd.InOrStdin()
+			if args[0] != "-" {
+				file, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer func() {
+					_ = file.Close()
+				}()
+				in = file
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			opts := &usecase.StreamImportOptions{
+				Scope:      sc,
+				BatchSize:  batchSize,
+				Provenance: usecase.CaptureProvenance(cmd.Context(), usecase.ToolCLI, "", "", capture),
+				Progress: func(imported int) {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Committed %d version(s)\n", imported)
+				},
+			}
+
+			uc := usecase.NewEntry(dbCtx)
+			result, err := uc.ImportStream(context.Background(), in, opts)
+			if err != nil {
+				if result != nil && result.Imported > 0 {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Imported %d version(s) before the error\n", result.Imported)
+				}
+				return err
+			}
+
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "Imported %d version(s) in %d batch(es)\n", result.Imported, result.Batches); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&ndjson, "ndjson", false, "Read newline-delimited JSON records")
+	cmd.Flags().IntVar(&batchSize, "batch-size", usecase.DefaultImportBatchSize, "Number of records committed per transaction")
+	cmd.Flags().BoolVar(&captureEnv, "capture-env", false, "Record hostname and git branch/commit/dirty state with each version (default from config)")
+	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
+	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
+	cmd.Flags().StringVar(&worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+
+	return cmd
+}
+
+// importBundle restores a bundle written by vault export, decrypting it
+// first when it was sealed with a passphrase.
+func importBundle(cmd *cobra.Command, path string) error {
+	var in io.Reader = cmd.InOrStdin()
+	if path != "-" {
+		file, err := os.Open(path) //nolint:gosec // G304: input path is chosen by the user
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		in = file
+	}
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	if snapshot.IsPassphraseSealed(data) {
+		passphrase, err := readPassphrase(cmd.ErrOrStderr(), false)
+		if err != nil {
+			return err
+		}
+		if data, err = snapshot.OpenWithPassphrase(passphrase, data); err != nil {
+			return fmt.Errorf("failed to decrypt bundle: %w", err)
+		}
+	}
+	if !snapshot.IsBundle(data) {
+		return fmt.Errorf("%s is not a vault export bundle; pass --ndjson to import NDJSON records", path)
+	}
+	bundle, err := snapshot.ReadBundle(data)
+	if err != nil {
+		return err
+	}
+
+	dbCtx, err := database.CreateDatabase("")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = database.CloseDatabase(dbCtx)
+	}()
+
+	uc := usecase.NewEntry(dbCtx)
+	result, id, err := uc.RestoreFrom(cmd.Context(), bundle, usecase.RestoreFromOptions{})
+	if err != nil {
+		return err
+	}
+	if err := printIntegrity(cmd, id, result.Integrity); err != nil {
+		return err
+	}
+	if err := printRestoreSkips(cmd, result); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "Imported %d version(s) (%d already present, %d conflicting)\n",
+		result.Imported, result.Present, len(result.Conflicts))
+	return err
+}
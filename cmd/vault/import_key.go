@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/snapshot"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newImportKeyCmd() *cobra.Command {
+	var (
+		keyFlag    string
+		scopeType  string
+		repoPath   string
+		branchName string
+		worktreeID string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import-key <file>",
+		Short: "Import a key's history exported with export-key",
+		Long: "Recreate every version from a `vault export-key` document, keeping version numbers, " +
+			"descriptions, and write times. Use - to read from stdin. The target key must not exist.\n\n" +
+			"Documents written with export-key --encrypt ask for their passphrase, or take it from VAULT_PASSPHRASE.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			var in io.Reader = cmd.InOrStdin()
+			if args[0] != "-" {
+				file, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer func() {
+					_ = file.Close()
+				}()
+				in = file
+			}
+
+			data, err := io.ReadAll(in)
+			if err != nil {
+				return err
+			}
+			if snapshot.IsPassphraseSealed(data) {
+				passphrase, err := readPassphrase(cmd.ErrOrStderr(), false)
+				if err != nil {
+					return err
+				}
+				if data, err = snapshot.OpenWithPassphrase(passphrase, data); err != nil {
+					return fmt.Errorf("failed to decrypt key export: %w", err)
+				}
+			}
+
+			var export usecase.KeyExport
+			if err := json.Unmarshal(data, &export); err != nil {
+				return fmt.Errorf("failed to parse key export: %w", err)
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			ctx := cmd.Context()
+			uc := usecase.NewEntry(dbCtx)
+			key, err := uc.ImportKey(ctx, sc, &export, &usecase.ImportKeyOptions{Key: keyFlag})
+			if err != nil {
+				return err
+			}
+
+			if export.IsNewer() {
+				if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "note: key export format version %d is newer than this vault's (%d); fields it does not know were ignored\n",
+					export.FormatVersion, usecase.KeyExportFormatVersion); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "Imported %d versions of %s\n", len(export.Versions), key); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&keyFlag, "key", "", "Import under this key instead of the exported one")
+	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
+	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
+	cmd.Flags().StringVar(&worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+
+	return cmd
+}
@@ -1,9 +1,9 @@
 package main
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
+	"text/template"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -17,6 +17,7 @@ func newInfoCmd() *cobra.Command {
 	var (
 		versionFlag int
 		format      string
+		tmplText    string
 		scopeType   string
 		repoPath    string
 		branchName  string
@@ -30,7 +31,7 @@ func newInfoCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			key := args[0]
 
-			sc, err := scope.ResolveScope(scope.ScopeOptions{
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
 				Type:     scopeType,
 				Repo:     repoPath,
 				Branch:   branchName,
@@ -40,6 +41,13 @@ func newInfoCmd() *cobra.Command {
 				return err
 			}
 
+			var tmpl *template.Template
+			if tmplText != "" {
+				if tmpl, err = parseOutputTemplate(cmd, tmplText); err != nil {
+					return err
+				}
+			}
+
 			var opts *usecase.GetOptions
 			if cmd.Flags().Changed("version") {
 				version := versionFlag
@@ -56,9 +64,9 @@ func newInfoCmd() *cobra.Command {
 				_ = database.CloseDatabase(dbCtx)
 			}()
 
-			ctx := context.Background()
+			ctx := cmd.Context()
 			uc := usecase.NewEntry(dbCtx)
-			result, err := uc.Get(ctx, sc, key, opts)
+			result, err := uc.Info(ctx, sc, key, opts)
 			if err != nil {
 				return err
 			}
@@ -66,6 +74,14 @@ func newInfoCmd() *cobra.Command {
 				return fmt.Errorf("key not found: %s", key)
 			}
 
+			if tmpl != nil {
+				out := newTemplateWriter(cmd.OutOrStdout(), tmpl)
+				if err := out.Write(newInfoOutputEntry(result)); err != nil {
+					return err
+				}
+				return out.Flush()
+			}
+
 			switch format {
 			case "json":
 				return outputInfoJSON(cmd, result)
@@ -79,6 +95,7 @@ func newInfoCmd() *cobra.Command {
 
 	cmd.Flags().IntVarP(&versionFlag, "version", "v", 0, "Specific version to retrieve")
 	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+	cmd.Flags().StringVar(&tmplText, "template", "", `Print the entry with a Go template over the JSON output fields (e.g. '{{.Key}}\t{{.Hash}}')`)
 	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
 	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
 	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
@@ -96,12 +113,21 @@ type infoOutputEntry struct {
 	FilePath    string  `json:"filePath"`
 	Hash        string  `json:"hash"`
 	Description *string `json:"description,omitempty"`
+	Language    string  `json:"language,omitempty"`
+	Summary     string  `json:"summary,omitempty"`
 	CreatedAt   string  `json:"createdAt"`
 	IsArchived  bool    `json:"isArchived"`
+
+	VersionCount   int64   `json:"versionCount"`
+	TotalSize      int64   `json:"totalSize"`
+	FirstWrittenAt *string `json:"firstWrittenAt,omitempty"`
+	LastWrittenAt  *string `json:"lastWrittenAt,omitempty"`
+	LastReadAt     *string `json:"lastReadAt,omitempty"`
+	ReadCount      int64   `json:"readCount"`
 }
 
-func outputInfoJSON(cmd *cobra.Command, result *usecase.GetResult) error {
-	output := infoOutputEntry{
+func newInfoOutputEntry(result *usecase.InfoResult) infoOutputEntry {
+	return infoOutputEntry{
 		ID:          result.Record.EntryID,
 		ScopeID:     result.Record.ScopeID,
 		Scope:       scope.FormatScope(result.Scope),
@@ -110,16 +136,27 @@ func outputInfoJSON(cmd *cobra.Command, result *usecase.GetResult) error {
 		FilePath:    result.Record.FilePath,
 		Hash:        result.Record.Hash,
 		Description: result.Record.Description,
+		Language:    result.Record.Language,
+		Summary:     result.Record.Summary,
 		CreatedAt:   result.Record.CreatedAt.Format(time.RFC3339),
 		IsArchived:  result.Record.IsArchived,
+
+		VersionCount:   result.Summary.VersionCount,
+		TotalSize:      result.Summary.TotalSize,
+		FirstWrittenAt: formatOptionalTime(result.Summary.FirstWrittenAt),
+		LastWrittenAt:  formatOptionalTime(result.Summary.LastWrittenAt),
+		LastReadAt:     formatOptionalTime(result.Record.LastReadAt),
+		ReadCount:      result.Record.ReadCount,
 	}
+}
 
+func outputInfoJSON(cmd *cobra.Command, result *usecase.InfoResult) error {
 	encoder := json.NewEncoder(cmd.OutOrStdout())
 	encoder.SetIndent("", "  ")
-	return encoder.Encode(output)
+	return encoder.Encode(newInfoOutputEntry(result))
 }
 
-func outputInfoTable(cmd *cobra.Command, result *usecase.GetResult) error {
+func outputInfoTable(cmd *cobra.Command, result *usecase.InfoResult) error {
 	// Helper function to handle output errors
 	out := cmd.OutOrStdout()
 	fprintf := func(format string, args ...interface{}) error {
@@ -130,44 +167,78 @@ func outputInfoTable(cmd *cobra.Command, result *usecase.GetResult) error {
 	}
 
 	// Key-value pair format for single entry
-	if err := fprintf("ID:          %d\n", result.Record.EntryID); err != nil {
+	if err := fprintf("ID:            %d\n", result.Record.EntryID); err != nil {
 		return err
 	}
-	if err := fprintf("Scope ID:    %d\n", result.Record.ScopeID); err != nil {
+	if err := fprintf("Scope ID:      %d\n", result.Record.ScopeID); err != nil {
 		return err
 	}
-	if err := fprintf("Scope:       %s\n", scope.FormatScope(result.Scope)); err != nil {
+	if err := fprintf("Scope:         %s\n", scope.FormatScope(result.Scope)); err != nil {
 		return err
 	}
-	if err := fprintf("Key:         %s\n", result.Record.Key); err != nil {
+	if err := fprintf("Key:           %s\n", result.Record.Key); err != nil {
 		return err
 	}
-	if err := fprintf("Version:     %d\n", result.Record.Version); err != nil {
+	if err := fprintf("Version:       %d\n", result.Record.Version); err != nil {
 		return err
 	}
-	if err := fprintf("File Path:   %s\n", result.Record.FilePath); err != nil {
+	if err := fprintf("File Path:     %s\n", result.Record.FilePath); err != nil {
 		return err
 	}
-	if err := fprintf("Hash:        %s\n", result.Record.Hash); err != nil {
+	if err := fprintf("Hash:          %s\n", result.Record.Hash); err != nil {
 		return err
 	}
 
 	if result.Record.Description != nil {
-		if err := fprintf("Description: %s\n", *result.Record.Description); err != nil {
+		if err := fprintf("Description:   %s\n", *result.Record.Description); err != nil {
 			return err
 		}
 	} else {
-		if err := fprintf("Description: \n"); err != nil {
+		if err := fprintf("Description:   \n"); err != nil {
 			return err
 		}
 	}
 
-	if err := fprintf("Created At:  %s\n", result.Record.CreatedAt.Format("2006-01-02 15:04:05")); err != nil {
+	if err := fprintf("Language:      %s\n", result.Record.Language); err != nil {
+		return err
+	}
+	if err := fprintf("Summary:       %s\n", result.Record.Summary); err != nil {
+		return err
+	}
+	if err := fprintf("Created At:    %s\n", display.timestamp(result.Record.CreatedAt)); err != nil {
+		return err
+	}
+	if err := fprintf("Archived:      %t\n", result.Record.IsArchived); err != nil {
+		return err
+	}
+
+	// Summary across all versions of the entry
+	if err := fprintf("Versions:      %d\n", result.Summary.VersionCount); err != nil {
+		return err
+	}
+	if err := fprintf("Total Size:    %d bytes\n", result.Summary.TotalSize); err != nil {
+		return err
+	}
+	if err := fprintf("First Written: %s\n", display.timestamp(result.Summary.FirstWrittenAt)); err != nil {
 		return err
 	}
-	if err := fprintf("Archived:    %t\n", result.Record.IsArchived); err != nil {
+	if err := fprintf("Last Written:  %s\n", display.timestamp(result.Summary.LastWrittenAt)); err != nil {
+		return err
+	}
+	if err := fprintf("Last Read:     %s\n", display.timestamp(result.Record.LastReadAt)); err != nil {
+		return err
+	}
+	if err := fprintf("Reads:         %d\n", result.Record.ReadCount); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func formatOptionalTime(t time.Time) *string {
+	if t.IsZero() {
+		return nil
+	}
+	formatted := t.Format(time.RFC3339)
+	return &formatted
+}
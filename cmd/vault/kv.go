@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newKVCmd() *cobra.Command {
+	flags := &scopeFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "kv",
+		Short: "Store and read small values such as checkpoints",
+		Long: "Keep tiny values, such as the last processed ID of an agent, without editors or file paths. " +
+			"Values are entries under kv/ (vault kv set agent.last-id 42 writes kv/agent.last-id), so they " +
+			"have history like any entry, but setting the value that is already stored adds no version.",
+	}
+
+	flags.bind(cmd.PersistentFlags())
+
+	cmd.AddCommand(newKVSetCmd(flags))
+	cmd.AddCommand(newKVGetCmd(flags))
+	cmd.AddCommand(newKVListCmd(flags))
+	cmd.AddCommand(newKVDeleteCmd(flags))
+	return cmd
+}
+
+func newKVSetCmd(flags *scopeFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <name> [value]",
+		Short: "Store a value",
+		Long:  "Store a value. The value is the argument, or read from stdin without its trailing newline.",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var value string
+			if len(args) == 2 {
+				value = args[1]
+			} else {
+				content, err := readContent(cmd, "")
+				if err != nil {
+					return err
+				}
+				value = strings.TrimSuffix(strings.TrimSuffix(content, "\n"), "\r")
+			}
+
+			capture, err := resolveCaptureEnv(cmd, false)
+			if err != nil {
+				return err
+			}
+
+			return flags.run(cmd, func(ctx context.Context, uc *usecase.Entry, sc scope.Scope) error {
+				opts := &usecase.SetOptions{
+					Provenance: usecase.CaptureProvenance(ctx, usecase.ToolCLI, "", "", capture),
+				}
+				_, err := uc.KVSet(ctx, sc, args[0], value, opts)
+				return err
+			})
+		},
+	}
+}
+
+func newKVGetCmd(flags *scopeFlags) *cobra.Command {
+	var fallback string
+
+	cmd := &cobra.Command{
+		Use:   "get <name>",
+		Short: "Print a value",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			skipVerify, err := resolveSkipVerify(cmd, false)
+			if err != nil {
+				return err
+			}
+			trackRead, err := resolveTrackRead()
+			if err != nil {
+				return err
+			}
+
+			return flags.run(cmd, func(ctx context.Context, uc *usecase.Entry, sc scope.Scope) error {
+				pair, err := uc.KVGet(ctx, sc, args[0], &usecase.GetOptions{SkipVerify: skipVerify, TrackRead: trackRead})
+				value := ""
+				switch {
+				case err == nil:
+					value = pair.Value
+				case errors.Is(err, services.ErrNotFound) && cmd.Flags().Changed("default"):
+					value = fallback
+				case errors.Is(err, services.ErrNotFound):
+					return fmt.Errorf("kv value not found: %s", args[0])
+				default:
+					return err
+				}
+				_, err = fmt.Fprintln(cmd.OutOrStdout(), value)
+				return err
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&fallback, "default", "", "Print this instead of failing when the value is not set")
+	return cmd
+}
+
+func newKVListCmd(flags *scopeFlags) *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "list [prefix]",
+		Short: "List values, optionally only those whose names start with prefix",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "text" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: text, json)", format)
+			}
+			prefix := ""
+			if len(args) == 1 {
+				prefix = args[0]
+			}
+
+			return flags.run(cmd, func(ctx context.Context, uc *usecase.Entry, sc scope.Scope) error {
+				pairs, err := uc.KVList(ctx, sc, prefix)
+				if err != nil {
+					return err
+				}
+				return outputKVPairs(cmd.OutOrStdout(), pairs, format)
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "text", "Output format: text (name=value lines) or json")
+	return cmd
+}
+
+func newKVDeleteCmd(flags *scopeFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a value and its history",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return flags.run(cmd, func(ctx context.Context, uc *usecase.Entry, sc scope.Scope) error {
+				deleted, err := uc.KVDelete(ctx, sc, args[0])
+				if err != nil {
+					return err
+				}
+				if !deleted {
+					return fmt.Errorf("kv value not found: %s", args[0])
+				}
+				return nil
+			})
+		},
+	}
+}
+
+func outputKVPairs(w io.Writer, pairs []usecase.KVPair, format string) error {
+	if format == "json" {
+		values := make(map[string]string, len(pairs))
+		for _, p := range pairs {
+			values[p.Name] = p.Value
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(values)
+	}
+	for _, p := range pairs {
+		// Keep one pair per line even for values spanning several.
+		if _, err := fmt.Fprintf(w, "%s=%s\n", p.Name, strings.ReplaceAll(p.Value, "\n", `\n`)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
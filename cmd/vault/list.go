@@ -1,11 +1,13 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/jedib0t/go-pretty/v6/table"
@@ -13,8 +15,10 @@ import (
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 
-	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/entrykey"
+	"github.com/choplin/vault.md/internal/language"
 	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
 	"github.com/choplin/vault.md/internal/usecase"
 )
 
@@ -22,7 +26,18 @@ func newListCmd() *cobra.Command {
 	var (
 		allVersions     bool
 		includeArchived bool
+		includeInternal bool
+		since           string
+		until           string
+		asOf            string
+		descContains    string
+		lang            string
+		sortBy          string
+		reverse         bool
 		format          string
+		tmplText        string
+		columnsSpec     string
+		absolute        bool
 		scopeType       string
 		repoPath        string
 		branchName      string
@@ -34,7 +49,7 @@ func newListCmd() *cobra.Command {
 		Short: "List keys in vault",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			sc, err := scope.ResolveScope(scope.ScopeOptions{
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
 				Type:     scopeType,
 				Repo:     repoPath,
 				Branch:   branchName,
@@ -44,26 +59,84 @@ func newListCmd() *cobra.Command {
 				return err
 			}
 
-			dbCtx, err := database.CreateDatabase("")
+			sortField, err := services.ParseSortField(sortBy)
 			if err != nil {
 				return err
 			}
-			defer func() {
-				_ = database.CloseDatabase(dbCtx)
-			}()
+			if lang != "" {
+				if lang, err = language.Normalize(lang); err != nil {
+					return err
+				}
+			}
+
+			var columns []listColumn
+			if columnsSpec != "" {
+				if format != "table" || tmplText != "" {
+					return fmt.Errorf("--columns only applies to table output")
+				}
+				if columns, err = parseListColumns(columnsSpec); err != nil {
+					return err
+				}
+			}
 
-			ctx := context.Background()
-			uc := usecase.NewEntry(dbCtx)
+			var tmpl *template.Template
+			if tmplText != "" {
+				if tmpl, err = parseOutputTemplate(cmd, tmplText); err != nil {
+					return err
+				}
+			}
+
+			now := time.Now()
+			sinceTime, err := parseTimeFlag(since, now)
+			if err != nil {
+				return fmt.Errorf("--since: %w", err)
+			}
+			untilTime, err := parseTimeFlag(until, now)
+			if err != nil {
+				return fmt.Errorf("--until: %w", err)
+			}
+			asOfTime, err := parseTimeFlag(asOf, now)
+			if err != nil {
+				return fmt.Errorf("--as-of: %w", err)
+			}
+
+			uc, closeEntry, err := openEntry()
+			if err != nil {
+				return err
+			}
+			defer closeEntry()
+
+			ctx := cmd.Context()
 
 			useAllScopes := scopeType == "" && repoPath == "" && branchName == "" && worktreeID == ""
 
-			var opts *usecase.ListOptions
-			if includeArchived || allVersions || useAllScopes {
-				opts = &usecase.ListOptions{
-					IncludeArchived: includeArchived,
-					AllVersions:     allVersions,
-					AllScopes:       useAllScopes,
-				}
+			opts := &usecase.ListOptions{
+				IncludeArchived:     includeArchived,
+				IncludeInternal:     includeInternal,
+				AllVersions:         allVersions,
+				AllScopes:           useAllScopes,
+				Since:               sinceTime,
+				Until:               untilTime,
+				AsOf:                asOfTime,
+				DescriptionContains: descContains,
+				Language:            lang,
+				Reverse:             reverse,
+			}
+			if cmd.Flags().Changed("sort") || reverse {
+				opts.SortBy = sortField
+			}
+
+			closePager, err := startPager(cmd)
+			if err != nil {
+				return err
+			}
+			defer closePager()
+
+			if tmpl != nil {
+				return outputListTemplate(ctx, cmd, uc, sc, opts, tmpl)
+			}
+			if format == "ndjson" {
+				return outputNDJSON(ctx, cmd, uc, sc, opts)
 			}
 
 			result, err := uc.List(ctx, sc, opts)
@@ -75,17 +148,33 @@ func newListCmd() *cobra.Command {
 			case "json":
 				return outputJSON(cmd, result)
 			case "table":
-				outputTable(cmd, result, includeArchived)
+				tf := listTimeFormat{absolute: absolute || ciMode, now: time.Now()}
+				if columns != nil {
+					outputColumnsTable(cmd, result, columns, tf)
+					return nil
+				}
+				outputTable(cmd, result, includeArchived, tf)
 				return nil
 			default:
-				return fmt.Errorf("invalid format: %s (valid values: table, json)", format)
+				return fmt.Errorf("invalid format: %s (valid values: table, json, ndjson)", format)
 			}
 		},
 	}
 
 	cmd.Flags().BoolVar(&allVersions, "all-versions", false, "Show all versions")
 	cmd.Flags().BoolVar(&includeArchived, "include-archived", false, "Include archived entries")
-	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+	cmd.Flags().BoolVar(&includeInternal, "include-internal", false, "Include the vault's own entries, whose keys start with "+entrykey.ReservedPrefix)
+	cmd.Flags().StringVar(&since, "since", "", "Only versions created at or after this time (RFC3339, YYYY-MM-DD, or age like 7d)")
+	cmd.Flags().StringVar(&until, "until", "", "Only versions created at or before this time (RFC3339, YYYY-MM-DD, or age like 7d)")
+	cmd.Flags().StringVar(&asOf, "as-of", "", "List the version of each key that was latest at this time (RFC3339, YYYY-MM-DD, or age like 7d)")
+	cmd.Flags().StringVar(&descContains, "description-contains", "", "Only versions whose description contains this text (case-insensitive)")
+	cmd.Flags().StringVar(&lang, "lang", "", "Only versions with this content language (e.g. markdown, text, go)")
+	cmd.Flags().StringVar(&sortBy, "sort", "key", "Sort by: key, created, updated, version, or size")
+	cmd.Flags().BoolVar(&reverse, "reverse", false, "Reverse the sort order")
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table, json, or ndjson")
+	cmd.Flags().BoolVar(&absolute, "absolute", false, "Show dates and times instead of relative ages (\"2h ago\") in the table")
+	cmd.Flags().StringVar(&columnsSpec, "columns", "", "Comma-separated table columns: "+listColumnNames())
+	cmd.Flags().StringVar(&tmplText, "template", "", `Print each entry with a Go template over the JSON output fields (e.g. '{{.Key}}\t{{.Version}}')`)
 	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
 	cmd.Flags().StringVar(&repoPath, "repo", "", "List from specific repository")
 	cmd.Flags().StringVar(&branchName, "branch", "", "List from specific branch")
@@ -101,26 +190,40 @@ type listOutputEntry struct {
 	Version     int64   `json:"version"`
 	Created     string  `json:"created"`
 	Description *string `json:"description,omitempty"`
+	Language    string  `json:"language,omitempty"`
+	Summary     string  `json:"summary,omitempty"`
+	LastRead    string  `json:"last_read,omitempty"`
+	Reads       int64   `json:"reads,omitempty"`
 	Archived    *bool   `json:"archived,omitempty"`
 }
 
+func newListOutputEntry(entry usecase.ListEntry) listOutputEntry {
+	item := listOutputEntry{
+		Scope:       entry.ScopeShort,
+		ScopeType:   string(entry.ScopeType),
+		Key:         entry.Record.Key,
+		Version:     entry.Record.Version,
+		Created:     entry.Record.CreatedAt.Format(time.RFC3339),
+		Description: entry.Record.Description,
+		Language:    entry.Record.Language,
+		Summary:     entry.Record.Summary,
+		Reads:       entry.Record.ReadCount,
+	}
+	if !entry.Record.LastReadAt.IsZero() {
+		item.LastRead = entry.Record.LastReadAt.Format(time.RFC3339)
+	}
+	if entry.Record.IsArchived {
+		archived := true
+		item.Archived = &archived
+	}
+	return item
+}
+
 func outputJSON(cmd *cobra.Command, result *usecase.ListResult) error {
 	var output []listOutputEntry
 
 	for _, entry := range result.Entries {
-		item := listOutputEntry{
-			Scope:       entry.ScopeShort,
-			ScopeType:   string(entry.ScopeType),
-			Key:         entry.Record.Key,
-			Version:     entry.Record.Version,
-			Created:     entry.Record.CreatedAt.Format(time.RFC3339),
-			Description: entry.Record.Description,
-		}
-		if entry.Record.IsArchived {
-			archived := true
-			item.Archived = &archived
-		}
-		output = append(output, item)
+		output = append(output, newListOutputEntry(entry))
 	}
 
 	encoder := json.NewEncoder(cmd.OutOrStdout())
@@ -128,7 +231,38 @@ func outputJSON(cmd *cobra.Command, result *usecase.ListResult) error {
 	return encoder.Encode(output)
 }
 
+// outputNDJSON writes one JSON object per line as rows are read from the
+// database, so memory stays flat regardless of how many versions are listed.
+func outputNDJSON(ctx context.Context, cmd *cobra.Command, uc *usecase.Entry, sc scope.Scope, opts *usecase.ListOptions) error {
+	out := bufio.NewWriter(cmd.OutOrStdout())
+	encoder := json.NewEncoder(out)
+
+	err := uc.ListEach(ctx, sc, opts, func(entry usecase.ListEntry) error {
+		return encoder.Encode(newListOutputEntry(entry))
+	})
+	if err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// outputListTemplate prints each entry through a --template as it is read
+// from the database.
+func outputListTemplate(ctx context.Context, cmd *cobra.Command, uc *usecase.Entry, sc scope.Scope, opts *usecase.ListOptions, tmpl *template.Template) error {
+	out := newTemplateWriter(cmd.OutOrStdout(), tmpl)
+	err := uc.ListEach(ctx, sc, opts, func(entry usecase.ListEntry) error {
+		return out.Write(newListOutputEntry(entry))
+	})
+	if err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
 func getTerminalWidth() int {
+	if ciMode {
+		return ciTerminalWidth
+	}
 	// Try to get terminal width from stdout
 	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
 		return width
@@ -194,7 +328,7 @@ type columnWidths struct {
 }
 
 // calculateColumnWidths determines optimal column widths based on terminal width and data
-func calculateColumnWidths(termWidth int, entries []usecase.ListEntry, includeArchived bool) columnWidths {
+func calculateColumnWidths(termWidth int, entries []usecase.ListEntry, includeArchived, absolute bool) columnWidths {
 	numColumns := 6
 	if includeArchived {
 		numColumns = 7
@@ -242,8 +376,11 @@ func calculateColumnWidths(termWidth int, entries []usecase.ListEntry, includeAr
 	}
 
 	// Calculate initial description width with full format for version and date
-	versionWidth := 7  // "Version"
-	createdWidth := 19 // "2006-01-02 15:04:05"
+	versionWidth := 7 // "Version"
+	createdWidth := display.timestampWidth()
+	if !absolute {
+		createdWidth = 8 // "11mo ago"
+	}
 	archivedWidth := 8 // "ARCHIVED"
 	if includeArchived && availableWidth < 80 {
 		archivedWidth = 6 // "Arch"
@@ -264,8 +401,10 @@ func calculateColumnWidths(termWidth int, entries []usecase.ListEntry, includeAr
 	if descWidth < 20 {
 		versionWidth = 5 // "Ver"
 		versionHeader = "Ver"
-		createdWidth = 11 // "01-02 15:04"
-		useShortDate = true
+		if absolute {
+			createdWidth = 11 // "01-02 15:04"
+			useShortDate = true
+		}
 
 		// Recalculate description width with abbreviated format
 		priorityWidth = availableWidth - versionWidth - createdWidth - scopeTypeWidth
@@ -301,14 +440,14 @@ func calculateColumnWidths(termWidth int, entries []usecase.ListEntry, includeAr
 	}
 }
 
-func outputTable(cmd *cobra.Command, result *usecase.ListResult, includeArchived bool) {
+func outputTable(cmd *cobra.Command, result *usecase.ListResult, includeArchived bool, tf listTimeFormat) {
 	t := table.NewWriter()
 	t.SetOutputMirror(cmd.OutOrStdout())
 	t.SetStyle(table.StyleLight)
 
 	// Get terminal width and calculate column widths
 	termWidth := getTerminalWidth()
-	widths := calculateColumnWidths(termWidth, result.Entries, includeArchived)
+	widths := calculateColumnWidths(termWidth, result.Entries, includeArchived, tf.absolute)
 
 	// Note: We don't set WidthMax on columns because we're manually
 	// wrapping/truncating the content before adding it to the table.
@@ -331,10 +470,10 @@ func outputTable(cmd *cobra.Command, result *usecase.ListResult, includeArchived
 		var created string
 		if widths.useShortDate {
 			// Short format: MM-DD HH:MM (no seconds)
-			created = entry.Record.CreatedAt.Format("01-02 15:04")
+			created = display.layout(entry.Record.CreatedAt, "01-02 15:04")
 		} else {
-			// Full format: YYYY-MM-DD HH:MM:SS
-			created = entry.Record.CreatedAt.Format("2006-01-02 15:04:05")
+			// Relative age, or the configured time format with --absolute
+			created = tf.format(entry.Record.CreatedAt)
 		}
 
 		description := ""
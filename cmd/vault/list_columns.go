@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+// listColumn is a column that --columns can select for list's table output.
+type listColumn struct {
+	name   string
+	header string
+	value  func(usecase.ListEntry, listTimeFormat) any
+}
+
+// listColumns are the selectable columns, in the order --help lists them.
+var listColumns = []listColumn{
+	{"scope", "Scope", func(e usecase.ListEntry, _ listTimeFormat) any { return e.ScopeShort }},
+	{"scope_type", "Scope Type", func(e usecase.ListEntry, _ listTimeFormat) any { return string(e.ScopeType) }},
+	{"key", "Key", func(e usecase.ListEntry, _ listTimeFormat) any { return e.Record.Key }},
+	{"version", "Version", func(e usecase.ListEntry, _ listTimeFormat) any { return e.Record.Version }},
+	{"created", "Created", func(e usecase.ListEntry, tf listTimeFormat) any { return tf.format(e.Record.CreatedAt) }},
+	{"updated", "Updated", func(e usecase.ListEntry, tf listTimeFormat) any { return tf.format(e.Record.UpdatedAt) }},
+	{"size", "Size", func(e usecase.ListEntry, _ listTimeFormat) any { return e.Record.Size }},
+	{"hash", "Hash", func(e usecase.ListEntry, _ listTimeFormat) any { return e.Record.Hash }},
+	{"description", "Description", func(e usecase.ListEntry, _ listTimeFormat) any {
+		if e.Record.Description == nil {
+			return ""
+		}
+		return *e.Record.Description
+	}},
+	{"language", "Language", func(e usecase.ListEntry, _ listTimeFormat) any { return e.Record.Language }},
+	{"summary", "Summary", func(e usecase.ListEntry, _ listTimeFormat) any { return e.Record.Summary }},
+	{"last_read", "Last Read", func(e usecase.ListEntry, tf listTimeFormat) any {
+		if e.Record.LastReadAt.IsZero() {
+			return ""
+		}
+		return tf.format(e.Record.LastReadAt)
+	}},
+	{"reads", "Reads", func(e usecase.ListEntry, _ listTimeFormat) any { return e.Record.ReadCount }},
+	{"archived", "Archived", func(e usecase.ListEntry, _ listTimeFormat) any { return e.Record.IsArchived }},
+}
+
+func listColumnNames() string {
+	names := make([]string, 0, len(listColumns))
+	for _, c := range listColumns {
+		names = append(names, c.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// parseListColumns resolves a comma-separated --columns value.
+func parseListColumns(spec string) ([]listColumn, error) {
+	var columns []listColumn
+	for name := range strings.SplitSeq(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, c := range listColumns {
+			if c.name == name {
+				columns = append(columns, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid column: %s (valid values: %s)", name, listColumnNames())
+		}
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("--columns needs at least one column (valid values: %s)", listColumnNames())
+	}
+	return columns, nil
+}
+
+// outputColumnsTable prints the chosen columns in full; unlike outputTable,
+// nothing is wrapped or shortened to fit the terminal.
+func outputColumnsTable(cmd *cobra.Command, result *usecase.ListResult, columns []listColumn, tf listTimeFormat) {
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.SetStyle(table.StyleLight)
+
+	header := make(table.Row, 0, len(columns))
+	for _, c := range columns {
+		header = append(header, c.header)
+	}
+	t.AppendHeader(header)
+
+	for _, entry := range result.Entries {
+		row := make(table.Row, 0, len(columns))
+		for _, c := range columns {
+			row = append(row, c.value(entry, tf))
+		}
+		t.AppendRow(row)
+	}
+
+	t.Render()
+}
+
+// listTimeFormat renders timestamps in list's table output: relative to now
+// ("2h ago") by default, or as local date and time with --absolute.
+type listTimeFormat struct {
+	absolute bool
+	now      time.Time
+}
+
+func (f listTimeFormat) format(t time.Time) string {
+	if f.absolute {
+		return display.timestamp(t)
+	}
+	return formatRelativeTime(t, f.now)
+}
+
+// formatRelativeTime renders t as an age such as "5m ago" or "3mo ago".
+// Times in the future are shown as a date, since they only occur with
+// skewed clocks.
+func formatRelativeTime(t, now time.Time) string {
+	age := now.Sub(t)
+	const day = 24 * time.Hour
+	switch {
+	case age < 0:
+		return display.date(t)
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age/time.Minute))
+	case age < day:
+		return fmt.Sprintf("%dh ago", int(age/time.Hour))
+	case age < 30*day:
+		return fmt.Sprintf("%dd ago", int(age/day))
+	case age < 365*day:
+		return fmt.Sprintf("%dmo ago", int(age/(30*day)))
+	default:
+		return fmt.Sprintf("%dy ago", int(age/(365*day)))
+	}
+}
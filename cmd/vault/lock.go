@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newLockCmd() *cobra.Command {
+	var (
+		ttl        time.Duration
+		force      bool
+		scopeType  string
+		repoPath   string
+		branchName string
+		worktreeID string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "lock <key>",
+		Short: "Claim a key so that others cannot write it for a while",
+		Long: "Take an advisory lock on a key so two agents working on the same document take turns instead " +
+			"of interleaving versions. While the lock is held, set, edit, patch, and the MCP vault_set tool " +
+			"fail for everyone but the owner unless they pass --force. The owner is VAULT_ACTOR, or the OS " +
+			"user; give concurrent agents different VAULT_ACTOR values. Locking again renews the lock, and it " +
+			"expires after --ttl. Release it with vault unlock.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key := args[0]
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			lock, err := usecase.NewEntry(dbCtx).Lock(cmd.Context(), sc, key, usecase.ResolveActor(""), ttl, force)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Locked %s for %s until %s\n", key, lock.Owner, display.timestamp(lock.ExpiresAt))
+			return err
+		},
+	}
+
+	cmd.Flags().DurationVar(&ttl, "ttl", 10*time.Minute, "How long the lock is held unless renewed or released")
+	cmd.Flags().BoolVar(&force, "force", false, "Take over a lock held by someone else")
+	addScopeFlags(cmd, &scopeType, &repoPath, &branchName, &worktreeID)
+
+	return cmd
+}
+
+func newUnlockCmd() *cobra.Command {
+	var (
+		force      bool
+		scopeType  string
+		repoPath   string
+		branchName string
+		worktreeID string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "unlock <key>",
+		Short: "Release a lock taken with vault lock",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key := args[0]
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			released, err := usecase.NewEntry(dbCtx).Unlock(cmd.Context(), sc, key, usecase.ResolveActor(""), force)
+			if err != nil {
+				return err
+			}
+			if !released {
+				_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s is not locked\n", key)
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Unlocked %s\n", key)
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Release a lock held by someone else")
+	addScopeFlags(cmd, &scopeType, &repoPath, &branchName, &worktreeID)
+
+	return cmd
+}
@@ -1,14 +1,37 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"time"
+
+	"github.com/choplin/vault.md/internal/usecase"
 )
 
-// version is set via ldflags during build
-var version = "dev"
+// notifyFlushTimeout bounds how long the process waits on exit for queued
+// change notifications to be sent.
+const notifyFlushTimeout = 10 * time.Second
+
+// version, commit, and date are set via ldflags during build
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	args, err := expandAliases(os.Args[1:])
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	rootCmd.SetArgs(args)
+
+	err = rootCmd.Execute()
+	if ferr := usecase.FlushNotifications(notifyFlushTimeout); ferr != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "warning:", ferr)
+	}
+	if err != nil {
 		os.Exit(1)
 	}
 }
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"context"
 	"log"
 
 	"github.com/spf13/cobra"
@@ -13,14 +12,20 @@ func newMCPCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "mcp",
 		Short: "Start MCP server",
-		Long:  "Start the Model Context Protocol server for vault.md",
-		RunE: func(_ *cobra.Command, _ []string) error {
-			server, err := mcp.NewServer()
+		Long: "Start the Model Context Protocol server for vault.md. Tool calls stop when the client " +
+			"cancels them or disconnects, and --timeout limits how long each call may run.",
+		Annotations: map[string]string{perCallTimeout: "true"},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			timeout, err := timeoutFlag(cmd)
+			if err != nil {
+				return err
+			}
+			server, err := mcp.NewServer(mcp.ServerOptions{ToolTimeout: timeout})
 			if err != nil {
 				log.Fatalf("Failed to create MCP server: %v", err)
 			}
 
-			ctx := context.Background()
+			ctx := cmd.Context()
 			return server.Run(ctx)
 		},
 	}
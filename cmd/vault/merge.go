@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newMergeCmd() *cobra.Command {
+	var (
+		ours        int64
+		theirs      int64
+		base        int64
+		description string
+		scopeType   string
+		repoPath    string
+		branchName  string
+		worktreeID  string
+		captureEnv  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "merge <key>",
+		Short: "Three-way merge two versions of an entry",
+		Long: "Merge the changes two versions made to their common ancestor (--base, by default the version " +
+			"before the older of the two). A clean merge is saved as a new version. Otherwise the merged " +
+			"content with conflict markers is printed and nothing is saved; resolve it and save it with vault set.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key := args[0]
+
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			capture, err := resolveCaptureEnv(cmd, captureEnv)
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			opts := usecase.MergeOptions{
+				Ours:   ours,
+				Theirs: theirs,
+				Set: &usecase.SetOptions{
+					Provenance: usecase.CaptureProvenance(cmd.Context(), usecase.ToolCLI, "", "", capture),
+				},
+			}
+			if cmd.Flags().Changed("base") {
+				opts.Base = &base
+			}
+			if strings.TrimSpace(description) == "" {
+				description = fmt.Sprintf("Merge of versions %d and %d", ours, theirs)
+			}
+			opts.Set.Description = &description
+
+			uc := usecase.NewEntry(dbCtx)
+			result, err := uc.Merge(context.Background(), sc, key, opts)
+			if err != nil {
+				return err
+			}
+
+			if result.Conflicts > 0 {
+				if _, err := io.WriteString(cmd.OutOrStdout(), result.Content); err != nil {
+					return err
+				}
+				// The merged content is the output; skip the usage text.
+				cmd.SilenceUsage = true
+				return fmt.Errorf("merge of %s versions %d and %d has %d conflict(s); nothing was saved", key, ours, theirs, result.Conflicts)
+			}
+
+			if _, err := fmt.Fprintln(cmd.OutOrStdout(), result.Stored.Path); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64Var(&ours, "ours", 0, "First version to merge")
+	cmd.Flags().Int64Var(&theirs, "theirs", 0, "Second version to merge")
+	cmd.Flags().Int64Var(&base, "base", 0, "Common ancestor version (0 for empty content)")
+	cmd.Flags().StringVarP(&description, "description", "d", "", "Description of the merged version")
+	cmd.Flags().BoolVar(&captureEnv, "capture-env", false, "Record hostname and git branch/commit/dirty state with the version (default from config)")
+	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
+	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
+	cmd.Flags().StringVar(&worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+	_ = cmd.MarkFlagRequired("ours")
+	_ = cmd.MarkFlagRequired("theirs")
+
+	return cmd
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/notify"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newNotifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "notify",
+		Short: "Check the chat notifiers",
+		Long: "The notifiers section of the config file posts a message to Slack, Discord, or any JSON " +
+			"webhook when matching entries are set, deleted, archived, or restored. Posting is best effort " +
+			"and never fails the change itself; use notify test to check the webhooks.",
+	}
+	cmd.AddCommand(newNotifyTestCmd())
+	return cmd
+}
+
+func newNotifyTestCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "test",
+		Short: "Post a test message to every configured notifier",
+		Long:  "Post a test event to every notifier in the config file, ignoring their filters, and report which ones failed.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			settings, err := config.Load()
+			if err != nil {
+				return err
+			}
+			hooks := notify.FromSettings(settings)
+			if len(hooks) == 0 {
+				return fmt.Errorf("no notifiers configured in %s", config.GetConfigPath())
+			}
+
+			ev := notify.Event{
+				Type:        usecase.EventSet,
+				Scope:       "global",
+				ScopeType:   "global",
+				Key:         "notify-test",
+				Version:     1,
+				Description: "test message from vault notify test",
+				Actor:       usecase.ResolveActor(""),
+				Time:        time.Now().UTC(),
+			}
+			failed := 0
+			for _, hook := range hooks {
+				if err := hook.Send(cmd.Context(), ev); err != nil {
+					failed++
+					if _, err := fmt.Fprintf(cmd.OutOrStdout(), "FAIL %s: %v\n", hook.Name(), err); err != nil {
+						return err
+					}
+					continue
+				}
+				if _, err := fmt.Fprintf(cmd.OutOrStdout(), "ok   %s\n", hook.Name()); err != nil {
+					return err
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d notifiers failed", failed, len(hooks))
+			}
+			return nil
+		},
+	}
+}
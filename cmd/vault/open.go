@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/language"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newOpenCmd() *cobra.Command {
+	var (
+		versionFlag int
+		viewer      string
+		watch       bool
+		interval    time.Duration
+		captureEnv  bool
+		scopeType   string
+		repoPath    string
+		branchName  string
+		worktreeID  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "open <key>",
+		Short: "Open entry content in an external application",
+		Long: "Copy the entry to a temporary file and open it with --with, the viewer setting in the config, or " +
+			"the operating system's default handler. The stored object file is never handed out.\n\n" +
+			"Without --watch the copy is read-only and left for the operating system to clean up. With --watch " +
+			"the copy is writable and every saved change is stored as a new version until you press Enter or " +
+			"Ctrl-C, or until a --with/viewer command exits. A change made to the key elsewhere in the meantime " +
+			"stops the watch instead of being overwritten.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := refuseInCI("vault open", "use vault get or vault cat"); err != nil {
+				return err
+			}
+			key := args[0]
+
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			var opts *usecase.GetOptions
+			if cmd.Flags().Changed("version") {
+				if watch {
+					return fmt.Errorf("--watch saves on top of the latest version and cannot be combined with --version")
+				}
+				version := versionFlag
+				opts = &usecase.GetOptions{Version: &version}
+			}
+			if interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+
+			settings, err := config.Load()
+			if err != nil {
+				return err
+			}
+			if viewer == "" {
+				viewer = settings.ViewerCommand()
+			}
+			argv := defaultOpener()
+			if viewer != "" {
+				if argv, err = config.SplitCommandLine(viewer); err != nil {
+					return fmt.Errorf("invalid viewer %q: %w", viewer, err)
+				}
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			ctx := cmd.Context()
+			uc := usecase.NewEntry(dbCtx)
+			result, err := uc.Get(ctx, sc, key, opts)
+			if err != nil {
+				return err
+			}
+			if result == nil {
+				return fmt.Errorf("key not found: %s", key)
+			}
+			//nolint:gosec // G304: path is from database, controlled by application
+			content, err := os.ReadFile(result.Record.FilePath)
+			if err != nil {
+				return err
+			}
+
+			tempDir, err := os.MkdirTemp("", "vault-open-")
+			if err != nil {
+				return err
+			}
+			tempFile := filepath.Join(tempDir, openFileName(key, result.Record.Language))
+			mode := os.FileMode(0o400)
+			if watch {
+				mode = 0o600
+			}
+			if err := os.WriteFile(tempFile, content, mode); err != nil {
+				return err
+			}
+
+			//nolint:gosec // G204: viewer comes from --with, the config file, or a fixed OS handler
+			viewerCmd := exec.Command(argv[0], append(argv[1:], tempFile)...)
+			viewerCmd.Stdin = os.Stdin
+			viewerCmd.Stdout = os.Stdout
+			viewerCmd.Stderr = os.Stderr
+
+			if !watch {
+				if err := viewerCmd.Run(); err != nil {
+					return fmt.Errorf("%s exited with error: %w", argv[0], err)
+				}
+				_, err := fmt.Fprintf(cmd.ErrOrStderr(), "Opened a read-only copy at %s\n", tempFile)
+				return err
+			}
+			defer func() { _ = os.RemoveAll(tempDir) }()
+
+			if viewer == "" {
+				viewerCmd.Stdin = nil
+			}
+			if err := viewerCmd.Start(); err != nil {
+				return err
+			}
+			// A configured viewer may run in this terminal, so it owns stdin
+			// and the watch ends when it exits. The OS handler returns at
+			// once, so Enter ends the watch instead.
+			var stop <-chan struct{}
+			hint := "press Enter or Ctrl-C to stop"
+			if viewer != "" {
+				hint = "stops when " + argv[0] + " exits"
+				viewerDone := make(chan struct{})
+				go func() {
+					_ = viewerCmd.Wait()
+					close(viewerDone)
+				}()
+				stop = viewerDone
+			} else {
+				_ = viewerCmd.Wait()
+				stop = waitForEnter(cmd)
+			}
+
+			capture, err := resolveCaptureEnv(cmd, captureEnv)
+			if err != nil {
+				return err
+			}
+			w := &openWatcher{
+				cmd:        cmd,
+				uc:         uc,
+				sc:         sc,
+				key:        key,
+				path:       tempFile,
+				version:    result.Record.Version,
+				language:   result.Record.Language,
+				hash:       sha256.Sum256(content),
+				provenance: usecase.CaptureProvenance(cmd.Context(), usecase.ToolCLI, "", "", capture),
+			}
+			if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "Watching %s for changes; %s\n", tempFile, hint); err != nil {
+				return err
+			}
+			return w.run(ctx, interval, stop)
+		},
+	}
+
+	cmd.Flags().IntVarP(&versionFlag, "version", "v", 0, "Open a specific version")
+	cmd.Flags().StringVar(&viewer, "with", "", "Command to open the file with (default: viewer from config, else the OS handler)")
+	cmd.Flags().BoolVar(&watch, "watch", false, "Store every saved change as a new version until stopped")
+	cmd.Flags().DurationVar(&interval, "interval", 500*time.Millisecond, "How often --watch checks the file for changes")
+	cmd.Flags().BoolVar(&captureEnv, "capture-env", false, "Record hostname and git branch/commit/dirty state with saved versions (default from config)")
+	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
+	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
+	cmd.Flags().StringVar(&worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+
+	return cmd
+}
+
+// defaultOpener is the command that opens a file with the operating
+// system's default application.
+func defaultOpener() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"open"}
+	case "windows":
+		return []string{"rundll32", "url.dll,FileProtocolHandler"}
+	default:
+		return []string{"xdg-open"}
+	}
+}
+
+// openFileNameReplacer replaces the characters that are not allowed in file
+// names on Windows, which includes both path separators.
+var openFileNameReplacer = strings.NewReplacer(
+	"/", "_", `\`, "_", ":", "_", "*", "_", "?", "_", `"`, "_", "<", "_", ">", "_", "|", "_",
+)
+
+// openFileName names a temporary copy of an entry after its key. Keys
+// without an extension get one for their language (markdown if unknown), so
+// that editors and the default handler pick a suitable mode or application.
+func openFileName(key, lang string) string {
+	name := openFileNameReplacer.Replace(key)
+	if filepath.Ext(name) == "" {
+		ext := language.Extension(lang)
+		if ext == "" {
+			ext = ".md"
+		}
+		name += ext
+	}
+	return name
+}
+
+// openWatcher stores changes to the temporary copy as new versions.
+type openWatcher struct {
+	cmd        *cobra.Command
+	uc         *usecase.Entry
+	sc         scope.Scope
+	key        string
+	path       string
+	version    int64
+	language   string
+	hash       [sha256.Size]byte
+	provenance *database.VersionProvenance
+}
+
+// waitForEnter returns a channel that is closed when a line is read from
+// stdin. It stays open if stdin ends first.
+func waitForEnter(cmd *cobra.Command) <-chan struct{} {
+	entered := make(chan struct{})
+	go func() {
+		if _, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n'); err == nil {
+			close(entered)
+		}
+	}()
+	return entered
+}
+
+// run polls the copy until done is closed or the process is interrupted,
+// saving once more before it returns.
+func (w *openWatcher) run(ctx context.Context, interval time.Duration, done <-chan struct{}) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.save(context.WithoutCancel(ctx)); err != nil {
+				return err
+			}
+			continue
+		case <-ctx.Done():
+		case <-done:
+		}
+		return w.save(context.WithoutCancel(ctx))
+	}
+}
+
+// save stores the copy as a new version if it changed since the last save.
+func (w *openWatcher) save(ctx context.Context) error {
+	//nolint:gosec // G304: path is the temporary copy created by open
+	content, err := os.ReadFile(w.path)
+	if errors.Is(err, os.ErrNotExist) {
+		// Editors that save by rename briefly remove the file.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(content)
+	if hash == w.hash {
+		return nil
+	}
+
+	description := "Saved from vault open"
+	base := w.version
+	result, err := w.uc.Set(ctx, w.sc, w.key, string(content), &usecase.SetOptions{
+		Description: &description,
+		Provenance:  w.provenance,
+		BaseVersion: &base,
+		Language:    w.language,
+	})
+	if err != nil {
+		return err
+	}
+	w.version = result.Version
+	w.hash = hash
+	_, err = fmt.Fprintf(w.cmd.ErrOrStderr(), "Saved %s version %d\n", w.key, result.Version)
+	return err
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/contextpack"
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/language"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newPackCmd() *cobra.Command {
+	var (
+		budget       int
+		strategyName string
+		lang         string
+		descContains string
+		format       string
+		outputPath   string
+		noVerify     bool
+		scopeType    string
+		repoPath     string
+		branchName   string
+		worktreeID   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "pack [key|glob]...",
+		Short: "Assemble entries into one document for an LLM prompt",
+		Long: "Concatenate the latest versions of the selected entries into one markdown document, each under " +
+			"a header with its key, version, and description, for pasting into an LLM prompt. Arguments are keys " +
+			"or globs such as 'adr/*', packed in the order given; without arguments every entry in the scope is " +
+			"packed. --budget caps the size in tokens (estimated at four characters per token), and --strategy " +
+			"decides what gives when entries do not fit: truncate cuts the entry that crosses the budget and omits " +
+			"the rest, proportional gives each entry an equal share, summary falls back to stored summaries " +
+			"(see vault summarize), and drop skips entries that do not fit whole.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "text" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: text, json)", format)
+			}
+			if budget < 0 {
+				return fmt.Errorf("--budget must not be negative")
+			}
+			strategy, err := contextpack.ParseStrategy(strategyName)
+			if err != nil {
+				return err
+			}
+			if lang != "" {
+				if lang, err = language.Normalize(lang); err != nil {
+					return err
+				}
+			}
+
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			skipVerify, err := resolveSkipVerify(cmd, noVerify)
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			uc := usecase.NewEntry(dbCtx)
+			result, err := uc.Pack(context.Background(), sc, usecase.PackOptions{
+				Patterns:            args,
+				Language:            lang,
+				DescriptionContains: descContains,
+				Budget:              budget,
+				Strategy:            strategy,
+				SkipVerify:          skipVerify,
+			})
+			if err != nil {
+				return err
+			}
+			if len(result.Items) == 0 {
+				return fmt.Errorf("no entries matched")
+			}
+
+			var out io.Writer = cmd.OutOrStdout()
+			if outputPath != "" && outputPath != "-" {
+				file, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600) //nolint:gosec // G304: output path is chosen by the user
+				if err != nil {
+					return err
+				}
+				defer func() {
+					_ = file.Close()
+				}()
+				out = file
+			}
+
+			if format == "json" {
+				encoder := json.NewEncoder(out)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(newPackOutput(result))
+			}
+			if _, err := io.WriteString(out, result.Text); err != nil {
+				return err
+			}
+			return reportPack(cmd, result, budget)
+		},
+	}
+
+	cmd.Flags().IntVar(&budget, "budget", 0, "Approximate token limit for the whole document (default: no limit)")
+	cmd.Flags().StringVar(&strategyName, "strategy", string(contextpack.Truncate), "What to do with entries over the budget: truncate, proportional, summary, or drop")
+	cmd.Flags().StringVar(&lang, "lang", "", "Only entries with this content language (e.g. markdown, go)")
+	cmd.Flags().StringVar(&descContains, "description-contains", "", "Only entries whose description contains this text (case-insensitive)")
+	cmd.Flags().StringVar(&format, "format", "text", "Output format: text or json")
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "Write to this file instead of stdout")
+	cmd.Flags().BoolVar(&noVerify, "no-verify", false, "Skip the content hash check (default from verifyOnRead in config)")
+	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
+	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
+	cmd.Flags().StringVar(&worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+
+	return cmd
+}
+
+type packOutput struct {
+	Content string           `json:"content"`
+	Tokens  int              `json:"tokens"`
+	Entries []packOutputItem `json:"entries"`
+}
+
+type packOutputItem struct {
+	Key     string `json:"key"`
+	Version int64  `json:"version"`
+	Mode    string `json:"mode"`
+	Tokens  int    `json:"tokens"`
+}
+
+func newPackOutput(result *contextpack.Result) packOutput {
+	output := packOutput{
+		Content: result.Text,
+		Tokens:  result.Tokens,
+		Entries: make([]packOutputItem, 0, len(result.Items)),
+	}
+	for _, item := range result.Items {
+		output.Entries = append(output.Entries, packOutputItem{
+			Key:     item.Key,
+			Version: item.Version,
+			Mode:    string(item.Mode),
+			Tokens:  item.Tokens,
+		})
+	}
+	return output
+}
+
+// reportPack notes on stderr how the budget was spent, so the document on
+// stdout stays clean for piping.
+func reportPack(cmd *cobra.Command, result *contextpack.Result, budget int) error {
+	counts := make(map[contextpack.Mode]int)
+	for _, item := range result.Items {
+		counts[item.Mode]++
+	}
+	limit := ""
+	if budget > 0 {
+		limit = fmt.Sprintf(" of %d", budget)
+	}
+	_, err := fmt.Fprintf(cmd.ErrOrStderr(), "Packed %d entries, about %d%s tokens (%d full, %d truncated, %d summarized, %d omitted)\n",
+		len(result.Items)-counts[contextpack.ModeOmitted], result.Tokens, limit,
+		counts[contextpack.ModeFull], counts[contextpack.ModeTruncated], counts[contextpack.ModeSummarized], counts[contextpack.ModeOmitted])
+	return err
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/choplin/vault.md/internal/config"
+)
+
+// startPager sends the rest of cmd's output through a pager, as git does:
+// VAULT_PAGER, then PAGER, then less (with LESS=FRX unless LESS is set, so
+// short output is printed as usual). Nothing changes when --no-pager is
+// given, in CI mode, stdout is not a terminal, the pager is empty or "cat", or it
+// cannot be started. Call the returned function once output is complete.
+func startPager(cmd *cobra.Command) (func(), error) {
+	noop := func() {}
+	if noPager, _ := cmd.Flags().GetBool("no-pager"); noPager || ciMode {
+		return noop, nil
+	}
+	if cmd.OutOrStdout() != io.Writer(os.Stdout) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return noop, nil
+	}
+
+	command, ok := os.LookupEnv("VAULT_PAGER")
+	if !ok {
+		command, ok = os.LookupEnv("PAGER")
+	}
+	if !ok {
+		command = "less"
+	}
+	if command == "" || command == "cat" {
+		return noop, nil
+	}
+	argv, err := config.SplitCommandLine(command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pager %q: %w", command, err)
+	}
+
+	//nolint:gosec // G204: pager comes from VAULT_PAGER or PAGER
+	pager := exec.Command(argv[0], argv[1:]...)
+	pager.Stdout = os.Stdout
+	pager.Stderr = os.Stderr
+	pager.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		pager.Env = append(pager.Env, "LESS=FRX")
+	}
+	in, err := pager.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if pager.Start() != nil {
+		_ = in.Close()
+		return noop, nil
+	}
+
+	cmd.SetOut(pagerWriter{in})
+	return func() {
+		_ = in.Close()
+		_ = pager.Wait()
+		cmd.SetOut(nil)
+	}, nil
+}
+
+// pagerWriter drops output once the pager has quit, so leaving the pager
+// early is not reported as a write error.
+type pagerWriter struct {
+	w io.Writer
+}
+
+func (p pagerWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	if errors.Is(err, syscall.EPIPE) || errors.Is(err, os.ErrClosed) {
+		return len(b), nil
+	}
+	return n, err
+}
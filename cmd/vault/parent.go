@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newParentCmd() *cobra.Command {
+	flags := &scopeFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "parent",
+		Short: "Declare entries children of another, such as chapters of a document",
+		Long: "Split a large document into entries: declare each part a child of the document with " +
+			"vault parent set (or vault set --parent), then read the whole with vault get --with-children. " +
+			"Children are kept in order of declaration unless given a --position, and may be declared " +
+			"before they are written.",
+	}
+	flags.bind(cmd.PersistentFlags())
+
+	cmd.AddCommand(newParentSetCmd(flags))
+	cmd.AddCommand(newParentClearCmd(flags))
+	cmd.AddCommand(newParentChildrenCmd(flags))
+	return cmd
+}
+
+func newParentSetCmd(flags *scopeFlags) *cobra.Command {
+	var position int64
+
+	cmd := &cobra.Command{
+		Use:   "set <child> <parent>",
+		Short: "Make an entry a child of another",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return flags.run(cmd, func(ctx context.Context, uc *usecase.Entry, sc scope.Scope) error {
+				var pos *int64
+				if cmd.Flags().Changed("position") {
+					pos = &position
+				}
+				record, err := uc.SetParent(ctx, sc, args[0], args[1], pos)
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s is now child %d of %s\n", record.ChildKey, record.Position, record.ParentKey)
+				return err
+			})
+		},
+	}
+
+	cmd.Flags().Int64Var(&position, "position", 0, "Order among the parent's children (default: after the last one)")
+	return cmd
+}
+
+func newParentClearCmd(flags *scopeFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear <child>",
+		Short: "Remove an entry's parent",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return flags.run(cmd, func(ctx context.Context, uc *usecase.Entry, sc scope.Scope) error {
+				cleared, err := uc.ClearParent(ctx, sc, args[0])
+				if err != nil {
+					return err
+				}
+				if !cleared {
+					return fmt.Errorf("%s has no parent", args[0])
+				}
+				_, err = fmt.Fprintf(cmd.OutOrStdout(), "Cleared the parent of %s\n", args[0])
+				return err
+			})
+		},
+	}
+}
+
+func newParentChildrenCmd(flags *scopeFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "children <key>",
+		Short: "List an entry's parent and its children in order",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return flags.run(cmd, func(ctx context.Context, uc *usecase.Entry, sc scope.Scope) error {
+				out := cmd.OutOrStdout()
+				parent, err := uc.Parent(ctx, sc, args[0])
+				switch {
+				case err == nil:
+					if _, err := fmt.Fprintf(out, "parent: %s\n", parent.ParentKey); err != nil {
+						return err
+					}
+				case !errors.Is(err, services.ErrNotFound):
+					return err
+				}
+
+				children, err := uc.Children(ctx, sc, args[0])
+				if err != nil {
+					return err
+				}
+				if len(children) == 0 {
+					_, err := fmt.Fprintln(out, "No children")
+					return err
+				}
+				for _, child := range children {
+					if _, err := fmt.Fprintf(out, "%d\t%s\n", child.Position, child.ChildKey); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		},
+	}
+}
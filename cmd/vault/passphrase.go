@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// passphraseEnv holds the passphrase for scripts and CI, which cannot answer
+// a prompt.
+const passphraseEnv = "VAULT_PASSPHRASE"
+
+// readPassphrase returns the passphrase from VAULT_PASSPHRASE, or asks for
+// it on the terminal, twice when confirm is set so a typo cannot lock the
+// data away. Prompts go to stderr.
+func readPassphrase(stderr io.Writer, confirm bool) ([]byte, error) {
+	if explicit := os.Getenv(passphraseEnv); explicit != "" {
+		return []byte(explicit), nil
+	}
+	if err := refuseInCI("entering a passphrase", "set "+passphraseEnv); err != nil {
+		return nil, err
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("a passphrase is needed but stdin is not a terminal; set %s", passphraseEnv)
+	}
+
+	passphrase, err := promptPassphrase(stderr, fd, "Passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase is empty")
+	}
+	if confirm {
+		again, err := promptPassphrase(stderr, fd, "Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if string(again) != string(passphrase) {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+func promptPassphrase(stderr io.Writer, fd int, prompt string) ([]byte, error) {
+	if _, err := fmt.Fprint(stderr, prompt); err != nil {
+		return nil, err
+	}
+	passphrase, err := term.ReadPassword(fd)
+	// ReadPassword swallows the newline the user typed.
+	_, _ = fmt.Fprintln(stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newPatchCmd() *cobra.Command {
+	var (
+		filePath    string
+		patchType   string
+		description string
+		scopeType   string
+		repoPath    string
+		branchName  string
+		worktreeID  string
+		captureEnv  bool
+		force       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "patch <key>",
+		Short: "Apply a patch to the latest version and save the result",
+		Long: "Apply a unified diff, or a JSON list of section edits, to the latest version of a key " +
+			"and save the result as a new version.\n\n" +
+			"A section edit replaces the lines between a start and an end marker line:\n" +
+			`  [{"start": "## Status", "end": "## Next", "content": "Done.\n"}]` + "\n\n" +
+			"The start marker must occur exactly once. The patch fails if another version is saved while it is being applied.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key := args[0]
+
+			patchFn, err := parsePatch(cmd, filePath, patchType)
+			if err != nil {
+				return err
+			}
+
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			capture, err := resolveCaptureEnv(cmd, captureEnv)
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			opts := &usecase.SetOptions{
+				Provenance: usecase.CaptureProvenance(cmd.Context(), usecase.ToolCLI, "", "", capture),
+				IgnoreLock: force,
+			}
+			if strings.TrimSpace(description) != "" {
+				d := description
+				opts.Description = &d
+			}
+
+			uc := usecase.NewEntry(dbCtx)
+			result, err := uc.Patch(context.Background(), sc, key, patchFn, opts)
+			if err != nil {
+				return err
+			}
+
+			if _, err := fmt.Fprintln(cmd.OutOrStdout(), result.Path); err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&filePath, "file", "f", "", "Read the patch from file instead of stdin")
+	cmd.Flags().StringVar(&patchType, "type", "auto", "Patch type: auto, unified, or sections")
+	cmd.Flags().StringVarP(&description, "description", "d", "", "Add description metadata")
+	cmd.Flags().BoolVar(&captureEnv, "capture-env", false, "Record hostname and git branch/commit/dirty state with the version (default from config)")
+	cmd.Flags().BoolVar(&force, "force", false, "Write even if another owner holds a lock on the key")
+	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
+	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
+	cmd.Flags().StringVar(&worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+
+	return cmd
+}
+
+func parsePatch(cmd *cobra.Command, filePath, patchType string) (usecase.PatchFunc, error) {
+	if patchType != "auto" && patchType != "unified" && patchType != "sections" {
+		return nil, fmt.Errorf("invalid patch type: %s (valid values: auto, unified, sections)", patchType)
+	}
+
+	patch, err := readContent(cmd, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return usecase.NewPatchFunc(patch, patchType)
+}
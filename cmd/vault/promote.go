@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newPromoteCmd() *cobra.Command {
+	var (
+		keys       []string
+		all        bool
+		dryRun     bool
+		format     string
+		repoPath   string
+		branchName string
+		captureEnv bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "promote",
+		Short: "Copy a branch's keys to the repository scope and archive the branch",
+		Long: "Wrap up a branch after its pull request merges: copy the latest versions of the keys selected " +
+			"with --keys (names or globs such as 'adr/*') or --all from the branch scope to the repository " +
+			"scope, then archive every key of the branch scope. The branch defaults to the current one. Keys " +
+			"the repository scope already holds with the same content are not written again, so an " +
+			"interrupted promote can be rerun. --dry-run shows what would happen.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if format != "text" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: text, json)", format)
+			}
+			if all == (len(keys) > 0) {
+				return fmt.Errorf("specify either --keys or --all")
+			}
+
+			from, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:   string(scope.ScopeBranch),
+				Repo:   repoPath,
+				Branch: branchName,
+			})
+			if err != nil {
+				return err
+			}
+			to := scope.NewRepository(from.PrimaryPath)
+
+			capture, err := resolveCaptureEnv(cmd, captureEnv)
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			result, err := usecase.NewEntry(dbCtx).Promote(cmd.Context(), from, to, usecase.PromoteOptions{
+				Patterns: keys,
+				DryRun:   dryRun,
+				Set: &usecase.SetOptions{
+					Provenance: usecase.CaptureProvenance(cmd.Context(), usecase.ToolCLI, "", "", capture),
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(newPromoteOutput(from, to, dryRun, result))
+			}
+			return outputPromoteText(cmd, from, to, dryRun, result)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&keys, "keys", nil, "Keys or globs to copy (comma-separated or repeated)")
+	cmd.Flags().BoolVar(&all, "all", false, "Copy every key of the branch scope")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be copied and archived without changing anything")
+	cmd.Flags().StringVar(&format, "format", "text", "Output format: text or json")
+	cmd.Flags().BoolVar(&captureEnv, "capture-env", false, "Record hostname and git branch/commit/dirty state with the versions (default from config)")
+	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path (default: the current repository)")
+	cmd.Flags().StringVar(&branchName, "branch", "", "Branch to promote (default: the current branch)")
+
+	return cmd
+}
+
+type promoteOutput struct {
+	From     string             `json:"from"`
+	To       string             `json:"to"`
+	DryRun   bool               `json:"dryRun"`
+	Keys     []promoteOutputKey `json:"keys"`
+	Archived []string           `json:"archived"`
+}
+
+type promoteOutputKey struct {
+	Key         string `json:"key"`
+	FromVersion int64  `json:"fromVersion"`
+	ToVersion   int64  `json:"toVersion,omitempty"`
+	Unchanged   bool   `json:"unchanged,omitempty"`
+}
+
+func newPromoteOutput(from, to scope.Scope, dryRun bool, result *usecase.PromoteResult) promoteOutput {
+	output := promoteOutput{
+		From:     scope.FormatScope(from),
+		To:       scope.FormatScope(to),
+		DryRun:   dryRun,
+		Keys:     make([]promoteOutputKey, 0, len(result.Keys)),
+		Archived: result.Archived,
+	}
+	if output.Archived == nil {
+		output.Archived = []string{}
+	}
+	for _, k := range result.Keys {
+		output.Keys = append(output.Keys, promoteOutputKey(k))
+	}
+	return output
+}
+
+func outputPromoteText(cmd *cobra.Command, from, to scope.Scope, dryRun bool, result *usecase.PromoteResult) error {
+	out := cmd.OutOrStdout()
+	verb, archiveVerb := "Copied", "Archived"
+	if dryRun {
+		verb, archiveVerb = "Would copy", "Would archive"
+	}
+
+	for _, k := range result.Keys {
+		var line string
+		switch {
+		case k.Unchanged:
+			line = fmt.Sprintf("Unchanged %s (already version %d in %s)", k.Key, k.ToVersion, scope.FormatScopeShort(to))
+		case dryRun:
+			line = fmt.Sprintf("%s %s version %d to %s", verb, k.Key, k.FromVersion, scope.FormatScopeShort(to))
+		default:
+			line = fmt.Sprintf("%s %s version %d to %s as version %d", verb, k.Key, k.FromVersion, scope.FormatScopeShort(to), k.ToVersion)
+		}
+		if _, err := fmt.Fprintln(out, line); err != nil {
+			return err
+		}
+	}
+	if len(result.Archived) == 0 {
+		_, err := fmt.Fprintf(out, "Nothing to archive in %s\n", scope.FormatScopeShort(from))
+		return err
+	}
+	_, err := fmt.Fprintf(out, "%s %d key(s) in %s: %s\n", archiveVerb, len(result.Archived), scope.FormatScopeShort(from), strings.Join(result.Archived, ", "))
+	return err
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/language"
+	"github.com/choplin/vault.md/internal/publish"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newPublishCmd() *cobra.Command {
+	var (
+		outDir     string
+		title      string
+		baseURL    string
+		scopeType  string
+		repoPath   string
+		branchName string
+		worktreeID string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "publish --out <dir>",
+		Short: "Render a scope's entries as a static HTML site",
+		Long: "Write a browsable, read-only HTML site of a scope's latest entries to a directory: an index " +
+			"of keys, a page per key with its rendered content and history, a page per older version, and " +
+			"an Atom feed (feed.xml) of recent changes that readers can subscribe to once the site is hosted. " +
+			"Markdown entries are rendered; other content is shown as is. Archived entries are left out. " +
+			"Pages link with relative paths, so the directory can be opened from disk or served by any " +
+			"web server.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outDir == "" {
+				return fmt.Errorf("--out is required")
+			}
+
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			ctx := cmd.Context()
+			uc := usecase.NewEntry(dbCtx)
+			list, err := uc.List(ctx, sc, nil)
+			if err != nil {
+				return err
+			}
+
+			if title == "" {
+				title = scope.FormatScopeShort(sc)
+			}
+			site := &publish.Site{
+				Title:       title,
+				Scope:       scope.FormatScope(sc),
+				BaseURL:     baseURL,
+				GeneratedAt: time.Now(),
+				FormatTime:  display.timestamp,
+				Entries:     make([]publish.Entry, 0, len(list.Entries)),
+			}
+			for _, e := range list.Entries {
+				export, err := uc.ExportKey(ctx, sc, e.Record.Key)
+				if err != nil {
+					return fmt.Errorf("%s: %w", e.Record.Key, err)
+				}
+				entry := publish.Entry{Key: export.Key, Versions: make([]publish.Version, 0, len(export.Versions))}
+				for _, v := range export.Versions {
+					version := publish.Version{Version: v.Version, CreatedAt: v.CreatedAt, Content: v.Content}
+					if v.Description != nil {
+						version.Description = *v.Description
+					}
+					entry.Versions = append(entry.Versions, version)
+				}
+				lang := e.Record.Language
+				if lang == "" {
+					lang = language.Detect(e.Record.Key, entry.Versions[len(entry.Versions)-1].Content)
+				}
+				entry.Markdown = lang == language.Markdown
+				site.Entries = append(site.Entries, entry)
+			}
+
+			if err := publish.Write(outDir, site); err != nil {
+				return err
+			}
+			index, err := filepath.Abs(filepath.Join(outDir, "index.html"))
+			if err != nil {
+				index = filepath.Join(outDir, "index.html")
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Published %d entries from %s to %s\n", len(site.Entries), scope.FormatScopeShort(sc), index)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&outDir, "out", "o", "", "Directory to write the site to (created if missing)")
+	cmd.Flags().StringVar(&title, "title", "", "Site title (default the scope)")
+	cmd.Flags().StringVar(&baseURL, "base-url", "", "URL the site will be served from, so feed readers can resolve the feed's links")
+	addScopeFlags(cmd, &scopeType, &repoPath, &branchName, &worktreeID)
+
+	return cmd
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/scope"
+)
+
+// applyRequireGit sets what scope detection does when git cannot be run:
+// fail with --require-git or the requireGit config setting, and otherwise
+// warn once and use the global scope. --require-git=false overrides the
+// config.
+func applyRequireGit(cmd *cobra.Command) error {
+	var require bool
+	if cmd.Flags().Changed("require-git") {
+		var err error
+		if require, err = cmd.Flags().GetBool("require-git"); err != nil {
+			return err
+		}
+	} else if settings, err := config.Load(); err == nil {
+		require = settings.ShouldRequireGit()
+	}
+
+	stderr := cmd.ErrOrStderr()
+	scope.SetGitFallback(require, func(err error) {
+		_, _ = fmt.Fprintf(stderr, "warning: %v; using the global scope. Pass --scope, or --require-git to make this an error.\n", err)
+	})
+	return nil
+}
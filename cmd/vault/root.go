@@ -9,15 +9,81 @@ var rootCmd = &cobra.Command{
 	Short:   "vault.md - A knowledge vault for AI-assisted development",
 	Long:    "vault.md stores versioned notes scoped to repositories, branches, and worktrees.",
 	Version: version,
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		loadDisplayTime()
+		if err := loadCIMode(cmd); err != nil {
+			return err
+		}
+		if err := applyEphemeral(cmd); err != nil {
+			return err
+		}
+		if err := applyScopeEnv(cmd); err != nil {
+			return err
+		}
+		if err := applyRequireGit(cmd); err != nil {
+			return err
+		}
+		if err := warnCloudSync(cmd); err != nil {
+			return err
+		}
+		return applyTimeout(cmd)
+	},
 }
 
 func init() {
+	rootCmd.PersistentFlags().Bool("ci", false, "Non-interactive output for pipelines: no prompts, pager, or relative times (default on when CI is set)")
+	rootCmd.PersistentFlags().Bool("ephemeral", false, "Use a throwaway vault (in-memory database, temporary objects dir) removed on exit (default on when VAULT_EPHEMERAL is set)")
+	rootCmd.PersistentFlags().Bool("no-pager", false, "Do not pipe get, list, and history output through $PAGER")
+	rootCmd.PersistentFlags().Bool("require-git", false, "Fail when git cannot be run to detect the scope instead of falling back to global (default from config)")
+	rootCmd.PersistentFlags().Duration("timeout", 0, "Abort the command after this long, e.g. 30s (0 for no limit; per tool call for mcp)")
+
 	rootCmd.AddCommand(newSetCmd())
 	rootCmd.AddCommand(newGetCmd())
 	rootCmd.AddCommand(newCatCmd())
 	rootCmd.AddCommand(newListCmd())
 	rootCmd.AddCommand(newInfoCmd())
+	rootCmd.AddCommand(newHistoryCmd())
+	rootCmd.AddCommand(newBlameCmd())
 	rootCmd.AddCommand(newDeleteCmd())
 	rootCmd.AddCommand(newEditCmd())
+	rootCmd.AddCommand(newOpenCmd())
+	rootCmd.AddCommand(newPatchCmd())
+	rootCmd.AddCommand(newMergeCmd())
+	rootCmd.AddCommand(newPromoteCmd())
+	rootCmd.AddCommand(newApproveCmd())
+	rootCmd.AddCommand(newSummarizeCmd())
+	rootCmd.AddCommand(newPackCmd())
+	rootCmd.AddCommand(newSessionCmd())
+	rootCmd.AddCommand(newKVCmd())
+	rootCmd.AddCommand(newCounterCmd())
+	rootCmd.AddCommand(newLogAppendCmd())
+	rootCmd.AddCommand(newParentCmd())
+	rootCmd.AddCommand(newStaleCmd())
+	rootCmd.AddCommand(newArchiveCmd())
+	rootCmd.AddCommand(newRestoreCmd())
+	rootCmd.AddCommand(newUndoCmd())
+	rootCmd.AddCommand(newLockCmd())
+	rootCmd.AddCommand(newUnlockCmd())
+	rootCmd.AddCommand(newScopeCmd())
+	rootCmd.AddCommand(newExportCmd())
+	rootCmd.AddCommand(newExportKeyCmd())
+	rootCmd.AddCommand(newPublishCmd())
+	rootCmd.AddCommand(newImportKeyCmd())
+	rootCmd.AddCommand(newImportCmd())
+	rootCmd.AddCommand(newSyncGitCmd())
+	rootCmd.AddCommand(newSnapshotCmd())
+	rootCmd.AddCommand(newSnapshotScopeCmd())
+	rootCmd.AddCommand(newSyncKeyCmd())
+	rootCmd.AddCommand(newDevicesCmd())
+	rootCmd.AddCommand(newNotifyCmd())
 	rootCmd.AddCommand(newMCPCmd())
+	rootCmd.AddCommand(newBenchCmd())
+	rootCmd.AddCommand(newStressCmd())
+	rootCmd.AddCommand(newDoctorCmd())
+	rootCmd.AddCommand(newDBCmd())
+	rootCmd.AddCommand(newStatsCmd())
+	rootCmd.AddCommand(newSizeCmd())
+	rootCmd.AddCommand(newSchemaCmd())
+	rootCmd.AddCommand(newSelfUpdateCmd())
+	rootCmd.AddCommand(newVersionCmd())
 }
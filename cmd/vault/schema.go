@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+// jsonOutputSchemas maps command names to generators for the JSON Schema of
+// their JSON output (`--format json`, or `--info` for get). Schemas are
+// derived from the same structs the commands encode, so they cannot drift
+// from the actual output.
+var jsonOutputSchemas = map[string]func() (*jsonschema.Schema, error){
+	"bench":   func() (*jsonschema.Schema, error) { return jsonschema.For[benchReport](nil) },
+	"blame":   func() (*jsonschema.Schema, error) { return jsonschema.For[[]blameOutputEntry](nil) },
+	"devices": func() (*jsonschema.Schema, error) { return jsonschema.For[[]deviceOutputEntry](nil) },
+	"doctor":  func() (*jsonschema.Schema, error) { return jsonschema.For[usecase.DoctorReport](nil) },
+	"get":     func() (*jsonschema.Schema, error) { return jsonschema.For[getInfoOutput](nil) },
+	"history": func() (*jsonschema.Schema, error) { return jsonschema.For[[]historyOutputEntry](nil) },
+	"info":    func() (*jsonschema.Schema, error) { return jsonschema.For[infoOutputEntry](nil) },
+	"list":    func() (*jsonschema.Schema, error) { return jsonschema.For[[]listOutputEntry](nil) },
+	"stats":   func() (*jsonschema.Schema, error) { return jsonschema.For[usecase.VaultStats](nil) },
+}
+
+func schemaCommandNames() []string {
+	names := make([]string, 0, len(jsonOutputSchemas))
+	for name := range jsonOutputSchemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newSchemaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema <command>",
+		Short: "Print the JSON Schema of a command's JSON output",
+		Long: "Print the JSON Schema describing the output of `vault <command> --format json`.\n\n" +
+			"Commands with JSON output: " + strings.Join(schemaCommandNames(), ", "),
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: schemaCommandNames(),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			generate, ok := jsonOutputSchemas[name]
+			if !ok {
+				return fmt.Errorf("no JSON output schema for command: %s (valid values: %s)",
+					name, strings.Join(schemaCommandNames(), ", "))
+			}
+
+			schema, err := generate()
+			if err != nil {
+				return fmt.Errorf("failed to generate schema for %s: %w", name, err)
+			}
+			schema.Schema = "https://json-schema.org/draft/2020-12/schema"
+			schema.Title = fmt.Sprintf("vault %s JSON output", name)
+
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(schema)
+		},
+	}
+
+	return cmd
+}
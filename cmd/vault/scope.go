@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newScopeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scope",
+		Short: "List scopes and describe what they are for",
+		Long: "Inspect the scopes entries are stored in. scope list shows every scope with its description; " +
+			"scope describe attaches a description and key=value metadata to a scope so teammates and " +
+			"agents know what a repository or branch scope holds; scope diff compares two scopes.",
+	}
+
+	cmd.AddCommand(newScopeListCmd())
+	cmd.AddCommand(newScopeDescribeCmd())
+	cmd.AddCommand(newScopeDiffCmd())
+	return cmd
+}
+
+func newScopeListCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List all scopes with their descriptions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: table, json)", format)
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			scopes, err := usecase.NewEntry(dbCtx).Scopes(cmd.Context(), nil)
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				output := make([]scopeOutputEntry, 0, len(scopes))
+				for _, s := range scopes {
+					keys := s.Keys
+					output = append(output, newScopeOutputEntry(s.Record, &keys))
+				}
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(output)
+			}
+
+			if len(scopes) == 0 {
+				_, err := fmt.Fprintln(cmd.OutOrStdout(), "No scopes found")
+				return err
+			}
+			outputScopesTable(cmd, scopes)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+	return cmd
+}
+
+func newScopeDescribeCmd() *cobra.Command {
+	var (
+		meta       []string
+		unsetMeta  []string
+		clearAll   bool
+		format     string
+		scopeType  string
+		repoPath   string
+		branchName string
+		worktreeID string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "describe [description]",
+		Short: "Set or show the description and metadata of a scope",
+		Long: "Set the description of the current scope (or the one selected with the scope flags) and add " +
+			"metadata with --meta key=value. An empty description removes it, --unset-meta removes single " +
+			"metadata keys, and --clear removes both before the other changes apply. Without a description " +
+			"or flags the current description is shown.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: table, json)", format)
+			}
+			set, err := usecase.ParseScopeMetadata(meta)
+			if err != nil {
+				return err
+			}
+
+			change := usecase.ScopeDescription{
+				ClearMetadata: clearAll,
+				Set:           set,
+				Unset:         unsetMeta,
+			}
+			if len(args) == 1 {
+				change.Description = &args[0]
+			} else if clearAll {
+				empty := ""
+				change.Description = &empty
+			}
+
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			record, err := usecase.NewEntry(dbCtx).DescribeScope(cmd.Context(), sc, change)
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(newScopeOutputEntry(*record, nil))
+			}
+			return outputScopeDescription(cmd, record)
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&meta, "meta", nil, "Metadata to set as key=value (repeatable)")
+	cmd.Flags().StringArrayVar(&unsetMeta, "unset-meta", nil, "Metadata key to remove (repeatable)")
+	cmd.Flags().BoolVar(&clearAll, "clear", false, "Remove the description and all metadata first")
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
+	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
+	cmd.Flags().StringVar(&worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+
+	return cmd
+}
+
+func newScopeDiffCmd() *cobra.Command {
+	var (
+		nameOnly bool
+		format   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "diff <scope-a> <scope-b>",
+		Short: "Show keys whose latest content differs between two scopes",
+		Long: "Compare the latest versions of the keys in two scopes, e.g. before promoting a branch's context " +
+			"to its repository, and print a unified diff for each key present in both with different content. " +
+			"Keys found in only one scope are listed at the end. A scope is given as shown by scope list " +
+			"(global, /path/to/repo, /path/to/repo:branch, or /path/to/repo@worktree), or as repository or " +
+			"branch for the current repository or branch, or :name for branch name of the current repository.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "text" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: text, json)", format)
+			}
+			a, err := parseScopeArg(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			b, err := parseScopeArg(cmd.Context(), args[1])
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			diff, err := usecase.NewEntry(dbCtx).DiffScopes(cmd.Context(), a, b)
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(newScopeDiffOutput(a, b, diff, !nameOnly))
+			}
+			return outputScopeDiff(cmd.OutOrStdout(), a, b, diff, nameOnly)
+		},
+	}
+
+	cmd.Flags().BoolVar(&nameOnly, "name-only", false, "List the differing keys without their diffs")
+	cmd.Flags().StringVar(&format, "format", "text", "Output format: text or json")
+
+	return cmd
+}
+
+// parseScopeArg reads a scope given as a command argument: the form shown
+// by scope list, or repository, branch, or :name relative to the current
+// repository.
+func parseScopeArg(ctx context.Context, arg string) (scope.Scope, error) {
+	switch {
+	case arg == string(scope.ScopeRepository), arg == string(scope.ScopeBranch), arg == string(scope.ScopeWorktree):
+		return scope.ResolveScope(ctx, scope.ScopeOptions{Type: arg})
+	case strings.HasPrefix(arg, ":") && len(arg) > 1:
+		return scope.ResolveScope(ctx, scope.ScopeOptions{Type: string(scope.ScopeBranch), Branch: arg[1:]})
+	default:
+		return scope.ParseScope(arg)
+	}
+}
+
+type scopeDiffOutput struct {
+	A       string               `json:"a"`
+	B       string               `json:"b"`
+	Changed []scopeDiffOutputKey `json:"changed"`
+	OnlyA   []string             `json:"onlyA"`
+	OnlyB   []string             `json:"onlyB"`
+	Same    int                  `json:"same"`
+}
+
+type scopeDiffOutputKey struct {
+	Key      string `json:"key"`
+	VersionA int64  `json:"versionA"`
+	VersionB int64  `json:"versionB"`
+	Diff     string `json:"diff,omitempty"`
+}
+
+func newScopeDiffOutput(a, b scope.Scope, diff *usecase.ScopeDiff, withDiffs bool) scopeDiffOutput {
+	output := scopeDiffOutput{
+		A:       scope.FormatScope(a),
+		B:       scope.FormatScope(b),
+		Changed: make([]scopeDiffOutputKey, 0, len(diff.Changed)),
+		OnlyA:   append([]string{}, diff.OnlyA...),
+		OnlyB:   append([]string{}, diff.OnlyB...),
+		Same:    diff.Same,
+	}
+	for _, c := range diff.Changed {
+		key := scopeDiffOutputKey{Key: c.Key, VersionA: c.A.Version, VersionB: c.B.Version}
+		if withDiffs {
+			key.Diff = c.Diff
+		}
+		output.Changed = append(output.Changed, key)
+	}
+	return output
+}
+
+func outputScopeDiff(out io.Writer, a, b scope.Scope, diff *usecase.ScopeDiff, nameOnly bool) error {
+	for _, c := range diff.Changed {
+		text := c.Diff
+		if nameOnly {
+			text = c.Key + "\n"
+		}
+		if _, err := io.WriteString(out, text); err != nil {
+			return err
+		}
+	}
+	if len(diff.OnlyA) > 0 {
+		if _, err := fmt.Fprintf(out, "Only in %s: %s\n", scope.FormatScope(a), strings.Join(diff.OnlyA, ", ")); err != nil {
+			return err
+		}
+	}
+	if len(diff.OnlyB) > 0 {
+		if _, err := fmt.Fprintf(out, "Only in %s: %s\n", scope.FormatScope(b), strings.Join(diff.OnlyB, ", ")); err != nil {
+			return err
+		}
+	}
+	if len(diff.Changed) == 0 && len(diff.OnlyA) == 0 && len(diff.OnlyB) == 0 {
+		_, err := fmt.Fprintf(out, "No differences (%d keys identical)\n", diff.Same)
+		return err
+	}
+	return nil
+}
+
+type scopeOutputEntry struct {
+	Scope       string            `json:"scope"`
+	Type        string            `json:"type"`
+	Description string            `json:"description,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Keys        *int              `json:"keys,omitempty"`
+}
+
+// newScopeOutputEntry converts a scope for JSON output; keys is nil when
+// the key count was not looked up.
+func newScopeOutputEntry(record database.ScopeRecord, keys *int) scopeOutputEntry {
+	return scopeOutputEntry{
+		Scope:       scope.FormatScope(record.Scope),
+		Type:        string(record.Scope.Type),
+		Description: record.Description,
+		Metadata:    record.Metadata,
+		Keys:        keys,
+	}
+}
+
+// formatScopeMetadata renders metadata as "key=value" pairs sorted by key.
+func formatScopeMetadata(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for key, value := range metadata {
+		pairs = append(pairs, key+"="+value)
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+func outputScopesTable(cmd *cobra.Command, scopes []usecase.ScopeInfo) {
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(table.Row{"Scope", "Type", "Keys", "Description", "Metadata"})
+
+	for _, s := range scopes {
+		t.AppendRow(table.Row{
+			scope.FormatScopeShort(s.Record.Scope),
+			s.Record.Scope.Type,
+			s.Keys,
+			s.Record.Description,
+			formatScopeMetadata(s.Record.Metadata),
+		})
+	}
+
+	t.Render()
+}
+
+func outputScopeDescription(cmd *cobra.Command, record *database.ScopeRecord) error {
+	out := cmd.OutOrStdout()
+	description := record.Description
+	if description == "" {
+		description = "(none)"
+	}
+	if _, err := fmt.Fprintf(out, "Scope:       %s\n", scope.FormatScope(record.Scope)); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(out, "Description: %s\n", description); err != nil {
+		return err
+	}
+	if len(record.Metadata) == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintf(out, "Metadata:    %s\n", formatScopeMetadata(record.Metadata))
+	return err
+}
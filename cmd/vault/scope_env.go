@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/scope"
+)
+
+// scopeEnv maps scope flags to the environment variables that default them.
+var scopeEnv = []struct {
+	flag, env string
+}{
+	{"scope", "VAULT_SCOPE"},
+	{"repo", "VAULT_REPO"},
+	{"branch", "VAULT_BRANCH"},
+}
+
+// scopeFlagNames are the flags that select a scope on the command line.
+var scopeFlagNames = []string{"scope", "repo", "branch", "worktree"}
+
+// applyScopeEnv defaults the scope flags of cmd from VAULT_SCOPE,
+// VAULT_REPO, and VAULT_BRANCH, so CI jobs and wrapper scripts can set the
+// scope once. A scope given with flags replaces the environment's as a
+// whole rather than being mixed with it.
+func applyScopeEnv(cmd *cobra.Command) error {
+	flags := cmd.Flags()
+	for _, name := range scopeFlagNames {
+		if flag := flags.Lookup(name); flag != nil && flag.Changed {
+			return nil
+		}
+	}
+
+	for _, s := range scopeEnv {
+		value := os.Getenv(s.env)
+		if value == "" || flags.Lookup(s.flag) == nil {
+			continue
+		}
+		if s.flag == "scope" {
+			switch scope.ScopeType(value) {
+			case scope.ScopeGlobal, scope.ScopeRepository, scope.ScopeBranch, scope.ScopeWorktree:
+			default:
+				return fmt.Errorf("invalid %s: %s (valid values: global, repository, branch, worktree)", s.env, value)
+			}
+		}
+		if err := flags.Set(s.flag, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", s.env, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+// scopeFlags are the scope flags of commands whose subcommands share them.
+type scopeFlags struct {
+	scopeType  string
+	repoPath   string
+	branchName string
+	worktreeID string
+}
+
+func (f *scopeFlags) bind(flags *pflag.FlagSet) {
+	flags.StringVar(&f.scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
+	flags.StringVar(&f.repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	flags.StringVar(&f.branchName, "branch", "", "Branch name (requires --scope branch)")
+	flags.StringVar(&f.worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+}
+
+// run resolves the scope, opens the database, and calls fn.
+func (f *scopeFlags) run(cmd *cobra.Command, fn func(context.Context, *usecase.Entry, scope.Scope) error) error {
+	sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+		Type:     f.scopeType,
+		Repo:     f.repoPath,
+		Branch:   f.branchName,
+		Worktree: f.worktreeID,
+	})
+	if err != nil {
+		return err
+	}
+
+	uc, closeEntry, err := openEntry()
+	if err != nil {
+		return err
+	}
+	defer closeEntry()
+
+	return fn(cmd.Context(), uc, sc)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/selfupdate"
+)
+
+func newSelfUpdateCmd() *cobra.Command {
+	var (
+		check  bool
+		target string
+		force  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "self-update",
+		Short: "Update vault to the latest GitHub release",
+		Long: "Download the release archive for this platform from GitHub, check it against the SHA-256 " +
+			"checksums published with the release, and replace the running binary. The checksums catch a " +
+			"corrupted download; they are not a signature. The old binary is kept if anything fails.\n\n" +
+			"Installs managed by Homebrew are left alone; update them with brew. " +
+			"Development builds are only replaced with --force.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			out := cmd.OutOrStdout()
+			current := strings.TrimPrefix(version, "v")
+
+			ctx := cmd.Context()
+			updater := selfupdate.New()
+			release, err := updater.Release(ctx, target)
+			if err != nil {
+				return err
+			}
+
+			if check {
+				if release.Version == current {
+					_, err = fmt.Fprintf(out, "vault %s is up to date\n", current)
+				} else {
+					_, err = fmt.Fprintf(out, "vault %s is available (installed: %s)\n", release.Version, current)
+				}
+				return err
+			}
+			if release.Version == current && !force {
+				_, err = fmt.Fprintf(out, "vault %s is up to date\n", current)
+				return err
+			}
+			if current == "dev" && !force {
+				return fmt.Errorf("this is a development build; pass --force to replace it with %s", release.Version)
+			}
+
+			exe, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			if exe, err = filepath.EvalSymlinks(exe); err != nil {
+				return err
+			}
+			if pm := selfupdate.PackageManager(exe); pm != "" && !force {
+				return fmt.Errorf("%s is managed by %s; run `%s` instead", exe, pm, packageUpgradeCommands[pm])
+			}
+
+			binary, err := updater.Download(ctx, release)
+			if err != nil {
+				return err
+			}
+			if err := selfupdate.Replace(exe, binary); err != nil {
+				return fmt.Errorf("failed to replace %s: %w", exe, err)
+			}
+			_, err = fmt.Fprintf(out, "Updated vault %s -> %s (%s)\n", current, release.Version, exe)
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&check, "check", false, "Only report whether a newer release is available")
+	cmd.Flags().StringVar(&target, "to", "", "Install this release tag instead of the latest")
+	cmd.Flags().BoolVar(&force, "force", false, "Reinstall the same version, replace development builds, and ignore package managers")
+
+	return cmd
+}
+
+// packageUpgradeCommands are the commands that update vault for each
+// package manager selfupdate.PackageManager recognises.
+var packageUpgradeCommands = map[string]string{
+	"brew": "brew upgrade vault",
+}
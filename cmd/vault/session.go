@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+// sessionFlags are the scope flags shared by the session subcommands.
+type sessionFlags struct {
+	scopeType  string
+	repoPath   string
+	branchName string
+	worktreeID string
+	captureEnv bool
+}
+
+func newSessionCmd() *cobra.Command {
+	flags := &sessionFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "session",
+		Short: "Keep a dated work log for the current branch",
+		Long: "Journal work in progress without inventing key names. session start opens a log under " +
+			"session/<date> (the day the session started) in the current branch's scope, or the global scope " +
+			"outside a git repository; session append adds timestamped notes to the open session, and " +
+			"session end closes it. Read logs with vault get, e.g. vault get session/2025-06-01.",
+	}
+
+	cmd.PersistentFlags().BoolVar(&flags.captureEnv, "capture-env", false, "Record hostname and git branch/commit/dirty state with the version (default from config)")
+	cmd.PersistentFlags().StringVar(&flags.scopeType, "scope", "", "Scope type: global, repository, branch, or worktree (default: branch, or global outside git)")
+	cmd.PersistentFlags().StringVar(&flags.repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	cmd.PersistentFlags().StringVar(&flags.branchName, "branch", "", "Branch name (requires --scope branch)")
+	cmd.PersistentFlags().StringVar(&flags.worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+
+	cmd.AddCommand(newSessionStartCmd(flags))
+	cmd.AddCommand(newSessionAppendCmd(flags))
+	cmd.AddCommand(newSessionEndCmd(flags))
+	return cmd
+}
+
+func newSessionStartCmd(flags *sessionFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "start [note]",
+		Short: "Open a session in today's log",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return flags.run(cmd, func(ctx context.Context, uc *usecase.Entry, sc scope.Scope, now time.Time, opts *usecase.SetOptions) error {
+				if key, err := uc.OpenSession(ctx, sc); err == nil {
+					return fmt.Errorf("session %s is still open; end it with vault session end", key)
+				} else if !errors.Is(err, usecase.ErrNoOpenSession) {
+					return err
+				}
+
+				result, err := uc.StartSession(ctx, sc, now, strings.Join(args, " "), opts)
+				if err != nil {
+					return err
+				}
+				verb := "Started"
+				if result.Resumed {
+					verb = "Resumed"
+				}
+				_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s session %s\n", verb, result.Key)
+				return err
+			})
+		},
+	}
+}
+
+func newSessionAppendCmd(flags *sessionFlags) *cobra.Command {
+	var filePath string
+
+	cmd := &cobra.Command{
+		Use:   "append [text]",
+		Short: "Add a timestamped note to the open session",
+		Long:  "Add a timestamped note to the open session. The note is the argument, or read from --file or stdin.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var text string
+			if len(args) == 1 {
+				text = args[0]
+			} else {
+				content, err := readContent(cmd, filePath)
+				if err != nil {
+					return err
+				}
+				text = content
+			}
+
+			return flags.run(cmd, func(ctx context.Context, uc *usecase.Entry, sc scope.Scope, now time.Time, opts *usecase.SetOptions) error {
+				result, err := uc.AppendSession(ctx, sc, now, text, opts)
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintf(cmd.OutOrStdout(), "Added to %s (version %d)\n", result.Key, result.Version)
+				return err
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&filePath, "file", "f", "", "Read the note from file instead of stdin")
+	return cmd
+}
+
+func newSessionEndCmd(flags *sessionFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "end [summary]",
+		Short: "Close the open session",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return flags.run(cmd, func(ctx context.Context, uc *usecase.Entry, sc scope.Scope, now time.Time, opts *usecase.SetOptions) error {
+				result, err := uc.EndSession(ctx, sc, now, strings.Join(args, " "), opts)
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintf(cmd.OutOrStdout(), "Ended session %s\n", result.Key)
+				return err
+			})
+		},
+	}
+}
+
+// run resolves the session scope, opens the database, and calls fn with
+// the current time in the display timezone.
+func (f *sessionFlags) run(cmd *cobra.Command, fn func(context.Context, *usecase.Entry, scope.Scope, time.Time, *usecase.SetOptions) error) error {
+	sc, err := f.resolveScope(cmd.Context())
+	if err != nil {
+		return err
+	}
+	capture, err := resolveCaptureEnv(cmd, f.captureEnv)
+	if err != nil {
+		return err
+	}
+
+	dbCtx, err := database.CreateDatabase("")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = database.CloseDatabase(dbCtx)
+	}()
+
+	opts := &usecase.SetOptions{
+		Provenance: usecase.CaptureProvenance(cmd.Context(), usecase.ToolCLI, "", "", capture),
+	}
+	err = fn(cmd.Context(), usecase.NewEntry(dbCtx), sc, time.Now().In(display.loc), opts)
+	if errors.Is(err, usecase.ErrNoOpenSession) {
+		return fmt.Errorf("no open session in %s; start one with vault session start", scope.FormatScope(sc))
+	}
+	return err
+}
+
+// resolveScope defaults to the current branch, so that each branch keeps
+// its own log, and falls back to the usual default outside a branch.
+func (f *sessionFlags) resolveScope(ctx context.Context) (scope.Scope, error) {
+	opts := scope.ScopeOptions{
+		Type:     f.scopeType,
+		Repo:     f.repoPath,
+		Branch:   f.branchName,
+		Worktree: f.worktreeID,
+	}
+	if opts.Type == "" && opts.Repo == "" {
+		branchOpts := opts
+		branchOpts.Type = string(scope.ScopeBranch)
+		if sc, err := scope.ResolveScope(ctx, branchOpts); err == nil {
+			return sc, nil
+		}
+	}
+	return scope.ResolveScope(ctx, opts)
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"io"
 	"os"
@@ -9,7 +8,8 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/language"
 	"github.com/choplin/vault.md/internal/scope"
 	"github.com/choplin/vault.md/internal/usecase"
 )
@@ -22,6 +22,14 @@ func newSetCmd() *cobra.Command {
 		repoPath    string
 		branchName  string
 		worktreeID  string
+		captureEnv  bool
+		idemKey     string
+		section     string
+		lang        string
+		asJSON      bool
+		parent      string
+		noSummary   bool
+		force       bool
 	)
 
 	cmd := &cobra.Command{
@@ -30,8 +38,14 @@ func newSetCmd() *cobra.Command {
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			key := args[0]
+			if asJSON {
+				if lang != "" && lang != language.JSON {
+					return fmt.Errorf("--json cannot be combined with --lang %s", lang)
+				}
+				lang = language.JSON
+			}
 
-			sc, err := scope.ResolveScope(scope.ScopeOptions{
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
 				Type:     scopeType,
 				Repo:     repoPath,
 				Branch:   branchName,
@@ -46,30 +60,99 @@ func newSetCmd() *cobra.Command {
 				return err
 			}
 
-			dbCtx, err := database.CreateDatabase("")
+			capture, err := resolveCaptureEnv(cmd, captureEnv)
+			if err != nil {
+				return err
+			}
+			summarize, err := loadSummarizer(noSummary)
 			if err != nil {
 				return err
 			}
-			defer func() {
-				_ = database.CloseDatabase(dbCtx)
-			}()
 
-			ctx := context.Background()
-			var opts *usecase.SetOptions
+			uc, closeEntry, err := openEntry()
+			if err != nil {
+				return err
+			}
+			defer closeEntry()
+
+			ctx := cmd.Context()
+			opts := &usecase.SetOptions{
+				Provenance:     usecase.CaptureProvenance(cmd.Context(), usecase.ToolCLI, "", "", capture),
+				IdempotencyKey: idemKey,
+				Language:       lang,
+				Summarizer:     summarize,
+				IgnoreLock:     force,
+			}
 			if strings.TrimSpace(description) != "" {
 				d := description
-				opts = &usecase.SetOptions{
-					Description: &d,
-				}
+				opts.Description = &d
 			}
 
-			uc := usecase.NewEntry(dbCtx)
-			path, err := uc.Set(ctx, sc, key, content, opts)
+			if parent != "" {
+				if _, err := uc.SetParent(ctx, sc, key, parent, nil); err != nil {
+					return err
+				}
+			}
+			var result *usecase.SetResult
+			if section != "" {
+				result, err = uc.Patch(ctx, sc, key, usecase.SectionPatch(section, content), opts)
+			} else {
+				result, err = uc.Set(ctx, sc, key, content, opts)
+			}
 			if err != nil {
 				return err
 			}
+			if result.Key != key {
+				if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "note: stored as %q\n", result.Key); err != nil {
+					return err
+				}
+				key = result.Key
+			}
 
-			if _, err := fmt.Fprintln(cmd.OutOrStdout(), path); err != nil {
+			if !result.Replayed {
+				if err := recordUndo(cmd, usecase.NewSetUndo(sc, key, result.Version)); err != nil {
+					return err
+				}
+			}
+			if result.Replayed {
+				if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "note: idempotency key already used; %s is unchanged at version %d\n", key, result.Version); err != nil {
+					return err
+				}
+			}
+			if result.ConcurrentWrite {
+				if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "note: another writer updated %s concurrently; saved as version %d\n", key, result.Version); err != nil {
+					return err
+				}
+			}
+			if result.Pruned > 0 {
+				if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "note: pruned %d old versions to stay within the scope quota\n", result.Pruned); err != nil {
+					return err
+				}
+			}
+			if result.PruneErr != nil {
+				if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "warning: saved %s version %d, but pruning for the scope quota failed: %v\n", key, result.Version, result.PruneErr); err != nil {
+					return err
+				}
+			}
+			if len(result.Templated) > 0 {
+				if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "note: new scope; created %s from key templates\n", strings.Join(result.Templated, ", ")); err != nil {
+					return err
+				}
+			}
+			if result.TemplateErr != nil {
+				if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "warning: saved %s version %d, but creating the key templates failed: %v\n", key, result.Version, result.TemplateErr); err != nil {
+					return err
+				}
+			}
+			for _, warning := range result.Warnings {
+				if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s: %s\n", key, warning); err != nil {
+					return err
+				}
+			}
+			if err := warnSummaryFailure(cmd, key, result); err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(cmd.OutOrStdout(), result.Path); err != nil {
 				return err
 			}
 			return nil
@@ -78,6 +161,14 @@ func newSetCmd() *cobra.Command {
 
 	cmd.Flags().StringVarP(&filePath, "file", "f", "", "Read content from file instead of stdin")
 	cmd.Flags().StringVarP(&description, "description", "d", "", "Add description metadata")
+	cmd.Flags().BoolVar(&captureEnv, "capture-env", false, "Record hostname and git branch/commit/dirty state with the version (default from config)")
+	cmd.Flags().StringVar(&section, "section", "", `Replace only the content under this markdown heading (e.g. "## Decisions") of the latest version`)
+	cmd.Flags().StringVar(&lang, "lang", "", "Content language such as markdown, text, go, or python (default: detected from the key and content)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Store the content as JSON, rejecting it unless it parses (same as --lang json)")
+	cmd.Flags().StringVar(&parent, "parent", "", "Declare the entry a child of this key, e.g. a chapter of a document (see vault parent)")
+	cmd.Flags().BoolVar(&noSummary, "no-summary", false, "Do not run the configured summarizer for this version")
+	cmd.Flags().BoolVar(&force, "force", false, "Write even if another owner holds a lock on the key")
+	cmd.Flags().StringVar(&idemKey, "idempotency-key", "", "Token identifying this write; retrying with the same token and content does not create another version")
 	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
 	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
 	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
@@ -86,6 +177,19 @@ func newSetCmd() *cobra.Command {
 	return cmd
 }
 
+// resolveCaptureEnv applies --capture-env when given, and otherwise falls
+// back to the captureEnvironment config setting.
+func resolveCaptureEnv(cmd *cobra.Command, captureEnv bool) (bool, error) {
+	if cmd.Flags().Changed("capture-env") {
+		return captureEnv, nil
+	}
+	settings, err := config.Load()
+	if err != nil {
+		return false, err
+	}
+	return settings.ShouldCaptureEnvironment(), nil
+}
+
 func readContent(cmd *cobra.Command, filePath string) (string, error) {
 	if filePath != "" {
 		//nolint:gosec // G304: filePath is from user's --file flag, intentional file read
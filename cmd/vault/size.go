@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newSizeCmd() *cobra.Command {
+	var (
+		format     string
+		scopeType  string
+		repoPath   string
+		branchName string
+		worktreeID string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "size",
+		Short: "Show how much each scope stores",
+		Long: "Show the keys, versions, and bytes each scope stores, and how much of the quota in the config " +
+			"file (quota.maxEntries, quota.maxBytes) they use. Without scope flags every scope is shown.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: table, json)", format)
+			}
+
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			allScopes := scopeType == "" && repoPath == "" && branchName == "" && worktreeID == ""
+			usages, err := usecase.NewEntry(dbCtx).Usage(context.Background(), sc, allScopes)
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				output := make([]sizeOutputEntry, 0, len(usages))
+				for _, u := range usages {
+					output = append(output, newSizeOutputEntry(u))
+				}
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(output)
+			}
+			outputSizeTable(cmd, usages)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
+	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
+	cmd.Flags().StringVar(&worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+
+	return cmd
+}
+
+type sizeOutputEntry struct {
+	Scope      string `json:"scope"`
+	ScopeType  string `json:"scopeType"`
+	Entries    int64  `json:"entries"`
+	Versions   int64  `json:"versions"`
+	Bytes      int64  `json:"bytes"`
+	MaxEntries int    `json:"maxEntries,omitempty"`
+	MaxBytes   int64  `json:"maxBytes,omitempty"`
+}
+
+func newSizeOutputEntry(u usecase.ScopeUsage) sizeOutputEntry {
+	entry := sizeOutputEntry{
+		Scope:     u.ScopeShort,
+		ScopeType: string(u.Scope.Type),
+		Entries:   u.Entries,
+		Versions:  u.Versions,
+		Bytes:     u.Bytes,
+	}
+	if u.Quota != nil {
+		entry.MaxEntries = u.Quota.MaxEntries
+		entry.MaxBytes = u.Quota.MaxBytes
+	}
+	return entry
+}
+
+func outputSizeTable(cmd *cobra.Command, usages []usecase.ScopeUsage) {
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(table.Row{"Scope", "Keys", "Versions", "Bytes", "Keys Quota", "Bytes Quota"})
+	for _, u := range usages {
+		var maxEntries, maxBytes int64
+		if u.Quota != nil {
+			maxEntries, maxBytes = int64(u.Quota.MaxEntries), u.Quota.MaxBytes
+		}
+		t.AppendRow(table.Row{u.ScopeShort, u.Entries, u.Versions, u.Bytes, quotaUse(u.Entries, maxEntries), quotaUse(u.Bytes, maxBytes)})
+	}
+	t.Render()
+}
+
+// quotaUse shows used against limit as a percentage, or "-" without a limit.
+func quotaUse(used, limit int64) string {
+	if limit == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d%% of %d", used*100/limit, limit)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/snapshot"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newSnapshotCmd() *cobra.Command {
+	var (
+		to        string
+		from      string
+		id        string
+		keep      int
+		keyGlob   string
+		since     string
+		scopeType string
+		ghSummary bool
+		format    int
+		signKey   string
+		signers   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Write or restore an incremental snapshot of the whole vault",
+		Long: "With --to, write a snapshot of every version in the vault to a snapshot directory. Content is " +
+			"stored once per hash, so each run only copies what changed since earlier snapshots. --keep N " +
+			"removes all but the newest N snapshots and the content only they referenced.\n\n" +
+			"--key-glob, --since, and --scope-type limit a snapshot to matching keys, recent versions, or one " +
+			"kind of scope, for focused bundles such as only the ADRs written in the last quarter.\n\n" +
+			"With --from, import the versions of a snapshot (the newest, or --id) that are missing locally.\n\n" +
+			"Targets are directories, file:// URLs, s3://bucket/prefix (credentials from the AWS environment " +
+			"variables or shared credentials file; AWS_ENDPOINT_URL_S3 for other S3-compatible services), or " +
+			"gs://bucket/prefix (an HMAC key in VAULT_GS_ACCESS_KEY_ID and VAULT_GS_SECRET_ACCESS_KEY).\n\n" +
+			"Run it from cron or a systemd timer for scheduled backups. In GitHub Actions, --github-summary " +
+			"adds the keys that changed since the previous snapshot to the job summary and sets step outputs " +
+			"(snapshot-id, keys, versions, new-objects, changed, changed-keys). With syncKeyFile set in the config " +
+			"(see sync-key), manifests and content are encrypted before they are written.\n\n" +
+			"--format-version 1 writes a manifest older releases can restore, leaving out version metadata, " +
+			"parent links, and scope information.\n\n" +
+			"Every snapshot has an integrity manifest, snapshots/<id>.sums, with the SHA-256 of its manifest and " +
+			"content. --sign-key signs it with an SSH key (ssh-keygen -Y sign). Restoring checks every file " +
+			"against it first and imports nothing if any differs; --allowed-signers also requires a valid " +
+			"signature from a key in that file, in the format of ssh-keygen's ALLOWED SIGNERS.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if (to == "") == (from == "") {
+				return fmt.Errorf("specify exactly one of --to or --from")
+			}
+			if keep < 0 {
+				return fmt.Errorf("invalid --keep: %d (must be 0 or greater)", keep)
+			}
+			if from != "" && (keyGlob != "" || since != "" || scopeType != "" || ghSummary || format != 0 || signKey != "") {
+				return fmt.Errorf("--key-glob, --since, --scope-type, --format-version, --sign-key, and --github-summary only apply to --to")
+			}
+			if to != "" && (id != "" || signers != "") {
+				return fmt.Errorf("--id and --allowed-signers only apply to --from")
+			}
+			if err := usecase.ValidateSnapshotFormatVersion(format); err != nil {
+				return err
+			}
+			if ghSummary {
+				if err := checkGitHubSummary(); err != nil {
+					return err
+				}
+			}
+			sinceTime, err := parseTimeFlag(since, time.Now())
+			if err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+			filter := &usecase.SnapshotFilter{
+				KeyGlob:   keyGlob,
+				Since:     sinceTime,
+				ScopeType: scope.ScopeType(scopeType),
+			}
+			if err := filter.Validate(); err != nil {
+				return err
+			}
+
+			target := to
+			if from != "" {
+				target = from
+			}
+			store, err := snapshot.Open(target)
+			if err != nil {
+				return err
+			}
+			syncCipher, err := loadSyncCipher()
+			if err != nil {
+				return err
+			}
+			if syncCipher != nil {
+				store = snapshot.NewEncryptedStore(store, syncCipher)
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			uc := usecase.NewEntry(dbCtx)
+			ctx := cmd.Context()
+			out := cmd.OutOrStdout()
+
+			if from != "" {
+				result, restoredID, err := uc.RestoreFrom(ctx, store, usecase.RestoreFromOptions{ID: id, AllowedSigners: signers})
+				if err != nil {
+					return err
+				}
+				if err := printIntegrity(cmd, restoredID, result.Integrity); err != nil {
+					return err
+				}
+				if err := printRestoreSkips(cmd, result); err != nil {
+					return err
+				}
+				_, err = fmt.Fprintf(out, "Imported %d version(s) from snapshot %s (%d already present, %d conflicting)\n",
+					result.Imported, restoredID, result.Present, len(result.Conflicts))
+				return err
+			}
+
+			result, err := uc.SnapshotTo(ctx, store, usecase.SnapshotToOptions{
+				Keep:          keep,
+				Filter:        filter,
+				FormatVersion: format,
+				SigningKey:    signKey,
+			})
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(out, "Wrote snapshot %s: %d key(s), %d version(s), %d new object(s)\n",
+				result.ID, result.Entries, result.Versions, result.Uploaded); err != nil {
+				return err
+			}
+			if len(result.Rotated) > 0 {
+				if _, err := fmt.Fprintf(out, "Removed %d old snapshot(s) and %d unreferenced object(s)\n", len(result.Rotated), result.Pruned); err != nil {
+					return err
+				}
+			}
+			if ghSummary {
+				return writeSnapshotGitHubSummary(to, result)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&to, "to", "", "Snapshot target (directory, file://, s3://, or gs:// URL) to write to")
+	cmd.Flags().StringVar(&from, "from", "", "Snapshot target (directory, file://, s3://, or gs:// URL) to restore from")
+	cmd.Flags().StringVar(&id, "id", "", "Snapshot to restore (default: the newest)")
+	cmd.Flags().IntVar(&keep, "keep", 0, "Keep only the newest N snapshots after writing (0 keeps all)")
+	cmd.Flags().StringVar(&keyGlob, "key-glob", "", `Only snapshot keys matching this glob (e.g. "adr/*")`)
+	cmd.Flags().StringVar(&since, "since", "", "Only snapshot versions written at or after this time (RFC3339, YYYY-MM-DD, or an age like 90d)")
+	cmd.Flags().BoolVar(&ghSummary, "github-summary", false, "Add the changed keys to $GITHUB_STEP_SUMMARY and set step outputs in $GITHUB_OUTPUT")
+	cmd.Flags().StringVar(&scopeType, "scope-type", "", "Only snapshot scopes of this type: global, repository, branch, or worktree")
+	cmd.Flags().StringVar(&signKey, "sign-key", "", "Sign the integrity manifest with this SSH key (private key, or public key held by ssh-agent)")
+	cmd.Flags().StringVar(&signers, "allowed-signers", "", "Require a valid signature from a key in this ssh-keygen allowed signers file")
+	cmd.Flags().IntVar(&format, "format-version", 0, fmt.Sprintf("Manifest format version to write, for older releases (1 to %d, default: current)", usecase.SnapshotFormatVersion))
+
+	return cmd
+}
+
+// printRestoreSkips reports on stderr the versions a restore did not import.
+// printIntegrity notes how a restored snapshot was verified.
+func printIntegrity(cmd *cobra.Command, id string, integrity *usecase.SnapshotIntegrity) error {
+	var err error
+	switch {
+	case integrity == nil:
+		_, err = fmt.Fprintf(cmd.ErrOrStderr(), "note: snapshot %s has no integrity manifest and was not verified\n", id)
+	case integrity.Signer != "":
+		_, err = fmt.Fprintf(cmd.ErrOrStderr(), "note: verified %d file(s), signed by %s\n", integrity.Files, integrity.Signer)
+	case integrity.Signed:
+		_, err = fmt.Fprintf(cmd.ErrOrStderr(), "note: verified %d file(s); the signature was not checked (pass --allowed-signers)\n", integrity.Files)
+	default:
+		_, err = fmt.Fprintf(cmd.ErrOrStderr(), "note: verified %d file(s) (unsigned)\n", integrity.Files)
+	}
+	return err
+}
+
+func printRestoreSkips(cmd *cobra.Command, result *usecase.RestoreResult) error {
+	for _, conflict := range result.Conflicts {
+		if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "conflict: %s differs locally; kept the local version\n", conflict); err != nil {
+			return err
+		}
+	}
+	for _, rejected := range result.Rejected {
+		if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "rejected: %s was written by a revoked device\n", rejected); err != nil {
+			return err
+		}
+	}
+	if result.FormatVersion > usecase.SnapshotFormatVersion {
+		if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "note: manifest format version %d is newer than this vault's (%d); fields it does not know were ignored\n",
+			result.FormatVersion, usecase.SnapshotFormatVersion); err != nil {
+			return err
+		}
+	}
+	if result.ScopeSnapshots > 0 {
+		if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "note: created %d scope snapshot(s)\n", result.ScopeSnapshots); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newSnapshotScopeCmd() *cobra.Command {
+	flags := &scopeFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "snapshot-scope",
+		Short: "Pin the current version of every key in a scope under a name",
+		Long: "Name the versions a scope holds right now, e.g. for a release, and read them again later with " +
+			"vault get --snapshot <name> however the keys change in the meantime. Only version numbers are " +
+			"recorded; deleting a pinned version makes it unreadable through the snapshot too. For copies of " +
+			"the content that can be restored elsewhere, see vault snapshot.",
+	}
+	flags.bind(cmd.PersistentFlags())
+
+	cmd.AddCommand(newSnapshotScopeCreateCmd(flags))
+	cmd.AddCommand(newSnapshotScopeListCmd(flags))
+	cmd.AddCommand(newSnapshotScopeShowCmd(flags))
+	cmd.AddCommand(newSnapshotScopeDeleteCmd(flags))
+	return cmd
+}
+
+func newSnapshotScopeCreateCmd(flags *scopeFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "create <name>",
+		Short: "Pin the latest version of every key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return flags.run(cmd, func(ctx context.Context, uc *usecase.Entry, sc scope.Scope) error {
+				record, err := uc.CreateScopeSnapshot(ctx, sc, args[0])
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintf(cmd.OutOrStdout(), "Created scope snapshot %s of %s (%d keys)\n", record.Name, scope.FormatScope(sc), record.KeyCount)
+				return err
+			})
+		},
+	}
+}
+
+type scopeSnapshotOutput struct {
+	Name      string           `json:"name"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Keys      int64            `json:"keys"`
+	Versions  map[string]int64 `json:"versions,omitempty"`
+}
+
+func newScopeSnapshotOutput(record database.ScopeSnapshotRecord, pinned []database.PinnedVersion) scopeSnapshotOutput {
+	output := scopeSnapshotOutput{Name: record.Name, CreatedAt: record.CreatedAt, Keys: record.KeyCount}
+	if pinned != nil {
+		output.Versions = make(map[string]int64, len(pinned))
+		for _, p := range pinned {
+			output.Versions[p.Key] = p.Version
+		}
+	}
+	return output
+}
+
+func newSnapshotScopeListCmd(flags *scopeFlags) *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the scope snapshots",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: table, json)", format)
+			}
+			return flags.run(cmd, func(ctx context.Context, uc *usecase.Entry, sc scope.Scope) error {
+				records, err := uc.ScopeSnapshots(ctx, sc)
+				if err != nil {
+					return err
+				}
+
+				if format == "json" {
+					output := make([]scopeSnapshotOutput, 0, len(records))
+					for _, r := range records {
+						output = append(output, newScopeSnapshotOutput(r, nil))
+					}
+					encoder := json.NewEncoder(cmd.OutOrStdout())
+					encoder.SetIndent("", "  ")
+					return encoder.Encode(output)
+				}
+
+				if len(records) == 0 {
+					_, err := fmt.Fprintf(cmd.OutOrStdout(), "No scope snapshots in %s\n", scope.FormatScope(sc))
+					return err
+				}
+				t := table.NewWriter()
+				t.SetOutputMirror(cmd.OutOrStdout())
+				t.SetStyle(table.StyleLight)
+				t.AppendHeader(table.Row{"Name", "Created", "Keys"})
+				for _, r := range records {
+					t.AppendRow(table.Row{r.Name, display.timestamp(r.CreatedAt), r.KeyCount})
+				}
+				t.Render()
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+	return cmd
+}
+
+func newSnapshotScopeShowCmd(flags *scopeFlags) *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "show <name>",
+		Short: "List the versions a scope snapshot pins",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: table, json)", format)
+			}
+			return flags.run(cmd, func(ctx context.Context, uc *usecase.Entry, sc scope.Scope) error {
+				snapshot, err := uc.ScopeSnapshot(ctx, sc, args[0])
+				if err != nil {
+					return err
+				}
+
+				if format == "json" {
+					encoder := json.NewEncoder(cmd.OutOrStdout())
+					encoder.SetIndent("", "  ")
+					return encoder.Encode(newScopeSnapshotOutput(snapshot.ScopeSnapshotRecord, snapshot.Versions))
+				}
+
+				t := table.NewWriter()
+				t.SetOutputMirror(cmd.OutOrStdout())
+				t.SetStyle(table.StyleLight)
+				t.AppendHeader(table.Row{"Key", "Version"})
+				for _, p := range snapshot.Versions {
+					t.AppendRow(table.Row{p.Key, p.Version})
+				}
+				t.Render()
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+	return cmd
+}
+
+func newSnapshotScopeDeleteCmd(flags *scopeFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a scope snapshot, keeping the versions it pinned",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return flags.run(cmd, func(ctx context.Context, uc *usecase.Entry, sc scope.Scope) error {
+				deleted, err := uc.DeleteScopeSnapshot(ctx, sc, args[0])
+				if err != nil {
+					return err
+				}
+				if !deleted {
+					return fmt.Errorf("scope snapshot not found: %s", args[0])
+				}
+				_, err = fmt.Fprintf(cmd.OutOrStdout(), "Deleted scope snapshot %s\n", args[0])
+				return err
+			})
+		},
+	}
+}
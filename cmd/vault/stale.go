@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+// staleColumns are the table columns of vault stale.
+const staleColumns = "scope,key,version,updated,last_read,size,description"
+
+func newStaleCmd() *cobra.Command {
+	var (
+		than       string
+		archive    bool
+		format     string
+		absolute   bool
+		scopeType  string
+		repoPath   string
+		branchName string
+		worktreeID string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "stale",
+		Short: "List entries that have not been written or read recently",
+		Long: "List entries whose latest version is older than --than, least recently written first, so outdated " +
+			"context can be refreshed or archived before an agent relies on it. With trackReads enabled in the " +
+			"config, entries read since --than are not stale. Without scope flags every scope is " +
+			"searched. --archive archives the listed entries; they stay in the vault and come back on the next write.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: table, json)", format)
+			}
+			now := time.Now()
+			before, err := parseTimeFlag(than, now)
+			if err != nil {
+				return fmt.Errorf("--than: %w", err)
+			}
+			if before.IsZero() {
+				return fmt.Errorf("--than must not be empty")
+			}
+
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			ctx := cmd.Context()
+			uc := usecase.NewEntry(dbCtx)
+			entries, err := uc.Stale(ctx, sc, usecase.StaleOptions{
+				Before:    before,
+				AllScopes: scopeType == "" && repoPath == "" && branchName == "" && worktreeID == "",
+			})
+			if err != nil {
+				return err
+			}
+			result := &usecase.ListResult{Entries: entries}
+
+			if format == "json" {
+				if err := outputJSON(cmd, result); err != nil {
+					return err
+				}
+			} else if len(entries) == 0 {
+				if _, err := fmt.Fprintf(cmd.OutOrStdout(), "No entries older than %s\n", than); err != nil {
+					return err
+				}
+			} else {
+				columns, err := parseListColumns(staleColumns)
+				if err != nil {
+					return err
+				}
+				outputColumnsTable(cmd, result, columns, listTimeFormat{absolute: absolute || ciMode, now: now})
+			}
+
+			if archive {
+				return archiveStale(ctx, cmd, uc, entries)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&than, "than", "90d", "Age or time before which an entry is stale (RFC3339, YYYY-MM-DD, or age like 90d)")
+	cmd.Flags().BoolVar(&archive, "archive", false, "Archive the stale entries")
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+	cmd.Flags().BoolVar(&absolute, "absolute", false, "Show dates and times instead of relative ages (\"2h ago\") in the table")
+	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
+	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
+	cmd.Flags().StringVar(&worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+
+	return cmd
+}
+
+// archiveStale archives entries and reports the count on stderr, keeping
+// stdout to the listing.
+func archiveStale(ctx context.Context, cmd *cobra.Command, uc *usecase.Entry, entries []usecase.ListEntry) error {
+	archived := 0
+	var failed []string
+	for _, entry := range entries {
+		ok, err := uc.Archive(ctx, entry.Scope, entry.Record.Key)
+		if err != nil {
+			return fmt.Errorf("archive %s: %w", entry.Record.Key, err)
+		}
+		if ok {
+			archived++
+		} else {
+			failed = append(failed, entry.Record.Key)
+		}
+	}
+	if len(failed) > 0 {
+		if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "note: already archived or deleted: %s\n", strings.Join(failed, ", ")); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(cmd.ErrOrStderr(), "Archived %d entries\n", archived)
+	return err
+}
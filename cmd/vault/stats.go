@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newStatsCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show vault usage",
+		Long: "Show how many keys and versions the vault stores across all scopes and their total size. " +
+			"When retention.keepVersions is set in the config file, also show how many versions " +
+			"and bytes fall outside the policy and could be pruned. When trackReads is set, also show " +
+			"how many keys have been read and which are read most.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: table, json)", format)
+			}
+
+			settings, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			stats, err := usecase.CollectStats(context.Background(), dbCtx, settings.RetentionKeepVersions(), settings.ShouldTrackReads())
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(stats)
+			}
+			return outputStatsTable(cmd, stats)
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+
+	return cmd
+}
+
+func outputStatsTable(cmd *cobra.Command, stats *usecase.VaultStats) error {
+	out := cmd.OutOrStdout()
+	fprintf := func(format string, args ...interface{}) error {
+		if _, err := fmt.Fprintf(out, format, args...); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	if err := fprintf("Keys:          %d\n", stats.Entries); err != nil {
+		return err
+	}
+	if err := fprintf("Versions:      %d\n", stats.Versions); err != nil {
+		return err
+	}
+	if err := fprintf("Total Size:    %d bytes\n", stats.TotalSize); err != nil {
+		return err
+	}
+
+	if stats.Retention == nil {
+		if err := fprintf("Retention:     none (set retention.keepVersions in %s)\n", config.GetConfigPath()); err != nil {
+			return err
+		}
+	} else {
+		if err := fprintf("Retention:     keep %d versions per key\n", stats.Retention.KeepVersions); err != nil {
+			return err
+		}
+		if err := fprintf("Reclaimable:   %d versions, %d bytes\n",
+			stats.Retention.ReclaimableVersions, stats.Retention.ReclaimableSize); err != nil {
+			return err
+		}
+	}
+
+	if stats.Reads == nil {
+		return nil
+	}
+	if err := fprintf("Reads:         %d (%d keys read, %d never read)\n",
+		stats.Reads.TotalReads, stats.Reads.ReadEntries, stats.Reads.UnreadEntries); err != nil {
+		return err
+	}
+	for i, r := range stats.Reads.MostRead {
+		label := ""
+		if i == 0 {
+			label = "Most Read:"
+		}
+		if err := fprintf("%-15s%s:%s (%d, last %s)\n", label, r.Scope, r.Key, r.Reads, display.timestamp(r.LastRead)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
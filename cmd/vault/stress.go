@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+// maxStressErrors caps how many operation errors a stress check lists.
+const maxStressErrors = 3
+
+func newStressCmd() *cobra.Command {
+	var (
+		writers int
+		seconds int
+		keys    int
+		format  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "stress",
+		Short: "Check concurrent writes against a throwaway vault",
+		Long: "Run concurrent writers against a temporary vault, each with its own database connection as " +
+			"separate agents would have, setting and reading back a few shared keys for the given time. " +
+			"Afterwards check that no two writes got the same version, every write is stored with its " +
+			"content, and no object file is missing or orphaned. Exits non-zero if any check fails. " +
+			"The user's vault is never touched, but the settings in the config file apply.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if writers <= 0 || seconds <= 0 || keys <= 0 {
+				return fmt.Errorf("--writers, --seconds, and --keys must be positive")
+			}
+			if format != "table" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: table, json)", format)
+			}
+
+			restore, err := useTempVault("vault-stress-")
+			if err != nil {
+				return err
+			}
+			defer restore()
+
+			if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "Running %d writers on %d keys for %ds...\n", writers, keys, seconds); err != nil {
+				return err
+			}
+			report, err := runStress(cmd.Context(), writers, keys, time.Duration(seconds)*time.Second)
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					return err
+				}
+			} else {
+				outputStressTable(cmd, report)
+			}
+
+			failed := 0
+			for _, check := range report.Checks {
+				if !check.OK {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d checks failed", failed, len(report.Checks))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&writers, "writers", 8, "Number of concurrent writers")
+	cmd.Flags().IntVar(&seconds, "seconds", 30, "How long to run")
+	cmd.Flags().IntVar(&keys, "keys", 4, "Number of keys the writers share; fewer keys mean more contention")
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+
+	return cmd
+}
+
+type stressCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+type stressReport struct {
+	Writers    int           `json:"writers"`
+	Keys       int           `json:"keys"`
+	Seconds    float64       `json:"seconds"`
+	Operations []benchStats  `json:"operations"`
+	Checks     []stressCheck `json:"checks"`
+}
+
+// stressWrite is a successful set as its writer saw it.
+type stressWrite struct {
+	key     string
+	version int64
+	content string
+}
+
+// stressWorker is what one writer did.
+type stressWorker struct {
+	writes       []stressWrite
+	setDurations []time.Duration
+	getDurations []time.Duration
+	setErrors    []error
+	getErrors    []error
+	mismatches   int
+}
+
+func runStress(ctx context.Context, writers, keyCount int, duration time.Duration) (*stressReport, error) {
+	// Create the database and run migrations once, before the writers race
+	// to open it; the connection then serves the checks.
+	dbCtx, err := database.CreateDatabase(config.GetDBPath())
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = database.CloseDatabase(dbCtx)
+	}()
+
+	keys := make([]string, keyCount)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("stress/key-%d", i)
+	}
+
+	workers := make([]stressWorker, writers)
+	deadline := time.Now().Add(duration)
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Go(func() {
+			runStressWorker(ctx, w, keys, deadline, &workers[w])
+		})
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	var (
+		all          []stressWrite
+		setDurations []time.Duration
+		getDurations []time.Duration
+		setErrors    []error
+		getErrors    []error
+		mismatches   int
+	)
+	for _, w := range workers {
+		all = append(all, w.writes...)
+		setDurations = append(setDurations, w.setDurations...)
+		getDurations = append(getDurations, w.getDurations...)
+		setErrors = append(setErrors, w.setErrors...)
+		getErrors = append(getErrors, w.getErrors...)
+		mismatches += w.mismatches
+	}
+
+	checks := []stressCheck{
+		errorsCheck("writes succeed", setErrors, len(setDurations)),
+		uniqueVersionsCheck(all),
+	}
+	stored, err := storedWritesCheck(ctx, usecase.NewEntry(dbCtx), all)
+	if err != nil {
+		return nil, err
+	}
+	checks = append(checks, stored, readBackCheck(getErrors, mismatches, len(getDurations)))
+	for _, c := range usecase.CheckConsistency(ctx, dbCtx) {
+		checks = append(checks, stressCheck{Name: c.Name, OK: c.Status == usecase.CheckOK, Detail: c.Message})
+	}
+
+	return &stressReport{
+		Writers: writers,
+		Keys:    keyCount,
+		Seconds: elapsed.Seconds(),
+		Operations: []benchStats{
+			summarizeDurations("set", setDurations),
+			summarizeDurations("get", getDurations),
+		},
+		Checks: checks,
+	}, nil
+}
+
+// runStressWorker sets random keys until the deadline, reading each
+// version back right after writing it.
+func runStressWorker(ctx context.Context, id int, keys []string, deadline time.Time, w *stressWorker) {
+	dbCtx, err := database.CreateDatabase(config.GetDBPath())
+	if err != nil {
+		w.setErrors = append(w.setErrors, err)
+		return
+	}
+	defer func() {
+		_ = database.CloseDatabase(dbCtx)
+	}()
+
+	uc := usecase.NewEntry(dbCtx)
+	sc := scope.NewGlobal()
+	rng := rand.New(rand.NewPCG(uint64(id), uint64(time.Now().UnixNano()))) //nolint:gosec // G115,G404: workload randomness, not security
+
+	for n := 0; time.Now().Before(deadline) && ctx.Err() == nil; n++ {
+		key := keys[rng.IntN(len(keys))]
+		content := fmt.Sprintf("writer %d write %d\n", id, n)
+
+		start := time.Now()
+		result, err := uc.Set(ctx, sc, key, content, nil)
+		w.setDurations = append(w.setDurations, time.Since(start))
+		if err != nil {
+			w.setErrors = append(w.setErrors, fmt.Errorf("set %s: %w", key, err))
+			continue
+		}
+		w.writes = append(w.writes, stressWrite{key: key, version: result.Version, content: content})
+
+		version := int(result.Version)
+		start = time.Now()
+		got, err := uc.Get(ctx, sc, key, &usecase.GetOptions{Version: &version})
+		if err == nil {
+			var read string
+			read, err = filesystem.ReadFile(got.Record.FilePath)
+			if err == nil && read != content {
+				w.mismatches++
+			}
+		}
+		w.getDurations = append(w.getDurations, time.Since(start))
+		if err != nil {
+			w.getErrors = append(w.getErrors, fmt.Errorf("get %s version %d: %w", key, version, err))
+		}
+	}
+}
+
+func errorsCheck(name string, errs []error, total int) stressCheck {
+	if len(errs) == 0 {
+		return stressCheck{Name: name, OK: true, Detail: fmt.Sprintf("%d operations", total)}
+	}
+	detail := fmt.Sprintf("%d of %d failed", len(errs), total)
+	for i, err := range errs {
+		if i == maxStressErrors {
+			detail += "; ..."
+			break
+		}
+		detail += "; " + err.Error()
+	}
+	return stressCheck{Name: name, Detail: detail}
+}
+
+// uniqueVersionsCheck fails if two writers were told they wrote the same
+// version of a key.
+func uniqueVersionsCheck(writes []stressWrite) stressCheck {
+	seen := make(map[string]bool, len(writes))
+	duplicates := 0
+	for _, w := range writes {
+		id := fmt.Sprintf("%s@%d", w.key, w.version)
+		if seen[id] {
+			duplicates++
+		}
+		seen[id] = true
+	}
+	if duplicates > 0 {
+		return stressCheck{Name: "unique versions", Detail: fmt.Sprintf("%d versions reported by more than one write", duplicates)}
+	}
+	return stressCheck{Name: "unique versions", OK: true, Detail: fmt.Sprintf("%d versions", len(writes))}
+}
+
+// storedWritesCheck fails if a reported write is missing from the index or
+// holds other content, or if the index has versions no writer reported.
+func storedWritesCheck(ctx context.Context, uc *usecase.Entry, writes []stressWrite) (stressCheck, error) {
+	list, err := uc.List(ctx, scope.NewGlobal(), &usecase.ListOptions{AllVersions: true, IncludeArchived: true})
+	if err != nil {
+		return stressCheck{}, err
+	}
+	hashes := make(map[string]string, len(list.Entries))
+	for _, e := range list.Entries {
+		hashes[fmt.Sprintf("%s@%d", e.Record.Key, e.Record.Version)] = e.Record.Hash
+	}
+
+	missing, wrong := 0, 0
+	for _, w := range writes {
+		hash, ok := hashes[fmt.Sprintf("%s@%d", w.key, w.version)]
+		switch {
+		case !ok:
+			missing++
+		case hash != filesystem.HashContent(w.content):
+			wrong++
+		}
+	}
+	extra := len(hashes) - (len(writes) - missing)
+
+	if missing > 0 || wrong > 0 || extra != 0 {
+		return stressCheck{
+			Name:   "writes stored",
+			Detail: fmt.Sprintf("%d missing, %d with other content, %d unreported versions", missing, wrong, extra),
+		}, nil
+	}
+	return stressCheck{Name: "writes stored", OK: true, Detail: fmt.Sprintf("%d versions in the index", len(hashes))}, nil
+}
+
+func readBackCheck(errs []error, mismatches, total int) stressCheck {
+	check := errorsCheck("reads return the written content", errs, total)
+	if mismatches > 0 {
+		check.OK = false
+		check.Detail = fmt.Sprintf("%d reads returned other content; %s", mismatches, check.Detail)
+	}
+	return check
+}
+
+func outputStressTable(cmd *cobra.Command, report *stressReport) {
+	outputOperationsTable(cmd, report.Operations)
+
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(table.Row{"Check", "Result", "Detail"})
+	for _, check := range report.Checks {
+		result := "ok"
+		if !check.OK {
+			result = "FAIL"
+		}
+		t.AppendRow(table.Row{check.Name, result, check.Detail})
+	}
+	t.Render()
+}
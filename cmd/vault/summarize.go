@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/summarizer"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newSummarizeCmd() *cobra.Command {
+	var (
+		versionFlag int
+		missing     bool
+		command     string
+		scopeType   string
+		repoPath    string
+		branchName  string
+		worktreeID  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "summarize [key]",
+		Short: "Generate and store a summary of an entry",
+		Long: "Run the configured summarizer (summarizer.command in the config file, or --with) over a version " +
+			"of an entry and store its output as that version's summary, replacing any earlier one. The command " +
+			"reads the content on stdin with VAULT_KEY set, and prints the summary. With --missing, summarize " +
+			"the latest version of every entry in the scope that has none yet and is at least summarizer.minSize " +
+			"bytes. New versions are summarized as they are written once a summarizer is configured.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if missing == (len(args) == 1) {
+				return fmt.Errorf("specify either a key or --missing")
+			}
+			if missing && cmd.Flags().Changed("version") {
+				return fmt.Errorf("--version cannot be combined with --missing")
+			}
+
+			sc, err := scope.ResolveScope(cmd.Context(), scope.ScopeOptions{
+				Type:     scopeType,
+				Repo:     repoPath,
+				Branch:   branchName,
+				Worktree: worktreeID,
+			})
+			if err != nil {
+				return err
+			}
+
+			settings, err := config.Load()
+			if err != nil {
+				return err
+			}
+			if command == "" {
+				command = settings.SummarizerCommand()
+			}
+			if command == "" {
+				return fmt.Errorf("no summarizer configured: set summarizer.command in %s or pass --with", config.GetConfigPath())
+			}
+			summarize, err := summarizer.New(command, settings.SummarizeMinSize())
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			ctx := cmd.Context()
+			uc := usecase.NewEntry(dbCtx)
+
+			if !missing {
+				var opts *usecase.GetOptions
+				if cmd.Flags().Changed("version") {
+					version := versionFlag
+					opts = &usecase.GetOptions{Version: &version}
+				}
+				result, err := uc.Summarize(ctx, sc, args[0], opts, summarize)
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintln(cmd.OutOrStdout(), result.Summary)
+				return err
+			}
+
+			list, err := uc.List(ctx, sc, nil)
+			if err != nil {
+				return err
+			}
+			count := 0
+			for _, entry := range list.Entries {
+				if entry.Record.Summary != "" || entry.Record.Size < int64(summarize.MinSize) {
+					continue
+				}
+				version := int(entry.Record.Version)
+				if _, err := uc.Summarize(ctx, sc, entry.Record.Key, &usecase.GetOptions{Version: &version}, summarize); err != nil {
+					return fmt.Errorf("%s: %w", entry.Record.Key, err)
+				}
+				count++
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Summarized %d entries\n", count)
+			return err
+		},
+	}
+
+	cmd.Flags().IntVarP(&versionFlag, "version", "v", 0, "Version to summarize (default: latest)")
+	cmd.Flags().BoolVar(&missing, "missing", false, "Summarize every entry in the scope whose latest version has no summary")
+	cmd.Flags().StringVar(&command, "with", "", "Summarizer command line to use instead of the configured one")
+	cmd.Flags().StringVar(&scopeType, "scope", "", "Scope type: global, repository, branch, or worktree")
+	cmd.Flags().StringVar(&repoPath, "repo", "", "Repository path for repository/branch/worktree scopes")
+	cmd.Flags().StringVar(&branchName, "branch", "", "Branch name (requires --scope branch)")
+	cmd.Flags().StringVar(&worktreeID, "worktree", "", "Worktree id (requires --scope worktree)")
+
+	return cmd
+}
+
+// loadSummarizer returns the configured summarizer for writes, or nil when
+// none is configured or disabled is set.
+func loadSummarizer(disabled bool) (*summarizer.Command, error) {
+	if disabled {
+		return nil, nil
+	}
+	settings, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	return summarizer.FromSettings(settings)
+}
+
+// warnSummaryFailure reports on stderr that a write succeeded without the
+// summary that was asked for.
+func warnSummaryFailure(cmd *cobra.Command, key string, result *usecase.SetResult) error {
+	if result.SummaryErr == nil {
+		return nil
+	}
+	_, err := fmt.Fprintf(cmd.ErrOrStderr(), "note: saved %s version %d without a summary: %v\n", key, result.Version, result.SummaryErr)
+	return err
+}
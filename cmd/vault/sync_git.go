@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/git"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newSyncGitCmd() *cobra.Command {
+	var (
+		remote  string
+		branch  string
+		repoDir string
+		restore bool
+		format  int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sync-git",
+		Short: "Sync the vault through a dedicated git branch",
+		Long: "Commit a snapshot of the whole vault (a manifest plus content files) to a dedicated branch " +
+			"and push it, or with --restore fetch the branch and import the versions missing locally.\n\n" +
+			"The branch never touches the working tree or index. A push is refused while the remote branch " +
+			"holds versions this vault lacks; restore first. Scopes are matched by their recorded paths, so " +
+			"repository scopes only line up between machines that use the same checkout paths. With syncKeyFile " +
+			"set in the config (see sync-key), the manifest and content are encrypted before they are committed.\n\n" +
+			"--format-version 1 commits a manifest older releases can restore, leaving out version metadata, " +
+			"parent links, and scope information.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if restore && format != 0 {
+				return fmt.Errorf("--format-version does not apply to --restore")
+			}
+			if err := usecase.ValidateSnapshotFormatVersion(format); err != nil {
+				return err
+			}
+
+			topLevel, err := git.TopLevel(cmd.Context(), repoDir)
+			if err != nil {
+				return fmt.Errorf("sync-git needs a git repository; run it inside one or pass --git-repo: %w", err)
+			}
+
+			syncCipher, err := loadSyncCipher()
+			if err != nil {
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			opts := usecase.GitSyncOptions{
+				RepoDir:       topLevel,
+				Branch:        branch,
+				Remote:        remote,
+				Cipher:        syncCipher,
+				FormatVersion: format,
+			}
+			uc := usecase.NewEntry(dbCtx)
+			ctx := cmd.Context()
+			out := cmd.OutOrStdout()
+
+			if restore {
+				result, err := uc.RestoreFromGit(ctx, opts)
+				if err != nil {
+					return err
+				}
+				if err := printRestoreSkips(cmd, &result.RestoreResult); err != nil {
+					return err
+				}
+				_, err = fmt.Fprintf(out, "Imported %d version(s) from %s at %s (%d already present, %d conflicting)\n",
+					result.Imported, branch, shortCommit(result.Commit), result.Present, len(result.Conflicts))
+				return err
+			}
+
+			result, err := uc.PushToGit(ctx, opts)
+			if err != nil {
+				return err
+			}
+			if !result.Changed {
+				_, err = fmt.Fprintf(out, "%s is already up to date at %s\n", branch, shortCommit(result.Commit))
+				return err
+			}
+			target := branch
+			if remote != "" {
+				target = remote + "/" + branch
+			}
+			_, err = fmt.Fprintf(out, "Committed %d key(s), %d version(s) to %s at %s\n",
+				result.Entries, result.Versions, target, shortCommit(result.Commit))
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&remote, "remote", "origin", `Git remote to fetch from and push to ("" to keep the branch local)`)
+	cmd.Flags().StringVar(&branch, "branch", "vault-data", "Branch holding the vault snapshots")
+	cmd.Flags().StringVar(&repoDir, "git-repo", "", "Git repository to use (default: the current one)")
+	cmd.Flags().BoolVar(&restore, "restore", false, "Import missing versions from the branch instead of pushing")
+	cmd.Flags().IntVar(&format, "format-version", 0, fmt.Sprintf("Manifest format version to commit, for older releases (1 to %d, default: current)", usecase.SnapshotFormatVersion))
+
+	return cmd
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/snapshot"
+)
+
+func newSyncKeyCmd() *cobra.Command {
+	var (
+		recipients     []string
+		recipientFiles []string
+		outputPath     string
+		importPath     string
+		identityPath   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sync-key [path]",
+		Short: "Generate a key that encrypts sync-git and snapshot data",
+		Long: "Write a new random sync key to path (default: sync.key next to the config file). Set syncKeyFile " +
+			"in the config to that path and sync-git and snapshot encrypt every manifest and content object " +
+			"they write, so the remote never sees plaintext.\n\n" +
+			"Every machine syncing through the same remote needs the same key. --recipient (or --recipients-file) " +
+			"also writes the key encrypted to those age recipients, to path.age unless -o is given, creating the " +
+			"key first if path does not exist yet. On the receiving machine, --import installs it with the " +
+			"matching age identity file given by --identity; `age -d` recovers the key file as well.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := filepath.Join(filepath.Dir(config.GetConfigPath()), "sync.key")
+			if len(args) == 1 {
+				path = args[0]
+			}
+
+			sharing := len(recipients) > 0 || len(recipientFiles) > 0
+			if importPath != "" {
+				if sharing || outputPath != "" {
+					return fmt.Errorf("--import cannot be combined with --recipient, --recipients-file, or --output")
+				}
+				return importSyncKey(cmd, path, importPath, identityPath)
+			}
+			if identityPath != "" {
+				return fmt.Errorf("--identity requires --import")
+			}
+			if !sharing {
+				if outputPath != "" {
+					return fmt.Errorf("--output requires --recipient or --recipients-file")
+				}
+				return generateSyncKey(cmd, path)
+			}
+
+			for _, file := range recipientFiles {
+				//nolint:gosec // G304: recipients file is chosen by the user
+				data, err := os.ReadFile(file)
+				if err != nil {
+					return fmt.Errorf("failed to read recipients file: %w", err)
+				}
+				recipients = append(recipients, strings.Split(string(data), "\n")...)
+			}
+			if err := snapshot.ValidateRecipients(recipients); err != nil {
+				return err
+			}
+			if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+				if err := generateSyncKey(cmd, path); err != nil {
+					return err
+				}
+			}
+			shared, err := snapshot.ShareKey(path, recipients)
+			if err != nil {
+				return err
+			}
+			if outputPath == "" {
+				outputPath = path + ".age"
+			}
+			if err := os.WriteFile(outputPath, shared, 0o600); err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Wrote the sync key encrypted to the age recipients to %s\n"+
+				"Install it on each machine with: vault sync-key --import %s --identity <age identity file>\n",
+				outputPath, filepath.Base(outputPath))
+			return err
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&recipients, "recipient", "r", nil, "Also write the key encrypted to this age recipient (age1...); repeatable")
+	cmd.Flags().StringArrayVarP(&recipientFiles, "recipients-file", "R", nil, "Also write the key encrypted to the age recipients listed in this file; repeatable")
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "File for the encrypted key (default: path.age)")
+	cmd.Flags().StringVar(&importPath, "import", "", "Install a key shared with --recipient instead of generating one")
+	cmd.Flags().StringVarP(&identityPath, "identity", "i", "", "age identity file that decrypts the key given to --import")
+
+	return cmd
+}
+
+func generateSyncKey(cmd *cobra.Command, path string) error {
+	if err := snapshot.GenerateKey(path); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(cmd.OutOrStdout(), "Wrote a new sync key to %s\nSet \"syncKeyFile\": %q in %s to encrypt sync data with it.\n",
+		path, path, config.GetConfigPath())
+	return err
+}
+
+func importSyncKey(cmd *cobra.Command, path, sharedPath, identityPath string) error {
+	if identityPath == "" {
+		return fmt.Errorf("--import requires --identity, the age identity file the key was shared with")
+	}
+	//nolint:gosec // G304: shared key file is chosen by the user
+	shared, err := os.ReadFile(sharedPath)
+	if err != nil {
+		return err
+	}
+	//nolint:gosec // G304: identity file is chosen by the user
+	identities, err := os.ReadFile(identityPath)
+	if err != nil {
+		return fmt.Errorf("failed to read age identity file: %w", err)
+	}
+	if err := snapshot.ReceiveKey(shared, identities, path); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "Installed the shared sync key at %s\nSet \"syncKeyFile\": %q in %s to encrypt sync data with it.\n",
+		path, path, config.GetConfigPath())
+	return err
+}
+
+// loadSyncCipher returns the cipher for the configured sync key, or nil
+// when sync data is not encrypted.
+func loadSyncCipher() (*snapshot.Cipher, error) {
+	settings, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	var c *snapshot.Cipher
+	if path := settings.SyncKeyPath(); path != "" {
+		key, err := snapshot.LoadKey(path)
+		if err != nil {
+			return nil, err
+		}
+		if c, err = snapshot.NewCipher(key); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
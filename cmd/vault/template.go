@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+// templateEscapes turns the escapes users type inside single quotes into
+// the characters they mean, so '{{.Key}}\t{{.Version}}' prints a tab.
+var templateEscapes = strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\\`, `\`)
+
+// templateFuncs are available to --template in addition to the text/template
+// builtins.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join": strings.Join,
+}
+
+// parseOutputTemplate parses a --template value. Fields are those of the
+// command's --format json output, addressed by Go field name (see vault
+// schema for the list).
+func parseOutputTemplate(cmd *cobra.Command, text string) (*template.Template, error) {
+	if cmd.Flags().Changed("format") {
+		return nil, fmt.Errorf("specify only one of --format or --template")
+	}
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(templateEscapes.Replace(text))
+	if err != nil {
+		return nil, fmt.Errorf("invalid --template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// templateWriter executes a --template once per output item, ending each
+// with a newline.
+type templateWriter struct {
+	tmpl *template.Template
+	out  *bufio.Writer
+}
+
+func newTemplateWriter(w io.Writer, tmpl *template.Template) *templateWriter {
+	return &templateWriter{tmpl: tmpl, out: bufio.NewWriter(w)}
+}
+
+func (w *templateWriter) Write(item any) error {
+	if err := w.tmpl.Execute(w.out, item); err != nil {
+		return err
+	}
+	return w.out.WriteByte('\n')
+}
+
+func (w *templateWriter) Flush() error {
+	return w.out.Flush()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// perCallTimeout is the annotation of commands that serve requests until
+// stopped, such as mcp. They apply --timeout to each request themselves
+// instead of to their whole run.
+const perCallTimeout = "perCallTimeout"
+
+// applyTimeout bounds the command's context by the --timeout flag. Database
+// queries, git invocations, and long scans of the object store stop once
+// it expires.
+func applyTimeout(cmd *cobra.Command) error {
+	timeout, err := timeoutFlag(cmd)
+	if err != nil || timeout == 0 || cmd.Annotations[perCallTimeout] != "" {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+	cmd.SetContext(ctx)
+	cobra.OnFinalize(cancel)
+	return nil
+}
+
+func timeoutFlag(cmd *cobra.Command) (time.Duration, error) {
+	timeout, err := cmd.Flags().GetDuration("timeout")
+	if err != nil {
+		return 0, err
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("--timeout must not be negative")
+	}
+	return timeout, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func newUndoCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "undo",
+		Short: "Revert the last set, delete, or archive",
+		Long: "Revert the last mutating command run from this machine: a set's version is deleted, the " +
+			"versions a delete removed are written back under their numbers, and keys archived by vault " +
+			"archive are restored. Only the last operation is kept, and undoing clears it. A set is not " +
+			"undone once the key has a newer version. --dry-run shows what would be undone.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if dryRun {
+				record, err := usecase.LastUndo()
+				if err != nil {
+					return undoError(err)
+				}
+				_, err = fmt.Fprintf(cmd.OutOrStdout(), "Would undo %s (%s)\n", record.Describe(), display.timestamp(record.Time))
+				return err
+			}
+
+			dbCtx, err := database.CreateDatabase("")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = database.CloseDatabase(dbCtx)
+			}()
+
+			record, err := usecase.NewEntry(dbCtx).Undo(cmd.Context())
+			if err != nil {
+				return undoError(err)
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Undid %s\n", record.Describe())
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show the operation that would be undone")
+
+	return cmd
+}
+
+func undoError(err error) error {
+	if errors.Is(err, usecase.ErrNothingToUndo) {
+		return fmt.Errorf("nothing to undo: set, delete, and archive record the last operation")
+	}
+	return err
+}
+
+// recordUndo journals record for vault undo. A failure only costs the undo,
+// so it is reported instead of failing the command that already succeeded.
+func recordUndo(cmd *cobra.Command, record *usecase.UndoRecord) error {
+	if record == nil {
+		return nil
+	}
+	if err := usecase.RecordUndo(record); err != nil {
+		_, werr := fmt.Fprintf(cmd.ErrOrStderr(), "note: could not record the operation for vault undo: %v\n", err)
+		return werr
+	}
+	return nil
+}
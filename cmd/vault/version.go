@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+// buildInfo describes how the running binary was built.
+type buildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+}
+
+// versionReport is the output of vault version.
+type versionReport struct {
+	Build   buildInfo            `json:"build"`
+	Storage *usecase.StorageInfo `json:"storage,omitempty"`
+}
+
+func newVersionCmd() *cobra.Command {
+	var (
+		format    string
+		buildOnly bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Show version, build, and storage details",
+		Long: "Show the vault version with the commit and date it was built from, the Go toolchain, the vault " +
+			"directory, the database schema version, and the size of the object store. Include this output in " +
+			"bug reports. --build-only skips opening the database.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("invalid format: %s (valid values: table, json)", format)
+			}
+
+			report := versionReport{Build: currentBuildInfo()}
+			if !buildOnly {
+				dbCtx, err := database.CreateDatabase("")
+				if err != nil {
+					return err
+				}
+				defer func() {
+					_ = database.CloseDatabase(dbCtx)
+				}()
+
+				if report.Storage, err = usecase.CollectStorageInfo(context.Background(), dbCtx); err != nil {
+					return err
+				}
+			}
+
+			if format == "json" {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(report)
+			}
+			return outputVersionTable(cmd, &report)
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table or json")
+	cmd.Flags().BoolVar(&buildOnly, "build-only", false, "Show only build details")
+
+	return cmd
+}
+
+// currentBuildInfo combines the ldflags set by release builds with the VCS
+// details the Go toolchain stamps into binaries built from a checkout.
+func currentBuildInfo() buildInfo {
+	info := buildInfo{
+		Version:   version,
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	stamped := false
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			stamped = true
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			if info.Date == "" {
+				info.Date = setting.Value
+			}
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	// go install module@version builds without VCS details but records
+	// the module version; checkouts get a pseudo-version we don't want.
+	if info.Version == "dev" && !stamped && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+	return info
+}
+
+func outputVersionTable(cmd *cobra.Command, report *versionReport) error {
+	out := cmd.OutOrStdout()
+	fprintf := func(format string, args ...interface{}) error {
+		if _, err := fmt.Fprintf(out, format, args...); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	build := report.Build
+	if err := fprintf("Version:       %s\n", build.Version); err != nil {
+		return err
+	}
+	commitText := build.Commit
+	if commitText == "" {
+		commitText = "unknown"
+	} else if build.Modified {
+		commitText += " (modified)"
+	}
+	if err := fprintf("Commit:        %s\n", commitText); err != nil {
+		return err
+	}
+	dateText := build.Date
+	if dateText == "" {
+		dateText = "unknown"
+	}
+	if err := fprintf("Built:         %s\n", dateText); err != nil {
+		return err
+	}
+	if err := fprintf("Go:            %s %s\n", build.GoVersion, build.Platform); err != nil {
+		return err
+	}
+
+	storage := report.Storage
+	if storage == nil {
+		return nil
+	}
+	if err := fprintf("Vault Dir:     %s\n", storage.VaultDir); err != nil {
+		return err
+	}
+	if err := fprintf("Database:      %s\n", storage.DatabasePath); err != nil {
+		return err
+	}
+	schema := fmt.Sprintf("%d", storage.SchemaVersion)
+	if storage.SchemaVersion != storage.LatestSchemaVersion {
+		schema += fmt.Sprintf(" (newer than this build, which knows up to %d)", storage.LatestSchemaVersion)
+	}
+	if err := fprintf("Schema:        %s\n", schema); err != nil {
+		return err
+	}
+	if err := fprintf("Keys:          %d\n", storage.Entries); err != nil {
+		return err
+	}
+	if err := fprintf("Versions:      %d\n", storage.Versions); err != nil {
+		return err
+	}
+	return fprintf("Objects:       %d files, %d bytes, %s layout\n", storage.ObjectFiles, storage.ObjectBytes, storage.ObjectLayout)
+}
@@ -0,0 +1,2 @@
+ALTER TABLE versions DROP COLUMN verified_size;
+ALTER TABLE versions DROP COLUMN verified_mtime;
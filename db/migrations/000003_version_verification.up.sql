@@ -0,0 +1,2 @@
+ALTER TABLE versions ADD COLUMN verified_mtime INTEGER;
+ALTER TABLE versions ADD COLUMN verified_size INTEGER;
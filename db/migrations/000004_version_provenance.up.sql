@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS version_provenance (
+    version_id INTEGER PRIMARY KEY REFERENCES versions (id) ON DELETE CASCADE,
+    tool TEXT,
+    hostname TEXT,
+    git_branch TEXT,
+    git_commit TEXT,
+    git_dirty INTEGER
+);
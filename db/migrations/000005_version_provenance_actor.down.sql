@@ -0,0 +1 @@
+ALTER TABLE version_provenance DROP COLUMN actor;
@@ -0,0 +1 @@
+ALTER TABLE version_provenance ADD COLUMN actor TEXT;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS devices;
+ALTER TABLE version_provenance DROP COLUMN device_id;
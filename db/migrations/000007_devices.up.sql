@@ -0,0 +1,8 @@
+ALTER TABLE version_provenance ADD COLUMN device_id TEXT;
+
+CREATE TABLE IF NOT EXISTS devices (
+    device_id TEXT PRIMARY KEY,
+    hostname TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP
+);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS version_approvals (
+    version_id INTEGER PRIMARY KEY REFERENCES versions (id) ON DELETE CASCADE,
+    approved_by TEXT,
+    approved_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS version_summaries;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS version_summaries (
+    version_id INTEGER PRIMARY KEY REFERENCES versions (id) ON DELETE CASCADE,
+    summary TEXT NOT NULL,
+    summarizer TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+ALTER TABLE entry_status DROP COLUMN read_count;
+ALTER TABLE entry_status DROP COLUMN last_read_at;
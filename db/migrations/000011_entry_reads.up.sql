@@ -0,0 +1,2 @@
+ALTER TABLE entry_status ADD COLUMN last_read_at TIMESTAMP;
+ALTER TABLE entry_status ADD COLUMN read_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE scopes DROP COLUMN metadata;
+ALTER TABLE scopes DROP COLUMN description;
@@ -0,0 +1,2 @@
+ALTER TABLE scopes ADD COLUMN description TEXT;
+ALTER TABLE scopes ADD COLUMN metadata TEXT;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS entry_locks (
+    scope_id INTEGER NOT NULL REFERENCES scopes (id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    owner TEXT NOT NULL,
+    acquired_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (scope_id, key)
+);
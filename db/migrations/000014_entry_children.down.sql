@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_entry_children_parent;
+DROP TABLE IF EXISTS entry_children;
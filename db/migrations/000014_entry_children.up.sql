@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS entry_children (
+    scope_id INTEGER NOT NULL REFERENCES scopes (id) ON DELETE CASCADE,
+    child_key TEXT NOT NULL,
+    parent_key TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (scope_id, child_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_entry_children_parent ON entry_children (scope_id, parent_key, position);
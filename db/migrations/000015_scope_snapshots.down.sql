@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS scope_snapshot_versions;
+DROP TABLE IF EXISTS scope_snapshots;
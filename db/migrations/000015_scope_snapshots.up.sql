@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS scope_snapshots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    scope_id INTEGER NOT NULL REFERENCES scopes (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (scope_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS scope_snapshot_versions (
+    snapshot_id INTEGER NOT NULL REFERENCES scope_snapshots (id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    PRIMARY KEY (snapshot_id, key)
+);
@@ -0,0 +1,34 @@
+-- name: ApproveVersion :execrows
+INSERT INTO version_approvals (version_id, approved_by)
+SELECT v.id, CAST(sqlc.arg(approved_by) AS TEXT)
+FROM versions v
+JOIN entries e ON e.id = v.entry_id
+WHERE e.scope_id = sqlc.arg(scope_id)
+  AND e.key = sqlc.arg(key)
+  AND v.version = sqlc.arg(version)
+ON CONFLICT (version_id) DO NOTHING;
+
+-- name: GetLatestApprovedVersion :one
+SELECT v.version
+FROM version_approvals a
+JOIN versions v ON v.id = a.version_id
+JOIN entries e ON e.id = v.entry_id
+WHERE e.scope_id = ? AND e.key = ?
+ORDER BY v.version DESC
+LIMIT 1;
+
+-- name: ImportVersionApproval :execrows
+INSERT INTO version_approvals (version_id, approved_by, approved_at)
+SELECT v.id, CAST(sqlc.arg(approved_by) AS TEXT), CAST(sqlc.arg(approved_at) AS TEXT)
+FROM versions v
+JOIN entries e ON e.id = v.entry_id
+WHERE e.scope_id = sqlc.arg(scope_id)
+  AND e.key = sqlc.arg(key)
+  AND v.version = sqlc.arg(version)
+ON CONFLICT (version_id) DO NOTHING;
+
+-- name: ListVersionApprovalsByEntry :many
+SELECT a.version_id, a.approved_by, a.approved_at
+FROM version_approvals a
+JOIN versions v ON v.id = a.version_id
+WHERE v.entry_id = ?;
@@ -0,0 +1,26 @@
+-- name: DeleteEntryParent :execrows
+DELETE FROM entry_children
+WHERE scope_id = ? AND child_key = ?;
+
+-- name: GetEntryParent :one
+SELECT scope_id, child_key, parent_key, position
+FROM entry_children
+WHERE scope_id = ? AND child_key = ?;
+
+-- name: ListEntryChildren :many
+SELECT scope_id, child_key, parent_key, position
+FROM entry_children
+WHERE scope_id = ? AND parent_key = ?
+ORDER BY position, child_key;
+
+-- name: MaxChildPosition :one
+SELECT CAST(COALESCE(MAX(position), 0) AS INTEGER) AS max_position
+FROM entry_children
+WHERE scope_id = ? AND parent_key = ?;
+
+-- name: UpsertEntryParent :exec
+INSERT INTO entry_children (scope_id, child_key, parent_key, position)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (scope_id, child_key) DO UPDATE SET
+    parent_key = excluded.parent_key,
+    position = excluded.position;
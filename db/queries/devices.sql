@@ -0,0 +1,35 @@
+-- name: ListDevices :many
+SELECT
+    d.device_id,
+    d.hostname,
+    d.created_at,
+    d.revoked_at,
+    COUNT(p.version_id) AS version_count,
+    COALESCE(CAST(MAX(v.created_at) AS TEXT), '') AS last_write_at
+FROM devices d
+LEFT JOIN version_provenance p ON p.device_id = d.device_id
+LEFT JOIN versions v ON v.id = p.version_id
+GROUP BY d.device_id
+ORDER BY d.created_at, d.device_id;
+
+-- name: ListRevokedDevices :many
+SELECT device_id
+FROM devices
+WHERE revoked_at IS NOT NULL
+ORDER BY device_id;
+
+-- name: ListVersionDevices :many
+SELECT v.entry_id, v.version, p.device_id
+FROM version_provenance p
+JOIN versions v ON v.id = p.version_id
+WHERE p.device_id IS NOT NULL;
+
+-- name: RevokeDevice :exec
+INSERT INTO devices (device_id, revoked_at)
+VALUES (?1, CURRENT_TIMESTAMP)
+ON CONFLICT (device_id) DO UPDATE SET revoked_at = COALESCE(devices.revoked_at, CURRENT_TIMESTAMP);
+
+-- name: UpsertDevice :exec
+INSERT INTO devices (device_id, hostname)
+VALUES (?1, ?2)
+ON CONFLICT (device_id) DO UPDATE SET hostname = COALESCE(excluded.hostname, devices.hostname);
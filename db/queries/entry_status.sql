@@ -1,5 +1,5 @@
 -- name: FindEntryStatusByEntryID :one
-SELECT entry_id, is_archived, current_version, updated_at
+SELECT entry_id, is_archived, current_version, updated_at, last_read_at, read_count
 FROM entry_status
 WHERE entry_id = ?
 LIMIT 1;
@@ -23,3 +23,9 @@ WHERE entry_id = ?;
 -- name: DeleteEntryStatus :execrows
 DELETE FROM entry_status
 WHERE entry_id = ?;
+
+-- name: RecordEntryRead :execrows
+UPDATE entry_status
+SET last_read_at = CURRENT_TIMESTAMP,
+    read_count = read_count + 1
+WHERE entry_id = ?;
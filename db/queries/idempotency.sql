@@ -0,0 +1,16 @@
+-- name: InsertIdempotencyKey :exec
+INSERT INTO idempotency_keys (token, version_id)
+VALUES (?, ?);
+
+-- name: FindVersionByIdempotencyKey :one
+SELECT e.scope_id, e.key, v.version, v.file_path, v.hash
+FROM idempotency_keys ik
+JOIN versions v ON v.id = ik.version_id
+JOIN entries e ON e.id = v.entry_id
+WHERE ik.token = CAST(sqlc.arg(token) AS TEXT)
+  AND ik.created_at >= CAST(sqlc.arg(since) AS TEXT)
+LIMIT 1;
+
+-- name: DeleteIdempotencyKeysBefore :execrows
+DELETE FROM idempotency_keys
+WHERE created_at < CAST(sqlc.arg(before) AS TEXT);
@@ -0,0 +1,83 @@
+-- name: ListVersionFiles :many
+SELECT id, entry_id, version, file_path, hash
+FROM versions
+ORDER BY id;
+
+-- name: CountEntriesWithoutVersions :one
+SELECT COUNT(*) AS count
+FROM entries e
+WHERE NOT EXISTS (
+    SELECT 1 FROM versions v WHERE v.entry_id = e.id
+);
+
+-- name: CountEntriesWithoutStatus :one
+SELECT COUNT(*) AS count
+FROM entries e
+WHERE NOT EXISTS (
+    SELECT 1 FROM entry_status es WHERE es.entry_id = e.id
+);
+
+-- name: CountStatusWithMissingCurrentVersion :one
+SELECT COUNT(*) AS count
+FROM entry_status es
+WHERE NOT EXISTS (
+    SELECT 1 FROM versions v
+    WHERE v.entry_id = es.entry_id AND v.version = es.current_version
+);
+
+-- name: GetVaultUsage :one
+SELECT
+    (SELECT COUNT(*) FROM entries) AS entry_count,
+    COUNT(*) AS version_count,
+    CAST(COALESCE(SUM(size), 0) AS INTEGER) AS total_size
+FROM versions;
+
+-- name: GetReclaimableVersions :one
+SELECT
+    COUNT(*) AS version_count,
+    CAST(COALESCE(SUM(size), 0) AS INTEGER) AS total_size
+FROM (
+    SELECT
+        size,
+        ROW_NUMBER() OVER (PARTITION BY entry_id ORDER BY version DESC) AS newest_rank
+    FROM versions
+) ranked
+WHERE newest_rank > CAST(sqlc.arg(keep_versions) AS INTEGER);
+
+-- name: GetScopeUsage :one
+SELECT
+    (SELECT COUNT(*) FROM entries WHERE scope_id = ?1) AS entry_count,
+    COUNT(v.id) AS version_count,
+    CAST(COALESCE(SUM(v.size), 0) AS INTEGER) AS total_size
+FROM entries e
+JOIN versions v ON e.id = v.entry_id
+WHERE e.scope_id = ?1;
+
+-- name: QueueEntryFilesForDeletion :exec
+INSERT INTO pending_deletions (file_path)
+SELECT file_path FROM versions WHERE entry_id = ?
+ON CONFLICT (file_path) DO NOTHING;
+
+-- name: QueueVersionFileForDeletion :exec
+INSERT INTO pending_deletions (file_path)
+SELECT file_path FROM versions WHERE entry_id = ? AND version = ?
+ON CONFLICT (file_path) DO NOTHING;
+
+-- name: DeleteReusedPendingDeletions :execrows
+DELETE FROM pending_deletions
+WHERE file_path IN (SELECT file_path FROM versions);
+
+-- name: ListPendingDeletions :many
+SELECT id, file_path, queued_at, attempts, last_error
+FROM pending_deletions
+WHERE file_path NOT IN (SELECT file_path FROM versions)
+ORDER BY id;
+
+-- name: DeletePendingDeletion :exec
+DELETE FROM pending_deletions
+WHERE file_path = ?;
+
+-- name: RecordPendingDeletionFailure :exec
+UPDATE pending_deletions
+SET attempts = attempts + 1, last_error = ?
+WHERE file_path = ?;
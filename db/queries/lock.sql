@@ -0,0 +1,16 @@
+-- name: DeleteEntryLock :execrows
+DELETE FROM entry_locks
+WHERE scope_id = ? AND key = ?;
+
+-- name: GetEntryLock :one
+SELECT scope_id, key, owner, acquired_at, expires_at
+FROM entry_locks
+WHERE scope_id = ? AND key = ?;
+
+-- name: UpsertEntryLock :exec
+INSERT INTO entry_locks (scope_id, key, owner, acquired_at, expires_at)
+VALUES (?1, ?2, ?3, CAST(?4 AS TEXT), CAST(?5 AS TEXT))
+ON CONFLICT (scope_id, key) DO UPDATE SET
+    owner = excluded.owner,
+    acquired_at = excluded.acquired_at,
+    expires_at = excluded.expires_at;
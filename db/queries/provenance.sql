@@ -0,0 +1,9 @@
+-- name: InsertVersionProvenance :exec
+INSERT INTO version_provenance (version_id, tool, hostname, git_branch, git_commit, git_dirty, actor, device_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: ListVersionProvenanceByEntry :many
+SELECT p.version_id, p.tool, p.hostname, p.git_branch, p.git_commit, p.git_dirty, p.actor, p.device_id
+FROM version_provenance p
+JOIN versions v ON v.id = p.version_id
+WHERE v.entry_id = ?;
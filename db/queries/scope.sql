@@ -1,17 +1,17 @@
 -- name: FindScopeByID :one
-SELECT id, type, primary_path, worktree_id, worktree_path, branch_name, scope_path, created_at, updated_at
+SELECT id, type, primary_path, worktree_id, worktree_path, branch_name, scope_path, created_at, updated_at, description, metadata
 FROM scopes
 WHERE id = ?
 LIMIT 1;
 
 -- name: FindScopeByPath :one
-SELECT id, type, primary_path, worktree_id, worktree_path, branch_name, scope_path, created_at, updated_at
+SELECT id, type, primary_path, worktree_id, worktree_path, branch_name, scope_path, created_at, updated_at, description, metadata
 FROM scopes
 WHERE scope_path = ?
 LIMIT 1;
 
 -- name: ListScopes :many
-SELECT id, type, primary_path, worktree_id, worktree_path, branch_name, scope_path, created_at, updated_at
+SELECT id, type, primary_path, worktree_id, worktree_path, branch_name, scope_path, created_at, updated_at, description, metadata
 FROM scopes
 ORDER BY type, primary_path, branch_name;
 
@@ -30,6 +30,13 @@ SET type = ?,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?;
 
+-- name: UpdateScopeDescription :exec
+UPDATE scopes
+SET description = ?,
+    metadata = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
 -- name: DeleteScopeByID :execrows
 DELETE FROM scopes
 WHERE id = ?;
@@ -0,0 +1,49 @@
+-- name: DeleteScopeSnapshot :execrows
+DELETE FROM scope_snapshots
+WHERE scope_id = ? AND name = ?;
+
+-- name: GetScopeSnapshot :one
+SELECT s.id, s.scope_id, s.name, s.created_at, COUNT(v.key) AS key_count
+FROM scope_snapshots s
+LEFT JOIN scope_snapshot_versions v ON v.snapshot_id = s.id
+WHERE s.scope_id = ? AND s.name = ?
+GROUP BY s.id;
+
+-- name: GetScopeSnapshotVersion :one
+SELECT v.version
+FROM scope_snapshot_versions v
+JOIN scope_snapshots s ON s.id = v.snapshot_id
+WHERE s.scope_id = ? AND s.name = ? AND v.key = ?;
+
+-- name: InsertScopeSnapshot :execresult
+INSERT INTO scope_snapshots (scope_id, name)
+VALUES (?, ?);
+
+-- name: InsertScopeSnapshotAt :execresult
+INSERT INTO scope_snapshots (scope_id, name, created_at)
+VALUES (sqlc.arg(scope_id), sqlc.arg(name), CAST(sqlc.arg(created_at) AS TEXT));
+
+-- name: InsertScopeSnapshotVersion :exec
+INSERT INTO scope_snapshot_versions (snapshot_id, key, version)
+VALUES (?, ?, ?);
+
+-- name: ListScopeSnapshotVersions :many
+SELECT key, version
+FROM scope_snapshot_versions
+WHERE snapshot_id = ?
+ORDER BY key;
+
+-- name: ListScopeSnapshots :many
+SELECT s.id, s.scope_id, s.name, s.created_at, COUNT(v.key) AS key_count
+FROM scope_snapshots s
+LEFT JOIN scope_snapshot_versions v ON v.snapshot_id = s.id
+WHERE s.scope_id = ?
+GROUP BY s.id
+ORDER BY s.created_at, s.id;
+
+-- name: PinScopeSnapshotVersions :execrows
+INSERT INTO scope_snapshot_versions (snapshot_id, key, version)
+SELECT ?1, e.key, es.current_version
+FROM entries e
+JOIN entry_status es ON es.entry_id = e.id
+WHERE e.scope_id = ?2 AND es.current_version > 0;
@@ -10,10 +10,16 @@ SELECT
     v.file_path,
     v.hash,
     v.description,
-    v.created_at AS version_created_at
+    v.created_at AS version_created_at,
+    v.size,
+    v.language,
+    vs.summary,
+    es.last_read_at,
+    es.read_count
 FROM entries e
 JOIN entry_status es ON e.id = es.entry_id
 JOIN versions v ON e.id = v.entry_id AND v.version = es.current_version
+LEFT JOIN version_summaries vs ON vs.version_id = v.id
 WHERE e.scope_id = ? AND e.key = ?
 LIMIT 1;
 
@@ -28,10 +34,16 @@ SELECT
     v.file_path,
     v.hash,
     v.description,
-    v.created_at AS version_created_at
+    v.created_at AS version_created_at,
+    v.size,
+    v.language,
+    vs.summary,
+    es.last_read_at,
+    es.read_count
 FROM entries e
 JOIN entry_status es ON e.id = es.entry_id
 JOIN versions v ON e.id = v.entry_id
+LEFT JOIN version_summaries vs ON vs.version_id = v.id
 WHERE e.scope_id = ? AND e.key = ? AND v.version = ?
 LIMIT 1;
 
@@ -47,13 +59,35 @@ SELECT
     v.file_path,
     v.hash,
     v.description,
-    v.created_at AS version_created_at
+    v.created_at AS version_created_at,
+    v.size,
+    v.language,
+    vs.summary,
+    es.last_read_at,
+    es.read_count
 FROM entries e
 JOIN entry_status es ON e.id = es.entry_id
 JOIN versions v ON e.id = v.entry_id AND v.version = es.current_version
+LEFT JOIN version_summaries vs ON vs.version_id = v.id
 WHERE e.scope_id = ?
   AND (sqlc.arg('include_archived') OR es.is_archived = 0)
-ORDER BY e.key;
+  AND (CAST(sqlc.narg('since') AS TEXT) IS NULL OR v.created_at >= CAST(sqlc.narg('since') AS TEXT))
+  AND (CAST(sqlc.narg('until') AS TEXT) IS NULL OR v.created_at <= CAST(sqlc.narg('until') AS TEXT))
+  AND (CAST(sqlc.narg('description_contains') AS TEXT) IS NULL OR instr(lower(v.description), lower(CAST(sqlc.narg('description_contains') AS TEXT))) > 0)
+  AND (CAST(sqlc.narg('language') AS TEXT) IS NULL OR v.language = CAST(sqlc.narg('language') AS TEXT))
+ORDER BY
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'key' AND NOT sqlc.arg('sort_desc') THEN e.key END ASC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'key' AND sqlc.arg('sort_desc') THEN e.key END DESC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'created' AND NOT sqlc.arg('sort_desc') THEN e.created_at END ASC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'created' AND sqlc.arg('sort_desc') THEN e.created_at END DESC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'updated' AND NOT sqlc.arg('sort_desc') THEN v.created_at END ASC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'updated' AND sqlc.arg('sort_desc') THEN v.created_at END DESC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'version' AND NOT sqlc.arg('sort_desc') THEN v.version END ASC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'version' AND sqlc.arg('sort_desc') THEN v.version END DESC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'size' AND NOT sqlc.arg('sort_desc') THEN v.size END ASC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'size' AND sqlc.arg('sort_desc') THEN v.size END DESC,
+    e.key,
+    v.version DESC;
 
 -- name: ListScopedEntriesAllVersions :many
 SELECT
@@ -66,13 +100,35 @@ SELECT
     v.file_path,
     v.hash,
     v.description,
-    v.created_at AS version_created_at
+    v.created_at AS version_created_at,
+    v.size,
+    v.language,
+    vs.summary,
+    es.last_read_at,
+    es.read_count
 FROM entries e
 JOIN entry_status es ON e.id = es.entry_id
 JOIN versions v ON e.id = v.entry_id
+LEFT JOIN version_summaries vs ON vs.version_id = v.id
 WHERE e.scope_id = ?
   AND (sqlc.arg('include_archived') OR es.is_archived = 0)
-ORDER BY e.key, v.version DESC;
+  AND (CAST(sqlc.narg('since') AS TEXT) IS NULL OR v.created_at >= CAST(sqlc.narg('since') AS TEXT))
+  AND (CAST(sqlc.narg('until') AS TEXT) IS NULL OR v.created_at <= CAST(sqlc.narg('until') AS TEXT))
+  AND (CAST(sqlc.narg('description_contains') AS TEXT) IS NULL OR instr(lower(v.description), lower(CAST(sqlc.narg('description_contains') AS TEXT))) > 0)
+  AND (CAST(sqlc.narg('language') AS TEXT) IS NULL OR v.language = CAST(sqlc.narg('language') AS TEXT))
+ORDER BY
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'key' AND NOT sqlc.arg('sort_desc') THEN e.key END ASC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'key' AND sqlc.arg('sort_desc') THEN e.key END DESC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'created' AND NOT sqlc.arg('sort_desc') THEN e.created_at END ASC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'created' AND sqlc.arg('sort_desc') THEN e.created_at END DESC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'updated' AND NOT sqlc.arg('sort_desc') THEN v.created_at END ASC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'updated' AND sqlc.arg('sort_desc') THEN v.created_at END DESC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'version' AND NOT sqlc.arg('sort_desc') THEN v.version END ASC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'version' AND sqlc.arg('sort_desc') THEN v.version END DESC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'size' AND NOT sqlc.arg('sort_desc') THEN v.size END ASC,
+    CASE WHEN CAST(sqlc.arg('sort_by') AS TEXT) = 'size' AND sqlc.arg('sort_desc') THEN v.size END DESC,
+    e.key,
+    v.version DESC;
 
 -- name: ListEntriesWithVersionCount :many
 SELECT
@@ -99,3 +155,15 @@ LEFT JOIN entries e ON s.id = e.scope_id
 LEFT JOIN versions v ON e.id = v.entry_id
 WHERE s.primary_path = ?
 GROUP BY s.id;
+
+-- name: ListSupersededVersions :many
+SELECT
+    e.key,
+    v.version,
+    v.file_path,
+    v.size
+FROM entries e
+JOIN entry_status es ON e.id = es.entry_id
+JOIN versions v ON e.id = v.entry_id
+WHERE e.scope_id = ? AND v.version <> es.current_version
+ORDER BY v.created_at, v.id;
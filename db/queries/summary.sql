@@ -0,0 +1,18 @@
+-- name: SetVersionSummary :execrows
+INSERT INTO version_summaries (version_id, summary, summarizer)
+SELECT v.id, CAST(sqlc.arg(summary) AS TEXT), sqlc.narg(summarizer)
+FROM versions v
+JOIN entries e ON e.id = v.entry_id
+WHERE e.scope_id = sqlc.arg(scope_id)
+  AND e.key = sqlc.arg(key)
+  AND v.version = sqlc.arg(version)
+ON CONFLICT (version_id) DO UPDATE SET
+    summary = excluded.summary,
+    summarizer = excluded.summarizer,
+    created_at = CURRENT_TIMESTAMP;
+
+-- name: ListVersionSummariesByEntry :many
+SELECT s.version_id, s.summary, s.summarizer, s.created_at
+FROM version_summaries s
+JOIN versions v ON v.id = s.version_id
+WHERE v.entry_id = ?;
@@ -1,17 +1,17 @@
 -- name: FindVersionByID :one
-SELECT id, entry_id, version, file_path, hash, description, created_at
+SELECT id, entry_id, version, file_path, hash, description, created_at, size, verified_mtime, verified_size, language
 FROM versions
 WHERE id = ?
 LIMIT 1;
 
 -- name: FindVersionByEntryAndVersion :one
-SELECT id, entry_id, version, file_path, hash, description, created_at
+SELECT id, entry_id, version, file_path, hash, description, created_at, size, verified_mtime, verified_size, language
 FROM versions
 WHERE entry_id = ? AND version = ?
 LIMIT 1;
 
 -- name: ListVersionsByEntry :many
-SELECT id, entry_id, version, file_path, hash, description, created_at
+SELECT id, entry_id, version, file_path, hash, description, created_at, size, verified_mtime, verified_size, language
 FROM versions
 WHERE entry_id = ?
 ORDER BY version DESC;
@@ -22,8 +22,8 @@ FROM versions
 WHERE entry_id = ?;
 
 -- name: InsertVersion :execresult
-INSERT INTO versions (entry_id, version, file_path, hash, description)
-VALUES (?, ?, ?, ?, ?);
+INSERT INTO versions (entry_id, version, file_path, hash, description, size, language)
+VALUES (?, ?, ?, ?, ?, ?, ?);
 
 -- name: DeleteVersionByID :execrows
 DELETE FROM versions
@@ -41,3 +41,33 @@ WHERE entry_id = ?;
 SELECT COUNT(*) AS count
 FROM versions
 WHERE entry_id = ?;
+
+-- name: GetVersionSummaryByEntry :one
+SELECT
+    COUNT(*) AS version_count,
+    CAST(COALESCE(SUM(size), 0) AS INTEGER) AS total_size,
+    CAST(COALESCE(MIN(created_at), '') AS TEXT) AS first_written_at,
+    CAST(COALESCE(MAX(created_at), '') AS TEXT) AS last_written_at
+FROM versions
+WHERE entry_id = ?;
+
+-- name: GetVersionVerification :one
+SELECT verified_mtime, verified_size
+FROM versions
+WHERE entry_id = ? AND version = ?
+LIMIT 1;
+
+-- name: UpdateVersionFilePath :execrows
+UPDATE versions
+SET file_path = sqlc.arg('new_path')
+WHERE id = sqlc.arg('id') AND file_path = sqlc.arg('old_path');
+
+-- name: UpdateVersionVerification :exec
+UPDATE versions
+SET verified_mtime = ?, verified_size = ?
+WHERE entry_id = ? AND version = ?;
+
+-- name: UpdateVersionCreatedAt :exec
+UPDATE versions
+SET created_at = CAST(sqlc.arg('created_at') AS TEXT)
+WHERE id = sqlc.arg('id');
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxAliasDepth bounds how many aliases may expand into one another.
+const maxAliasDepth = 10
+
+// ExpandAlias rewrites command-line arguments whose first argument names an
+// alias, in the manner of git aliases. The alias is split into words like a
+// shell would (single and double quotes group words), $1 through $9 are
+// replaced by the positional arguments after the alias name, and $@ by all
+// of them. Arguments no placeholder referred to are appended. Aliases may
+// expand to other aliases. Names for which builtin returns true are never
+// expanded, so an alias cannot shadow a builtin command.
+func (s *Settings) ExpandAlias(args []string, builtin func(string) bool) ([]string, error) {
+	if s == nil || len(s.Aliases) == 0 {
+		return args, nil
+	}
+
+	var chain []string
+	for len(args) > 0 && !builtin(args[0]) {
+		line, ok := s.Aliases[args[0]]
+		if !ok {
+			break
+		}
+		chain = append(chain, args[0])
+		if len(chain) > maxAliasDepth {
+			return nil, fmt.Errorf("alias %s: too many nested aliases (%s)", chain[0], strings.Join(chain, " -> "))
+		}
+
+		words, err := SplitCommandLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("alias %s: %w", args[0], err)
+		}
+		args = substituteAliasArgs(words, args[1:])
+	}
+	return args, nil
+}
+
+// substituteAliasArgs fills $1-$9 and $@ in words from params and appends
+// the params that were not referred to.
+func substituteAliasArgs(words, params []string) []string {
+	used := make([]bool, len(params))
+	expanded := make([]string, 0, len(words)+len(params))
+	for _, word := range words {
+		if word == "$@" {
+			expanded = append(expanded, params...)
+			for i := range used {
+				used[i] = true
+			}
+			continue
+		}
+		if n, ok := aliasParam(word); ok {
+			if n <= len(params) {
+				expanded = append(expanded, params[n-1])
+				used[n-1] = true
+			}
+			continue
+		}
+		expanded = append(expanded, word)
+	}
+	for i, param := range params {
+		if !used[i] {
+			expanded = append(expanded, param)
+		}
+	}
+	return expanded
+}
+
+// aliasParam reports whether word is a positional placeholder $1-$9.
+func aliasParam(word string) (int, bool) {
+	if len(word) != 2 || word[0] != '$' {
+		return 0, false
+	}
+	n, err := strconv.Atoi(word[1:])
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n, true
+}
+
+// SplitCommandLine splits line into words at unquoted whitespace. Single
+// quotes keep their content literally; inside double quotes a backslash
+// escapes the next character.
+func SplitCommandLine(line string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	return words, nil
+}
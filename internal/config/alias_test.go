@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestExpandAlias(t *testing.T) {
+	settings := &Settings{Aliases: map[string]string{
+		"notes":  "get daily-notes --scope global",
+		"today":  `set "$1" -d 'written today'`,
+		"all":    "list $@ --all-versions",
+		"n":      "notes",
+		"get":    "list",
+		"loop":   "loop",
+		"quoted": `get "two words" it\'s`,
+	}}
+	builtin := func(name string) bool { return name == "get" || name == "set" || name == "list" }
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"notes"}, []string{"get", "daily-notes", "--scope", "global"}},
+		{[]string{"notes", "--version", "2"}, []string{"get", "daily-notes", "--scope", "global", "--version", "2"}},
+		{[]string{"today", "journal", "--scope", "global"}, []string{"set", "journal", "-d", "written today", "--scope", "global"}},
+		{[]string{"all", "--scope", "global"}, []string{"list", "--scope", "global", "--all-versions"}},
+		{[]string{"n"}, []string{"get", "daily-notes", "--scope", "global"}},
+		{[]string{"get", "key"}, []string{"get", "key"}},
+		{[]string{"quoted"}, []string{"get", "two words", "it's"}},
+		{[]string{"unknown"}, []string{"unknown"}},
+	}
+	for _, tt := range tests {
+		got, err := settings.ExpandAlias(tt.args, builtin)
+		if err != nil {
+			t.Fatalf("ExpandAlias(%q) error: %v", tt.args, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ExpandAlias(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	if _, err := settings.ExpandAlias([]string{"loop"}, builtin); err == nil {
+		t.Fatalf("expected an error for a recursive alias")
+	}
+}
+
+func TestLoadFromRejectsInvalidAlias(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"aliases": {"notes": "get 'daily-notes"}}`), 0o600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	if _, err := LoadFrom(path); err == nil {
+		t.Fatalf("expected validation error")
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cloudSyncFolders are the folder names sync clients create, and the
+// service each belongs to. Prefixes match business and per-account
+// folders such as "OneDrive - Contoso" or "GoogleDrive-me@example.com".
+var cloudSyncFolders = []struct {
+	name    string
+	prefix  bool
+	service string
+}{
+	{"Dropbox", false, "Dropbox"},
+	{"Dropbox (", true, "Dropbox"},
+	{"Dropbox-", true, "Dropbox"},
+	{"Mobile Documents", false, "iCloud Drive"},
+	{"iCloudDrive", false, "iCloud Drive"},
+	{"iCloud Drive", false, "iCloud Drive"},
+	{"OneDrive", false, "OneDrive"},
+	{"OneDrive - ", true, "OneDrive"},
+	{"OneDrive-", true, "OneDrive"},
+	{"Google Drive", false, "Google Drive"},
+	{"GoogleDrive-", true, "Google Drive"},
+}
+
+// CloudSyncService returns the name of the file sync service, such as
+// Dropbox or iCloud Drive, whose folder contains dir, or "" if dir is not in
+// one it recognises. Sync clients copy files without regard for SQLite's
+// locking, which corrupts a database that is written while it syncs.
+func CloudSyncService(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for d := abs; ; d = filepath.Dir(d) {
+		name := filepath.Base(d)
+		for _, f := range cloudSyncFolders {
+			if name == f.name || (f.prefix && strings.HasPrefix(name, f.name)) {
+				return f.service
+			}
+		}
+		// Dropbox marks the root of its folder, whatever it is called.
+		if _, err := os.Stat(filepath.Join(d, ".dropbox")); err == nil {
+			return "Dropbox"
+		}
+		if filepath.Dir(d) == d {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCloudSyncService(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cases := map[string]string{
+		"Dropbox/vault":                                   "Dropbox",
+		"Dropbox (Contoso)/notes/vault":                   "Dropbox",
+		"Library/Mobile Documents/com~apple~CloudDocs/v":  "iCloud Drive",
+		"OneDrive - Contoso/vault":                        "OneDrive",
+		"Library/CloudStorage/GoogleDrive-me@example.com": "Google Drive",
+		"projects/vault":                                  "",
+		"DropboxArchive/vault":                            "",
+	}
+	for rel, want := range cases {
+		if got := CloudSyncService(filepath.Join(tmpDir, rel)); got != want {
+			t.Errorf("CloudSyncService(%q) = %q, want %q", rel, got, want)
+		}
+	}
+
+	// A renamed Dropbox folder is recognised by its marker file.
+	root := filepath.Join(tmpDir, "Sync")
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".dropbox"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := CloudSyncService(filepath.Join(root, "vault")); got != "Dropbox" {
+		t.Errorf("CloudSyncService in marked folder = %q, want Dropbox", got)
+	}
+}
+
+func TestSharedStorageDefaultsOnInSyncedFolder(t *testing.T) {
+	t.Setenv("VAULT_DIR", filepath.Join(t.TempDir(), "Dropbox", "vault"))
+
+	settings := &Settings{}
+	if !settings.IsSharedStorage() {
+		t.Fatalf("expected shared storage by default in a Dropbox folder")
+	}
+	off := false
+	settings.SharedStorage = &off
+	if settings.IsSharedStorage() {
+		t.Fatalf("expected an explicit sharedStorage to win")
+	}
+}
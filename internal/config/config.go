@@ -50,3 +50,57 @@ func EncodeProjectPath(projectPath string) string {
 	replacer := strings.NewReplacer("/", "-", ".", "-", "_", "-")
 	return replacer.Replace(projectPath)
 }
+
+// EphemeralEnv is the environment variable that, when set to a true value,
+// makes the vault ephemeral: see IsEphemeral.
+const EphemeralEnv = "VAULT_EPHEMERAL"
+
+// IsEphemeral reports whether the vault lives only for the current process:
+// the database is kept in memory instead of in index.db. StartEphemeral also
+// moves the vault directory to a temporary one.
+func IsEphemeral() bool {
+	return envTrue(EphemeralEnv)
+}
+
+// NoNotifyEnv is the environment variable that, when set to a true value,
+// keeps changes from being sent to the configured notifiers, as for bulk
+// imports or throwaway vaults.
+const NoNotifyEnv = "VAULT_NO_NOTIFY"
+
+// NotificationsEnabled reports whether changes are sent to the configured
+// notifiers: not for ephemeral vaults, nor when NoNotifyEnv is set.
+func NotificationsEnabled() bool {
+	return !IsEphemeral() && !envTrue(NoNotifyEnv)
+}
+
+// envTrue reports whether the environment variable name is set to a true
+// value.
+func envTrue(name string) bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(name))) {
+	case "", "0", "false", "no":
+		return false
+	default:
+		return true
+	}
+}
+
+// StartEphemeral points the process at an empty temporary vault directory
+// and an in-memory database, so nothing touches the user's vault. The
+// returned function removes the directory again.
+func StartEphemeral() (func(), error) {
+	dir, err := os.MkdirTemp("", "vault-ephemeral-*")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Setenv("VAULT_DIR", dir); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	if err := os.Setenv(EphemeralEnv, "1"); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	return func() {
+		_ = os.RemoveAll(dir)
+	}, nil
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -56,3 +57,45 @@ func TestEncodeProjectPath(t *testing.T) {
 		t.Fatalf("expected encoded path to differ from input")
 	}
 }
+
+func TestStartEphemeral(t *testing.T) {
+	t.Setenv("VAULT_DIR", t.TempDir())
+	t.Setenv(EphemeralEnv, "")
+	if IsEphemeral() {
+		t.Fatalf("expected a regular vault without %s", EphemeralEnv)
+	}
+
+	cleanup, err := StartEphemeral()
+	if err != nil {
+		t.Fatalf("StartEphemeral returned error: %v", err)
+	}
+	dir := GetVaultDir()
+	if !IsEphemeral() || !strings.Contains(filepath.Base(dir), "vault-ephemeral-") {
+		t.Fatalf("expected an ephemeral vault in a temporary dir, got %q", dir)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatalf("expected the temporary dir to exist: %v", err)
+	}
+
+	cleanup()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("expected the temporary dir to be removed, stat err: %v", err)
+	}
+}
+
+func TestNotificationsEnabled(t *testing.T) {
+	t.Setenv(EphemeralEnv, "")
+	t.Setenv(NoNotifyEnv, "")
+	if !NotificationsEnabled() {
+		t.Fatal("expected notifications for a regular vault")
+	}
+	t.Setenv(NoNotifyEnv, "1")
+	if NotificationsEnabled() {
+		t.Fatalf("expected no notifications with %s set", NoNotifyEnv)
+	}
+	t.Setenv(NoNotifyEnv, "false")
+	t.Setenv(EphemeralEnv, "1")
+	if NotificationsEnabled() {
+		t.Fatal("expected no notifications for an ephemeral vault")
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GetDeviceIDPath returns the file that holds this installation's device ID.
+// It lives next to the config file rather than in the vault directory, so
+// machines sharing a vault over network storage still get distinct IDs.
+func GetDeviceIDPath() string {
+	return filepath.Join(filepath.Dir(GetConfigPath()), "device-id")
+}
+
+// DeviceID returns the ID identifying this installation in provenance and
+// sync manifests. VAULT_DEVICE_ID overrides it; otherwise the ID is read
+// from GetDeviceIDPath, generated and saved there on first use.
+func DeviceID() (string, error) {
+	if explicit := os.Getenv("VAULT_DEVICE_ID"); explicit != "" {
+		return explicit, nil
+	}
+
+	path := GetDeviceIDPath()
+	if id, err := readDeviceID(path); err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return id, err
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(buf)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	// Exclusive create: if another process got there first, use its ID.
+	//nolint:gosec // G304: path is derived from the config directory
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return readDeviceID(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(id + "\n"); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func readDeviceID(path string) (string, error) {
+	//nolint:gosec // G304: path is derived from the config directory
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	id := strings.TrimSpace(string(data))
+	if id == "" {
+		return "", fmt.Errorf("device ID file %s is empty", path)
+	}
+	return id, nil
+}
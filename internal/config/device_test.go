@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeviceIDIsGeneratedOnce(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("VAULT_CONFIG", filepath.Join(dir, "config.json"))
+	t.Setenv("VAULT_DEVICE_ID", "")
+
+	first, err := DeviceID()
+	if err != nil {
+		t.Fatalf("DeviceID failed: %v", err)
+	}
+	if len(first) != 16 {
+		t.Fatalf("expected a 16 character ID, got %q", first)
+	}
+
+	second, err := DeviceID()
+	if err != nil {
+		t.Fatalf("DeviceID failed: %v", err)
+	}
+	if second != first {
+		t.Fatalf("expected the saved ID %q, got %q", first, second)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "device-id"))
+	if err != nil {
+		t.Fatalf("read device-id: %v", err)
+	}
+	if string(data) != first+"\n" {
+		t.Fatalf("unexpected device-id file content %q", data)
+	}
+}
+
+func TestDeviceIDFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("VAULT_CONFIG", filepath.Join(dir, "config.json"))
+	t.Setenv("VAULT_DEVICE_ID", "laptop")
+
+	id, err := DeviceID()
+	if err != nil {
+		t.Fatalf("DeviceID failed: %v", err)
+	}
+	if id != "laptop" {
+		t.Fatalf("expected laptop, got %q", id)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "device-id")); !os.IsNotExist(err) {
+		t.Fatalf("expected no device-id file to be written, stat err: %v", err)
+	}
+}
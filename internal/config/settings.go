@@ -0,0 +1,615 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/adrg/xdg"
+
+	"github.com/choplin/vault.md/internal/entrykey"
+)
+
+// Settings holds user preferences read from the optional config file.
+// Fields are pointers so an absent key can be told apart from an explicit
+// zero value; use the accessor methods to read them with defaults applied.
+type Settings struct {
+	// VerifyOnRead controls whether reads check content against the stored
+	// SHA-256 hash. Defaults to true.
+	VerifyOnRead *bool `json:"verifyOnRead,omitempty"`
+
+	// SharedStorage tunes the vault for a directory on NFS/SMB shared by
+	// several machines: writes serialise on a lockfile and SQLite stays on
+	// the rollback journal. Defaults to true when the vault directory is in
+	// a cloud-synced folder (see CloudSyncService) and false otherwise.
+	SharedStorage *bool `json:"sharedStorage,omitempty"`
+
+	// CaptureEnvironment records the hostname and the git branch, commit,
+	// and dirty flag alongside each written version. Defaults to false.
+	CaptureEnvironment *bool `json:"captureEnvironment,omitempty"`
+
+	// TrackReads records when each entry was last read and how often, for
+	// vault stats and vault stale. Defaults to false, since it turns every
+	// read into a write.
+	TrackReads *bool `json:"trackReads,omitempty"`
+
+	// RequireGit makes commands fail when git cannot be run to detect the
+	// scope, instead of falling back to the global scope with a warning.
+	// Defaults to false.
+	RequireGit *bool `json:"requireGit,omitempty"`
+
+	// Retention describes how much history is worth keeping. Versions
+	// outside the policy are reported as reclaimable; nothing is deleted
+	// automatically.
+	Retention *RetentionSettings `json:"retention,omitempty"`
+
+	// DatabaseURL points the index at a remote database, such as a hosted
+	// libsql instance, instead of the local vault.db. Content objects stay
+	// in the local objects directory. The URL scheme selects a backend
+	// registered with database.RegisterBackend; builds with -tags libsql
+	// register libsql://.
+	DatabaseURL *string `json:"databaseUrl,omitempty"`
+
+	// SyncKeyFile is the path of the key that encrypts everything sync-git
+	// and snapshot write, so the remote never sees plaintext. Unset means
+	// no encryption.
+	SyncKeyFile *string `json:"syncKeyFile,omitempty"`
+
+	// Aliases maps a command name to the command line it stands for, such
+	// as "notes": "get daily-notes --scope global". See ExpandAlias.
+	Aliases map[string]string `json:"aliases,omitempty"`
+
+	// Viewer is the command vault open runs with the entry's file as its
+	// last argument. Unset means the operating system's default handler.
+	Viewer *string `json:"viewer,omitempty"`
+
+	// Display controls how timestamps appear in tables and other
+	// human-readable output. The database stores UTC and JSON output stays
+	// RFC3339 regardless.
+	Display *DisplaySettings `json:"display,omitempty"`
+
+	// Summarizer generates a short summary for each long version as it is
+	// written. Unset means no summaries are generated.
+	Summarizer *SummarizerSettings `json:"summarizer,omitempty"`
+
+	// Quota limits how much each scope may hold. Unset means no limits.
+	Quota *QuotaSettings `json:"quota,omitempty"`
+
+	// KeyTemplates maps a scope type (global, repository, branch, or
+	// worktree) to the keys every new scope of that type starts with, such
+	// as "branch": {"plan": {...}, "progress": {...}}. They are written
+	// along with the first write to the scope.
+	KeyTemplates map[string]map[string]KeyTemplate `json:"keyTemplates,omitempty"`
+
+	// ContextKeys are the keys the vault_context MCP tool reads from every
+	// scope that applies to the caller. Defaults to DefaultContextKeys.
+	ContextKeys []string `json:"contextKeys,omitempty"`
+
+	// Notifiers post a message to a chat webhook whenever a matching entry
+	// changes.
+	Notifiers []NotifierSettings `json:"notifiers,omitempty"`
+
+	// Validators check the content of matching keys before it is stored,
+	// so entries read by scripts and agents stay parseable.
+	Validators []ValidatorSettings `json:"validators,omitempty"`
+}
+
+// DefaultContextKeys are the keys vault_context reads when contextKeys is
+// unset.
+var DefaultContextKeys = []string{"plan", "conventions", "decisions"}
+
+// KeyTemplate is the initial content of a templated key. Exactly one of
+// Content and File is set.
+type KeyTemplate struct {
+	// Content is the content itself.
+	Content *string `json:"content,omitempty"`
+	// File is the path of a file holding the content, read at the time
+	// the key is created.
+	File *string `json:"file,omitempty"`
+	// Description is stored with the version. Defaults to "Created from
+	// key template".
+	Description *string `json:"description,omitempty"`
+}
+
+// Load returns the template's content.
+func (t KeyTemplate) Load() (string, error) {
+	if t.Content != nil {
+		return *t.Content, nil
+	}
+	if t.File == nil {
+		return "", nil
+	}
+	data, err := os.ReadFile(*t.File) //nolint:gosec // G304: template path is chosen by the user
+	if err != nil {
+		return "", fmt.Errorf("failed to read key template: %w", err)
+	}
+	return string(data), nil
+}
+
+// Default layouts for DisplaySettings.
+const (
+	DefaultTimeFormat = "2006-01-02 15:04:05"
+	DefaultDateFormat = "2006-01-02"
+)
+
+// DisplaySettings is the display section of the config file.
+type DisplaySettings struct {
+	// Timezone is an IANA zone name such as "Europe/Berlin", "UTC", or
+	// "Local". Defaults to the local timezone, which honours TZ.
+	Timezone *string `json:"timezone,omitempty"`
+
+	// TimeFormat is the Go layout for timestamps, or "rfc3339".
+	// Defaults to DefaultTimeFormat.
+	TimeFormat *string `json:"timeFormat,omitempty"`
+
+	// DateFormat is the Go layout for dates shown without a time of day.
+	// Defaults to DefaultDateFormat.
+	DateFormat *string `json:"dateFormat,omitempty"`
+}
+
+// DefaultSummarizeMinSize is the content size in bytes from which versions
+// are summarized when SummarizerSettings.MinSize is unset.
+const DefaultSummarizeMinSize = 2048
+
+// SummarizerSettings is the summarizer section of the config file.
+type SummarizerSettings struct {
+	// Command is run with the content on stdin and VAULT_KEY set; what it
+	// prints becomes the summary. Wrap an API call in a script to use one.
+	Command string `json:"command"`
+
+	// MinSize is the content size in bytes from which versions are
+	// summarized. Defaults to DefaultSummarizeMinSize.
+	MinSize *int `json:"minSize,omitempty"`
+}
+
+// Values of QuotaSettings.OnExceed.
+const (
+	// QuotaFail rejects a write that would exceed a quota.
+	QuotaFail = "fail"
+	// QuotaPrune deletes the oldest superseded versions in the scope to
+	// make room, and rejects the write if that is not enough.
+	QuotaPrune = "prune"
+)
+
+// QuotaSettings is the quota section of the config file. The limits apply
+// to each scope separately.
+type QuotaSettings struct {
+	// MaxEntries is the number of keys a scope may hold, archived ones
+	// included.
+	MaxEntries *int `json:"maxEntries,omitempty"`
+	// MaxBytes is the total size of all versions in a scope.
+	MaxBytes *int64 `json:"maxBytes,omitempty"`
+	// OnExceed is QuotaFail or QuotaPrune. Defaults to QuotaFail.
+	OnExceed *string `json:"onExceed,omitempty"`
+}
+
+// Values of NotifierSettings.Format.
+const (
+	// NotifySlack posts {"text": ...} as Slack incoming webhooks expect.
+	NotifySlack = "slack"
+	// NotifyDiscord posts {"content": ...} as Discord webhooks expect.
+	NotifyDiscord = "discord"
+	// NotifyJSON posts the event itself, for custom receivers.
+	NotifyJSON = "json"
+)
+
+// NotifyEvents are the event types a notifier can subscribe to.
+var NotifyEvents = []string{"set", "delete", "archive", "restore"}
+
+// NotifierSettings is one entry of the notifiers section of the config
+// file.
+type NotifierSettings struct {
+	// URL is the webhook to post to.
+	URL string `json:"url"`
+	// Format is NotifySlack, NotifyDiscord, or NotifyJSON. Defaults to
+	// NotifySlack.
+	Format *string `json:"format,omitempty"`
+	// Scopes are globs matched against the scope as vault list shows it,
+	// such as "/home/me/app" or "/home/me/app:*", or scope types such as
+	// "global". Empty matches every scope.
+	Scopes []string `json:"scopes,omitempty"`
+	// Keys are globs such as "plan" or "decisions/*". Empty matches every
+	// key.
+	Keys []string `json:"keys,omitempty"`
+	// Events are the NotifyEvents to post. Empty posts all of them.
+	Events []string `json:"events,omitempty"`
+}
+
+// NotifyFormat returns the payload format of the notifier.
+func (n NotifierSettings) NotifyFormat() string {
+	if n.Format == nil {
+		return NotifySlack
+	}
+	return *n.Format
+}
+
+// Values of ValidatorSettings.OnFail.
+const (
+	// ValidateReject fails the write.
+	ValidateReject = "reject"
+	// ValidateWarn stores the content and reports the problem.
+	ValidateWarn = "warn"
+)
+
+// ValidatorSettings is one entry of the validators section of the config
+// file. Exactly one of Pattern, Schema, and SchemaFile is set.
+type ValidatorSettings struct {
+	// Prefix selects the keys to check, such as "adr/". Empty checks every
+	// key.
+	Prefix string `json:"prefix"`
+	// Pattern is a regular expression the content must match, such as
+	// "(?m)^## Decision$". Use (?m) to anchor at line starts.
+	Pattern *string `json:"pattern,omitempty"`
+	// Schema is a JSON Schema the content, parsed as JSON, must satisfy.
+	Schema json.RawMessage `json:"schema,omitempty"`
+	// SchemaFile is the path of a file holding the JSON Schema.
+	SchemaFile *string `json:"schemaFile,omitempty"`
+	// OnFail is ValidateReject or ValidateWarn. Defaults to ValidateReject.
+	OnFail *string `json:"onFail,omitempty"`
+	// Message describes the expected structure in errors and warnings.
+	Message *string `json:"message,omitempty"`
+}
+
+// Rejects reports whether content failing the validator is not stored.
+func (v ValidatorSettings) Rejects() bool {
+	return v.OnFail == nil || *v.OnFail == ValidateReject
+}
+
+func (v ValidatorSettings) validate() error {
+	sources := 0
+	for _, set := range []bool{v.Pattern != nil, len(v.Schema) > 0, v.SchemaFile != nil} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("set exactly one of pattern, schema, or schemaFile")
+	}
+	if v.Pattern != nil {
+		if _, err := regexp.Compile(*v.Pattern); err != nil {
+			return fmt.Errorf("pattern: %w", err)
+		}
+	}
+	if v.OnFail != nil && *v.OnFail != ValidateReject && *v.OnFail != ValidateWarn {
+		return fmt.Errorf("invalid onFail: %s (valid values: %s, %s)", *v.OnFail, ValidateReject, ValidateWarn)
+	}
+	return nil
+}
+
+// RetentionSettings is the retention policy section of the config file.
+type RetentionSettings struct {
+	// KeepVersions is the number of newest versions to keep per key.
+	KeepVersions *int `json:"keepVersions,omitempty"`
+}
+
+// GetConfigPath returns the location of the config file. VAULT_CONFIG takes
+// precedence, followed by $XDG_CONFIG_HOME/vault.md/config.json.
+func GetConfigPath() string {
+	if explicit := os.Getenv("VAULT_CONFIG"); explicit != "" {
+		return explicit
+	}
+
+	xdg.Reload()
+
+	configHome := xdg.ConfigHome
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(os.TempDir(), "vault.md", "config.json")
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(configHome, "vault.md", "config.json")
+}
+
+// Load reads settings from GetConfigPath. A missing file yields default
+// settings rather than an error.
+func Load() (*Settings, error) {
+	return LoadFrom(GetConfigPath())
+}
+
+// LoadFrom reads settings from path. A missing file yields default settings.
+func LoadFrom(path string) (*Settings, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // G304: config path is chosen by the user
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Settings{}, nil
+		}
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	var settings Settings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if err := settings.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return &settings, nil
+}
+
+func (s *Settings) validate() error {
+	if s.Retention != nil && s.Retention.KeepVersions != nil && *s.Retention.KeepVersions < 1 {
+		return fmt.Errorf("retention.keepVersions must be at least 1, got %d", *s.Retention.KeepVersions)
+	}
+	for name, line := range s.Aliases {
+		if name == "" || strings.HasPrefix(name, "-") || strings.ContainsFunc(name, unicode.IsSpace) {
+			return fmt.Errorf("invalid alias name %q", name)
+		}
+		if _, err := SplitCommandLine(line); err != nil {
+			return fmt.Errorf("alias %s: %w", name, err)
+		}
+	}
+	if s.Viewer != nil {
+		if _, err := SplitCommandLine(*s.Viewer); err != nil {
+			return fmt.Errorf("viewer: %w", err)
+		}
+	}
+	if sm := s.Summarizer; sm != nil {
+		if strings.TrimSpace(sm.Command) == "" {
+			return fmt.Errorf("summarizer.command must be set")
+		}
+		if _, err := SplitCommandLine(sm.Command); err != nil {
+			return fmt.Errorf("summarizer.command: %w", err)
+		}
+		if sm.MinSize != nil && *sm.MinSize < 0 {
+			return fmt.Errorf("summarizer.minSize must not be negative, got %d", *sm.MinSize)
+		}
+	}
+	if q := s.Quota; q != nil {
+		if q.MaxEntries != nil && *q.MaxEntries < 1 {
+			return fmt.Errorf("quota.maxEntries must be at least 1, got %d", *q.MaxEntries)
+		}
+		if q.MaxBytes != nil && *q.MaxBytes < 1 {
+			return fmt.Errorf("quota.maxBytes must be at least 1, got %d", *q.MaxBytes)
+		}
+		if q.OnExceed != nil && *q.OnExceed != QuotaFail && *q.OnExceed != QuotaPrune {
+			return fmt.Errorf("invalid quota.onExceed: %s (valid values: %s, %s)", *q.OnExceed, QuotaFail, QuotaPrune)
+		}
+	}
+	for scopeType, templates := range s.KeyTemplates {
+		switch scopeType {
+		case "global", "repository", "branch", "worktree":
+		default:
+			return fmt.Errorf("invalid keyTemplates scope type: %s (valid values: global, repository, branch, worktree)", scopeType)
+		}
+		for key, t := range templates {
+			if err := entrykey.Validate(entrykey.Normalize(key)); err != nil {
+				return fmt.Errorf("keyTemplates.%s: %w", scopeType, err)
+			}
+			if (t.Content == nil) == (t.File == nil) {
+				return fmt.Errorf("keyTemplates.%s.%s: set exactly one of content or file", scopeType, key)
+			}
+		}
+	}
+	for _, key := range s.ContextKeys {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("contextKeys must not contain empty keys")
+		}
+	}
+	for i, n := range s.Notifiers {
+		if err := n.validate(); err != nil {
+			return fmt.Errorf("notifiers[%d]: %w", i, err)
+		}
+	}
+	for i, v := range s.Validators {
+		if err := v.validate(); err != nil {
+			return fmt.Errorf("validators[%d]: %w", i, err)
+		}
+	}
+	if d := s.Display; d != nil {
+		if d.Timezone != nil {
+			if _, err := time.LoadLocation(*d.Timezone); err != nil {
+				return fmt.Errorf("display.timezone: %w", err)
+			}
+		}
+		if d.TimeFormat != nil && !isTimeLayout(timeLayout(*d.TimeFormat)) {
+			return fmt.Errorf("display.timeFormat %q is not a Go time layout such as %q", *d.TimeFormat, DefaultTimeFormat)
+		}
+		if d.DateFormat != nil && !isTimeLayout(*d.DateFormat) {
+			return fmt.Errorf("display.dateFormat %q is not a Go time layout such as %q", *d.DateFormat, DefaultDateFormat)
+		}
+	}
+	return nil
+}
+
+func (n NotifierSettings) validate() error {
+	u, err := url.Parse(n.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http or https URL, got %q", n.URL)
+	}
+	switch format := n.NotifyFormat(); format {
+	case NotifySlack, NotifyDiscord, NotifyJSON:
+	default:
+		return fmt.Errorf("invalid format: %s (valid values: %s, %s, %s)", format, NotifySlack, NotifyDiscord, NotifyJSON)
+	}
+	for _, pattern := range append(slices.Clone(n.Scopes), n.Keys...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	for _, event := range n.Events {
+		if !slices.Contains(NotifyEvents, event) {
+			return fmt.Errorf("invalid event: %s (valid values: %s)", event, strings.Join(NotifyEvents, ", "))
+		}
+	}
+	return nil
+}
+
+// isTimeLayout reports whether layout contains at least one element of Go's
+// reference time, so that formatting with it shows something of the time.
+func isTimeLayout(layout string) bool {
+	return layout != "" && time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC).Format(layout) != layout
+}
+
+// timeLayout resolves the names accepted in place of a layout.
+func timeLayout(format string) string {
+	if strings.EqualFold(format, "rfc3339") {
+		return time.RFC3339
+	}
+	return format
+}
+
+// ShouldVerifyOnRead reports whether reads should verify content hashes.
+func (s *Settings) ShouldVerifyOnRead() bool {
+	if s == nil || s.VerifyOnRead == nil {
+		return true
+	}
+	return *s.VerifyOnRead
+}
+
+// IsSharedStorage reports whether shared (network filesystem) mode is enabled.
+func (s *Settings) IsSharedStorage() bool {
+	if s == nil || s.SharedStorage == nil {
+		return CloudSyncService(GetVaultDir()) != ""
+	}
+	return *s.SharedStorage
+}
+
+// ShouldCaptureEnvironment reports whether writes record their environment.
+func (s *Settings) ShouldCaptureEnvironment() bool {
+	return s != nil && s.CaptureEnvironment != nil && *s.CaptureEnvironment
+}
+
+// ShouldTrackReads reports whether reads are recorded.
+func (s *Settings) ShouldTrackReads() bool {
+	return s != nil && s.TrackReads != nil && *s.TrackReads
+}
+
+// ShouldRequireGit reports whether scope detection fails without git.
+func (s *Settings) ShouldRequireGit() bool {
+	return s != nil && s.RequireGit != nil && *s.RequireGit
+}
+
+// RetentionKeepVersions returns how many versions per key the retention
+// policy keeps, or 0 when no policy is configured.
+func (s *Settings) RetentionKeepVersions() int {
+	if s == nil || s.Retention == nil || s.Retention.KeepVersions == nil {
+		return 0
+	}
+	return *s.Retention.KeepVersions
+}
+
+// RemoteDatabaseURL returns the configured remote index URL, or "" to use
+// the local database file.
+func (s *Settings) RemoteDatabaseURL() string {
+	if s == nil || s.DatabaseURL == nil {
+		return ""
+	}
+	return *s.DatabaseURL
+}
+
+// SyncKeyPath returns the configured sync key file, or "" when sync data is
+// not encrypted.
+func (s *Settings) SyncKeyPath() string {
+	if s == nil || s.SyncKeyFile == nil {
+		return ""
+	}
+	return *s.SyncKeyFile
+}
+
+// ViewerCommand returns the configured viewer command line, or "" to use
+// the operating system's default handler.
+func (s *Settings) ViewerCommand() string {
+	if s == nil || s.Viewer == nil {
+		return ""
+	}
+	return *s.Viewer
+}
+
+// DisplayLocation returns the timezone timestamps are displayed in.
+func (s *Settings) DisplayLocation() *time.Location {
+	if s == nil || s.Display == nil || s.Display.Timezone == nil {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(*s.Display.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// DisplayTimeFormat returns the layout timestamps are displayed with.
+func (s *Settings) DisplayTimeFormat() string {
+	if s == nil || s.Display == nil || s.Display.TimeFormat == nil {
+		return DefaultTimeFormat
+	}
+	return timeLayout(*s.Display.TimeFormat)
+}
+
+// DisplayDateFormat returns the layout dates are displayed with.
+func (s *Settings) DisplayDateFormat() string {
+	if s == nil || s.Display == nil || s.Display.DateFormat == nil {
+		return DefaultDateFormat
+	}
+	return *s.Display.DateFormat
+}
+
+// SummarizerCommand returns the configured summarizer command line, or ""
+// when summaries are disabled.
+func (s *Settings) SummarizerCommand() string {
+	if s == nil || s.Summarizer == nil {
+		return ""
+	}
+	return s.Summarizer.Command
+}
+
+// SummarizeMinSize returns the content size from which versions are
+// summarized.
+func (s *Settings) SummarizeMinSize() int {
+	if s == nil || s.Summarizer == nil || s.Summarizer.MinSize == nil {
+		return DefaultSummarizeMinSize
+	}
+	return *s.Summarizer.MinSize
+}
+
+// QuotaMaxEntries returns the number of keys each scope may hold, or 0 for
+// no limit.
+func (s *Settings) QuotaMaxEntries() int {
+	if s == nil || s.Quota == nil || s.Quota.MaxEntries == nil {
+		return 0
+	}
+	return *s.Quota.MaxEntries
+}
+
+// QuotaMaxBytes returns the total version size each scope may hold, or 0
+// for no limit.
+func (s *Settings) QuotaMaxBytes() int64 {
+	if s == nil || s.Quota == nil || s.Quota.MaxBytes == nil {
+		return 0
+	}
+	return *s.Quota.MaxBytes
+}
+
+// KeyTemplatesFor returns the key templates for new scopes of scopeType,
+// or nil when there are none.
+func (s *Settings) KeyTemplatesFor(scopeType string) map[string]KeyTemplate {
+	if s == nil {
+		return nil
+	}
+	return s.KeyTemplates[scopeType]
+}
+
+// ContextKeyList returns the keys vault_context reads.
+func (s *Settings) ContextKeyList() []string {
+	if s == nil || len(s.ContextKeys) == 0 {
+		return DefaultContextKeys
+	}
+	return s.ContextKeys
+}
+
+// QuotaPrunes reports whether exceeding quota.maxBytes prunes old versions
+// instead of failing the write.
+func (s *Settings) QuotaPrunes() bool {
+	return s != nil && s.Quota != nil && s.Quota.OnExceed != nil && *s.Quota.OnExceed == QuotaPrune
+}
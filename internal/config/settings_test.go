@@ -0,0 +1,342 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetConfigPathWithExplicitEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.json")
+	t.Setenv("VAULT_CONFIG", path)
+
+	if got := GetConfigPath(); got != path {
+		t.Fatalf("expected %q, got %q", path, got)
+	}
+}
+
+func TestGetConfigPathFallsBackToXDG(t *testing.T) {
+	xdgDir := filepath.Join(t.TempDir(), "xdg")
+	t.Setenv("VAULT_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", xdgDir)
+
+	want := filepath.Join(xdgDir, "vault.md", "config.json")
+	if got := GetConfigPath(); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestLoadFromMissingFileReturnsDefaults(t *testing.T) {
+	settings, err := LoadFrom(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("LoadFrom error: %v", err)
+	}
+	if !settings.ShouldVerifyOnRead() {
+		t.Fatalf("expected verification to be enabled by default")
+	}
+	if settings.ShouldTrackReads() {
+		t.Fatalf("expected read tracking to be disabled by default")
+	}
+	if settings.ShouldRequireGit() {
+		t.Fatalf("expected git to be optional by default")
+	}
+}
+
+func TestLoadFromParsesSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"verifyOnRead": false, "trackReads": true, "requireGit": true}`), 0o600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	settings, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom error: %v", err)
+	}
+	if settings.ShouldVerifyOnRead() {
+		t.Fatalf("expected verification to be disabled")
+	}
+	if !settings.ShouldTrackReads() {
+		t.Fatalf("expected read tracking to be enabled")
+	}
+	if !settings.ShouldRequireGit() {
+		t.Fatalf("expected git to be required")
+	}
+}
+
+func TestLoadFromRejectsInvalidJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{`), 0o600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	if _, err := LoadFrom(path); err == nil {
+		t.Fatalf("expected parse error")
+	}
+}
+
+func TestLoadFromParsesRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"retention": {"keepVersions": 5}}`), 0o600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	settings, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom error: %v", err)
+	}
+	if got := settings.RetentionKeepVersions(); got != 5 {
+		t.Fatalf("expected keepVersions 5, got %d", got)
+	}
+}
+
+func TestLoadFromRejectsInvalidRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"retention": {"keepVersions": 0}}`), 0o600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	if _, err := LoadFrom(path); err == nil {
+		t.Fatalf("expected validation error")
+	}
+}
+
+func TestLoadFromParsesDisplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := `{"display": {"timezone": "Asia/Tokyo", "timeFormat": "RFC3339", "dateFormat": "02 Jan 2006"}}`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	settings, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom error: %v", err)
+	}
+	if got := settings.DisplayLocation().String(); got != "Asia/Tokyo" {
+		t.Fatalf("expected Asia/Tokyo, got %s", got)
+	}
+	if got := settings.DisplayTimeFormat(); got != time.RFC3339 {
+		t.Fatalf("expected RFC3339 layout, got %q", got)
+	}
+	if got := settings.DisplayDateFormat(); got != "02 Jan 2006" {
+		t.Fatalf("expected date layout, got %q", got)
+	}
+}
+
+func TestDisplayDefaults(t *testing.T) {
+	settings := &Settings{}
+	if settings.DisplayLocation() != time.Local {
+		t.Fatalf("expected local timezone by default")
+	}
+	if got := settings.DisplayTimeFormat(); got != DefaultTimeFormat {
+		t.Fatalf("expected default time format, got %q", got)
+	}
+}
+
+func TestLoadFromRejectsInvalidDisplay(t *testing.T) {
+	for _, config := range []string{
+		`{"display": {"timezone": "Mars/Olympus"}}`,
+		`{"display": {"timeFormat": "yyyy-mm-dd"}}`,
+		`{"display": {"dateFormat": ""}}`,
+	} {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+			t.Fatalf("WriteFile error: %v", err)
+		}
+		if _, err := LoadFrom(path); err == nil {
+			t.Fatalf("expected validation error for %s", config)
+		}
+	}
+}
+
+func TestLoadFromParsesSummarizer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := `{"summarizer": {"command": "llm -s 'Summarize in one sentence'", "minSize": 100}}`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	settings, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom error: %v", err)
+	}
+	if got := settings.SummarizerCommand(); got != "llm -s 'Summarize in one sentence'" {
+		t.Fatalf("unexpected summarizer command %q", got)
+	}
+	if got := settings.SummarizeMinSize(); got != 100 {
+		t.Fatalf("expected minSize 100, got %d", got)
+	}
+	if got := (&Settings{}).SummarizeMinSize(); got != DefaultSummarizeMinSize {
+		t.Fatalf("expected default minSize, got %d", got)
+	}
+}
+
+func TestLoadFromRejectsInvalidSummarizer(t *testing.T) {
+	for _, config := range []string{
+		`{"summarizer": {}}`,
+		`{"summarizer": {"command": "llm 'unterminated"}}`,
+		`{"summarizer": {"command": "llm", "minSize": -1}}`,
+	} {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+			t.Fatalf("WriteFile error: %v", err)
+		}
+		if _, err := LoadFrom(path); err == nil {
+			t.Fatalf("expected validation error for %s", config)
+		}
+	}
+}
+
+func TestLoadFromParsesQuota(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := `{"quota": {"maxEntries": 100, "maxBytes": 1048576, "onExceed": "prune"}}`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	settings, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom error: %v", err)
+	}
+	if got := settings.QuotaMaxEntries(); got != 100 {
+		t.Fatalf("expected maxEntries 100, got %d", got)
+	}
+	if got := settings.QuotaMaxBytes(); got != 1048576 {
+		t.Fatalf("expected maxBytes 1048576, got %d", got)
+	}
+	if !settings.QuotaPrunes() {
+		t.Fatalf("expected onExceed prune")
+	}
+	if (&Settings{}).QuotaMaxEntries() != 0 || (&Settings{}).QuotaMaxBytes() != 0 || (&Settings{}).QuotaPrunes() {
+		t.Fatalf("expected no quota by default")
+	}
+}
+
+func TestLoadFromRejectsInvalidQuota(t *testing.T) {
+	for _, config := range []string{
+		`{"quota": {"maxEntries": 0}}`,
+		`{"quota": {"maxBytes": -1}}`,
+		`{"quota": {"onExceed": "delete"}}`,
+	} {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+			t.Fatalf("WriteFile error: %v", err)
+		}
+		if _, err := LoadFrom(path); err == nil {
+			t.Fatalf("expected validation error for %s", config)
+		}
+	}
+}
+
+func TestLoadFromParsesKeyTemplates(t *testing.T) {
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "progress.md")
+	if err := os.WriteFile(templatePath, []byte("# Progress\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	path := filepath.Join(dir, "config.json")
+	config := `{"keyTemplates": {"branch": {"plan": {"content": "# Plan\n"}, "progress": {"file": "` + filepath.ToSlash(templatePath) + `"}}}}`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	settings, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom error: %v", err)
+	}
+	templates := settings.KeyTemplatesFor("branch")
+	if len(templates) != 2 {
+		t.Fatalf("expected 2 branch templates, got %v", templates)
+	}
+	for key, want := range map[string]string{"plan": "# Plan\n", "progress": "# Progress\n"} {
+		got, err := templates[key].Load()
+		if err != nil {
+			t.Fatalf("Load %s error: %v", key, err)
+		}
+		if got != want {
+			t.Fatalf("template %s = %q, want %q", key, got, want)
+		}
+	}
+	if settings.KeyTemplatesFor("global") != nil || (&Settings{}).KeyTemplatesFor("branch") != nil {
+		t.Fatalf("expected no templates for unconfigured scope types")
+	}
+}
+
+func TestLoadFromRejectsInvalidKeyTemplates(t *testing.T) {
+	for _, config := range []string{
+		`{"keyTemplates": {"team": {"plan": {"content": ""}}}}`,
+		`{"keyTemplates": {"branch": {"": {"content": ""}}}}`,
+		`{"keyTemplates": {"branch": {"_vault/plan": {"content": ""}}}}`,
+		`{"keyTemplates": {"branch": {"../plan": {"content": ""}}}}`,
+		`{"keyTemplates": {"branch": {"plan": {}}}}`,
+		`{"keyTemplates": {"branch": {"plan": {"content": "x", "file": "plan.md"}}}}`,
+	} {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+			t.Fatalf("WriteFile error: %v", err)
+		}
+		if _, err := LoadFrom(path); err == nil {
+			t.Fatalf("expected validation error for %s", config)
+		}
+	}
+}
+
+func TestContextKeysDefaultAndOverride(t *testing.T) {
+	if got := strings.Join((&Settings{}).ContextKeyList(), ","); got != "plan,conventions,decisions" {
+		t.Fatalf("expected default context keys, got %s", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"contextKeys": ["plan", "notes/setup"]}`), 0o600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	settings, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom error: %v", err)
+	}
+	if got := strings.Join(settings.ContextKeyList(), ","); got != "plan,notes/setup" {
+		t.Fatalf("expected configured context keys, got %s", got)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"contextKeys": ["plan", " "]}`), 0o600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if _, err := LoadFrom(path); err == nil {
+		t.Fatalf("expected an empty context key to be rejected")
+	}
+}
+
+func TestLoadFromRejectsInvalidNotifiers(t *testing.T) {
+	for _, config := range []string{
+		`{"notifiers": [{"url": "hooks.slack.com/services/x"}]}`,
+		`{"notifiers": [{"url": "https://hooks.slack.com/services/x", "format": "teams"}]}`,
+		`{"notifiers": [{"url": "https://hooks.slack.com/services/x", "events": ["read"]}]}`,
+		`{"notifiers": [{"url": "https://hooks.slack.com/services/x", "keys": ["["]}]}`,
+	} {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+			t.Fatalf("WriteFile error: %v", err)
+		}
+		if _, err := LoadFrom(path); err == nil {
+			t.Fatalf("expected validation error for %s", config)
+		}
+	}
+}
+
+func TestLoadFromRejectsInvalidValidators(t *testing.T) {
+	for _, config := range []string{
+		`{"validators": [{"prefix": "adr/"}]}`,
+		`{"validators": [{"prefix": "adr/", "pattern": "^#", "schemaFile": "adr.json"}]}`,
+		`{"validators": [{"prefix": "adr/", "pattern": "("}]}`,
+		`{"validators": [{"prefix": "adr/", "pattern": "^#", "onFail": "ignore"}]}`,
+	} {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+			t.Fatalf("WriteFile error: %v", err)
+		}
+		if _, err := LoadFrom(path); err == nil {
+			t.Fatalf("expected validation error for %s", config)
+		}
+	}
+}
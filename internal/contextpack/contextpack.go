@@ -0,0 +1,320 @@
+// Package contextpack assembles several entries into one document that fits
+// a token budget, for pasting into an LLM prompt.
+package contextpack
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/choplin/vault.md/internal/language"
+)
+
+// Strategy decides what to do with entries that do not fit the budget.
+type Strategy string
+
+const (
+	// Truncate includes entries in order, cutting the one that crosses the
+	// budget and omitting the rest.
+	Truncate Strategy = "truncate"
+	// Proportional gives every entry an equal share of the budget; entries
+	// smaller than their share pass the remainder on to the others.
+	Proportional Strategy = "proportional"
+	// Summary includes entries in order and falls back to an entry's
+	// stored summary when its content no longer fits.
+	Summary Strategy = "summary"
+	// Drop includes only whole entries, skipping those that do not fit.
+	Drop Strategy = "drop"
+)
+
+// ParseStrategy validates a strategy name.
+func ParseStrategy(name string) (Strategy, error) {
+	switch s := Strategy(strings.ToLower(name)); s {
+	case Truncate, Proportional, Summary, Drop:
+		return s, nil
+	case "":
+		return Truncate, nil
+	default:
+		return "", fmt.Errorf("invalid strategy: %s (valid values: truncate, proportional, summary, drop)", name)
+	}
+}
+
+// charsPerToken approximates how many characters one token covers in
+// English prose and code. It is deliberately simple: the budget is a guide
+// for the reader's model, not an exact count for one tokenizer.
+const charsPerToken = 4
+
+// minSectionTokens is the smallest content allowance worth including; a
+// section that would get less is omitted instead.
+const minSectionTokens = 32
+
+// EstimateTokens approximates the number of tokens in s.
+func EstimateTokens(s string) int {
+	return (utf8.RuneCountInString(s) + charsPerToken - 1) / charsPerToken
+}
+
+// Document is one entry to pack.
+type Document struct {
+	Key         string
+	Scope       string
+	Version     int64
+	Description string
+	Language    string
+	Summary     string
+	Content     string
+}
+
+// Mode describes how a document ended up in the pack.
+type Mode string
+
+// Modes reported in Item.
+const (
+	ModeFull       Mode = "full"
+	ModeTruncated  Mode = "truncated"
+	ModeSummarized Mode = "summarized"
+	ModeOmitted    Mode = "omitted"
+)
+
+// Item reports what Build did with one document.
+type Item struct {
+	Key     string
+	Version int64
+	Mode    Mode
+	// Tokens is the estimated size of the document's section in the pack.
+	Tokens int
+}
+
+// Result is a packed document.
+type Result struct {
+	Text   string
+	Tokens int
+	Items  []Item
+}
+
+// Build packs docs in order. A budget of zero or less means no limit.
+func Build(docs []Document, budget int, strategy Strategy) *Result {
+	sections := make([]section, len(docs))
+	for i, doc := range docs {
+		sections[i] = newSection(doc)
+	}
+
+	if budget > 0 {
+		switch strategy {
+		case Proportional:
+			allocateProportional(sections, budget)
+		case Drop:
+			allocateDrop(sections, budget)
+		default:
+			allocateInOrder(sections, budget, strategy == Summary)
+		}
+	}
+
+	result := &Result{Items: make([]Item, 0, len(sections))}
+	var b strings.Builder
+	var omitted []string
+	for _, s := range sections {
+		item := Item{Key: s.doc.Key, Version: s.doc.Version, Mode: s.mode}
+		if s.mode == ModeOmitted {
+			omitted = append(omitted, s.doc.Key)
+		} else {
+			text := s.render()
+			item.Tokens = EstimateTokens(text)
+			if b.Len() > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(text)
+		}
+		result.Items = append(result.Items, item)
+	}
+	if len(omitted) > 0 {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "_Omitted to fit the token budget: %s_\n", strings.Join(omitted, ", "))
+	}
+	result.Text = b.String()
+	result.Tokens = EstimateTokens(result.Text)
+	return result
+}
+
+// section is a document together with how much of it will be included.
+type section struct {
+	doc    Document
+	header string
+	mode   Mode
+	// allowance is the number of content tokens to keep when truncating.
+	allowance int
+}
+
+func newSection(doc Document) section {
+	var h strings.Builder
+	fmt.Fprintf(&h, "## %s\n\n", doc.Key)
+	meta := []string{fmt.Sprintf("version %d", doc.Version)}
+	if doc.Scope != "" {
+		meta = append(meta, doc.Scope)
+	}
+	if doc.Language != "" {
+		meta = append(meta, doc.Language)
+	}
+	fmt.Fprintf(&h, "_%s_\n", strings.Join(meta, " · "))
+	if doc.Description != "" {
+		fmt.Fprintf(&h, "\n> %s\n", strings.ReplaceAll(doc.Description, "\n", " "))
+	}
+	h.WriteString("\n")
+	return section{doc: doc, header: h.String(), mode: ModeFull}
+}
+
+func (s *section) headerTokens() int {
+	return EstimateTokens(s.header) + sectionOverhead
+}
+
+func (s *section) fullTokens() int {
+	return s.headerTokens() + EstimateTokens(s.doc.Content)
+}
+
+func (s *section) summaryTokens() int {
+	return s.headerTokens() + EstimateTokens(summaryText(s.doc.Summary))
+}
+
+// sectionOverhead covers the code fence and truncation note around content.
+const sectionOverhead = 24
+
+func (s *section) render() string {
+	var body string
+	switch s.mode {
+	case ModeSummarized:
+		return s.header + summaryText(s.doc.Summary)
+	case ModeTruncated:
+		body = truncate(s.doc.Content, s.allowance)
+	default:
+		body = s.doc.Content
+	}
+	if !strings.HasSuffix(body, "\n") {
+		body += "\n"
+	}
+
+	var b strings.Builder
+	b.WriteString(s.header)
+	if fenced(s.doc.Language) {
+		fence := codeFence(body)
+		fmt.Fprintf(&b, "%s%s\n%s%s\n", fence, s.doc.Language, body, fence)
+	} else {
+		b.WriteString(body)
+	}
+	if s.mode == ModeTruncated {
+		fmt.Fprintf(&b, "\n_[truncated: %d of about %d tokens shown]_\n", s.allowance, EstimateTokens(s.doc.Content))
+	}
+	return b.String()
+}
+
+func summaryText(summary string) string {
+	return "Summary (full content omitted): " + summary + "\n"
+}
+
+// fenced reports whether content of lang is wrapped in a code fence.
+// Markdown and plain text are included as they are.
+func fenced(lang string) bool {
+	return lang != "" && lang != language.Markdown && lang != language.Text
+}
+
+// codeFence returns a backtick fence longer than any run in content.
+func codeFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// truncate keeps about tokens worth of content, ending at a line break when
+// one is close enough to the cut.
+func truncate(content string, tokens int) string {
+	limit := tokens * charsPerToken
+	if utf8.RuneCountInString(content) <= limit {
+		return content
+	}
+	cut := 0
+	for i := range content {
+		if limit == 0 {
+			cut = i
+			break
+		}
+		limit--
+	}
+	kept := content[:cut]
+	if nl := strings.LastIndexByte(kept, '\n'); nl >= len(kept)*3/4 {
+		kept = kept[:nl+1]
+	}
+	return kept
+}
+
+// allocateInOrder fills the budget front to back. With summaries, a
+// section that does not fit whole is replaced by its summary when that fits.
+func allocateInOrder(sections []section, budget int, useSummaries bool) {
+	remaining := budget
+	for i := range sections {
+		s := &sections[i]
+		full := s.fullTokens()
+		switch {
+		case full <= remaining:
+			remaining -= full
+		case useSummaries && s.doc.Summary != "" && s.summaryTokens() <= remaining:
+			s.mode = ModeSummarized
+			remaining -= s.summaryTokens()
+		case remaining-s.headerTokens() >= minSectionTokens:
+			s.mode = ModeTruncated
+			s.allowance = remaining - s.headerTokens()
+			remaining = 0
+		default:
+			s.mode = ModeOmitted
+		}
+	}
+}
+
+// allocateProportional shares the budget equally, handing the unused part
+// of small sections' shares to the larger ones.
+func allocateProportional(sections []section, budget int) {
+	order := make([]int, len(sections))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return sections[a].fullTokens() - sections[b].fullTokens()
+	})
+
+	remaining := budget
+	for n, idx := range order {
+		s := &sections[idx]
+		share := remaining / (len(order) - n)
+		full := s.fullTokens()
+		switch {
+		case full <= share:
+			remaining -= full
+		case share-s.headerTokens() >= minSectionTokens:
+			s.mode = ModeTruncated
+			s.allowance = share - s.headerTokens()
+			remaining -= share
+		default:
+			s.mode = ModeOmitted
+		}
+	}
+}
+
+// allocateDrop keeps whole sections in order, skipping any that do not fit.
+func allocateDrop(sections []section, budget int) {
+	remaining := budget
+	for i := range sections {
+		s := &sections[i]
+		if full := s.fullTokens(); full <= remaining {
+			remaining -= full
+		} else {
+			s.mode = ModeOmitted
+		}
+	}
+}
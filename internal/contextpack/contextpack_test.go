@@ -0,0 +1,109 @@
+package contextpack
+
+import (
+	"strings"
+	"testing"
+)
+
+func modes(result *Result) string {
+	var parts []string
+	for _, item := range result.Items {
+		parts = append(parts, item.Key+"="+string(item.Mode))
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestBuildUnlimited(t *testing.T) {
+	result := Build([]Document{
+		{Key: "notes", Version: 2, Scope: "global", Description: "meeting notes", Language: "markdown", Content: "# Notes\n\nShip it."},
+		{Key: "main.go", Version: 1, Language: "go", Content: "package main\n"},
+	}, 0, Truncate)
+
+	if got := modes(result); got != "notes=full,main.go=full" {
+		t.Fatalf("unexpected modes %s", got)
+	}
+	for _, want := range []string{"## notes\n", "_version 2 · global · markdown_", "> meeting notes", "# Notes\n\nShip it.\n", "```go\npackage main\n```"} {
+		if !strings.Contains(result.Text, want) {
+			t.Fatalf("expected %q in pack:\n%s", want, result.Text)
+		}
+	}
+	if strings.Contains(result.Text, "```markdown") {
+		t.Fatalf("markdown should not be fenced:\n%s", result.Text)
+	}
+}
+
+func TestBuildTruncate(t *testing.T) {
+	long := strings.Repeat("line of text\n", 200)
+	result := Build([]Document{
+		{Key: "a", Version: 1, Content: "short"},
+		{Key: "b", Version: 1, Content: long},
+		{Key: "c", Version: 1, Content: "never reached"},
+	}, 200, Truncate)
+
+	if got := modes(result); got != "a=full,b=truncated,c=omitted" {
+		t.Fatalf("unexpected modes %s", got)
+	}
+	if !strings.Contains(result.Text, "_[truncated:") || !strings.Contains(result.Text, "_Omitted to fit the token budget: c_") {
+		t.Fatalf("expected truncation and omission notes:\n%s", result.Text)
+	}
+	if result.Tokens > 230 {
+		t.Fatalf("pack of %d tokens is far over the budget", result.Tokens)
+	}
+}
+
+func TestBuildSummary(t *testing.T) {
+	long := strings.Repeat("word ", 1000)
+	result := Build([]Document{
+		{Key: "big", Version: 1, Summary: "A long essay about words.", Content: long},
+		{Key: "small", Version: 1, Content: "fits"},
+	}, 150, Summary)
+
+	if got := modes(result); got != "big=summarized,small=full" {
+		t.Fatalf("unexpected modes %s", got)
+	}
+	if !strings.Contains(result.Text, "A long essay about words.") {
+		t.Fatalf("expected summary in pack:\n%s", result.Text)
+	}
+}
+
+func TestBuildProportional(t *testing.T) {
+	long := strings.Repeat("x", 4000)
+	result := Build([]Document{
+		{Key: "one", Version: 1, Content: long},
+		{Key: "two", Version: 1, Content: long},
+		{Key: "tiny", Version: 1, Content: "small"},
+	}, 600, Proportional)
+
+	if got := modes(result); got != "one=truncated,two=truncated,tiny=full" {
+		t.Fatalf("unexpected modes %s", got)
+	}
+	if a, b := result.Items[0].Tokens, result.Items[1].Tokens; a != b {
+		t.Fatalf("expected equal shares, got %d and %d", a, b)
+	}
+}
+
+func TestBuildDrop(t *testing.T) {
+	result := Build([]Document{
+		{Key: "big", Version: 1, Content: strings.Repeat("x", 4000)},
+		{Key: "small", Version: 1, Content: "fits"},
+	}, 100, Drop)
+
+	if got := modes(result); got != "big=omitted,small=full" {
+		t.Fatalf("unexpected modes %s", got)
+	}
+}
+
+func TestCodeFenceAvoidsContentBackticks(t *testing.T) {
+	if got := codeFence("use ```go blocks"); got != "````" {
+		t.Fatalf("expected a four-backtick fence, got %q", got)
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	if s, err := ParseStrategy(""); err != nil || s != Truncate {
+		t.Fatalf("expected truncate default, got %q, %v", s, err)
+	}
+	if _, err := ParseStrategy("random"); err == nil {
+		t.Fatalf("expected error for unknown strategy")
+	}
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Backend opens a connection to a remote index database. The database must
+// speak SQLite's SQL dialect, since queries and migrations are shared with
+// the local file backend.
+type Backend func(rawURL string) (*sql.DB, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]Backend{}
+)
+
+// RegisterBackend makes a remote index backend available for URLs with the
+// given scheme (for example "libsql"). Backends are registered from an
+// init function, typically in a file behind a build tag so the default
+// binary does not carry the client library.
+func RegisterBackend(scheme string, open Backend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	backends[strings.ToLower(scheme)] = open
+}
+
+// openBackend opens rawURL with the backend registered for its scheme.
+func openBackend(rawURL string) (*sql.DB, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" {
+		return nil, fmt.Errorf("invalid databaseUrl %q: expected scheme://...", rawURL)
+	}
+
+	backendsMu.RLock()
+	open, ok := backends[strings.ToLower(u.Scheme)]
+	var available []string
+	for scheme := range backends {
+		available = append(available, scheme)
+	}
+	backendsMu.RUnlock()
+
+	if !ok {
+		sort.Strings(available)
+		if len(available) == 0 {
+			return nil, fmt.Errorf("no database backend for %s:// in this build (no remote backends are compiled in)", u.Scheme)
+		}
+		return nil, fmt.Errorf("no database backend for %s:// in this build (available: %s)", u.Scheme, strings.Join(available, ", "))
+	}
+
+	db, err := open(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %w", u.Scheme, err)
+	}
+	return db, nil
+}
@@ -0,0 +1,49 @@
+//go:build libsql
+
+package database
+
+import (
+	"database/sql"
+	"net/url"
+	"os"
+
+	"github.com/tursodatabase/libsql-client-go/libsql"
+)
+
+// Built with -tags libsql, the index can live in a libSQL server or a Turso
+// database: databaseUrl "libsql://<host>".
+func init() {
+	RegisterBackend("libsql", openLibSQL)
+}
+
+// openLibSQL opens a libsql:// URL. The auth token comes from the URL's
+// authToken parameter or, to keep it out of the config file, from
+// VAULT_DATABASE_AUTH_TOKEN; tls=0 talks plain HTTP to a local sqld.
+func openLibSQL(rawURL string) (*sql.DB, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+
+	var opts []libsql.Option
+	token := query.Get("authToken")
+	if token == "" {
+		token = os.Getenv("VAULT_DATABASE_AUTH_TOKEN")
+	}
+	if token != "" {
+		opts = append(opts, libsql.WithAuthToken(token))
+	}
+	if tls := query.Get("tls"); tls != "" {
+		opts = append(opts, libsql.WithTls(tls != "0"))
+	}
+	query.Del("authToken")
+	query.Del("tls")
+	u.RawQuery = query.Encode()
+
+	connector, err := libsql.NewConnector(u.String(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
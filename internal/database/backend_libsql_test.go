@@ -0,0 +1,37 @@
+//go:build libsql
+
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLibSQLBackend(t *testing.T) {
+	backendsMu.RLock()
+	_, ok := backends["libsql"]
+	backendsMu.RUnlock()
+	if !ok {
+		t.Fatal("libsql backend is not registered")
+	}
+
+	t.Setenv("VAULT_DATABASE_AUTH_TOKEN", "token")
+	for _, rawURL := range []string{
+		"libsql://vault-team.turso.io",
+		"libsql://vault-team.turso.io?authToken=other",
+		"libsql://localhost:8080?tls=0",
+	} {
+		db, err := openLibSQL(rawURL)
+		if err != nil {
+			t.Fatalf("openLibSQL(%q) failed: %v", rawURL, err)
+		}
+		_ = db.Close()
+	}
+
+	if _, err := openLibSQL("libsql://localhost?tls=0"); err == nil || !strings.Contains(err.Error(), "port") {
+		t.Errorf("plain HTTP without a port = %v, want an error", err)
+	}
+	if _, err := openBackend("libsql://vault-team.turso.io?unknown=1"); err == nil {
+		t.Error("unknown query parameters should be rejected")
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/sqlite"
@@ -25,24 +28,53 @@ import (
 type Context struct {
 	DB      *sql.DB
 	Queries *sqldb.Queries
+
+	stmts     *stmtCache
+	writeLock *writeLock
+	// tx is set on Contexts handed out by RunInTx; savepoints counts the
+	// savepoints open on it.
+	tx         *sql.Tx
+	savepoints int
 }
 
 // CreateDatabase creates and initializes a database connection with migrations.
 func CreateDatabase(dbPath string) (*Context, error) {
 	path := dbPath
+	if path == "" && config.IsEphemeral() {
+		path = ":memory:"
+	}
+	useMemory := path == ":memory:"
+
+	var settings *config.Settings
+	if !useMemory {
+		var err error
+		settings, err = config.Load()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if path == "" {
+		if remote := settings.RemoteDatabaseURL(); remote != "" {
+			db, err := openBackend(remote)
+			if err != nil {
+				return nil, err
+			}
+			return initDatabase(db, nil)
+		}
 		path = config.GetDBPath()
 	}
 
-	useMemory := path == ":memory:"
-
 	if !useMemory {
 		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
 			return nil, fmt.Errorf("failed to create database directory: %w", err)
 		}
 	}
 
-	var dsn string
+	var (
+		dsn  string
+		lock *writeLock
+	)
 	if useMemory {
 		dsn = "file::memory:?cache=shared&_pragma=foreign_keys(ON)"
 	} else {
@@ -50,7 +82,13 @@ func CreateDatabase(dbPath string) (*Context, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to resolve database path: %w", err)
 		}
-		dsn = fmt.Sprintf("file:%s?_pragma=foreign_keys(ON)", filepath.ToSlash(absPath))
+		dsn = fmt.Sprintf("file:%s?_pragma=foreign_keys(ON)&_pragma=busy_timeout(%d)", filepath.ToSlash(absPath), busyTimeoutMillis)
+		if settings.IsSharedStorage() {
+			// WAL relies on shared memory, which network filesystems cannot
+			// provide; stay on the rollback journal and add the lockfile.
+			dsn += "&_pragma=journal_mode(DELETE)"
+			lock = newWriteLock(WriteLockFilePath(absPath))
+		}
 	}
 
 	db, err := sql.Open("sqlite", dsn)
@@ -58,6 +96,46 @@ func CreateDatabase(dbPath string) (*Context, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
+	return initDatabase(db, lock)
+}
+
+// OpenReadOnly opens an existing database file, such as a backup, without
+// creating it, migrating it, or writing to it.
+func OpenReadOnly(path string) (*Context, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve database path: %w", err)
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+
+	dsn := fmt.Sprintf("file:%s?mode=ro&_pragma=busy_timeout(%d)", filepath.ToSlash(absPath), busyTimeoutMillis)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	stmts := newStmtCache(db)
+	return &Context{
+		DB:      db,
+		Queries: sqldb.New(stmts),
+		stmts:   stmts,
+	}, nil
+}
+
+// initDatabase prepares a freshly opened connection: it enables foreign
+// keys, checks connectivity, applies migrations (under lock, if given), and
+// wraps it in a Context.
+func initDatabase(db *sql.DB, lock *writeLock) (*Context, error) {
 	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
 		_ = db.Close()
 		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
@@ -68,22 +146,45 @@ func CreateDatabase(dbPath string) (*Context, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	if err := runMigrations(db); err != nil {
+	if err := migrateLocked(db, lock); err != nil {
 		_ = db.Close()
 		return nil, err
 	}
 
+	stmts := newStmtCache(db)
 	return &Context{
-		DB:      db,
-		Queries: sqldb.New(db),
+		DB:        db,
+		Queries:   sqldb.New(stmts),
+		stmts:     stmts,
+		writeLock: lock,
 	}, nil
 }
 
+// migrateLocked applies migrations, holding the shared storage lockfile when
+// there is one so that two hosts opening an outdated vault at once do not
+// both migrate it.
+func migrateLocked(db *sql.DB, lock *writeLock) error {
+	if lock != nil {
+		release, err := lock.acquire(context.Background())
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+	return runMigrations(db)
+}
+
 // CloseDatabase closes the database connection.
 func CloseDatabase(ctx *Context) error {
 	if ctx == nil || ctx.DB == nil {
 		return nil
 	}
+	if ctx.stmts != nil {
+		if err := ctx.stmts.close(); err != nil {
+			_ = ctx.DB.Close()
+			return fmt.Errorf("failed to close prepared statements: %w", err)
+		}
+	}
 	return ctx.DB.Close()
 }
 
@@ -140,7 +241,36 @@ func ClearDatabase(ctx *Context) error {
 	return nil
 }
 
+// runMigrations brings the schema up to date. The applied version is mirrored
+// into PRAGMA user_version so that regular invocations can skip the migrator
+// entirely when the database is already at the embedded schema version.
 func runMigrations(db *sql.DB) error {
+	latest, err := latestMigrationVersion()
+	if err != nil {
+		return err
+	}
+
+	var current int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if current >= latest {
+		return nil
+	}
+
+	if err := applyMigrations(db); err != nil {
+		return err
+	}
+
+	// PRAGMA statements do not accept bound parameters.
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", latest)); err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+
+	return nil
+}
+
+func applyMigrations(db *sql.DB) error {
 	driver, err := sqlite.WithInstance(db, &sqlite.Config{})
 	if err != nil {
 		return fmt.Errorf("failed to initialise migrate driver: %w", err)
@@ -165,3 +295,44 @@ func runMigrations(db *sql.DB) error {
 
 	return nil
 }
+
+// SchemaVersion returns the schema version recorded in the database and the
+// latest version among the embedded migrations.
+func SchemaVersion(ctx *Context) (current, latest int, err error) {
+	if latest, err = latestMigrationVersion(); err != nil {
+		return 0, 0, err
+	}
+	if err := ctx.DB.QueryRow("PRAGMA user_version").Scan(&current); err != nil {
+		return 0, 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return current, latest, nil
+}
+
+// latestMigrationVersion returns the highest version among the embedded migration files.
+func latestMigrationVersion() (int, error) {
+	files, err := fs.ReadDir(migrations.Files, ".")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+
+	latest := 0
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasSuffix(name, ".up.sql") {
+			continue
+		}
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			continue
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return 0, fmt.Errorf("invalid migration file name %s: %w", name, err)
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+
+	return latest, nil
+}
@@ -49,8 +49,12 @@ func TestDatabaseCreationAndMigration(t *testing.T) {
 		t.Fatalf("failed to read schema_migrations: %v", err)
 	}
 
-	if version != 1 || dirty {
-		t.Fatalf("expected schema version 1 and clean state, got version=%d dirty=%t", version, dirty)
+	latest, err := latestMigrationVersion()
+	if err != nil {
+		t.Fatalf("latestMigrationVersion returned error: %v", err)
+	}
+	if version != latest || dirty {
+		t.Fatalf("expected schema version %d and clean state, got version=%d dirty=%t", latest, version, dirty)
 	}
 
 	tables := []string{"scopes", "entries", "entry_status", "versions"}
@@ -61,6 +65,84 @@ func TestDatabaseCreationAndMigration(t *testing.T) {
 	}
 }
 
+func TestSchemaVersionSkipsMigrator(t *testing.T) {
+	ctx := setupTestDB(t)
+
+	latest, err := latestMigrationVersion()
+	if err != nil {
+		t.Fatalf("latestMigrationVersion returned error: %v", err)
+	}
+
+	var userVersion int
+	if err := ctx.DB.QueryRow("PRAGMA user_version").Scan(&userVersion); err != nil {
+		t.Fatalf("failed to read user_version: %v", err)
+	}
+	if userVersion != latest {
+		t.Fatalf("expected user_version %d, got %d", latest, userVersion)
+	}
+
+	// Dropping the migrate bookkeeping table proves the migrator is not
+	// consulted once user_version is current.
+	if _, err := ctx.DB.Exec("DROP TABLE schema_migrations"); err != nil {
+		t.Fatalf("failed to drop schema_migrations: %v", err)
+	}
+
+	reopened, err := CreateDatabase("")
+	if err != nil {
+		t.Fatalf("CreateDatabase (reopen) returned error: %v", err)
+	}
+	defer func() {
+		_ = CloseDatabase(reopened)
+	}()
+
+	if tableExists(t, reopened.DB, "schema_migrations") {
+		t.Fatal("expected migrator to be skipped when user_version is current")
+	}
+}
+
+func TestSchemaVersionReportsMigratedDatabase(t *testing.T) {
+	ctx := setupTestDB(t)
+
+	current, latest, err := SchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("SchemaVersion returned error: %v", err)
+	}
+	if latest == 0 || current != latest {
+		t.Fatalf("expected current schema version to equal latest, got %d and %d", current, latest)
+	}
+}
+
+func TestOpenReadOnlyNeitherCreatesNorWrites(t *testing.T) {
+	setupTestDB(t)
+
+	missing := filepath.Join(t.TempDir(), "backup.db")
+	if _, err := OpenReadOnly(missing); err == nil {
+		t.Fatal("expected OpenReadOnly to fail for a missing file")
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Fatalf("expected OpenReadOnly not to create %s, got %v", missing, err)
+	}
+
+	ro, err := OpenReadOnly(config.GetDBPath())
+	if err != nil {
+		t.Fatalf("OpenReadOnly returned error: %v", err)
+	}
+	defer func() {
+		_ = CloseDatabase(ro)
+	}()
+
+	problems, err := ro.IntegrityCheck(t.Context())
+	if err != nil {
+		t.Fatalf("IntegrityCheck returned error: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Fatalf("expected a sound database, got %v", problems)
+	}
+	if _, err := ro.DB.Exec("DELETE FROM scopes"); err == nil {
+		t.Fatal("expected writes through a read-only connection to fail")
+	}
+}
+
 func TestClearDatabaseRemovesAllRows(t *testing.T) {
 	ctx := setupTestDB(t)
 
@@ -147,3 +229,48 @@ func assertCount(t *testing.T, db *sql.DB, table string, expected int) {
 		t.Fatalf("expected %s to have %d rows, got %d", table, expected, count)
 	}
 }
+
+func TestCreateDatabaseUsesRegisteredBackend(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("VAULT_DIR", tmp)
+	remote := filepath.Join(t.TempDir(), "remote.db")
+
+	configPath := filepath.Join(tmp, "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"databaseUrl": "testdb://index"}`), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	t.Setenv("VAULT_CONFIG", configPath)
+
+	if _, err := CreateDatabase(""); err == nil {
+		t.Fatal("expected an error for an unregistered backend")
+	}
+
+	var opened string
+	RegisterBackend("testdb", func(rawURL string) (*sql.DB, error) {
+		opened = rawURL
+		return sql.Open("sqlite", "file:"+filepath.ToSlash(remote))
+	})
+	t.Cleanup(func() {
+		backendsMu.Lock()
+		delete(backends, "testdb")
+		backendsMu.Unlock()
+	})
+
+	ctx, err := CreateDatabase("")
+	if err != nil {
+		t.Fatalf("CreateDatabase returned error: %v", err)
+	}
+	defer func() {
+		_ = CloseDatabase(ctx)
+	}()
+
+	if opened != "testdb://index" {
+		t.Fatalf("expected backend to receive the configured URL, got %q", opened)
+	}
+	if !tableExists(t, ctx.DB, "versions") {
+		t.Fatal("expected migrations to run against the remote database")
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "index.db")); !os.IsNotExist(err) {
+		t.Fatalf("expected no local index.db, got err=%v", err)
+	}
+}
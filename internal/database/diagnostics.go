@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// SchemaVersion returns the schema version recorded in the database and the
+// latest version among the embedded migrations.
+func (c *Context) SchemaVersion(ctx context.Context) (current, latest int, err error) {
+	latest, err = latestMigrationVersion()
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := c.DB.QueryRowContext(ctx, "PRAGMA user_version").Scan(&current); err != nil {
+		return 0, 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return current, latest, nil
+}
+
+// JournalMode returns SQLite's journal mode for the connection, e.g. "delete" or "wal".
+func (c *Context) JournalMode(ctx context.Context) (string, error) {
+	var mode string
+	if err := c.DB.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&mode); err != nil {
+		return "", fmt.Errorf("failed to read journal mode: %w", err)
+	}
+	return mode, nil
+}
+
+// IntegrityCheck runs SQLite's integrity check and returns the problems it
+// reports, or nil when the database file is sound.
+func (c *Context) IntegrityCheck(ctx context.Context) ([]string, error) {
+	rows, err := c.DB.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return nil, fmt.Errorf("failed to run integrity check: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	return problems, rows.Err()
+}
+
+// WriteLockPath returns the lockfile guarding writes in shared storage mode,
+// or an empty string when the mode is off.
+func (c *Context) WriteLockPath() string {
+	if c == nil || c.writeLock == nil {
+		return ""
+	}
+	return c.writeLock.path
+}
+
+// WriteLockStaleAfter is the age after which a shared storage lockfile is
+// considered abandoned and broken by the next writer.
+const WriteLockStaleAfter = writeLockStaleAfter
+
+// WriteLockFilePath returns where the shared storage lockfile for dbPath lives.
+func WriteLockFilePath(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), writeLockFileName)
+}
@@ -5,6 +5,18 @@ import (
 	"time"
 )
 
+// TimestampLayout matches the text SQLite stores for CURRENT_TIMESTAMP columns,
+// which lets bound timestamps compare lexicographically against them.
+const TimestampLayout = "2006-01-02 15:04:05"
+
+// NullTimestamp formats t in UTC using TimestampLayout. A zero time yields NULL.
+func NullTimestamp(t time.Time) sql.NullString {
+	if t.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.UTC().Format(TimestampLayout), Valid: true}
+}
+
 func nullString(value string) sql.NullString {
 	if value == "" {
 		return sql.NullString{}
@@ -39,3 +51,17 @@ func optionalTime(nt sql.NullTime) time.Time {
 	}
 	return nt.Time
 }
+
+// parseTimestamp parses timestamps returned as plain text, e.g. from aggregate
+// expressions where the driver cannot see the TIMESTAMP column type.
+func parseTimestamp(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	for _, layout := range []string{TimestampLayout, time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
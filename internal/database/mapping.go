@@ -2,7 +2,9 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	sqldb "github.com/choplin/vault.md/internal/database/sqlc"
 	"github.com/choplin/vault.md/internal/scope"
@@ -35,7 +37,41 @@ func ScopeRecordFromRow(row sqldb.Scope) ScopeRecord {
 		ScopePath: row.ScopePath,
 		CreatedAt: optionalTime(row.CreatedAt),
 		UpdatedAt: optionalTime(row.UpdatedAt),
+
+		Description: optionalString(row.Description),
+		Metadata:    scopeMetadataFromColumn(row.Metadata),
+	}
+}
+
+// scopeMetadataFromColumn decodes the JSON object stored in scopes.metadata.
+// Only vault writes the column, so a value that does not decode is treated
+// as no metadata rather than failing every scope lookup.
+func scopeMetadataFromColumn(ns sql.NullString) map[string]string {
+	if !ns.Valid || ns.String == "" {
+		return nil
+	}
+	var metadata map[string]string
+	if err := json.Unmarshal([]byte(ns.String), &metadata); err != nil {
+		return nil
 	}
+	return metadata
+}
+
+// ScopeDescriptionParams creates the parameters that set a scope's
+// description and metadata. Empty values are stored as NULL.
+func ScopeDescriptionParams(id int64, description string, metadata map[string]string) (sqldb.UpdateScopeDescriptionParams, error) {
+	params := sqldb.UpdateScopeDescriptionParams{
+		Description: nullString(description),
+		ID:          id,
+	}
+	if len(metadata) > 0 {
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			return params, err
+		}
+		params.Metadata = nullString(string(encoded))
+	}
+	return params, nil
 }
 
 // ScopeInsertParams creates insert parameters from a scope.
@@ -94,6 +130,16 @@ func ScopeCountsFromRows(rows []sqldb.ListScopesWithCountsRow) []ScopeCounts {
 	return result
 }
 
+// EntryVersionSummaryFromRow converts an aggregate version row to an EntryVersionSummary.
+func EntryVersionSummaryFromRow(row sqldb.GetVersionSummaryByEntryRow) EntryVersionSummary {
+	return EntryVersionSummary{
+		VersionCount:   row.VersionCount,
+		TotalSize:      row.TotalSize,
+		FirstWrittenAt: parseTimestamp(row.FirstWrittenAt),
+		LastWrittenAt:  parseTimestamp(row.LastWrittenAt),
+	}
+}
+
 // EntryRecordFromRow converts a database entry row to an EntryRecord.
 func EntryRecordFromRow(row sqldb.Entry) EntryRecord {
 	return EntryRecord{
@@ -130,11 +176,105 @@ func VersionRecordFromRow(row sqldb.Version) VersionRecord {
 		Hash:        row.Hash,
 		Description: description,
 		CreatedAt:   optionalTime(row.CreatedAt),
+		Size:        optionalInt64(row.Size),
+		Language:    optionalString(row.Language),
+	}
+}
+
+// VersionProvenanceFromRow converts a database provenance row to a VersionProvenance.
+func VersionProvenanceFromRow(row sqldb.VersionProvenance) VersionProvenance {
+	var dirty *bool
+	if row.GitDirty.Valid {
+		val := row.GitDirty.Int64 != 0
+		dirty = &val
+	}
+
+	return VersionProvenance{
+		Actor:     optionalString(row.Actor),
+		Tool:      optionalString(row.Tool),
+		Hostname:  optionalString(row.Hostname),
+		GitBranch: optionalString(row.GitBranch),
+		GitCommit: optionalString(row.GitCommit),
+		GitDirty:  dirty,
+		DeviceID:  optionalString(row.DeviceID),
+	}
+}
+
+// VersionProvenanceInsertParams converts a VersionProvenance into insert parameters.
+func VersionProvenanceInsertParams(versionID int64, p VersionProvenance) sqldb.InsertVersionProvenanceParams {
+	var dirty sql.NullInt64
+	if p.GitDirty != nil {
+		dirty.Valid = true
+		if *p.GitDirty {
+			dirty.Int64 = 1
+		}
+	}
+
+	return sqldb.InsertVersionProvenanceParams{
+		VersionID: versionID,
+		Tool:      nullString(p.Tool),
+		Hostname:  nullString(p.Hostname),
+		GitBranch: nullString(p.GitBranch),
+		GitCommit: nullString(p.GitCommit),
+		GitDirty:  dirty,
+		Actor:     nullString(p.Actor),
+		DeviceID:  nullString(p.DeviceID),
+	}
+}
+
+// DeviceRecordFromRow converts a device listing row to a DeviceRecord.
+func DeviceRecordFromRow(row sqldb.ListDevicesRow) DeviceRecord {
+	var revokedAt *time.Time
+	if row.RevokedAt.Valid {
+		val := row.RevokedAt.Time
+		revokedAt = &val
+	}
+
+	return DeviceRecord{
+		DeviceID:     row.DeviceID,
+		Hostname:     optionalString(row.Hostname),
+		FirstSeenAt:  optionalTime(row.CreatedAt),
+		RevokedAt:    revokedAt,
+		VersionCount: row.VersionCount,
+		LastWriteAt:  parseTimestamp(row.LastWriteAt),
+	}
+}
+
+// LockRecordFromRow converts a database entry lock row to a LockRecord.
+func LockRecordFromRow(row sqldb.EntryLock) LockRecord {
+	return LockRecord{
+		ScopeID:    row.ScopeID,
+		Key:        row.Key,
+		Owner:      row.Owner,
+		AcquiredAt: row.AcquiredAt,
+		ExpiresAt:  row.ExpiresAt,
+	}
+}
+
+// ChildRecordFromRow converts a database entry_children row to a ChildRecord.
+func ChildRecordFromRow(row sqldb.EntryChild) ChildRecord {
+	return ChildRecord{
+		ScopeID:   row.ScopeID,
+		ChildKey:  row.ChildKey,
+		ParentKey: row.ParentKey,
+		Position:  row.Position,
+	}
+}
+
+// ScopeSnapshotRecordFromRow converts a database scope snapshot row to a
+// ScopeSnapshotRecord.
+func ScopeSnapshotRecordFromRow(row sqldb.GetScopeSnapshotRow) ScopeSnapshotRecord {
+	return ScopeSnapshotRecord{
+		ID:        row.ID,
+		ScopeID:   row.ScopeID,
+		Name:      row.Name,
+		CreatedAt: row.CreatedAt,
+		KeyCount:  row.KeyCount,
 	}
 }
 
 // ScopedEntryRecordFromRow creates a ScopedEntryRecord from individual fields.
-func ScopedEntryRecordFromRow(entryID, scopeID int64, key string, entryCreatedAt sql.NullTime, isArchived sql.NullInt64, version int64, filePath, hash string, description sql.NullString) ScopedEntryRecord {
+func ScopedEntryRecordFromRow(entryID, scopeID int64, key string, entryCreatedAt sql.NullTime, isArchived sql.NullInt64, version int64, filePath, hash string, description sql.NullString, versionCreatedAt sql.NullTime, size sql.NullInt64, language, summary sql.NullString, lastReadAt sql.NullTime, readCount int64) ScopedEntryRecord {
 	var descPtr *string
 	if description.Valid {
 		val := description.String
@@ -150,6 +290,12 @@ func ScopedEntryRecordFromRow(entryID, scopeID int64, key string, entryCreatedAt
 		Hash:        hash,
 		Description: descPtr,
 		CreatedAt:   optionalTime(entryCreatedAt),
+		UpdatedAt:   optionalTime(versionCreatedAt),
+		Size:        optionalInt64(size),
 		IsArchived:  optionalBool(isArchived),
+		Language:    optionalString(language),
+		Summary:     optionalString(summary),
+		LastReadAt:  optionalTime(lastReadAt),
+		ReadCount:   readCount,
 	}
 }
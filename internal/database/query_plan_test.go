@@ -0,0 +1,58 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	sqldb "github.com/choplin/vault.md/internal/database/sqlc"
+)
+
+// TestLatestVersionQueriesUseIndexes guards the lookup paths that run on
+// every get/set/list against regressing into full table scans as the schema
+// evolves. The versions(entry_id, version DESC) and entries(scope_id, key)
+// indexes from the initial migration must keep serving these queries.
+func TestLatestVersionQueriesUseIndexes(t *testing.T) {
+	ctx := setupTestDB(t)
+
+	queries := map[string]string{
+		"GetScopedEntryLatest":         sqldb.GetScopedEntryLatest,
+		"GetScopedEntryByVersion":      sqldb.GetScopedEntryByVersion,
+		"ListScopedEntriesLatest":      sqldb.ListScopedEntriesLatest,
+		"ListScopedEntriesAllVersions": sqldb.ListScopedEntriesAllVersions,
+		"FindEntryByScopeAndKey":       sqldb.FindEntryByScopeAndKey,
+		"MaxVersionForEntry":           sqldb.MaxVersionForEntry,
+	}
+
+	for name, query := range queries {
+		t.Run(name, func(t *testing.T) {
+			args := make([]interface{}, strings.Count(query, "?"))
+			for i := range args {
+				args[i] = 1
+			}
+
+			rows, err := ctx.DB.Query("EXPLAIN QUERY PLAN "+query, args...)
+			if err != nil {
+				t.Fatalf("EXPLAIN QUERY PLAN failed: %v", err)
+			}
+			defer func() {
+				_ = rows.Close()
+			}()
+
+			for rows.Next() {
+				var (
+					id, parent, notused int
+					detail              string
+				)
+				if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+					t.Fatalf("failed to scan plan row: %v", err)
+				}
+				if strings.HasPrefix(detail, "SCAN") {
+					t.Fatalf("expected index lookups only, got %q", detail)
+				}
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatalf("plan rows error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Shared storage mode is meant for vault directories on NFS/SMB mounts, where
+// SQLite's own POSIX locks are unreliable. Writers additionally serialise on
+// an O_EXCL lockfile next to the database, which network filesystems do
+// implement atomically.
+const (
+	writeLockFileName   = "write.lock"
+	writeLockStaleAfter = 2 * time.Minute
+	writeLockTimeout    = 30 * time.Second
+	writeLockPoll       = 50 * time.Millisecond
+)
+
+// busyTimeoutMillis is how long SQLite itself waits on a locked database
+// before returning SQLITE_BUSY.
+const busyTimeoutMillis = 5000
+
+// Retry policy for transactions that fail with SQLITE_BUSY/SQLITE_LOCKED
+// after the driver's busy_timeout has already expired.
+const (
+	busyRetryAttempts = 5
+	busyRetryBackoff  = 100 * time.Millisecond
+)
+
+// writeLock is a cooperative cross-host lock backed by an exclusive file.
+// Each acquisition writes a token naming the holder (pid, host, and a random
+// nonce) into the file, so a holder can tell its own lockfile from one that
+// replaced it after the lock was broken.
+type writeLock struct {
+	path       string
+	staleAfter time.Duration
+	timeout    time.Duration
+}
+
+func newWriteLock(path string) *writeLock {
+	return &writeLock{
+		path:       path,
+		staleAfter: writeLockStaleAfter,
+		timeout:    writeLockTimeout,
+	}
+}
+
+// acquire creates the lockfile, waiting for other holders to release it.
+// Locks not refreshed for staleAfter are assumed abandoned by a crashed
+// writer and are broken. While the lock is held its mtime is refreshed, so
+// a long write is never mistaken for a crashed one.
+func (l *writeLock) acquire(ctx context.Context) (func(), error) {
+	deadline := time.Now().Add(l.timeout)
+	for {
+		token, err := l.create()
+		if err == nil {
+			stop := l.refresh(token)
+			return func() {
+				stop()
+				l.removeIf(func(data []byte, _ fs.FileInfo) bool { return string(data) == token })
+			}, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+
+		if l.breakStale() {
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for write lock %s", l.path)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(writeLockPoll):
+		}
+	}
+}
+
+// create makes the lockfile with O_EXCL and writes a fresh token into it.
+// It fails with an error wrapping fs.ErrExist while another holder has it.
+func (l *writeLock) create() (string, error) {
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) //nolint:gosec // G304: path is derived from the vault directory
+	if errors.Is(err, fs.ErrExist) {
+		return "", err
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create lockfile %s: %w", l.path, err)
+	}
+	host, _ := os.Hostname()
+	token := fmt.Sprintf("pid=%d host=%s nonce=%s acquired=%s\n", os.Getpid(), host, rand.Text(), time.Now().UTC().Format(time.RFC3339))
+	_, writeErr := file.WriteString(token)
+	closeErr := file.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		_ = os.Remove(l.path)
+		return "", fmt.Errorf("failed to write lockfile %s: %w", l.path, err)
+	}
+	return token, nil
+}
+
+// breakStale removes the lockfile if it has not been refreshed for
+// staleAfter, and reports whether it did. The holder is identified by its
+// token, so a lock that was released and taken again in the meantime is
+// left alone even if it happens to look old.
+func (l *writeLock) breakStale() bool {
+	info, err := os.Stat(l.path)
+	if err != nil || time.Since(info.ModTime()) <= l.staleAfter {
+		return false
+	}
+	token, err := os.ReadFile(l.path)
+	if err != nil {
+		return false
+	}
+	return l.removeIf(func(data []byte, info fs.FileInfo) bool {
+		return string(data) == string(token) && time.Since(info.ModTime()) > l.staleAfter
+	})
+}
+
+// removeIf removes the lockfile if owned accepts its content and file info.
+// The file is first renamed to a name no other process uses, so the check
+// and the removal cannot race with a writer breaking the lock and taking
+// it: a renamed file that fails the check is put back.
+func (l *writeLock) removeIf(owned func(data []byte, info fs.FileInfo) bool) bool {
+	aside := fmt.Sprintf("%s.%s", l.path, rand.Text())
+	if err := os.Rename(l.path, aside); err != nil {
+		return false
+	}
+	data, err := os.ReadFile(aside) //nolint:gosec // G304: path is derived from the vault directory
+	if err == nil {
+		if info, statErr := os.Stat(aside); statErr == nil && owned(data, info) {
+			_ = os.Remove(aside)
+			return true
+		}
+	}
+	// Link refuses to replace a lockfile a third writer created meanwhile;
+	// that writer then holds the lock.
+	if err := os.Link(aside, l.path); err != nil && !errors.Is(err, fs.ErrExist) {
+		_ = os.Rename(aside, l.path)
+		return false
+	}
+	_ = os.Remove(aside)
+	return false
+}
+
+// refresh touches the lockfile every quarter of staleAfter whenever it holds
+// token, and returns a function that stops it.
+func (l *writeLock) refresh(token string) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(l.staleAfter / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				// The file may be set aside for a moment by a writer
+				// checking whether it is stale; try again next tick.
+				if data, err := os.ReadFile(l.path); err == nil && string(data) == token {
+					now := time.Now()
+					_ = os.Chtimes(l.path, now, now)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// RunWrite executes fn, which must run a complete write transaction. In shared
+// storage mode fn runs while holding the vault's lockfile. Whenever SQLite
+// reports the database busy or locked, fn is retried from scratch with
+// backoff.
+func (c *Context) RunWrite(ctx context.Context, fn func() error) error {
+	if c.InTx() {
+		// The enclosing RunInTx holds the lock and retries as a whole.
+		return fn()
+	}
+
+	if c != nil && c.writeLock != nil {
+		release, err := c.writeLock.acquire(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	backoff := busyRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !IsBusy(err) || attempt == busyRetryAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// IsBusy reports whether err is SQLite signalling lock contention.
+func IsBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	// Extended result codes keep the primary code in the low byte.
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsUniqueViolation reports whether err is SQLite rejecting a row that
+// duplicates a UNIQUE or PRIMARY KEY constraint.
+func IsUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return true
+	default:
+		return false
+	}
+}
+
+// SharedStorage reports whether the database was opened in shared storage mode.
+func (c *Context) SharedStorage() bool {
+	return c != nil && c.writeLock != nil
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteLockSerialisesHolders(t *testing.T) {
+	lock := newWriteLock(filepath.Join(t.TempDir(), writeLockFileName))
+	lock.timeout = 200 * time.Millisecond
+
+	release, err := lock.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire error: %v", err)
+	}
+
+	if _, err := lock.acquire(context.Background()); err == nil {
+		t.Fatalf("expected second acquire to time out while the lock is held")
+	}
+
+	release()
+	release, err = lock.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire after release error: %v", err)
+	}
+	release()
+
+	if _, err := os.Stat(lock.path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected lockfile to be removed on release, stat err: %v", err)
+	}
+}
+
+func TestWriteLockBreaksStaleLock(t *testing.T) {
+	lock := newWriteLock(filepath.Join(t.TempDir(), writeLockFileName))
+	lock.timeout = 200 * time.Millisecond
+
+	if err := os.WriteFile(lock.path, []byte("pid=1\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	old := time.Now().Add(-2 * lock.staleAfter)
+	if err := os.Chtimes(lock.path, old, old); err != nil {
+		t.Fatalf("Chtimes error: %v", err)
+	}
+
+	release, err := lock.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("expected stale lock to be broken, got %v", err)
+	}
+	release()
+}
+
+func TestWriteLockRefreshesWhileHeld(t *testing.T) {
+	path := filepath.Join(t.TempDir(), writeLockFileName)
+	holder := newWriteLock(path)
+	holder.staleAfter = 80 * time.Millisecond
+
+	release, err := holder.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire error: %v", err)
+	}
+	time.Sleep(3 * holder.staleAfter)
+
+	other := newWriteLock(path)
+	other.staleAfter = holder.staleAfter
+	other.timeout = holder.staleAfter
+	if _, err := other.acquire(context.Background()); err == nil {
+		t.Fatal("a lock held past staleAfter was broken although its holder refreshed it")
+	}
+	release()
+}
+
+func TestWriteLockReleaseKeepsReplacement(t *testing.T) {
+	dir := t.TempDir()
+	lock := newWriteLock(filepath.Join(dir, writeLockFileName))
+
+	release, err := lock.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire error: %v", err)
+	}
+	// Another writer broke the lock and took it.
+	if err := os.WriteFile(lock.path, []byte("pid=2 host=other\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	release()
+
+	data, err := os.ReadFile(lock.path)
+	if err != nil || string(data) != "pid=2 host=other\n" {
+		t.Fatalf("release removed another holder's lock: %q, %v", data, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected only the lockfile to remain, got %v, %v", entries, err)
+	}
+}
+
+func TestMigrationsTakeWriteLock(t *testing.T) {
+	dir := t.TempDir()
+	lock := newWriteLock(filepath.Join(dir, writeLockFileName))
+	lock.timeout = 100 * time.Millisecond
+
+	db, err := sql.Open("sqlite", "file:"+filepath.ToSlash(filepath.Join(dir, "vault.db")))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	release, err := lock.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire error: %v", err)
+	}
+	if err := migrateLocked(db, lock); err == nil {
+		t.Fatal("expected migrations to wait for the write lock")
+	}
+	release()
+
+	if err := migrateLocked(db, lock); err != nil {
+		t.Fatalf("migrateLocked error: %v", err)
+	}
+	if !tableExists(t, db, "versions") {
+		t.Fatal("expected migrations to run once the lock was free")
+	}
+}
+
+func TestRunWriteRetriesBusy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "busy.db")
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(0)", filepath.ToSlash(path))
+
+	holder, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("open holder: %v", err)
+	}
+	defer func() { _ = holder.Close() }()
+	if _, err := holder.Exec("CREATE TABLE t (v INTEGER)"); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	writer, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("open writer: %v", err)
+	}
+	defer func() { _ = writer.Close() }()
+
+	ctx := context.Background()
+	conn, err := holder.Conn(ctx)
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err := conn.ExecContext(ctx, "BEGIN EXCLUSIVE"); err != nil {
+		t.Fatalf("begin exclusive: %v", err)
+	}
+
+	_, err = writer.Exec("INSERT INTO t (v) VALUES (1)")
+	if !IsBusy(err) {
+		t.Fatalf("expected busy error while exclusive lock is held, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		_, _ = conn.ExecContext(ctx, "COMMIT")
+	}()
+
+	attempts := 0
+	err = (&Context{}).RunWrite(ctx, func() error {
+		attempts++
+		_, err := writer.Exec("INSERT INTO t (v) VALUES (1)")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("RunWrite error: %v", err)
+	}
+	if attempts < 2 {
+		t.Fatalf("expected RunWrite to retry, ran %d time(s)", attempts)
+	}
+}
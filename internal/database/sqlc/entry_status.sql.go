@@ -24,7 +24,7 @@ func (q *Queries) DeleteEntryStatus(ctx context.Context, entryID int64) (int64,
 }
 
 const FindEntryStatusByEntryID = `-- name: FindEntryStatusByEntryID :one
-SELECT entry_id, is_archived, current_version, updated_at
+SELECT entry_id, is_archived, current_version, updated_at, last_read_at, read_count
 FROM entry_status
 WHERE entry_id = ?
 LIMIT 1
@@ -38,6 +38,8 @@ func (q *Queries) FindEntryStatusByEntryID(ctx context.Context, entryID int64) (
 		&i.IsArchived,
 		&i.CurrentVersion,
 		&i.UpdatedAt,
+		&i.LastReadAt,
+		&i.ReadCount,
 	)
 	return i, err
 }
@@ -58,6 +60,21 @@ func (q *Queries) InsertEntryStatus(ctx context.Context, arg InsertEntryStatusPa
 	return err
 }
 
+const RecordEntryRead = `-- name: RecordEntryRead :execrows
+UPDATE entry_status
+SET last_read_at = CURRENT_TIMESTAMP,
+    read_count = read_count + 1
+WHERE entry_id = ?
+`
+
+func (q *Queries) RecordEntryRead(ctx context.Context, entryID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, RecordEntryRead, entryID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const UpdateEntryStatusArchived = `-- name: UpdateEntryStatusArchived :execrows
 UPDATE entry_status
 SET is_archived = ?,
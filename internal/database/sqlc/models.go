@@ -6,8 +6,16 @@ package sqldb
 
 import (
 	"database/sql"
+	"time"
 )
 
+type Device struct {
+	DeviceID  string         `json:"device_id"`
+	Hostname  sql.NullString `json:"hostname"`
+	CreatedAt sql.NullTime   `json:"created_at"`
+	RevokedAt sql.NullTime   `json:"revoked_at"`
+}
+
 type Entry struct {
 	ID        int64        `json:"id"`
 	ScopeID   int64        `json:"scope_id"`
@@ -15,11 +23,42 @@ type Entry struct {
 	CreatedAt sql.NullTime `json:"created_at"`
 }
 
+type EntryChild struct {
+	ScopeID   int64  `json:"scope_id"`
+	ChildKey  string `json:"child_key"`
+	ParentKey string `json:"parent_key"`
+	Position  int64  `json:"position"`
+}
+
+type EntryLock struct {
+	ScopeID    int64     `json:"scope_id"`
+	Key        string    `json:"key"`
+	Owner      string    `json:"owner"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
 type EntryStatus struct {
 	EntryID        int64         `json:"entry_id"`
 	IsArchived     sql.NullInt64 `json:"is_archived"`
 	CurrentVersion sql.NullInt64 `json:"current_version"`
 	UpdatedAt      sql.NullTime  `json:"updated_at"`
+	LastReadAt     sql.NullTime  `json:"last_read_at"`
+	ReadCount      int64         `json:"read_count"`
+}
+
+type IdempotencyKey struct {
+	Token     string       `json:"token"`
+	VersionID int64        `json:"version_id"`
+	CreatedAt sql.NullTime `json:"created_at"`
+}
+
+type PendingDeletion struct {
+	ID        int64          `json:"id"`
+	FilePath  string         `json:"file_path"`
+	QueuedAt  time.Time      `json:"queued_at"`
+	Attempts  int64          `json:"attempts"`
+	LastError sql.NullString `json:"last_error"`
 }
 
 type Scope struct {
@@ -32,14 +71,57 @@ type Scope struct {
 	ScopePath    string         `json:"scope_path"`
 	CreatedAt    sql.NullTime   `json:"created_at"`
 	UpdatedAt    sql.NullTime   `json:"updated_at"`
+	Description  sql.NullString `json:"description"`
+	Metadata     sql.NullString `json:"metadata"`
+}
+
+type ScopeSnapshot struct {
+	ID        int64     `json:"id"`
+	ScopeID   int64     `json:"scope_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ScopeSnapshotVersion struct {
+	SnapshotID int64  `json:"snapshot_id"`
+	Key        string `json:"key"`
+	Version    int64  `json:"version"`
 }
 
 type Version struct {
-	ID          int64          `json:"id"`
-	EntryID     int64          `json:"entry_id"`
-	Version     int64          `json:"version"`
-	FilePath    string         `json:"file_path"`
-	Hash        string         `json:"hash"`
-	Description sql.NullString `json:"description"`
-	CreatedAt   sql.NullTime   `json:"created_at"`
+	ID            int64          `json:"id"`
+	EntryID       int64          `json:"entry_id"`
+	Version       int64          `json:"version"`
+	FilePath      string         `json:"file_path"`
+	Hash          string         `json:"hash"`
+	Description   sql.NullString `json:"description"`
+	CreatedAt     sql.NullTime   `json:"created_at"`
+	Size          sql.NullInt64  `json:"size"`
+	VerifiedMtime sql.NullInt64  `json:"verified_mtime"`
+	VerifiedSize  sql.NullInt64  `json:"verified_size"`
+	Language      sql.NullString `json:"language"`
+}
+
+type VersionApproval struct {
+	VersionID  int64          `json:"version_id"`
+	ApprovedBy sql.NullString `json:"approved_by"`
+	ApprovedAt sql.NullTime   `json:"approved_at"`
+}
+
+type VersionProvenance struct {
+	VersionID int64          `json:"version_id"`
+	Tool      sql.NullString `json:"tool"`
+	Hostname  sql.NullString `json:"hostname"`
+	GitBranch sql.NullString `json:"git_branch"`
+	GitCommit sql.NullString `json:"git_commit"`
+	GitDirty  sql.NullInt64  `json:"git_dirty"`
+	Actor     sql.NullString `json:"actor"`
+	DeviceID  sql.NullString `json:"device_id"`
+}
+
+type VersionSummary struct {
+	VersionID  int64          `json:"version_id"`
+	Summary    string         `json:"summary"`
+	Summarizer sql.NullString `json:"summarizer"`
+	CreatedAt  sql.NullTime   `json:"created_at"`
 }
@@ -58,7 +58,7 @@ func (q *Queries) DeleteScopesByPrimaryPath(ctx context.Context, primaryPath sql
 }
 
 const FindScopeByID = `-- name: FindScopeByID :one
-SELECT id, type, primary_path, worktree_id, worktree_path, branch_name, scope_path, created_at, updated_at
+SELECT id, type, primary_path, worktree_id, worktree_path, branch_name, scope_path, created_at, updated_at, description, metadata
 FROM scopes
 WHERE id = ?
 LIMIT 1
@@ -77,12 +77,14 @@ func (q *Queries) FindScopeByID(ctx context.Context, id int64) (Scope, error) {
 		&i.ScopePath,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Description,
+		&i.Metadata,
 	)
 	return i, err
 }
 
 const FindScopeByPath = `-- name: FindScopeByPath :one
-SELECT id, type, primary_path, worktree_id, worktree_path, branch_name, scope_path, created_at, updated_at
+SELECT id, type, primary_path, worktree_id, worktree_path, branch_name, scope_path, created_at, updated_at, description, metadata
 FROM scopes
 WHERE scope_path = ?
 LIMIT 1
@@ -101,6 +103,8 @@ func (q *Queries) FindScopeByPath(ctx context.Context, scopePath string) (Scope,
 		&i.ScopePath,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Description,
+		&i.Metadata,
 	)
 	return i, err
 }
@@ -131,7 +135,7 @@ func (q *Queries) InsertScope(ctx context.Context, arg InsertScopeParams) (sql.R
 }
 
 const ListScopes = `-- name: ListScopes :many
-SELECT id, type, primary_path, worktree_id, worktree_path, branch_name, scope_path, created_at, updated_at
+SELECT id, type, primary_path, worktree_id, worktree_path, branch_name, scope_path, created_at, updated_at, description, metadata
 FROM scopes
 ORDER BY type, primary_path, branch_name
 `
@@ -155,6 +159,8 @@ func (q *Queries) ListScopes(ctx context.Context) ([]Scope, error) {
 			&i.ScopePath,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Description,
+			&i.Metadata,
 		); err != nil {
 			return nil, err
 		}
@@ -203,3 +209,22 @@ func (q *Queries) UpdateScope(ctx context.Context, arg UpdateScopeParams) error
 	)
 	return err
 }
+
+const UpdateScopeDescription = `-- name: UpdateScopeDescription :exec
+UPDATE scopes
+SET description = ?,
+    metadata = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`
+
+type UpdateScopeDescriptionParams struct {
+	Description sql.NullString `json:"description"`
+	Metadata    sql.NullString `json:"metadata"`
+	ID          int64          `json:"id"`
+}
+
+func (q *Queries) UpdateScopeDescription(ctx context.Context, arg UpdateScopeDescriptionParams) error {
+	_, err := q.db.ExecContext(ctx, UpdateScopeDescription, arg.Description, arg.Metadata, arg.ID)
+	return err
+}
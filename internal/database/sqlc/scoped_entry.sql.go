@@ -35,10 +35,16 @@ SELECT
     v.file_path,
     v.hash,
     v.description,
-    v.created_at AS version_created_at
+    v.created_at AS version_created_at,
+    v.size,
+    v.language,
+    vs.summary,
+    es.last_read_at,
+    es.read_count
 FROM entries e
 JOIN entry_status es ON e.id = es.entry_id
 JOIN versions v ON e.id = v.entry_id
+LEFT JOIN version_summaries vs ON vs.version_id = v.id
 WHERE e.scope_id = ? AND e.key = ? AND v.version = ?
 LIMIT 1
 `
@@ -60,6 +66,11 @@ type GetScopedEntryByVersionRow struct {
 	Hash             string         `json:"hash"`
 	Description      sql.NullString `json:"description"`
 	VersionCreatedAt sql.NullTime   `json:"version_created_at"`
+	Size             sql.NullInt64  `json:"size"`
+	Language         sql.NullString `json:"language"`
+	Summary          sql.NullString `json:"summary"`
+	LastReadAt       sql.NullTime   `json:"last_read_at"`
+	ReadCount        int64          `json:"read_count"`
 }
 
 func (q *Queries) GetScopedEntryByVersion(ctx context.Context, arg GetScopedEntryByVersionParams) (GetScopedEntryByVersionRow, error) {
@@ -76,6 +87,11 @@ func (q *Queries) GetScopedEntryByVersion(ctx context.Context, arg GetScopedEntr
 		&i.Hash,
 		&i.Description,
 		&i.VersionCreatedAt,
+		&i.Size,
+		&i.Language,
+		&i.Summary,
+		&i.LastReadAt,
+		&i.ReadCount,
 	)
 	return i, err
 }
@@ -92,10 +108,16 @@ SELECT
     v.file_path,
     v.hash,
     v.description,
-    v.created_at AS version_created_at
+    v.created_at AS version_created_at,
+    v.size,
+    v.language,
+    vs.summary,
+    es.last_read_at,
+    es.read_count
 FROM entries e
 JOIN entry_status es ON e.id = es.entry_id
 JOIN versions v ON e.id = v.entry_id AND v.version = es.current_version
+LEFT JOIN version_summaries vs ON vs.version_id = v.id
 WHERE e.scope_id = ? AND e.key = ?
 LIMIT 1
 `
@@ -117,6 +139,11 @@ type GetScopedEntryLatestRow struct {
 	Hash             string         `json:"hash"`
 	Description      sql.NullString `json:"description"`
 	VersionCreatedAt sql.NullTime   `json:"version_created_at"`
+	Size             sql.NullInt64  `json:"size"`
+	Language         sql.NullString `json:"language"`
+	Summary          sql.NullString `json:"summary"`
+	LastReadAt       sql.NullTime   `json:"last_read_at"`
+	ReadCount        int64          `json:"read_count"`
 }
 
 func (q *Queries) GetScopedEntryLatest(ctx context.Context, arg GetScopedEntryLatestParams) (GetScopedEntryLatestRow, error) {
@@ -134,6 +161,11 @@ func (q *Queries) GetScopedEntryLatest(ctx context.Context, arg GetScopedEntryLa
 		&i.Hash,
 		&i.Description,
 		&i.VersionCreatedAt,
+		&i.Size,
+		&i.Language,
+		&i.Summary,
+		&i.LastReadAt,
+		&i.ReadCount,
 	)
 	return i, err
 }
@@ -187,18 +219,46 @@ SELECT
     v.file_path,
     v.hash,
     v.description,
-    v.created_at AS version_created_at
+    v.created_at AS version_created_at,
+    v.size,
+    v.language,
+    vs.summary,
+    es.last_read_at,
+    es.read_count
 FROM entries e
 JOIN entry_status es ON e.id = es.entry_id
 JOIN versions v ON e.id = v.entry_id
+LEFT JOIN version_summaries vs ON vs.version_id = v.id
 WHERE e.scope_id = ?
   AND (?2 OR es.is_archived = 0)
-ORDER BY e.key, v.version DESC
+  AND (CAST(?3 AS TEXT) IS NULL OR v.created_at >= CAST(?3 AS TEXT))
+  AND (CAST(?4 AS TEXT) IS NULL OR v.created_at <= CAST(?4 AS TEXT))
+  AND (CAST(?5 AS TEXT) IS NULL OR instr(lower(v.description), lower(CAST(?5 AS TEXT))) > 0)
+  AND (CAST(?6 AS TEXT) IS NULL OR v.language = CAST(?6 AS TEXT))
+ORDER BY
+    CASE WHEN CAST(?7 AS TEXT) = 'key' AND NOT ?8 THEN e.key END ASC,
+    CASE WHEN CAST(?7 AS TEXT) = 'key' AND ?8 THEN e.key END DESC,
+    CASE WHEN CAST(?7 AS TEXT) = 'created' AND NOT ?8 THEN e.created_at END ASC,
+    CASE WHEN CAST(?7 AS TEXT) = 'created' AND ?8 THEN e.created_at END DESC,
+    CASE WHEN CAST(?7 AS TEXT) = 'updated' AND NOT ?8 THEN v.created_at END ASC,
+    CASE WHEN CAST(?7 AS TEXT) = 'updated' AND ?8 THEN v.created_at END DESC,
+    CASE WHEN CAST(?7 AS TEXT) = 'version' AND NOT ?8 THEN v.version END ASC,
+    CASE WHEN CAST(?7 AS TEXT) = 'version' AND ?8 THEN v.version END DESC,
+    CASE WHEN CAST(?7 AS TEXT) = 'size' AND NOT ?8 THEN v.size END ASC,
+    CASE WHEN CAST(?7 AS TEXT) = 'size' AND ?8 THEN v.size END DESC,
+    e.key,
+    v.version DESC
 `
 
 type ListScopedEntriesAllVersionsParams struct {
-	ScopeID         int64       `json:"scope_id"`
-	IncludeArchived interface{} `json:"include_archived"`
+	ScopeID             int64          `json:"scope_id"`
+	IncludeArchived     interface{}    `json:"include_archived"`
+	Since               sql.NullString `json:"since"`
+	Until               sql.NullString `json:"until"`
+	DescriptionContains sql.NullString `json:"description_contains"`
+	Language            sql.NullString `json:"language"`
+	SortBy              string         `json:"sort_by"`
+	SortDesc            interface{}    `json:"sort_desc"`
 }
 
 type ListScopedEntriesAllVersionsRow struct {
@@ -212,10 +272,24 @@ type ListScopedEntriesAllVersionsRow struct {
 	Hash             string         `json:"hash"`
 	Description      sql.NullString `json:"description"`
 	VersionCreatedAt sql.NullTime   `json:"version_created_at"`
+	Size             sql.NullInt64  `json:"size"`
+	Language         sql.NullString `json:"language"`
+	Summary          sql.NullString `json:"summary"`
+	LastReadAt       sql.NullTime   `json:"last_read_at"`
+	ReadCount        int64          `json:"read_count"`
 }
 
 func (q *Queries) ListScopedEntriesAllVersions(ctx context.Context, arg ListScopedEntriesAllVersionsParams) ([]ListScopedEntriesAllVersionsRow, error) {
-	rows, err := q.db.QueryContext(ctx, ListScopedEntriesAllVersions, arg.ScopeID, arg.IncludeArchived)
+	rows, err := q.db.QueryContext(ctx, ListScopedEntriesAllVersions,
+		arg.ScopeID,
+		arg.IncludeArchived,
+		arg.Since,
+		arg.Until,
+		arg.DescriptionContains,
+		arg.Language,
+		arg.SortBy,
+		arg.SortDesc,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -234,6 +308,11 @@ func (q *Queries) ListScopedEntriesAllVersions(ctx context.Context, arg ListScop
 			&i.Hash,
 			&i.Description,
 			&i.VersionCreatedAt,
+			&i.Size,
+			&i.Language,
+			&i.Summary,
+			&i.LastReadAt,
+			&i.ReadCount,
 		); err != nil {
 			return nil, err
 		}
@@ -260,18 +339,46 @@ SELECT
     v.file_path,
     v.hash,
     v.description,
-    v.created_at AS version_created_at
+    v.created_at AS version_created_at,
+    v.size,
+    v.language,
+    vs.summary,
+    es.last_read_at,
+    es.read_count
 FROM entries e
 JOIN entry_status es ON e.id = es.entry_id
 JOIN versions v ON e.id = v.entry_id AND v.version = es.current_version
+LEFT JOIN version_summaries vs ON vs.version_id = v.id
 WHERE e.scope_id = ?
   AND (?2 OR es.is_archived = 0)
-ORDER BY e.key
+  AND (CAST(?3 AS TEXT) IS NULL OR v.created_at >= CAST(?3 AS TEXT))
+  AND (CAST(?4 AS TEXT) IS NULL OR v.created_at <= CAST(?4 AS TEXT))
+  AND (CAST(?5 AS TEXT) IS NULL OR instr(lower(v.description), lower(CAST(?5 AS TEXT))) > 0)
+  AND (CAST(?6 AS TEXT) IS NULL OR v.language = CAST(?6 AS TEXT))
+ORDER BY
+    CASE WHEN CAST(?7 AS TEXT) = 'key' AND NOT ?8 THEN e.key END ASC,
+    CASE WHEN CAST(?7 AS TEXT) = 'key' AND ?8 THEN e.key END DESC,
+    CASE WHEN CAST(?7 AS TEXT) = 'created' AND NOT ?8 THEN e.created_at END ASC,
+    CASE WHEN CAST(?7 AS TEXT) = 'created' AND ?8 THEN e.created_at END DESC,
+    CASE WHEN CAST(?7 AS TEXT) = 'updated' AND NOT ?8 THEN v.created_at END ASC,
+    CASE WHEN CAST(?7 AS TEXT) = 'updated' AND ?8 THEN v.created_at END DESC,
+    CASE WHEN CAST(?7 AS TEXT) = 'version' AND NOT ?8 THEN v.version END ASC,
+    CASE WHEN CAST(?7 AS TEXT) = 'version' AND ?8 THEN v.version END DESC,
+    CASE WHEN CAST(?7 AS TEXT) = 'size' AND NOT ?8 THEN v.size END ASC,
+    CASE WHEN CAST(?7 AS TEXT) = 'size' AND ?8 THEN v.size END DESC,
+    e.key,
+    v.version DESC
 `
 
 type ListScopedEntriesLatestParams struct {
-	ScopeID         int64       `json:"scope_id"`
-	IncludeArchived interface{} `json:"include_archived"`
+	ScopeID             int64          `json:"scope_id"`
+	IncludeArchived     interface{}    `json:"include_archived"`
+	Since               sql.NullString `json:"since"`
+	Until               sql.NullString `json:"until"`
+	DescriptionContains sql.NullString `json:"description_contains"`
+	Language            sql.NullString `json:"language"`
+	SortBy              string         `json:"sort_by"`
+	SortDesc            interface{}    `json:"sort_desc"`
 }
 
 type ListScopedEntriesLatestRow struct {
@@ -286,10 +393,24 @@ type ListScopedEntriesLatestRow struct {
 	Hash             string         `json:"hash"`
 	Description      sql.NullString `json:"description"`
 	VersionCreatedAt sql.NullTime   `json:"version_created_at"`
+	Size             sql.NullInt64  `json:"size"`
+	Language         sql.NullString `json:"language"`
+	Summary          sql.NullString `json:"summary"`
+	LastReadAt       sql.NullTime   `json:"last_read_at"`
+	ReadCount        int64          `json:"read_count"`
 }
 
 func (q *Queries) ListScopedEntriesLatest(ctx context.Context, arg ListScopedEntriesLatestParams) ([]ListScopedEntriesLatestRow, error) {
-	rows, err := q.db.QueryContext(ctx, ListScopedEntriesLatest, arg.ScopeID, arg.IncludeArchived)
+	rows, err := q.db.QueryContext(ctx, ListScopedEntriesLatest,
+		arg.ScopeID,
+		arg.IncludeArchived,
+		arg.Since,
+		arg.Until,
+		arg.DescriptionContains,
+		arg.Language,
+		arg.SortBy,
+		arg.SortDesc,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -309,6 +430,11 @@ func (q *Queries) ListScopedEntriesLatest(ctx context.Context, arg ListScopedEnt
 			&i.Hash,
 			&i.Description,
 			&i.VersionCreatedAt,
+			&i.Size,
+			&i.Language,
+			&i.Summary,
+			&i.LastReadAt,
+			&i.ReadCount,
 		); err != nil {
 			return nil, err
 		}
@@ -363,3 +489,51 @@ func (q *Queries) ListScopesWithCounts(ctx context.Context, primaryPath sql.Null
 	}
 	return items, nil
 }
+
+const ListSupersededVersions = `-- name: ListSupersededVersions :many
+SELECT
+    e.key,
+    v.version,
+    v.file_path,
+    v.size
+FROM entries e
+JOIN entry_status es ON e.id = es.entry_id
+JOIN versions v ON e.id = v.entry_id
+WHERE e.scope_id = ? AND v.version <> es.current_version
+ORDER BY v.created_at, v.id
+`
+
+type ListSupersededVersionsRow struct {
+	Key      string        `json:"key"`
+	Version  int64         `json:"version"`
+	FilePath string        `json:"file_path"`
+	Size     sql.NullInt64 `json:"size"`
+}
+
+func (q *Queries) ListSupersededVersions(ctx context.Context, scopeID int64) ([]ListSupersededVersionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, ListSupersededVersions, scopeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSupersededVersionsRow
+	for rows.Next() {
+		var i ListSupersededVersionsRow
+		if err := rows.Scan(
+			&i.Key,
+			&i.Version,
+			&i.FilePath,
+			&i.Size,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -68,7 +68,7 @@ func (q *Queries) DeleteVersionsByEntry(ctx context.Context, entryID int64) (int
 }
 
 const FindVersionByEntryAndVersion = `-- name: FindVersionByEntryAndVersion :one
-SELECT id, entry_id, version, file_path, hash, description, created_at
+SELECT id, entry_id, version, file_path, hash, description, created_at, size, verified_mtime, verified_size, language
 FROM versions
 WHERE entry_id = ? AND version = ?
 LIMIT 1
@@ -90,12 +90,16 @@ func (q *Queries) FindVersionByEntryAndVersion(ctx context.Context, arg FindVers
 		&i.Hash,
 		&i.Description,
 		&i.CreatedAt,
+		&i.Size,
+		&i.VerifiedMtime,
+		&i.VerifiedSize,
+		&i.Language,
 	)
 	return i, err
 }
 
 const FindVersionByID = `-- name: FindVersionByID :one
-SELECT id, entry_id, version, file_path, hash, description, created_at
+SELECT id, entry_id, version, file_path, hash, description, created_at, size, verified_mtime, verified_size, language
 FROM versions
 WHERE id = ?
 LIMIT 1
@@ -112,13 +116,73 @@ func (q *Queries) FindVersionByID(ctx context.Context, id int64) (Version, error
 		&i.Hash,
 		&i.Description,
 		&i.CreatedAt,
+		&i.Size,
+		&i.VerifiedMtime,
+		&i.VerifiedSize,
+		&i.Language,
+	)
+	return i, err
+}
+
+const GetVersionSummaryByEntry = `-- name: GetVersionSummaryByEntry :one
+SELECT
+    COUNT(*) AS version_count,
+    CAST(COALESCE(SUM(size), 0) AS INTEGER) AS total_size,
+    CAST(COALESCE(MIN(created_at), '') AS TEXT) AS first_written_at,
+    CAST(COALESCE(MAX(created_at), '') AS TEXT) AS last_written_at
+FROM versions
+WHERE entry_id = ?
+`
+
+type GetVersionSummaryByEntryRow struct {
+	VersionCount   int64  `json:"version_count"`
+	TotalSize      int64  `json:"total_size"`
+	FirstWrittenAt string `json:"first_written_at"`
+	LastWrittenAt  string `json:"last_written_at"`
+}
+
+func (q *Queries) GetVersionSummaryByEntry(ctx context.Context, entryID int64) (GetVersionSummaryByEntryRow, error) {
+	row := q.db.QueryRowContext(ctx, GetVersionSummaryByEntry, entryID)
+	var i GetVersionSummaryByEntryRow
+	err := row.Scan(
+		&i.VersionCount,
+		&i.TotalSize,
+		&i.FirstWrittenAt,
+		&i.LastWrittenAt,
+	)
+	return i, err
+}
+
+const GetVersionVerification = `-- name: GetVersionVerification :one
+SELECT verified_mtime, verified_size
+FROM versions
+WHERE entry_id = ? AND version = ?
+LIMIT 1
+`
+
+type GetVersionVerificationParams struct {
+	EntryID int64 `json:"entry_id"`
+	Version int64 `json:"version"`
+}
+
+type GetVersionVerificationRow struct {
+	VerifiedMtime sql.NullInt64 `json:"verified_mtime"`
+	VerifiedSize  sql.NullInt64 `json:"verified_size"`
+}
+
+func (q *Queries) GetVersionVerification(ctx context.Context, arg GetVersionVerificationParams) (GetVersionVerificationRow, error) {
+	row := q.db.QueryRowContext(ctx, GetVersionVerification, arg.EntryID, arg.Version)
+	var i GetVersionVerificationRow
+	err := row.Scan(
+		&i.VerifiedMtime,
+		&i.VerifiedSize,
 	)
 	return i, err
 }
 
 const InsertVersion = `-- name: InsertVersion :execresult
-INSERT INTO versions (entry_id, version, file_path, hash, description)
-VALUES (?, ?, ?, ?, ?)
+INSERT INTO versions (entry_id, version, file_path, hash, description, size, language)
+VALUES (?, ?, ?, ?, ?, ?, ?)
 `
 
 type InsertVersionParams struct {
@@ -127,6 +191,8 @@ type InsertVersionParams struct {
 	FilePath    string         `json:"file_path"`
 	Hash        string         `json:"hash"`
 	Description sql.NullString `json:"description"`
+	Size        sql.NullInt64  `json:"size"`
+	Language    sql.NullString `json:"language"`
 }
 
 func (q *Queries) InsertVersion(ctx context.Context, arg InsertVersionParams) (sql.Result, error) {
@@ -136,11 +202,13 @@ func (q *Queries) InsertVersion(ctx context.Context, arg InsertVersionParams) (s
 		arg.FilePath,
 		arg.Hash,
 		arg.Description,
+		arg.Size,
+		arg.Language,
 	)
 }
 
 const ListVersionsByEntry = `-- name: ListVersionsByEntry :many
-SELECT id, entry_id, version, file_path, hash, description, created_at
+SELECT id, entry_id, version, file_path, hash, description, created_at, size, verified_mtime, verified_size, language
 FROM versions
 WHERE entry_id = ?
 ORDER BY version DESC
@@ -163,6 +231,10 @@ func (q *Queries) ListVersionsByEntry(ctx context.Context, entryID int64) ([]Ver
 			&i.Hash,
 			&i.Description,
 			&i.CreatedAt,
+			&i.Size,
+			&i.VerifiedMtime,
+			&i.VerifiedSize,
+			&i.Language,
 		); err != nil {
 			return nil, err
 		}
@@ -189,3 +261,62 @@ func (q *Queries) MaxVersionForEntry(ctx context.Context, entryID int64) (int64,
 	err := row.Scan(&max_version)
 	return max_version, err
 }
+
+const UpdateVersionCreatedAt = `-- name: UpdateVersionCreatedAt :exec
+UPDATE versions
+SET created_at = CAST(?1 AS TEXT)
+WHERE id = ?2
+`
+
+type UpdateVersionCreatedAtParams struct {
+	CreatedAt string `json:"created_at"`
+	ID        int64  `json:"id"`
+}
+
+func (q *Queries) UpdateVersionCreatedAt(ctx context.Context, arg UpdateVersionCreatedAtParams) error {
+	_, err := q.db.ExecContext(ctx, UpdateVersionCreatedAt, arg.CreatedAt, arg.ID)
+	return err
+}
+
+const UpdateVersionFilePath = `-- name: UpdateVersionFilePath :execrows
+UPDATE versions
+SET file_path = ?1
+WHERE id = ?2 AND file_path = ?3
+`
+
+type UpdateVersionFilePathParams struct {
+	NewPath string `json:"new_path"`
+	ID      int64  `json:"id"`
+	OldPath string `json:"old_path"`
+}
+
+func (q *Queries) UpdateVersionFilePath(ctx context.Context, arg UpdateVersionFilePathParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, UpdateVersionFilePath, arg.NewPath, arg.ID, arg.OldPath)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const UpdateVersionVerification = `-- name: UpdateVersionVerification :exec
+UPDATE versions
+SET verified_mtime = ?, verified_size = ?
+WHERE entry_id = ? AND version = ?
+`
+
+type UpdateVersionVerificationParams struct {
+	VerifiedMtime sql.NullInt64 `json:"verified_mtime"`
+	VerifiedSize  sql.NullInt64 `json:"verified_size"`
+	EntryID       int64         `json:"entry_id"`
+	Version       int64         `json:"version"`
+}
+
+func (q *Queries) UpdateVersionVerification(ctx context.Context, arg UpdateVersionVerificationParams) error {
+	_, err := q.db.ExecContext(ctx, UpdateVersionVerification,
+		arg.VerifiedMtime,
+		arg.VerifiedSize,
+		arg.EntryID,
+		arg.Version,
+	)
+	return err
+}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	sqldb "github.com/choplin/vault.md/internal/database/sqlc"
+)
+
+// hotQueries lists the sqlc queries executed on nearly every CLI/MCP call.
+// Only these are prepared up front; everything else goes straight to the
+// connection pool so rarely used statements don't hold resources.
+var hotQueries = map[string]struct{}{
+	sqldb.GetScopedEntryLatest:   {},
+	sqldb.FindEntryByScopeAndKey: {},
+	sqldb.InsertVersion:          {},
+}
+
+// stmtCache implements sqldb.DBTX on top of *sql.DB, lazily preparing hot
+// queries once and reusing the prepared statements for subsequent calls.
+type stmtCache struct {
+	db    *sql.DB
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+func newStmtCache(db *sql.DB) *stmtCache {
+	return &stmtCache{
+		db:    db,
+		stmts: make(map[string]*sql.Stmt, len(hotQueries)),
+	}
+}
+
+// lookup returns the prepared statement for query, preparing it on first use.
+// A nil result means the caller should run the query unprepared.
+func (c *stmtCache) lookup(ctx context.Context, query string) *sql.Stmt {
+	if _, ok := hotQueries[query]; !ok {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if stmt, ok := c.stmts[query]; ok {
+		return stmt
+	}
+
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		// Fall back to unprepared execution; the query itself will surface
+		// any real error to the caller.
+		return nil
+	}
+	c.stmts[query] = stmt
+	return stmt
+}
+
+func (c *stmtCache) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if stmt := c.lookup(ctx, query); stmt != nil {
+		return stmt.ExecContext(ctx, args...)
+	}
+	return c.db.ExecContext(ctx, query, args...)
+}
+
+func (c *stmtCache) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.db.PrepareContext(ctx, query)
+}
+
+func (c *stmtCache) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if stmt := c.lookup(ctx, query); stmt != nil {
+		return stmt.QueryContext(ctx, args...)
+	}
+	return c.db.QueryContext(ctx, query, args...)
+}
+
+func (c *stmtCache) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if stmt := c.lookup(ctx, query); stmt != nil {
+		return stmt.QueryRowContext(ctx, args...)
+	}
+	return c.db.QueryRowContext(ctx, query, args...)
+}
+
+// close releases all prepared statements.
+func (c *stmtCache) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var firstErr error
+	for query, stmt := range c.stmts {
+		if err := stmt.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(c.stmts, query)
+	}
+	return firstErr
+}
+
+// txStmts binds cached statements to a transaction so hot queries issued
+// inside withTx helpers also skip re-parsing.
+type txStmts struct {
+	tx    *sql.Tx
+	cache *stmtCache
+}
+
+func (t *txStmts) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if stmt := t.cache.lookup(ctx, query); stmt != nil {
+		return t.tx.StmtContext(ctx, stmt).ExecContext(ctx, args...)
+	}
+	return t.tx.ExecContext(ctx, query, args...)
+}
+
+func (t *txStmts) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.tx.PrepareContext(ctx, query)
+}
+
+func (t *txStmts) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if stmt := t.cache.lookup(ctx, query); stmt != nil {
+		return t.tx.StmtContext(ctx, stmt).QueryContext(ctx, args...)
+	}
+	return t.tx.QueryContext(ctx, query, args...)
+}
+
+func (t *txStmts) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if stmt := t.cache.lookup(ctx, query); stmt != nil {
+		return t.tx.StmtContext(ctx, stmt).QueryRowContext(ctx, args...)
+	}
+	return t.tx.QueryRowContext(ctx, query, args...)
+}
+
+// TxQueries returns a Queries helper bound to tx that reuses the context's
+// prepared statements when available.
+func (c *Context) TxQueries(tx *sql.Tx) *sqldb.Queries {
+	if c == nil || c.stmts == nil {
+		return sqldb.New(tx)
+	}
+	return sqldb.New(&txStmts{tx: tx, cache: c.stmts})
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	sqldb "github.com/choplin/vault.md/internal/database/sqlc"
+)
+
+func TestStmtCachePreparesHotQueriesOnce(t *testing.T) {
+	ctx := setupTestDB(t)
+	bg := context.Background()
+
+	scopeID := insertScope(t, ctx.DB, "repository", "/repo", "repo-scope")
+	insertEntry(t, ctx.DB, scopeID, "notes")
+
+	for i := 0; i < 2; i++ {
+		if _, err := ctx.Queries.FindEntryByScopeAndKey(bg, sqldb.FindEntryByScopeAndKeyParams{ScopeID: scopeID, Key: "notes"}); err != nil {
+			t.Fatalf("FindEntryByScopeAndKey returned error: %v", err)
+		}
+	}
+	if _, err := ctx.Queries.ListScopes(bg); err != nil {
+		t.Fatalf("ListScopes returned error: %v", err)
+	}
+
+	if got := len(ctx.stmts.stmts); got != 1 {
+		t.Fatalf("expected only the hot query to be prepared, got %d statements", got)
+	}
+
+	tx, err := ctx.DB.BeginTx(bg, nil)
+	if err != nil {
+		t.Fatalf("BeginTx returned error: %v", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = ctx.TxQueries(tx).FindEntryByScopeAndKey(bg, sqldb.FindEntryByScopeAndKeyParams{ScopeID: scopeID, Key: "missing"})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows inside transaction, got %v", err)
+	}
+}
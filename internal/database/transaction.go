@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// RunInTx runs fn in a single write transaction, committing it when fn
+// returns nil and rolling it back otherwise. The Context passed to fn is
+// bound to the transaction: its Queries run inside it, and services that
+// would start their own transaction on it join this one instead, so
+// everything fn does through it is applied together or not at all.
+//
+// Calling RunInTx on a Context that is already bound runs fn under a
+// savepoint of the existing transaction, so a failing fn undoes only its own
+// changes. A bound Context must not be used from several goroutines.
+func (c *Context) RunInTx(ctx context.Context, fn func(txCtx *Context) error) error {
+	if c == nil || c.DB == nil {
+		return fmt.Errorf("database: missing database context")
+	}
+	if c.tx != nil {
+		return c.savepoint(ctx, func() error { return fn(c) })
+	}
+
+	return c.RunWrite(ctx, func() error {
+		tx, err := c.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		txCtx := &Context{
+			DB:        c.DB,
+			Queries:   c.TxQueries(tx),
+			stmts:     c.stmts,
+			writeLock: c.writeLock,
+			tx:        tx,
+		}
+		if err := fn(txCtx); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		return nil
+	})
+}
+
+// savepoint runs fn under a new savepoint of the bound transaction, rolling
+// back to it if fn fails.
+func (c *Context) savepoint(ctx context.Context, fn func() error) error {
+	c.savepoints++
+	defer func() { c.savepoints-- }()
+	name := fmt.Sprintf("vault_sp_%d", c.savepoints)
+
+	if _, err := c.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		_, _ = c.tx.ExecContext(ctx, "ROLLBACK TO "+name)
+		_, _ = c.tx.ExecContext(ctx, "RELEASE "+name)
+		return err
+	}
+	_, err := c.tx.ExecContext(ctx, "RELEASE "+name)
+	return err
+}
+
+// InTx reports whether c is bound to a transaction started by RunInTx.
+func (c *Context) InTx() bool {
+	return c != nil && c.tx != nil
+}
+
+// QueryContext runs a query that sqlc cannot express, inside the bound
+// transaction if there is one and on the connection pool otherwise.
+func (c *Context) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if c.tx != nil {
+		return c.tx.QueryContext(ctx, query, args...)
+	}
+	return c.DB.QueryContext(ctx, query, args...)
+}
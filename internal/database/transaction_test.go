@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	sqldb "github.com/choplin/vault.md/internal/database/sqlc"
+)
+
+func insertGlobalScope(ctx context.Context, t *testing.T, q *sqldb.Queries, path string) {
+	t.Helper()
+	if _, err := q.InsertScope(ctx, sqldb.InsertScopeParams{Type: "global", ScopePath: path}); err != nil {
+		t.Fatalf("InsertScope(%s) returned error: %v", path, err)
+	}
+}
+
+func scopeExists(ctx context.Context, t *testing.T, dbCtx *Context, path string) bool {
+	t.Helper()
+	_, err := dbCtx.Queries.FindScopeByPath(ctx, path)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		t.Fatalf("FindScopeByPath(%s) returned error: %v", path, err)
+	}
+	return true
+}
+
+func TestRunInTxCommitsAndRollsBack(t *testing.T) {
+	dbCtx := setupTestDB(t)
+	ctx := context.Background()
+
+	err := dbCtx.RunInTx(ctx, func(txCtx *Context) error {
+		if !txCtx.InTx() {
+			t.Fatal("expected the Context passed to fn to be bound")
+		}
+		insertGlobalScope(ctx, t, txCtx.Queries, "committed")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunInTx returned error: %v", err)
+	}
+	if dbCtx.InTx() {
+		t.Fatal("expected the outer Context to stay unbound")
+	}
+
+	errBoom := errors.New("boom")
+	err = dbCtx.RunInTx(ctx, func(txCtx *Context) error {
+		insertGlobalScope(ctx, t, txCtx.Queries, "rolled-back")
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected fn's error, got %v", err)
+	}
+
+	if !scopeExists(ctx, t, dbCtx, "committed") {
+		t.Fatal("expected the committed scope to exist")
+	}
+	if scopeExists(ctx, t, dbCtx, "rolled-back") {
+		t.Fatal("expected the rolled back scope to be gone")
+	}
+}
+
+func TestRunInTxNestedFailureUndoesOnlyItsOwnChanges(t *testing.T) {
+	dbCtx := setupTestDB(t)
+	ctx := context.Background()
+
+	err := dbCtx.RunInTx(ctx, func(txCtx *Context) error {
+		insertGlobalScope(ctx, t, txCtx.Queries, "outer")
+		nestedErr := txCtx.RunInTx(ctx, func(nested *Context) error {
+			insertGlobalScope(ctx, t, nested.Queries, "inner")
+			return errors.New("inner failed")
+		})
+		if nestedErr == nil {
+			t.Fatal("expected the nested error to be returned")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunInTx returned error: %v", err)
+	}
+
+	if !scopeExists(ctx, t, dbCtx, "outer") {
+		t.Fatal("expected the outer change to be committed")
+	}
+	if scopeExists(ctx, t, dbCtx, "inner") {
+		t.Fatal("expected the nested change to be rolled back")
+	}
+}
@@ -14,6 +14,11 @@ type ScopeRecord struct {
 	ScopePath string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+
+	// Description and Metadata tell people what the scope is for; both
+	// are empty unless set with vault scope describe.
+	Description string
+	Metadata    map[string]string
 }
 
 // EntryRecord represents a row in the entries table. Each entry belongs to a
@@ -44,11 +49,16 @@ type VersionRecord struct {
 	Hash        string
 	Description *string
 	CreatedAt   time.Time
+	Size        int64
+	// Language is the content language hint, such as "markdown" or "go",
+	// or "" if unknown.
+	Language string
 }
 
 // ScopedEntryRecord is a denormalised view combining information from
 // entries, entry_status, and versions for easy consumption at the service
-// layer.
+// layer. CreatedAt is when the entry was first created; UpdatedAt is when
+// this particular version was written.
 type ScopedEntryRecord struct {
 	EntryID     int64
 	ScopeID     int64
@@ -58,7 +68,22 @@ type ScopedEntryRecord struct {
 	Hash        string
 	Description *string
 	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Size        int64
 	IsArchived  bool
+	// Language is the content language hint, such as "markdown" or "go",
+	// or "" if unknown.
+	Language string
+	// Summary is the generated summary of the version, or "" if none.
+	Summary string
+	// LastReadAt and ReadCount record reads of the entry when read
+	// tracking is enabled; LastReadAt is zero if it was never read.
+	LastReadAt time.Time
+	ReadCount  int64
+	// Provenance, when set, is stored alongside the version on Create.
+	Provenance *VersionProvenance
+	// IdempotencyKey, when set, is bound to the version on Create.
+	IdempotencyKey string
 }
 
 // EntryVersionInfo contains version information for an entry.
@@ -68,6 +93,161 @@ type EntryVersionInfo struct {
 	CreatedAt time.Time
 }
 
+// VersionFileRecord identifies the object file backing one version.
+type VersionFileRecord struct {
+	VersionID int64
+	EntryID   int64
+	Version   int64
+	FilePath  string
+	Hash      string
+}
+
+// PendingDeletionRecord is an object file whose version rows are gone but
+// which has not been removed from disk yet.
+type PendingDeletionRecord struct {
+	FilePath  string
+	QueuedAt  time.Time
+	Attempts  int64
+	LastError string
+}
+
+// DanglingRowCounts counts rows whose references no longer resolve.
+type DanglingRowCounts struct {
+	EntriesWithoutVersions   int64
+	EntriesWithoutStatus     int64
+	StatusWithMissingVersion int64
+}
+
+// Total returns the number of dangling rows across all categories.
+func (c DanglingRowCounts) Total() int64 {
+	return c.EntriesWithoutVersions + c.EntriesWithoutStatus + c.StatusWithMissingVersion
+}
+
+// VersionProvenance records where and how a version was written. Fields
+// that were not captured are empty; GitDirty is nil when unknown.
+type VersionProvenance struct {
+	Actor     string
+	Tool      string
+	Hostname  string
+	GitBranch string
+	GitCommit string
+	GitDirty  *bool
+	DeviceID  string
+}
+
+// VersionApproval records that a version was approved, and by whom.
+type VersionApproval struct {
+	ApprovedBy string
+	ApprovedAt time.Time
+}
+
+// VersionHistoryRecord pairs a version with its provenance, if any was
+// recorded, and its approval; versions without one are drafts.
+type VersionHistoryRecord struct {
+	VersionRecord
+	Provenance *VersionProvenance
+	Approval   *VersionApproval
+	// Summary is the generated summary of the version, or "" if none.
+	Summary string
+}
+
+// DeviceRecord describes an installation that has written to the vault.
+type DeviceRecord struct {
+	DeviceID     string
+	Hostname     string
+	FirstSeenAt  time.Time
+	RevokedAt    *time.Time
+	VersionCount int64
+	LastWriteAt  time.Time
+}
+
+// VersionDeviceRecord names the device that wrote one version of an entry.
+type VersionDeviceRecord struct {
+	EntryID  int64
+	Version  int64
+	DeviceID string
+}
+
+// LockRecord is an advisory lock on a key, held by Owner until ExpiresAt.
+type LockRecord struct {
+	ScopeID    int64
+	Key        string
+	Owner      string
+	AcquiredAt time.Time
+	ExpiresAt  time.Time
+}
+
+// ChildRecord declares ChildKey a child of ParentKey. Children of a parent
+// are ordered by Position.
+type ChildRecord struct {
+	ScopeID   int64
+	ChildKey  string
+	ParentKey string
+	Position  int64
+}
+
+// ScopeSnapshotRecord is a named set of versions pinned in a scope, one per
+// key, for reading the scope as it was when the snapshot was created.
+type ScopeSnapshotRecord struct {
+	ID        int64
+	ScopeID   int64
+	Name      string
+	CreatedAt time.Time
+	// KeyCount is how many keys have a pinned version.
+	KeyCount int64
+}
+
+// PinnedVersion is the version of Key a scope snapshot pins.
+type PinnedVersion struct {
+	Key     string
+	Version int64
+}
+
+// IdempotentWrite is the version previously created under an idempotency key.
+type IdempotentWrite struct {
+	ScopeID  int64
+	Key      string
+	Version  int64
+	FilePath string
+	Hash     string
+}
+
+// VaultUsage summarises how much the vault stores across all scopes.
+type VaultUsage struct {
+	EntryCount   int64
+	VersionCount int64
+	TotalSize    int64
+}
+
+// SupersededVersion is a version that is no longer the latest of its entry.
+type SupersededVersion struct {
+	Key      string
+	Version  int64
+	FilePath string
+	Size     int64
+}
+
+// ReclaimableVersions counts versions that fall outside the retention policy.
+type ReclaimableVersions struct {
+	VersionCount int64
+	TotalSize    int64
+}
+
+// VersionVerification records the file mtime and size observed the last time a
+// version's content was successfully verified against its hash.
+type VersionVerification struct {
+	MtimeNs int64
+	Size    int64
+}
+
+// EntryVersionSummary aggregates metadata across every version of an entry.
+type EntryVersionSummary struct {
+	VersionCount   int64
+	TotalSize      int64
+	FirstWrittenAt time.Time
+	LastWrittenAt  time.Time
+}
+
 // EntryVersionCount contains version count for an entry.
 type EntryVersionCount struct {
 	EntryID      int64
@@ -0,0 +1,127 @@
+// Package entrykey decides which keys the vault accepts for new entries.
+// Keys become part of object file names, so every way of writing an entry
+// (the CLI, the MCP server, imports, and snapshot restores) runs the key
+// through Clean first, and gets the same normalisation and the same errors.
+// Lookups fall back to the Normalize form of a key no entry has as given,
+// so the key a write was given also reads it back.
+package entrykey
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxEncodedLength is the longest a key may be once percent-encoded for its
+// object file name. Together with the version suffix this keeps file names
+// within the 255 bytes most filesystems allow.
+const MaxEncodedLength = 200
+
+// ReservedPrefix starts the keys of entries the vault keeps for itself.
+// Users cannot write them, and vault list leaves them out unless asked.
+const ReservedPrefix = "_vault/"
+
+var (
+	// ErrInvalid is returned for keys the vault does not accept.
+	ErrInvalid = errors.New("invalid key")
+	// ErrReserved is returned, along with ErrInvalid, for keys under
+	// ReservedPrefix written by users.
+	ErrReserved = errors.New("reserved for the vault's own entries")
+)
+
+// IsReserved reports whether key is under ReservedPrefix.
+func IsReserved(key string) bool {
+	return strings.HasPrefix(key, ReservedPrefix)
+}
+
+// Normalize trims surrounding whitespace from key and puts it in Unicode
+// normalization form C, so that keys typed on different systems, such as a
+// decomposed "é" from macOS, name the same entry.
+func Normalize(key string) string {
+	return norm.NFC.String(strings.TrimSpace(key))
+}
+
+// Validate reports why key cannot name a new entry. Keys are treated as
+// slash-separated paths: they may not start with "/", contain empty
+// segments, or use "." and ".." as segments. Keys under ReservedPrefix are
+// rejected with ErrReserved; see ValidateInternal.
+func Validate(key string) error {
+	if err := ValidateInternal(key); err != nil {
+		return err
+	}
+	if IsReserved(key) {
+		return fmt.Errorf("%w %q: keys starting with %q are %w", ErrInvalid, key, ReservedPrefix, ErrReserved)
+	}
+	return nil
+}
+
+// ValidateInternal is Validate for writes by the vault itself, which may
+// use ReservedPrefix.
+func ValidateInternal(key string) error {
+	if key == "" {
+		return fmt.Errorf("%w: key is empty", ErrInvalid)
+	}
+	if err := CheckText(key); err != nil {
+		return fmt.Errorf("%w %q: %v", ErrInvalid, key, err)
+	}
+	if strings.HasPrefix(key, "/") {
+		return fmt.Errorf("%w %q: must not start with \"/\"", ErrInvalid, key)
+	}
+	for segment := range strings.SplitSeq(key, "/") {
+		switch segment {
+		case "":
+			return fmt.Errorf("%w %q: must not contain empty path segments", ErrInvalid, key)
+		case ".", "..":
+			return fmt.Errorf("%w %q: must not contain %q as a path segment", ErrInvalid, key, segment)
+		}
+	}
+	if n := encodedLength(key); n > MaxEncodedLength {
+		return fmt.Errorf("%w %q: too long (%d bytes encoded for the file name, max %d; non-ASCII characters take 6 to 12)", ErrInvalid, key, n, MaxEncodedLength)
+	}
+	return nil
+}
+
+// Clean normalizes key and validates the result, returning the key to store.
+func Clean(key string) (string, error) {
+	key = Normalize(key)
+	if err := Validate(key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// CleanInternal is Clean for writes by the vault itself, which may use
+// ReservedPrefix.
+func CleanInternal(key string) (string, error) {
+	key = Normalize(key)
+	if err := ValidateInternal(key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// CheckText rejects text that is not valid UTF-8 or that contains control
+// characters, including line and paragraph separators, which would garble
+// listings and file names.
+func CheckText(s string) error {
+	if !utf8.ValidString(s) {
+		return errors.New("not valid UTF-8")
+	}
+	for _, r := range s {
+		if unicode.IsControl(r) || r == '\u2028' || r == '\u2029' {
+			return fmt.Errorf("contains control character %U", r)
+		}
+	}
+	return nil
+}
+
+// encodedLength is the length of key in an object file name, which
+// percent-encodes it like encodeURIComponent.
+func encodedLength(key string) int {
+	return len(strings.ReplaceAll(url.QueryEscape(key), "+", "%20"))
+}
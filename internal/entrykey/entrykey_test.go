@@ -0,0 +1,115 @@
+package entrykey
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"notes", "notes"},
+		{"  notes\n", "notes"},
+		{"docs/design.md", "docs/design.md"},
+		{"with space", "with space"},
+		{"café", "café"},
+		{"日本語", "日本語"},
+		{strings.Repeat("a", MaxEncodedLength), strings.Repeat("a", MaxEncodedLength)},
+	}
+	for _, tt := range tests {
+		got, err := Clean(tt.key)
+		if err != nil {
+			t.Errorf("Clean(%q): %v", tt.key, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Clean(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestCleanRejects(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"", "empty"},
+		{"   ", "empty"},
+		{"a\x00b", "control character U+0000"},
+		{"a\tb", "control character U+0009"},
+		{"a\x7fb", "control character U+007F"},
+		{"a\u2028b", "control character U+2028"},
+		{"a\xffb", "UTF-8"},
+		{"/etc/passwd", "start with"},
+		{"a//b", "empty path segments"},
+		{"a/", "empty path segments"},
+		{"../secret", `".."`},
+		{"a/./b", `"."`},
+		{strings.Repeat("a", MaxEncodedLength+1), "too long"},
+		{strings.Repeat("é", 40), "too long"},
+		{"_vault/templates/readme", "reserved"},
+		{" _vault/x", "reserved"},
+	}
+	for _, tt := range tests {
+		_, err := Clean(tt.key)
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("Clean(%q) error = %v, want ErrInvalid", tt.key, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Clean(%q) error = %q, want it to mention %q", tt.key, err, tt.want)
+		}
+	}
+}
+
+func TestReserved(t *testing.T) {
+	_, err := Clean("_vault/config")
+	if !errors.Is(err, ErrReserved) || !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Clean error = %v, want ErrReserved and ErrInvalid", err)
+	}
+
+	got, err := CleanInternal(" _vault/config")
+	if err != nil || got != "_vault/config" {
+		t.Fatalf("CleanInternal = %q, %v, want _vault/config", got, err)
+	}
+	if _, err := CleanInternal("_vault/../x"); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("CleanInternal error = %v, want ErrInvalid", err)
+	}
+
+	for key, want := range map[string]bool{"_vault/x": true, "_vault": false, "_vaults/x": false, "notes/_vault/x": false} {
+		if got := IsReserved(key); got != want {
+			t.Errorf("IsReserved(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func FuzzClean(f *testing.F) {
+	for _, seed := range []string{"notes", " a/b ", "café", "a\x00", "../x", "\xff", "a//b"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, key string) {
+		cleaned, err := Clean(key)
+		if err != nil {
+			if !errors.Is(err, ErrInvalid) {
+				t.Fatalf("Clean(%q) error %v does not wrap ErrInvalid", key, err)
+			}
+			return
+		}
+		if again, err := Clean(cleaned); err != nil || again != cleaned {
+			t.Fatalf("Clean(%q) = %q, but Clean of that = %q, %v", key, cleaned, again, err)
+		}
+		if !utf8.ValidString(cleaned) || CheckText(cleaned) != nil {
+			t.Fatalf("Clean(%q) = %q, which has invalid text", key, cleaned)
+		}
+		if IsReserved(cleaned) {
+			t.Fatalf("Clean(%q) = %q, which is reserved", key, cleaned)
+		}
+		if encodedLength(cleaned) > MaxEncodedLength {
+			t.Fatalf("Clean(%q) = %q, which is too long", key, cleaned)
+		}
+	})
+}
@@ -4,6 +4,7 @@ package filesystem
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"io/fs"
 	"net/url"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 
 	"github.com/choplin/vault.md/internal/config"
 )
@@ -21,7 +23,7 @@ var ensureOnce sync.Once
 func ensureObjectsDir() error {
 	var setupErr error
 	ensureOnce.Do(func() {
-		setupErr = os.MkdirAll(config.GetObjectsDir(), 0o750)
+		setupErr = mkdirAllDurable(config.GetObjectsDir())
 	})
 	return setupErr
 }
@@ -34,25 +36,159 @@ func GetProjectDir(project string) string {
 
 // SaveFile writes content to the on-disk object store and returns the file path and hash.
 func SaveFile(project, key string, version int, content string) (string, string, error) {
+	return saveFile(project, key, version, content, writeFileAtomic)
+}
+
+// SaveNewFile is like SaveFile but fails with an error matching fs.ErrExist
+// if the version's file already exists, so writers that picked the same
+// version number cannot overwrite each other's content.
+func SaveNewFile(project, key string, version int, content string) (string, string, error) {
+	return saveFile(project, key, version, content, writeFileExclusive)
+}
+
+func saveFile(project, key string, version int, content string, write func(string, []byte) error) (string, string, error) {
 	if err := ensureObjectsDir(); err != nil {
 		return "", "", err
 	}
 
-	projectDir := GetProjectDir(project)
-	if err := os.MkdirAll(projectDir, 0o750); err != nil {
+	layout, err := CurrentLayout()
+	if err != nil {
+		return "", "", err
+	}
+	filePath := getFilePath(project, key, version, layout)
+	if err := mkdirAllDurable(filepath.Dir(filePath)); err != nil {
 		return "", "", err
 	}
-
-	filePath := getFilePath(project, key, version)
 	hash := calculateHash(content)
 
-	if err := os.WriteFile(filePath, []byte(content), 0o600); err != nil {
+	if err := write(filePath, []byte(content)); err != nil {
 		return "", "", err
 	}
 
 	return filePath, hash, nil
 }
 
+// mkdirAllDurable is os.MkdirAll that also syncs the parent of every
+// directory it creates, so the directories survive a crash along with the
+// objects written into them.
+func mkdirAllDurable(dir string) error {
+	var created []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil || filepath.Dir(d) == d {
+			break
+		}
+		created = append(created, d)
+	}
+	if len(created) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+	for _, d := range created {
+		if err := syncDir(filepath.Dir(d)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTemp writes data to a new temporary file next to path and syncs it to
+// stable storage, returning its name.
+func writeTemp(path string, data []byte) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-"+filepath.Base(path)+"-*")
+	if err != nil {
+		return "", err
+	}
+	tmpPath := tmp.Name()
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return "", err
+	}
+	return tmpPath, nil
+}
+
+// writeFileAtomic writes data to a temporary file in the destination directory
+// and renames it into place, so concurrent readers (possibly on other hosts
+// sharing the directory) never observe a partially written object. The file
+// and the directory are synced, so after a crash the object is either
+// complete or absent, never truncated.
+func writeFileAtomic(path string, data []byte) error {
+	tmpPath, err := writeTemp(path, data)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// link is os.Link, replaced in tests to act like a filesystem without hard
+// links.
+var link = os.Link
+
+// writeFileExclusive is writeFileAtomic without replacing an existing file:
+// the complete temporary file is hard-linked into place, which fails if the
+// destination already exists. Filesystems without hard links, such as FAT,
+// exFAT, and many SMB and cloud-sync mounts, get the file created
+// exclusively and written in place instead, so a concurrent reader may see
+// it before it is complete.
+func writeFileExclusive(path string, data []byte) error {
+	tmpPath, err := writeTemp(path, data)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmpPath)
+	}()
+
+	if err := link(tmpPath, path); err != nil {
+		if !linkUnsupported(err) {
+			return err
+		}
+		return createExclusive(path, data)
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// linkUnsupported reports whether a failed os.Link means the filesystem
+// cannot hard-link, rather than that the destination exists.
+func linkUnsupported(err error) bool {
+	return errors.Is(err, errors.ErrUnsupported) || errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EXDEV)
+}
+
+// createExclusive writes data to path, which must not exist yet, and syncs
+// it and its directory. A failed write leaves no file behind.
+func createExclusive(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600) //nolint:gosec // G304: object path inside the vault
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
 // ReadFile reads a file from disk and returns its contents as a string.
 func ReadFile(path string) (string, error) {
 	//nolint:gosec // G304: path is from database, controlled by application
@@ -106,29 +242,33 @@ func DeleteProjectFiles(project string) error {
 
 // DeleteKeyFiles removes all versions of a key within a project and returns the number of removed files.
 func DeleteKeyFiles(project, key string) (int, error) {
-	dir := GetProjectDir(project)
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return 0, nil
-		}
-		return 0, err
-	}
-
+	projectDir := GetProjectDir(project)
 	encodedKey := urlEncode(key)
 	prefix := encodedKey + "_v"
 	count := 0
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+	// Objects written before a relayout may remain in the other layout.
+	for _, layout := range []Layout{LayoutFlat, LayoutSharded} {
+		dir := objectDir(projectDir, encodedKey, layout)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return count, err
 		}
-		name := entry.Name()
-		if strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ".txt") {
-			if err := os.Remove(filepath.Join(dir, name)); err != nil {
-				return count, err
+
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			name := entry.Name()
+			if strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ".txt") {
+				if err := RemoveObject(filepath.Join(dir, name)); err != nil {
+					return count, err
+				}
+				count++
 			}
-			count++
 		}
 	}
 
@@ -136,9 +276,15 @@ func DeleteKeyFiles(project, key string) (int, error) {
 }
 
 // getFilePath constructs the storage path for a key/version pair.
-func getFilePath(project, key string, version int) string {
-	filename := urlEncode(key) + "_v" + strconv.Itoa(version) + ".txt"
-	return filepath.Join(GetProjectDir(project), filename)
+func getFilePath(project, key string, version int, layout Layout) string {
+	encodedKey := urlEncode(key)
+	filename := encodedKey + "_v" + strconv.Itoa(version) + ".txt"
+	return filepath.Join(objectDir(GetProjectDir(project), encodedKey, layout), filename)
+}
+
+// HashContent returns the hex-encoded SHA-256 digest used to identify content.
+func HashContent(content string) string {
+	return calculateHash(content)
 }
 
 func calculateHash(content string) string {
@@ -154,7 +300,8 @@ func urlEncode(value string) string {
 // WalkFunc explores each entry under the project's object directory.
 type WalkFunc func(path string, d fs.DirEntry) error
 
-// WalkProjectFiles iterates over all files in a project directory.
+// WalkProjectFiles iterates over all files in a project directory, including
+// those in shard directories.
 func WalkProjectFiles(project string, fn WalkFunc) error {
 	dir := GetProjectDir(project)
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
@@ -167,7 +314,20 @@ func WalkProjectFiles(project string, fn WalkFunc) error {
 	}
 
 	for _, entry := range entries {
-		if err := fn(filepath.Join(dir, entry.Name()), entry); err != nil {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() && isShardName(entry.Name()) {
+			shard, err := os.ReadDir(path)
+			if err != nil {
+				return err
+			}
+			for _, object := range shard {
+				if err := fn(filepath.Join(path, object.Name()), object); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := fn(path, entry); err != nil {
 			return err
 		}
 	}
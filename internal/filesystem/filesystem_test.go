@@ -1,10 +1,13 @@
 package filesystem
 
 import (
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 	"testing"
 )
 
@@ -84,3 +87,101 @@ func TestDeleteKeyAndProjectFiles(t *testing.T) {
 		t.Fatalf("expected project dir to be removed, stat err: %v", err)
 	}
 }
+
+func TestSaveNewFileRefusesExistingVersion(t *testing.T) {
+	setupEnv(t)
+	project := "/Users/example/project"
+
+	path, _, err := SaveNewFile(project, "notes", 1, "first")
+	if err != nil {
+		t.Fatalf("SaveNewFile returned error: %v", err)
+	}
+
+	if _, _, err := SaveNewFile(project, "notes", 1, "second"); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("expected fs.ErrExist, got %v", err)
+	}
+
+	content, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile error: %v", err)
+	}
+	if content != "first" {
+		t.Fatalf("expected original content to survive, got %q", content)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("ReadDir error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected temporary files to be cleaned up, found %d entries", len(entries))
+	}
+}
+
+func TestSaveNewFileWithoutHardLinks(t *testing.T) {
+	setupEnv(t)
+	project := "/Users/example/project"
+
+	var dir string
+	for _, linkErr := range []error{syscall.EPERM, syscall.EXDEV, syscall.ENOSYS, syscall.ENOTSUP} {
+		t.Run(linkErr.Error(), func(t *testing.T) {
+			defer func(orig func(string, string) error) { link = orig }(link)
+			link = func(oldname, newname string) error {
+				return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: linkErr}
+			}
+
+			key := "notes-" + strings.ReplaceAll(linkErr.Error(), " ", "-")
+			path, _, err := SaveNewFile(project, key, 1, "first")
+			if err != nil {
+				t.Fatalf("SaveNewFile returned error: %v", err)
+			}
+			dir = filepath.Dir(path)
+			if _, _, err := SaveNewFile(project, key, 1, "second"); !errors.Is(err, fs.ErrExist) {
+				t.Fatalf("expected fs.ErrExist, got %v", err)
+			}
+			content, err := ReadFile(path)
+			if err != nil || content != "first" {
+				t.Fatalf("ReadFile = %q, %v; want the first write", content, err)
+			}
+		})
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir error: %v", err)
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".tmp-") {
+			t.Fatalf("expected temporary files to be cleaned up, found %s", e.Name())
+		}
+	}
+}
+
+func TestSaveFileReplacesAtomically(t *testing.T) {
+	setupEnv(t)
+	project := "/Users/example/new/project"
+
+	if _, _, err := SaveFile(project, "notes", 1, "first"); err != nil {
+		t.Fatalf("SaveFile returned error: %v", err)
+	}
+	path, _, err := SaveFile(project, "notes", 1, "second")
+	if err != nil {
+		t.Fatalf("SaveFile returned error: %v", err)
+	}
+
+	content, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile error: %v", err)
+	}
+	if content != "second" {
+		t.Fatalf("expected replaced content, got %q", content)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("ReadDir error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected temporary files to be cleaned up, found %d entries", len(entries))
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package filesystem
+
+import "errors"
+
+// FreeSpace is not implemented on this platform.
+func FreeSpace(string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,198 @@
+package filesystem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/choplin/vault.md/internal/config"
+)
+
+// Layout is the arrangement of objects within the directory of a scope.
+type Layout string
+
+const (
+	// LayoutFlat keeps every object of a scope directly in its directory.
+	LayoutFlat Layout = "flat"
+	// LayoutSharded spreads the objects of a scope over up to 256
+	// subdirectories, like git's objects/ab/, so that busy scopes do not
+	// end up with directories of many thousand files. The subdirectory is
+	// named after the first byte of the SHA-256 of the encoded key, so all
+	// versions of a key share one.
+	LayoutSharded Layout = "sharded"
+)
+
+// layoutFileName is the file in the objects directory recording the layout
+// new objects are written in. Vaults without one use LayoutFlat.
+const layoutFileName = ".layout"
+
+// ParseLayout validates a layout name.
+func ParseLayout(name string) (Layout, error) {
+	switch layout := Layout(name); layout {
+	case LayoutFlat, LayoutSharded:
+		return layout, nil
+	default:
+		return "", fmt.Errorf("invalid object layout: %s (valid values: %s, %s)", name, LayoutFlat, LayoutSharded)
+	}
+}
+
+func layoutFilePath() string {
+	return filepath.Join(config.GetObjectsDir(), layoutFileName)
+}
+
+// IsLayoutFile reports whether path is the file recording the layout rather
+// than an object.
+func IsLayoutFile(path string) bool {
+	return filepath.Clean(path) == layoutFilePath()
+}
+
+// CurrentLayout returns the layout new objects are written in.
+func CurrentLayout() (Layout, error) {
+	data, err := os.ReadFile(layoutFilePath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return LayoutFlat, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return ParseLayout(strings.TrimSpace(string(data)))
+}
+
+// SetLayout makes new objects be written in layout. Existing objects stay
+// where they are; see RelayoutPath for moving them.
+func SetLayout(layout Layout) error {
+	if _, err := ParseLayout(string(layout)); err != nil {
+		return err
+	}
+	if err := ensureObjectsDir(); err != nil {
+		return err
+	}
+	return writeFileAtomic(layoutFilePath(), []byte(string(layout)+"\n"))
+}
+
+// objectDir returns the directory that holds the objects of encodedKey.
+func objectDir(projectDir, encodedKey string, layout Layout) string {
+	if layout == LayoutSharded {
+		return filepath.Join(projectDir, shardName(encodedKey))
+	}
+	return projectDir
+}
+
+func shardName(encodedKey string) string {
+	sum := sha256.Sum256([]byte(encodedKey))
+	return hex.EncodeToString(sum[:1])
+}
+
+// isShardName reports whether a subdirectory of a scope's directory is a
+// shard of LayoutSharded.
+func isShardName(name string) bool {
+	if len(name) != 2 {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil && strings.ToLower(name) == name
+}
+
+// RelayoutPath returns where the object at path belongs in layout. ok is
+// false when path is not an object in the objects directory, such as one
+// recorded by a vault at another location.
+func RelayoutPath(path string, layout Layout) (string, bool) {
+	objectsDir := config.GetObjectsDir()
+	rel, err := filepath.Rel(objectsDir, path)
+	if err != nil {
+		return "", false
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	switch {
+	case parts[0] == "..":
+		return "", false
+	case len(parts) == 2:
+	case len(parts) == 3 && isShardName(parts[1]):
+	default:
+		return "", false
+	}
+
+	name := parts[len(parts)-1]
+	i := strings.LastIndex(name, "_v")
+	if i <= 0 || !strings.HasSuffix(name, ".txt") {
+		return "", false
+	}
+	dir := objectDir(filepath.Join(objectsDir, parts[0]), name[:i], layout)
+	return filepath.Join(dir, name), true
+}
+
+// CopyObject places a copy of the object at from at to, hard-linking it
+// where the filesystem allows. An identical object already at to is kept,
+// so an interrupted relayout can be run again.
+func CopyObject(from, to string) error {
+	if err := mkdirAllDurable(filepath.Dir(to)); err != nil {
+		return err
+	}
+	err := os.Link(from, to)
+	if err == nil {
+		return syncDir(filepath.Dir(to))
+	}
+	if errors.Is(err, fs.ErrExist) {
+		same, sameErr := sameContent(from, to)
+		if sameErr != nil {
+			return sameErr
+		}
+		if !same {
+			return fmt.Errorf("%s already exists with other content", to)
+		}
+		return nil
+	}
+
+	//nolint:gosec // G304: path is from database, controlled by application
+	data, readErr := os.ReadFile(from)
+	if readErr != nil {
+		return readErr
+	}
+	return writeFileExclusive(to, data)
+}
+
+func sameContent(a, b string) (bool, error) {
+	fa, err := os.Open(a) //nolint:gosec // G304: path is from database, controlled by application
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = fa.Close()
+	}()
+	fb, err := os.Open(b) //nolint:gosec // G304: path is from database, controlled by application
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = fb.Close()
+	}()
+
+	ha, hb := sha256.New(), sha256.New()
+	if _, err := io.Copy(ha, fa); err != nil {
+		return false, err
+	}
+	if _, err := io.Copy(hb, fb); err != nil {
+		return false, err
+	}
+	return string(ha.Sum(nil)) == string(hb.Sum(nil)), nil
+}
+
+// RemoveObject deletes the object at path, and its shard directory once that
+// is empty.
+func RemoveObject(path string) error {
+	if err := DeleteFile(path); err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if isShardName(filepath.Base(dir)) {
+		// Fails while other objects remain, which is fine.
+		_ = os.Remove(dir)
+	}
+	return nil
+}
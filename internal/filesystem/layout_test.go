@@ -0,0 +1,78 @@
+package filesystem
+
+import (
+	"io/fs"
+	"path/filepath"
+	"testing"
+)
+
+func TestShardedLayoutAndRelayout(t *testing.T) {
+	setupEnv(t)
+	project := "/Users/example/project"
+
+	flatPath, _, err := SaveFile(project, "notes", 1, "flat")
+	if err != nil {
+		t.Fatalf("SaveFile returned error: %v", err)
+	}
+	if filepath.Dir(flatPath) != GetProjectDir(project) {
+		t.Fatalf("expected flat object in project dir, got %s", flatPath)
+	}
+
+	if err := SetLayout(LayoutSharded); err != nil {
+		t.Fatalf("SetLayout returned error: %v", err)
+	}
+	layout, err := CurrentLayout()
+	if err != nil || layout != LayoutSharded {
+		t.Fatalf("CurrentLayout = %q, %v; want sharded", layout, err)
+	}
+
+	shardedPath, _, err := SaveFile(project, "notes", 2, "sharded")
+	if err != nil {
+		t.Fatalf("SaveFile returned error: %v", err)
+	}
+	shardDir := filepath.Dir(shardedPath)
+	if filepath.Dir(shardDir) != GetProjectDir(project) || !isShardName(filepath.Base(shardDir)) {
+		t.Fatalf("expected sharded object one level below project dir, got %s", shardedPath)
+	}
+
+	target, ok := RelayoutPath(flatPath, LayoutSharded)
+	if !ok || filepath.Dir(target) != shardDir {
+		t.Fatalf("RelayoutPath = %q, %v; want a path in %s", target, ok, shardDir)
+	}
+	if back, ok := RelayoutPath(target, LayoutFlat); !ok || back != flatPath {
+		t.Fatalf("RelayoutPath back = %q, %v; want %s", back, ok, flatPath)
+	}
+	if _, ok := RelayoutPath(filepath.Join(t.TempDir(), "notes_v1.txt"), LayoutSharded); ok {
+		t.Fatalf("expected paths outside the objects directory to be rejected")
+	}
+
+	if err := CopyObject(flatPath, target); err != nil {
+		t.Fatalf("CopyObject returned error: %v", err)
+	}
+	// Copying again, as a repeated relayout does, keeps the identical copy.
+	if err := CopyObject(flatPath, target); err != nil {
+		t.Fatalf("repeated CopyObject returned error: %v", err)
+	}
+	if err := CopyObject(shardedPath, target); err == nil {
+		t.Fatalf("expected CopyObject onto different content to fail")
+	}
+	if err := RemoveObject(flatPath); err != nil {
+		t.Fatalf("RemoveObject returned error: %v", err)
+	}
+
+	var walked int
+	if err := WalkProjectFiles(project, func(string, fs.DirEntry) error {
+		walked++
+		return nil
+	}); err != nil {
+		t.Fatalf("WalkProjectFiles returned error: %v", err)
+	}
+	if walked != 2 {
+		t.Fatalf("expected WalkProjectFiles to visit 2 sharded objects, got %d", walked)
+	}
+
+	count, err := DeleteKeyFiles(project, "notes")
+	if err != nil || count != 2 {
+		t.Fatalf("DeleteKeyFiles = %d, %v; want 2", count, err)
+	}
+}
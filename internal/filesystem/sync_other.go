@@ -0,0 +1,9 @@
+//go:build !unix
+
+package filesystem
+
+// syncDir does nothing on this platform, which cannot open directories for
+// syncing; NTFS journals the rename itself.
+func syncDir(string) error {
+	return nil
+}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// syncDir flushes the entries of dir, such as a file just renamed into it,
+// to stable storage.
+func syncDir(dir string) error {
+	f, err := os.Open(dir) //nolint:gosec // G304: directory inside the vault
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	// Some network filesystems cannot sync directories at all.
+	if errors.Is(err, unix.EINVAL) || errors.Is(err, unix.ENOTSUP) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// infoCacheTTL is how long a detected GitInfo is reused. The CLI asks for
+// it several times per command and the MCP server once per tool call, each
+// costing four git processes.
+const infoCacheTTL = 10 * time.Second
+
+// infoCache holds the GitInfo of each directory detected within the last
+// infoCacheTTL. A repository's entry is also dropped as soon as its HEAD
+// changes, so a checkout is seen by the very next command.
+var infoCache = &gitInfoCache{entries: make(map[string]cachedGitInfo)}
+
+type gitInfoCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedGitInfo
+}
+
+type cachedGitInfo struct {
+	info      GitInfo
+	expires   time.Time
+	headMtime time.Time
+}
+
+// get returns a copy of the cached GitInfo for dir, if it is still current.
+func (c *gitInfoCache) get(dir string) (*GitInfo, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[dir]
+	c.mu.Unlock()
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	if entry.info.IsGitRepo && !headMtime(entry.info.gitDir).Equal(entry.headMtime) {
+		return nil, false
+	}
+	info := entry.info
+	return &info, true
+}
+
+func (c *gitInfoCache) put(dir string, info *GitInfo) {
+	entry := cachedGitInfo{info: *info, expires: time.Now().Add(infoCacheTTL)}
+	if info.IsGitRepo {
+		entry.headMtime = headMtime(info.gitDir)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for key, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, key)
+		}
+	}
+	c.entries[dir] = entry
+}
+
+func (c *gitInfoCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// headMtime returns when HEAD in gitDir last changed, or the zero time if
+// it cannot be read.
+func headMtime(gitDir string) time.Time {
+	fi, err := os.Stat(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
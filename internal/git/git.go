@@ -2,12 +2,33 @@
 package git
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
+// ErrUnavailable is returned when git itself could not be run or did not
+// answer in time, so whether a directory is in a repository is unknown.
+var ErrUnavailable = errors.New("git is unavailable")
+
+// commandTimeout bounds each git invocation made to inspect a working tree,
+// so a hung credential helper or fsmonitor daemon cannot stall every
+// command.
+var commandTimeout = 5 * time.Second
+
+// errTimedOut is the cause of a git invocation stopped by commandTimeout.
+var errTimedOut = errors.New("timed out")
+
+// commandEnv is added to git's environment when inspecting a working tree:
+// no optional locks, so reads never contend with the user's own git
+// commands, and untranslated messages.
+var commandEnv = []string{"GIT_OPTIONAL_LOCKS=0", "LC_ALL=C"}
+
 // GitInfo contains information about a git repository
 //
 //nolint:revive // GitInfo is intentionally prefixed to avoid overly generic "Info" type
@@ -19,12 +40,18 @@ type GitInfo struct {
 	IsWorktree          bool
 	WorktreeID          string
 	WorktreePath        string
+
+	// gitDir is the absolute git directory, whose HEAD tells a cached
+	// GitInfo from a stale one.
+	gitDir string
 }
 
 // GetGitInfo retrieves git repository information for the given directory.
 // If dir is empty, it uses the current working directory.
-// Returns a GitInfo with IsGitRepo=false if the directory is not a git repository.
-func GetGitInfo(dir string) (*GitInfo, error) {
+// Returns a GitInfo with IsGitRepo=false if the directory is not a git repository,
+// ErrUnavailable if git could not be run, and ctx's error if ctx ends before
+// that could be determined. Results are cached per directory; see infoCache.
+func GetGitInfo(ctx context.Context, dir string) (*GitInfo, error) {
 	if dir == "" {
 		var err error
 		dir, err = os.Getwd()
@@ -33,10 +60,37 @@ func GetGitInfo(dir string) (*GitInfo, error) {
 			return &GitInfo{IsGitRepo: false}, nil
 		}
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	key := dir
+	if abs, err := filepath.Abs(dir); err == nil {
+		key = abs
+	}
+	if info, ok := infoCache.get(key); ok {
+		return info, nil
+	}
+	info, err := detectGitInfo(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	infoCache.put(key, info)
+	return info, nil
+}
 
+// detectGitInfo runs git to fill in a GitInfo for dir.
+func detectGitInfo(ctx context.Context, dir string) (*GitInfo, error) {
 	// Check if it's a git repository
-	gitRoot, err := runGitCommand(dir, "rev-parse", "--show-toplevel")
+	gitRoot, err := runGitCommand(ctx, dir, "rev-parse", "--show-toplevel")
 	if err != nil {
+		if ctx.Err() != nil {
+			// Git was stopped, so the directory may well be a repository.
+			return nil, ctx.Err()
+		}
+		if unavailable(err) {
+			return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
 		//nolint:nilerr // Intentionally return non-repo info instead of error
 		return &GitInfo{IsGitRepo: false}, nil
 	}
@@ -46,15 +100,27 @@ func GetGitInfo(dir string) (*GitInfo, error) {
 	}
 
 	// Get current branch
-	branch, err := runGitCommand(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	branch, err := runGitCommand(ctx, dir, "rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if unavailable(err) {
+			return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
 		//nolint:nilerr // Intentionally return non-repo info instead of error
 		return &GitInfo{IsGitRepo: false}, nil
 	}
 
 	// Get git directory
-	gitDir, err := runGitCommand(dir, "rev-parse", "--git-dir")
+	gitDir, err := runGitCommand(ctx, dir, "rev-parse", "--git-dir")
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if unavailable(err) {
+			return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
 		//nolint:nilerr // Intentionally return non-repo info instead of error
 		return &GitInfo{IsGitRepo: false}, nil
 	}
@@ -73,7 +139,7 @@ func GetGitInfo(dir string) (*GitInfo, error) {
 	primaryWorktreePath := gitRoot
 
 	// Try to get common directory for primary worktree path
-	commonDir, err := runGitCommand(dir, "rev-parse", "--git-common-dir")
+	commonDir, err := runGitCommand(ctx, dir, "rev-parse", "--git-common-dir")
 	if err == nil && commonDir != "" {
 		// Common dir is relative to the git dir, so resolve it
 		if !filepath.IsAbs(commonDir) {
@@ -83,6 +149,10 @@ func GetGitInfo(dir string) (*GitInfo, error) {
 		primaryWorktreePath = filepath.Dir(commonDir)
 	}
 
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
 	// Determine worktree ID
 	worktreeBasename := filepath.Base(absoluteGitDir)
 	worktreeID := worktreeBasename
@@ -98,18 +168,75 @@ func GetGitInfo(dir string) (*GitInfo, error) {
 		IsWorktree:          isWorktree,
 		WorktreeID:          worktreeID,
 		WorktreePath:        gitRoot,
+		gitDir:              absoluteGitDir,
 	}, nil
 }
 
-// runGitCommand executes a git command and returns the trimmed output
-func runGitCommand(dir string, args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
+// WorkingState describes the checked-out state of a working tree.
+type WorkingState struct {
+	Branch string
+	Commit string
+	Dirty  bool
+}
+
+// GetWorkingState reports the current branch, HEAD commit, and whether the
+// working tree has uncommitted changes. If dir is empty, it uses the current
+// working directory. Returns nil if the directory is not a git repository.
+func GetWorkingState(ctx context.Context, dir string) *WorkingState {
+	if dir == "" {
+		var err error
+		dir, err = os.Getwd()
+		if err != nil {
+			return nil
+		}
+	}
+
+	branch, err := runGitCommand(ctx, dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return nil
+	}
+
+	// HEAD does not resolve before the first commit; keep the commit empty.
+	commit, _ := runGitCommand(ctx, dir, "rev-parse", "HEAD")
+
+	status, err := runGitCommand(ctx, dir, "status", "--porcelain")
+	if err != nil {
+		return nil
+	}
+
+	return &WorkingState{
+		Branch: branch,
+		Commit: commit,
+		Dirty:  status != "",
+	}
+}
+
+// unavailable reports whether err means git did not run at all or did not
+// finish in time, as opposed to git exiting with an error.
+func unavailable(err error) bool {
+	var execErr *exec.Error
+	return errors.As(err, &execErr) || errors.Is(err, errTimedOut)
+}
+
+// runGitCommand executes a git command and returns the trimmed output. It
+// stops git after commandTimeout.
+func runGitCommand(ctx context.Context, dir string, args ...string) (string, error) {
+	callCtx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(callCtx, "git", args...)
 	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), commandEnv...)
 	// Suppress stderr to avoid noise when not in a git repository
 	cmd.Stderr = nil
+	// A helper git started may hold its stdout open after git is killed.
+	cmd.WaitDelay = time.Second
 
 	output, err := cmd.Output()
 	if err != nil {
+		if ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("git %s %w after %s", args[0], errTimedOut, commandTimeout)
+		}
 		return "", err
 	}
 
@@ -1,17 +1,21 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
 )
 
 func TestGetGitInfo_NotGitRepo(t *testing.T) {
 	// Create a temporary directory that's not a git repository
 	tmpDir := t.TempDir()
 
-	info, err := GetGitInfo(tmpDir)
+	info, err := GetGitInfo(context.Background(), tmpDir)
 	if err != nil {
 		t.Fatalf("GetGitInfo returned error: %v", err)
 	}
@@ -64,7 +68,7 @@ func TestGetGitInfo_GitRepo(t *testing.T) {
 		t.Skipf("Skipping test: git commit failed: %v", err)
 	}
 
-	info, err := GetGitInfo(tmpDir)
+	info, err := GetGitInfo(context.Background(), tmpDir)
 	if err != nil {
 		t.Fatalf("GetGitInfo returned error: %v", err)
 	}
@@ -94,7 +98,7 @@ func TestGetGitInfo_GitRepo(t *testing.T) {
 
 func TestGetGitInfo_EmptyDir(t *testing.T) {
 	// Test with empty string - should use current working directory
-	info, err := GetGitInfo("")
+	info, err := GetGitInfo(context.Background(), "")
 	if err != nil {
 		t.Fatalf("GetGitInfo returned error: %v", err)
 	}
@@ -159,7 +163,7 @@ func TestGetGitInfo_Worktree(t *testing.T) {
 	}
 
 	// Get git info from the worktree
-	info, err := GetGitInfo(worktreePath)
+	info, err := GetGitInfo(context.Background(), worktreePath)
 	if err != nil {
 		t.Fatalf("GetGitInfo returned error: %v", err)
 	}
@@ -180,3 +184,208 @@ func TestGetGitInfo_Worktree(t *testing.T) {
 		t.Errorf("Expected CurrentBranch to be 'test-branch', got %q", info.CurrentBranch)
 	}
 }
+
+func TestGetWorkingState(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if state := GetWorkingState(context.Background(), tmpDir); state != nil {
+		t.Fatalf("Expected nil state for non-git directory, got %#v", state)
+	}
+
+	for _, args := range [][]string{
+		{"init"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test User"},
+		{"commit", "--allow-empty", "-m", "Initial commit"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tmpDir
+		if err := cmd.Run(); err != nil {
+			t.Skipf("Skipping test: git %v failed: %v", args, err)
+		}
+	}
+
+	state := GetWorkingState(context.Background(), tmpDir)
+	if state == nil {
+		t.Fatal("Expected working state for git repository")
+	}
+	if state.Branch == "" || len(state.Commit) != 40 {
+		t.Errorf("Expected branch and full commit SHA, got %#v", state)
+	}
+	if state.Dirty {
+		t.Error("Expected clean working tree")
+	}
+
+	//nolint:gosec // G306: test file permissions are acceptable
+	if err := os.WriteFile(filepath.Join(tmpDir, "new.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if state := GetWorkingState(context.Background(), tmpDir); state == nil || !state.Dirty {
+		t.Errorf("Expected dirty working tree, got %#v", state)
+	}
+}
+
+func TestCommitSnapshot(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	for _, args := range [][]string{
+		{"init"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test User"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tmpDir
+		if err := cmd.Run(); err != nil {
+			t.Skipf("Skipping test: git %v failed: %v", args, err)
+		}
+	}
+
+	source := filepath.Join(t.TempDir(), "object.txt")
+	//nolint:gosec // G306: test file permissions are acceptable
+	if err := os.WriteFile(source, []byte("from disk\n"), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	files := []SnapshotFile{
+		{Path: "objects/a", Source: source},
+		{Path: "manifest.json", Data: []byte("{}\n")},
+	}
+
+	ref := "refs/heads/data"
+	commit, changed, err := CommitSnapshot(context.Background(), tmpDir, ref, "", files, "snapshot")
+	if err != nil {
+		t.Fatalf("CommitSnapshot failed: %v", err)
+	}
+	if !changed || ResolveRef(context.Background(), tmpDir, ref) != commit {
+		t.Fatalf("Expected %s to point at new commit %s", ref, commit)
+	}
+
+	for path, want := range map[string]string{"objects/a": "from disk\n", "manifest.json": "{}\n"} {
+		got, err := ReadBlob(context.Background(), tmpDir, commit, path)
+		if err != nil {
+			t.Fatalf("ReadBlob %s failed: %v", path, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: expected %q, got %q", path, want, got)
+		}
+	}
+
+	again, changed, err := CommitSnapshot(context.Background(), tmpDir, ref, commit, files, "snapshot")
+	if err != nil {
+		t.Fatalf("CommitSnapshot failed: %v", err)
+	}
+	if changed || again != commit {
+		t.Errorf("Expected unchanged snapshot to reuse %s, got %s (changed=%v)", commit, again, changed)
+	}
+
+	if status, err := runGit(context.Background(), tmpDir, nil, nil, "status", "--porcelain"); err != nil || status != "" {
+		t.Errorf("Expected working tree to stay untouched, got %q (err=%v)", status, err)
+	}
+}
+
+func TestCanceledContextStopsGit(t *testing.T) {
+	tmpDir := t.TempDir()
+	if out, err := exec.Command("git", "init", tmpDir).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := TopLevel(ctx, tmpDir); err == nil {
+		t.Fatal("expected TopLevel to fail with a canceled context")
+	}
+	if _, err := GetGitInfo(ctx, tmpDir); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected GetGitInfo to fail with context.Canceled, got %v", err)
+	}
+}
+
+func TestGetGitInfo_GitUnavailable(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	if _, err := GetGitInfo(context.Background(), t.TempDir()); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable without git on PATH, got %v", err)
+	}
+}
+
+// fakeGit puts a git on PATH that runs script instead.
+func fakeGit(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake git is a shell script")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "git"), []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil { //nolint:gosec // G306: the fake git must be executable
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestGetGitInfo_GitTimesOut(t *testing.T) {
+	fakeGit(t, "sleep 10")
+	defer func(timeout time.Duration) { commandTimeout = timeout }(commandTimeout)
+	commandTimeout = 100 * time.Millisecond
+
+	start := time.Now()
+	if _, err := GetGitInfo(context.Background(), t.TempDir()); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable from a hung git, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("GetGitInfo took %s despite the timeout", elapsed)
+	}
+}
+
+func TestRunGitCommandSanitizesEnvironment(t *testing.T) {
+	fakeGit(t, `echo "$GIT_OPTIONAL_LOCKS $LC_ALL"`)
+	t.Setenv("LC_ALL", "de_DE.UTF-8")
+
+	out, err := runGitCommand(context.Background(), t.TempDir(), "status")
+	if err != nil {
+		t.Fatalf("runGitCommand failed: %v", err)
+	}
+	if out != "0 C" {
+		t.Fatalf("expected GIT_OPTIONAL_LOCKS=0 and LC_ALL=C, got %q", out)
+	}
+}
+
+func TestGetGitInfo_Cached(t *testing.T) {
+	infoCache.reset()
+	tmpDir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-b", "main"},
+		{"-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "--allow-empty", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", tmpDir}, args...)...).CombinedOutput(); err != nil {
+			t.Skipf("git %s failed: %v: %s", args[0], err, out)
+		}
+	}
+	outside := t.TempDir()
+
+	if info, err := GetGitInfo(context.Background(), tmpDir); err != nil || info.CurrentBranch != "main" {
+		t.Fatalf("GetGitInfo = %+v, %v; want branch main", info, err)
+	}
+	if info, err := GetGitInfo(context.Background(), outside); err != nil || info.IsGitRepo {
+		t.Fatalf("GetGitInfo outside a repository = %+v, %v", info, err)
+	}
+
+	// Checking out another branch rewrites HEAD, which invalidates the
+	// cached result at once.
+	if out, err := exec.Command("git", "-C", tmpDir, "checkout", "-q", "-b", "feature").CombinedOutput(); err != nil {
+		t.Fatalf("git checkout failed: %v: %s", err, out)
+	}
+	if info, err := GetGitInfo(context.Background(), tmpDir); err != nil || info.CurrentBranch != "feature" {
+		t.Fatalf("GetGitInfo after checkout = %+v, %v; want branch feature", info, err)
+	}
+
+	// Cached results are served without running git.
+	fakeGit(t, "exit 1")
+	if info, err := GetGitInfo(context.Background(), tmpDir); err != nil || info.CurrentBranch != "feature" {
+		t.Fatalf("cached GetGitInfo = %+v, %v; want branch feature", info, err)
+	}
+	if info, err := GetGitInfo(context.Background(), outside); err != nil || info.IsGitRepo {
+		t.Fatalf("cached GetGitInfo outside a repository = %+v, %v", info, err)
+	}
+	infoCache.reset()
+	if info, err := GetGitInfo(context.Background(), tmpDir); err != nil || info.IsGitRepo {
+		t.Fatalf("GetGitInfo with a failing git and no cache = %+v, %v; want no repository", info, err)
+	}
+}
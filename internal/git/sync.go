@@ -0,0 +1,177 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// SnapshotFile is one file of a snapshot commit. Its content is either read
+// from Source, a path on disk, or taken from Data.
+type SnapshotFile struct {
+	Path   string
+	Source string
+	Data   []byte
+}
+
+// CommitSnapshot writes files as the complete tree of a new commit on top of
+// parent (empty for a root commit) and points ref at it. It works on the
+// object database only, leaving the index and working tree alone. If the
+// tree is identical to parent's, no commit is made and parent is returned
+// with changed set to false.
+func CommitSnapshot(ctx context.Context, repoDir, ref, parent string, files []SnapshotFile, message string) (commit string, changed bool, err error) {
+	var sources []string
+	for _, f := range files {
+		if f.Source != "" {
+			sources = append(sources, f.Source)
+		}
+	}
+	sourceBlobs, err := runGit(ctx, repoDir, strings.NewReader(strings.Join(sources, "\n")+"\n"), nil, "hash-object", "-w", "--stdin-paths")
+	if err != nil {
+		return "", false, err
+	}
+	blobs := strings.Fields(sourceBlobs)
+	if len(blobs) != len(sources) {
+		return "", false, fmt.Errorf("git hash-object returned %d objects for %d files", len(blobs), len(sources))
+	}
+
+	var index strings.Builder
+	for _, f := range files {
+		var blob string
+		if f.Source != "" {
+			blob, blobs = blobs[0], blobs[1:]
+		} else {
+			blob, err = runGit(ctx, repoDir, bytes.NewReader(f.Data), nil, "hash-object", "-w", "--stdin")
+			if err != nil {
+				return "", false, err
+			}
+		}
+		fmt.Fprintf(&index, "100644 blob %s\t%s\n", blob, f.Path)
+	}
+
+	indexDir, err := os.MkdirTemp("", "vault-git-index-")
+	if err != nil {
+		return "", false, err
+	}
+	defer func() {
+		_ = os.RemoveAll(indexDir)
+	}()
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(indexDir, "index")}
+
+	if _, err := runGit(ctx, repoDir, strings.NewReader(index.String()), env, "update-index", "--add", "--index-info"); err != nil {
+		return "", false, err
+	}
+	tree, err := runGit(ctx, repoDir, nil, env, "write-tree")
+	if err != nil {
+		return "", false, err
+	}
+
+	args := []string{"commit-tree", tree, "-m", message}
+	if parent != "" {
+		parentTree, err := runGit(ctx, repoDir, nil, nil, "rev-parse", parent+"^{tree}")
+		if err != nil {
+			return "", false, err
+		}
+		if parentTree == tree {
+			return parent, false, nil
+		}
+		args = append(args, "-p", parent)
+	}
+	commit, err = runGit(ctx, repoDir, nil, nil, args...)
+	if err != nil {
+		return "", false, err
+	}
+
+	if _, err := runGit(ctx, repoDir, nil, nil, "update-ref", ref, commit); err != nil {
+		return "", false, err
+	}
+	return commit, true, nil
+}
+
+// TopLevel returns the root of the working tree containing dir, which may
+// be empty for the current directory.
+func TopLevel(ctx context.Context, dir string) (string, error) {
+	if dir == "" {
+		dir = "."
+	}
+	return runGit(ctx, dir, nil, nil, "rev-parse", "--show-toplevel")
+}
+
+// ResolveRef returns the commit ref points to, or "" if it does not exist.
+func ResolveRef(ctx context.Context, repoDir, ref string) string {
+	commit, err := runGit(ctx, repoDir, nil, nil, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return ""
+	}
+	return commit
+}
+
+// FetchBranch fetches branch from remote and returns its commit, or "" if
+// the remote has no such branch.
+func FetchBranch(ctx context.Context, repoDir, remote, branch string) (string, error) {
+	heads, err := runGit(ctx, repoDir, nil, nil, "ls-remote", "--heads", remote, "refs/heads/"+branch)
+	if err != nil {
+		return "", err
+	}
+	if heads == "" {
+		return "", nil
+	}
+	if _, err := runGit(ctx, repoDir, nil, nil, "fetch", "--quiet", remote, "refs/heads/"+branch); err != nil {
+		return "", err
+	}
+	return runGit(ctx, repoDir, nil, nil, "rev-parse", "FETCH_HEAD")
+}
+
+// PushBranch pushes the local branch to the same name on remote.
+func PushBranch(ctx context.Context, repoDir, remote, branch string) error {
+	_, err := runGit(ctx, repoDir, nil, nil, "push", "--quiet", remote, "refs/heads/"+branch+":refs/heads/"+branch)
+	return err
+}
+
+// ReadBlob returns the content of path in the tree of rev.
+func ReadBlob(ctx context.Context, repoDir, rev, path string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", "cat-file", "blob", rev+":"+path)
+	cmd.Dir = repoDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, gitError([]string{"cat-file", "blob", rev + ":" + path}, &stderr, err)
+	}
+	return out, nil
+}
+
+// runGit runs a git command with optional stdin and extra environment and
+// returns its trimmed output. Unlike runGitCommand, failures carry git's
+// error message, since callers report them to the user.
+func runGit(ctx context.Context, dir string, stdin io.Reader, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", gitError(args, &stderr, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func gitError(args []string, stderr *bytes.Buffer, err error) error {
+	msg := strings.TrimSpace(stderr.String())
+	if msg == "" {
+		return fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return fmt.Errorf("git %s failed: %s", args[0], msg)
+}
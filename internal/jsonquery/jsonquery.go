@@ -0,0 +1,287 @@
+// Package jsonquery evaluates jq-style path expressions such as ".foo.bar"
+// or ".items[0].name" against JSON documents, so that small state blobs in
+// the vault can be read one field at a time.
+package jsonquery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Query is a parsed path expression.
+type Query struct {
+	expr  string
+	steps []step
+}
+
+// step is one path component: a field, an index, or an iteration.
+type step struct {
+	field    string
+	index    int
+	kind     stepKind
+	optional bool
+}
+
+type stepKind int
+
+const (
+	stepField stepKind = iota
+	stepIndex
+	stepIterate
+)
+
+// Parse parses expr. The supported subset of jq is the identity ".",
+// fields (".foo", `."foo bar"`, `.["foo"]`), array indexes (".[0]",
+// ".[-1]"), iteration (".[]"), and a trailing "?" on any of them to skip
+// values of the wrong type instead of failing.
+func Parse(expr string) (*Query, error) {
+	p := &parser{src: strings.TrimSpace(expr)}
+	if !strings.HasPrefix(p.src, ".") {
+		return nil, fmt.Errorf("invalid query %q: must start with '.'", expr)
+	}
+	q := &Query{expr: p.src}
+	for p.pos < len(p.src) {
+		s, err := p.step()
+		if err != nil {
+			return nil, fmt.Errorf("invalid query %q: %w", expr, err)
+		}
+		if s != nil {
+			q.steps = append(q.steps, *s)
+		}
+	}
+	return q, nil
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+// step parses the next component, or returns nil for a lone "." that
+// only introduces a bracket.
+func (p *parser) step() (*step, error) {
+	var s *step
+	switch {
+	case p.peek() == '[':
+		var err error
+		if s, err = p.bracket(); err != nil {
+			return nil, err
+		}
+	case p.peek() == '.':
+		p.pos++
+		switch c := p.peek(); {
+		case c == '[' || (c == 0 && p.pos == 1):
+			return nil, nil
+		case c == 0:
+			return nil, fmt.Errorf("unexpected end after '.'")
+		case c == '"':
+			name, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			s = &step{kind: stepField, field: name}
+		case isIdentStart(c):
+			start := p.pos
+			for p.pos < len(p.src) && isIdentPart(p.src[p.pos]) {
+				p.pos++
+			}
+			s = &step{kind: stepField, field: p.src[start:p.pos]}
+		default:
+			return nil, fmt.Errorf("unexpected %q at offset %d", c, p.pos)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.peek(), p.pos)
+	}
+	if p.peek() == '?' {
+		p.pos++
+		s.optional = true
+	}
+	return s, nil
+}
+
+func (p *parser) bracket() (*step, error) {
+	p.pos++ // [
+	var s *step
+	switch c := p.peek(); {
+	case c == ']':
+		s = &step{kind: stepIterate}
+	case c == '"':
+		name, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		s = &step{kind: stepField, field: name}
+	default:
+		start := p.pos
+		for p.pos < len(p.src) && p.src[p.pos] != ']' {
+			p.pos++
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(p.src[start:p.pos]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q", p.src[start:p.pos])
+		}
+		s = &step{kind: stepIndex, index: n}
+	}
+	if p.peek() != ']' {
+		return nil, fmt.Errorf("missing ']' at offset %d", p.pos)
+	}
+	p.pos++
+	return s, nil
+}
+
+// quoted reads a JSON string literal.
+func (p *parser) quoted() (string, error) {
+	start := p.pos
+	for p.pos++; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos++
+		case '"':
+			p.pos++
+			var name string
+			if err := json.Unmarshal([]byte(p.src[start:p.pos]), &name); err != nil {
+				return "", fmt.Errorf("invalid string %s", p.src[start:p.pos])
+			}
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("unterminated string at offset %d", start)
+}
+
+func (p *parser) peek() byte {
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// Run applies the query to a decoded JSON value and returns its results.
+// As in jq, a missing field or an index past the end yields null, and
+// iteration yields one result per element, in key order for objects.
+func (q *Query) Run(doc any) ([]any, error) {
+	values := []any{doc}
+	for _, s := range q.steps {
+		var next []any
+		for _, v := range values {
+			out, err := s.apply(v)
+			if err != nil {
+				if s.optional {
+					continue
+				}
+				return nil, err
+			}
+			next = append(next, out...)
+		}
+		values = next
+	}
+	return values, nil
+}
+
+func (s step) apply(v any) ([]any, error) {
+	switch s.kind {
+	case stepField:
+		switch v := v.(type) {
+		case nil:
+			return []any{nil}, nil
+		case map[string]any:
+			return []any{v[s.field]}, nil
+		default:
+			return nil, fmt.Errorf("cannot index %s with %q", typeName(v), s.field)
+		}
+	case stepIndex:
+		switch v := v.(type) {
+		case nil:
+			return []any{nil}, nil
+		case []any:
+			i := s.index
+			if i < 0 {
+				i += len(v)
+			}
+			if i < 0 || i >= len(v) {
+				return []any{nil}, nil
+			}
+			return []any{v[i]}, nil
+		default:
+			return nil, fmt.Errorf("cannot index %s with number", typeName(v))
+		}
+	default:
+		switch v := v.(type) {
+		case []any:
+			return v, nil
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			slices.Sort(keys)
+			out := make([]any, 0, len(keys))
+			for _, k := range keys {
+				out = append(out, v[k])
+			}
+			return out, nil
+		default:
+			return nil, fmt.Errorf("cannot iterate over %s", typeName(v))
+		}
+	}
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number, float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// Decode parses content as a single JSON value, keeping numbers exact.
+func Decode(content string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(content))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return doc, nil
+}
+
+// Format renders a result as jq does: indented JSON, or the bare text of a
+// string when raw is set.
+func Format(v any, raw bool) (string, error) {
+	if s, ok := v.(string); ok && raw {
+		return s, nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
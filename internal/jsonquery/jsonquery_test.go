@@ -0,0 +1,112 @@
+package jsonquery
+
+import (
+	"strings"
+	"testing"
+)
+
+const doc = `{
+  "name": "deploy",
+  "count": 12345678901234567890,
+  "tags": ["a", "b", "c"],
+  "owner": {"login": "alice", "team id": 7},
+  "steps": [{"name": "build"}, {"name": "test"}, {"id": 3}],
+  "none": null
+}`
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{".", nil},
+		{".name", []string{`"deploy"`}},
+		{".count", []string{"12345678901234567890"}},
+		{".owner.login", []string{`"alice"`}},
+		{`."owner"["team id"]`, []string{"7"}},
+		{`.owner."team id"`, []string{"7"}},
+		{".tags[0]", []string{`"a"`}},
+		{".tags[-1]", []string{`"c"`}},
+		{".tags[9]", []string{"null"}},
+		{".[\"tags\"][1]", []string{`"b"`}},
+		{".missing.deeper", []string{"null"}},
+		{".none.field", []string{"null"}},
+		{".steps[].name", []string{`"build"`, `"test"`, "null"}},
+		{".owner[]", []string{`"alice"`, "7"}},
+		{".tags[].x?", []string{}},
+	}
+
+	value, err := Decode(doc)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", tt.expr, err)
+		}
+		results, err := q.Run(value)
+		if err != nil {
+			t.Fatalf("Run(%q) error: %v", tt.expr, err)
+		}
+		if tt.want == nil {
+			if len(results) != 1 {
+				t.Fatalf("expected identity to yield the document, got %v", results)
+			}
+			continue
+		}
+		got := make([]string, 0, len(results))
+		for _, r := range results {
+			s, err := Format(r, false)
+			if err != nil {
+				t.Fatalf("Format error: %v", err)
+			}
+			got = append(got, s)
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Fatalf("%s: expected %v, got %v", tt.expr, tt.want, got)
+		}
+	}
+}
+
+func TestRunTypeErrors(t *testing.T) {
+	value, err := Decode(doc)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	for _, expr := range []string{".name.first", ".tags.first", ".owner[0]", ".name[]"} {
+		q, err := Parse(expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", expr, err)
+		}
+		if _, err := q.Run(value); err == nil {
+			t.Fatalf("expected %s to fail", expr)
+		}
+	}
+}
+
+func TestParseRejectsInvalidExpressions(t *testing.T) {
+	for _, expr := range []string{"", "foo", ".foo.", ".foo..bar", ".[", ".[x]", `.["open`, ".foo bar"} {
+		if _, err := Parse(expr); err == nil {
+			t.Fatalf("expected Parse(%q) to fail", expr)
+		}
+	}
+}
+
+func TestDecodeRejectsTrailingData(t *testing.T) {
+	if _, err := Decode(`{"a": 1} {"b": 2}`); err == nil {
+		t.Fatal("expected trailing data to be rejected")
+	}
+	if _, err := Decode(`{"a": 1}` + "\n"); err != nil {
+		t.Fatalf("expected a trailing newline to be accepted, got %v", err)
+	}
+}
+
+func TestFormatRaw(t *testing.T) {
+	if s, _ := Format("line\nbreak", true); s != "line\nbreak" {
+		t.Fatalf("expected raw string, got %q", s)
+	}
+	if s, _ := Format("<a>", false); s != `"<a>"` {
+		t.Fatalf("expected JSON string without HTML escaping, got %q", s)
+	}
+}
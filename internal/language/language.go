@@ -0,0 +1,210 @@
+// Package language guesses and names the language of entry content, so that
+// versions can carry a hint such as "markdown" or "go" for display and
+// filtering.
+package language
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Languages with special meaning. Any other valid name is stored as given.
+const (
+	Markdown = "markdown"
+	Text     = "text"
+	// JSON content is checked to be valid JSON when it is given as a hint.
+	JSON = "json"
+)
+
+// extensions maps file extensions to language names.
+var extensions = map[string]string{
+	".md":       Markdown,
+	".markdown": Markdown,
+	".txt":      Text,
+	".go":       "go",
+	".py":       "python",
+	".js":       "javascript",
+	".mjs":      "javascript",
+	".cjs":      "javascript",
+	".ts":       "typescript",
+	".tsx":      "typescript",
+	".rs":       "rust",
+	".java":     "java",
+	".c":        "c",
+	".h":        "c",
+	".cpp":      "cpp",
+	".cc":       "cpp",
+	".hpp":      "cpp",
+	".rb":       "ruby",
+	".sh":       "shell",
+	".bash":     "shell",
+	".zsh":      "shell",
+	".sql":      "sql",
+	".json":     "json",
+	".yaml":     "yaml",
+	".yml":      "yaml",
+	".toml":     "toml",
+	".html":     "html",
+	".htm":      "html",
+	".css":      "css",
+	".xml":      "xml",
+	".diff":     "diff",
+	".patch":    "diff",
+}
+
+// canonicalExtensions is the extension Extension returns for each language.
+var canonicalExtensions = map[string]string{
+	Markdown:     ".md",
+	Text:         ".txt",
+	"go":         ".go",
+	"python":     ".py",
+	"javascript": ".js",
+	"typescript": ".ts",
+	"rust":       ".rs",
+	"java":       ".java",
+	"c":          ".c",
+	"cpp":        ".cpp",
+	"ruby":       ".rb",
+	"shell":      ".sh",
+	"sql":        ".sql",
+	"json":       ".json",
+	"yaml":       ".yaml",
+	"toml":       ".toml",
+	"html":       ".html",
+	"css":        ".css",
+	"xml":        ".xml",
+	"diff":       ".diff",
+}
+
+// aliases are alternative spellings accepted by Normalize.
+var aliases = map[string]string{
+	"md":        Markdown,
+	"txt":       Text,
+	"plain":     Text,
+	"plaintext": Text,
+	"golang":    "go",
+	"py":        "python",
+	"js":        "javascript",
+	"ts":        "typescript",
+	"rs":        "rust",
+	"c++":       "cpp",
+	"rb":        "ruby",
+	"sh":        "shell",
+	"bash":      "shell",
+	"zsh":       "shell",
+	"yml":       "yaml",
+	"htm":       "html",
+	"patch":     "diff",
+}
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9+#._-]{0,31}$`)
+
+// Normalize lowercases name and resolves common aliases ("md", "py", "sh").
+// Names outside the built-in list are accepted if they look like a language
+// identifier.
+func Normalize(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if canonical, ok := aliases[name]; ok {
+		return canonical, nil
+	}
+	if !validName.MatchString(name) {
+		return "", fmt.Errorf("invalid language %q: use a name such as markdown, text, go, or python", name)
+	}
+	return name, nil
+}
+
+// Extension returns the file extension for lang, or "" if it has none.
+func Extension(lang string) string {
+	return canonicalExtensions[lang]
+}
+
+var (
+	markdownHeading = regexp.MustCompile(`(?m)^#{1,6} \S`)
+	markdownList    = regexp.MustCompile(`(?m)^\s*(?:[-*+]|\d+\.) (?:\[[ xX]\] )?\S`)
+	markdownLink    = regexp.MustCompile(`\[[^\]\n]+\]\([^)\s]+\)`)
+	markdownFence   = regexp.MustCompile("(?m)^(?:```|~~~)")
+	markdownEmph    = regexp.MustCompile(`(?:\*\*|__)\S[^\n]*?\S(?:\*\*|__)|` + "`[^`\n]+`")
+	goPackage       = regexp.MustCompile(`(?m)^package \w+\s*$`)
+	goFunc          = regexp.MustCompile(`(?m)^(?:func|import|type) `)
+	pythonDef       = regexp.MustCompile(`(?m)^(?:def \w+\(.*\):|class \w+.*:|from [\w.]+ import |import \w+)\s*$`)
+)
+
+// Detect guesses the language of content stored under key. A file extension
+// in the key wins; otherwise the content is inspected. Content that shows no
+// sign of markdown or code is reported as Text.
+func Detect(key, content string) string {
+	if lang, ok := extensions[strings.ToLower(path.Ext(key))]; ok {
+		return lang
+	}
+
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		return Text
+	}
+	if lang := detectShebang(trimmed); lang != "" {
+		return lang
+	}
+	if (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid([]byte(trimmed)) {
+		return JSON
+	}
+	lower := strings.ToLower(trimmed[:min(len(trimmed), 64)])
+	switch {
+	case strings.HasPrefix(trimmed, "diff --git ") || (strings.HasPrefix(trimmed, "--- ") && strings.Contains(trimmed, "\n+++ ")):
+		return "diff"
+	case strings.HasPrefix(lower, "<?xml"):
+		return "xml"
+	case strings.HasPrefix(lower, "<!doctype html") || strings.HasPrefix(lower, "<html"):
+		return "html"
+	}
+	if goPackage.MatchString(trimmed) && goFunc.MatchString(trimmed) {
+		return "go"
+	}
+	if isMarkdown(trimmed) {
+		return Markdown
+	}
+	if len(pythonDef.FindAllStringIndex(trimmed, 2)) == 2 {
+		return "python"
+	}
+	return Text
+}
+
+func detectShebang(content string) string {
+	if !strings.HasPrefix(content, "#!") {
+		return ""
+	}
+	line, _, _ := strings.Cut(content, "\n")
+	switch {
+	case strings.Contains(line, "python"):
+		return "python"
+	case strings.Contains(line, "node") || strings.Contains(line, "deno") || strings.Contains(line, "bun"):
+		return "javascript"
+	case strings.Contains(line, "ruby"):
+		return "ruby"
+	case strings.Contains(line, "sh"):
+		return "shell"
+	}
+	return ""
+}
+
+// isMarkdown reports whether content uses markdown syntax. A heading, fence,
+// or link is enough; lists and emphasis also occur in plain notes, so two
+// such signs are required.
+func isMarkdown(content string) bool {
+	if markdownHeading.MatchString(content) || markdownFence.MatchString(content) || markdownLink.MatchString(content) {
+		return true
+	}
+	signs := 0
+	if markdownList.MatchString(content) {
+		signs++
+	}
+	if markdownEmph.MatchString(content) {
+		signs++
+	}
+	if strings.HasPrefix(content, "> ") || strings.Contains(content, "\n> ") {
+		signs++
+	}
+	return signs >= 2
+}
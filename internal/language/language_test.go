@@ -0,0 +1,74 @@
+package language
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		content string
+		want    string
+	}{
+		{"extension wins", "notes/main.go", "# not markdown", "go"},
+		{"yaml extension", "ci.yml", "on: push", "yaml"},
+		{"heading", "notes", "# Title\n\nSome text.", Markdown},
+		{"fence", "notes", "Run this:\n```\nmake\n```", Markdown},
+		{"link", "notes", "See [the docs](https://example.com).", Markdown},
+		{"list and emphasis", "todo", "- buy **milk**\n- call Bob", Markdown},
+		{"list alone is text", "todo", "- buy milk\n- call Bob", Text},
+		{"prose", "notes", "Remember to rotate the keys on Friday.", Text},
+		{"empty", "notes", "  \n", Text},
+		{"json", "data", `{"a": [1, 2]}`, "json"},
+		{"invalid json", "data", `{not json}`, Text},
+		{"shebang", "script", "#!/usr/bin/env python3\nprint(1)", "python"},
+		{"shell shebang", "script", "#!/bin/sh\necho hi", "shell"},
+		{"go source", "snippet", "package main\n\nfunc main() {}\n", "go"},
+		{"python source", "snippet", "import os\n\ndef main():\n    pass\n", "python"},
+		{"diff", "change", "diff --git a/x b/x\n--- a/x\n+++ b/x\n", "diff"},
+		{"html", "page", "<!DOCTYPE html>\n<html></html>", "html"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.key, tt.content); got != tt.want {
+				t.Errorf("Detect(%q, %q) = %q, want %q", tt.key, tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"Markdown": Markdown,
+		"md":       Markdown,
+		" py ":     "python",
+		"sh":       "shell",
+		"c++":      "cpp",
+		"elixir":   "elixir",
+		"c#":       "c#",
+	}
+	for in, want := range tests {
+		got, err := Normalize(in)
+		if err != nil {
+			t.Errorf("Normalize(%q) error = %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	for _, in := range []string{"", "two words", "<script>"} {
+		if _, err := Normalize(in); err == nil {
+			t.Errorf("Normalize(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestExtension(t *testing.T) {
+	if got := Extension("python"); got != ".py" {
+		t.Errorf("Extension(python) = %q, want .py", got)
+	}
+	if got := Extension("elixir"); got != "" {
+		t.Errorf("Extension(elixir) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,196 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+// Error codes of ToolError.
+const (
+	CodeNotFound            = "not_found"
+	CodeIntegrityFailure    = "integrity_failure"
+	CodeVersionConflict     = "version_conflict"
+	CodeIdempotencyConflict = "idempotency_conflict"
+	CodeLocked              = "locked"
+	CodeQuotaExceeded       = "quota_exceeded"
+	CodeInvalidContent      = "invalid_content"
+	CodeDeviceRevoked       = "device_revoked"
+	CodeNoOpenSession       = "no_open_session"
+	CodeInvalidScope        = "invalid_scope"
+	CodeTimeout             = "timeout"
+	CodeCanceled            = "canceled"
+	CodeInternal            = "internal"
+)
+
+// errorMetaKey is the _meta field of a failed tool result that holds its
+// ToolError.
+const errorMetaKey = "vault.md/error"
+
+// ToolError is the machine-readable description of a failed tool call. It
+// is attached to the error result next to the plain message, so that agents
+// can tell a missing key from corrupted content and act on it.
+type ToolError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Key and Scope are the entry and scope the call was about, when known.
+	Key   string `json:"key,omitempty"`
+	Scope string `json:"scope,omitempty"`
+	// Suggestion tells the agent what to do next.
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// errorCodes maps sentinel errors to codes, checked in order.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{usecase.ErrIntegrity, CodeIntegrityFailure},
+	{services.ErrLocked, CodeLocked},
+	{services.ErrVersionConflict, CodeVersionConflict},
+	{services.ErrIdempotencyKeyUsed, CodeIdempotencyConflict},
+	{usecase.ErrQuotaExceeded, CodeQuotaExceeded},
+	{usecase.ErrInvalidContent, CodeInvalidContent},
+	{usecase.ErrDeviceRevoked, CodeDeviceRevoked},
+	{usecase.ErrNoOpenSession, CodeNoOpenSession},
+	{services.ErrNotFound, CodeNotFound},
+	{context.DeadlineExceeded, CodeTimeout},
+	{context.Canceled, CodeCanceled},
+}
+
+var errorSuggestions = map[string]string{
+	CodeNotFound:            "Check the key and scope with vault_list; keys are case-sensitive and the scope defaults to the current repository.",
+	CodeIntegrityFailure:    "The stored file no longer matches its hash, so retrying will not help; ask the user to run vault doctor or restore the entry from a snapshot.",
+	CodeVersionConflict:     "The entry changed since it was read; fetch the latest version with vault_get and apply the change to it.",
+	CodeIdempotencyConflict: "The idempotency key was already used for different content; use a new key for a new write.",
+	CodeLocked:              "Another agent holds a lock on the key; wait for it to expire or be released before writing.",
+	CodeQuotaExceeded:       "The scope is over its quota; archive or delete entries that are no longer needed, or ask the user to raise the quota.",
+	CodeInvalidContent:      "The content does not have the structure configured for this key; fix it as the message describes and write it again.",
+	CodeDeviceRevoked:       "This device was revoked; ask the user to register it again.",
+	CodeNoOpenSession:       "Start a session with vault_session action start first.",
+	CodeInvalidScope:        "Use scope global, repository, branch, or worktree, and pass workingDir or repo when the server does not run inside the repository.",
+	CodeTimeout:             "The call ran out of time; retry it with fewer keys or a narrower scope.",
+}
+
+// toolCallKey is the context key of the *toolCall of a tools/call request.
+type toolCallKey struct{}
+
+// toolCall collects what a tool handler learned before it failed, for
+// structuredErrors to describe.
+type toolCall struct {
+	err          error
+	ctxErr       error
+	scope        *scope.Scope
+	invalidScope bool
+}
+
+func toolCallFrom(ctx context.Context) *toolCall {
+	call, _ := ctx.Value(toolCallKey{}).(*toolCall)
+	return call
+}
+
+// recordScope notes the scope a tool call resolved, or that resolving it
+// failed. The last call wins, as handlers may fall back to another scope.
+func recordScope(ctx context.Context, sc scope.Scope, err error) {
+	call := toolCallFrom(ctx)
+	if call == nil {
+		return
+	}
+	call.invalidScope = err != nil
+	if err == nil {
+		call.scope = &sc
+	}
+}
+
+// addTool registers a typed tool handler whose errors are kept for
+// structuredErrors.
+func addTool[In, Out any](server *mcp.Server, tool *mcp.Tool, handler mcp.ToolHandlerFor[In, Out]) {
+	mcp.AddTool(server, tool, func(ctx context.Context, req *mcp.CallToolRequest, input In) (*mcp.CallToolResult, Out, error) {
+		result, output, err := handler(ctx, req, input)
+		if call := toolCallFrom(ctx); call != nil && err != nil {
+			call.err = err
+			call.ctxErr = ctx.Err()
+		}
+		return result, output, err
+	})
+}
+
+// structuredErrors attaches a ToolError to failed tool results, both as a
+// JSON text block after the message and under errorMetaKey in _meta.
+func structuredErrors(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if method != "tools/call" {
+			return next(ctx, method, req)
+		}
+		call := &toolCall{}
+		res, err := next(context.WithValue(ctx, toolCallKey{}, call), method, req)
+		result, ok := res.(*mcp.CallToolResult)
+		if err != nil || !ok || result == nil || !result.IsError || call.err == nil {
+			return res, err
+		}
+
+		toolErr := newToolError(call, requestKey(req))
+		data, jsonErr := json.Marshal(map[string]*ToolError{"error": toolErr})
+		if jsonErr != nil {
+			return res, err
+		}
+		result.Content = append(result.Content, &mcp.TextContent{Text: string(data)})
+		if result.Meta == nil {
+			result.Meta = mcp.Meta{}
+		}
+		result.Meta[errorMetaKey] = toolErr
+		return result, nil
+	}
+}
+
+// newToolError describes the failure recorded in call.
+func newToolError(call *toolCall, key string) *ToolError {
+	toolErr := &ToolError{
+		Code:    errorCode(call),
+		Message: call.err.Error(),
+		Key:     key,
+	}
+	if call.scope != nil {
+		toolErr.Scope = scope.FormatScope(*call.scope)
+	}
+	toolErr.Suggestion = errorSuggestions[toolErr.Code]
+	return toolErr
+}
+
+func errorCode(call *toolCall) string {
+	if call.invalidScope {
+		return CodeInvalidScope
+	}
+	for _, c := range errorCodes {
+		if errors.Is(call.err, c.err) {
+			return c.code
+		}
+	}
+	// Failures caused by the deadline do not always wrap the context error.
+	switch {
+	case errors.Is(call.ctxErr, context.DeadlineExceeded):
+		return CodeTimeout
+	case errors.Is(call.ctxErr, context.Canceled):
+		return CodeCanceled
+	}
+	return CodeInternal
+}
+
+// requestKey returns the key argument of a tool call, if it has one.
+func requestKey(req mcp.Request) string {
+	callReq, ok := req.(*mcp.CallToolRequest)
+	if !ok || callReq.Params == nil {
+		return ""
+	}
+	var args struct {
+		Key string `json:"key"`
+	}
+	_ = json.Unmarshal(callReq.Params.Arguments, &args)
+	return args.Key
+}
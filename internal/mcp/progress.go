@@ -0,0 +1,44 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+// progressReporter returns a usecase.ProgressFunc that sends MCP progress
+// notifications for req, or nil when the client did not ask for progress by
+// sending a progress token. Notifications are sent at most once per percent
+// so that large vaults do not flood the client; failures to send are
+// ignored, since progress is advisory.
+func progressReporter(ctx context.Context, req *mcp.CallToolRequest, verb, unit string) usecase.ProgressFunc {
+	if req == nil || req.Session == nil || req.Params == nil {
+		return nil
+	}
+	token := req.Params.GetProgressToken()
+
+	if token == nil {
+		return nil
+	}
+
+	lastPercent := -1
+	return func(done, total int) {
+		percent := 100
+		if total > 0 {
+			percent = done * 100 / total
+		}
+		if percent == lastPercent {
+			return
+		}
+		lastPercent = percent
+		_ = req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+			ProgressToken: token,
+			Progress:      float64(done),
+			Total:         float64(total),
+			Message:       fmt.Sprintf("%s %d of %d %s (%d%%)", verb, done, total, unit, percent),
+		})
+	}
+}
@@ -6,24 +6,53 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/contextpack"
 	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/jsonquery"
+	"github.com/choplin/vault.md/internal/language"
 	"github.com/choplin/vault.md/internal/scope"
 	"github.com/choplin/vault.md/internal/services"
+	"github.com/choplin/vault.md/internal/summarizer"
+	"github.com/choplin/vault.md/internal/textpatch"
 	"github.com/choplin/vault.md/internal/usecase"
 )
 
 // Server wraps the MCP server with vault-specific functionality
 type Server struct {
-	server *mcp.Server
-	dbCtx  *database.Context
+	server     *mcp.Server
+	dbCtx      *database.Context
+	settings   *config.Settings
+	summarizer *summarizer.Command
+
+	// entry returns the use case the tool handlers run on; tests replace it
+	// to use the in-memory repositories.
+	entry func() *usecase.Entry
+}
+
+// ServerOptions configures a Server.
+type ServerOptions struct {
+	// ToolTimeout, when positive, bounds how long a single tool call may
+	// run before its context is canceled.
+	ToolTimeout time.Duration
 }
 
 // NewServer creates a new MCP server instance
-func NewServer() (*Server, error) {
+func NewServer(opts ServerOptions) (*Server, error) {
+	settings, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	summarize, err := summarizer.FromSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
 	dbCtx, err := database.CreateDatabase("")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create database: %w", err)
@@ -35,16 +64,41 @@ func NewServer() (*Server, error) {
 	}, nil)
 
 	s := &Server{
-		server: mcpServer,
-		dbCtx:  dbCtx,
+		server:     mcpServer,
+		dbCtx:      dbCtx,
+		settings:   settings,
+		summarizer: summarize,
+		entry: func() *usecase.Entry {
+			return usecase.NewEntry(dbCtx)
+		},
 	}
 
 	// Register tools
 	s.registerTools()
+	if opts.ToolTimeout > 0 {
+		mcpServer.AddReceivingMiddleware(toolTimeout(opts.ToolTimeout))
+	}
+	mcpServer.AddReceivingMiddleware(structuredErrors)
 
 	return s, nil
 }
 
+// toolTimeout cancels the context of each tool call after timeout. Tool
+// calls already stop when the client cancels them or disconnects; this
+// also bounds calls the client leaves waiting.
+func toolTimeout(timeout time.Duration) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if method != "tools/call" {
+				return next(ctx, method, req)
+			}
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return next(ctx, method, req)
+		}
+	}
+}
+
 // Run starts the MCP server with stdio transport
 func (s *Server) Run(ctx context.Context) error {
 	defer func() {
@@ -57,43 +111,119 @@ func (s *Server) Run(ctx context.Context) error {
 
 func (s *Server) registerTools() {
 	// vault_set
-	mcp.AddTool(s.server, &mcp.Tool{
+	addTool(s.server, &mcp.Tool{
 		Name:        "vault_set",
 		Description: "Store content in the vault with a key",
 	}, s.handleSet)
 
+	// vault_patch
+	addTool(s.server, &mcp.Tool{
+		Name:        "vault_patch",
+		Description: "Apply a unified diff or section edits to the latest version of an entry and store the result",
+	}, s.handlePatch)
+
 	// vault_get
-	mcp.AddTool(s.server, &mcp.Tool{
+	addTool(s.server, &mcp.Tool{
 		Name:        "vault_get",
 		Description: "Retrieve content from the vault by key",
 	}, s.handleGet)
 
 	// vault_list
-	mcp.AddTool(s.server, &mcp.Tool{
+	addTool(s.server, &mcp.Tool{
 		Name:        "vault_list",
 		Description: "List all entries in the vault",
 	}, s.handleList)
 
 	// vault_delete
-	mcp.AddTool(s.server, &mcp.Tool{
+	addTool(s.server, &mcp.Tool{
 		Name:        "vault_delete",
 		Description: "Delete an entry from the vault",
 	}, s.handleDelete)
 
 	// vault_info
-	mcp.AddTool(s.server, &mcp.Tool{
+	addTool(s.server, &mcp.Tool{
 		Name:        "vault_info",
 		Description: "Get metadata about a vault entry",
 	}, s.handleInfo)
+
+	// vault_summary
+	addTool(s.server, &mcp.Tool{
+		Name:        "vault_summary",
+		Description: "Get the stored summary of a vault entry without reading its content; use it to decide whether an entry is worth retrieving",
+	}, s.handleSummary)
+
+	// vault_pack
+	addTool(s.server, &mcp.Tool{
+		Name:        "vault_pack",
+		Description: "Assemble the latest versions of several entries (keys or globs) into one markdown document within a token budget",
+	}, s.handlePack)
+
+	// vault_session
+	addTool(s.server, &mcp.Tool{
+		Name:        "vault_session",
+		Description: "Journal work in progress in a dated session log (session/<date>) kept per branch: start a session, append notes to it, or end it",
+	}, s.handleSession)
+
+	// vault_scopes
+	addTool(s.server, &mcp.Tool{
+		Name:        "vault_scopes",
+		Description: "List the scopes in the vault with their descriptions, metadata, and number of keys, to find where relevant context is stored",
+	}, s.handleScopes)
+
+	// vault_lock
+	addTool(s.server, &mcp.Tool{
+		Name:        "vault_lock",
+		Description: "Claim a key for a while so other agents cannot write it, or release the claim; writes to a key locked by someone else fail until it is released or expires",
+	}, s.handleLock)
+
+	// vault_context
+	addTool(s.server, &mcp.Tool{
+		Name:        "vault_context",
+		Description: "Read the latest plan, conventions, decisions, or other configured keys from every scope that applies to the working directory (worktree, branch, repository, global) in one call; use it to pick up context at the start of a task",
+	}, s.handleContext)
 }
 
 // Input/Output types for each tool
 
 // SetInput is the input for the vault_set tool.
 type SetInput struct {
+	Key            string  `json:"key" jsonschema_description:"The key for the vault entry"`
+	Content        string  `json:"content" jsonschema_description:"The content to store"`
+	Description    *string `json:"description,omitempty" jsonschema_description:"Optional description for the entry"`
+	Scope          *string `json:"scope,omitempty" jsonschema_description:"Scope type (global, repository, branch, or worktree)"`
+	Repo           *string `json:"repo,omitempty" jsonschema_description:"Repository path"`
+	Branch         *string `json:"branch,omitempty" jsonschema_description:"Branch name (for branch scope)"`
+	Worktree       *string `json:"worktree,omitempty" jsonschema_description:"Worktree ID (for worktree scope)"`
+	WorkingDir     *string `json:"workingDir,omitempty" jsonschema_description:"Working directory for git detection"`
+	IdempotencyKey *string `json:"idempotencyKey,omitempty" jsonschema_description:"Optional token identifying this write; repeating a call with the same token and content returns the version created the first time"`
+	Section        *string `json:"section,omitempty" jsonschema_description:"Markdown heading (e.g. '## Decisions'); replace only the content under it in the latest version"`
+	Language       *string `json:"language,omitempty" jsonschema_description:"Content language such as markdown, text, go, or python (detected from the key and content if omitted); json also rejects content that does not parse"`
+}
+
+// SetOutput is the output for the vault_set tool.
+type SetOutput struct {
+	Message         string   `json:"message"`
+	Key             string   `json:"key" jsonschema_description:"The key the content was stored under, after trimming and Unicode normalization"`
+	Path            string   `json:"path"`
+	Version         int64    `json:"version" jsonschema_description:"The version number the content was stored as"`
+	PreviousVersion int64    `json:"previousVersion" jsonschema_description:"The latest version before this write, 0 for a new key"`
+	ConcurrentWrite bool     `json:"concurrentWrite,omitempty" jsonschema_description:"True if another writer took the first version chosen"`
+	Replayed        bool     `json:"replayed,omitempty" jsonschema_description:"True if the idempotency key was already used and no new version was created"`
+	Summary         string   `json:"summary,omitempty" jsonschema_description:"The summary generated for the new version, if a summarizer is configured"`
+	SummaryError    string   `json:"summaryError,omitempty" jsonschema_description:"Why the configured summarizer could not summarize the new version; the content was stored regardless"`
+	Pruned          int      `json:"pruned,omitempty" jsonschema_description:"How many old versions in the scope were deleted to stay within the quota"`
+	PruneError      string   `json:"pruneError,omitempty" jsonschema_description:"Why deleting old versions to stay within the quota failed; the content was stored regardless"`
+	Templated       []string `json:"templated,omitempty" jsonschema_description:"Keys created from the configured key templates because this was the first write to the scope"`
+	TemplateError   string   `json:"templateError,omitempty" jsonschema_description:"Why creating the key templates failed; the content was stored regardless"`
+	Warnings        []string `json:"warnings,omitempty" jsonschema_description:"Configured validators the content failed; it was stored regardless, but should be fixed"`
+}
+
+// PatchInput is the input for the vault_patch tool.
+type PatchInput struct {
 	Key         string  `json:"key" jsonschema_description:"The key for the vault entry"`
-	Content     string  `json:"content" jsonschema_description:"The content to store"`
-	Description *string `json:"description,omitempty" jsonschema_description:"Optional description for the entry"`
+	Patch       string  `json:"patch" jsonschema_description:"A unified diff, or a JSON list of {start, end, content} section edits replacing the lines between marker lines"`
+	Type        *string `json:"type,omitempty" jsonschema_description:"Patch type: auto, unified, or sections (default auto)"`
+	Description *string `json:"description,omitempty" jsonschema_description:"Optional description for the new version"`
 	Scope       *string `json:"scope,omitempty" jsonschema_description:"Scope type (global, repository, branch, or worktree)"`
 	Repo        *string `json:"repo,omitempty" jsonschema_description:"Repository path"`
 	Branch      *string `json:"branch,omitempty" jsonschema_description:"Branch name (for branch scope)"`
@@ -101,32 +231,50 @@ type SetInput struct {
 	WorkingDir  *string `json:"workingDir,omitempty" jsonschema_description:"Working directory for git detection"`
 }
 
-// SetOutput is the output for the vault_set tool.
-type SetOutput struct {
-	Message string `json:"message"`
-	Path    string `json:"path"`
+// PatchOutput is the output for the vault_patch tool.
+type PatchOutput struct {
+	Message         string `json:"message"`
+	Path            string `json:"path"`
+	Version         int64  `json:"version" jsonschema_description:"The version number the patched content was stored as"`
+	PreviousVersion int64  `json:"previousVersion" jsonschema_description:"The version the patch was applied to"`
 }
 
 // GetInput is the input for the vault_get tool.
 type GetInput struct {
-	Key        string  `json:"key" jsonschema_description:"The key for the vault entry"`
-	Version    *int    `json:"version,omitempty" jsonschema_description:"Specific version to retrieve (latest if not specified)"`
-	Scope      *string `json:"scope,omitempty" jsonschema_description:"Scope type (global, repository, branch, or worktree)"`
-	Repo       *string `json:"repo,omitempty" jsonschema_description:"Repository path"`
-	Branch     *string `json:"branch,omitempty" jsonschema_description:"Branch name (for branch scope)"`
-	Worktree   *string `json:"worktree,omitempty" jsonschema_description:"Worktree ID (for worktree scope)"`
-	WorkingDir *string `json:"workingDir,omitempty" jsonschema_description:"Working directory for git detection"`
+	Key          string  `json:"key" jsonschema_description:"The key for the vault entry"`
+	Version      *int    `json:"version,omitempty" jsonschema_description:"Specific version to retrieve (latest if not specified)"`
+	Scope        *string `json:"scope,omitempty" jsonschema_description:"Scope type (global, repository, branch, or worktree)"`
+	Repo         *string `json:"repo,omitempty" jsonschema_description:"Repository path"`
+	Branch       *string `json:"branch,omitempty" jsonschema_description:"Branch name (for branch scope)"`
+	Worktree     *string `json:"worktree,omitempty" jsonschema_description:"Worktree ID (for worktree scope)"`
+	WorkingDir   *string `json:"workingDir,omitempty" jsonschema_description:"Working directory for git detection"`
+	Section      *string `json:"section,omitempty" jsonschema_description:"Markdown heading (e.g. '## Decisions'); return only the content under it"`
+	Approved     *bool   `json:"approved,omitempty" jsonschema_description:"Return the newest approved version instead of the latest draft (ignored when version is set)"`
+	Snapshot     *string `json:"snapshot,omitempty" jsonschema_description:"Return the version pinned by this named scope snapshot (ignored when version is set)"`
+	AsOf         *string `json:"asOf,omitempty" jsonschema_description:"Return the version that was latest at this RFC3339 timestamp (ignored when version or snapshot is set)"`
+	Query        *string `json:"query,omitempty" jsonschema_description:"jq-style path such as '.tasks[0].status'; return only the JSON values it selects from JSON content, one per line"`
+	WithChildren *bool   `json:"withChildren,omitempty" jsonschema_description:"Append the entry's children (declared with vault parent) in order, each followed by its own"`
+
+	IncludeMetadata *bool `json:"includeMetadata,omitempty" jsonschema_description:"Also return the metadata (version, hash, etc.) of the returned content"`
 }
 
 // GetOutput is the output for the vault_get tool.
 type GetOutput struct {
-	Content string `json:"content"`
+	Content  string      `json:"content"`
+	Metadata *InfoOutput `json:"metadata,omitempty"`
 }
 
 // ListInput is the input for the vault_list tool.
 type ListInput struct {
 	AllVersions     *bool   `json:"allVersions,omitempty" jsonschema_description:"Include all versions, not just latest"`
 	IncludeArchived *bool   `json:"includeArchived,omitempty" jsonschema_description:"Include archived entries"`
+	Since           *string `json:"since,omitempty" jsonschema_description:"Only versions created at or after this RFC3339 timestamp"`
+	Until           *string `json:"until,omitempty" jsonschema_description:"Only versions created at or before this RFC3339 timestamp"`
+	AsOf            *string `json:"asOf,omitempty" jsonschema_description:"List the version of each key that was latest at this RFC3339 timestamp instead of the latest one"`
+	DescContains    *string `json:"descriptionContains,omitempty" jsonschema_description:"Only versions whose description contains this text (case-insensitive)"`
+	Language        *string `json:"language,omitempty" jsonschema_description:"Only versions with this content language (e.g. markdown, text, go)"`
+	Sort            *string `json:"sort,omitempty" jsonschema_description:"Sort by key, created, updated, version, or size (default key)"`
+	Reverse         *bool   `json:"reverse,omitempty" jsonschema_description:"Reverse the sort order"`
 	Scope           *string `json:"scope,omitempty" jsonschema_description:"Scope type (global, repository, branch, or worktree)"`
 	Repo            *string `json:"repo,omitempty" jsonschema_description:"Repository path"`
 	Branch          *string `json:"branch,omitempty" jsonschema_description:"Branch name (for branch scope)"`
@@ -145,6 +293,8 @@ type ListEntry struct {
 	Version     int64   `json:"version"`
 	Scope       string  `json:"scope"`
 	Description *string `json:"description,omitempty"`
+	Language    string  `json:"language,omitempty"`
+	Summary     string  `json:"summary,omitempty"`
 	CreatedAt   string  `json:"createdAt"`
 	IsArchived  bool    `json:"isArchived,omitempty"`
 }
@@ -187,12 +337,151 @@ type InfoOutput struct {
 	FilePath    string  `json:"filePath"`
 	Hash        string  `json:"hash"`
 	Description *string `json:"description,omitempty"`
+	Language    string  `json:"language,omitempty"`
+	Summary     string  `json:"summary,omitempty"`
 	CreatedAt   string  `json:"createdAt"`
 	IsArchived  bool    `json:"isArchived"`
+
+	VersionCount   int64   `json:"versionCount"`
+	TotalSize      int64   `json:"totalSize"`
+	FirstWrittenAt *string `json:"firstWrittenAt,omitempty"`
+	LastWrittenAt  *string `json:"lastWrittenAt,omitempty"`
+}
+
+// SummaryInput is the input for the vault_summary tool.
+type SummaryInput struct {
+	Key        string  `json:"key" jsonschema_description:"The key for the vault entry"`
+	Version    *int    `json:"version,omitempty" jsonschema_description:"Specific version (latest if not specified)"`
+	Generate   *bool   `json:"generate,omitempty" jsonschema_description:"Run the configured summarizer now if the version has no summary yet"`
+	Scope      *string `json:"scope,omitempty" jsonschema_description:"Scope type (global, repository, branch, or worktree)"`
+	Repo       *string `json:"repo,omitempty" jsonschema_description:"Repository path"`
+	Branch     *string `json:"branch,omitempty" jsonschema_description:"Branch name (for branch scope)"`
+	Worktree   *string `json:"worktree,omitempty" jsonschema_description:"Worktree ID (for worktree scope)"`
+	WorkingDir *string `json:"workingDir,omitempty" jsonschema_description:"Working directory for git detection"`
+}
+
+// SummaryOutput is the output for the vault_summary tool.
+type SummaryOutput struct {
+	Key         string  `json:"key"`
+	Version     int64   `json:"version"`
+	Summary     string  `json:"summary,omitempty" jsonschema_description:"The stored summary; empty if the version has none"`
+	Description *string `json:"description,omitempty"`
+	Language    string  `json:"language,omitempty"`
+	Size        int64   `json:"size" jsonschema_description:"Content size in bytes"`
+}
+
+// PackInput is the input for the vault_pack tool.
+type PackInput struct {
+	Keys         []string `json:"keys,omitempty" jsonschema_description:"Keys or globs such as 'adr/*', packed in this order (all entries in the scope if omitted)"`
+	Budget       *int     `json:"budget,omitempty" jsonschema_description:"Approximate token limit for the whole document (no limit if omitted)"`
+	Strategy     *string  `json:"strategy,omitempty" jsonschema_description:"What to do with entries over the budget: truncate (default), proportional, summary, or drop"`
+	Language     *string  `json:"language,omitempty" jsonschema_description:"Only entries with this content language (e.g. markdown, go)"`
+	DescContains *string  `json:"descriptionContains,omitempty" jsonschema_description:"Only entries whose description contains this text (case-insensitive)"`
+	Scope        *string  `json:"scope,omitempty" jsonschema_description:"Scope type (global, repository, branch, or worktree)"`
+	Repo         *string  `json:"repo,omitempty" jsonschema_description:"Repository path"`
+	Branch       *string  `json:"branch,omitempty" jsonschema_description:"Branch name (for branch scope)"`
+	Worktree     *string  `json:"worktree,omitempty" jsonschema_description:"Worktree ID (for worktree scope)"`
+	WorkingDir   *string  `json:"workingDir,omitempty" jsonschema_description:"Working directory for git detection"`
+}
+
+// PackOutput is the output for the vault_pack tool.
+type PackOutput struct {
+	Content string      `json:"content"`
+	Tokens  int         `json:"tokens" jsonschema_description:"Estimated size of the document in tokens"`
+	Entries []PackEntry `json:"entries"`
+}
+
+// PackEntry reports how one entry was included in the pack.
+type PackEntry struct {
+	Key     string `json:"key"`
+	Version int64  `json:"version"`
+	Mode    string `json:"mode" jsonschema_description:"full, truncated, summarized, or omitted"`
+	Tokens  int    `json:"tokens"`
+}
+
+// SessionInput is the input for the vault_session tool.
+type SessionInput struct {
+	Action     string  `json:"action" jsonschema_description:"start, append, or end"`
+	Text       *string `json:"text,omitempty" jsonschema_description:"The note to append (required for append), or a note for start or a summary for end"`
+	Scope      *string `json:"scope,omitempty" jsonschema_description:"Scope type (default: the current branch, or global outside a git repository)"`
+	Repo       *string `json:"repo,omitempty" jsonschema_description:"Repository path"`
+	Branch     *string `json:"branch,omitempty" jsonschema_description:"Branch name (for branch scope)"`
+	Worktree   *string `json:"worktree,omitempty" jsonschema_description:"Worktree ID (for worktree scope)"`
+	WorkingDir *string `json:"workingDir,omitempty" jsonschema_description:"Working directory for git detection"`
+}
+
+// SessionOutput is the output for the vault_session tool.
+type SessionOutput struct {
+	Message string `json:"message"`
+	Key     string `json:"key" jsonschema_description:"The session log key"`
+	Version int64  `json:"version"`
+}
+
+// ScopesInput is the input for the vault_scopes tool.
+type ScopesInput struct {
+	Repo *string `json:"repo,omitempty" jsonschema_description:"Only scopes of this repository path"`
+}
+
+// ScopesOutput is the output for the vault_scopes tool.
+type ScopesOutput struct {
+	Scopes []ScopeEntry `json:"scopes"`
+}
+
+// LockInput is the input for the vault_lock tool.
+type LockInput struct {
+	Key        string  `json:"key" jsonschema_description:"The key to lock or unlock"`
+	TTL        *string `json:"ttl,omitempty" jsonschema_description:"How long to hold the lock, such as 10m (default 10m); locking again renews it"`
+	Release    *bool   `json:"release,omitempty" jsonschema_description:"Release the lock instead of taking it"`
+	Scope      *string `json:"scope,omitempty" jsonschema_description:"Scope type (global, repository, branch, or worktree)"`
+	Repo       *string `json:"repo,omitempty" jsonschema_description:"Repository path"`
+	Branch     *string `json:"branch,omitempty" jsonschema_description:"Branch name (for branch scope)"`
+	Worktree   *string `json:"worktree,omitempty" jsonschema_description:"Worktree ID (for worktree scope)"`
+	WorkingDir *string `json:"workingDir,omitempty" jsonschema_description:"Working directory for git detection"`
+}
+
+// LockOutput is the output for the vault_lock tool.
+type LockOutput struct {
+	Message   string     `json:"message"`
+	Owner     string     `json:"owner,omitempty" jsonschema_description:"Who holds the lock"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" jsonschema_description:"When the lock expires unless renewed"`
+}
+
+// ContextInput is the input for the vault_context tool.
+type ContextInput struct {
+	Keys       []string `json:"keys,omitempty" jsonschema_description:"Keys to read (default the contextKeys setting, or plan, conventions, and decisions)"`
+	WorkingDir *string  `json:"workingDir,omitempty" jsonschema_description:"Working directory for git detection"`
+}
+
+// ContextOutput is the output for the vault_context tool.
+type ContextOutput struct {
+	Scopes  []string             `json:"scopes" jsonschema_description:"The scopes searched, most specific first"`
+	Entries []ContextEntryOutput `json:"entries" jsonschema_description:"Each key found, in every scope it was found in, most specific scope first"`
+	Missing []string             `json:"missing,omitempty" jsonschema_description:"Keys found in no scope"`
+}
+
+// ContextEntryOutput is one entry in the vault_context output.
+type ContextEntryOutput struct {
+	Key       string `json:"key"`
+	Scope     string `json:"scope"`
+	Version   int64  `json:"version"`
+	CreatedAt string `json:"createdAt"`
+	Content   string `json:"content"`
+}
+
+// ScopeEntry describes one scope in the vault_scopes output.
+type ScopeEntry struct {
+	Scope       string            `json:"scope" jsonschema_description:"The scope as shown in vault_list output"`
+	Type        string            `json:"type"`
+	Repo        string            `json:"repo,omitempty"`
+	Branch      string            `json:"branch,omitempty"`
+	Worktree    string            `json:"worktree,omitempty"`
+	Description string            `json:"description,omitempty" jsonschema_description:"What the scope is for, as set with vault scope describe"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Keys        int               `json:"keys" jsonschema_description:"Number of keys that are not archived"`
 }
 
 // Helper function to resolve scope from input parameters
-func resolveScopeFromInput(scopeType, repo, branch, worktree, workingDir *string) (scope.Scope, error) {
+func resolveScopeFromInput(ctx context.Context, scopeType, repo, branch, worktree, workingDir *string) (scope.Scope, error) {
 	opts := scope.ScopeOptions{}
 	if scopeType != nil {
 		opts.Type = *scopeType
@@ -210,75 +499,272 @@ func resolveScopeFromInput(scopeType, repo, branch, worktree, workingDir *string
 		opts.WorkingDir = *workingDir
 	}
 
-	return scope.ResolveScope(opts)
+	sc, err := scope.ResolveScope(ctx, opts)
+	recordScope(ctx, sc, err)
+	return sc, err
 }
 
 // Tool handlers
 
-func (s *Server) handleSet(ctx context.Context, _ *mcp.CallToolRequest, input SetInput) (*mcp.CallToolResult, SetOutput, error) {
-	sc, err := resolveScopeFromInput(input.Scope, input.Repo, input.Branch, input.Worktree, input.WorkingDir)
+func (s *Server) handleSet(ctx context.Context, req *mcp.CallToolRequest, input SetInput) (*mcp.CallToolResult, SetOutput, error) {
+	sc, err := resolveScopeFromInput(ctx, input.Scope, input.Repo, input.Branch, input.Worktree, input.WorkingDir)
 	if err != nil {
 		return nil, SetOutput{}, fmt.Errorf("failed to resolve scope: %w", err)
 	}
 
-	uc := usecase.NewEntry(s.dbCtx)
-	var opts *usecase.SetOptions
-	if input.Description != nil {
-		opts = &usecase.SetOptions{
-			Description: input.Description,
-		}
+	var workingDir string
+	if input.WorkingDir != nil {
+		workingDir = *input.WorkingDir
+	}
+
+	uc := s.entry()
+	opts := &usecase.SetOptions{
+		Description: input.Description,
+		Provenance:  usecase.CaptureProvenance(ctx, usecase.ToolMCP, clientName(req), workingDir, s.settings.ShouldCaptureEnvironment()),
+		Summarizer:  s.summarizer,
+	}
+	if input.IdempotencyKey != nil {
+		opts.IdempotencyKey = *input.IdempotencyKey
+	}
+	if input.Language != nil {
+		opts.Language = *input.Language
 	}
 
-	path, err := uc.Set(ctx, sc, input.Key, input.Content, opts)
+	var result *usecase.SetResult
+	if input.Section != nil && *input.Section != "" {
+		result, err = uc.Patch(ctx, sc, input.Key, usecase.SectionPatch(*input.Section, input.Content), opts)
+	} else {
+		result, err = uc.Set(ctx, sc, input.Key, input.Content, opts)
+	}
 	if err != nil {
 		return nil, SetOutput{}, fmt.Errorf("failed to set entry: %w", err)
 	}
 
-	return nil, SetOutput{
-		Message: "Stored content successfully",
-		Path:    path,
+	message := fmt.Sprintf("Stored content successfully as version %d", result.Version)
+	if result.Replayed {
+		message = fmt.Sprintf("Already stored as version %d", result.Version)
+	}
+
+	output := SetOutput{
+		Message:         message,
+		Key:             result.Key,
+		Path:            result.Path,
+		Version:         result.Version,
+		PreviousVersion: result.PreviousVersion,
+		ConcurrentWrite: result.ConcurrentWrite,
+		Replayed:        result.Replayed,
+		Summary:         result.Summary,
+		Pruned:          result.Pruned,
+		Templated:       result.Templated,
+		Warnings:        result.Warnings,
+	}
+	if result.SummaryErr != nil {
+		output.SummaryError = result.SummaryErr.Error()
+	}
+	if result.PruneErr != nil {
+		output.PruneError = result.PruneErr.Error()
+	}
+	if result.TemplateErr != nil {
+		output.TemplateError = result.TemplateErr.Error()
+	}
+	return nil, output, nil
+}
+
+func (s *Server) handlePatch(ctx context.Context, req *mcp.CallToolRequest, input PatchInput) (*mcp.CallToolResult, PatchOutput, error) {
+	sc, err := resolveScopeFromInput(ctx, input.Scope, input.Repo, input.Branch, input.Worktree, input.WorkingDir)
+	if err != nil {
+		return nil, PatchOutput{}, fmt.Errorf("failed to resolve scope: %w", err)
+	}
+
+	patchType := "auto"
+	if input.Type != nil {
+		patchType = *input.Type
+	}
+	patch, err := usecase.NewPatchFunc(input.Patch, patchType)
+	if err != nil {
+		return nil, PatchOutput{}, err
+	}
+
+	var workingDir string
+	if input.WorkingDir != nil {
+		workingDir = *input.WorkingDir
+	}
+
+	uc := s.entry()
+	opts := &usecase.SetOptions{
+		Description: input.Description,
+		Provenance:  usecase.CaptureProvenance(ctx, usecase.ToolMCP, clientName(req), workingDir, s.settings.ShouldCaptureEnvironment()),
+		Summarizer:  s.summarizer,
+	}
+
+	result, err := uc.Patch(ctx, sc, input.Key, patch, opts)
+	if err != nil {
+		return nil, PatchOutput{}, fmt.Errorf("failed to patch entry: %w", err)
+	}
+
+	return nil, PatchOutput{
+		Message:         fmt.Sprintf("Patched version %d and stored it as version %d", result.PreviousVersion, result.Version),
+		Path:            result.Path,
+		Version:         result.Version,
+		PreviousVersion: result.PreviousVersion,
 	}, nil
 }
 
+// clientName returns the name the MCP client reported when it connected,
+// used to attribute writes to the agent that made them.
+func clientName(req *mcp.CallToolRequest) string {
+	if req == nil || req.Session == nil {
+		return ""
+	}
+	params := req.Session.InitializeParams()
+	if params == nil || params.ClientInfo == nil {
+		return ""
+	}
+	return params.ClientInfo.Name
+}
+
 func (s *Server) handleGet(ctx context.Context, _ *mcp.CallToolRequest, input GetInput) (*mcp.CallToolResult, GetOutput, error) {
-	sc, err := resolveScopeFromInput(input.Scope, input.Repo, input.Branch, input.Worktree, input.WorkingDir)
+	sc, err := resolveScopeFromInput(ctx, input.Scope, input.Repo, input.Branch, input.Worktree, input.WorkingDir)
 	if err != nil {
 		return nil, GetOutput{}, fmt.Errorf("failed to resolve scope: %w", err)
 	}
 
-	uc := usecase.NewEntry(s.dbCtx)
-	var opts *usecase.GetOptions
-	if input.Version != nil {
-		opts = &usecase.GetOptions{
-			Version: input.Version,
+	uc := s.entry()
+	opts := &usecase.GetOptions{
+		Version:    input.Version,
+		SkipVerify: !s.settings.ShouldVerifyOnRead(),
+		Approved:   input.Approved != nil && *input.Approved,
+		TrackRead:  s.settings.ShouldTrackReads(),
+	}
+	if input.Snapshot != nil {
+		opts.Snapshot = *input.Snapshot
+	}
+	if input.AsOf != nil {
+		asOf, err := time.Parse(time.RFC3339, *input.AsOf)
+		if err != nil {
+			return nil, GetOutput{}, fmt.Errorf("invalid asOf timestamp: %w", err)
 		}
+		opts.AsOf = asOf
+	}
+
+	if input.WithChildren != nil && *input.WithChildren {
+		if (input.Section != nil && *input.Section != "") || (input.Query != nil && *input.Query != "") {
+			return nil, GetOutput{}, fmt.Errorf("withChildren cannot be combined with section or query")
+		}
+		doc, err := uc.Assemble(ctx, sc, input.Key, opts)
+		if err != nil {
+			if errors.Is(err, services.ErrNotFound) {
+				return nil, GetOutput{}, fmt.Errorf("%w: %s", services.ErrNotFound, input.Key)
+			}
+			return nil, GetOutput{}, fmt.Errorf("failed to get entry: %w", err)
+		}
+		output := GetOutput{Content: doc.Content()}
+		if input.IncludeMetadata != nil && *input.IncludeMetadata {
+			result, err := uc.Describe(ctx, &doc.Root)
+			if err != nil {
+				return nil, GetOutput{}, fmt.Errorf("failed to get entry: %w", err)
+			}
+			metadata := newInfoOutput(result)
+			output.Metadata = &metadata
+		}
+		return nil, output, nil
+	}
+
+	if input.IncludeMetadata != nil && *input.IncludeMetadata {
+		result, err := uc.Info(ctx, sc, input.Key, opts)
+		if err != nil {
+			if errors.Is(err, services.ErrNotFound) {
+				return nil, GetOutput{}, fmt.Errorf("%w: %s", services.ErrNotFound, input.Key)
+			}
+			return nil, GetOutput{}, fmt.Errorf("failed to get entry: %w", err)
+		}
+
+		content, err := readSection(result.Record.FilePath, input.Section)
+		if err != nil {
+			return nil, GetOutput{}, err
+		}
+		if content, err = queryContent(input.Key, content, input.Query); err != nil {
+			return nil, GetOutput{}, err
+		}
+
+		metadata := newInfoOutput(result)
+		return nil, GetOutput{
+			Content:  content,
+			Metadata: &metadata,
+		}, nil
 	}
 
 	result, err := uc.Get(ctx, sc, input.Key, opts)
 	if err != nil {
 		if errors.Is(err, services.ErrNotFound) {
-			return nil, GetOutput{}, fmt.Errorf("entry not found: %s", input.Key)
+			return nil, GetOutput{}, fmt.Errorf("%w: %s", services.ErrNotFound, input.Key)
 		}
 		return nil, GetOutput{}, fmt.Errorf("failed to get entry: %w", err)
 	}
 
-	content, err := os.ReadFile(result.Record.FilePath)
+	content, err := readSection(result.Record.FilePath, input.Section)
 	if err != nil {
-		return nil, GetOutput{}, fmt.Errorf("failed to read file: %w", err)
+		return nil, GetOutput{}, err
+	}
+	if content, err = queryContent(input.Key, content, input.Query); err != nil {
+		return nil, GetOutput{}, err
 	}
 
 	return nil, GetOutput{
-		Content: string(content),
+		Content: content,
 	}, nil
 }
 
+// readSection reads a stored version, narrowed to one markdown section when
+// section is set.
+func readSection(path string, section *string) (string, error) {
+	//nolint:gosec // G304: path is from database, controlled by application
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	if section == nil || *section == "" {
+		return string(content), nil
+	}
+	return textpatch.ExtractSection(string(content), *section)
+}
+
+// queryContent narrows JSON content to the values query selects, one per
+// line, when query is set.
+func queryContent(key, content string, query *string) (string, error) {
+	if query == nil || *query == "" {
+		return content, nil
+	}
+	q, err := jsonquery.Parse(*query)
+	if err != nil {
+		return "", err
+	}
+	doc, err := jsonquery.Decode(content)
+	if err != nil {
+		return "", fmt.Errorf("%s is not JSON: %w", key, err)
+	}
+	values, err := q.Run(doc)
+	if err != nil {
+		return "", err
+	}
+	lines := make([]string, 0, len(values))
+	for _, v := range values {
+		line, err := jsonquery.Format(v, false)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
 func (s *Server) handleList(ctx context.Context, _ *mcp.CallToolRequest, input ListInput) (*mcp.CallToolResult, ListOutput, error) {
-	sc, err := resolveScopeFromInput(input.Scope, input.Repo, input.Branch, input.Worktree, input.WorkingDir)
+	sc, err := resolveScopeFromInput(ctx, input.Scope, input.Repo, input.Branch, input.Worktree, input.WorkingDir)
 	if err != nil {
 		return nil, ListOutput{}, fmt.Errorf("failed to resolve scope: %w", err)
 	}
 
-	uc := usecase.NewEntry(s.dbCtx)
+	uc := s.entry()
 	opts := &usecase.ListOptions{}
 	if input.AllVersions != nil {
 		opts.AllVersions = *input.AllVersions
@@ -286,6 +772,50 @@ func (s *Server) handleList(ctx context.Context, _ *mcp.CallToolRequest, input L
 	if input.IncludeArchived != nil {
 		opts.IncludeArchived = *input.IncludeArchived
 	}
+	if input.Since != nil {
+		since, err := time.Parse(time.RFC3339, *input.Since)
+		if err != nil {
+			return nil, ListOutput{}, fmt.Errorf("invalid since timestamp: %w", err)
+		}
+		opts.Since = since
+	}
+	if input.Until != nil {
+		until, err := time.Parse(time.RFC3339, *input.Until)
+		if err != nil {
+			return nil, ListOutput{}, fmt.Errorf("invalid until timestamp: %w", err)
+		}
+		opts.Until = until
+	}
+	if input.AsOf != nil {
+		asOf, err := time.Parse(time.RFC3339, *input.AsOf)
+		if err != nil {
+			return nil, ListOutput{}, fmt.Errorf("invalid asOf timestamp: %w", err)
+		}
+		opts.AsOf = asOf
+	}
+	if input.DescContains != nil {
+		opts.DescriptionContains = *input.DescContains
+	}
+	if input.Language != nil {
+		lang, err := language.Normalize(*input.Language)
+		if err != nil {
+			return nil, ListOutput{}, err
+		}
+		opts.Language = lang
+	}
+	if input.Sort != nil {
+		sortField, err := services.ParseSortField(*input.Sort)
+		if err != nil {
+			return nil, ListOutput{}, err
+		}
+		opts.SortBy = sortField
+	}
+	if input.Reverse != nil {
+		opts.Reverse = *input.Reverse
+		if opts.SortBy == "" {
+			opts.SortBy = services.SortByKey
+		}
+	}
 
 	result, err := uc.List(ctx, sc, opts)
 	if err != nil {
@@ -299,6 +829,8 @@ func (s *Server) handleList(ctx context.Context, _ *mcp.CallToolRequest, input L
 			Version:     e.Record.Version,
 			Scope:       scope.FormatScope(e.Scope),
 			Description: e.Record.Description,
+			Language:    e.Record.Language,
+			Summary:     e.Record.Summary,
 			CreatedAt:   e.Record.CreatedAt.Format(time.RFC3339),
 			IsArchived:  e.Record.IsArchived,
 		})
@@ -310,12 +842,12 @@ func (s *Server) handleList(ctx context.Context, _ *mcp.CallToolRequest, input L
 }
 
 func (s *Server) handleDelete(ctx context.Context, _ *mcp.CallToolRequest, input DeleteInput) (*mcp.CallToolResult, DeleteOutput, error) {
-	sc, err := resolveScopeFromInput(input.Scope, input.Repo, input.Branch, input.Worktree, input.WorkingDir)
+	sc, err := resolveScopeFromInput(ctx, input.Scope, input.Repo, input.Branch, input.Worktree, input.WorkingDir)
 	if err != nil {
 		return nil, DeleteOutput{}, fmt.Errorf("failed to resolve scope: %w", err)
 	}
 
-	uc := usecase.NewEntry(s.dbCtx)
+	uc := s.entry()
 
 	if input.Version != nil {
 		// Delete specific version
@@ -324,7 +856,7 @@ func (s *Server) handleDelete(ctx context.Context, _ *mcp.CallToolRequest, input
 			return nil, DeleteOutput{}, fmt.Errorf("failed to delete version: %w", err)
 		}
 		if !deleted {
-			return nil, DeleteOutput{}, fmt.Errorf("version %d of key '%s' not found", *input.Version, input.Key)
+			return nil, DeleteOutput{}, fmt.Errorf("%w: version %d of key '%s'", services.ErrNotFound, *input.Version, input.Key)
 		}
 		return nil, DeleteOutput{
 			Message: fmt.Sprintf("Deleted version %d of key '%s'", *input.Version, input.Key),
@@ -338,7 +870,7 @@ func (s *Server) handleDelete(ctx context.Context, _ *mcp.CallToolRequest, input
 		return nil, DeleteOutput{}, fmt.Errorf("failed to delete key: %w", err)
 	}
 	if count == 0 {
-		return nil, DeleteOutput{}, fmt.Errorf("key '%s' not found", input.Key)
+		return nil, DeleteOutput{}, fmt.Errorf("%w: key '%s'", services.ErrNotFound, input.Key)
 	}
 
 	return nil, DeleteOutput{
@@ -348,12 +880,12 @@ func (s *Server) handleDelete(ctx context.Context, _ *mcp.CallToolRequest, input
 }
 
 func (s *Server) handleInfo(ctx context.Context, _ *mcp.CallToolRequest, input InfoInput) (*mcp.CallToolResult, InfoOutput, error) {
-	sc, err := resolveScopeFromInput(input.Scope, input.Repo, input.Branch, input.Worktree, input.WorkingDir)
+	sc, err := resolveScopeFromInput(ctx, input.Scope, input.Repo, input.Branch, input.Worktree, input.WorkingDir)
 	if err != nil {
 		return nil, InfoOutput{}, fmt.Errorf("failed to resolve scope: %w", err)
 	}
 
-	uc := usecase.NewEntry(s.dbCtx)
+	uc := s.entry()
 	var opts *usecase.GetOptions
 	if input.Version != nil {
 		opts = &usecase.GetOptions{
@@ -361,15 +893,286 @@ func (s *Server) handleInfo(ctx context.Context, _ *mcp.CallToolRequest, input I
 		}
 	}
 
-	result, err := uc.Get(ctx, sc, input.Key, opts)
+	result, err := uc.Info(ctx, sc, input.Key, opts)
 	if err != nil {
 		if errors.Is(err, services.ErrNotFound) {
-			return nil, InfoOutput{}, fmt.Errorf("entry not found: %s", input.Key)
+			return nil, InfoOutput{}, fmt.Errorf("%w: %s", services.ErrNotFound, input.Key)
 		}
 		return nil, InfoOutput{}, fmt.Errorf("failed to get entry info: %w", err)
 	}
 
-	return nil, InfoOutput{
+	return nil, newInfoOutput(result), nil
+}
+
+func (s *Server) handleSummary(ctx context.Context, _ *mcp.CallToolRequest, input SummaryInput) (*mcp.CallToolResult, SummaryOutput, error) {
+	sc, err := resolveScopeFromInput(ctx, input.Scope, input.Repo, input.Branch, input.Worktree, input.WorkingDir)
+	if err != nil {
+		return nil, SummaryOutput{}, fmt.Errorf("failed to resolve scope: %w", err)
+	}
+
+	uc := s.entry()
+	// The content is not read, so there is nothing to verify.
+	opts := &usecase.GetOptions{Version: input.Version, SkipVerify: true}
+	result, err := uc.Get(ctx, sc, input.Key, opts)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			return nil, SummaryOutput{}, fmt.Errorf("%w: %s", services.ErrNotFound, input.Key)
+		}
+		return nil, SummaryOutput{}, fmt.Errorf("failed to get entry: %w", err)
+	}
+	record := result.Record
+
+	if record.Summary == "" && input.Generate != nil && *input.Generate {
+		if s.summarizer == nil {
+			return nil, SummaryOutput{}, fmt.Errorf("no summarizer is configured")
+		}
+		version := int(record.Version)
+		generated, err := uc.Summarize(ctx, sc, input.Key, &usecase.GetOptions{Version: &version}, s.summarizer)
+		if err != nil {
+			return nil, SummaryOutput{}, fmt.Errorf("failed to summarize entry: %w", err)
+		}
+		record.Summary = generated.Summary
+	}
+
+	return nil, SummaryOutput{
+		Key:         record.Key,
+		Version:     record.Version,
+		Summary:     record.Summary,
+		Description: record.Description,
+		Language:    record.Language,
+		Size:        record.Size,
+	}, nil
+}
+
+func (s *Server) handlePack(ctx context.Context, req *mcp.CallToolRequest, input PackInput) (*mcp.CallToolResult, PackOutput, error) {
+	sc, err := resolveScopeFromInput(ctx, input.Scope, input.Repo, input.Branch, input.Worktree, input.WorkingDir)
+	if err != nil {
+		return nil, PackOutput{}, fmt.Errorf("failed to resolve scope: %w", err)
+	}
+
+	opts := usecase.PackOptions{
+		Patterns:   input.Keys,
+		SkipVerify: !s.settings.ShouldVerifyOnRead(),
+		Progress:   progressReporter(ctx, req, "packed", "entries"),
+	}
+	if input.Budget != nil {
+		if *input.Budget < 0 {
+			return nil, PackOutput{}, fmt.Errorf("budget must not be negative")
+		}
+		opts.Budget = *input.Budget
+	}
+	if input.Strategy != nil {
+		if opts.Strategy, err = contextpack.ParseStrategy(*input.Strategy); err != nil {
+			return nil, PackOutput{}, err
+		}
+	}
+	if input.Language != nil {
+		if opts.Language, err = language.Normalize(*input.Language); err != nil {
+			return nil, PackOutput{}, err
+		}
+	}
+	if input.DescContains != nil {
+		opts.DescriptionContains = *input.DescContains
+	}
+
+	result, err := s.entry().Pack(ctx, sc, opts)
+	if err != nil {
+		return nil, PackOutput{}, fmt.Errorf("failed to pack entries: %w", err)
+	}
+
+	entries := make([]PackEntry, 0, len(result.Items))
+	for _, item := range result.Items {
+		entries = append(entries, PackEntry{
+			Key:     item.Key,
+			Version: item.Version,
+			Mode:    string(item.Mode),
+			Tokens:  item.Tokens,
+		})
+	}
+	return nil, PackOutput{
+		Content: result.Text,
+		Tokens:  result.Tokens,
+		Entries: entries,
+	}, nil
+}
+
+func (s *Server) handleSession(ctx context.Context, req *mcp.CallToolRequest, input SessionInput) (*mcp.CallToolResult, SessionOutput, error) {
+	sc, err := resolveSessionScope(ctx, input)
+	if err != nil {
+		return nil, SessionOutput{}, fmt.Errorf("failed to resolve scope: %w", err)
+	}
+
+	var text, workingDir string
+	if input.Text != nil {
+		text = *input.Text
+	}
+	if input.WorkingDir != nil {
+		workingDir = *input.WorkingDir
+	}
+
+	uc := s.entry()
+	opts := &usecase.SetOptions{
+		Provenance: usecase.CaptureProvenance(ctx, usecase.ToolMCP, clientName(req), workingDir, s.settings.ShouldCaptureEnvironment()),
+		Summarizer: s.summarizer,
+	}
+	now := time.Now()
+
+	var (
+		result *usecase.SessionResult
+		verb   string
+	)
+	switch input.Action {
+	case "start":
+		if key, err := uc.OpenSession(ctx, sc); err == nil {
+			return nil, SessionOutput{}, fmt.Errorf("session %s is still open; end it first", key)
+		}
+		result, err = uc.StartSession(ctx, sc, now, text, opts)
+		verb = "Started session"
+		if result != nil && result.Resumed {
+			verb = "Resumed session"
+		}
+	case "append":
+		result, err = uc.AppendSession(ctx, sc, now, text, opts)
+		verb = "Added to"
+	case "end":
+		result, err = uc.EndSession(ctx, sc, now, text, opts)
+		verb = "Ended session"
+	default:
+		return nil, SessionOutput{}, fmt.Errorf("invalid action: %s (valid values: start, append, end)", input.Action)
+	}
+	if errors.Is(err, usecase.ErrNoOpenSession) {
+		return nil, SessionOutput{}, fmt.Errorf("no open session in %s; start one first", scope.FormatScope(sc))
+	}
+	if err != nil {
+		return nil, SessionOutput{}, err
+	}
+
+	return nil, SessionOutput{
+		Message: fmt.Sprintf("%s %s", verb, result.Key),
+		Key:     result.Key,
+		Version: result.Version,
+	}, nil
+}
+
+func (s *Server) handleScopes(ctx context.Context, req *mcp.CallToolRequest, input ScopesInput) (*mcp.CallToolResult, ScopesOutput, error) {
+	scopes, err := s.entry().Scopes(ctx, progressReporter(ctx, req, "counted keys in", "scopes"))
+	if err != nil {
+		return nil, ScopesOutput{}, fmt.Errorf("failed to list scopes: %w", err)
+	}
+
+	entries := make([]ScopeEntry, 0, len(scopes))
+	for _, info := range scopes {
+		sc := info.Record.Scope
+		if input.Repo != nil && sc.PrimaryPath != *input.Repo {
+			continue
+		}
+		entries = append(entries, ScopeEntry{
+			Scope:       scope.FormatScope(sc),
+			Type:        string(sc.Type),
+			Repo:        sc.PrimaryPath,
+			Branch:      sc.BranchName,
+			Worktree:    sc.WorktreeID,
+			Description: info.Record.Description,
+			Metadata:    info.Record.Metadata,
+			Keys:        info.Keys,
+		})
+	}
+	return nil, ScopesOutput{Scopes: entries}, nil
+}
+
+func (s *Server) handleLock(ctx context.Context, req *mcp.CallToolRequest, input LockInput) (*mcp.CallToolResult, LockOutput, error) {
+	sc, err := resolveScopeFromInput(ctx, input.Scope, input.Repo, input.Branch, input.Worktree, input.WorkingDir)
+	if err != nil {
+		return nil, LockOutput{}, fmt.Errorf("failed to resolve scope: %w", err)
+	}
+	owner := usecase.ResolveActor(clientName(req))
+	uc := s.entry()
+
+	if input.Release != nil && *input.Release {
+		released, err := uc.Unlock(ctx, sc, input.Key, owner, false)
+		if err != nil {
+			return nil, LockOutput{}, fmt.Errorf("failed to unlock entry: %w", err)
+		}
+		message := fmt.Sprintf("Unlocked %s", input.Key)
+		if !released {
+			message = fmt.Sprintf("%s is not locked", input.Key)
+		}
+		return nil, LockOutput{Message: message}, nil
+	}
+
+	ttl := 10 * time.Minute
+	if input.TTL != nil {
+		if ttl, err = time.ParseDuration(*input.TTL); err != nil {
+			return nil, LockOutput{}, fmt.Errorf("invalid ttl: %w", err)
+		}
+	}
+	lock, err := uc.Lock(ctx, sc, input.Key, owner, ttl, false)
+	if err != nil {
+		return nil, LockOutput{}, fmt.Errorf("failed to lock entry: %w", err)
+	}
+	return nil, LockOutput{
+		Message:   fmt.Sprintf("Locked %s until %s", input.Key, lock.ExpiresAt.Format(time.RFC3339)),
+		Owner:     lock.Owner,
+		ExpiresAt: &lock.ExpiresAt,
+	}, nil
+}
+
+func (s *Server) handleContext(ctx context.Context, _ *mcp.CallToolRequest, input ContextInput) (*mcp.CallToolResult, ContextOutput, error) {
+	var workingDir string
+	if input.WorkingDir != nil {
+		workingDir = *input.WorkingDir
+	}
+	scopes, err := scope.ResolveApplicable(ctx, workingDir)
+	if err != nil {
+		return nil, ContextOutput{}, fmt.Errorf("failed to resolve scopes: %w", err)
+	}
+	keys := input.Keys
+	if len(keys) == 0 {
+		keys = s.settings.ContextKeyList()
+	}
+
+	result, err := s.entry().LoadContext(ctx, scopes, keys, &usecase.GetOptions{
+		SkipVerify: !s.settings.ShouldVerifyOnRead(),
+		TrackRead:  s.settings.ShouldTrackReads(),
+	})
+	if err != nil {
+		return nil, ContextOutput{}, fmt.Errorf("failed to read context: %w", err)
+	}
+
+	output := ContextOutput{
+		Scopes:  make([]string, 0, len(scopes)),
+		Entries: make([]ContextEntryOutput, 0, len(result.Entries)),
+		Missing: result.Missing,
+	}
+	for _, sc := range scopes {
+		output.Scopes = append(output.Scopes, scope.FormatScope(sc))
+	}
+	for _, e := range result.Entries {
+		output.Entries = append(output.Entries, ContextEntryOutput{
+			Key:       e.Record.Key,
+			Scope:     scope.FormatScope(e.Scope),
+			Version:   e.Record.Version,
+			CreatedAt: e.Record.CreatedAt.Format(time.RFC3339),
+			Content:   e.Content,
+		})
+	}
+	return nil, output, nil
+}
+
+// resolveSessionScope defaults to the current branch, so that each branch
+// keeps its own log, and falls back to the usual default outside one.
+func resolveSessionScope(ctx context.Context, input SessionInput) (scope.Scope, error) {
+	if input.Scope == nil && input.Repo == nil {
+		branch := string(scope.ScopeBranch)
+		if sc, err := resolveScopeFromInput(ctx, &branch, nil, input.Branch, nil, input.WorkingDir); err == nil {
+			return sc, nil
+		}
+	}
+	return resolveScopeFromInput(ctx, input.Scope, input.Repo, input.Branch, input.Worktree, input.WorkingDir)
+}
+
+func newInfoOutput(result *usecase.InfoResult) InfoOutput {
+	return InfoOutput{
 		ID:          result.Record.EntryID,
 		ScopeID:     result.Record.ScopeID,
 		Scope:       scope.FormatScope(result.Scope),
@@ -378,7 +1181,22 @@ func (s *Server) handleInfo(ctx context.Context, _ *mcp.CallToolRequest, input I
 		FilePath:    result.Record.FilePath,
 		Hash:        result.Record.Hash,
 		Description: result.Record.Description,
+		Language:    result.Record.Language,
+		Summary:     result.Record.Summary,
 		CreatedAt:   result.Record.CreatedAt.Format(time.RFC3339),
 		IsArchived:  result.Record.IsArchived,
-	}, nil
+
+		VersionCount:   result.Summary.VersionCount,
+		TotalSize:      result.Summary.TotalSize,
+		FirstWrittenAt: optionalRFC3339(result.Summary.FirstWrittenAt),
+		LastWrittenAt:  optionalRFC3339(result.Summary.LastWrittenAt),
+	}
+}
+
+func optionalRFC3339(t time.Time) *string {
+	if t.IsZero() {
+		return nil
+	}
+	formatted := t.Format(time.RFC3339)
+	return &formatted
 }
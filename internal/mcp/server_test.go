@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/services/memory"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+// connectTestServer serves the vault tools on the in-memory repositories and
+// returns a client session connected to them.
+func connectTestServer(t *testing.T) *mcp.ClientSession {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("VAULT_DIR", dir)
+	t.Setenv("VAULT_CONFIG", filepath.Join(dir, "config.json"))
+
+	scopes, entries := memory.NewScopeService(), memory.NewEntryService()
+	s := &Server{
+		server:   mcp.NewServer(&mcp.Implementation{Name: "vault.md", Version: "test"}, nil),
+		settings: &config.Settings{},
+		entry: func() *usecase.Entry {
+			return usecase.NewEntryFromRepositories(scopes, entries)
+		},
+	}
+	s.registerTools()
+	s.server.AddReceivingMiddleware(structuredErrors)
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := s.server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server Connect failed: %v", err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "test"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client Connect failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = session.Close()
+		_ = serverSession.Wait()
+	})
+	return session
+}
+
+// callTool calls a tool and decodes its structured output into out.
+func callTool(t *testing.T, session *mcp.ClientSession, name string, args map[string]any, out any) *mcp.CallToolResult {
+	t.Helper()
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: name, Arguments: args})
+	if err != nil {
+		t.Fatalf("%s failed: %v", name, err)
+	}
+	if out != nil && !result.IsError {
+		data, err := json.Marshal(result.StructuredContent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("%s returned %s: %v", name, data, err)
+		}
+	}
+	return result
+}
+
+func TestToolHandlersOverMemoryRepositories(t *testing.T) {
+	session := connectTestServer(t)
+
+	for i, content := range []string{"first\n", "second\n"} {
+		var set SetOutput
+		if result := callTool(t, session, "vault_set", map[string]any{"key": "notes", "content": content, "scope": "global"}, &set); result.IsError {
+			t.Fatalf("vault_set returned an error: %+v", result.Content)
+		}
+		if set.Key != "notes" || set.Version != int64(i+1) {
+			t.Fatalf("vault_set = %+v, want notes version %d", set, i+1)
+		}
+	}
+
+	var got GetOutput
+	if result := callTool(t, session, "vault_get", map[string]any{"key": "notes", "scope": "global"}, &got); result.IsError {
+		t.Fatalf("vault_get returned an error: %+v", result.Content)
+	}
+	if got.Content != "second\n" {
+		t.Errorf("vault_get content = %q, want the latest version", got.Content)
+	}
+
+	var list ListOutput
+	if result := callTool(t, session, "vault_list", map[string]any{"scope": "global", "allVersions": true}, &list); result.IsError {
+		t.Fatalf("vault_list returned an error: %+v", result.Content)
+	}
+	if len(list.Entries) != 2 || list.Entries[0].Key != "notes" {
+		t.Errorf("vault_list = %+v, want both versions of notes", list.Entries)
+	}
+
+	result := callTool(t, session, "vault_get", map[string]any{"key": "missing", "scope": "global"}, nil)
+	if !result.IsError {
+		t.Fatalf("vault_get of a missing key succeeded: %+v", result.Content)
+	}
+	data, err := json.Marshal(result.Meta[errorMetaKey])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var toolErr ToolError
+	if err := json.Unmarshal(data, &toolErr); err != nil || toolErr.Code != CodeNotFound || toolErr.Key != "missing" {
+		t.Errorf("error metadata = %s, want %s for missing", data, CodeNotFound)
+	}
+
+	if result := callTool(t, session, "vault_set", map[string]any{"key": "_vault/notes", "content": "x\n", "scope": "global"}, nil); !result.IsError {
+		t.Error("vault_set accepted a key under the reserved prefix")
+	}
+}
@@ -0,0 +1,252 @@
+// Package notify posts messages about vault changes to chat webhooks such
+// as Slack and Discord.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choplin/vault.md/internal/config"
+)
+
+// sendTimeout bounds each webhook request, so that an unreachable chat
+// service delays a write by seconds at most.
+const sendTimeout = 5 * time.Second
+
+// Event is a change to an entry.
+type Event struct {
+	// Type is one of config.NotifyEvents.
+	Type string `json:"type"`
+	// Scope is the scope as vault list shows it; ScopeType is its type.
+	Scope     string `json:"scope"`
+	ScopeType string `json:"scopeType"`
+	Key       string `json:"key"`
+	// Version is the version written or deleted, or 0 for changes to all
+	// versions of the key.
+	Version     int64     `json:"version,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Actor       string    `json:"actor,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// Message renders ev as one line of chat text.
+func (ev Event) Message() string {
+	var sb strings.Builder
+	if ev.Actor != "" {
+		sb.WriteString(ev.Actor)
+		sb.WriteString(" ")
+	}
+	switch ev.Type {
+	case "set":
+		fmt.Fprintf(&sb, "stored %s v%d", ev.Key, ev.Version)
+	case "delete":
+		if ev.Version != 0 {
+			fmt.Fprintf(&sb, "deleted %s v%d", ev.Key, ev.Version)
+		} else {
+			fmt.Fprintf(&sb, "deleted %s", ev.Key)
+		}
+	case "archive":
+		fmt.Fprintf(&sb, "archived %s", ev.Key)
+	case "restore":
+		fmt.Fprintf(&sb, "restored %s", ev.Key)
+	default:
+		fmt.Fprintf(&sb, "%s %s", ev.Type, ev.Key)
+	}
+	fmt.Fprintf(&sb, " in %s", ev.Scope)
+	if ev.Description != "" {
+		fmt.Fprintf(&sb, ": %s", ev.Description)
+	}
+	return "vault.md: " + sb.String()
+}
+
+// Webhook is a configured notifier.
+type Webhook struct {
+	settings config.NotifierSettings
+	client   *http.Client
+}
+
+// FromSettings returns the notifiers in settings.
+func FromSettings(settings *config.Settings) []*Webhook {
+	if settings == nil {
+		return nil
+	}
+	hooks := make([]*Webhook, 0, len(settings.Notifiers))
+	for _, n := range settings.Notifiers {
+		hooks = append(hooks, &Webhook{settings: n, client: &http.Client{Timeout: sendTimeout}})
+	}
+	return hooks
+}
+
+// Name identifies the webhook in messages by its host alone, since webhook
+// URLs carry their secret in the path.
+func (w *Webhook) Name() string {
+	u, err := url.Parse(w.settings.URL)
+	if err != nil {
+		return "webhook"
+	}
+	return u.Host
+}
+
+// Matches reports whether the webhook subscribes to ev.
+func (w *Webhook) Matches(ev Event) bool {
+	if len(w.settings.Events) > 0 && !slices.Contains(w.settings.Events, ev.Type) {
+		return false
+	}
+	return matchAny(w.settings.Scopes, ev.Scope, ev.ScopeType) && matchAny(w.settings.Keys, ev.Key)
+}
+
+// matchAny reports whether any pattern matches any of values, or whether
+// there are no patterns.
+func matchAny(patterns []string, values ...string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		for _, value := range values {
+			if ok, _ := path.Match(pattern, value); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Send posts ev to the webhook regardless of its filters.
+func (w *Webhook) Send(ctx context.Context, ev Event) error {
+	var payload any
+	switch w.settings.NotifyFormat() {
+	case config.NotifyDiscord:
+		payload = map[string]string{"content": ev.Message()}
+	case config.NotifyJSON:
+		payload = ev
+	default:
+		payload = map[string]string{"text": ev.Message()}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.settings.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		// url.Error repeats the URL, secret included.
+		if urlErr := (*url.Error)(nil); errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// Dispatch sends ev to each of hooks that matches it and reports the
+// failures together.
+func Dispatch(ctx context.Context, hooks []*Webhook, ev Event) error {
+	var errs []error
+	for _, hook := range hooks {
+		if !hook.Matches(ev) {
+			continue
+		}
+		if err := hook.Send(ctx, ev); err != nil {
+			errs = append(errs, fmt.Errorf("notify %s: %w", hook.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Queue sends events to webhooks from one background goroutine, so a slow
+// or unreachable chat service never delays the change being announced.
+// Events published while the queue is full are dropped rather than
+// blocking the writer; Close sends the rest before the process exits.
+type Queue struct {
+	mu      sync.Mutex
+	started bool
+	closed  bool
+	items   chan queued
+	done    chan struct{}
+}
+
+type queued struct {
+	hooks []*Webhook
+	ev    Event
+}
+
+// NewQueue returns a queue holding up to size unsent events. Its goroutine
+// starts with the first event.
+func NewQueue(size int) *Queue {
+	return &Queue{items: make(chan queued, size), done: make(chan struct{})}
+}
+
+// Publish queues ev for those of hooks that match it. It reports false if
+// ev was dropped because the queue is full or closed.
+func (q *Queue) Publish(hooks []*Webhook, ev Event) bool {
+	if !slices.ContainsFunc(hooks, func(h *Webhook) bool { return h.Matches(ev) }) {
+		return true
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	if !q.started {
+		q.started = true
+		go q.run()
+	}
+	select {
+	case q.items <- queued{hooks: hooks, ev: ev}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *Queue) run() {
+	defer close(q.done)
+	for item := range q.items {
+		// Failures are not reported; vault notify test checks the webhooks.
+		_ = Dispatch(context.Background(), item.hooks, item.ev)
+	}
+}
+
+// Close stops accepting events and waits until the queued ones are sent,
+// or until ctx is done.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	started := q.started
+	if !q.closed {
+		q.closed = true
+		close(q.items)
+	}
+	q.mu.Unlock()
+	if !started {
+		return nil
+	}
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d notification(s) not sent: %w", len(q.items), ctx.Err())
+	}
+}
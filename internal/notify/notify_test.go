@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/choplin/vault.md/internal/config"
+)
+
+func TestDispatchPostsMatchingEvents(t *testing.T) {
+	var received []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode error: %v", err)
+		}
+		received = append(received, body)
+	}))
+	defer server.Close()
+
+	discord := config.NotifyDiscord
+	hooks := FromSettings(&config.Settings{Notifiers: []config.NotifierSettings{
+		{URL: server.URL, Keys: []string{"decisions/*"}},
+		{URL: server.URL, Format: &discord, Scopes: []string{"global"}, Events: []string{"delete"}},
+	}})
+
+	ev := Event{Type: "set", Scope: "/repo:main", ScopeType: "branch", Key: "decisions/db", Version: 2, Actor: "alice", Description: "pick sqlite"}
+	if err := Dispatch(context.Background(), hooks, ev); err != nil {
+		t.Fatalf("Dispatch error: %v", err)
+	}
+	if len(received) != 1 || received[0]["text"] != "vault.md: alice stored decisions/db v2 in /repo:main: pick sqlite" {
+		t.Fatalf("unexpected posts: %v", received)
+	}
+
+	received = nil
+	ev = Event{Type: "delete", Scope: "global", ScopeType: "global", Key: "plan"}
+	if err := Dispatch(context.Background(), hooks, ev); err != nil {
+		t.Fatalf("Dispatch error: %v", err)
+	}
+	if len(received) != 1 || received[0]["content"] != "vault.md: deleted plan in global" {
+		t.Fatalf("unexpected posts: %v", received)
+	}
+}
+
+func TestSendReportsFailuresWithoutTheURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	hooks := FromSettings(&config.Settings{Notifiers: []config.NotifierSettings{{URL: server.URL + "/services/SECRET"}}})
+	err := Dispatch(context.Background(), hooks, Event{Type: "set", Key: "plan"})
+	if err == nil || !strings.Contains(err.Error(), "403") || strings.Contains(err.Error(), "SECRET") {
+		t.Fatalf("expected a 403 error without the secret, got %v", err)
+	}
+
+	server.Close()
+	err = Dispatch(context.Background(), hooks, Event{Type: "set", Key: "plan"})
+	if err == nil || strings.Contains(err.Error(), "SECRET") {
+		t.Fatalf("expected a connection error without the secret, got %v", err)
+	}
+}
+
+func TestQueueSendsInTheBackground(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		var ev Event
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Errorf("decode error: %v", err)
+		}
+		mu.Lock()
+		keys = append(keys, ev.Key)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	format := config.NotifyJSON
+	hooks := FromSettings(&config.Settings{Notifiers: []config.NotifierSettings{{URL: server.URL, Format: &format, Keys: []string{"plan*"}}}})
+	q := NewQueue(2)
+
+	// The first event is in flight while the webhook stalls, the next two
+	// wait in the queue, and the last one does not fit.
+	if !q.Publish(hooks, Event{Type: "set", Key: "plan1"}) {
+		t.Fatal("Publish dropped the first event")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(q.items) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !q.Publish(hooks, Event{Type: "set", Key: "plan2"}) || !q.Publish(hooks, Event{Type: "set", Key: "plan3"}) {
+		t.Fatal("Publish dropped an event that fits in the queue")
+	}
+	if q.Publish(hooks, Event{Type: "set", Key: "plan4"}) {
+		t.Fatal("Publish queued an event beyond the queue's size")
+	}
+	if !q.Publish(hooks, Event{Type: "set", Key: "notes"}) {
+		t.Fatal("Publish reported an event no webhook subscribes to as dropped")
+	}
+
+	close(release)
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	if strings.Join(keys, " ") != "plan1 plan2 plan3" {
+		t.Fatalf("webhook received %v", keys)
+	}
+	if q.Publish(hooks, Event{Type: "set", Key: "plan5"}) {
+		t.Fatal("Publish queued an event after Close")
+	}
+}
+
+func TestQueueCloseGivesUpAtTheDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	if err := NewQueue(1).Close(context.Background()); err != nil {
+		t.Fatalf("Close of an unused queue: %v", err)
+	}
+
+	hooks := FromSettings(&config.Settings{Notifiers: []config.NotifierSettings{{URL: server.URL}}})
+	q := NewQueue(1)
+	q.Publish(hooks, Event{Type: "set", Key: "plan"})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := q.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close with a stalled webhook = %v, want DeadlineExceeded", err)
+	}
+}
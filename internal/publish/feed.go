@@ -0,0 +1,107 @@
+package publish
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// feedFile is the Atom feed Write puts next to index.html.
+const feedFile = "feed.xml"
+
+// feedLimit is the number of most recent versions the feed lists.
+const feedLimit = 50
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Base    string      `xml:"xml:base,attr,omitempty"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Summary string   `xml:"summary,omitempty"`
+	Content atomText `xml:"content"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// feedItem is a version to list in the feed with the page that shows it.
+type feedItem struct {
+	Key     string
+	Version Version
+	Page    string
+	Body    string
+}
+
+// writeFeed writes the Atom feed of the most recent versions in items.
+// Entry ids are tag URIs built from the scope, so they stay stable when
+// the site is published again or moved.
+func writeFeed(dir string, site *Site, items []feedItem) error {
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Version.CreatedAt.After(items[j].Version.CreatedAt)
+	})
+	if len(items) > feedLimit {
+		items = items[:feedLimit]
+	}
+
+	feed := atomFeed{
+		Base:    site.BaseURL,
+		ID:      tagURI(site.Scope, ""),
+		Title:   site.Title,
+		Updated: site.GeneratedAt.UTC().Format(time.RFC3339),
+		Links:   []atomLink{{Href: "index.html"}, {Href: feedFile, Rel: "self"}},
+		Author:  atomAuthor{Name: "vault.md"},
+	}
+	for _, item := range items {
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      tagURI(site.Scope, fmt.Sprintf("%s/v%d", item.Key, item.Version.Version)),
+			Title:   fmt.Sprintf("%s v%d", item.Key, item.Version.Version),
+			Updated: item.Version.CreatedAt.UTC().Format(time.RFC3339),
+			Link:    atomLink{Href: entriesDir + "/" + item.Page},
+			Summary: item.Version.Description,
+			Content: atomText{Type: "html", Body: item.Body},
+		})
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append([]byte(xml.Header), append(data, '\n')...)
+	return os.WriteFile(filepath.Join(dir, feedFile), data, 0o644) //nolint:gosec // G306: the site is meant to be shared
+}
+
+// tagURI returns a tag: URI (RFC 4151) naming scope, or name within it.
+func tagURI(scope, name string) string {
+	replacer := strings.NewReplacer("%", "%25", " ", "%20", "#", "%23")
+	uri := "tag:vault.md,2025:" + replacer.Replace(scope)
+	if name != "" {
+		uri += "#" + replacer.Replace(name)
+	}
+	return uri
+}
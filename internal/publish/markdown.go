@@ -0,0 +1,344 @@
+package publish
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// RenderMarkdown converts the common subset of Markdown that entries are
+// written in to HTML: ATX headings, paragraphs, fenced code, block quotes,
+// nested lists, pipe tables, thematic breaks, and inline code, emphasis,
+// and links. Raw HTML is escaped rather than passed through, so a published
+// page shows exactly what the entry holds.
+func RenderMarkdown(source string) string {
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+	var sb strings.Builder
+	renderBlocks(&sb, lines)
+	return sb.String()
+}
+
+var (
+	headingPattern  = regexp.MustCompile(`^(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	fencePattern    = regexp.MustCompile("^ {0,3}(```+|~~~+)[ \t]*([^`\\s]*)")
+	rulePattern     = regexp.MustCompile(`^ {0,3}([-*_])(?:[ \t]*([-*_])){2,}[ \t]*$`)
+	listPattern     = regexp.MustCompile(`^( {0,3})([-*+]|\d{1,9}[.)])([ \t]+|$)`)
+	quotePattern    = regexp.MustCompile(`^ {0,3}> ?`)
+	tableSepPattern = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+)
+
+func renderBlocks(sb *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+		case fencePattern.MatchString(line):
+			i = renderFence(sb, lines, i)
+		case headingPattern.MatchString(strings.TrimLeft(line, " ")) && leadingSpaces(line) < 4:
+			m := headingPattern.FindStringSubmatch(strings.TrimLeft(line, " "))
+			level := len(m[1])
+			fmt.Fprintf(sb, "<h%d id=\"%s\">%s</h%d>\n", level, headingID(m[2]), renderInline(m[2]), level)
+			i++
+		case isRule(line):
+			sb.WriteString("<hr>\n")
+			i++
+		case quotePattern.MatchString(line):
+			var quoted []string
+			for ; i < len(lines) && quotePattern.MatchString(lines[i]); i++ {
+				quoted = append(quoted, quotePattern.ReplaceAllString(lines[i], ""))
+			}
+			sb.WriteString("<blockquote>\n")
+			renderBlocks(sb, quoted)
+			sb.WriteString("</blockquote>\n")
+		case listPattern.MatchString(line):
+			i = renderList(sb, lines, i)
+		case i+1 < len(lines) && strings.Contains(line, "|") && tableSepPattern.MatchString(lines[i+1]):
+			i = renderTable(sb, lines, i)
+		default:
+			start := i
+			for i++; i < len(lines) && !startsBlock(lines, i); i++ {
+			}
+			fmt.Fprintf(sb, "<p>%s</p>\n", renderInline(strings.Join(trimLines(lines[start:i]), "\n")))
+		}
+	}
+}
+
+// startsBlock reports whether lines[i] ends a paragraph.
+func startsBlock(lines []string, i int) bool {
+	line := lines[i]
+	return strings.TrimSpace(line) == "" ||
+		fencePattern.MatchString(line) ||
+		(headingPattern.MatchString(strings.TrimLeft(line, " ")) && leadingSpaces(line) < 4) ||
+		isRule(line) ||
+		quotePattern.MatchString(line) ||
+		listPattern.MatchString(line)
+}
+
+func isRule(line string) bool {
+	m := rulePattern.FindStringSubmatch(line)
+	if m == nil {
+		return false
+	}
+	// All markers must be the same character.
+	return strings.Trim(line, " \t"+m[1]) == ""
+}
+
+func renderFence(sb *strings.Builder, lines []string, i int) int {
+	m := fencePattern.FindStringSubmatch(lines[i])
+	fence, lang := m[1], m[2]
+	var code []string
+	for i++; i < len(lines); i++ {
+		if trimmed := strings.TrimSpace(lines[i]); strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		code = append(code, lines[i])
+	}
+	if lang != "" {
+		fmt.Fprintf(sb, "<pre><code class=\"language-%s\">", html.EscapeString(lang))
+	} else {
+		sb.WriteString("<pre><code>")
+	}
+	for _, line := range code {
+		sb.WriteString(html.EscapeString(line))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("</code></pre>\n")
+	return i
+}
+
+// renderList renders the list starting at lines[i] and returns the index
+// of the first line after it. Item content is everything indented past the
+// marker; it is rendered as blocks of its own, so lists nest.
+func renderList(sb *strings.Builder, lines []string, i int) int {
+	first := listPattern.FindStringSubmatch(lines[i])
+	ordered := first[2][0] >= '0' && first[2][0] <= '9'
+	tag, attrs := "ul", ""
+	if ordered {
+		tag = "ol"
+		if start := strings.TrimRight(first[2], ".)"); start != "1" {
+			attrs = fmt.Sprintf(" start=\"%s\"", strings.TrimLeft(start[:len(start)-1], "0")+start[len(start)-1:])
+		}
+	}
+	fmt.Fprintf(sb, "<%s%s>\n", tag, attrs)
+
+	var items [][]string
+	tight := true
+	for i < len(lines) {
+		m := listPattern.FindStringSubmatch(lines[i])
+		if m == nil || (m[2][0] >= '0' && m[2][0] <= '9') != ordered {
+			break
+		}
+		indent := len(m[1]) + len(m[2]) + len(m[3])
+		if m[3] == "" || len(m[3]) > 4 {
+			indent = len(m[1]) + len(m[2]) + 1
+		}
+		item := []string{strings.TrimLeft(lines[i][len(m[0]):], " \t")}
+		if len(m[3]) > 4 {
+			item[0] = lines[i][len(m[1])+len(m[2])+1:]
+		}
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				// A blank line continues the item only if indented content follows.
+				if i+1 < len(lines) && leadingSpaces(lines[i+1]) >= indent && strings.TrimSpace(lines[i+1]) != "" {
+					tight = false
+					item = append(item, "")
+					continue
+				}
+				if i+1 < len(lines) && listPattern.MatchString(lines[i+1]) && leadingSpaces(lines[i+1]) < indent {
+					tight = false
+				}
+				break
+			}
+			if leadingSpaces(line) >= indent {
+				item = append(item, line[indent:])
+				continue
+			}
+			if listPattern.MatchString(line) || startsBlock(lines, i) {
+				break
+			}
+			// A lazy continuation line of the item's paragraph.
+			item = append(item, strings.TrimLeft(line, " "))
+		}
+		items = append(items, item)
+		for i < len(lines) && strings.TrimSpace(lines[i]) == "" && i+1 < len(lines) && listPattern.MatchString(lines[i+1]) {
+			i++
+		}
+	}
+
+	for _, item := range items {
+		var inner strings.Builder
+		renderBlocks(&inner, item)
+		content := inner.String()
+		if tight {
+			content = unwrapParagraphs(content)
+		}
+		fmt.Fprintf(sb, "<li>%s</li>\n", strings.TrimSuffix(content, "\n"))
+	}
+	fmt.Fprintf(sb, "</%s>\n", tag)
+	return i
+}
+
+// unwrapParagraphs drops the <p> tags of a tight list item, as Markdown
+// renderers do.
+func unwrapParagraphs(content string) string {
+	content = strings.ReplaceAll(content, "<p>", "")
+	return strings.ReplaceAll(content, "</p>", "")
+}
+
+func renderTable(sb *strings.Builder, lines []string, i int) int {
+	header := splitTableRow(lines[i])
+	seps := splitTableRow(lines[i+1])
+	aligns := make([]string, len(header))
+	for j := range aligns {
+		if j >= len(seps) {
+			continue
+		}
+		sep := strings.TrimSpace(seps[j])
+		switch {
+		case strings.HasPrefix(sep, ":") && strings.HasSuffix(sep, ":"):
+			aligns[j] = " style=\"text-align: center\""
+		case strings.HasSuffix(sep, ":"):
+			aligns[j] = " style=\"text-align: right\""
+		case strings.HasPrefix(sep, ":"):
+			aligns[j] = " style=\"text-align: left\""
+		}
+	}
+
+	sb.WriteString("<table>\n<thead>\n<tr>")
+	for j, cell := range header {
+		fmt.Fprintf(sb, "<th%s>%s</th>", aligns[j], renderInline(strings.TrimSpace(cell)))
+	}
+	sb.WriteString("</tr>\n</thead>\n<tbody>\n")
+	for i += 2; i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|"); i++ {
+		cells := splitTableRow(lines[i])
+		sb.WriteString("<tr>")
+		for j := range header {
+			cell := ""
+			if j < len(cells) {
+				cell = strings.TrimSpace(cells[j])
+			}
+			fmt.Fprintf(sb, "<td%s>%s</td>", aligns[j], renderInline(cell))
+		}
+		sb.WriteString("</tr>\n")
+	}
+	sb.WriteString("</tbody>\n</table>\n")
+	return i
+}
+
+// splitTableRow splits a pipe table row into cells. Escaped pipes stay in
+// their cell.
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	var cell strings.Builder
+	for k := 0; k < len(line); k++ {
+		switch {
+		case line[k] == '\\' && k+1 < len(line) && line[k+1] == '|':
+			cell.WriteByte('|')
+			k++
+		case line[k] == '|':
+			cells = append(cells, cell.String())
+			cell.Reset()
+		default:
+			cell.WriteByte(line[k])
+		}
+	}
+	return append(cells, cell.String())
+}
+
+var (
+	linkPattern   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	strongPattern = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	emPattern     = regexp.MustCompile(`\*([^*\s][^*]*)\*|\b_([^_\s][^_]*)_\b`)
+	strikePattern = regexp.MustCompile(`~~([^~]+)~~`)
+)
+
+// renderInline renders the inline markup of text. Code spans are taken
+// out first so that nothing inside them is interpreted.
+func renderInline(text string) string {
+	var sb strings.Builder
+	for {
+		start := strings.Index(text, "`")
+		if start < 0 {
+			break
+		}
+		ticks := len(text[start:]) - len(strings.TrimLeft(text[start:], "`"))
+		fence := text[start : start+ticks]
+		end := strings.Index(text[start+ticks:], fence)
+		if end < 0 {
+			break
+		}
+		sb.WriteString(renderSpan(text[:start]))
+		code := strings.TrimSpace(text[start+ticks : start+ticks+end])
+		fmt.Fprintf(&sb, "<code>%s</code>", html.EscapeString(code))
+		text = text[start+ticks+end+ticks:]
+	}
+	sb.WriteString(renderSpan(text))
+	return sb.String()
+}
+
+func renderSpan(text string) string {
+	text = html.EscapeString(text)
+	text = linkPattern.ReplaceAllStringFunc(text, func(match string) string {
+		m := linkPattern.FindStringSubmatch(match)
+		if !safeURL(html.UnescapeString(m[2])) {
+			return m[1]
+		}
+		return fmt.Sprintf("<a href=\"%s\">%s</a>", m[2], m[1])
+	})
+	text = strongPattern.ReplaceAllString(text, "<strong>$1$2</strong>")
+	text = emPattern.ReplaceAllString(text, "<em>$1$2</em>")
+	text = strikePattern.ReplaceAllString(text, "<del>$1</del>")
+	return strings.ReplaceAll(text, "  \n", "<br>\n")
+}
+
+// safeURL reports whether a link target may be written into a page:
+// relative references and web or mail links, never scripts.
+func safeURL(url string) bool {
+	scheme, _, found := strings.Cut(url, ":")
+	if !found || strings.ContainsAny(scheme, "/?#") {
+		return true
+	}
+	switch strings.ToLower(scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// headingID turns heading text into an anchor such as "next-steps".
+func headingID(text string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r > 127:
+			sb.WriteRune(r)
+			dash = false
+		case !dash && sb.Len() > 0:
+			sb.WriteByte('-')
+			dash = true
+		}
+	}
+	return html.EscapeString(strings.TrimSuffix(sb.String(), "-"))
+}
+
+func leadingSpaces(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func trimLines(lines []string) []string {
+	trimmed := make([]string, len(lines))
+	for i, line := range lines {
+		trimmed[i] = strings.TrimLeft(line, " \t")
+	}
+	return trimmed
+}
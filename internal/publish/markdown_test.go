@@ -0,0 +1,38 @@
+package publish
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	cases := []struct {
+		name, source, want string
+	}{
+		{"heading", "# Plan #\n\n## Next steps", "<h1 id=\"plan\">Plan</h1>\n<h2 id=\"next-steps\">Next steps</h2>\n"},
+		{"paragraph", "one\ntwo\n\nthree", "<p>one\ntwo</p>\n<p>three</p>\n"},
+		{"inline", "**bold** *em* `a<b>` [site](https://example.com)", "<p><strong>bold</strong> <em>em</em> <code>a&lt;b&gt;</code> <a href=\"https://example.com\">site</a></p>\n"},
+		{"unsafe link", "[x](javascript:alert)", "<p>x</p>\n"},
+		{"raw html", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"fence", "```go\nif a < b {}\n```", "<pre><code class=\"language-go\">if a &lt; b {}\n</code></pre>\n"},
+		{"nested list", "- a\n  - b\n- c", "<ul>\n<li>a\n<ul>\n<li>b</li>\n</ul></li>\n<li>c</li>\n</ul>\n"},
+		{"ordered list", "3. a\n4. b", "<ol start=\"3\">\n<li>a</li>\n<li>b</li>\n</ol>\n"},
+		{"quote", "> note\n> more", "<blockquote>\n<p>note\nmore</p>\n</blockquote>\n"},
+		{"rule", "a\n\n---\n\nb", "<p>a</p>\n<hr>\n<p>b</p>\n"},
+		{"table", "| Key | Size |\n|-----|-----:|\n| a | 1 |", "<table>\n<thead>\n<tr><th>Key</th><th style=\"text-align: right\">Size</th></tr>\n</thead>\n<tbody>\n<tr><td>a</td><td style=\"text-align: right\">1</td></tr>\n</tbody>\n</table>\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := RenderMarkdown(tc.source); got != tc.want {
+				t.Fatalf("RenderMarkdown(%q) =\n%s\nwant\n%s", tc.source, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownLooseList(t *testing.T) {
+	got := RenderMarkdown("- a\n\n- b\n")
+	if !strings.Contains(got, "<li><p>a</p></li>") {
+		t.Fatalf("expected a loose list to keep paragraphs, got %s", got)
+	}
+}
@@ -0,0 +1,253 @@
+// Package publish renders vault entries as a static HTML site.
+package publish
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Site is what Write renders: the latest entries of one scope with their
+// history.
+type Site struct {
+	Title string
+	// Scope names the scope the entries come from.
+	Scope string
+	// BaseURL is where the site will be served from, such as
+	// "https://example.com/context/". It lets feed readers resolve the
+	// feed's links; pages themselves only use relative links.
+	BaseURL     string
+	GeneratedAt time.Time
+	// FormatTime formats the timestamps shown on pages. Defaults to
+	// RFC3339.
+	FormatTime func(time.Time) string
+	Entries    []Entry
+}
+
+// Entry is one key and its versions, oldest first.
+type Entry struct {
+	Key string
+	// Markdown renders the content as Markdown; otherwise it is shown as
+	// preformatted text.
+	Markdown bool
+	Versions []Version
+}
+
+// Version is one version of an Entry.
+type Version struct {
+	Version     int64
+	CreatedAt   time.Time
+	Description string
+	Content     string
+}
+
+// Write renders site into dir: index.html listing the keys, a page per key
+// with its latest content and history, a page per older version, and an
+// Atom feed of the most recent versions. Pages link to each other with
+// relative paths, so the directory can be served from anywhere or opened
+// from disk.
+func Write(dir string, site *Site) error {
+	if site.FormatTime == nil {
+		site.FormatTime = func(t time.Time) string { return t.Format(time.RFC3339) }
+	}
+	if err := os.MkdirAll(filepath.Join(dir, entriesDir), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "style.css"), []byte(styleSheet), 0o644); err != nil { //nolint:gosec // G306: the site is meant to be shared
+		return err
+	}
+
+	var items []feedItem
+	slugs := make(map[string]bool, len(site.Entries))
+	index := indexPage{Site: site}
+	for _, entry := range site.Entries {
+		if len(entry.Versions) == 0 {
+			continue
+		}
+		slug := uniqueSlug(entry.Key, slugs)
+		latest := entry.Versions[len(entry.Versions)-1]
+		index.Entries = append(index.Entries, indexEntry{Entry: entry, Latest: latest, Page: entriesDir + "/" + slug + ".html"})
+
+		history := make([]historyLink, 0, len(entry.Versions))
+		for i := len(entry.Versions) - 1; i >= 0; i-- {
+			v := entry.Versions[i]
+			page := fmt.Sprintf("%s-v%d.html", slug, v.Version)
+			if i == len(entry.Versions)-1 {
+				page = slug + ".html"
+			}
+			history = append(history, historyLink{Version: v, Page: page})
+		}
+		for i, link := range history {
+			page := entryPage{
+				Site:    site,
+				Entry:   entry,
+				Version: link.Version,
+				Latest:  i == 0,
+				History: history,
+				Body:    renderContent(entry, link.Version.Content),
+			}
+			if err := writePage(filepath.Join(dir, entriesDir, link.Page), entryTemplate, page); err != nil {
+				return err
+			}
+			items = append(items, feedItem{Key: entry.Key, Version: link.Version, Page: link.Page, Body: string(page.Body)})
+		}
+	}
+	if err := writeFeed(dir, site, items); err != nil {
+		return err
+	}
+	return writePage(filepath.Join(dir, "index.html"), indexTemplate, index)
+}
+
+// entriesDir is the directory of the entry pages.
+const entriesDir = "entries"
+
+type indexPage struct {
+	Site    *Site
+	Entries []indexEntry
+}
+
+type indexEntry struct {
+	Entry
+	Latest Version
+	Page   string
+}
+
+type entryPage struct {
+	Site    *Site
+	Entry   Entry
+	Version Version
+	Latest  bool
+	History []historyLink
+	Body    template.HTML
+}
+
+type historyLink struct {
+	Version
+	Page string
+}
+
+func renderContent(entry Entry, content string) template.HTML {
+	if entry.Markdown {
+		return template.HTML(RenderMarkdown(content)) //nolint:gosec // G203: RenderMarkdown escapes the content
+	}
+	return template.HTML("<pre>" + template.HTMLEscapeString(content) + "</pre>") //nolint:gosec // G203: escaped above
+}
+
+func writePage(path string, tmpl *template.Template, data any) error {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0o644) //nolint:gosec // G306: the site is meant to be shared
+}
+
+// uniqueSlug turns key into a file name, such as "notes-setup" for
+// "notes/setup", that no other key of the site uses.
+func uniqueSlug(key string, used map[string]bool) string {
+	var sb strings.Builder
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('-')
+		}
+	}
+	base := strings.Trim(sb.String(), "-")
+	if base == "" {
+		base = "entry"
+	}
+	slug := base
+	for n := 2; used[strings.ToLower(slug)]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	// Case-insensitive file systems would merge slugs differing in case.
+	used[strings.ToLower(slug)] = true
+	return slug
+}
+
+var funcs = template.FuncMap{
+	"time": func(site *Site, t time.Time) string { return site.FormatTime(t) },
+}
+
+var indexTemplate = template.Must(template.New("index").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Site.Title}}</title>
+<link rel="stylesheet" href="style.css">
+<link rel="alternate" type="application/atom+xml" title="{{.Site.Title}}" href="feed.xml">
+</head>
+<body>
+<header>
+<h1>{{.Site.Title}}</h1>
+<p class="meta">{{.Site.Scope}} · {{len .Entries}} entries · generated {{time .Site .Site.GeneratedAt}} · <a href="feed.xml">feed</a></p>
+</header>
+<main>
+{{- if .Entries}}
+<table>
+<thead><tr><th>Key</th><th>Version</th><th>Updated</th><th>Description</th></tr></thead>
+<tbody>
+{{- range .Entries}}
+<tr><td><a href="{{.Page}}">{{.Key}}</a></td><td>{{.Latest.Version}}</td><td>{{time $.Site .Latest.CreatedAt}}</td><td>{{.Latest.Description}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- else}}
+<p>No entries.</p>
+{{- end}}
+</main>
+</body>
+</html>
+`))
+
+var entryTemplate = template.Must(template.New("entry").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Entry.Key}}{{if not .Latest}} v{{.Version.Version}}{{end}} · {{.Site.Title}}</title>
+<link rel="stylesheet" href="../style.css">
+</head>
+<body>
+<header>
+<p class="meta"><a href="../index.html">{{.Site.Title}}</a></p>
+<h1>{{.Entry.Key}}</h1>
+<p class="meta">version {{.Version.Version}}{{if not .Latest}} (not the latest){{end}} · {{time .Site .Version.CreatedAt}}{{with .Version.Description}} · {{.}}{{end}}</p>
+</header>
+<main class="content">
+{{.Body}}
+</main>
+<aside>
+<h2>History</h2>
+<ul class="history">
+{{- range .History}}
+<li>{{if eq .Version.Version $.Version.Version}}<strong>v{{.Version.Version}}</strong>{{else}}<a href="{{.Page}}">v{{.Version.Version}}</a>{{end}} · {{time $.Site .CreatedAt}}{{with .Description}} · {{.}}{{end}}</li>
+{{- end}}
+</ul>
+</aside>
+</body>
+</html>
+`))
+
+const styleSheet = `body {
+  max-width: 60rem;
+  margin: 2rem auto;
+  padding: 0 1rem;
+  font: 16px/1.6 -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif;
+  color: #1f2328;
+}
+a { color: #0969da; }
+.meta { color: #59636e; font-size: 0.9rem; }
+table { border-collapse: collapse; width: 100%; margin: 1rem 0; }
+th, td { border: 1px solid #d1d9e0; padding: 0.3rem 0.6rem; text-align: left; vertical-align: top; }
+pre { background: #f6f8fa; padding: 0.8rem; overflow-x: auto; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; font-size: 0.9em; }
+blockquote { margin: 0; padding-left: 1rem; border-left: 0.25rem solid #d1d9e0; color: #59636e; }
+aside { border-top: 1px solid #d1d9e0; margin-top: 2rem; }
+.history { list-style: none; padding: 0; }
+`
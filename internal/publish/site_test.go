@@ -0,0 +1,56 @@
+package publish
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteLinksEntriesAndVersions(t *testing.T) {
+	dir := t.TempDir()
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	site := &Site{
+		Title:       "Context",
+		Scope:       "/repo",
+		GeneratedAt: created,
+		Entries: []Entry{
+			{Key: "notes/plan", Markdown: true, Versions: []Version{
+				{Version: 1, CreatedAt: created, Content: "# Old"},
+				{Version: 2, CreatedAt: created, Description: "rewrite", Content: "# New <plan>"},
+			}},
+			{Key: "notes-plan", Versions: []Version{{Version: 1, CreatedAt: created, Content: "<raw>"}}},
+		},
+	}
+	if err := Write(dir, site); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("ReadFile %s error: %v", name, err)
+		}
+		return string(data)
+	}
+	index := read("index.html")
+	for _, want := range []string{`href="entries/notes-plan.html"`, `href="entries/notes-plan-2.html"`, "rewrite"} {
+		if !strings.Contains(index, want) {
+			t.Fatalf("index.html lacks %s:\n%s", want, index)
+		}
+	}
+	if latest := read("entries/notes-plan.html"); !strings.Contains(latest, "<h1 id=\"new-plan\">New &lt;plan&gt;</h1>") || !strings.Contains(latest, `href="notes-plan-v1.html"`) {
+		t.Fatalf("unexpected latest page:\n%s", latest)
+	}
+	if old := read("entries/notes-plan-v1.html"); !strings.Contains(old, "not the latest") {
+		t.Fatalf("expected the old version page to say so:\n%s", old)
+	}
+	feed := read("feed.xml")
+	if !strings.Contains(feed, "<title>notes/plan v2</title>") || !strings.Contains(feed, `<link href="entries/notes-plan-v1.html"></link>`) {
+		t.Fatalf("unexpected feed:\n%s", feed)
+	}
+	if plain := read("entries/notes-plan-2.html"); !strings.Contains(plain, "<pre>&lt;raw&gt;</pre>") {
+		t.Fatalf("expected plain text to be preformatted:\n%s", plain)
+	}
+}
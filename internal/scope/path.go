@@ -0,0 +1,34 @@
+package scope
+
+import (
+	"runtime"
+	"strings"
+)
+
+// normalizePath returns the canonical form of a repository or worktree path,
+// so that one directory always maps to one scope. On Windows, git reports
+// paths as "C:/src/app" while users type "c:\src\app\"; both are rewritten to
+// git's form. Other systems keep the path as given.
+func normalizePath(path string) string {
+	if runtime.GOOS != "windows" {
+		return path
+	}
+	return normalizeWindowsPath(path)
+}
+
+func normalizeWindowsPath(path string) string {
+	p := strings.ReplaceAll(path, `\`, "/")
+	if hasDriveLetter(p) {
+		p = strings.ToUpper(p[:1]) + p[1:]
+	}
+	// Keep the slash of a drive root ("C:/") and of "/".
+	for len(p) > 1 && strings.HasSuffix(p, "/") && !(len(p) == 3 && hasDriveLetter(p)) {
+		p = p[:len(p)-1]
+	}
+	return p
+}
+
+// hasDriveLetter reports whether p starts with a Windows drive such as "C:".
+func hasDriveLetter(p string) bool {
+	return len(p) >= 2 && p[1] == ':' && ('a' <= p[0] && p[0] <= 'z' || 'A' <= p[0] && p[0] <= 'Z')
+}
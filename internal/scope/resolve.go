@@ -1,7 +1,10 @@
 package scope
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/choplin/vault.md/internal/git"
 )
@@ -17,10 +20,44 @@ type ScopeOptions struct {
 	WorkingDir string // Directory to detect git info from (empty = current dir)
 }
 
+var (
+	requireGit    bool
+	warnNoGit     func(error)
+	warnNoGitOnce sync.Once
+)
+
+// SetGitFallback decides what scope detection does when git cannot be run.
+// With require set it fails; otherwise it falls back to the global scope
+// and calls warn with the cause, once per process. warn may be nil.
+func SetGitFallback(require bool, warn func(error)) {
+	requireGit = require
+	warnNoGit = warn
+	warnNoGitOnce = sync.Once{}
+}
+
+// fallBackToGlobal returns nil when a detection error allows falling back
+// to the global scope, and the error to fail with otherwise.
+func fallBackToGlobal(err error) error {
+	if !errors.Is(err, git.ErrUnavailable) {
+		return err
+	}
+	if requireGit {
+		return fmt.Errorf("cannot detect the scope: %w", err)
+	}
+	warnNoGitOnce.Do(func() {
+		if warnNoGit != nil {
+			warnNoGit(err)
+		}
+	})
+	return nil
+}
+
 // ResolveScope converts CLI/MCP-level scope options into a validated Scope.
 // If no scope type is specified, it defaults to 'repository' and attempts to
-// auto-detect git repository information.
-func ResolveScope(opts ScopeOptions) (Scope, error) {
+// auto-detect git repository information. Without git, repository scopes
+// fall back to global as SetGitFallback allows; branch and worktree scopes
+// then need their flags.
+func ResolveScope(ctx context.Context, opts ScopeOptions) (Scope, error) {
 	// Default to repository scope if not specified
 	scopeType := ScopeType(opts.Type)
 	if scopeType == "" {
@@ -39,8 +76,15 @@ func ResolveScope(opts ScopeOptions) (Scope, error) {
 		// Auto-detect repository if not explicitly provided
 		repo := opts.Repo
 		if repo == "" {
-			gitInfo, err := git.GetGitInfo(opts.WorkingDir)
-			if err == nil && gitInfo.IsGitRepo {
+			gitInfo, err := git.GetGitInfo(ctx, opts.WorkingDir)
+			if err != nil {
+				if err := fallBackToGlobal(err); err != nil {
+					return Scope{}, err
+				}
+				s := NewGlobal()
+				return s, Validate(s)
+			}
+			if gitInfo.IsGitRepo {
 				repo = gitInfo.PrimaryWorktreePath
 			} else {
 				// If not in a git repository and no explicit repo provided, use global scope
@@ -62,8 +106,11 @@ func ResolveScope(opts ScopeOptions) (Scope, error) {
 		branch := opts.Branch
 
 		if repo == "" || branch == "" {
-			gitInfo, err := git.GetGitInfo(opts.WorkingDir)
-			if err == nil && gitInfo.IsGitRepo {
+			gitInfo, err := git.GetGitInfo(ctx, opts.WorkingDir)
+			if err != nil {
+				return Scope{}, err
+			}
+			if gitInfo.IsGitRepo {
 				if repo == "" {
 					repo = gitInfo.PrimaryWorktreePath
 				}
@@ -86,8 +133,11 @@ func ResolveScope(opts ScopeOptions) (Scope, error) {
 		worktree := opts.Worktree
 
 		if repo == "" || worktree == "" {
-			gitInfo, err := git.GetGitInfo(opts.WorkingDir)
-			if err == nil && gitInfo.IsGitRepo {
+			gitInfo, err := git.GetGitInfo(ctx, opts.WorkingDir)
+			if err != nil {
+				return Scope{}, err
+			}
+			if gitInfo.IsGitRepo {
 				if repo == "" {
 					repo = gitInfo.PrimaryWorktreePath
 				}
@@ -108,3 +158,35 @@ func ResolveScope(opts ScopeOptions) (Scope, error) {
 		return Scope{}, fmt.Errorf("invalid scope: %s (valid values: global, repository, branch, worktree)", opts.Type)
 	}
 }
+
+// ResolveApplicable returns the scopes that apply to workingDir, most
+// specific first: its worktree, branch, and repository when it is inside a
+// git repository, then global. A detached HEAD has no branch scope. Without
+// git, only global applies, as SetGitFallback allows.
+func ResolveApplicable(ctx context.Context, workingDir string) ([]Scope, error) {
+	gitInfo, err := git.GetGitInfo(ctx, workingDir)
+	if err != nil {
+		if err := fallBackToGlobal(err); err != nil {
+			return nil, err
+		}
+		return []Scope{NewGlobal()}, nil
+	}
+	if !gitInfo.IsGitRepo {
+		return []Scope{NewGlobal()}, nil
+	}
+
+	candidates := []Scope{NewWorktree(gitInfo.PrimaryWorktreePath, gitInfo.WorktreeID, "")}
+	if gitInfo.CurrentBranch != "HEAD" {
+		candidates = append(candidates, NewBranch(gitInfo.PrimaryWorktreePath, gitInfo.CurrentBranch))
+	}
+	candidates = append(candidates, NewRepository(gitInfo.PrimaryWorktreePath), NewGlobal())
+
+	scopes := make([]Scope, 0, len(candidates))
+	for _, s := range candidates {
+		// Branch and worktree names can collide with reserved words.
+		if Validate(s) == nil {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes, nil
+}
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/choplin/vault.md/internal/entrykey"
 )
 
 // ScopeType defines the type of scope for vault entries.
@@ -41,17 +43,17 @@ func NewGlobal() Scope {
 
 // NewRepository creates a new repository scope with the given path.
 func NewRepository(path string) Scope {
-	return Scope{Type: ScopeRepository, PrimaryPath: path}
+	return Scope{Type: ScopeRepository, PrimaryPath: normalizePath(path)}
 }
 
 // NewBranch creates a new branch scope with the given repository path and branch name.
 func NewBranch(path, branch string) Scope {
-	return Scope{Type: ScopeBranch, PrimaryPath: path, BranchName: branch}
+	return Scope{Type: ScopeBranch, PrimaryPath: normalizePath(path), BranchName: branch}
 }
 
 // NewWorktree creates a new worktree scope with the given repository path, worktree ID, and worktree path.
 func NewWorktree(path, id, wtPath string) Scope {
-	return Scope{Type: ScopeWorktree, PrimaryPath: path, WorktreeID: id, WorktreePath: wtPath}
+	return Scope{Type: ScopeWorktree, PrimaryPath: normalizePath(path), WorktreeID: id, WorktreePath: normalizePath(wtPath)}
 }
 
 // IsGlobal returns true if the scope is global.
@@ -71,7 +73,12 @@ func IsWorktree(s Scope) bool { return s.Type == ScopeWorktree }
 //   - ScopeRepository: PrimaryPath must be set.
 //   - ScopeBranch: PrimaryPath and BranchName must be set.
 //   - ScopeWorktree: PrimaryPath and WorktreeID must be set; WorktreePath is optional metadata.
+//
+// None of the fields may contain control characters or invalid UTF-8.
 func Validate(s Scope) error {
+	if err := checkText(s); err != nil {
+		return err
+	}
 	switch s.Type {
 	case ScopeGlobal:
 		return nil
@@ -128,6 +135,9 @@ func GetScopeStorageKey(s Scope) string {
 }
 
 // FormatScope returns a formatted string representation of the scope.
+// Branch scopes are "path:branch". A Windows drive letter ("C:/src/app:main")
+// does not make this ambiguous: git forbids ':' in branch names, so the
+// branch always follows the last colon.
 func FormatScope(s Scope) string {
 	switch s.Type {
 	case ScopeGlobal:
@@ -143,6 +153,38 @@ func FormatScope(s Scope) string {
 	}
 }
 
+// ParseScope is the inverse of FormatScope: it reads "global", a repository
+// path, "path:branch", or "path@worktree-id" as listed by vault scope list.
+// The colon of a Windows drive letter does not start a branch, and an "@"
+// followed by a "/" is part of the path. Worktree paths are not part of the
+// format and are left empty.
+func ParseScope(text string) (Scope, error) {
+	colon := strings.LastIndex(text, ":")
+	if colon == 1 && hasDriveLetter(text) {
+		colon = -1
+	}
+	at := strings.LastIndex(text, "@")
+	if at >= 0 && strings.Contains(text[at:], "/") {
+		at = -1
+	}
+
+	var s Scope
+	switch {
+	case text == string(ScopeGlobal):
+		s = NewGlobal()
+	case colon > 0:
+		s = NewBranch(text[:colon], text[colon+1:])
+	case at > 0:
+		s = NewWorktree(text[:at], text[at+1:], "")
+	default:
+		s = NewRepository(text)
+	}
+	if err := Validate(s); err != nil {
+		return Scope{}, fmt.Errorf("invalid scope %q: %w", text, err)
+	}
+	return s, nil
+}
+
 // FormatScopeShort returns a short formatted string representation of the scope.
 func FormatScopeShort(s Scope) string {
 	switch s.Type {
@@ -198,6 +240,20 @@ func getDisplayName(path string) string {
 	return name
 }
 
+func checkText(s Scope) error {
+	fields := []struct{ name, value string }{
+		{"repository path", s.PrimaryPath},
+		{"branch name", s.BranchName},
+		{"worktree id", s.WorktreeID},
+	}
+	for _, f := range fields {
+		if err := entrykey.CheckText(f.value); err != nil {
+			return fmt.Errorf("invalid %s %q: %v", f.name, f.value, err)
+		}
+	}
+	return nil
+}
+
 func ensureNonEmpty(msg, value string) error {
 	if strings.TrimSpace(value) == "" {
 		return errors.New(msg)
@@ -1,8 +1,13 @@
 package scope
 
 import (
+	"context"
+	"errors"
+	"os/exec"
 	"strings"
 	"testing"
+
+	"github.com/choplin/vault.md/internal/git"
 )
 
 func TestValidateScopes(t *testing.T) {
@@ -20,6 +25,9 @@ func TestValidateScopes(t *testing.T) {
 		{"branch reserved", NewBranch("/repo", "global"), true},
 		{"worktree no id", NewWorktree("/repo", "", ""), true},
 		{"worktree reserved", NewWorktree("/repo", "repository", ""), true},
+		{"control character in path", NewRepository("/repo\n"), true},
+		{"control character in branch", NewBranch("/repo", "main\x1b[31m"), true},
+		{"invalid UTF-8 in worktree id", NewWorktree("/repo", "wt-\xff", ""), true},
 	}
 
 	for _, tc := range cases {
@@ -71,3 +79,119 @@ func TestGetScopeStorageKeySanitises(t *testing.T) {
 		t.Fatalf("expected key to be sanitised, got %q", key)
 	}
 }
+
+func TestNormalizeWindowsPath(t *testing.T) {
+	tests := map[string]string{
+		`C:\src\app`:       "C:/src/app",
+		`c:\src\app\`:      "C:/src/app",
+		"C:/src/app":       "C:/src/app",
+		`C:\`:              "C:/",
+		`\\server\share\x`: "//server/share/x",
+		"/home/user/repo/": "/home/user/repo",
+		"relative\\dir":    "relative/dir",
+		"":                 "",
+	}
+	for in, want := range tests {
+		if got := normalizeWindowsPath(in); got != want {
+			t.Errorf("normalizeWindowsPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFormatScopeWithDriveLetter(t *testing.T) {
+	branch := Scope{Type: ScopeBranch, PrimaryPath: "C:/src/app", BranchName: "main"}
+	formatted := FormatScope(branch)
+	sep := strings.LastIndex(formatted, ":")
+	if formatted[:sep] != "C:/src/app" || formatted[sep+1:] != "main" {
+		t.Fatalf("expected the branch after the last colon, got %q", formatted)
+	}
+	if got, want := FormatScopeShort(branch), "app:main"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestParseScopeRoundTrips(t *testing.T) {
+	for _, want := range []Scope{
+		NewGlobal(),
+		NewRepository("/repo"),
+		NewRepository("/home/me@work/repo"),
+		NewRepository("C:/src/app"),
+		NewBranch("/repo", "feature/login"),
+		NewBranch("C:/src/app", "main"),
+		NewWorktree("/repo", "wt-1", ""),
+	} {
+		got, err := ParseScope(FormatScope(want))
+		if err != nil {
+			t.Fatalf("ParseScope(%q) error: %v", FormatScope(want), err)
+		}
+		if got != want {
+			t.Fatalf("ParseScope(%q) = %+v, want %+v", FormatScope(want), got, want)
+		}
+	}
+
+	if _, err := ParseScope("/repo:global"); err == nil {
+		t.Fatalf("expected reserved branch name to be rejected")
+	}
+}
+
+func TestResolveApplicableOrdersMostSpecificFirst(t *testing.T) {
+	outside, err := ResolveApplicable(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatalf("ResolveApplicable error: %v", err)
+	}
+	if len(outside) != 1 || !IsGlobal(outside[0]) {
+		t.Fatalf("expected only global outside a repository, got %+v", outside)
+	}
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-b", "main"},
+		{"-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "--allow-empty", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Skipf("git %s failed: %v: %s", args[0], err, out)
+		}
+	}
+	scopes, err := ResolveApplicable(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("ResolveApplicable error: %v", err)
+	}
+	var types []string
+	for _, s := range scopes {
+		types = append(types, string(s.Type))
+	}
+	if got, want := strings.Join(types, ","), "worktree,branch,repository,global"; got != want {
+		t.Fatalf("expected scopes %s, got %s", want, got)
+	}
+	if scopes[1].BranchName != "main" {
+		t.Fatalf("expected branch main, got %q", scopes[1].BranchName)
+	}
+}
+
+func TestResolveScopeFallsBackWithoutGit(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	t.Cleanup(func() { SetGitFallback(false, nil) })
+
+	var warnings []error
+	SetGitFallback(false, func(err error) { warnings = append(warnings, err) })
+	for range 2 {
+		sc, err := ResolveScope(context.Background(), ScopeOptions{WorkingDir: t.TempDir()})
+		if err != nil || !IsGlobal(sc) {
+			t.Fatalf("ResolveScope = %+v, %v; want global", sc, err)
+		}
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("expected one warning, got %v", warnings)
+	}
+	if _, err := ResolveScope(context.Background(), ScopeOptions{Type: string(ScopeBranch), WorkingDir: t.TempDir()}); err == nil {
+		t.Fatal("expected --scope branch to fail without git")
+	}
+
+	SetGitFallback(true, nil)
+	if _, err := ResolveScope(context.Background(), ScopeOptions{WorkingDir: t.TempDir()}); !errors.Is(err, git.ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable with git required, got %v", err)
+	}
+	if _, err := ResolveApplicable(context.Background(), t.TempDir()); !errors.Is(err, git.ErrUnavailable) {
+		t.Fatalf("expected ResolveApplicable to fail with git required, got %v", err)
+	}
+}
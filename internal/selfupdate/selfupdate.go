@@ -0,0 +1,252 @@
+// Package selfupdate replaces the running vault binary with a release
+// published on GitHub.
+package selfupdate
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is the GitHub API endpoint of the vault.md repository.
+const DefaultAPIURL = "https://api.github.com/repos/choplin/vault.md"
+
+// checksumsAsset is the name goreleaser gives the checksum file.
+const checksumsAsset = "checksums.txt"
+
+// maxBinarySize bounds the size of the binary read from an archive.
+const maxBinarySize = 256 << 20
+
+// ErrNoAsset is returned when a release has no archive for this platform.
+var ErrNoAsset = errors.New("release has no archive for this platform")
+
+// Release is a published release and the assets needed to install it.
+type Release struct {
+	// Version is the release tag without its leading "v".
+	Version   string
+	Archive   Asset
+	Checksums Asset
+}
+
+// Asset is a downloadable file attached to a release.
+type Asset struct {
+	Name string
+	URL  string
+}
+
+// Updater finds and downloads releases.
+type Updater struct {
+	APIURL string
+	Client *http.Client
+	GOOS   string
+	GOARCH string
+}
+
+// New returns an Updater for the platform vault is running on.
+func New() *Updater {
+	return &Updater{
+		APIURL: DefaultAPIURL,
+		Client: &http.Client{Timeout: 2 * time.Minute},
+		GOOS:   runtime.GOOS,
+		GOARCH: runtime.GOARCH,
+	}
+}
+
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// Release looks up the release tagged tag, or the latest release when tag
+// is empty.
+func (u *Updater) Release(ctx context.Context, tag string) (*Release, error) {
+	endpoint := u.APIURL + "/releases/latest"
+	if tag != "" {
+		if !strings.HasPrefix(tag, "v") {
+			tag = "v" + tag
+		}
+		endpoint = u.APIURL + "/releases/tags/" + tag
+	}
+
+	body, err := u.get(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	var gh githubRelease
+	if err := json.Unmarshal(body, &gh); err != nil {
+		return nil, fmt.Errorf("failed to parse release: %w", err)
+	}
+
+	release := &Release{Version: strings.TrimPrefix(gh.TagName, "v")}
+	suffix := "_" + u.archiveSuffix()
+	for _, a := range gh.Assets {
+		switch {
+		case a.Name == checksumsAsset:
+			release.Checksums = Asset{Name: a.Name, URL: a.URL}
+		case strings.HasSuffix(a.Name, suffix):
+			release.Archive = Asset{Name: a.Name, URL: a.URL}
+		}
+	}
+	if release.Archive.URL == "" {
+		return nil, fmt.Errorf("%s %s/%s: %w", gh.TagName, u.GOOS, u.GOARCH, ErrNoAsset)
+	}
+	if release.Checksums.URL == "" {
+		return nil, fmt.Errorf("%s has no %s; refusing to install an unchecked binary", gh.TagName, checksumsAsset)
+	}
+	return release, nil
+}
+
+// archiveSuffix matches the archive names in .goreleaser.yml, such as
+// "Linux_x86_64.tar.gz".
+func (u *Updater) archiveSuffix() string {
+	arch := u.GOARCH
+	switch arch {
+	case "amd64":
+		arch = "x86_64"
+	case "386":
+		arch = "i386"
+	}
+	return strings.ToUpper(u.GOOS[:1]) + u.GOOS[1:] + "_" + arch + ".tar.gz"
+}
+
+// Download fetches the release archive, checks it against the SHA-256
+// checksum published with it, and returns the vault binary inside it. The
+// checksum file comes from the same release, so this catches a corrupted or
+// truncated download but does not prove who built the archive; that rests on
+// the release page being served over HTTPS by GitHub.
+func (u *Updater) Download(ctx context.Context, release *Release) ([]byte, error) {
+	sums, err := u.get(ctx, release.Checksums.URL)
+	if err != nil {
+		return nil, err
+	}
+	want, err := findChecksum(sums, release.Archive.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := u.get(ctx, release.Archive.URL)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(archive)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", release.Archive.Name, want, got)
+	}
+	return extractBinary(archive, "vault")
+}
+
+func (u *Updater) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := u.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// findChecksum returns the checksum listed for name in a sha256sum-style file.
+func findChecksum(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s does not list %s", checksumsAsset, name)
+}
+
+// extractBinary returns the file called name from a .tar.gz archive.
+func extractBinary(archive []byte, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("archive does not contain %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg || filepath.Base(hdr.Name) != name {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, maxBinarySize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxBinarySize {
+			return nil, fmt.Errorf("%s in archive is larger than %d bytes", name, maxBinarySize)
+		}
+		return data, nil
+	}
+}
+
+// Replace installs binary in place of the executable at path. The new file
+// is written next to it and renamed over it, so a failed update leaves the
+// old binary working.
+func Replace(path string, binary []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, ".vault-update-*")
+	if err != nil {
+		return fmt.Errorf("cannot write to %s: %w", dir, err)
+	}
+	tmpPath := tmp.Name()
+	defer func() {
+		_ = os.Remove(tmpPath)
+	}()
+	if _, err := tmp.Write(binary); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	//nolint:gosec // G302: the binary must stay executable for everyone who could run the old one
+	if err := os.Chmod(tmpPath, 0o755); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// PackageManager reports which package manager installed the executable at
+// path, or "" if none is recognised. Such installs should be updated with
+// the package manager instead.
+func PackageManager(path string) string {
+	if strings.Contains(path, "/Cellar/") || strings.Contains(path, "/homebrew/") || strings.Contains(path, "/linuxbrew/") {
+		return "brew"
+	}
+	return ""
+}
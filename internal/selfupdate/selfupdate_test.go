@@ -0,0 +1,148 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func makeArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newReleaseServer(t *testing.T, archive []byte, sum string) *Updater {
+	t.Helper()
+	const archiveName = "vault.md_1.2.3_Linux_x86_64.tar.gz"
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("/releases/latest", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"tag_name":"v1.2.3","assets":[
+			{"name":"vault.md_1.2.3_Darwin_arm64.tar.gz","browser_download_url":"%[1]s/darwin"},
+			{"name":%[2]q,"browser_download_url":"%[1]s/archive"},
+			{"name":"checksums.txt","browser_download_url":"%[1]s/checksums"}]}`, server.URL, archiveName)
+	})
+	mux.HandleFunc("/archive", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archive)
+	})
+	mux.HandleFunc("/checksums", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, "0000  vault.md_1.2.3_Darwin_arm64.tar.gz\n%s  %s\n", sum, archiveName)
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return &Updater{APIURL: server.URL, Client: server.Client(), GOOS: "linux", GOARCH: "amd64"}
+}
+
+func TestDownload(t *testing.T) {
+	archive := makeArchive(t, map[string]string{"README.md": "readme", "vault": "new binary"})
+	digest := sha256.Sum256(archive)
+	u := newReleaseServer(t, archive, hex.EncodeToString(digest[:]))
+
+	ctx := context.Background()
+	release, err := u.Release(ctx, "")
+	if err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if release.Version != "1.2.3" || !strings.HasSuffix(release.Archive.URL, "/archive") {
+		t.Fatalf("Release() = %+v", release)
+	}
+
+	binary, err := u.Download(ctx, release)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if string(binary) != "new binary" {
+		t.Errorf("Download() = %q, want %q", binary, "new binary")
+	}
+}
+
+func TestDownloadRejectsChecksumMismatch(t *testing.T) {
+	archive := makeArchive(t, map[string]string{"vault": "tampered"})
+	u := newReleaseServer(t, archive, strings.Repeat("0", 64))
+
+	ctx := context.Background()
+	release, err := u.Release(ctx, "")
+	if err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if _, err := u.Download(ctx, release); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Download() error = %v, want checksum mismatch", err)
+	}
+}
+
+func TestReleaseWithoutPlatformArchive(t *testing.T) {
+	u := newReleaseServer(t, nil, "")
+	u.GOOS = "windows"
+
+	if _, err := u.Release(context.Background(), ""); !errors.Is(err, ErrNoAsset) {
+		t.Fatalf("Release() error = %v, want ErrNoAsset", err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault")
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Replace(path, []byte("new")); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "new" {
+		t.Errorf("content = %q, want %q", got, "new")
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the binary", len(entries))
+	}
+}
+
+func TestPackageManager(t *testing.T) {
+	tests := map[string]string{
+		"/opt/homebrew/Cellar/vault/1.2.3/bin/vault": "brew",
+		"/home/linuxbrew/.linuxbrew/bin/vault":       "brew",
+		"/usr/local/bin/vault":                       "",
+		"/home/me/go/bin/vault":                      "",
+	}
+	for path, want := range tests {
+		if got := PackageManager(path); got != want {
+			t.Errorf("PackageManager(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/choplin/vault.md/internal/database"
+	sqldb "github.com/choplin/vault.md/internal/database/sqlc"
+)
+
+// DeviceService keeps track of the installations that write to the vault.
+// Devices are registered when a version recorded with their device ID is
+// created; a revoked device stays listed so its history remains attributable.
+type DeviceService struct {
+	ctx *database.Context
+}
+
+// NewDeviceService creates a new DeviceService.
+func NewDeviceService(ctx *database.Context) *DeviceService {
+	return &DeviceService{ctx: ctx}
+}
+
+// List returns every known device in the order it was first seen.
+func (s *DeviceService) List(ctx context.Context) ([]database.DeviceRecord, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]database.DeviceRecord, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, database.DeviceRecordFromRow(row))
+	}
+	return result, nil
+}
+
+// Revoked returns the IDs of revoked devices, sorted.
+func (s *DeviceService) Revoked(ctx context.Context) ([]string, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	return q.ListRevokedDevices(ctx)
+}
+
+// Revoke marks the given devices as revoked. Devices that were never seen
+// are registered as revoked, so that a revocation learned from a remote
+// applies before any of their versions arrive. Revoking a device again keeps
+// the original revocation time.
+func (s *DeviceService) Revoke(ctx context.Context, deviceIDs ...string) error {
+	return s.withTx(ctx, func(txCtx context.Context, q *sqldb.Queries) error {
+		for _, id := range deviceIDs {
+			if err := q.RevokeDevice(txCtx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// VersionDevices returns the device recorded for every version that has one.
+func (s *DeviceService) VersionDevices(ctx context.Context) ([]database.VersionDeviceRecord, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.ListVersionDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]database.VersionDeviceRecord, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, database.VersionDeviceRecord{
+			EntryID:  row.EntryID,
+			Version:  row.Version,
+			DeviceID: row.DeviceID.String,
+		})
+	}
+	return result, nil
+}
+
+func (s *DeviceService) withTx(ctx context.Context, fn func(context.Context, *sqldb.Queries) error) error {
+	if s.ctx == nil || s.ctx.DB == nil {
+		return fmt.Errorf("device service: missing database context")
+	}
+	if s.ctx.InTx() {
+		// Nest in the transaction the caller opened with RunInTx.
+		return s.ctx.RunInTx(ctx, func(txCtx *database.Context) error {
+			return fn(ctx, txCtx.Queries)
+		})
+	}
+
+	return s.ctx.RunWrite(ctx, func() error {
+		tx, err := s.ctx.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		queries := s.ctx.TxQueries(tx)
+		if err := fn(ctx, queries); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (s *DeviceService) queries() (*sqldb.Queries, error) {
+	if s.ctx == nil {
+		return nil, fmt.Errorf("device service: missing database context")
+	}
+	if s.ctx.Queries == nil {
+		if s.ctx.DB == nil {
+			return nil, fmt.Errorf("device service: database handle not initialised")
+		}
+		s.ctx.Queries = sqldb.New(s.ctx.DB)
+	}
+	return s.ctx.Queries, nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+)
+
+func TestDeviceServiceTracksWritersAndRevocations(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeID, err := NewScopeService(dbCtx).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	entrySvc := NewEntryService(dbCtx)
+	writes := []struct {
+		version  int64
+		device   string
+		hostname string
+	}{
+		{1, "laptop", ""},
+		{2, "desktop", "desk"},
+		{3, "laptop", "lap"},
+		{4, "", ""},
+	}
+	for _, w := range writes {
+		record := database.ScopedEntryRecord{
+			ScopeID: scopeID, Key: "notes", Version: w.version, FilePath: "file", Hash: "hash",
+			Provenance: &database.VersionProvenance{Tool: "cli", DeviceID: w.device, Hostname: w.hostname},
+		}
+		if _, err := entrySvc.Create(ctx, record); err != nil {
+			t.Fatalf("Create v%d failed: %v", w.version, err)
+		}
+	}
+
+	svc := NewDeviceService(dbCtx)
+	devices, err := svc.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("expected 2 devices, got %#v", devices)
+	}
+	byID := map[string]database.DeviceRecord{}
+	for _, d := range devices {
+		byID[d.DeviceID] = d
+	}
+	if d := byID["laptop"]; d.VersionCount != 2 || d.Hostname != "lap" || d.RevokedAt != nil || d.LastWriteAt.IsZero() {
+		t.Fatalf("unexpected laptop record: %#v", d)
+	}
+	if d := byID["desktop"]; d.VersionCount != 1 || d.Hostname != "desk" {
+		t.Fatalf("unexpected desktop record: %#v", d)
+	}
+
+	writers, err := svc.VersionDevices(ctx)
+	if err != nil {
+		t.Fatalf("VersionDevices failed: %v", err)
+	}
+	if len(writers) != 3 {
+		t.Fatalf("expected 3 versions with a device, got %#v", writers)
+	}
+
+	// Revoking an unknown device registers it, so that it is already
+	// revoked when its versions arrive.
+	if err := svc.Revoke(ctx, "desktop", "stolen"); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if err := svc.Revoke(ctx, "desktop"); err != nil {
+		t.Fatalf("Revoke again failed: %v", err)
+	}
+	revoked, err := svc.Revoked(ctx)
+	if err != nil {
+		t.Fatalf("Revoked failed: %v", err)
+	}
+	if len(revoked) != 2 || revoked[0] != "desktop" || revoked[1] != "stolen" {
+		t.Fatalf("unexpected revoked devices: %v", revoked)
+	}
+
+	devices, err = svc.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	for _, d := range devices {
+		if (d.DeviceID == "laptop") != (d.RevokedAt == nil) {
+			t.Fatalf("unexpected revocation state for %s: %#v", d.DeviceID, d.RevokedAt)
+		}
+	}
+}
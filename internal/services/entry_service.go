@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/choplin/vault.md/internal/database"
 	sqldb "github.com/choplin/vault.md/internal/database/sqlc"
@@ -13,6 +14,18 @@ import (
 // ErrNotFound is returned when a requested entry is not found.
 var ErrNotFound = errors.New("entry not found")
 
+// ErrVersionConflict is returned by Create when the version being written
+// already exists, typically because a concurrent writer got there first.
+var ErrVersionConflict = errors.New("version already exists")
+
+// ErrIdempotencyKeyUsed is returned by Create when another write has already
+// been recorded under the same idempotency key.
+var ErrIdempotencyKeyUsed = errors.New("idempotency key already used")
+
+// IdempotencyKeyTTL is how long an idempotency key keeps identifying the
+// version it created. Later writes with the same key are treated as new.
+const IdempotencyKeyTTL = 24 * time.Hour
+
 // EntryService exposes high-level operations for scoped entries using sqlc-generated queries.
 type EntryService struct {
 	ctx *database.Context
@@ -43,7 +56,7 @@ func (s *EntryService) GetLatest(ctx context.Context, scopeID int64, key string)
 		return nil, err
 	}
 
-	record := database.ScopedEntryRecordFromRow(row.EntryID, row.ScopeID, row.Key, row.EntryCreatedAt, row.IsArchived, row.Version, row.FilePath, row.Hash, row.Description)
+	record := database.ScopedEntryRecordFromRow(row.EntryID, row.ScopeID, row.Key, row.EntryCreatedAt, row.IsArchived, row.Version, row.FilePath, row.Hash, row.Description, row.VersionCreatedAt, row.Size, row.Language, row.Summary, row.LastReadAt, row.ReadCount)
 	return &record, nil
 }
 
@@ -66,7 +79,7 @@ func (s *EntryService) GetByVersion(ctx context.Context, scopeID int64, key stri
 		return nil, err
 	}
 
-	record := database.ScopedEntryRecordFromRow(row.EntryID, row.ScopeID, row.Key, row.EntryCreatedAt, row.IsArchived, row.Version, row.FilePath, row.Hash, row.Description)
+	record := database.ScopedEntryRecordFromRow(row.EntryID, row.ScopeID, row.Key, row.EntryCreatedAt, row.IsArchived, row.Version, row.FilePath, row.Hash, row.Description, row.VersionCreatedAt, row.Size, row.Language, row.Summary, row.LastReadAt, row.ReadCount)
 	return &record, nil
 }
 
@@ -98,27 +111,69 @@ func (s *EntryService) GetNextVersion(ctx context.Context, scopeID int64, key st
 // Create persists a new entry version, provisioning the entry/status rows as needed.
 func (s *EntryService) Create(ctx context.Context, entry database.ScopedEntryRecord) (versionID int64, err error) {
 	err = s.withTx(ctx, func(txCtx context.Context, q *sqldb.Queries) error {
-		row, err := q.FindEntryByScopeAndKey(txCtx, sqldb.FindEntryByScopeAndKeyParams{
+		versionID, err = createVersion(txCtx, q, entry)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return versionID, nil
+}
+
+// CreateBatch persists several versions in a single transaction, so either
+// all of them are stored or none is. Versions of the same key must be given
+// in increasing order.
+func (s *EntryService) CreateBatch(ctx context.Context, entries []database.ScopedEntryRecord) error {
+	return s.withTx(ctx, func(txCtx context.Context, q *sqldb.Queries) error {
+		for _, entry := range entries {
+			if _, err := createVersion(txCtx, q, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func createVersion(txCtx context.Context, q *sqldb.Queries, entry database.ScopedEntryRecord) (versionID int64, err error) {
+	row, err := q.FindEntryByScopeAndKey(txCtx, sqldb.FindEntryByScopeAndKeyParams{
+		ScopeID: entry.ScopeID,
+		Key:     entry.Key,
+	})
+
+	var entryID int64
+	switch {
+	case err == nil:
+		entryID = row.ID
+	case errors.Is(err, sql.ErrNoRows):
+		res, err := q.InsertEntry(txCtx, sqldb.InsertEntryParams{
 			ScopeID: entry.ScopeID,
 			Key:     entry.Key,
 		})
+		if err != nil {
+			return 0, err
+		}
+		entryID, err = res.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		isArchived := sql.NullInt64{Int64: 0, Valid: true}
+		if entry.IsArchived {
+			isArchived.Int64 = 1
+		}
+		if err := q.InsertEntryStatus(txCtx, sqldb.InsertEntryStatusParams{
+			EntryID:        entryID,
+			IsArchived:     isArchived,
+			CurrentVersion: sql.NullInt64{Int64: entry.Version, Valid: true},
+		}); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, err
+	}
 
-		var entryID int64
-		switch {
-		case err == nil:
-			entryID = row.ID
-		case errors.Is(err, sql.ErrNoRows):
-			res, err := q.InsertEntry(txCtx, sqldb.InsertEntryParams{
-				ScopeID: entry.ScopeID,
-				Key:     entry.Key,
-			})
-			if err != nil {
-				return err
-			}
-			entryID, err = res.LastInsertId()
-			if err != nil {
-				return err
-			}
+	if err == nil {
+		_, err = q.FindEntryStatusByEntryID(txCtx, entryID)
+		if errors.Is(err, sql.ErrNoRows) {
 			isArchived := sql.NullInt64{Int64: 0, Valid: true}
 			if entry.IsArchived {
 				isArchived.Int64 = 1
@@ -128,72 +183,164 @@ func (s *EntryService) Create(ctx context.Context, entry database.ScopedEntryRec
 				IsArchived:     isArchived,
 				CurrentVersion: sql.NullInt64{Int64: entry.Version, Valid: true},
 			}); err != nil {
-				return err
+				return 0, err
 			}
-		default:
-			return err
+		} else if err != nil {
+			return 0, err
 		}
+	}
 
-		if err == nil {
-			_, err = q.FindEntryStatusByEntryID(txCtx, entryID)
-			if errors.Is(err, sql.ErrNoRows) {
-				isArchived := sql.NullInt64{Int64: 0, Valid: true}
-				if entry.IsArchived {
-					isArchived.Int64 = 1
-				}
-				if err := q.InsertEntryStatus(txCtx, sqldb.InsertEntryStatusParams{
-					EntryID:        entryID,
-					IsArchived:     isArchived,
-					CurrentVersion: sql.NullInt64{Int64: entry.Version, Valid: true},
-				}); err != nil {
-					return err
-				}
-			} else if err != nil {
-				return err
-			}
+	// The version was chosen before the transaction started; make sure no
+	// concurrent writer has claimed it since.
+	maxVersion, err := q.MaxVersionForEntry(txCtx, entryID)
+	if err != nil {
+		return 0, err
+	}
+	if entry.Version <= maxVersion {
+		return 0, fmt.Errorf("%w: %s version %d (latest is %d)", ErrVersionConflict, entry.Key, entry.Version, maxVersion)
+	}
+
+	var description sql.NullString
+	if entry.Description != nil {
+		description = sql.NullString{String: *entry.Description, Valid: true}
+	}
+
+	res, err := q.InsertVersion(txCtx, sqldb.InsertVersionParams{
+		EntryID:     entryID,
+		Version:     entry.Version,
+		FilePath:    entry.FilePath,
+		Hash:        entry.Hash,
+		Description: description,
+		Size:        sql.NullInt64{Int64: entry.Size, Valid: true},
+		Language:    sql.NullString{String: entry.Language, Valid: entry.Language != ""},
+	})
+	if database.IsUniqueViolation(err) {
+		// UNIQUE (entry_id, version) backs up the check above.
+		return 0, fmt.Errorf("%w: %s version %d", ErrVersionConflict, entry.Key, entry.Version)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if versionID, err = res.LastInsertId(); err != nil {
+		return 0, err
+	}
+
+	// Imported versions keep their original write time.
+	if !entry.UpdatedAt.IsZero() {
+		if err := q.UpdateVersionCreatedAt(txCtx, sqldb.UpdateVersionCreatedAtParams{
+			CreatedAt: entry.UpdatedAt.UTC().Format(database.TimestampLayout),
+			ID:        versionID,
+		}); err != nil {
+			return 0, err
 		}
+	}
 
-		var description sql.NullString
-		if entry.Description != nil {
-			description = sql.NullString{String: *entry.Description, Valid: true}
+	if entry.Provenance != nil {
+		if err := q.InsertVersionProvenance(txCtx, database.VersionProvenanceInsertParams(versionID, *entry.Provenance)); err != nil {
+			return 0, err
 		}
+		if entry.Provenance.DeviceID != "" {
+			if err := q.UpsertDevice(txCtx, sqldb.UpsertDeviceParams{
+				DeviceID: entry.Provenance.DeviceID,
+				Hostname: sql.NullString{String: entry.Provenance.Hostname, Valid: entry.Provenance.Hostname != ""},
+			}); err != nil {
+				return 0, err
+			}
+		}
+	}
 
-		res, err := q.InsertVersion(txCtx, sqldb.InsertVersionParams{
-			EntryID:     entryID,
-			Version:     entry.Version,
-			FilePath:    entry.FilePath,
-			Hash:        entry.Hash,
-			Description: description,
+	if entry.IdempotencyKey != "" {
+		// Expired keys are dropped so that they can be reused.
+		cutoff := time.Now().Add(-IdempotencyKeyTTL).UTC().Format(database.TimestampLayout)
+		if _, err := q.DeleteIdempotencyKeysBefore(txCtx, cutoff); err != nil {
+			return 0, err
+		}
+		err := q.InsertIdempotencyKey(txCtx, sqldb.InsertIdempotencyKeyParams{
+			Token:     entry.IdempotencyKey,
+			VersionID: versionID,
 		})
-		if err != nil {
-			return err
+		if database.IsUniqueViolation(err) {
+			return 0, fmt.Errorf("%w: %s", ErrIdempotencyKeyUsed, entry.IdempotencyKey)
 		}
-		if versionID, err = res.LastInsertId(); err != nil {
-			return err
+		if err != nil {
+			return 0, err
 		}
+	}
 
-		return q.UpdateEntryStatusCurrentVersion(txCtx, sqldb.UpdateEntryStatusCurrentVersionParams{
-			CurrentVersion: sql.NullInt64{Int64: entry.Version, Valid: true},
-			EntryID:        entryID,
-		})
-	})
-	if err != nil {
+	if err := q.UpdateEntryStatusCurrentVersion(txCtx, sqldb.UpdateEntryStatusCurrentVersionParams{
+		CurrentVersion: sql.NullInt64{Int64: entry.Version, Valid: true},
+		EntryID:        entryID,
+	}); err != nil {
 		return 0, err
 	}
 	return versionID, nil
 }
 
+// SortField names a column List results can be ordered by.
+type SortField string
+
+// Sort fields supported by List.
+const (
+	SortByKey     SortField = "key"
+	SortByCreated SortField = "created"
+	SortByUpdated SortField = "updated"
+	SortByVersion SortField = "version"
+	SortBySize    SortField = "size"
+)
+
+// ParseSortField validates a user-supplied sort field. An empty value selects SortByKey.
+func ParseSortField(value string) (SortField, error) {
+	switch SortField(value) {
+	case "":
+		return SortByKey, nil
+	case SortByKey, SortByCreated, SortByUpdated, SortByVersion, SortBySize:
+		return SortField(value), nil
+	default:
+		return "", fmt.Errorf("invalid sort field: %s (valid values: key, created, updated, version, size)", value)
+	}
+}
+
+// ListFilter narrows and orders List results. Zero values disable the
+// corresponding filter and keep the default key order.
+type ListFilter struct {
+	// Since keeps versions created at or after this instant.
+	Since time.Time
+	// Until keeps versions created at or before this instant.
+	Until time.Time
+	// DescriptionContains keeps versions whose description contains this text (case-insensitive).
+	DescriptionContains string
+	// Language keeps versions with this language hint.
+	Language string
+	// SortBy selects the primary ordering; ties fall back to key then newest version.
+	SortBy SortField
+	// Reverse flips the direction of SortBy.
+	Reverse bool
+}
+
 // List retrieves entries from the vault with specified filters.
 func (s *EntryService) List(ctx context.Context, scopeID int64, includeArchived, allVersions bool) ([]database.ScopedEntryRecord, error) {
+	return s.ListWithFilter(ctx, scopeID, includeArchived, allVersions, ListFilter{})
+}
+
+// ListWithFilter retrieves entries like List, additionally applying filter in SQL.
+func (s *EntryService) ListWithFilter(ctx context.Context, scopeID int64, includeArchived, allVersions bool, filter ListFilter) ([]database.ScopedEntryRecord, error) {
 	q, err := s.queries()
 	if err != nil {
 		return nil, err
 	}
 
+	args := newListArgs(filter)
+
 	if allVersions {
 		rows, err := q.ListScopedEntriesAllVersions(ctx, sqldb.ListScopedEntriesAllVersionsParams{
-			ScopeID:         scopeID,
-			IncludeArchived: includeArchived,
+			ScopeID:             scopeID,
+			IncludeArchived:     includeArchived,
+			Since:               args.since,
+			Until:               args.until,
+			DescriptionContains: args.descriptionContains,
+			Language:            args.language,
+			SortBy:              args.sortBy,
+			SortDesc:            args.sortDesc,
 		})
 		if err != nil {
 			return nil, err
@@ -201,14 +348,20 @@ func (s *EntryService) List(ctx context.Context, scopeID int64, includeArchived,
 
 		result := make([]database.ScopedEntryRecord, 0, len(rows))
 		for _, row := range rows {
-			result = append(result, database.ScopedEntryRecordFromRow(row.EntryID, row.ScopeID, row.Key, row.EntryCreatedAt, row.IsArchived, row.Version, row.FilePath, row.Hash, row.Description))
+			result = append(result, database.ScopedEntryRecordFromRow(row.EntryID, row.ScopeID, row.Key, row.EntryCreatedAt, row.IsArchived, row.Version, row.FilePath, row.Hash, row.Description, row.VersionCreatedAt, row.Size, row.Language, row.Summary, row.LastReadAt, row.ReadCount))
 		}
 		return result, nil
 	}
 
 	rows, err := q.ListScopedEntriesLatest(ctx, sqldb.ListScopedEntriesLatestParams{
-		ScopeID:         scopeID,
-		IncludeArchived: includeArchived,
+		ScopeID:             scopeID,
+		IncludeArchived:     includeArchived,
+		Since:               args.since,
+		Until:               args.until,
+		DescriptionContains: args.descriptionContains,
+		Language:            args.language,
+		SortBy:              args.sortBy,
+		SortDesc:            args.sortDesc,
 	})
 	if err != nil {
 		return nil, err
@@ -216,11 +369,95 @@ func (s *EntryService) List(ctx context.Context, scopeID int64, includeArchived,
 
 	result := make([]database.ScopedEntryRecord, 0, len(rows))
 	for _, row := range rows {
-		result = append(result, database.ScopedEntryRecordFromRow(row.EntryID, row.ScopeID, row.Key, row.EntryCreatedAt, row.IsArchived, row.Version, row.FilePath, row.Hash, row.Description))
+		result = append(result, database.ScopedEntryRecordFromRow(row.EntryID, row.ScopeID, row.Key, row.EntryCreatedAt, row.IsArchived, row.Version, row.FilePath, row.Hash, row.Description, row.VersionCreatedAt, row.Size, row.Language, row.Summary, row.LastReadAt, row.ReadCount))
 	}
 	return result, nil
 }
 
+// EachWithFilter streams the same rows as ListWithFilter, calling fn for each
+// record as it is read from the cursor. Unlike ListWithFilter it never holds
+// the full result set in memory, which matters for --all-versions on large
+// vaults. Returning an error from fn stops iteration.
+func (s *EntryService) EachWithFilter(ctx context.Context, scopeID int64, includeArchived, allVersions bool, filter ListFilter, fn func(database.ScopedEntryRecord) error) error {
+	if s.ctx == nil || s.ctx.DB == nil {
+		return fmt.Errorf("entry service: database handle not initialised")
+	}
+
+	// sqlc cannot generate iterators for database/sql, so run the generated
+	// statement directly. The latest-version query additionally selects
+	// es.current_version, which equals v.version there.
+	query := sqldb.ListScopedEntriesLatest
+	if allVersions {
+		query = sqldb.ListScopedEntriesAllVersions
+	}
+
+	args := newListArgs(filter)
+	rows, err := s.ctx.QueryContext(ctx, query,
+		scopeID,
+		includeArchived,
+		args.since,
+		args.until,
+		args.descriptionContains,
+		args.language,
+		args.sortBy,
+		args.sortDesc,
+	)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		var (
+			row            sqldb.ListScopedEntriesAllVersionsRow
+			currentVersion sql.NullInt64
+		)
+		dest := []any{&row.EntryID, &row.ScopeID, &row.Key, &row.EntryCreatedAt, &row.IsArchived}
+		if !allVersions {
+			dest = append(dest, &currentVersion)
+		}
+		dest = append(dest, &row.Version, &row.FilePath, &row.Hash, &row.Description, &row.VersionCreatedAt, &row.Size, &row.Language, &row.Summary, &row.LastReadAt, &row.ReadCount)
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		if err := fn(database.ScopedEntryRecordFromRow(row.EntryID, row.ScopeID, row.Key, row.EntryCreatedAt, row.IsArchived, row.Version, row.FilePath, row.Hash, row.Description, row.VersionCreatedAt, row.Size, row.Language, row.Summary, row.LastReadAt, row.ReadCount)); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// listArgs holds the SQL parameters derived from a ListFilter.
+type listArgs struct {
+	since               sql.NullString
+	until               sql.NullString
+	descriptionContains sql.NullString
+	language            sql.NullString
+	sortBy              string
+	sortDesc            bool
+}
+
+func newListArgs(filter ListFilter) listArgs {
+	args := listArgs{
+		since:    database.NullTimestamp(filter.Since),
+		until:    database.NullTimestamp(filter.Until),
+		sortBy:   string(filter.SortBy),
+		sortDesc: filter.Reverse,
+	}
+	if filter.DescriptionContains != "" {
+		args.descriptionContains = sql.NullString{String: filter.DescriptionContains, Valid: true}
+	}
+	if filter.Language != "" {
+		args.language = sql.NullString{String: filter.Language, Valid: true}
+	}
+	if args.sortBy == "" {
+		args.sortBy = string(SortByKey)
+	}
+	return args
+}
+
 // DeleteVersion deletes a specific version of an entry and returns true if deleted.
 func (s *EntryService) DeleteVersion(ctx context.Context, scopeID int64, key string, version int64) (bool, error) {
 	var deleted bool
@@ -237,6 +474,14 @@ func (s *EntryService) DeleteVersion(ctx context.Context, scopeID int64, key str
 			return err
 		}
 
+		// Queue the file in the same transaction, so it is not forgotten if
+		// removing it fails after the row is gone.
+		if err := q.QueueVersionFileForDeletion(txCtx, sqldb.QueueVersionFileForDeletionParams{
+			EntryID: row.ID,
+			Version: version,
+		}); err != nil {
+			return err
+		}
 		affected, err := q.DeleteVersionByEntryAndVersion(txCtx, sqldb.DeleteVersionByEntryAndVersionParams{
 			EntryID: row.ID,
 			Version: version,
@@ -267,6 +512,29 @@ func (s *EntryService) DeleteVersion(ctx context.Context, scopeID int64, key str
 	return deleted, nil
 }
 
+// ListSuperseded returns the versions in the scope that are not the latest
+// of their entry, oldest first.
+func (s *EntryService) ListSuperseded(ctx context.Context, scopeID int64) ([]database.SupersededVersion, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.ListSupersededVersions(ctx, scopeID)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]database.SupersededVersion, 0, len(rows))
+	for _, row := range rows {
+		versions = append(versions, database.SupersededVersion{
+			Key:      row.Key,
+			Version:  row.Version,
+			FilePath: row.FilePath,
+			Size:     row.Size.Int64,
+		})
+	}
+	return versions, nil
+}
+
 // DeleteAll deletes all versions of an entry and returns true if deleted.
 func (s *EntryService) DeleteAll(ctx context.Context, scopeID int64, key string) (bool, error) {
 	var deleted bool
@@ -283,6 +551,9 @@ func (s *EntryService) DeleteAll(ctx context.Context, scopeID int64, key string)
 			return err
 		}
 
+		if err := q.QueueEntryFilesForDeletion(txCtx, row.ID); err != nil {
+			return err
+		}
 		if _, err := q.DeleteVersionsByEntry(txCtx, row.ID); err != nil {
 			return err
 		}
@@ -343,6 +614,23 @@ func (s *EntryService) Archive(ctx context.Context, scopeID int64, key string) (
 	return affected > 0, nil
 }
 
+// RecordRead notes a read of the entry: its last read time becomes now and
+// its read count goes up by one.
+func (s *EntryService) RecordRead(ctx context.Context, entryID int64) error {
+	q, err := s.queries()
+	if err != nil {
+		return err
+	}
+	affected, err := q.RecordEntryRead(ctx, entryID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 // Restore unarchives an entry and returns true if restored.
 func (s *EntryService) Restore(ctx context.Context, scopeID int64, key string) (bool, error) {
 	q, err := s.queries()
@@ -403,29 +691,301 @@ func (s *EntryService) GetEntryByKey(ctx context.Context, scopeID int64, key str
 	return &record, nil
 }
 
-func (s *EntryService) withTx(ctx context.Context, fn func(context.Context, *sqldb.Queries) error) error {
-	if s.ctx == nil || s.ctx.DB == nil {
-		return fmt.Errorf("entry service: missing database context")
+// ListVersions returns every version of key, newest first.
+func (s *EntryService) ListVersions(ctx context.Context, scopeID int64, key string) ([]database.VersionRecord, error) {
+	entry, err := s.GetEntryByKey(ctx, scopeID, key)
+	if err != nil {
+		return nil, err
 	}
 
-	tx, err := s.ctx.DB.BeginTx(ctx, nil)
+	q, err := s.queries()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	rows, err := q.ListVersionsByEntry(ctx, entry.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	queries := sqldb.New(tx)
+	result := make([]database.VersionRecord, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, database.VersionRecordFromRow(row))
+	}
+	return result, nil
+}
+
+// ListHistory returns every version of key, newest first, together with the
+// provenance recorded when each version was written.
+func (s *EntryService) ListHistory(ctx context.Context, scopeID int64, key string) ([]database.VersionHistoryRecord, error) {
+	versions, err := s.ListVersions(ctx, scopeID, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return []database.VersionHistoryRecord{}, nil
+	}
+
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.ListVersionProvenanceByEntry(ctx, versions[0].EntryID)
+	if err != nil {
+		return nil, err
+	}
+	provenance := make(map[int64]database.VersionProvenance, len(rows))
+	for _, row := range rows {
+		provenance[row.VersionID] = database.VersionProvenanceFromRow(row)
+	}
+
+	approvalRows, err := q.ListVersionApprovalsByEntry(ctx, versions[0].EntryID)
+	if err != nil {
+		return nil, err
+	}
+	approvals := make(map[int64]database.VersionApproval, len(approvalRows))
+	for _, row := range approvalRows {
+		approvals[row.VersionID] = database.VersionApproval{
+			ApprovedBy: row.ApprovedBy.String,
+			ApprovedAt: row.ApprovedAt.Time,
+		}
+	}
+
+	summaryRows, err := q.ListVersionSummariesByEntry(ctx, versions[0].EntryID)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make(map[int64]string, len(summaryRows))
+	for _, row := range summaryRows {
+		summaries[row.VersionID] = row.Summary
+	}
+
+	result := make([]database.VersionHistoryRecord, 0, len(versions))
+	for _, v := range versions {
+		record := database.VersionHistoryRecord{VersionRecord: v, Summary: summaries[v.ID]}
+		if p, ok := provenance[v.ID]; ok {
+			record.Provenance = &p
+		}
+		if a, ok := approvals[v.ID]; ok {
+			record.Approval = &a
+		}
+		result = append(result, record)
+	}
+	return result, nil
+}
 
-	if err := fn(ctx, queries); err != nil {
-		_ = tx.Rollback()
+// Approve marks a version of key as approved by approvedBy. It returns false
+// when the version was already approved, keeping the original approval, and
+// ErrNotFound when the version does not exist.
+func (s *EntryService) Approve(ctx context.Context, scopeID int64, key string, version int64, approvedBy string) (bool, error) {
+	approved := false
+	err := s.withTx(ctx, func(txCtx context.Context, q *sqldb.Queries) error {
+		if _, err := q.GetScopedEntryByVersion(txCtx, sqldb.GetScopedEntryByVersionParams{
+			ScopeID: scopeID,
+			Key:     key,
+			Version: version,
+		}); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		affected, err := q.ApproveVersion(txCtx, sqldb.ApproveVersionParams{
+			ApprovedBy: approvedBy,
+			ScopeID:    scopeID,
+			Key:        key,
+			Version:    version,
+		})
+		approved = affected > 0
+		return err
+	})
+	return approved, err
+}
+
+// ImportApproval records approval, with its original time, for a version of
+// key, as carried by a snapshot. Like Approve, it returns false when the
+// version was already approved and ErrNotFound when it does not exist.
+func (s *EntryService) ImportApproval(ctx context.Context, scopeID int64, key string, version int64, approval database.VersionApproval) (bool, error) {
+	approved := false
+	err := s.withTx(ctx, func(txCtx context.Context, q *sqldb.Queries) error {
+		if _, err := q.GetScopedEntryByVersion(txCtx, sqldb.GetScopedEntryByVersionParams{
+			ScopeID: scopeID,
+			Key:     key,
+			Version: version,
+		}); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		affected, err := q.ImportVersionApproval(txCtx, sqldb.ImportVersionApprovalParams{
+			ApprovedBy: approval.ApprovedBy,
+			ApprovedAt: approval.ApprovedAt.UTC().Format(database.TimestampLayout),
+			ScopeID:    scopeID,
+			Key:        key,
+			Version:    version,
+		})
+		approved = affected > 0
+		return err
+	})
+	return approved, err
+}
+
+// SetSummary stores summary as the summary of a version of key, replacing
+// any earlier one. summarizer names the command that produced it. It
+// returns ErrNotFound when the version does not exist.
+func (s *EntryService) SetSummary(ctx context.Context, scopeID int64, key string, version int64, summary, summarizer string) error {
+	q, err := s.queries()
+	if err != nil {
 		return err
 	}
+	affected, err := q.SetVersionSummary(ctx, sqldb.SetVersionSummaryParams{
+		Summary:    summary,
+		Summarizer: sql.NullString{String: summarizer, Valid: summarizer != ""},
+		ScopeID:    scopeID,
+		Key:        key,
+		Version:    version,
+	})
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// LatestApprovedVersion returns the highest approved version of key, or
+// ErrNotFound when no version has been approved.
+func (s *EntryService) LatestApprovedVersion(ctx context.Context, scopeID int64, key string) (int64, error) {
+	q, err := s.queries()
+	if err != nil {
+		return 0, err
+	}
+	version, err := q.GetLatestApprovedVersion(ctx, sqldb.GetLatestApprovedVersionParams{
+		ScopeID: scopeID,
+		Key:     key,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return version, err
+}
 
-	if err := tx.Commit(); err != nil {
-		_ = tx.Rollback()
+// FindByIdempotencyKey returns the version created under token within
+// IdempotencyKeyTTL, or ErrNotFound.
+func (s *EntryService) FindByIdempotencyKey(ctx context.Context, token string) (*database.IdempotentWrite, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	row, err := q.FindVersionByIdempotencyKey(ctx, sqldb.FindVersionByIdempotencyKeyParams{
+		Token: token,
+		Since: time.Now().Add(-IdempotencyKeyTTL).UTC().Format(database.TimestampLayout),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &database.IdempotentWrite{
+		ScopeID:  row.ScopeID,
+		Key:      row.Key,
+		Version:  row.Version,
+		FilePath: row.FilePath,
+		Hash:     row.Hash,
+	}, nil
+}
+
+// GetVersionSummary aggregates version count, total size, and first/last
+// write times across all versions of an entry.
+func (s *EntryService) GetVersionSummary(ctx context.Context, entryID int64) (*database.EntryVersionSummary, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	row, err := q.GetVersionSummaryByEntry(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+	summary := database.EntryVersionSummaryFromRow(row)
+	return &summary, nil
+}
+
+// GetVerification returns the file stamp recorded when the version was last
+// verified, or nil if it has never been verified.
+func (s *EntryService) GetVerification(ctx context.Context, entryID, version int64) (*database.VersionVerification, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	row, err := q.GetVersionVerification(ctx, sqldb.GetVersionVerificationParams{
+		EntryID: entryID,
+		Version: version,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if !row.VerifiedMtime.Valid || !row.VerifiedSize.Valid {
+		return nil, nil
+	}
+	return &database.VersionVerification{
+		MtimeNs: row.VerifiedMtime.Int64,
+		Size:    row.VerifiedSize.Int64,
+	}, nil
+}
+
+// RecordVerification stores the file stamp observed after a successful
+// verification so later reads can skip re-hashing an unchanged file.
+func (s *EntryService) RecordVerification(ctx context.Context, entryID, version int64, stamp database.VersionVerification) error {
+	q, err := s.queries()
+	if err != nil {
 		return err
 	}
+	return q.UpdateVersionVerification(ctx, sqldb.UpdateVersionVerificationParams{
+		VerifiedMtime: sql.NullInt64{Int64: stamp.MtimeNs, Valid: true},
+		VerifiedSize:  sql.NullInt64{Int64: stamp.Size, Valid: true},
+		EntryID:       entryID,
+		Version:       version,
+	})
+}
 
-	return nil
+func (s *EntryService) withTx(ctx context.Context, fn func(context.Context, *sqldb.Queries) error) error {
+	if s.ctx == nil || s.ctx.DB == nil {
+		return fmt.Errorf("entry service: missing database context")
+	}
+	if s.ctx.InTx() {
+		// Nest in the transaction the caller opened with RunInTx.
+		return s.ctx.RunInTx(ctx, func(txCtx *database.Context) error {
+			return fn(ctx, txCtx.Queries)
+		})
+	}
+
+	return s.ctx.RunWrite(ctx, func() error {
+		tx, err := s.ctx.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		queries := s.ctx.TxQueries(tx)
+
+		if err := fn(ctx, queries); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		return nil
+	})
 }
 
 func (s *EntryService) queries() (*sqldb.Queries, error) {
@@ -3,7 +3,11 @@ package services
 import (
 	"context"
 	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/choplin/vault.md/internal/database"
 	"github.com/choplin/vault.md/internal/scope"
@@ -125,3 +129,791 @@ func TestEntryServiceDeleteAndArchive(t *testing.T) {
 		t.Fatalf("expected ErrNotFound after delete, got err=%v latest=%#v", err, latest)
 	}
 }
+
+func TestEntryServiceListWithFilter(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeSvc := NewScopeService(dbCtx)
+	scopeID, err := scopeSvc.GetOrCreate(ctx, scope.NewRepository("/filter-repo"))
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	svc := NewEntryService(dbCtx)
+
+	planning := "Planning Session notes"
+	review := "code review"
+	for _, rec := range []database.ScopedEntryRecord{
+		{ScopeID: scopeID, Key: "old", Version: 1, FilePath: "f1", Hash: "h1", Description: &planning, Language: "markdown"},
+		{ScopeID: scopeID, Key: "new", Version: 1, FilePath: "f2", Hash: "h2", Description: &review, Language: "go"},
+	} {
+		if _, err := svc.Create(ctx, rec); err != nil {
+			t.Fatalf("Create %s failed: %v", rec.Key, err)
+		}
+	}
+
+	if _, err := dbCtx.DB.Exec(`UPDATE versions SET created_at = '2024-01-10 09:00:00' WHERE file_path = 'f1'`); err != nil {
+		t.Fatalf("failed to backdate version: %v", err)
+	}
+	if _, err := dbCtx.DB.Exec(`UPDATE versions SET created_at = '2024-03-05 18:30:00' WHERE file_path = 'f2'`); err != nil {
+		t.Fatalf("failed to backdate version: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		filter ListFilter
+		want   []string
+	}{
+		{"no filter", ListFilter{}, []string{"new", "old"}},
+		{"since", ListFilter{Since: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}, []string{"new"}},
+		{"until", ListFilter{Until: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}, []string{"old"}},
+		{"inclusive bounds", ListFilter{Since: time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC), Until: time.Date(2024, 3, 5, 18, 30, 0, 0, time.UTC)}, []string{"new", "old"}},
+		{"description", ListFilter{DescriptionContains: "session"}, []string{"old"}},
+		{"description miss", ListFilter{DescriptionContains: "deploy"}, nil},
+		{"language", ListFilter{Language: "go"}, []string{"new"}},
+		{"language miss", ListFilter{Language: "python"}, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, allVersions := range []bool{false, true} {
+				records, err := svc.ListWithFilter(ctx, scopeID, false, allVersions, tc.filter)
+				if err != nil {
+					t.Fatalf("ListWithFilter failed: %v", err)
+				}
+				var keys []string
+				for _, r := range records {
+					keys = append(keys, r.Key)
+				}
+				if strings.Join(keys, ",") != strings.Join(tc.want, ",") {
+					t.Fatalf("allVersions=%v: expected keys %v, got %v", allVersions, tc.want, keys)
+				}
+
+				keys = nil
+				err = svc.EachWithFilter(ctx, scopeID, false, allVersions, tc.filter, func(r database.ScopedEntryRecord) error {
+					keys = append(keys, r.Key)
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("EachWithFilter failed: %v", err)
+				}
+				if strings.Join(keys, ",") != strings.Join(tc.want, ",") {
+					t.Fatalf("EachWithFilter allVersions=%v: expected keys %v, got %v", allVersions, tc.want, keys)
+				}
+			}
+		})
+	}
+}
+
+func TestEntryServiceListSort(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeSvc := NewScopeService(dbCtx)
+	scopeID, err := scopeSvc.GetOrCreate(ctx, scope.NewRepository("/sort-repo"))
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	svc := NewEntryService(dbCtx)
+	for _, rec := range []database.ScopedEntryRecord{
+		{ScopeID: scopeID, Key: "alpha", Version: 1, FilePath: "a1", Hash: "h", Size: 300},
+		{ScopeID: scopeID, Key: "beta", Version: 1, FilePath: "b1", Hash: "h", Size: 10},
+		{ScopeID: scopeID, Key: "beta", Version: 2, FilePath: "b2", Hash: "h", Size: 20},
+		{ScopeID: scopeID, Key: "gamma", Version: 1, FilePath: "g1", Hash: "h", Size: 200},
+	} {
+		if _, err := svc.Create(ctx, rec); err != nil {
+			t.Fatalf("Create %s v%d failed: %v", rec.Key, rec.Version, err)
+		}
+	}
+
+	cases := []struct {
+		name   string
+		filter ListFilter
+		want   string
+	}{
+		{"default", ListFilter{}, "alpha,beta,gamma"},
+		{"key reverse", ListFilter{SortBy: SortByKey, Reverse: true}, "gamma,beta,alpha"},
+		{"size", ListFilter{SortBy: SortBySize}, "beta,gamma,alpha"},
+		{"size reverse", ListFilter{SortBy: SortBySize, Reverse: true}, "alpha,gamma,beta"},
+		{"version reverse", ListFilter{SortBy: SortByVersion, Reverse: true}, "beta,alpha,gamma"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			records, err := svc.ListWithFilter(ctx, scopeID, false, false, tc.filter)
+			if err != nil {
+				t.Fatalf("ListWithFilter failed: %v", err)
+			}
+			var keys []string
+			for _, r := range records {
+				keys = append(keys, r.Key)
+			}
+			if got := strings.Join(keys, ","); got != tc.want {
+				t.Fatalf("expected order %s, got %s", tc.want, got)
+			}
+		})
+	}
+
+	all, err := svc.ListWithFilter(ctx, scopeID, false, true, ListFilter{})
+	if err != nil {
+		t.Fatalf("ListWithFilter all versions failed: %v", err)
+	}
+	if len(all) != 4 || all[1].Key != "beta" || all[1].Version != 2 || all[2].Version != 1 {
+		t.Fatalf("expected newest version first within a key, got %#v", all)
+	}
+}
+
+func TestEntryServiceGetVersionSummary(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeSvc := NewScopeService(dbCtx)
+	scopeID, err := scopeSvc.GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	svc := NewEntryService(dbCtx)
+	for version, size := range []int64{10, 25, 5} {
+		record := database.ScopedEntryRecord{
+			ScopeID:  scopeID,
+			Key:      "notes",
+			Version:  int64(version + 1),
+			FilePath: "file",
+			Hash:     "hash",
+			Size:     size,
+		}
+		if _, err := svc.Create(ctx, record); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	latest, err := svc.GetLatest(ctx, scopeID, "notes")
+	if err != nil {
+		t.Fatalf("GetLatest failed: %v", err)
+	}
+
+	summary, err := svc.GetVersionSummary(ctx, latest.EntryID)
+	if err != nil {
+		t.Fatalf("GetVersionSummary failed: %v", err)
+	}
+	if summary.VersionCount != 3 {
+		t.Fatalf("expected 3 versions, got %d", summary.VersionCount)
+	}
+	if summary.TotalSize != 40 {
+		t.Fatalf("expected total size 40, got %d", summary.TotalSize)
+	}
+	if summary.FirstWrittenAt.IsZero() || summary.LastWrittenAt.IsZero() {
+		t.Fatalf("expected first/last written timestamps, got %#v", summary)
+	}
+	if summary.LastWrittenAt.Before(summary.FirstWrittenAt) {
+		t.Fatalf("last written %v before first written %v", summary.LastWrittenAt, summary.FirstWrittenAt)
+	}
+}
+
+func TestEntryServiceEachWithFilter(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeSvc := NewScopeService(dbCtx)
+	scopeID, err := scopeSvc.GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	svc := NewEntryService(dbCtx)
+	for _, key := range []string{"alpha", "beta"} {
+		for version := int64(1); version <= 3; version++ {
+			record := database.ScopedEntryRecord{ScopeID: scopeID, Key: key, Version: version, FilePath: "file", Hash: "hash"}
+			if _, err := svc.Create(ctx, record); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+		}
+	}
+
+	for _, allVersions := range []bool{true, false} {
+		want, err := svc.ListWithFilter(ctx, scopeID, false, allVersions, ListFilter{})
+		if err != nil {
+			t.Fatalf("ListWithFilter failed: %v", err)
+		}
+
+		var got []database.ScopedEntryRecord
+		err = svc.EachWithFilter(ctx, scopeID, false, allVersions, ListFilter{}, func(record database.ScopedEntryRecord) error {
+			got = append(got, record)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("EachWithFilter(allVersions=%v) failed: %v", allVersions, err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("allVersions=%v: expected %d records, got %d", allVersions, len(want), len(got))
+		}
+		for i := range want {
+			if got[i].Key != want[i].Key || got[i].Version != want[i].Version {
+				t.Fatalf("allVersions=%v record %d: expected %s v%d, got %s v%d", allVersions, i, want[i].Key, want[i].Version, got[i].Key, got[i].Version)
+			}
+		}
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = svc.EachWithFilter(ctx, scopeID, false, true, ListFilter{}, func(database.ScopedEntryRecord) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("expected iteration to stop after first callback error, got err=%v calls=%d", err, calls)
+	}
+}
+
+func TestEntryServiceVerificationStamp(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeSvc := NewScopeService(dbCtx)
+	scopeID, err := scopeSvc.GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	svc := NewEntryService(dbCtx)
+	record := database.ScopedEntryRecord{ScopeID: scopeID, Key: "notes", Version: 1, FilePath: "file", Hash: "hash"}
+	if _, err := svc.Create(ctx, record); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	latest, err := svc.GetLatest(ctx, scopeID, "notes")
+	if err != nil {
+		t.Fatalf("GetLatest failed: %v", err)
+	}
+
+	stamp, err := svc.GetVerification(ctx, latest.EntryID, 1)
+	if err != nil {
+		t.Fatalf("GetVerification failed: %v", err)
+	}
+	if stamp != nil {
+		t.Fatalf("expected no stamp before verification, got %#v", stamp)
+	}
+
+	want := database.VersionVerification{MtimeNs: 1700000000123456789, Size: 42}
+	if err := svc.RecordVerification(ctx, latest.EntryID, 1, want); err != nil {
+		t.Fatalf("RecordVerification failed: %v", err)
+	}
+
+	stamp, err = svc.GetVerification(ctx, latest.EntryID, 1)
+	if err != nil {
+		t.Fatalf("GetVerification failed: %v", err)
+	}
+	if stamp == nil || *stamp != want {
+		t.Fatalf("expected stamp %#v, got %#v", want, stamp)
+	}
+
+	if _, err := svc.GetVerification(ctx, latest.EntryID, 2); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for missing version, got %v", err)
+	}
+}
+
+func TestEntryServiceListVersionsKeepsImportedTimestamps(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeSvc := NewScopeService(dbCtx)
+	scopeID, err := scopeSvc.GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	svc := NewEntryService(dbCtx)
+	written := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	for version := int64(1); version <= 2; version++ {
+		record := database.ScopedEntryRecord{
+			ScopeID:   scopeID,
+			Key:       "notes",
+			Version:   version,
+			FilePath:  "file",
+			Hash:      "hash",
+			UpdatedAt: written.Add(time.Duration(version) * time.Hour),
+		}
+		if _, err := svc.Create(ctx, record); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	versions, err := svc.ListVersions(ctx, scopeID, "notes")
+	if err != nil {
+		t.Fatalf("ListVersions failed: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Version != 2 || versions[1].Version != 1 {
+		t.Fatalf("expected versions 2,1, got %#v", versions)
+	}
+	if want := written.Add(time.Hour); !versions[1].CreatedAt.Equal(want) {
+		t.Fatalf("expected version 1 written at %v, got %v", want, versions[1].CreatedAt)
+	}
+
+	if _, err := svc.ListVersions(ctx, scopeID, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for missing key, got %v", err)
+	}
+}
+
+func TestEntryServiceListHistoryIncludesProvenance(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeSvc := NewScopeService(dbCtx)
+	scopeID, err := scopeSvc.GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	svc := NewEntryService(dbCtx)
+	dirty := true
+	records := []database.ScopedEntryRecord{
+		{ScopeID: scopeID, Key: "notes", Version: 1, FilePath: "file1", Hash: "hash1"},
+		{ScopeID: scopeID, Key: "notes", Version: 2, FilePath: "file2", Hash: "hash2", Provenance: &database.VersionProvenance{
+			Actor:     "claude-code",
+			Tool:      "mcp",
+			Hostname:  "laptop",
+			GitBranch: "main",
+			GitCommit: "abc123",
+			GitDirty:  &dirty,
+		}},
+	}
+	for _, record := range records {
+		if _, err := svc.Create(ctx, record); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	history, err := svc.ListHistory(ctx, scopeID, "notes")
+	if err != nil {
+		t.Fatalf("ListHistory failed: %v", err)
+	}
+	if len(history) != 2 || history[0].Version != 2 {
+		t.Fatalf("expected two versions newest first, got %#v", history)
+	}
+
+	p := history[0].Provenance
+	if p == nil || p.Actor != "claude-code" || p.Tool != "mcp" || p.Hostname != "laptop" || p.GitBranch != "main" || p.GitCommit != "abc123" || p.GitDirty == nil || !*p.GitDirty {
+		t.Fatalf("unexpected provenance for version 2: %#v", p)
+	}
+	if history[1].Provenance != nil {
+		t.Fatalf("expected no provenance for version 1, got %#v", history[1].Provenance)
+	}
+
+	// Provenance rows go away with their version.
+	if deleted, err := svc.DeleteVersion(ctx, scopeID, "notes", 2); err != nil || !deleted {
+		t.Fatalf("DeleteVersion failed: err=%v deleted=%v", err, deleted)
+	}
+	var remaining int
+	if err := dbCtx.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM version_provenance").Scan(&remaining); err != nil {
+		t.Fatalf("count provenance: %v", err)
+	}
+	if remaining != 0 {
+		t.Fatalf("expected provenance to be deleted with its version, %d rows left", remaining)
+	}
+}
+
+func TestEntryServiceCreateRejectsClaimedVersion(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeID, err := NewScopeService(dbCtx).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	svc := NewEntryService(dbCtx)
+	first := "first writer"
+	if _, err := svc.Create(ctx, database.ScopedEntryRecord{ScopeID: scopeID, Key: "notes", Version: 1, FilePath: "a", Hash: "a", Description: &first}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// A second writer that planned version 1 before the first committed.
+	_, err = svc.Create(ctx, database.ScopedEntryRecord{ScopeID: scopeID, Key: "notes", Version: 1, FilePath: "b", Hash: "b"})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+
+	versions, err := svc.ListVersions(ctx, scopeID, "notes")
+	if err != nil {
+		t.Fatalf("ListVersions failed: %v", err)
+	}
+	if len(versions) != 1 || versions[0].Hash != "a" {
+		t.Fatalf("expected only the first writer's version, got %#v", versions)
+	}
+}
+
+func TestEntryServiceConcurrentWritersGetDistinctVersions(t *testing.T) {
+	t.Setenv("VAULT_CONFIG", filepath.Join(t.TempDir(), "missing.json"))
+	dbPath := filepath.Join(t.TempDir(), "index.db")
+
+	// Separate contexts stand in for separate processes sharing one vault.
+	const writers, writesPerWriter = 3, 10
+	contexts := make([]*database.Context, writers)
+	for i := range contexts {
+		dbCtx, err := database.CreateDatabase(dbPath)
+		if err != nil {
+			t.Fatalf("CreateDatabase error: %v", err)
+		}
+		t.Cleanup(func() { _ = database.CloseDatabase(dbCtx) })
+		contexts[i] = dbCtx
+	}
+
+	ctx := context.Background()
+	scopeID, err := NewScopeService(contexts[0]).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for _, dbCtx := range contexts {
+		wg.Add(1)
+		go func(svc *EntryService) {
+			defer wg.Done()
+			for i := 0; i < writesPerWriter; i++ {
+				for {
+					next, err := svc.GetNextVersion(ctx, scopeID, "notes")
+					if err != nil {
+						errs <- err
+						return
+					}
+					_, err = svc.Create(ctx, database.ScopedEntryRecord{ScopeID: scopeID, Key: "notes", Version: next, FilePath: "f", Hash: "h"})
+					if errors.Is(err, ErrVersionConflict) {
+						continue
+					}
+					if err != nil {
+						errs <- err
+						return
+					}
+					break
+				}
+			}
+		}(NewEntryService(dbCtx))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("writer failed: %v", err)
+	}
+
+	versions, err := NewEntryService(contexts[0]).ListVersions(ctx, scopeID, "notes")
+	if err != nil {
+		t.Fatalf("ListVersions failed: %v", err)
+	}
+	if len(versions) != writers*writesPerWriter {
+		t.Fatalf("expected %d versions, got %d", writers*writesPerWriter, len(versions))
+	}
+	for i, v := range versions {
+		if want := int64(len(versions) - i); v.Version != want {
+			t.Fatalf("expected contiguous versions, got %d at position %d", v.Version, i)
+		}
+	}
+}
+
+func TestEntryServiceIdempotencyKey(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeID, err := NewScopeService(dbCtx).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	svc := NewEntryService(dbCtx)
+	if _, err := svc.FindByIdempotencyKey(ctx, "retry-1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for unused key, got %v", err)
+	}
+
+	record := database.ScopedEntryRecord{ScopeID: scopeID, Key: "notes", Version: 1, FilePath: "file1", Hash: "hash1", IdempotencyKey: "retry-1"}
+	if _, err := svc.Create(ctx, record); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	prior, err := svc.FindByIdempotencyKey(ctx, "retry-1")
+	if err != nil {
+		t.Fatalf("FindByIdempotencyKey failed: %v", err)
+	}
+	if prior.ScopeID != scopeID || prior.Key != "notes" || prior.Version != 1 || prior.Hash != "hash1" {
+		t.Fatalf("unexpected prior write: %#v", prior)
+	}
+
+	// A concurrent retry that planned a later version loses on the key.
+	retry := database.ScopedEntryRecord{ScopeID: scopeID, Key: "notes", Version: 2, FilePath: "file2", Hash: "hash1", IdempotencyKey: "retry-1"}
+	if _, err := svc.Create(ctx, retry); !errors.Is(err, ErrIdempotencyKeyUsed) {
+		t.Fatalf("expected ErrIdempotencyKeyUsed, got %v", err)
+	}
+	versions, err := svc.ListVersions(ctx, scopeID, "notes")
+	if err != nil {
+		t.Fatalf("ListVersions failed: %v", err)
+	}
+	if len(versions) != 1 {
+		t.Fatalf("expected the retry to be rolled back, got %d versions", len(versions))
+	}
+}
+
+func TestEntryServiceApprove(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeID, err := NewScopeService(dbCtx).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	svc := NewEntryService(dbCtx)
+	for version := int64(1); version <= 3; version++ {
+		record := database.ScopedEntryRecord{ScopeID: scopeID, Key: "spec", Version: version, FilePath: "file", Hash: "hash"}
+		if _, err := svc.Create(ctx, record); err != nil {
+			t.Fatalf("Create v%d failed: %v", version, err)
+		}
+	}
+
+	if _, err := svc.LatestApprovedVersion(ctx, scopeID, "spec"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before any approval, got %v", err)
+	}
+
+	for _, version := range []int64{1, 2} {
+		approved, err := svc.Approve(ctx, scopeID, "spec", version, "alice")
+		if err != nil || !approved {
+			t.Fatalf("Approve v%d = %v, %v", version, approved, err)
+		}
+	}
+	if approved, err := svc.Approve(ctx, scopeID, "spec", 1, "bob"); err != nil || approved {
+		t.Fatalf("approving again = %v, %v; want false, nil", approved, err)
+	}
+	if _, err := svc.Approve(ctx, scopeID, "spec", 9, "alice"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("approving a missing version = %v, want ErrNotFound", err)
+	}
+
+	latest, err := svc.LatestApprovedVersion(ctx, scopeID, "spec")
+	if err != nil || latest != 2 {
+		t.Fatalf("LatestApprovedVersion = %d, %v; want 2", latest, err)
+	}
+
+	history, err := svc.ListHistory(ctx, scopeID, "spec")
+	if err != nil {
+		t.Fatalf("ListHistory failed: %v", err)
+	}
+	if history[0].Approval != nil {
+		t.Fatalf("expected version 3 to be a draft, got %#v", history[0].Approval)
+	}
+	if a := history[2].Approval; a == nil || a.ApprovedBy != "alice" || a.ApprovedAt.IsZero() {
+		t.Fatalf("unexpected approval of version 1: %#v", a)
+	}
+}
+
+func TestEntryServiceImportApproval(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeID, err := NewScopeService(dbCtx).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	svc := NewEntryService(dbCtx)
+	if _, err := svc.Create(ctx, database.ScopedEntryRecord{ScopeID: scopeID, Key: "spec", Version: 1, FilePath: "file", Hash: "hash"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	approval := database.VersionApproval{ApprovedBy: "alice", ApprovedAt: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+	if approved, err := svc.ImportApproval(ctx, scopeID, "spec", 1, approval); err != nil || !approved {
+		t.Fatalf("ImportApproval = %v, %v", approved, err)
+	}
+	if approved, err := svc.ImportApproval(ctx, scopeID, "spec", 1, database.VersionApproval{ApprovedBy: "bob"}); err != nil || approved {
+		t.Fatalf("importing again = %v, %v; want false, nil", approved, err)
+	}
+	if _, err := svc.ImportApproval(ctx, scopeID, "spec", 9, approval); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("importing for a missing version = %v, want ErrNotFound", err)
+	}
+
+	history, err := svc.ListHistory(ctx, scopeID, "spec")
+	if err != nil {
+		t.Fatalf("ListHistory failed: %v", err)
+	}
+	if a := history[0].Approval; a == nil || *a != approval {
+		t.Fatalf("approval = %#v, want %#v", a, approval)
+	}
+}
+
+func TestEntryServiceSetSummary(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeID, err := NewScopeService(dbCtx).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	svc := NewEntryService(dbCtx)
+	for version := int64(1); version <= 2; version++ {
+		record := database.ScopedEntryRecord{ScopeID: scopeID, Key: "design", Version: version, FilePath: "file", Hash: "hash"}
+		if _, err := svc.Create(ctx, record); err != nil {
+			t.Fatalf("Create v%d failed: %v", version, err)
+		}
+	}
+
+	if err := svc.SetSummary(ctx, scopeID, "design", 1, "first draft", "llm"); err != nil {
+		t.Fatalf("SetSummary failed: %v", err)
+	}
+	if err := svc.SetSummary(ctx, scopeID, "design", 2, "outdated", "llm"); err != nil {
+		t.Fatalf("SetSummary failed: %v", err)
+	}
+	if err := svc.SetSummary(ctx, scopeID, "design", 2, "final design", "llm"); err != nil {
+		t.Fatalf("SetSummary replace failed: %v", err)
+	}
+	if err := svc.SetSummary(ctx, scopeID, "design", 9, "missing", "llm"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("summarizing a missing version = %v, want ErrNotFound", err)
+	}
+
+	latest, err := svc.GetLatest(ctx, scopeID, "design")
+	if err != nil {
+		t.Fatalf("GetLatest failed: %v", err)
+	}
+	if latest.Summary != "final design" {
+		t.Fatalf("expected latest summary, got %q", latest.Summary)
+	}
+
+	var summaries []string
+	err = svc.EachWithFilter(ctx, scopeID, false, true, ListFilter{SortBy: SortByVersion}, func(r database.ScopedEntryRecord) error {
+		summaries = append(summaries, r.Summary)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachWithFilter failed: %v", err)
+	}
+	if strings.Join(summaries, ",") != "first draft,final design" {
+		t.Fatalf("unexpected listed summaries %v", summaries)
+	}
+
+	history, err := svc.ListHistory(ctx, scopeID, "design")
+	if err != nil {
+		t.Fatalf("ListHistory failed: %v", err)
+	}
+	if history[0].Summary != "final design" || history[1].Summary != "first draft" {
+		t.Fatalf("unexpected history summaries %q, %q", history[0].Summary, history[1].Summary)
+	}
+}
+
+func TestEntryServiceCreateBatchIsAtomic(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeID, err := NewScopeService(dbCtx).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	svc := NewEntryService(dbCtx)
+	if err := svc.CreateBatch(ctx, []database.ScopedEntryRecord{
+		{ScopeID: scopeID, Key: "notes", Version: 1, FilePath: "a", Hash: "a"},
+		{ScopeID: scopeID, Key: "notes", Version: 2, FilePath: "b", Hash: "b"},
+		{ScopeID: scopeID, Key: "todo", Version: 1, FilePath: "c", Hash: "c"},
+	}); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+
+	// The second record reuses a claimed version, so the whole batch is rolled back.
+	err = svc.CreateBatch(ctx, []database.ScopedEntryRecord{
+		{ScopeID: scopeID, Key: "todo", Version: 2, FilePath: "d", Hash: "d"},
+		{ScopeID: scopeID, Key: "notes", Version: 2, FilePath: "e", Hash: "e"},
+	})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+
+	notes, err := svc.ListVersions(ctx, scopeID, "notes")
+	if err != nil {
+		t.Fatalf("ListVersions failed: %v", err)
+	}
+	todo, err := svc.ListVersions(ctx, scopeID, "todo")
+	if err != nil {
+		t.Fatalf("ListVersions failed: %v", err)
+	}
+	if len(notes) != 2 || len(todo) != 1 {
+		t.Fatalf("expected 2 notes and 1 todo version, got %d and %d", len(notes), len(todo))
+	}
+}
+
+func TestEntryServiceRecordRead(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeID, err := NewScopeService(dbCtx).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	svc := NewEntryService(dbCtx)
+	if _, err := svc.Create(ctx, database.ScopedEntryRecord{ScopeID: scopeID, Key: "notes", Version: 1, FilePath: "file", Hash: "hash"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	unread, err := svc.GetLatest(ctx, scopeID, "notes")
+	if err != nil {
+		t.Fatalf("GetLatest failed: %v", err)
+	}
+	entryID := unread.EntryID
+	if unread.ReadCount != 0 || !unread.LastReadAt.IsZero() {
+		t.Fatalf("expected no reads yet, got %d at %v", unread.ReadCount, unread.LastReadAt)
+	}
+
+	for range 2 {
+		if err := svc.RecordRead(ctx, entryID); err != nil {
+			t.Fatalf("RecordRead failed: %v", err)
+		}
+	}
+	if err := svc.RecordRead(ctx, entryID+100); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("recording a read of a missing entry = %v, want ErrNotFound", err)
+	}
+
+	read, err := svc.GetLatest(ctx, scopeID, "notes")
+	if err != nil {
+		t.Fatalf("GetLatest failed: %v", err)
+	}
+	if read.ReadCount != 2 {
+		t.Fatalf("expected 2 reads, got %d", read.ReadCount)
+	}
+	if read.LastReadAt.IsZero() {
+		t.Fatalf("expected last read time to be set")
+	}
+}
+
+func TestEntryServiceJoinsCallerTransaction(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeID, err := NewScopeService(dbCtx).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	errAbort := errors.New("abort")
+	err = dbCtx.RunInTx(ctx, func(txCtx *database.Context) error {
+		svc := NewEntryService(txCtx)
+		for v := int64(1); v <= 2; v++ {
+			if _, err := svc.Create(ctx, database.ScopedEntryRecord{
+				ScopeID: scopeID, Key: "notes", Version: v, FilePath: "file", Hash: "hash",
+			}); err != nil {
+				t.Fatalf("Create version %d failed: %v", v, err)
+			}
+		}
+		records, err := svc.ListWithFilter(ctx, scopeID, false, true, ListFilter{})
+		if err != nil {
+			t.Fatalf("ListWithFilter inside the transaction failed: %v", err)
+		}
+		if len(records) != 2 {
+			t.Fatalf("expected 2 uncommitted versions inside the transaction, got %d", len(records))
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected abort error, got %v", err)
+	}
+
+	if _, err := NewEntryService(dbCtx).GetLatest(ctx, scopeID, "notes"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected rolled back entry to be gone, got %v", err)
+	}
+}
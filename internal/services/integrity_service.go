@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/choplin/vault.md/internal/database"
+	sqldb "github.com/choplin/vault.md/internal/database/sqlc"
+)
+
+// IntegrityService inspects the index as a whole: rows that disagree with
+// each other or with the object store, and how much history it holds.
+type IntegrityService struct {
+	ctx *database.Context
+}
+
+// NewIntegrityService creates a new IntegrityService.
+func NewIntegrityService(ctx *database.Context) *IntegrityService {
+	return &IntegrityService{ctx: ctx}
+}
+
+// VersionFiles returns the object file recorded for every version.
+func (s *IntegrityService) VersionFiles(ctx context.Context) ([]database.VersionFileRecord, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.ListVersionFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]database.VersionFileRecord, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, database.VersionFileRecord{
+			VersionID: row.ID,
+			EntryID:   row.EntryID,
+			Version:   row.Version,
+			FilePath:  row.FilePath,
+			Hash:      row.Hash,
+		})
+	}
+	return result, nil
+}
+
+// MoveVersionFile points a version at newPath, provided it still points at
+// oldPath. It reports whether the version was updated.
+func (s *IntegrityService) MoveVersionFile(ctx context.Context, versionID int64, oldPath, newPath string) (bool, error) {
+	q, err := s.queries()
+	if err != nil {
+		return false, err
+	}
+	rows, err := q.UpdateVersionFilePath(ctx, sqldb.UpdateVersionFilePathParams{
+		NewPath: newPath,
+		ID:      versionID,
+		OldPath: oldPath,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// PendingDeletions returns the object files queued for removal, oldest
+// first. Files that a version points at again, because the key was written
+// anew after the delete, are left out.
+func (s *IntegrityService) PendingDeletions(ctx context.Context) ([]database.PendingDeletionRecord, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.ListPendingDeletions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]database.PendingDeletionRecord, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, database.PendingDeletionRecord{
+			FilePath:  row.FilePath,
+			QueuedAt:  row.QueuedAt,
+			Attempts:  row.Attempts,
+			LastError: row.LastError.String,
+		})
+	}
+	return result, nil
+}
+
+// DropReusedDeletions removes the queued files that a version points at
+// again from the deletion queue and returns how many there were.
+func (s *IntegrityService) DropReusedDeletions(ctx context.Context) (int64, error) {
+	q, err := s.queries()
+	if err != nil {
+		return 0, err
+	}
+	return q.DeleteReusedPendingDeletions(ctx)
+}
+
+// DeletionDone removes filePath from the deletion queue.
+func (s *IntegrityService) DeletionDone(ctx context.Context, filePath string) error {
+	q, err := s.queries()
+	if err != nil {
+		return err
+	}
+	return q.DeletePendingDeletion(ctx, filePath)
+}
+
+// DeletionFailed records a failed attempt to remove filePath, which stays
+// queued.
+func (s *IntegrityService) DeletionFailed(ctx context.Context, filePath string, cause error) error {
+	q, err := s.queries()
+	if err != nil {
+		return err
+	}
+	return q.RecordPendingDeletionFailure(ctx, sqldb.RecordPendingDeletionFailureParams{
+		LastError: sql.NullString{String: cause.Error(), Valid: true},
+		FilePath:  filePath,
+	})
+}
+
+// DanglingRows counts entries and statuses whose referenced rows are missing.
+func (s *IntegrityService) DanglingRows(ctx context.Context) (*database.DanglingRowCounts, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+
+	var counts database.DanglingRowCounts
+	if counts.EntriesWithoutVersions, err = q.CountEntriesWithoutVersions(ctx); err != nil {
+		return nil, err
+	}
+	if counts.EntriesWithoutStatus, err = q.CountEntriesWithoutStatus(ctx); err != nil {
+		return nil, err
+	}
+	if counts.StatusWithMissingVersion, err = q.CountStatusWithMissingCurrentVersion(ctx); err != nil {
+		return nil, err
+	}
+	return &counts, nil
+}
+
+// Usage returns the number of entries and versions and their total size.
+func (s *IntegrityService) Usage(ctx context.Context) (*database.VaultUsage, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	row, err := q.GetVaultUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &database.VaultUsage{
+		EntryCount:   row.EntryCount,
+		VersionCount: row.VersionCount,
+		TotalSize:    row.TotalSize,
+	}, nil
+}
+
+// ScopeUsage reports how much one scope stores. EntryCount includes
+// archived entries.
+func (s *IntegrityService) ScopeUsage(ctx context.Context, scopeID int64) (*database.VaultUsage, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	row, err := q.GetScopeUsage(ctx, scopeID)
+	if err != nil {
+		return nil, err
+	}
+	return &database.VaultUsage{
+		EntryCount:   row.EntryCount,
+		VersionCount: row.VersionCount,
+		TotalSize:    row.TotalSize,
+	}, nil
+}
+
+// Reclaimable counts the versions that are older than the keepVersions newest
+// versions of their entry.
+func (s *IntegrityService) Reclaimable(ctx context.Context, keepVersions int) (*database.ReclaimableVersions, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	row, err := q.GetReclaimableVersions(ctx, int64(keepVersions))
+	if err != nil {
+		return nil, err
+	}
+	return &database.ReclaimableVersions{
+		VersionCount: row.VersionCount,
+		TotalSize:    row.TotalSize,
+	}, nil
+}
+
+func (s *IntegrityService) queries() (*sqldb.Queries, error) {
+	if s.ctx == nil {
+		return nil, fmt.Errorf("integrity service: missing database context")
+	}
+	if s.ctx.Queries == nil {
+		if s.ctx.DB == nil {
+			return nil, fmt.Errorf("integrity service: database handle not initialised")
+		}
+		s.ctx.Queries = sqldb.New(s.ctx.DB)
+	}
+	return s.ctx.Queries, nil
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+)
+
+func TestIntegrityServiceDetectsDanglingRows(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeID, err := NewScopeService(dbCtx).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	entrySvc := NewEntryService(dbCtx)
+	for _, key := range []string{"kept", "emptied"} {
+		record := database.ScopedEntryRecord{ScopeID: scopeID, Key: key, Version: 1, FilePath: key + "_v1.txt", Hash: "hash"}
+		if _, err := entrySvc.Create(ctx, record); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	svc := NewIntegrityService(dbCtx)
+	counts, err := svc.DanglingRows(ctx)
+	if err != nil {
+		t.Fatalf("DanglingRows failed: %v", err)
+	}
+	if counts.Total() != 0 {
+		t.Fatalf("expected no dangling rows, got %#v", counts)
+	}
+
+	// Removing the only version leaves the entry and its status behind.
+	if _, err := dbCtx.DB.ExecContext(ctx, "DELETE FROM versions WHERE file_path = 'emptied_v1.txt'"); err != nil {
+		t.Fatalf("delete version: %v", err)
+	}
+
+	counts, err = svc.DanglingRows(ctx)
+	if err != nil {
+		t.Fatalf("DanglingRows failed: %v", err)
+	}
+	if counts.EntriesWithoutVersions != 1 || counts.StatusWithMissingVersion != 1 || counts.EntriesWithoutStatus != 0 {
+		t.Fatalf("unexpected dangling counts: %#v", counts)
+	}
+
+	files, err := svc.VersionFiles(ctx)
+	if err != nil {
+		t.Fatalf("VersionFiles failed: %v", err)
+	}
+	if len(files) != 1 || files[0].FilePath != "kept_v1.txt" {
+		t.Fatalf("unexpected version files: %#v", files)
+	}
+}
+
+func TestIntegrityServiceReclaimable(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeID, err := NewScopeService(dbCtx).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	entrySvc := NewEntryService(dbCtx)
+	for version := int64(1); version <= 3; version++ {
+		record := database.ScopedEntryRecord{ScopeID: scopeID, Key: "notes", Version: version, FilePath: "notes.txt", Hash: "hash", Size: 10 * version}
+		if _, err := entrySvc.Create(ctx, record); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	single := database.ScopedEntryRecord{ScopeID: scopeID, Key: "single", Version: 1, FilePath: "single.txt", Hash: "hash", Size: 5}
+	if _, err := entrySvc.Create(ctx, single); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	svc := NewIntegrityService(dbCtx)
+	usage, err := svc.Usage(ctx)
+	if err != nil {
+		t.Fatalf("Usage failed: %v", err)
+	}
+	if usage.EntryCount != 2 || usage.VersionCount != 4 || usage.TotalSize != 65 {
+		t.Fatalf("unexpected usage: %#v", usage)
+	}
+
+	// Keeping one version per key leaves notes v1 and v2 reclaimable.
+	reclaimable, err := svc.Reclaimable(ctx, 1)
+	if err != nil {
+		t.Fatalf("Reclaimable failed: %v", err)
+	}
+	if reclaimable.VersionCount != 2 || reclaimable.TotalSize != 30 {
+		t.Fatalf("unexpected reclaimable: %#v", reclaimable)
+	}
+
+	reclaimable, err = svc.Reclaimable(ctx, 3)
+	if err != nil {
+		t.Fatalf("Reclaimable failed: %v", err)
+	}
+	if reclaimable.VersionCount != 0 || reclaimable.TotalSize != 0 {
+		t.Fatalf("expected nothing reclaimable, got %#v", reclaimable)
+	}
+}
+
+func TestIntegrityServiceScopeUsage(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeSvc := NewScopeService(dbCtx)
+	globalID, err := scopeSvc.GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+	repoID, err := scopeSvc.GetOrCreate(ctx, scope.NewRepository("/repo"))
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	entrySvc := NewEntryService(dbCtx)
+	for _, record := range []database.ScopedEntryRecord{
+		{ScopeID: globalID, Key: "notes", Version: 1, FilePath: "n1", Hash: "hash", Size: 10},
+		{ScopeID: globalID, Key: "notes", Version: 2, FilePath: "n2", Hash: "hash", Size: 20},
+		{ScopeID: globalID, Key: "todo", Version: 1, FilePath: "t1", Hash: "hash", Size: 5},
+		{ScopeID: repoID, Key: "notes", Version: 1, FilePath: "r1", Hash: "hash", Size: 100},
+	} {
+		if _, err := entrySvc.Create(ctx, record); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	usage, err := NewIntegrityService(dbCtx).ScopeUsage(ctx, globalID)
+	if err != nil {
+		t.Fatalf("ScopeUsage failed: %v", err)
+	}
+	if usage.EntryCount != 2 || usage.VersionCount != 3 || usage.TotalSize != 35 {
+		t.Fatalf("unexpected usage: %#v", usage)
+	}
+
+	superseded, err := entrySvc.ListSuperseded(ctx, globalID)
+	if err != nil {
+		t.Fatalf("ListSuperseded failed: %v", err)
+	}
+	if len(superseded) != 1 || superseded[0].Key != "notes" || superseded[0].Version != 1 || superseded[0].Size != 10 {
+		t.Fatalf("unexpected superseded versions: %#v", superseded)
+	}
+}
+
+func TestIntegrityServiceMoveVersionFile(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeID, err := NewScopeService(dbCtx).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+	record := database.ScopedEntryRecord{ScopeID: scopeID, Key: "notes", Version: 1, FilePath: "notes_v1.txt", Hash: "hash"}
+	if _, err := NewEntryService(dbCtx).Create(ctx, record); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	svc := NewIntegrityService(dbCtx)
+	files, err := svc.VersionFiles(ctx)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("VersionFiles = %#v, %v", files, err)
+	}
+
+	moved, err := svc.MoveVersionFile(ctx, files[0].VersionID, "notes_v1.txt", "ab/notes_v1.txt")
+	if err != nil || !moved {
+		t.Fatalf("MoveVersionFile = %v, %v; want moved", moved, err)
+	}
+	// The version no longer points at the old path.
+	moved, err = svc.MoveVersionFile(ctx, files[0].VersionID, "notes_v1.txt", "cd/notes_v1.txt")
+	if err != nil || moved {
+		t.Fatalf("stale MoveVersionFile = %v, %v; want not moved", moved, err)
+	}
+
+	files, err = svc.VersionFiles(ctx)
+	if err != nil || files[0].FilePath != "ab/notes_v1.txt" {
+		t.Fatalf("VersionFiles after move = %#v, %v", files, err)
+	}
+}
+
+func TestIntegrityServicePendingDeletions(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeID, err := NewScopeService(dbCtx).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	entrySvc := NewEntryService(dbCtx)
+	records := []database.ScopedEntryRecord{
+		{ScopeID: scopeID, Key: "one", Version: 1, FilePath: "one_v1.txt", Hash: "hash"},
+		{ScopeID: scopeID, Key: "one", Version: 2, FilePath: "one_v2.txt", Hash: "hash"},
+		{ScopeID: scopeID, Key: "two", Version: 1, FilePath: "two_v1.txt", Hash: "hash"},
+	}
+	for _, record := range records {
+		if _, err := entrySvc.Create(ctx, record); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	if _, err := entrySvc.DeleteVersion(ctx, scopeID, "one", 1); err != nil {
+		t.Fatalf("DeleteVersion failed: %v", err)
+	}
+	if _, err := entrySvc.DeleteAll(ctx, scopeID, "two"); err != nil {
+		t.Fatalf("DeleteAll failed: %v", err)
+	}
+
+	svc := NewIntegrityService(dbCtx)
+	pending, err := svc.PendingDeletions(ctx)
+	if err != nil {
+		t.Fatalf("PendingDeletions failed: %v", err)
+	}
+	if len(pending) != 2 || pending[0].FilePath != "one_v1.txt" || pending[1].FilePath != "two_v1.txt" {
+		t.Fatalf("unexpected pending deletions: %#v", pending)
+	}
+
+	if err := svc.DeletionFailed(ctx, "one_v1.txt", errors.New("permission denied")); err != nil {
+		t.Fatalf("DeletionFailed failed: %v", err)
+	}
+	if err := svc.DeletionDone(ctx, "two_v1.txt"); err != nil {
+		t.Fatalf("DeletionDone failed: %v", err)
+	}
+	pending, err = svc.PendingDeletions(ctx)
+	if err != nil {
+		t.Fatalf("PendingDeletions failed: %v", err)
+	}
+	if len(pending) != 1 || pending[0].Attempts != 1 || pending[0].LastError != "permission denied" {
+		t.Fatalf("unexpected pending deletions after a failure: %#v", pending)
+	}
+
+	// A new version written to the queued path must not be deleted with it.
+	if _, err := entrySvc.Create(ctx, database.ScopedEntryRecord{ScopeID: scopeID, Key: "one", Version: 3, FilePath: "one_v1.txt", Hash: "hash"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	pending, err = svc.PendingDeletions(ctx)
+	if err != nil {
+		t.Fatalf("PendingDeletions failed: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("expected a reused path to be left out, got %#v", pending)
+	}
+	dropped, err := svc.DropReusedDeletions(ctx)
+	if err != nil || dropped != 1 {
+		t.Fatalf("DropReusedDeletions = %d, %v; want 1", dropped, err)
+	}
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/choplin/vault.md/internal/database"
+	sqldb "github.com/choplin/vault.md/internal/database/sqlc"
+)
+
+// ErrLocked is returned when another owner holds an unexpired lock on the
+// key.
+var ErrLocked = errors.New("key is locked")
+
+// LockService keeps advisory locks on keys, so that writers can claim a key
+// for a while instead of interleaving versions. Locks are not enforced by
+// the database; writers check them with Check.
+type LockService struct {
+	ctx *database.Context
+}
+
+// NewLockService creates a new LockService.
+func NewLockService(ctx *database.Context) *LockService {
+	return &LockService{ctx: ctx}
+}
+
+// Get returns the lock on key, expired or not, or ErrNotFound.
+func (s *LockService) Get(ctx context.Context, scopeID int64, key string) (*database.LockRecord, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	row, err := q.GetEntryLock(ctx, sqldb.GetEntryLockParams{ScopeID: scopeID, Key: key})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	record := database.LockRecordFromRow(row)
+	return &record, nil
+}
+
+// Check returns ErrLocked if someone other than owner holds an unexpired
+// lock on key at now.
+func (s *LockService) Check(ctx context.Context, scopeID int64, key, owner string, now time.Time) error {
+	lock, err := s.Get(ctx, scopeID, key)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return checkLock(lock, key, owner, now)
+}
+
+// Acquire locks key for owner until now+ttl. Owner can renew its own lock
+// and take over an expired one; force takes over a lock held by someone
+// else.
+func (s *LockService) Acquire(ctx context.Context, scopeID int64, key, owner string, ttl time.Duration, force bool, now time.Time) (*database.LockRecord, error) {
+	record := &database.LockRecord{
+		ScopeID:    scopeID,
+		Key:        key,
+		Owner:      owner,
+		AcquiredAt: now.UTC().Truncate(time.Second),
+		ExpiresAt:  now.Add(ttl).UTC().Truncate(time.Second),
+	}
+	err := s.withTx(ctx, func(txCtx context.Context, q *sqldb.Queries) error {
+		if !force {
+			row, err := q.GetEntryLock(txCtx, sqldb.GetEntryLockParams{ScopeID: scopeID, Key: key})
+			switch {
+			case err == nil:
+				lock := database.LockRecordFromRow(row)
+				if err := checkLock(&lock, key, owner, now); err != nil {
+					return err
+				}
+			case !errors.Is(err, sql.ErrNoRows):
+				return err
+			}
+		}
+		return q.UpsertEntryLock(txCtx, sqldb.UpsertEntryLockParams{
+			ScopeID:    scopeID,
+			Key:        key,
+			Owner:      owner,
+			AcquiredAt: record.AcquiredAt.Format(database.TimestampLayout),
+			ExpiresAt:  record.ExpiresAt.Format(database.TimestampLayout),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Release removes the lock on key and returns false if there was none. A
+// lock held by someone else is only released when it has expired or force
+// is set.
+func (s *LockService) Release(ctx context.Context, scopeID int64, key, owner string, force bool, now time.Time) (bool, error) {
+	var released bool
+	err := s.withTx(ctx, func(txCtx context.Context, q *sqldb.Queries) error {
+		if !force {
+			row, err := q.GetEntryLock(txCtx, sqldb.GetEntryLockParams{ScopeID: scopeID, Key: key})
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			lock := database.LockRecordFromRow(row)
+			if err := checkLock(&lock, key, owner, now); err != nil {
+				return err
+			}
+		}
+		affected, err := q.DeleteEntryLock(txCtx, sqldb.DeleteEntryLockParams{ScopeID: scopeID, Key: key})
+		if err != nil {
+			return err
+		}
+		released = affected > 0
+		return nil
+	})
+	return released, err
+}
+
+func checkLock(lock *database.LockRecord, key, owner string, now time.Time) error {
+	if lock.Owner == owner || !now.Before(lock.ExpiresAt) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is held by %s until %s", ErrLocked, key, lock.Owner, lock.ExpiresAt.Local().Format(time.RFC3339))
+}
+
+func (s *LockService) withTx(ctx context.Context, fn func(context.Context, *sqldb.Queries) error) error {
+	if s.ctx == nil || s.ctx.DB == nil {
+		return fmt.Errorf("lock service: missing database context")
+	}
+	if s.ctx.InTx() {
+		// Nest in the transaction the caller opened with RunInTx.
+		return s.ctx.RunInTx(ctx, func(txCtx *database.Context) error {
+			return fn(ctx, txCtx.Queries)
+		})
+	}
+
+	return s.ctx.RunWrite(ctx, func() error {
+		tx, err := s.ctx.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		queries := s.ctx.TxQueries(tx)
+		if err := fn(ctx, queries); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (s *LockService) queries() (*sqldb.Queries, error) {
+	if s.ctx == nil {
+		return nil, fmt.Errorf("lock service: missing database context")
+	}
+	if s.ctx.Queries == nil {
+		if s.ctx.DB == nil {
+			return nil, fmt.Errorf("lock service: database handle not initialised")
+		}
+		s.ctx.Queries = sqldb.New(s.ctx.DB)
+	}
+	return s.ctx.Queries, nil
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/choplin/vault.md/internal/scope"
+)
+
+func TestLockServiceAcquireCheckRelease(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeID, err := NewScopeService(dbCtx).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	svc := NewLockService(dbCtx)
+	now := time.Now()
+	lock, err := svc.Acquire(ctx, scopeID, "plan", "alice", 10*time.Minute, false, now)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	stored, err := svc.Get(ctx, scopeID, "plan")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if stored.Owner != "alice" || !stored.ExpiresAt.Equal(lock.ExpiresAt) {
+		t.Fatalf("unexpected stored lock: %#v (acquired %#v)", stored, lock)
+	}
+
+	if err := svc.Check(ctx, scopeID, "plan", "alice", now); err != nil {
+		t.Fatalf("owner should pass Check: %v", err)
+	}
+	if err := svc.Check(ctx, scopeID, "plan", "bob", now); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked for another owner, got %v", err)
+	}
+	if err := svc.Check(ctx, scopeID, "plan", "bob", now.Add(time.Hour)); err != nil {
+		t.Fatalf("expired lock should pass Check: %v", err)
+	}
+	if _, err := svc.Acquire(ctx, scopeID, "plan", "bob", time.Minute, false, now); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked when acquiring a held lock, got %v", err)
+	}
+	if _, err := svc.Release(ctx, scopeID, "plan", "bob", false, now); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked when releasing another owner's lock, got %v", err)
+	}
+
+	if _, err := svc.Acquire(ctx, scopeID, "plan", "bob", time.Minute, true, now); err != nil {
+		t.Fatalf("forced Acquire failed: %v", err)
+	}
+	released, err := svc.Release(ctx, scopeID, "plan", "bob", false, now)
+	if err != nil || !released {
+		t.Fatalf("Release = %v, %v", released, err)
+	}
+	if _, err := svc.Get(ctx, scopeID, "plan"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after release, got %v", err)
+	}
+}
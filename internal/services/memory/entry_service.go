@@ -0,0 +1,662 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// EntryService keeps entries and their versions in memory.
+type EntryService struct {
+	mu            sync.Mutex
+	nextEntryID   int64
+	nextVersionID int64
+	entries       map[entryKey]*entry
+	byID          map[int64]*entry
+	tokens        map[string]idempotencyToken
+}
+
+type entryKey struct {
+	scopeID int64
+	key     string
+}
+
+type entry struct {
+	record     database.EntryRecord
+	archived   bool
+	current    int64
+	lastReadAt time.Time
+	readCount  int64
+	// versions is ordered by version number.
+	versions []*version
+}
+
+type version struct {
+	record       database.VersionRecord
+	summary      string
+	provenance   *database.VersionProvenance
+	approval     *database.VersionApproval
+	verification *database.VersionVerification
+}
+
+type idempotencyToken struct {
+	entry     *entry
+	version   *version
+	createdAt time.Time
+}
+
+// NewEntryService creates an empty EntryService.
+func NewEntryService() *EntryService {
+	return &EntryService{
+		entries: map[entryKey]*entry{},
+		byID:    map[int64]*entry{},
+		tokens:  map[string]idempotencyToken{},
+	}
+}
+
+func (e *entry) find(v int64) *version {
+	for _, ver := range e.versions {
+		if ver.record.Version == v {
+			return ver
+		}
+	}
+	return nil
+}
+
+func (e *entry) maxVersion() int64 {
+	if len(e.versions) == 0 {
+		return 0
+	}
+	return e.versions[len(e.versions)-1].record.Version
+}
+
+func (e *entry) scoped(v *version) database.ScopedEntryRecord {
+	return database.ScopedEntryRecord{
+		EntryID:     e.record.ID,
+		ScopeID:     e.record.ScopeID,
+		Key:         e.record.Key,
+		Version:     v.record.Version,
+		FilePath:    v.record.FilePath,
+		Hash:        v.record.Hash,
+		Description: v.record.Description,
+		CreatedAt:   e.record.CreatedAt,
+		UpdatedAt:   v.record.CreatedAt,
+		Size:        v.record.Size,
+		IsArchived:  e.archived,
+		Language:    v.record.Language,
+		Summary:     v.summary,
+		LastReadAt:  e.lastReadAt,
+		ReadCount:   e.readCount,
+	}
+}
+
+// GetLatest retrieves the latest version of an entry.
+func (s *EntryService) GetLatest(_ context.Context, scopeID int64, key string) (*database.ScopedEntryRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[entryKey{scopeID, key}]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+	v := e.find(e.current)
+	if v == nil {
+		return nil, services.ErrNotFound
+	}
+	record := e.scoped(v)
+	return &record, nil
+}
+
+// GetByVersion retrieves a specific version of an entry.
+func (s *EntryService) GetByVersion(_ context.Context, scopeID int64, key string, ver int64) (*database.ScopedEntryRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[entryKey{scopeID, key}]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+	v := e.find(ver)
+	if v == nil {
+		return nil, services.ErrNotFound
+	}
+	record := e.scoped(v)
+	return &record, nil
+}
+
+// GetNextVersion returns the next version number for an entry.
+func (s *EntryService) GetNextVersion(_ context.Context, scopeID int64, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[entryKey{scopeID, key}]
+	if !ok {
+		return 1, nil
+	}
+	return e.maxVersion() + 1, nil
+}
+
+// GetEntryByKey retrieves the entry record for a given key.
+func (s *EntryService) GetEntryByKey(_ context.Context, scopeID int64, key string) (*database.EntryRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[entryKey{scopeID, key}]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+	record := e.record
+	return &record, nil
+}
+
+// Create persists a new entry version, provisioning the entry as needed.
+func (s *EntryService) Create(_ context.Context, record database.ScopedEntryRecord) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.check([]database.ScopedEntryRecord{record}); err != nil {
+		return 0, err
+	}
+	return s.create(record), nil
+}
+
+// CreateBatch persists several versions at once, so either all of them are
+// stored or none is. Versions of the same key must be given in increasing
+// order.
+func (s *EntryService) CreateBatch(_ context.Context, records []database.ScopedEntryRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.check(records); err != nil {
+		return err
+	}
+	for _, record := range records {
+		s.create(record)
+	}
+	return nil
+}
+
+// check reports the error Create would return for the first record that
+// conflicts with stored versions, earlier records, or a live idempotency key.
+func (s *EntryService) check(records []database.ScopedEntryRecord) error {
+	s.expireTokens()
+
+	latest := map[entryKey]int64{}
+	tokens := map[string]bool{}
+	for _, record := range records {
+		k := entryKey{record.ScopeID, record.Key}
+		maxVersion, ok := latest[k]
+		if !ok {
+			if e, exists := s.entries[k]; exists {
+				maxVersion = e.maxVersion()
+			}
+		}
+		if record.Version <= maxVersion {
+			return fmt.Errorf("%w: %s version %d (latest is %d)", services.ErrVersionConflict, record.Key, record.Version, maxVersion)
+		}
+		latest[k] = record.Version
+
+		if token := record.IdempotencyKey; token != "" {
+			if _, used := s.tokens[token]; used || tokens[token] {
+				return fmt.Errorf("%w: %s", services.ErrIdempotencyKeyUsed, token)
+			}
+			tokens[token] = true
+		}
+	}
+	return nil
+}
+
+// create stores a record that passed check and returns its version ID.
+func (s *EntryService) create(record database.ScopedEntryRecord) int64 {
+	now := time.Now().UTC()
+	k := entryKey{record.ScopeID, record.Key}
+	e, ok := s.entries[k]
+	if !ok {
+		s.nextEntryID++
+		e = &entry{
+			record:   database.EntryRecord{ID: s.nextEntryID, ScopeID: record.ScopeID, Key: record.Key, CreatedAt: now},
+			archived: record.IsArchived,
+		}
+		s.entries[k] = e
+		s.byID[e.record.ID] = e
+	}
+
+	createdAt := now
+	// Imported versions keep their original write time.
+	if !record.UpdatedAt.IsZero() {
+		createdAt = record.UpdatedAt.UTC()
+	}
+	s.nextVersionID++
+	v := &version{
+		record: database.VersionRecord{
+			ID:          s.nextVersionID,
+			EntryID:     e.record.ID,
+			Version:     record.Version,
+			FilePath:    record.FilePath,
+			Hash:        record.Hash,
+			Description: record.Description,
+			CreatedAt:   createdAt,
+			Size:        record.Size,
+			Language:    record.Language,
+		},
+		provenance: record.Provenance,
+	}
+	e.versions = append(e.versions, v)
+	e.current = record.Version
+
+	if record.IdempotencyKey != "" {
+		s.tokens[record.IdempotencyKey] = idempotencyToken{entry: e, version: v, createdAt: now}
+	}
+	return v.record.ID
+}
+
+// expireTokens drops idempotency keys older than services.IdempotencyKeyTTL
+// so that they can be reused.
+func (s *EntryService) expireTokens() {
+	cutoff := time.Now().Add(-services.IdempotencyKeyTTL)
+	for token, t := range s.tokens {
+		if t.createdAt.Before(cutoff) {
+			delete(s.tokens, token)
+		}
+	}
+}
+
+// List retrieves entries from the vault with specified filters.
+func (s *EntryService) List(ctx context.Context, scopeID int64, includeArchived, allVersions bool) ([]database.ScopedEntryRecord, error) {
+	return s.ListWithFilter(ctx, scopeID, includeArchived, allVersions, services.ListFilter{})
+}
+
+// ListWithFilter retrieves entries like List, additionally applying filter.
+func (s *EntryService) ListWithFilter(_ context.Context, scopeID int64, includeArchived, allVersions bool, filter services.ListFilter) ([]database.ScopedEntryRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []database.ScopedEntryRecord
+	for _, e := range s.entries {
+		if e.record.ScopeID != scopeID || (e.archived && !includeArchived) {
+			continue
+		}
+		for _, v := range e.versions {
+			if !allVersions && v.record.Version != e.current {
+				continue
+			}
+			if matches(v, filter) {
+				result = append(result, e.scoped(v))
+			}
+		}
+	}
+
+	sortBy := cmp.Or(filter.SortBy, services.SortByKey)
+	slices.SortFunc(result, func(a, b database.ScopedEntryRecord) int {
+		var c int
+		switch sortBy {
+		case services.SortByKey:
+			c = cmp.Compare(a.Key, b.Key)
+		case services.SortByCreated:
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		case services.SortByUpdated:
+			c = a.UpdatedAt.Compare(b.UpdatedAt)
+		case services.SortByVersion:
+			c = cmp.Compare(a.Version, b.Version)
+		case services.SortBySize:
+			c = cmp.Compare(a.Size, b.Size)
+		}
+		if filter.Reverse {
+			c = -c
+		}
+		return cmp.Or(c, cmp.Compare(a.Key, b.Key), cmp.Compare(b.Version, a.Version))
+	})
+	return result, nil
+}
+
+func matches(v *version, filter services.ListFilter) bool {
+	if !filter.Since.IsZero() && v.record.CreatedAt.Before(filter.Since) {
+		return false
+	}
+	if !filter.Until.IsZero() && v.record.CreatedAt.After(filter.Until) {
+		return false
+	}
+	if filter.DescriptionContains != "" {
+		if v.record.Description == nil ||
+			!strings.Contains(strings.ToLower(*v.record.Description), strings.ToLower(filter.DescriptionContains)) {
+			return false
+		}
+	}
+	if filter.Language != "" && v.record.Language != filter.Language {
+		return false
+	}
+	return true
+}
+
+// EachWithFilter calls fn for each record ListWithFilter would return.
+// Returning an error from fn stops iteration.
+func (s *EntryService) EachWithFilter(ctx context.Context, scopeID int64, includeArchived, allVersions bool, filter services.ListFilter, fn func(database.ScopedEntryRecord) error) error {
+	records, err := s.ListWithFilter(ctx, scopeID, includeArchived, allVersions, filter)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListVersions returns every version of key, newest first.
+func (s *EntryService) ListVersions(_ context.Context, scopeID int64, key string) ([]database.VersionRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[entryKey{scopeID, key}]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+	result := make([]database.VersionRecord, 0, len(e.versions))
+	for i := len(e.versions) - 1; i >= 0; i-- {
+		result = append(result, e.versions[i].record)
+	}
+	return result, nil
+}
+
+// ListHistory returns every version of key, newest first, together with its
+// provenance, approval, and summary.
+func (s *EntryService) ListHistory(_ context.Context, scopeID int64, key string) ([]database.VersionHistoryRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[entryKey{scopeID, key}]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+	result := make([]database.VersionHistoryRecord, 0, len(e.versions))
+	for i := len(e.versions) - 1; i >= 0; i-- {
+		v := e.versions[i]
+		result = append(result, database.VersionHistoryRecord{
+			VersionRecord: v.record,
+			Provenance:    v.provenance,
+			Approval:      v.approval,
+			Summary:       v.summary,
+		})
+	}
+	return result, nil
+}
+
+// ListSuperseded returns the versions in the scope that are not the latest
+// of their entry, oldest first.
+func (s *EntryService) ListSuperseded(_ context.Context, scopeID int64) ([]database.SupersededVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type superseded struct {
+		database.SupersededVersion
+		createdAt time.Time
+		id        int64
+	}
+	var found []superseded
+	for _, e := range s.entries {
+		if e.record.ScopeID != scopeID {
+			continue
+		}
+		for _, v := range e.versions {
+			if v.record.Version == e.current {
+				continue
+			}
+			found = append(found, superseded{
+				SupersededVersion: database.SupersededVersion{
+					Key:      e.record.Key,
+					Version:  v.record.Version,
+					FilePath: v.record.FilePath,
+					Size:     v.record.Size,
+				},
+				createdAt: v.record.CreatedAt,
+				id:        v.record.ID,
+			})
+		}
+	}
+	slices.SortFunc(found, func(a, b superseded) int {
+		return cmp.Or(a.createdAt.Compare(b.createdAt), cmp.Compare(a.id, b.id))
+	})
+
+	result := make([]database.SupersededVersion, 0, len(found))
+	for _, f := range found {
+		result = append(result, f.SupersededVersion)
+	}
+	return result, nil
+}
+
+// DeleteVersion deletes a specific version of an entry and returns true if deleted.
+func (s *EntryService) DeleteVersion(_ context.Context, scopeID int64, key string, ver int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[entryKey{scopeID, key}]
+	if !ok {
+		return false, nil
+	}
+	i := slices.IndexFunc(e.versions, func(v *version) bool { return v.record.Version == ver })
+	if i < 0 {
+		return false, nil
+	}
+	e.versions = slices.Delete(e.versions, i, i+1)
+	if maxVersion := e.maxVersion(); maxVersion > 0 {
+		e.current = maxVersion
+	}
+	return true, nil
+}
+
+// DeleteAll deletes all versions of an entry and returns true if deleted.
+func (s *EntryService) DeleteAll(_ context.Context, scopeID int64, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := entryKey{scopeID, key}
+	e, ok := s.entries[k]
+	if !ok {
+		return false, nil
+	}
+	delete(s.entries, k)
+	delete(s.byID, e.record.ID)
+	return true, nil
+}
+
+// Archive marks an entry as archived and returns true if archived.
+func (s *EntryService) Archive(_ context.Context, scopeID int64, key string) (bool, error) {
+	return s.setArchived(scopeID, key, true), nil
+}
+
+// Restore unarchives an entry and returns true if restored.
+func (s *EntryService) Restore(_ context.Context, scopeID int64, key string) (bool, error) {
+	return s.setArchived(scopeID, key, false), nil
+}
+
+func (s *EntryService) setArchived(scopeID int64, key string, archived bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[entryKey{scopeID, key}]
+	if !ok || e.archived == archived {
+		return false
+	}
+	e.archived = archived
+	return true
+}
+
+// RecordRead notes a read of the entry: its last read time becomes now and
+// its read count goes up by one.
+func (s *EntryService) RecordRead(_ context.Context, entryID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.byID[entryID]
+	if !ok {
+		return services.ErrNotFound
+	}
+	e.lastReadAt = time.Now().UTC()
+	e.readCount++
+	return nil
+}
+
+// Approve marks a version of key as approved by approvedBy. It returns false
+// when the version was already approved, keeping the original approval, and
+// services.ErrNotFound when the version does not exist.
+func (s *EntryService) Approve(_ context.Context, scopeID int64, key string, ver int64, approvedBy string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.version(scopeID, key, ver)
+	if err != nil {
+		return false, err
+	}
+	if v.approval != nil {
+		return false, nil
+	}
+	v.approval = &database.VersionApproval{ApprovedBy: approvedBy, ApprovedAt: time.Now().UTC()}
+	return true, nil
+}
+
+// ImportApproval records approval, with its original time, for a version of
+// key. It returns false when the version was already approved.
+func (s *EntryService) ImportApproval(_ context.Context, scopeID int64, key string, ver int64, approval database.VersionApproval) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.version(scopeID, key, ver)
+	if err != nil {
+		return false, err
+	}
+	if v.approval != nil {
+		return false, nil
+	}
+	approval.ApprovedAt = approval.ApprovedAt.UTC()
+	v.approval = &approval
+	return true, nil
+}
+
+// LatestApprovedVersion returns the highest approved version of key, or
+// services.ErrNotFound when no version has been approved.
+func (s *EntryService) LatestApprovedVersion(_ context.Context, scopeID int64, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[entryKey{scopeID, key}]; ok {
+		for i := len(e.versions) - 1; i >= 0; i-- {
+			if e.versions[i].approval != nil {
+				return e.versions[i].record.Version, nil
+			}
+		}
+	}
+	return 0, services.ErrNotFound
+}
+
+// SetSummary stores summary as the summary of a version of key, replacing
+// any earlier one. It returns services.ErrNotFound when the version does
+// not exist.
+func (s *EntryService) SetSummary(_ context.Context, scopeID int64, key string, ver int64, summary, _ string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.version(scopeID, key, ver)
+	if err != nil {
+		return err
+	}
+	v.summary = summary
+	return nil
+}
+
+func (s *EntryService) version(scopeID int64, key string, ver int64) (*version, error) {
+	e, ok := s.entries[entryKey{scopeID, key}]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+	v := e.find(ver)
+	if v == nil {
+		return nil, services.ErrNotFound
+	}
+	return v, nil
+}
+
+// FindByIdempotencyKey returns the version created under token within
+// services.IdempotencyKeyTTL, or services.ErrNotFound.
+func (s *EntryService) FindByIdempotencyKey(_ context.Context, token string) (*database.IdempotentWrite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[token]
+	if !ok || t.createdAt.Before(time.Now().Add(-services.IdempotencyKeyTTL)) {
+		return nil, services.ErrNotFound
+	}
+	return &database.IdempotentWrite{
+		ScopeID:  t.entry.record.ScopeID,
+		Key:      t.entry.record.Key,
+		Version:  t.version.record.Version,
+		FilePath: t.version.record.FilePath,
+		Hash:     t.version.record.Hash,
+	}, nil
+}
+
+// GetVersionSummary aggregates version count, total size, and first/last
+// write times across all versions of an entry.
+func (s *EntryService) GetVersionSummary(_ context.Context, entryID int64) (*database.EntryVersionSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summary := &database.EntryVersionSummary{}
+	e, ok := s.byID[entryID]
+	if !ok {
+		return summary, nil
+	}
+	for _, v := range e.versions {
+		summary.VersionCount++
+		summary.TotalSize += v.record.Size
+		if summary.FirstWrittenAt.IsZero() || v.record.CreatedAt.Before(summary.FirstWrittenAt) {
+			summary.FirstWrittenAt = v.record.CreatedAt
+		}
+		if v.record.CreatedAt.After(summary.LastWrittenAt) {
+			summary.LastWrittenAt = v.record.CreatedAt
+		}
+	}
+	return summary, nil
+}
+
+// GetVerification returns the file stamp recorded when the version was last
+// verified, or nil if it has never been verified.
+func (s *EntryService) GetVerification(_ context.Context, entryID, ver int64) (*database.VersionVerification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.byID[entryID]
+	if !ok {
+		return nil, services.ErrNotFound
+	}
+	v := e.find(ver)
+	if v == nil {
+		return nil, services.ErrNotFound
+	}
+	if v.verification == nil {
+		return nil, nil
+	}
+	stamp := *v.verification
+	return &stamp, nil
+}
+
+// RecordVerification stores the file stamp observed after a successful
+// verification.
+func (s *EntryService) RecordVerification(_ context.Context, entryID, ver int64, stamp database.VersionVerification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.byID[entryID]; ok {
+		if v := e.find(ver); v != nil {
+			v.verification = &stamp
+		}
+	}
+	return nil
+}
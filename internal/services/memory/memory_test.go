@@ -0,0 +1,487 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/entrykey"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+	"github.com/choplin/vault.md/internal/services/memory"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+var (
+	_ usecase.EntryRepository = (*memory.EntryService)(nil)
+	_ usecase.ScopeRepository = (*memory.ScopeService)(nil)
+)
+
+func TestEntryServiceVersionsAndConflicts(t *testing.T) {
+	ctx := context.Background()
+	svc := memory.NewEntryService()
+
+	for v := int64(1); v <= 2; v++ {
+		if _, err := svc.Create(ctx, database.ScopedEntryRecord{ScopeID: 1, Key: "notes", Version: v, Size: v}); err != nil {
+			t.Fatalf("Create version %d failed: %v", v, err)
+		}
+	}
+
+	if _, err := svc.Create(ctx, database.ScopedEntryRecord{ScopeID: 1, Key: "notes", Version: 2}); !errors.Is(err, services.ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+
+	err := svc.CreateBatch(ctx, []database.ScopedEntryRecord{
+		{ScopeID: 1, Key: "other", Version: 1},
+		{ScopeID: 1, Key: "notes", Version: 1},
+	})
+	if !errors.Is(err, services.ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict from batch, got %v", err)
+	}
+	if _, err := svc.GetLatest(ctx, 1, "other"); !errors.Is(err, services.ErrNotFound) {
+		t.Fatalf("expected failed batch to store nothing, got %v", err)
+	}
+
+	latest, err := svc.GetLatest(ctx, 1, "notes")
+	if err != nil {
+		t.Fatalf("GetLatest failed: %v", err)
+	}
+	if latest.Version != 2 {
+		t.Fatalf("expected latest version 2, got %d", latest.Version)
+	}
+
+	superseded, err := svc.ListSuperseded(ctx, 1)
+	if err != nil {
+		t.Fatalf("ListSuperseded failed: %v", err)
+	}
+	if len(superseded) != 1 || superseded[0].Version != 1 {
+		t.Fatalf("expected version 1 to be superseded, got %+v", superseded)
+	}
+
+	if deleted, err := svc.DeleteVersion(ctx, 1, "notes", 2); err != nil || !deleted {
+		t.Fatalf("DeleteVersion = %t, %v", deleted, err)
+	}
+	if latest, err = svc.GetLatest(ctx, 1, "notes"); err != nil || latest.Version != 1 {
+		t.Fatalf("expected version 1 to become latest, got %+v, %v", latest, err)
+	}
+}
+
+func TestUsecaseOverMemoryRepositories(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("VAULT_DIR", dir)
+	t.Setenv("VAULT_CONFIG", filepath.Join(dir, "config.json"))
+	ctx := context.Background()
+
+	uc := usecase.NewEntryFromRepositories(memory.NewScopeService(), memory.NewEntryService())
+	sc := scope.NewGlobal()
+
+	for _, content := range []string{"first\n", "second\n"} {
+		if _, err := uc.Set(ctx, sc, "notes", content, nil); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+
+	got, err := uc.Get(ctx, sc, "notes", nil)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.Record.Version != 2 {
+		t.Fatalf("expected version 2, got %d", got.Record.Version)
+	}
+
+	list, err := uc.List(ctx, sc, &usecase.ListOptions{AllVersions: true})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list.Entries) != 2 {
+		t.Fatalf("expected 2 versions, got %d", len(list.Entries))
+	}
+
+	if _, err := uc.Devices(ctx); !errors.Is(err, usecase.ErrNoDatabase) {
+		t.Fatalf("expected ErrNoDatabase from Devices, got %v", err)
+	}
+}
+
+func TestDescribeScopeMergesMetadata(t *testing.T) {
+	ctx := context.Background()
+	uc := usecase.NewEntryFromRepositories(memory.NewScopeService(), memory.NewEntryService())
+	sc := scope.NewRepository("/repo")
+
+	description := "Shared design notes"
+	if _, err := uc.DescribeScope(ctx, sc, usecase.ScopeDescription{
+		Description: &description,
+		Set:         map[string]string{"owner": "docs", "status": "draft"},
+	}); err != nil {
+		t.Fatalf("DescribeScope failed: %v", err)
+	}
+
+	record, err := uc.DescribeScope(ctx, sc, usecase.ScopeDescription{
+		Set:   map[string]string{"status": "final"},
+		Unset: []string{"owner"},
+	})
+	if err != nil {
+		t.Fatalf("DescribeScope failed: %v", err)
+	}
+	if record.Description != description {
+		t.Fatalf("expected description to be kept, got %q", record.Description)
+	}
+	if len(record.Metadata) != 1 || record.Metadata["status"] != "final" {
+		t.Fatalf("unexpected metadata: %v", record.Metadata)
+	}
+
+	scopes, err := uc.Scopes(ctx, nil)
+	if err != nil {
+		t.Fatalf("Scopes failed: %v", err)
+	}
+	if len(scopes) != 1 || scopes[0].Record.Description != description || scopes[0].Keys != 0 {
+		t.Fatalf("unexpected scopes: %+v", scopes)
+	}
+}
+
+func TestFirstWriteSeedsKeyTemplates(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	t.Setenv("VAULT_DIR", dir)
+	t.Setenv("VAULT_CONFIG", configPath)
+	config := `{"keyTemplates": {"branch": {"plan": {"content": "# Plan\n"}, "progress": {"content": "# Progress\n"}}}}`
+	if err := os.WriteFile(configPath, []byte(config), 0o600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	ctx := context.Background()
+
+	uc := usecase.NewEntryFromRepositories(memory.NewScopeService(), memory.NewEntryService())
+	branch := scope.NewBranch("/repo", "feature")
+
+	result, err := uc.Set(ctx, branch, "plan", "my own plan\n", nil)
+	if err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if len(result.Templated) != 1 || result.Templated[0] != "progress" {
+		t.Fatalf("expected progress to be templated, got %v", result.Templated)
+	}
+
+	progress, err := uc.Get(ctx, branch, "progress", nil)
+	if err != nil {
+		t.Fatalf("Get progress failed: %v", err)
+	}
+	if progress.Record.Version != 1 {
+		t.Fatalf("expected templated version 1, got %d", progress.Record.Version)
+	}
+	plan, err := uc.Get(ctx, branch, "plan", nil)
+	if err != nil {
+		t.Fatalf("Get plan failed: %v", err)
+	}
+	if plan.Record.Version != 1 {
+		t.Fatalf("expected the written plan to be the only version, got %d", plan.Record.Version)
+	}
+
+	// Later writes, and scopes of other types, are left alone.
+	if result, err = uc.Set(ctx, branch, "notes", "x\n", nil); err != nil || len(result.Templated) != 0 {
+		t.Fatalf("expected no templates on a later write, got %v, %v", result, err)
+	}
+	if result, err = uc.Set(ctx, scope.NewGlobal(), "notes", "x\n", nil); err != nil || len(result.Templated) != 0 {
+		t.Fatalf("expected no templates for global scope, got %v, %v", result, err)
+	}
+}
+
+func TestPromoteCopiesAndArchivesBranch(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("VAULT_DIR", dir)
+	t.Setenv("VAULT_CONFIG", filepath.Join(dir, "config.json"))
+	ctx := context.Background()
+
+	uc := usecase.NewEntryFromRepositories(memory.NewScopeService(), memory.NewEntryService())
+	branch := scope.NewBranch("/repo", "feature")
+	repo := scope.NewRepository("/repo")
+
+	for key, content := range map[string]string{"plan": "plan\n", "adr/1": "adr\n", "scratch": "tmp\n"} {
+		if _, err := uc.Set(ctx, branch, key, content, nil); err != nil {
+			t.Fatalf("Set %s failed: %v", key, err)
+		}
+	}
+	if _, err := uc.Set(ctx, repo, "plan", "plan\n", nil); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	dry, err := uc.Promote(ctx, branch, repo, usecase.PromoteOptions{Patterns: []string{"adr/*", "plan"}, DryRun: true})
+	if err != nil {
+		t.Fatalf("Promote dry run failed: %v", err)
+	}
+	if len(dry.Keys) != 2 || len(dry.Archived) != 3 {
+		t.Fatalf("unexpected dry run result: %+v", dry)
+	}
+	if _, err := uc.Get(ctx, repo, "adr/1", nil); !errors.Is(err, services.ErrNotFound) {
+		t.Fatalf("expected dry run not to copy, got %v", err)
+	}
+
+	result, err := uc.Promote(ctx, branch, repo, usecase.PromoteOptions{Patterns: []string{"adr/*", "plan"}})
+	if err != nil {
+		t.Fatalf("Promote failed: %v", err)
+	}
+	if len(result.Keys) != 2 || result.Keys[0].Key != "adr/1" || result.Keys[0].ToVersion != 1 {
+		t.Fatalf("unexpected promoted keys: %+v", result.Keys)
+	}
+	if !result.Keys[1].Unchanged || result.Keys[1].ToVersion != 1 {
+		t.Fatalf("expected plan to be unchanged in the repository scope, got %+v", result.Keys[1])
+	}
+
+	remaining, err := uc.List(ctx, branch, nil)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(remaining.Entries) != 0 {
+		t.Fatalf("expected the branch scope to be archived, got %d entries", len(remaining.Entries))
+	}
+}
+
+func TestDiffScopesReportsChangedAndMissingKeys(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("VAULT_DIR", dir)
+	t.Setenv("VAULT_CONFIG", filepath.Join(dir, "config.json"))
+	ctx := context.Background()
+
+	uc := usecase.NewEntryFromRepositories(memory.NewScopeService(), memory.NewEntryService())
+	repo := scope.NewRepository("/repo")
+	branch := scope.NewBranch("/repo", "feature")
+
+	for _, write := range []struct {
+		sc           scope.Scope
+		key, content string
+	}{
+		{repo, "plan", "a\nb\nc\n"},
+		{branch, "plan", "a\nB\nc\n"},
+		{repo, "notes", "same\n"},
+		{branch, "notes", "same\n"},
+		{repo, "old", "x\n"},
+		{branch, "new", "y\n"},
+	} {
+		if _, err := uc.Set(ctx, write.sc, write.key, write.content, nil); err != nil {
+			t.Fatalf("Set %s failed: %v", write.key, err)
+		}
+	}
+
+	diff, err := uc.DiffScopes(ctx, repo, branch)
+	if err != nil {
+		t.Fatalf("DiffScopes failed: %v", err)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Key != "plan" || diff.Same != 1 {
+		t.Fatalf("unexpected diff: %+v", diff)
+	}
+	if !strings.Contains(diff.Changed[0].Diff, "-b\n+B\n") {
+		t.Fatalf("unexpected unified diff:\n%s", diff.Changed[0].Diff)
+	}
+	if len(diff.OnlyA) != 1 || diff.OnlyA[0] != "old" || len(diff.OnlyB) != 1 || diff.OnlyB[0] != "new" {
+		t.Fatalf("unexpected one-sided keys: %+v %+v", diff.OnlyA, diff.OnlyB)
+	}
+}
+
+func TestArchiveMatchingAppliesEveryFilter(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("VAULT_DIR", dir)
+	t.Setenv("VAULT_CONFIG", filepath.Join(dir, "config.json"))
+	ctx := context.Background()
+
+	uc := usecase.NewEntryFromRepositories(memory.NewScopeService(), memory.NewEntryService())
+	sc := scope.NewRepository("/repo")
+	for _, key := range []string{"tmp/a", "tmp/b", "plan"} {
+		if _, err := uc.Set(ctx, sc, key, key+"\n", nil); err != nil {
+			t.Fatalf("Set %s failed: %v", key, err)
+		}
+	}
+
+	if _, err := uc.ArchiveMatching(ctx, sc, usecase.ArchiveFilter{}); err == nil {
+		t.Fatal("expected an error without filters")
+	}
+	old, err := uc.ArchiveMatching(ctx, sc, usecase.ArchiveFilter{Prefix: "tmp/", Before: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("ArchiveMatching failed: %v", err)
+	}
+	if len(old) != 0 {
+		t.Fatalf("expected no entries older than an hour, got %d", len(old))
+	}
+
+	archived, err := uc.ArchiveMatching(ctx, sc, usecase.ArchiveFilter{Prefix: "tmp/"})
+	if err != nil {
+		t.Fatalf("ArchiveMatching failed: %v", err)
+	}
+	if len(archived) != 2 {
+		t.Fatalf("expected 2 archived entries, got %d", len(archived))
+	}
+	list, err := uc.List(ctx, sc, nil)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list.Entries) != 1 || list.Entries[0].Record.Key != "plan" {
+		t.Fatalf("unexpected remaining entries: %+v", list.Entries)
+	}
+
+	if ok, err := uc.Restore(ctx, sc, "tmp/a"); err != nil || !ok {
+		t.Fatalf("Restore = %v, %v", ok, err)
+	}
+	if ok, err := uc.Restore(ctx, sc, "tmp/a"); err != nil || ok {
+		t.Fatalf("second Restore = %v, %v", ok, err)
+	}
+}
+
+func TestUndoRevertsSetAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("VAULT_DIR", dir)
+	t.Setenv("VAULT_CONFIG", filepath.Join(dir, "config.json"))
+	ctx := context.Background()
+
+	uc := usecase.NewEntryFromRepositories(memory.NewScopeService(), memory.NewEntryService())
+	sc := scope.NewRepository("/repo")
+
+	if _, err := uc.Undo(ctx); !errors.Is(err, usecase.ErrNothingToUndo) {
+		t.Fatalf("expected ErrNothingToUndo, got %v", err)
+	}
+
+	if _, err := uc.Set(ctx, sc, "plan", "first\n", nil); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	second, err := uc.Set(ctx, sc, "plan", "second\n", nil)
+	if err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := usecase.RecordUndo(usecase.NewSetUndo(sc, "plan", second.Version)); err != nil {
+		t.Fatalf("RecordUndo failed: %v", err)
+	}
+	if _, err := uc.Undo(ctx); err != nil {
+		t.Fatalf("Undo set failed: %v", err)
+	}
+	got, err := uc.Get(ctx, sc, "plan", nil)
+	if err != nil || got.Record.Version != 1 {
+		t.Fatalf("expected version 1 after undoing the set, got %+v, %v", got, err)
+	}
+
+	undo, err := uc.CaptureDelete(ctx, sc, "plan", 0)
+	if err != nil {
+		t.Fatalf("CaptureDelete failed: %v", err)
+	}
+	if _, err := uc.DeleteKey(ctx, sc, "plan"); err != nil {
+		t.Fatalf("DeleteKey failed: %v", err)
+	}
+	if err := usecase.RecordUndo(undo); err != nil {
+		t.Fatalf("RecordUndo failed: %v", err)
+	}
+	if _, err := uc.Undo(ctx); err != nil {
+		t.Fatalf("Undo delete failed: %v", err)
+	}
+	restored, err := uc.Get(ctx, sc, "plan", nil)
+	if err != nil || restored.Record.Version != 1 || restored.Record.Hash != got.Record.Hash {
+		t.Fatalf("expected the deleted version back, got %+v, %v", restored, err)
+	}
+	if _, err := uc.Undo(ctx); !errors.Is(err, usecase.ErrNothingToUndo) {
+		t.Fatalf("expected the journal to be cleared, got %v", err)
+	}
+}
+
+func TestLoadContextReadsEveryScopeMostSpecificFirst(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("VAULT_DIR", dir)
+	t.Setenv("VAULT_CONFIG", filepath.Join(dir, "config.json"))
+	ctx := context.Background()
+
+	uc := usecase.NewEntryFromRepositories(memory.NewScopeService(), memory.NewEntryService())
+	branch := scope.NewBranch("/repo", "feature")
+	repo := scope.NewRepository("/repo")
+	global := scope.NewGlobal()
+
+	for _, write := range []struct {
+		sc           scope.Scope
+		key, content string
+	}{
+		{branch, "plan", "branch plan\n"},
+		{repo, "plan", "repo plan\n"},
+		{global, "conventions", "tabs\n"},
+	} {
+		if _, err := uc.Set(ctx, write.sc, write.key, write.content, nil); err != nil {
+			t.Fatalf("Set %s failed: %v", write.key, err)
+		}
+	}
+
+	result, err := uc.LoadContext(ctx, []scope.Scope{branch, repo, global}, []string{"plan", "conventions", "decisions"}, nil)
+	if err != nil {
+		t.Fatalf("LoadContext failed: %v", err)
+	}
+	var got []string
+	for _, e := range result.Entries {
+		got = append(got, e.Record.Key+"@"+string(e.Scope.Type)+"="+strings.TrimSpace(e.Content))
+	}
+	want := "plan@branch=branch plan,plan@repository=repo plan,conventions@global=tabs"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("unexpected entries %v, want %s", got, want)
+	}
+	if len(result.Missing) != 1 || result.Missing[0] != "decisions" {
+		t.Fatalf("expected decisions to be missing, got %v", result.Missing)
+	}
+}
+
+func TestLookupsFindNormalizedKeys(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("VAULT_DIR", dir)
+	t.Setenv("VAULT_CONFIG", filepath.Join(dir, "config.json"))
+	ctx := context.Background()
+
+	uc := usecase.NewEntryFromRepositories(memory.NewScopeService(), memory.NewEntryService())
+	sc := scope.NewGlobal()
+
+	decomposed := "cafe\u0301"
+	for _, key := range []string{" spaced ", decomposed} {
+		if _, err := uc.Set(ctx, sc, key, "v1\n", nil); err != nil {
+			t.Fatalf("Set %q failed: %v", key, err)
+		}
+	}
+	if _, err := uc.Get(ctx, sc, "spaced", nil); err != nil {
+		t.Fatalf("expected %q to be stored as spaced: %v", " spaced ", err)
+	}
+
+	for _, key := range []string{" spaced ", decomposed} {
+		got, err := uc.Get(ctx, sc, key, nil)
+		if err != nil {
+			t.Fatalf("Get %q failed: %v", key, err)
+		}
+		if got.Record.Key == key {
+			t.Fatalf("expected %q to be read back under its normalized key", key)
+		}
+		if _, err := uc.Info(ctx, sc, key, nil); err != nil {
+			t.Fatalf("Info %q failed: %v", key, err)
+		}
+		if history, err := uc.History(ctx, sc, key, nil); err != nil || len(history) != 1 {
+			t.Fatalf("History %q = %v, %v; want one version", key, history, err)
+		}
+		if n, err := uc.DeleteKey(ctx, sc, key); err != nil || n != 1 {
+			t.Fatalf("DeleteKey %q = %d, %v; want 1", key, n, err)
+		}
+		if _, err := uc.Get(ctx, sc, key, nil); !errors.Is(err, services.ErrNotFound) {
+			t.Fatalf("expected %q to be deleted, got %v", key, err)
+		}
+	}
+}
+
+func TestReservedKeysRejectUserWrites(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("VAULT_DIR", dir)
+	t.Setenv("VAULT_CONFIG", filepath.Join(dir, "config.json"))
+	ctx := context.Background()
+
+	uc := usecase.NewEntryFromRepositories(memory.NewScopeService(), memory.NewEntryService())
+	sc := scope.NewGlobal()
+
+	if _, err := uc.Set(ctx, sc, "_vault/state", "v1\n", &usecase.SetOptions{Internal: true}); err != nil {
+		t.Fatalf("internal Set failed: %v", err)
+	}
+	for _, key := range []string{"_vault/state", "_vault/new", " _vault/state"} {
+		if _, err := uc.Set(ctx, sc, key, "v2\n", nil); !errors.Is(err, entrykey.ErrReserved) {
+			t.Fatalf("Set %q = %v, want ErrReserved", key, err)
+		}
+	}
+	if got, err := uc.Get(ctx, sc, "_vault/state", nil); err != nil || got.Record.Version != 1 {
+		t.Fatalf("Get = %v, %v; want the internal version untouched", got, err)
+	}
+}
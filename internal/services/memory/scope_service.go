@@ -0,0 +1,128 @@
+// Package memory provides in-memory implementations of the repositories the
+// usecase layer consumes, so handlers can be exercised without SQLite. They
+// follow the semantics of their database-backed counterparts in package
+// services, including the errors they return.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// ScopeService keeps scopes in memory.
+type ScopeService struct {
+	mu     sync.Mutex
+	nextID int64
+	scopes map[string]*database.ScopeRecord
+}
+
+// NewScopeService creates an empty ScopeService.
+func NewScopeService() *ScopeService {
+	return &ScopeService{scopes: map[string]*database.ScopeRecord{}}
+}
+
+// GetOrCreate retrieves or creates a scope and returns its ID.
+func (s *ScopeService) GetOrCreate(_ context.Context, sc scope.Scope) (int64, error) {
+	switch sc.Type {
+	case scope.ScopeGlobal, scope.ScopeRepository, scope.ScopeBranch, scope.ScopeWorktree:
+	default:
+		return 0, fmt.Errorf("unsupported scope type: %s", sc.Type)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := scope.GetScopeStorageKey(sc)
+	now := time.Now().UTC()
+	if record, ok := s.scopes[path]; ok {
+		record.Scope = sc
+		record.UpdatedAt = now
+		return record.ID, nil
+	}
+
+	s.nextID++
+	s.scopes[path] = &database.ScopeRecord{
+		ID:        s.nextID,
+		Scope:     sc,
+		ScopePath: path,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	return s.nextID, nil
+}
+
+// GetAll retrieves all scopes ordered by type, primary path, and branch.
+func (s *ScopeService) GetAll(_ context.Context) ([]database.ScopeRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]database.ScopeRecord, 0, len(s.scopes))
+	for _, record := range s.scopes {
+		result = append(result, copyScope(record))
+	}
+	slices.SortFunc(result, func(a, b database.ScopeRecord) int {
+		return cmp.Or(
+			cmp.Compare(a.Scope.Type, b.Scope.Type),
+			cmp.Compare(a.Scope.PrimaryPath, b.Scope.PrimaryPath),
+			cmp.Compare(a.Scope.BranchName, b.Scope.BranchName),
+		)
+	})
+	return result, nil
+}
+
+// GetByID retrieves a scope by its ID.
+func (s *ScopeService) GetByID(_ context.Context, id int64) (*database.ScopeRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := s.byID(id)
+	if record == nil {
+		return nil, services.ErrNotFound
+	}
+	result := copyScope(record)
+	return &result, nil
+}
+
+// Describe sets the description and metadata of a scope, replacing what
+// it had. Empty values clear them.
+func (s *ScopeService) Describe(_ context.Context, id int64, description string, metadata map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := s.byID(id)
+	if record == nil {
+		return services.ErrNotFound
+	}
+	record.Description = description
+	record.Metadata = nil
+	if len(metadata) > 0 {
+		record.Metadata = maps.Clone(metadata)
+	}
+	record.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+func (s *ScopeService) byID(id int64) *database.ScopeRecord {
+	for _, record := range s.scopes {
+		if record.ID == id {
+			return record
+		}
+	}
+	return nil
+}
+
+// copyScope copies a record so callers cannot change the stored metadata.
+func copyScope(record *database.ScopeRecord) database.ScopeRecord {
+	result := *record
+	result.Metadata = maps.Clone(record.Metadata)
+	return result
+}
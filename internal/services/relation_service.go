@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/choplin/vault.md/internal/database"
+	sqldb "github.com/choplin/vault.md/internal/database/sqlc"
+)
+
+// RelationService records which entries are children of which, such as the
+// chapters of a large document. Relations are kept by key, so a parent can
+// be declared before the child is written and survives the child being
+// deleted and written again.
+type RelationService struct {
+	ctx *database.Context
+}
+
+// NewRelationService creates a new RelationService.
+func NewRelationService(ctx *database.Context) *RelationService {
+	return &RelationService{ctx: ctx}
+}
+
+// Parent returns the relation declaring child's parent, or ErrNotFound.
+func (s *RelationService) Parent(ctx context.Context, scopeID int64, child string) (*database.ChildRecord, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	row, err := q.GetEntryParent(ctx, sqldb.GetEntryParentParams{ScopeID: scopeID, ChildKey: child})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	record := database.ChildRecordFromRow(row)
+	return &record, nil
+}
+
+// Children returns the children of parent in order.
+func (s *RelationService) Children(ctx context.Context, scopeID int64, parent string) ([]database.ChildRecord, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.ListEntryChildren(ctx, sqldb.ListEntryChildrenParams{ScopeID: scopeID, ParentKey: parent})
+	if err != nil {
+		return nil, err
+	}
+	records := make([]database.ChildRecord, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, database.ChildRecordFromRow(row))
+	}
+	return records, nil
+}
+
+// SetParent makes child a child of parent at position, replacing any parent
+// it had. A nil position keeps the position of a child that already belongs
+// to parent and puts a new one after its last sibling. Relations that would
+// make an entry its own ancestor are rejected.
+func (s *RelationService) SetParent(ctx context.Context, scopeID int64, child, parent string, position *int64) (*database.ChildRecord, error) {
+	if child == parent {
+		return nil, fmt.Errorf("%s cannot be its own parent", child)
+	}
+	record := &database.ChildRecord{ScopeID: scopeID, ChildKey: child, ParentKey: parent}
+	err := s.withTx(ctx, func(txCtx context.Context, q *sqldb.Queries) error {
+		for ancestor := parent; ; {
+			row, err := q.GetEntryParent(txCtx, sqldb.GetEntryParentParams{ScopeID: scopeID, ChildKey: ancestor})
+			if errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if row.ParentKey == child {
+				return fmt.Errorf("%s cannot be a child of %s, which descends from it", child, parent)
+			}
+			ancestor = row.ParentKey
+		}
+
+		switch current, err := q.GetEntryParent(txCtx, sqldb.GetEntryParentParams{ScopeID: scopeID, ChildKey: child}); {
+		case position != nil:
+			record.Position = *position
+		case err == nil && current.ParentKey == parent:
+			record.Position = current.Position
+		case err != nil && !errors.Is(err, sql.ErrNoRows):
+			return err
+		default:
+			last, err := q.MaxChildPosition(txCtx, sqldb.MaxChildPositionParams{ScopeID: scopeID, ParentKey: parent})
+			if err != nil {
+				return err
+			}
+			record.Position = last + 1
+		}
+
+		return q.UpsertEntryParent(txCtx, sqldb.UpsertEntryParentParams{
+			ScopeID:   scopeID,
+			ChildKey:  child,
+			ParentKey: parent,
+			Position:  record.Position,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// ClearParent removes child's parent and returns false if it had none.
+func (s *RelationService) ClearParent(ctx context.Context, scopeID int64, child string) (bool, error) {
+	var cleared bool
+	err := s.withTx(ctx, func(txCtx context.Context, q *sqldb.Queries) error {
+		affected, err := q.DeleteEntryParent(txCtx, sqldb.DeleteEntryParentParams{ScopeID: scopeID, ChildKey: child})
+		cleared = affected > 0
+		return err
+	})
+	return cleared, err
+}
+
+func (s *RelationService) withTx(ctx context.Context, fn func(context.Context, *sqldb.Queries) error) error {
+	if s.ctx == nil || s.ctx.DB == nil {
+		return fmt.Errorf("relation service: missing database context")
+	}
+	if s.ctx.InTx() {
+		// Nest in the transaction the caller opened with RunInTx.
+		return s.ctx.RunInTx(ctx, func(txCtx *database.Context) error {
+			return fn(ctx, txCtx.Queries)
+		})
+	}
+
+	return s.ctx.RunWrite(ctx, func() error {
+		tx, err := s.ctx.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		queries := s.ctx.TxQueries(tx)
+		if err := fn(ctx, queries); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (s *RelationService) queries() (*sqldb.Queries, error) {
+	if s.ctx == nil {
+		return nil, fmt.Errorf("relation service: missing database context")
+	}
+	if s.ctx.Queries == nil {
+		if s.ctx.DB == nil {
+			return nil, fmt.Errorf("relation service: database handle not initialised")
+		}
+		s.ctx.Queries = sqldb.New(s.ctx.DB)
+	}
+	return s.ctx.Queries, nil
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/choplin/vault.md/internal/scope"
+)
+
+func TestRelationServiceOrdersChildrenAndRejectsCycles(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeID, err := NewScopeService(dbCtx).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	svc := NewRelationService(dbCtx)
+	for _, child := range []string{"book/ch2", "book/ch3"} {
+		if _, err := svc.SetParent(ctx, scopeID, child, "book", nil); err != nil {
+			t.Fatalf("SetParent %s failed: %v", child, err)
+		}
+	}
+	first := int64(0)
+	if _, err := svc.SetParent(ctx, scopeID, "book/ch1", "book", &first); err != nil {
+		t.Fatalf("SetParent with position failed: %v", err)
+	}
+	// Declaring the same parent again keeps the position.
+	again, err := svc.SetParent(ctx, scopeID, "book/ch2", "book", nil)
+	if err != nil || again.Position != 1 {
+		t.Fatalf("SetParent again = %#v, %v; want position 1", again, err)
+	}
+
+	children, err := svc.Children(ctx, scopeID, "book")
+	if err != nil {
+		t.Fatalf("Children failed: %v", err)
+	}
+	var keys []string
+	for _, c := range children {
+		keys = append(keys, c.ChildKey)
+	}
+	if want := []string{"book/ch1", "book/ch2", "book/ch3"}; len(keys) != len(want) || keys[0] != want[0] || keys[1] != want[1] || keys[2] != want[2] {
+		t.Fatalf("children = %v, want %v", keys, want)
+	}
+
+	if _, err := svc.SetParent(ctx, scopeID, "book/ch1/section", "book/ch1", nil); err != nil {
+		t.Fatalf("SetParent grandchild failed: %v", err)
+	}
+	if _, err := svc.SetParent(ctx, scopeID, "book", "book/ch1/section", nil); err == nil {
+		t.Fatal("expected a cycle to be rejected")
+	}
+	if _, err := svc.SetParent(ctx, scopeID, "book", "book", nil); err == nil {
+		t.Fatal("expected an entry to be rejected as its own parent")
+	}
+
+	cleared, err := svc.ClearParent(ctx, scopeID, "book/ch3")
+	if err != nil || !cleared {
+		t.Fatalf("ClearParent = %v, %v", cleared, err)
+	}
+	if _, err := svc.Parent(ctx, scopeID, "book/ch3"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after ClearParent, got %v", err)
+	}
+}
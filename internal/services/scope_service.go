@@ -112,6 +112,24 @@ func (s *ScopeService) GetAll(ctx context.Context) ([]database.ScopeRecord, erro
 	return result, nil
 }
 
+// Describe sets the description and metadata of a scope, replacing what
+// it had. Empty values clear them.
+func (s *ScopeService) Describe(ctx context.Context, id int64, description string, metadata map[string]string) error {
+	params, err := database.ScopeDescriptionParams(id, description, metadata)
+	if err != nil {
+		return err
+	}
+	return s.withTx(ctx, func(txCtx context.Context, q *sqldb.Queries) error {
+		if _, err := q.FindScopeByID(txCtx, id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+		return q.UpdateScopeDescription(txCtx, params)
+	})
+}
+
 // GetAllEntriesGrouped retrieves all entries grouped by scope.
 func (s *ScopeService) GetAllEntriesGrouped(ctx context.Context) (map[scope.Scope][]database.ScopedEntryRecord, error) {
 	scopes, err := s.GetAll(ctx)
@@ -250,7 +268,7 @@ func (s *ScopeService) listEntriesByScopes(ctx context.Context, scopeIDs []int64
 
 		entries := make([]database.ScopedEntryRecord, 0, len(rows))
 		for _, row := range rows {
-			entries = append(entries, database.ScopedEntryRecordFromRow(row.EntryID, row.ScopeID, row.Key, row.EntryCreatedAt, row.IsArchived, row.Version, row.FilePath, row.Hash, row.Description))
+			entries = append(entries, database.ScopedEntryRecordFromRow(row.EntryID, row.ScopeID, row.Key, row.EntryCreatedAt, row.IsArchived, row.Version, row.FilePath, row.Hash, row.Description, row.VersionCreatedAt, row.Size, row.Language, row.Summary, row.LastReadAt, row.ReadCount))
 		}
 		result[scopeID] = entries
 	}
@@ -262,24 +280,32 @@ func (s *ScopeService) withTx(ctx context.Context, fn func(context.Context, *sql
 	if s.ctx == nil || s.ctx.DB == nil {
 		return fmt.Errorf("scope service: missing database context")
 	}
-
-	tx, err := s.ctx.DB.BeginTx(ctx, nil)
-	if err != nil {
-		return err
+	if s.ctx.InTx() {
+		// Nest in the transaction the caller opened with RunInTx.
+		return s.ctx.RunInTx(ctx, func(txCtx *database.Context) error {
+			return fn(ctx, txCtx.Queries)
+		})
 	}
 
-	queries := sqldb.New(tx)
-	if err := fn(ctx, queries); err != nil {
-		_ = tx.Rollback()
-		return err
-	}
+	return s.ctx.RunWrite(ctx, func() error {
+		tx, err := s.ctx.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
 
-	if err := tx.Commit(); err != nil {
-		_ = tx.Rollback()
-		return err
-	}
+		queries := s.ctx.TxQueries(tx)
+		if err := fn(ctx, queries); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
 
-	return nil
+		if err := tx.Commit(); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		return nil
+	})
 }
 
 func (s *ScopeService) queries() (*sqldb.Queries, error) {
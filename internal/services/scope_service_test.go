@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/choplin/vault.md/internal/database"
@@ -97,3 +98,50 @@ func TestScopeServiceDeleteAllBranches(t *testing.T) {
 		}
 	}
 }
+
+func TestScopeServiceDescribe(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeSvc := NewScopeService(dbCtx)
+	branch := scope.NewBranch("/repo", "feature/login")
+	scopeID, err := scopeSvc.GetOrCreate(ctx, branch)
+	if err != nil {
+		t.Fatalf("GetOrCreate failed: %v", err)
+	}
+
+	metadata := map[string]string{"owner": "auth-team", "ticket": "AUTH-12"}
+	if err := scopeSvc.Describe(ctx, scopeID, "Login rework notes", metadata); err != nil {
+		t.Fatalf("Describe failed: %v", err)
+	}
+	// Resolving the scope again must keep its description.
+	if _, err := scopeSvc.GetOrCreate(ctx, branch); err != nil {
+		t.Fatalf("GetOrCreate failed: %v", err)
+	}
+
+	scopes, err := scopeSvc.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(scopes) != 1 || scopes[0].Description != "Login rework notes" {
+		t.Fatalf("expected described scope, got %+v", scopes)
+	}
+	if scopes[0].Metadata["owner"] != "auth-team" || scopes[0].Metadata["ticket"] != "AUTH-12" {
+		t.Fatalf("unexpected metadata: %v", scopes[0].Metadata)
+	}
+
+	if err := scopeSvc.Describe(ctx, scopeID, "", nil); err != nil {
+		t.Fatalf("Describe failed: %v", err)
+	}
+	record, err := scopeSvc.GetByID(ctx, scopeID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if record.Description != "" || record.Metadata != nil {
+		t.Fatalf("expected description and metadata cleared, got %q %v", record.Description, record.Metadata)
+	}
+
+	if err := scopeSvc.Describe(ctx, scopeID+1, "missing", nil); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for unknown scope, got %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/choplin/vault.md/internal/database"
+	sqldb "github.com/choplin/vault.md/internal/database/sqlc"
+)
+
+// ErrScopeSnapshotExists is returned when creating a scope snapshot under a
+// name the scope already uses.
+var ErrScopeSnapshotExists = errors.New("scope snapshot already exists")
+
+// ScopeSnapshotService keeps named scope snapshots: the version that was
+// latest for every key of a scope at one moment, so that reads can be
+// repeated against exactly those versions later. Only version numbers are
+// recorded; content stays with the versions themselves.
+type ScopeSnapshotService struct {
+	ctx *database.Context
+}
+
+// NewScopeSnapshotService creates a new ScopeSnapshotService.
+func NewScopeSnapshotService(ctx *database.Context) *ScopeSnapshotService {
+	return &ScopeSnapshotService{ctx: ctx}
+}
+
+// Create pins the current version of every key in the scope under name.
+func (s *ScopeSnapshotService) Create(ctx context.Context, scopeID int64, name string) (*database.ScopeSnapshotRecord, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("scope snapshot name is empty")
+	}
+	var record database.ScopeSnapshotRecord
+	err := s.withTx(ctx, func(txCtx context.Context, q *sqldb.Queries) error {
+		_, err := q.GetScopeSnapshot(txCtx, sqldb.GetScopeSnapshotParams{ScopeID: scopeID, Name: name})
+		if err == nil {
+			return fmt.Errorf("%w: %s", ErrScopeSnapshotExists, name)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		result, err := q.InsertScopeSnapshot(txCtx, sqldb.InsertScopeSnapshotParams{ScopeID: scopeID, Name: name})
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if _, err := q.PinScopeSnapshotVersions(txCtx, sqldb.PinScopeSnapshotVersionsParams{SnapshotID: id, ScopeID: scopeID}); err != nil {
+			return err
+		}
+		row, err := q.GetScopeSnapshot(txCtx, sqldb.GetScopeSnapshotParams{ScopeID: scopeID, Name: name})
+		if err != nil {
+			return err
+		}
+		record = database.ScopeSnapshotRecordFromRow(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Import recreates a scope snapshot taken elsewhere, pinning exactly pins
+// and keeping its creation time. It returns false, changing nothing, when
+// the scope already has a snapshot called name.
+func (s *ScopeSnapshotService) Import(ctx context.Context, scopeID int64, name string, createdAt time.Time, pins []database.PinnedVersion) (bool, error) {
+	if strings.TrimSpace(name) == "" {
+		return false, fmt.Errorf("scope snapshot name is empty")
+	}
+	created := false
+	err := s.withTx(ctx, func(txCtx context.Context, q *sqldb.Queries) error {
+		_, err := q.GetScopeSnapshot(txCtx, sqldb.GetScopeSnapshotParams{ScopeID: scopeID, Name: name})
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		result, err := q.InsertScopeSnapshotAt(txCtx, sqldb.InsertScopeSnapshotAtParams{
+			ScopeID:   scopeID,
+			Name:      name,
+			CreatedAt: createdAt.UTC().Format(database.TimestampLayout),
+		})
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for _, pin := range pins {
+			if err := q.InsertScopeSnapshotVersion(txCtx, sqldb.InsertScopeSnapshotVersionParams{
+				SnapshotID: id,
+				Key:        pin.Key,
+				Version:    pin.Version,
+			}); err != nil {
+				return err
+			}
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+// Get returns the scope snapshot called name, or ErrNotFound.
+func (s *ScopeSnapshotService) Get(ctx context.Context, scopeID int64, name string) (*database.ScopeSnapshotRecord, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	row, err := q.GetScopeSnapshot(ctx, sqldb.GetScopeSnapshotParams{ScopeID: scopeID, Name: name})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: scope snapshot %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	record := database.ScopeSnapshotRecordFromRow(row)
+	return &record, nil
+}
+
+// List returns the scope snapshots of the scope, oldest first.
+func (s *ScopeSnapshotService) List(ctx context.Context, scopeID int64) ([]database.ScopeSnapshotRecord, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.ListScopeSnapshots(ctx, scopeID)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]database.ScopeSnapshotRecord, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, database.ScopeSnapshotRecordFromRow(sqldb.GetScopeSnapshotRow(row)))
+	}
+	return records, nil
+}
+
+// Versions returns the versions snapshotID pins, by key.
+func (s *ScopeSnapshotService) Versions(ctx context.Context, snapshotID int64) ([]database.PinnedVersion, error) {
+	q, err := s.queries()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.ListScopeSnapshotVersions(ctx, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	pinned := make([]database.PinnedVersion, 0, len(rows))
+	for _, row := range rows {
+		pinned = append(pinned, database.PinnedVersion{Key: row.Key, Version: row.Version})
+	}
+	return pinned, nil
+}
+
+// PinnedVersion returns the version of key the snapshot called name pins.
+// It returns ErrNotFound if there is no such snapshot or the key had no
+// version when it was created.
+func (s *ScopeSnapshotService) PinnedVersion(ctx context.Context, scopeID int64, name, key string) (int64, error) {
+	q, err := s.queries()
+	if err != nil {
+		return 0, err
+	}
+	version, err := q.GetScopeSnapshotVersion(ctx, sqldb.GetScopeSnapshotVersionParams{ScopeID: scopeID, Name: name, Key: key})
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := s.Get(ctx, scopeID, name); err != nil {
+			return 0, err
+		}
+		return 0, fmt.Errorf("%w: %s is not in scope snapshot %s", ErrNotFound, key, name)
+	}
+	return version, err
+}
+
+// Delete removes the snapshot called name and returns false if there was
+// none. The versions it pinned are kept.
+func (s *ScopeSnapshotService) Delete(ctx context.Context, scopeID int64, name string) (bool, error) {
+	var deleted bool
+	err := s.withTx(ctx, func(txCtx context.Context, q *sqldb.Queries) error {
+		affected, err := q.DeleteScopeSnapshot(txCtx, sqldb.DeleteScopeSnapshotParams{ScopeID: scopeID, Name: name})
+		deleted = affected > 0
+		return err
+	})
+	return deleted, err
+}
+
+func (s *ScopeSnapshotService) withTx(ctx context.Context, fn func(context.Context, *sqldb.Queries) error) error {
+	if s.ctx == nil || s.ctx.DB == nil {
+		return fmt.Errorf("scope snapshot service: missing database context")
+	}
+	if s.ctx.InTx() {
+		// Nest in the transaction the caller opened with RunInTx.
+		return s.ctx.RunInTx(ctx, func(txCtx *database.Context) error {
+			return fn(ctx, txCtx.Queries)
+		})
+	}
+
+	return s.ctx.RunWrite(ctx, func() error {
+		tx, err := s.ctx.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		queries := s.ctx.TxQueries(tx)
+		if err := fn(ctx, queries); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (s *ScopeSnapshotService) queries() (*sqldb.Queries, error) {
+	if s.ctx == nil {
+		return nil, fmt.Errorf("scope snapshot service: missing database context")
+	}
+	if s.ctx.Queries == nil {
+		if s.ctx.DB == nil {
+			return nil, fmt.Errorf("scope snapshot service: database handle not initialised")
+		}
+		s.ctx.Queries = sqldb.New(s.ctx.DB)
+	}
+	return s.ctx.Queries, nil
+}
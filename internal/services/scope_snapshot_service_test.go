@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+)
+
+func TestScopeSnapshotServicePinsCurrentVersions(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeID, err := NewScopeService(dbCtx).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+	entries := NewEntryService(dbCtx)
+	create := func(key string, version int64) {
+		t.Helper()
+		if _, err := entries.Create(ctx, database.ScopedEntryRecord{ScopeID: scopeID, Key: key, Version: version, FilePath: key, Hash: key}); err != nil {
+			t.Fatalf("Create %s v%d failed: %v", key, version, err)
+		}
+	}
+	create("plan", 1)
+	create("plan", 2)
+	create("notes", 1)
+
+	svc := NewScopeSnapshotService(dbCtx)
+	snapshot, err := svc.Create(ctx, scopeID, "release-1.0")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if snapshot.KeyCount != 2 {
+		t.Fatalf("KeyCount = %d, want 2", snapshot.KeyCount)
+	}
+	if _, err := svc.Create(ctx, scopeID, "release-1.0"); !errors.Is(err, ErrScopeSnapshotExists) {
+		t.Fatalf("expected ErrScopeSnapshotExists, got %v", err)
+	}
+
+	create("plan", 3)
+	create("later", 1)
+	version, err := svc.PinnedVersion(ctx, scopeID, "release-1.0", "plan")
+	if err != nil || version != 2 {
+		t.Fatalf("PinnedVersion(plan) = %d, %v; want 2", version, err)
+	}
+	if _, err := svc.PinnedVersion(ctx, scopeID, "release-1.0", "later"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a key written after the snapshot, got %v", err)
+	}
+	if _, err := svc.PinnedVersion(ctx, scopeID, "missing", "plan"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing snapshot, got %v", err)
+	}
+
+	pinned, err := svc.Versions(ctx, snapshot.ID)
+	if err != nil {
+		t.Fatalf("Versions failed: %v", err)
+	}
+	if len(pinned) != 2 || pinned[0] != (database.PinnedVersion{Key: "notes", Version: 1}) || pinned[1] != (database.PinnedVersion{Key: "plan", Version: 2}) {
+		t.Fatalf("unexpected pinned versions: %#v", pinned)
+	}
+
+	deleted, err := svc.Delete(ctx, scopeID, "release-1.0")
+	if err != nil || !deleted {
+		t.Fatalf("Delete = %v, %v", deleted, err)
+	}
+	list, err := svc.List(ctx, scopeID)
+	if err != nil || len(list) != 0 {
+		t.Fatalf("List after Delete = %#v, %v", list, err)
+	}
+}
+
+func TestScopeSnapshotServiceImport(t *testing.T) {
+	dbCtx := setupServiceDB(t)
+	ctx := context.Background()
+
+	scopeID, err := NewScopeService(dbCtx).GetOrCreate(ctx, scope.NewGlobal())
+	if err != nil {
+		t.Fatalf("GetOrCreate scope failed: %v", err)
+	}
+
+	svc := NewScopeSnapshotService(dbCtx)
+	createdAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	pins := []database.PinnedVersion{{Key: "notes", Version: 4}, {Key: "plan", Version: 2}}
+	created, err := svc.Import(ctx, scopeID, "release-1.0", createdAt, pins)
+	if err != nil || !created {
+		t.Fatalf("Import = %v, %v", created, err)
+	}
+	if created, err := svc.Import(ctx, scopeID, "release-1.0", createdAt, nil); err != nil || created {
+		t.Fatalf("importing again = %v, %v; want false, nil", created, err)
+	}
+
+	record, err := svc.Get(ctx, scopeID, "release-1.0")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if !record.CreatedAt.Equal(createdAt) || record.KeyCount != 2 {
+		t.Fatalf("unexpected snapshot: %#v", record)
+	}
+	pinned, err := svc.Versions(ctx, record.ID)
+	if err != nil || len(pinned) != 2 || pinned[0] != pins[0] || pinned[1] != pins[1] {
+		t.Fatalf("Versions = %#v, %v; want %#v", pinned, err, pins)
+	}
+}
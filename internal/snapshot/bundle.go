@@ -0,0 +1,265 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxBundleFile caps a single file read from a bundle, so a crafted archive
+// cannot exhaust memory.
+const maxBundleFile = 1 << 30
+
+// BundleStore is a Store held in memory and written out as one gzipped tar
+// archive, with the same layout as DirStore. It is the single-file form vault
+// export produces for mail or upload, as opposed to a backup target.
+type BundleStore struct {
+	files map[string][]byte
+}
+
+// NewBundleStore returns an empty bundle.
+func NewBundleStore() *BundleStore {
+	return &BundleStore{files: map[string][]byte{}}
+}
+
+// IsBundle reports whether data starts like a bundle written by
+// BundleStore.Bytes.
+func IsBundle(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
+
+// ReadBundle parses a bundle written by BundleStore.Bytes.
+func ReadBundle(data []byte) (*BundleStore, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("not a vault bundle: %w", err)
+	}
+	defer func() {
+		_ = zr.Close()
+	}()
+
+	s := NewBundleStore()
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return s, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("not a vault bundle: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := io.ReadAll(io.LimitReader(tr, maxBundleFile+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(content) > maxBundleFile {
+			return nil, fmt.Errorf("bundle file %s is too large", hdr.Name)
+		}
+		s.files[hdr.Name] = content
+	}
+}
+
+// Bytes returns the bundle as a gzipped tar archive. Files are written in
+// name order with a fixed time, so the same content gives the same bytes.
+func (s *BundleStore) Bytes() ([]byte, error) {
+	names := make([]string, 0, len(s.files))
+	for name := range s.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, name := range names {
+		content := s.files[name]
+		hdr := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0o600,
+			Size:     int64(len(content)),
+			ModTime:  time.Unix(0, 0),
+			Format:   tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *BundleStore) get(name string) ([]byte, error) {
+	content, ok := s.files[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	return content, nil
+}
+
+func (s *BundleStore) list(dir string) []string {
+	var names []string
+	for name := range s.files {
+		if rest, ok := strings.CutPrefix(name, dir); ok {
+			names = append(names, rest)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HasObject implements Store.
+func (s *BundleStore) HasObject(hash string) (bool, error) {
+	key, err := objectKey(hash)
+	if err != nil {
+		return false, err
+	}
+	_, ok := s.files[key]
+	return ok, nil
+}
+
+// PutObject implements Store.
+func (s *BundleStore) PutObject(hash string, content io.Reader) error {
+	key, err := objectKey(hash)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	s.files[key] = data
+	return nil
+}
+
+// GetObject implements Store.
+func (s *BundleStore) GetObject(hash string) ([]byte, error) {
+	key, err := objectKey(hash)
+	if err != nil {
+		return nil, err
+	}
+	return s.get(key)
+}
+
+// ListObjects implements Store.
+func (s *BundleStore) ListObjects() ([]string, error) {
+	var hashes []string
+	for _, name := range s.list("objects/") {
+		base := name[strings.LastIndex(name, "/")+1:]
+		if base != "" && !strings.HasPrefix(base, ".") {
+			hashes = append(hashes, base)
+		}
+	}
+	return hashes, nil
+}
+
+// DeleteObject implements Store.
+func (s *BundleStore) DeleteObject(hash string) error {
+	key, err := objectKey(hash)
+	if err != nil {
+		return err
+	}
+	if _, err := s.get(key); err != nil {
+		return err
+	}
+	delete(s.files, key)
+	return nil
+}
+
+// PutManifest implements Store.
+func (s *BundleStore) PutManifest(id string, data []byte) error {
+	key, err := manifestKey(id)
+	if err != nil {
+		return err
+	}
+	s.files[key] = data
+	return nil
+}
+
+// GetManifest implements Store.
+func (s *BundleStore) GetManifest(id string) ([]byte, error) {
+	key, err := manifestKey(id)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := s.files[key]
+	if !ok {
+		return nil, fmt.Errorf("snapshot %s not found", id)
+	}
+	return data, nil
+}
+
+// ListManifests implements Store.
+func (s *BundleStore) ListManifests() ([]string, error) {
+	var ids []string
+	for _, name := range s.list("snapshots/") {
+		if strings.Contains(name, "/") || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(name, ".json"))
+	}
+	return ids, nil
+}
+
+// DeleteManifest implements Store.
+func (s *BundleStore) DeleteManifest(id string) error {
+	key, err := manifestKey(id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.get(key); err != nil {
+		return err
+	}
+	delete(s.files, key)
+	delete(s.files, sumsKey(key))
+	delete(s.files, sumsKey(key)+".sig")
+	return nil
+}
+
+// PutSums implements Store.
+func (s *BundleStore) PutSums(id string, sums, sig []byte) error {
+	key, err := manifestKey(id)
+	if err != nil {
+		return err
+	}
+	key = sumsKey(key)
+	s.files[key] = sums
+	if sig == nil {
+		delete(s.files, key+".sig")
+	} else {
+		s.files[key+".sig"] = sig
+	}
+	return nil
+}
+
+// GetSums implements Store.
+func (s *BundleStore) GetSums(id string) ([]byte, []byte, error) {
+	key, err := manifestKey(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	key = sumsKey(key)
+	sums, err := s.get(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sums, s.files[key+".sig"], nil
+}
@@ -0,0 +1,59 @@
+package snapshot
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBundleStore(t *testing.T) {
+	checkStore(t, NewBundleStore())
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	bundle := NewBundleStore()
+	hash := strings.Repeat("cd", 32)
+	if err := bundle.PutObject(hash, strings.NewReader("content")); err != nil {
+		t.Fatal(err)
+	}
+	if err := bundle.PutManifest("20260101T000000.000Z", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if err := bundle.PutSums("20260101T000000.000Z", []byte("sums"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := bundle.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	if !IsBundle(data) {
+		t.Fatal("IsBundle should recognise a written bundle")
+	}
+	again, err := bundle.Bytes()
+	if err != nil || !bytes.Equal(data, again) {
+		t.Fatalf("Bytes should be deterministic: %v", err)
+	}
+
+	read, err := ReadBundle(data)
+	if err != nil {
+		t.Fatalf("ReadBundle failed: %v", err)
+	}
+	if got, err := read.GetObject(hash); err != nil || string(got) != "content" {
+		t.Fatalf("GetObject = %q, %v", got, err)
+	}
+	if ids, err := read.ListManifests(); err != nil || !slices.Equal(ids, []string{"20260101T000000.000Z"}) {
+		t.Fatalf("ListManifests = %v, %v", ids, err)
+	}
+	if sums, sig, err := read.GetSums("20260101T000000.000Z"); err != nil || string(sums) != "sums" || sig != nil {
+		t.Fatalf("GetSums = %q, %q, %v", sums, sig, err)
+	}
+
+	if IsBundle([]byte("{}")) {
+		t.Error("IsBundle should reject JSON")
+	}
+	if _, err := ReadBundle([]byte("not a bundle")); err == nil {
+		t.Error("ReadBundle should reject other data")
+	}
+}
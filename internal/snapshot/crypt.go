@@ -0,0 +1,287 @@
+package snapshot
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KeySize is the length in bytes of a sync key.
+const KeySize = 32
+
+// encryptedMagic prefixes every sealed blob, so encrypted and plain data can
+// be told apart before decoding.
+var encryptedMagic = []byte("vault.md/enc1\n")
+
+var (
+	// ErrNotEncrypted is returned by Cipher.Open for data that was not sealed.
+	ErrNotEncrypted = errors.New("data is not encrypted")
+	// ErrUndecryptable is returned by Cipher.Open for data sealed with
+	// another key, or altered since.
+	ErrUndecryptable = errors.New("cannot decrypt with this sync key")
+)
+
+// IsEncrypted reports whether data was produced by Cipher.Seal.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedMagic)
+}
+
+// Cipher encrypts snapshot objects and manifests with a sync key shared by
+// every vault that syncs through the same remote. Sealing is AES-256-GCM
+// with a synthetic nonce derived from the name and plaintext, so sealing
+// the same object twice yields the same bytes: stores stay deduplicated and
+// unchanged git snapshots stay unchanged. The name is authenticated as well,
+// so a remote cannot swap one object or manifest for another. Objects are
+// kept under ObjectID rather than their content hash, so the remote cannot
+// confirm a guessed plaintext by hashing it.
+type Cipher struct {
+	aead     cipher.AEAD
+	nonceKey []byte
+	idKey    []byte
+}
+
+// NewCipher derives the encryption and nonce keys from a sync key.
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("sync key must be %d bytes, got %d", KeySize, len(key))
+	}
+	encKey, err := hkdf.Key(sha256.New, key, nil, "vault.md sync encryption", KeySize)
+	if err != nil {
+		return nil, err
+	}
+	nonceKey, err := hkdf.Key(sha256.New, key, nil, "vault.md sync nonce", KeySize)
+	if err != nil {
+		return nil, err
+	}
+	idKey, err := hkdf.Key(sha256.New, key, nil, "vault.md sync object id", KeySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead, nonceKey: nonceKey, idKey: idKey}, nil
+}
+
+// ObjectID returns the name the object with the given content hash is
+// stored under on the remote: an HMAC of the hash under the sync key, which
+// only holders of the key can compute.
+func (c *Cipher) ObjectID(hash string) string {
+	mac := hmac.New(sha256.New, c.idKey)
+	_, _ = io.WriteString(mac, hash)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Seal encrypts plaintext stored under name.
+func (c *Cipher) Seal(name string, plaintext []byte) []byte {
+	mac := hmac.New(sha256.New, c.nonceKey)
+	_, _ = io.WriteString(mac, name)
+	_, _ = mac.Write([]byte{0})
+	_, _ = mac.Write(plaintext)
+	nonce := mac.Sum(nil)[:c.aead.NonceSize()]
+
+	out := make([]byte, 0, len(encryptedMagic)+len(nonce)+len(plaintext)+c.aead.Overhead())
+	out = append(out, encryptedMagic...)
+	out = append(out, nonce...)
+	return c.aead.Seal(out, nonce, plaintext, []byte(name))
+}
+
+// Open decrypts data sealed under name.
+func (c *Cipher) Open(name string, data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, fmt.Errorf("%s: %w", name, ErrNotEncrypted)
+	}
+	data = data[len(encryptedMagic):]
+	if len(data) < c.aead.NonceSize() {
+		return nil, fmt.Errorf("%s: encrypted data is truncated", name)
+	}
+	nonce, ciphertext := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, ErrUndecryptable)
+	}
+	return plaintext, nil
+}
+
+// ObjectName is the name the object with the given hash is sealed under.
+func ObjectName(hash string) string { return "object/" + hash }
+
+// ManifestName is the name the manifest with the given id is sealed under.
+func ManifestName(id string) string { return "manifest/" + id }
+
+// SumsName is the name the integrity manifest of the snapshot id is sealed
+// under.
+func SumsName(id string) string { return "sums/" + id }
+
+// GenerateKey writes a new random sync key to path, readable only by the
+// owner. It refuses to overwrite an existing file.
+func GenerateKey(path string) error {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	return writeKey(path, key)
+}
+
+// writeKey writes key to path in the format LoadKey reads, readable only by
+// the owner. It refuses to overwrite an existing file.
+func writeKey(path string, key []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	//nolint:gosec // G304: path is chosen by the user
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(encodeKey(key) + "\n"); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadKey reads a sync key written by GenerateKey.
+func LoadKey(path string) ([]byte, error) {
+	//nolint:gosec // G304: path is chosen by the user
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync key: %w", err)
+	}
+	key, ok := decodeKey(data)
+	if !ok {
+		return nil, fmt.Errorf("invalid sync key in %s: expected %d base64-encoded bytes", path, KeySize)
+	}
+	return key, nil
+}
+
+// encodeKey returns the text of a key file, without the trailing newline.
+func encodeKey(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// decodeKey parses the contents of a key file.
+func decodeKey(data []byte) ([]byte, bool) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != KeySize {
+		return nil, false
+	}
+	return key, true
+}
+
+// EncryptedStore seals everything written to an underlying Store and opens
+// everything read from it. Objects are stored under their Cipher.ObjectID
+// and snapshot ids stay visible to the store; content hashes, content,
+// keys, scopes, and descriptions do not.
+type EncryptedStore struct {
+	inner  Store
+	cipher *Cipher
+}
+
+// NewEncryptedStore wraps inner so that it only ever sees sealed data.
+func NewEncryptedStore(inner Store, c *Cipher) *EncryptedStore {
+	return &EncryptedStore{inner: inner, cipher: c}
+}
+
+// StoredName returns the name the object with the given hash is stored
+// under in store, as ListObjects reports it.
+func StoredName(store Store, hash string) string {
+	if s, ok := store.(*EncryptedStore); ok {
+		return s.cipher.ObjectID(hash)
+	}
+	return hash
+}
+
+// HasObject implements Store.
+func (s *EncryptedStore) HasObject(hash string) (bool, error) {
+	return s.inner.HasObject(s.cipher.ObjectID(hash))
+}
+
+// PutObject implements Store.
+func (s *EncryptedStore) PutObject(hash string, content io.Reader) error {
+	plaintext, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	return s.inner.PutObject(s.cipher.ObjectID(hash), bytes.NewReader(s.cipher.Seal(ObjectName(hash), plaintext)))
+}
+
+// GetObject implements Store.
+func (s *EncryptedStore) GetObject(hash string) ([]byte, error) {
+	data, err := s.inner.GetObject(s.cipher.ObjectID(hash))
+	if err != nil {
+		return nil, err
+	}
+	return s.cipher.Open(ObjectName(hash), data)
+}
+
+// ListObjects implements Store. It returns the object ids, which cannot be
+// turned back into hashes; see StoredName.
+func (s *EncryptedStore) ListObjects() ([]string, error) {
+	return s.inner.ListObjects()
+}
+
+// DeleteObject implements Store.
+func (s *EncryptedStore) DeleteObject(name string) error {
+	return s.inner.DeleteObject(name)
+}
+
+// PutManifest implements Store.
+func (s *EncryptedStore) PutManifest(id string, data []byte) error {
+	return s.inner.PutManifest(id, s.cipher.Seal(ManifestName(id), data))
+}
+
+// GetManifest implements Store.
+func (s *EncryptedStore) GetManifest(id string) ([]byte, error) {
+	data, err := s.inner.GetManifest(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.cipher.Open(ManifestName(id), data)
+}
+
+// ListManifests implements Store.
+func (s *EncryptedStore) ListManifests() ([]string, error) {
+	return s.inner.ListManifests()
+}
+
+// DeleteManifest implements Store.
+func (s *EncryptedStore) DeleteManifest(id string) error {
+	return s.inner.DeleteManifest(id)
+}
+
+// PutSums implements Store. The signature is stored as it is: it reveals
+// nothing, and is checked against the opened integrity manifest.
+func (s *EncryptedStore) PutSums(id string, sums, sig []byte) error {
+	return s.inner.PutSums(id, s.cipher.Seal(SumsName(id), sums), sig)
+}
+
+// GetSums implements Store.
+func (s *EncryptedStore) GetSums(id string) ([]byte, []byte, error) {
+	sums, sig, err := s.inner.GetSums(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	sums, err = s.cipher.Open(SumsName(id), sums)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sums, sig, nil
+}
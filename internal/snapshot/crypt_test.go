@@ -0,0 +1,170 @@
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func newTestCipher(t *testing.T) *Cipher {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sync.key")
+	if err := GenerateKey(path); err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	if err := GenerateKey(path); err == nil {
+		t.Fatal("GenerateKey should not overwrite an existing key")
+	}
+	key, err := LoadKey(path)
+	if err != nil {
+		t.Fatalf("LoadKey failed: %v", err)
+	}
+	c, err := NewCipher(key)
+	if err != nil {
+		t.Fatalf("NewCipher failed: %v", err)
+	}
+	return c
+}
+
+func TestCipherSealIsDeterministicAndBound(t *testing.T) {
+	c := newTestCipher(t)
+
+	sealed := c.Seal(ObjectName("aa"), []byte("secret notes"))
+	if !IsEncrypted(sealed) || bytes.Contains(sealed, []byte("secret")) {
+		t.Fatalf("sealed data does not look encrypted: %q", sealed)
+	}
+	if again := c.Seal(ObjectName("aa"), []byte("secret notes")); !bytes.Equal(again, sealed) {
+		t.Fatal("sealing the same object twice should give the same bytes")
+	}
+	if other := c.Seal(ObjectName("bb"), []byte("secret notes")); bytes.Equal(other, sealed) {
+		t.Fatal("sealing under another name should give different bytes")
+	}
+
+	plain, err := c.Open(ObjectName("aa"), sealed)
+	if err != nil || string(plain) != "secret notes" {
+		t.Fatalf("Open = %q, %v", plain, err)
+	}
+	if _, err := c.Open(ObjectName("bb"), sealed); err == nil {
+		t.Fatal("Open should reject data sealed under another name")
+	}
+	if _, err := newTestCipher(t).Open(ObjectName("aa"), sealed); err == nil {
+		t.Fatal("Open should reject data sealed with another key")
+	}
+	if _, err := c.Open(ObjectName("aa"), []byte("plain")); !errors.Is(err, ErrNotEncrypted) {
+		t.Fatalf("Open of plain data = %v, want ErrNotEncrypted", err)
+	}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	inner := NewDirStore(t.TempDir())
+	store := NewEncryptedStore(inner, newTestCipher(t))
+	hash := strings.Repeat("cd", 32)
+
+	if err := store.PutObject(hash, strings.NewReader("hello")); err != nil {
+		t.Fatalf("PutObject failed: %v", err)
+	}
+	if err := store.PutManifest("20260101T000000.000Z", []byte(`{"key":"notes"}`)); err != nil {
+		t.Fatalf("PutManifest failed: %v", err)
+	}
+
+	id := StoredName(store, hash)
+	if names, err := inner.ListObjects(); err != nil || len(names) != 1 || names[0] != id || id == hash {
+		t.Fatalf("inner objects = %v, %v; want only the object id %s", names, err, id)
+	}
+	if other := StoredName(NewEncryptedStore(inner, newTestCipher(t)), hash); other == id {
+		t.Fatal("object ids under different keys are equal")
+	}
+	raw, err := inner.GetObject(id)
+	if err != nil || !IsEncrypted(raw) {
+		t.Fatalf("stored object is not encrypted: %q, %v", raw, err)
+	}
+	raw, err = inner.GetManifest("20260101T000000.000Z")
+	if err != nil || !IsEncrypted(raw) || bytes.Contains(raw, []byte("notes")) {
+		t.Fatalf("stored manifest is not encrypted: %q, %v", raw, err)
+	}
+
+	if data, err := store.GetObject(hash); err != nil || string(data) != "hello" {
+		t.Fatalf("GetObject = %q, %v", data, err)
+	}
+	if data, err := store.GetManifest("20260101T000000.000Z"); err != nil || string(data) != `{"key":"notes"}` {
+		t.Fatalf("GetManifest = %q, %v", data, err)
+	}
+
+	if err := store.PutSums("20260101T000000.000Z", []byte("sums"), []byte("sig")); err != nil {
+		t.Fatalf("PutSums failed: %v", err)
+	}
+	if raw, _, err := inner.GetSums("20260101T000000.000Z"); err != nil || !IsEncrypted(raw) {
+		t.Fatalf("stored integrity manifest is not encrypted: %q, %v", raw, err)
+	}
+	if sums, sig, err := store.GetSums("20260101T000000.000Z"); err != nil || string(sums) != "sums" || string(sig) != "sig" {
+		t.Fatalf("GetSums = %q, %q, %v", sums, sig, err)
+	}
+
+	raw, err = inner.GetObject(id)
+	if err != nil {
+		t.Fatalf("GetObject failed: %v", err)
+	}
+	raw[len(raw)-1] ^= 1
+	if err := inner.PutObject(id, bytes.NewReader(raw)); err != nil {
+		t.Fatalf("PutObject failed: %v", err)
+	}
+	if _, err := store.GetObject(hash); !errors.Is(err, ErrUndecryptable) {
+		t.Fatalf("GetObject of tampered data = %v, want ErrUndecryptable", err)
+	}
+}
+
+func TestShareKey(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sync.key")
+	if err := GenerateKey(path); err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	alice, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mallory, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shared, err := ShareKey(path, []string{alice.Recipient().String(), "# bob's laptop", bob.Recipient().String()})
+	if err != nil {
+		t.Fatalf("ShareKey failed: %v", err)
+	}
+	want, err := LoadKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(shared, []byte(encodeKey(want))) {
+		t.Fatal("shared key holds the key in plaintext")
+	}
+
+	received := filepath.Join(dir, "bob", "sync.key")
+	if err := ReceiveKey(shared, []byte(bob.String()+"\n"), received); err != nil {
+		t.Fatalf("ReceiveKey failed: %v", err)
+	}
+	if got, err := LoadKey(received); err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("received key = %x, %v, want %x", got, err, want)
+	}
+	if err := ReceiveKey(shared, []byte(alice.String()), received); err == nil {
+		t.Error("ReceiveKey overwrote an existing key")
+	}
+	if err := ReceiveKey(shared, []byte(mallory.String()), filepath.Join(dir, "mallory.key")); err == nil {
+		t.Error("ReceiveKey decrypted with an identity the key was not shared with")
+	}
+	if _, err := ShareKey(path, []string{"ssh-ed25519 AAAA"}); err == nil {
+		t.Error("ShareKey accepted an invalid recipient")
+	}
+	if _, err := ShareKey(path, nil); err == nil {
+		t.Error("ShareKey accepted no recipients")
+	}
+}
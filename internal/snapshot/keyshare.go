@@ -0,0 +1,85 @@
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// maxSharedKey caps the plaintext read from a shared key; a key file is far
+// smaller.
+const maxSharedKey = 1 << 10
+
+// ShareKey encrypts the sync key at path to age recipients ("age1..."
+// public keys, or lines of a recipients file), so it can be sent to other
+// machines over an untrusted channel. The result is an age file holding the
+// key file itself: ReceiveKey installs it, and `age -d` recovers it too.
+func ShareKey(path string, recipients []string) ([]byte, error) {
+	parsed, err := parseRecipients(recipients)
+	if err != nil {
+		return nil, err
+	}
+	key, err := LoadKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, parsed...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(fmt.Appendf(nil, "%s\n", encodeKey(key))); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ValidateRecipients reports whether ShareKey accepts recipients, so a
+// caller can check them before creating the key to share.
+func ValidateRecipients(recipients []string) error {
+	_, err := parseRecipients(recipients)
+	return err
+}
+
+func parseRecipients(recipients []string) ([]age.Recipient, error) {
+	parsed, err := age.ParseRecipients(strings.NewReader(strings.Join(recipients, "\n")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid age recipients: %w", err)
+	}
+	return parsed, nil
+}
+
+// ReceiveKey decrypts a key written by ShareKey with one of the age
+// identities in identities (the contents of an age identity file) and
+// writes it to path. It refuses to overwrite an existing file.
+func ReceiveKey(shared, identities []byte, path string) error {
+	parsed, err := age.ParseIdentities(bytes.NewReader(identities))
+	if err != nil {
+		return fmt.Errorf("invalid age identity file: %w", err)
+	}
+	r, err := age.Decrypt(bytes.NewReader(shared), parsed...)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return fmt.Errorf("the shared key was not encrypted to any of these identities")
+		}
+		return fmt.Errorf("cannot decrypt the shared key: %w", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxSharedKey+1))
+	if err != nil {
+		return fmt.Errorf("cannot decrypt the shared key: %w", err)
+	}
+	key, ok := decodeKey(data)
+	if len(data) > maxSharedKey || !ok {
+		return fmt.Errorf("the shared file does not hold a sync key")
+	}
+	return writeKey(path, key)
+}
@@ -0,0 +1,533 @@
+package snapshot
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// objectRequestTimeout bounds a single request to the object store.
+const objectRequestTimeout = 2 * time.Minute
+
+// ObjectStore keeps snapshots in an S3-compatible bucket with the same layout
+// as DirStore, under an optional key prefix. Requests are signed with AWS
+// Signature Version 4, which Google Cloud Storage also accepts from HMAC keys.
+type ObjectStore struct {
+	// base holds the scheme and host, plus "/<bucket>" for path-style
+	// addressing.
+	base   *url.URL
+	prefix string
+	region string
+	creds  objectCredentials
+	client *http.Client
+	now    func() time.Time
+}
+
+type objectCredentials struct {
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+func newObjectStore(base *url.URL, prefix, region string, creds objectCredentials) *ObjectStore {
+	base.Path = strings.TrimSuffix(base.Path, "/")
+	return &ObjectStore{
+		base:   base,
+		prefix: strings.Trim(prefix, "/"),
+		region: region,
+		creds:  creds,
+		client: &http.Client{Timeout: objectRequestTimeout},
+		now:    time.Now,
+	}
+}
+
+// openS3 returns the store for s3://bucket/prefix. Credentials and region
+// come from the standard AWS environment variables or the shared credentials
+// file; AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL points it at another
+// S3-compatible service.
+func openS3(u *url.URL) (Store, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("s3:// snapshot target has no bucket: %s", u)
+	}
+	creds, err := awsCredentials()
+	if err != nil {
+		return nil, err
+	}
+	region := cmp.Or(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1")
+
+	var base *url.URL
+	switch endpoint := cmp.Or(os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL")); {
+	case endpoint != "":
+		base, err = url.Parse(endpoint)
+		if err != nil || base.Host == "" {
+			return nil, fmt.Errorf("invalid S3 endpoint: %s", endpoint)
+		}
+		base.Path = strings.TrimSuffix(base.Path, "/") + "/" + u.Host
+	case strings.Contains(u.Host, "."):
+		// Dotted bucket names do not match the wildcard certificate of
+		// virtual-hosted addresses.
+		base = &url.URL{Scheme: "https", Host: "s3." + region + ".amazonaws.com", Path: "/" + u.Host}
+	default:
+		base = &url.URL{Scheme: "https", Host: u.Host + ".s3." + region + ".amazonaws.com"}
+	}
+	return newObjectStore(base, u.Path, region, creds), nil
+}
+
+// openGS returns the store for gs://bucket/prefix, reached through the Cloud
+// Storage XML API with an HMAC key.
+func openGS(u *url.URL) (Store, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("gs:// snapshot target has no bucket: %s", u)
+	}
+	creds := objectCredentials{
+		accessKey: os.Getenv("VAULT_GS_ACCESS_KEY_ID"),
+		secretKey: os.Getenv("VAULT_GS_SECRET_ACCESS_KEY"),
+	}
+	if creds.accessKey == "" || creds.secretKey == "" {
+		return nil, fmt.Errorf("gs:// snapshot targets need an HMAC key: set VAULT_GS_ACCESS_KEY_ID and VAULT_GS_SECRET_ACCESS_KEY")
+	}
+	base := &url.URL{Scheme: "https", Host: "storage.googleapis.com", Path: "/" + u.Host}
+	return newObjectStore(base, u.Path, "auto", creds), nil
+}
+
+// awsCredentials reads AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, falling
+// back to the AWS_PROFILE (or default) profile of the shared credentials file.
+func awsCredentials() (objectCredentials, error) {
+	creds := objectCredentials{
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.accessKey != "" && creds.secretKey != "" {
+		return creds, nil
+	}
+
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, ".aws", "credentials")
+		}
+	}
+	profile := cmp.Or(os.Getenv("AWS_PROFILE"), "default")
+	creds = readCredentialsFile(path, profile)
+	if creds.accessKey == "" || creds.secretKey == "" {
+		return creds, fmt.Errorf("s3:// snapshot targets need credentials: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or add the %q profile to %s", profile, path)
+	}
+	return creds, nil
+}
+
+// readCredentialsFile returns the keys of profile in an AWS shared
+// credentials file, or nothing if the file or profile is missing.
+func readCredentialsFile(path, profile string) objectCredentials {
+	var creds objectCredentials
+	if path == "" {
+		return creds
+	}
+	//nolint:gosec // G304: the credentials file is chosen by the user
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return creds
+	}
+	section := ""
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok || section != profile {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(name) {
+		case "aws_access_key_id":
+			creds.accessKey = value
+		case "aws_secret_access_key":
+			creds.secretKey = value
+		case "aws_session_token":
+			creds.sessionToken = value
+		}
+	}
+	return creds
+}
+
+func (s *ObjectStore) key(name string) string {
+	if s.prefix == "" {
+		return name
+	}
+	return s.prefix + "/" + name
+}
+
+func objectKey(hash string) (string, error) {
+	if err := checkHash(hash); err != nil {
+		return "", err
+	}
+	return "objects/" + hash[:2] + "/" + hash, nil
+}
+
+func manifestKey(id string) (string, error) {
+	if err := checkID(id); err != nil {
+		return "", err
+	}
+	return "snapshots/" + id + ".json", nil
+}
+
+// HasObject implements Store.
+func (s *ObjectStore) HasObject(hash string) (bool, error) {
+	key, err := objectKey(hash)
+	if err != nil {
+		return false, err
+	}
+	_, err = s.do(http.MethodHead, key, nil, nil)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// PutObject implements Store.
+func (s *ObjectStore) PutObject(hash string, content io.Reader) error {
+	key, err := objectKey(hash)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	_, err = s.do(http.MethodPut, key, nil, data)
+	return err
+}
+
+// GetObject implements Store.
+func (s *ObjectStore) GetObject(hash string) ([]byte, error) {
+	key, err := objectKey(hash)
+	if err != nil {
+		return nil, err
+	}
+	return s.do(http.MethodGet, key, nil, nil)
+}
+
+// ListObjects implements Store.
+func (s *ObjectStore) ListObjects() ([]string, error) {
+	names, err := s.list("objects/")
+	if err != nil {
+		return nil, err
+	}
+	var hashes []string
+	for _, name := range names {
+		base := name[strings.LastIndex(name, "/")+1:]
+		if base != "" && !strings.HasPrefix(base, ".") {
+			hashes = append(hashes, base)
+		}
+	}
+	return hashes, nil
+}
+
+// DeleteObject implements Store.
+func (s *ObjectStore) DeleteObject(hash string) error {
+	key, err := objectKey(hash)
+	if err != nil {
+		return err
+	}
+	_, err = s.do(http.MethodDelete, key, nil, nil)
+	return err
+}
+
+// PutManifest implements Store.
+func (s *ObjectStore) PutManifest(id string, data []byte) error {
+	key, err := manifestKey(id)
+	if err != nil {
+		return err
+	}
+	_, err = s.do(http.MethodPut, key, nil, data)
+	return err
+}
+
+// GetManifest implements Store.
+func (s *ObjectStore) GetManifest(id string) ([]byte, error) {
+	key, err := manifestKey(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.do(http.MethodGet, key, nil, nil)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("snapshot %s not found", id)
+	}
+	return data, err
+}
+
+// ListManifests implements Store. Ids are timestamps, so sorting them by
+// name orders them by age.
+func (s *ObjectStore) ListManifests() ([]string, error) {
+	names, err := s.list("snapshots/")
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, name := range names {
+		if strings.Contains(name, "/") || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(name, ".json"))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// DeleteManifest implements Store.
+func (s *ObjectStore) DeleteManifest(id string) error {
+	key, err := manifestKey(id)
+	if err != nil {
+		return err
+	}
+	for _, k := range []string{key, sumsKey(key), sumsKey(key) + ".sig"} {
+		if _, err := s.do(http.MethodDelete, k, nil, nil); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func sumsKey(manifestKey string) string {
+	return strings.TrimSuffix(manifestKey, ".json") + ".sums"
+}
+
+// PutSums implements Store.
+func (s *ObjectStore) PutSums(id string, sums, sig []byte) error {
+	key, err := manifestKey(id)
+	if err != nil {
+		return err
+	}
+	key = sumsKey(key)
+	if _, err := s.do(http.MethodPut, key, nil, sums); err != nil {
+		return err
+	}
+	if sig == nil {
+		if _, err := s.do(http.MethodDelete, key+".sig", nil, nil); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	_, err = s.do(http.MethodPut, key+".sig", nil, sig)
+	return err
+}
+
+// GetSums implements Store.
+func (s *ObjectStore) GetSums(id string) ([]byte, []byte, error) {
+	key, err := manifestKey(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	key = sumsKey(key)
+	sums, err := s.do(http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	sig, err := s.do(http.MethodGet, key+".sig", nil, nil)
+	if errors.Is(err, fs.ErrNotExist) {
+		return sums, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return sums, sig, nil
+}
+
+// listBucketResult is the part of a ListObjectsV2 response the store reads.
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// list returns the names of the keys under dir, relative to dir, following
+// continuation tokens until the listing is complete.
+func (s *ObjectStore) list(dir string) ([]string, error) {
+	prefix := s.key(dir)
+	var names []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		data, err := s.request(http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page listBucketResult
+		if err := xml.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse listing of %s: %w", prefix, err)
+		}
+		for _, c := range page.Contents {
+			names = append(names, strings.TrimPrefix(c.Key, prefix))
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return names, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// do sends a request for name, relative to the store prefix.
+func (s *ObjectStore) do(method, name string, query url.Values, body []byte) ([]byte, error) {
+	return s.request(method, s.key(name), query, body)
+}
+
+// objectError is the error document S3-compatible services return.
+type objectError struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// request sends a signed request for key, relative to the bucket, and
+// returns the response body. A missing key fails with an error wrapping
+// fs.ErrNotExist.
+func (s *ObjectStore) request(method, key string, query url.Values, body []byte) ([]byte, error) {
+	u := *s.base
+	u.Path = s.base.Path + "/" + key
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	signV4(req, s.creds, s.region, hex.EncodeToString(sum[:]), s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	target := u.Host + u.Path
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return data, nil
+	case resp.StatusCode == http.StatusNotFound && key != "":
+		return nil, fmt.Errorf("%s: %w", target, fs.ErrNotExist)
+	}
+	var e objectError
+	if xml.Unmarshal(data, &e) == nil && e.Code != "" {
+		return nil, fmt.Errorf("%s %s failed: %s: %s", method, target, e.Code, e.Message)
+	}
+	return nil, fmt.Errorf("%s %s failed: %s", method, target, resp.Status)
+}
+
+// signV4 adds an AWS Signature Version 4 Authorization header to req,
+// signing the host and every header already set.
+func signV4(req *http.Request, creds objectCredentials, region, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	values := map[string]string{"host": req.URL.Host}
+	for name, vals := range req.Header {
+		values[strings.ToLower(name)] = strings.TrimSpace(strings.Join(vals, ","))
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var headers strings.Builder
+	for _, name := range names {
+		headers.WriteString(name + ":" + values[name] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		headers.String(),
+		signed,
+		payloadHash,
+	}, "\n")
+	hashed := sha256.Sum256([]byte(canonical))
+	scope := date + "/" + region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := []byte("AWS4" + creds.secretKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.accessKey, scope, signed, hex.EncodeToString(hmacSHA256(key, toSign))))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query the way Signature Version 4 canonicalises
+// it: sorted by name, every reserved character percent-encoded.
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		for _, value := range query[name] {
+			parts = append(parts, awsEscape(name)+"="+awsEscape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath percent-encodes each segment of path.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = awsEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// awsEscape percent-encodes everything but the RFC 3986 unreserved
+// characters.
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := range len(s) {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,111 @@
+package snapshot
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// passphraseMagic prefixes every blob sealed with a passphrase. The scrypt
+// parameters follow it, so they can be raised without breaking old blobs.
+var passphraseMagic = []byte("vault.md/pass1\n")
+
+// Default scrypt parameters: N=2^15, r=8, p=1, which takes around 100ms and
+// 32 MiB of memory, a reasonable cost for a file sealed or opened by hand.
+const (
+	passphraseLogN = 15
+	passphraseR    = 8
+	passphraseP    = 1
+	saltSize       = 16
+)
+
+// ErrWrongPassphrase is returned by OpenWithPassphrase when the passphrase
+// does not decrypt the data.
+var ErrWrongPassphrase = errors.New("wrong passphrase, or the data is corrupted")
+
+// IsPassphraseSealed reports whether data was produced by SealWithPassphrase.
+func IsPassphraseSealed(data []byte) bool {
+	return bytes.HasPrefix(data, passphraseMagic)
+}
+
+// SealWithPassphrase encrypts plaintext with AES-256-GCM under a key derived
+// from passphrase with scrypt and a random salt. Unlike Cipher, it needs no
+// key file, so the result can be handed to anyone who is told the passphrase.
+func SealWithPassphrase(passphrase, plaintext []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase is empty")
+	}
+	header := make([]byte, 0, len(passphraseMagic)+3+saltSize)
+	header = append(header, passphraseMagic...)
+	header = append(header, passphraseLogN, passphraseR, passphraseP)
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	header = append(header, salt...)
+
+	aead, err := passphraseAEAD(passphrase, salt, passphraseLogN, passphraseR, passphraseP)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(header)+len(nonce)+len(plaintext)+aead.Overhead())
+	out = append(out, header...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, header), nil
+}
+
+// OpenWithPassphrase decrypts data sealed by SealWithPassphrase.
+func OpenWithPassphrase(passphrase, data []byte) ([]byte, error) {
+	if !IsPassphraseSealed(data) {
+		return nil, ErrNotEncrypted
+	}
+	headerSize := len(passphraseMagic) + 3 + saltSize
+	if len(data) < headerSize {
+		return nil, errors.New("encrypted data is truncated")
+	}
+	header := data[:headerSize]
+	params := header[len(passphraseMagic):]
+	logN, r, p := params[0], params[1], params[2]
+	// Bound the parameters so a crafted file cannot demand gigabytes.
+	if logN < 10 || logN > 22 || r < 1 || r > 32 || p < 1 || p > 16 {
+		return nil, fmt.Errorf("unsupported key derivation parameters (N=2^%d, r=%d, p=%d)", logN, r, p)
+	}
+	salt := params[3:]
+
+	aead, err := passphraseAEAD(passphrase, salt, logN, r, p)
+	if err != nil {
+		return nil, err
+	}
+	data = data[headerSize:]
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("encrypted data is truncated")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+func passphraseAEAD(passphrase, salt []byte, logN, r, p byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, 1<<logN, int(r), int(p), KeySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,53 @@
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSealWithPassphrase(t *testing.T) {
+	sealed, err := SealWithPassphrase([]byte("correct horse"), []byte("secret notes"))
+	if err != nil {
+		t.Fatalf("SealWithPassphrase failed: %v", err)
+	}
+	if !IsPassphraseSealed(sealed) || IsEncrypted(sealed) || bytes.Contains(sealed, []byte("secret")) {
+		t.Fatalf("sealed data does not look passphrase-encrypted: %q", sealed)
+	}
+	again, err := SealWithPassphrase([]byte("correct horse"), []byte("secret notes"))
+	if err != nil || bytes.Equal(again, sealed) {
+		t.Fatal("sealing twice should use a fresh salt and nonce")
+	}
+
+	plain, err := OpenWithPassphrase([]byte("correct horse"), sealed)
+	if err != nil || string(plain) != "secret notes" {
+		t.Fatalf("OpenWithPassphrase = %q, %v", plain, err)
+	}
+	if _, err := OpenWithPassphrase([]byte("wrong horse"), sealed); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("OpenWithPassphrase with the wrong passphrase = %v, want ErrWrongPassphrase", err)
+	}
+
+	tampered := bytes.Clone(sealed)
+	tampered[len(passphraseMagic)+3] ^= 1 // salt
+	if _, err := OpenWithPassphrase([]byte("correct horse"), tampered); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("OpenWithPassphrase of a tampered header = %v, want ErrWrongPassphrase", err)
+	}
+
+	if _, err := OpenWithPassphrase([]byte("correct horse"), []byte("plain")); !errors.Is(err, ErrNotEncrypted) {
+		t.Fatalf("OpenWithPassphrase of plain data = %v, want ErrNotEncrypted", err)
+	}
+	if _, err := SealWithPassphrase(nil, []byte("x")); err == nil {
+		t.Fatal("SealWithPassphrase should reject an empty passphrase")
+	}
+}
+
+func TestOpenWithPassphraseRejectsCostlyParameters(t *testing.T) {
+	sealed, err := SealWithPassphrase([]byte("pw"), []byte("x"))
+	if err != nil {
+		t.Fatalf("SealWithPassphrase failed: %v", err)
+	}
+	sealed[len(passphraseMagic)] = 30 // N=2^30
+	if _, err := OpenWithPassphrase([]byte("pw"), sealed); err == nil || errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("OpenWithPassphrase = %v, want an unsupported parameters error", err)
+	}
+}
@@ -0,0 +1,80 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// SignatureNamespace is the ssh-keygen -Y namespace integrity manifests are
+// signed in, so a signature made for git commits or other files cannot be
+// passed off as one for a snapshot.
+const SignatureNamespace = "vault.md-snapshot"
+
+// SignSSH signs data with the SSH private key at keyPath, or the key whose
+// public half is at keyPath for keys held by ssh-agent, using ssh-keygen.
+func SignSSH(ctx context.Context, keyPath string, data []byte) ([]byte, error) {
+	//nolint:gosec // G204: the key path is chosen by the user
+	cmd := exec.CommandContext(ctx, "ssh-keygen", "-q", "-Y", "sign", "-n", SignatureNamespace, "-f", keyPath)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	sig, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with %s: %w", keyPath, sshKeygenError(err, &stderr))
+	}
+	return sig, nil
+}
+
+// VerifySSH checks sig over data against an allowed signers file in the
+// format of ssh-keygen(1), and returns the principal that made it.
+func VerifySSH(ctx context.Context, allowedSigners string, data, sig []byte) (string, error) {
+	sigFile, err := os.CreateTemp("", "vault-snapshot-*.sig")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = os.Remove(sigFile.Name())
+	}()
+	if _, err := sigFile.Write(sig); err != nil {
+		_ = sigFile.Close()
+		return "", err
+	}
+	if err := sigFile.Close(); err != nil {
+		return "", err
+	}
+
+	//nolint:gosec // G204: the allowed signers file is chosen by the user
+	find := exec.CommandContext(ctx, "ssh-keygen", "-Y", "find-principals", "-s", sigFile.Name(), "-f", allowedSigners)
+	var stderr bytes.Buffer
+	find.Stderr = &stderr
+	out, err := find.Output()
+	if err != nil {
+		return "", fmt.Errorf("signature is not from a key in %s: %w", allowedSigners, sshKeygenError(err, &stderr))
+	}
+	principal, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+
+	//nolint:gosec // G204: the allowed signers file is chosen by the user
+	verify := exec.CommandContext(ctx, "ssh-keygen", "-Y", "verify", "-n", SignatureNamespace,
+		"-f", allowedSigners, "-I", principal, "-s", sigFile.Name())
+	verify.Stdin = bytes.NewReader(data)
+	stderr.Reset()
+	verify.Stderr = &stderr
+	if err := verify.Run(); err != nil {
+		return "", fmt.Errorf("bad signature by %s: %w", principal, sshKeygenError(err, &stderr))
+	}
+	return principal, nil
+}
+
+// sshKeygenError adds what ssh-keygen printed to err.
+func sshKeygenError(err error, stderr *bytes.Buffer) error {
+	var exitErr *exec.ExitError
+	if msg := strings.TrimSpace(stderr.String()); msg != "" && errors.As(err, &exitErr) {
+		return fmt.Errorf("%w: %s", err, msg)
+	}
+	return err
+}
@@ -0,0 +1,311 @@
+// Package snapshot stores vault snapshot bundles: content objects addressed
+// by hash plus one manifest per snapshot.
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Store holds snapshot manifests and the objects they reference.
+type Store interface {
+	// HasObject reports whether content with the given hash is stored.
+	HasObject(hash string) (bool, error)
+	// PutObject stores content under hash.
+	PutObject(hash string, content io.Reader) error
+	// GetObject returns the content stored under hash.
+	GetObject(hash string) ([]byte, error)
+	// ListObjects returns the names of all stored objects: their hashes,
+	// except in an EncryptedStore; see StoredName.
+	ListObjects() ([]string, error)
+	// DeleteObject removes the object stored under name, as ListObjects
+	// returns it.
+	DeleteObject(name string) error
+
+	// PutManifest stores a manifest under id.
+	PutManifest(id string, data []byte) error
+	// GetManifest returns the manifest stored under id.
+	GetManifest(id string) ([]byte, error)
+	// ListManifests returns the ids of all manifests, oldest first.
+	ListManifests() ([]string, error)
+	// DeleteManifest removes the manifest stored under id, along with its
+	// integrity manifest and signature.
+	DeleteManifest(id string) error
+
+	// PutSums stores the integrity manifest of the snapshot id, and its
+	// signature unless sig is nil.
+	PutSums(id string, sums, sig []byte) error
+	// GetSums returns the integrity manifest of the snapshot id and its
+	// signature, which is nil for unsigned snapshots. Snapshots written
+	// without an integrity manifest fail with an error wrapping
+	// fs.ErrNotExist.
+	GetSums(id string) (sums, sig []byte, err error)
+}
+
+// Open returns the store for target: a directory path, a file:// URL, or an
+// s3://bucket/prefix or gs://bucket/prefix URL.
+func Open(target string) (Store, error) {
+	if target == "" {
+		return nil, fmt.Errorf("snapshot target is empty")
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 {
+		// Plain paths, including Windows drive letters.
+		return NewDirStore(target), nil
+	}
+	switch u.Scheme {
+	case "file":
+		return NewDirStore(u.Path), nil
+	case "s3":
+		return openS3(u)
+	case "gs":
+		return openGS(u)
+	default:
+		return nil, fmt.Errorf("unsupported snapshot target: %s", target)
+	}
+}
+
+// DirStore keeps snapshots in a local (or mounted) directory:
+// objects/<hash[:2]>/<hash> and snapshots/<id>.json, with the integrity
+// manifest in snapshots/<id>.sums and its signature in snapshots/<id>.sums.sig.
+type DirStore struct {
+	root string
+}
+
+// NewDirStore returns a store rooted at dir.
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{root: dir}
+}
+
+// checkHash rejects object hashes that could escape the objects directory.
+func checkHash(hash string) error {
+	if len(hash) < 3 || strings.ContainsAny(hash, `/\.`) {
+		return fmt.Errorf("invalid object hash: %q", hash)
+	}
+	return nil
+}
+
+// checkID rejects snapshot ids that could escape the snapshots directory.
+func checkID(id string) error {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return fmt.Errorf("invalid snapshot id: %q", id)
+	}
+	return nil
+}
+
+func (s *DirStore) objectPath(hash string) (string, error) {
+	if err := checkHash(hash); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, "objects", hash[:2], hash), nil
+}
+
+func (s *DirStore) manifestPath(id string) (string, error) {
+	if err := checkID(id); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, "snapshots", id+".json"), nil
+}
+
+// HasObject implements Store.
+func (s *DirStore) HasObject(hash string) (bool, error) {
+	path, err := s.objectPath(hash)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// PutObject implements Store.
+func (s *DirStore) PutObject(hash string, content io.Reader) error {
+	path, err := s.objectPath(hash)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(path, content)
+}
+
+// GetObject implements Store.
+func (s *DirStore) GetObject(hash string) ([]byte, error) {
+	path, err := s.objectPath(hash)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:gosec // G304: path is built from a validated hash under the store root
+	return os.ReadFile(path)
+}
+
+// ListObjects implements Store.
+func (s *DirStore) ListObjects() ([]string, error) {
+	var hashes []string
+	err := filepath.WalkDir(filepath.Join(s.root, "objects"), func(_ string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fs.SkipAll
+		}
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && !strings.HasPrefix(d.Name(), ".") {
+			hashes = append(hashes, d.Name())
+		}
+		return nil
+	})
+	return hashes, err
+}
+
+// DeleteObject implements Store.
+func (s *DirStore) DeleteObject(hash string) error {
+	path, err := s.objectPath(hash)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// PutManifest implements Store.
+func (s *DirStore) PutManifest(id string, data []byte) error {
+	path, err := s.manifestPath(id)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(path, strings.NewReader(string(data)))
+}
+
+// GetManifest implements Store.
+func (s *DirStore) GetManifest(id string) ([]byte, error) {
+	path, err := s.manifestPath(id)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:gosec // G304: path is built from a validated id under the store root
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("snapshot %s not found", id)
+	}
+	return data, err
+}
+
+// ListManifests implements Store. Ids are timestamps, so sorting them by
+// name orders them by age.
+func (s *DirStore) ListManifests() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.root, "snapshots"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(name, ".json"))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// DeleteManifest implements Store.
+func (s *DirStore) DeleteManifest(id string) error {
+	path, err := s.manifestPath(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	for _, sidecar := range []string{s.sumsPath(path), s.sumsPath(path) + ".sig"} {
+		if err := os.Remove(sidecar); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *DirStore) sumsPath(manifestPath string) string {
+	return strings.TrimSuffix(manifestPath, ".json") + ".sums"
+}
+
+// PutSums implements Store.
+func (s *DirStore) PutSums(id string, sums, sig []byte) error {
+	path, err := s.manifestPath(id)
+	if err != nil {
+		return err
+	}
+	path = s.sumsPath(path)
+	if err := writeAtomic(path, bytes.NewReader(sums)); err != nil {
+		return err
+	}
+	if sig == nil {
+		if err := os.Remove(path + ".sig"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return writeAtomic(path+".sig", bytes.NewReader(sig))
+}
+
+// GetSums implements Store.
+func (s *DirStore) GetSums(id string) ([]byte, []byte, error) {
+	path, err := s.manifestPath(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	path = s.sumsPath(path)
+	//nolint:gosec // G304: path is built from a validated id under the store root
+	sums, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	//nolint:gosec // G304: path is built from a validated id under the store root
+	sig, err := os.ReadFile(path + ".sig")
+	if errors.Is(err, fs.ErrNotExist) {
+		return sums, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return sums, sig, nil
+}
+
+// writeAtomic copies r to a temporary file next to path and renames it into
+// place, so an interrupted snapshot never leaves a truncated file behind.
+func writeAtomic(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-"+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
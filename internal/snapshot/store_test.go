@@ -0,0 +1,97 @@
+package snapshot
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	for _, target := range []string{dir, "file://" + filepath.ToSlash(dir)} {
+		store, err := Open(target)
+		if err != nil {
+			t.Fatalf("Open(%q) failed: %v", target, err)
+		}
+		if ds, ok := store.(*DirStore); !ok || filepath.Clean(ds.root) != filepath.Clean(dir) {
+			t.Fatalf("Open(%q) returned %#v", target, store)
+		}
+	}
+
+	for _, target := range []string{"s3:///prefix", "gs:///prefix", "ftp://host/x", ""} {
+		if _, err := Open(target); err == nil {
+			t.Errorf("Open(%q) should fail", target)
+		}
+	}
+}
+
+func TestDirStore(t *testing.T) {
+	checkStore(t, NewDirStore(t.TempDir()))
+}
+
+// checkStore runs a store through every Store method.
+func checkStore(t *testing.T, store Store) {
+	t.Helper()
+
+	hash := strings.Repeat("ab", 32)
+
+	if ok, err := store.HasObject(hash); err != nil || ok {
+		t.Fatalf("HasObject on empty store = %v, %v", ok, err)
+	}
+	if err := store.PutObject(hash, strings.NewReader("hello")); err != nil {
+		t.Fatalf("PutObject failed: %v", err)
+	}
+	if ok, err := store.HasObject(hash); err != nil || !ok {
+		t.Fatalf("HasObject after put = %v, %v", ok, err)
+	}
+	if data, err := store.GetObject(hash); err != nil || string(data) != "hello" {
+		t.Fatalf("GetObject = %q, %v", data, err)
+	}
+	if hashes, err := store.ListObjects(); err != nil || !slices.Equal(hashes, []string{hash}) {
+		t.Fatalf("ListObjects = %v, %v", hashes, err)
+	}
+	if _, err := store.GetObject("../../etc/passwd"); err == nil {
+		t.Fatal("GetObject should reject paths")
+	}
+
+	for _, id := range []string{"20260102T000000.000Z", "20260101T000000.000Z"} {
+		if err := store.PutManifest(id, []byte("{}")); err != nil {
+			t.Fatalf("PutManifest failed: %v", err)
+		}
+	}
+	ids, err := store.ListManifests()
+	if err != nil || !slices.Equal(ids, []string{"20260101T000000.000Z", "20260102T000000.000Z"}) {
+		t.Fatalf("ListManifests = %v, %v", ids, err)
+	}
+	if _, _, err := store.GetSums(ids[0]); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("GetSums without an integrity manifest = %v, want fs.ErrNotExist", err)
+	}
+	if err := store.PutSums(ids[0], []byte("sums"), []byte("sig")); err != nil {
+		t.Fatalf("PutSums failed: %v", err)
+	}
+	if sums, sig, err := store.GetSums(ids[0]); err != nil || string(sums) != "sums" || string(sig) != "sig" {
+		t.Fatalf("GetSums = %q, %q, %v", sums, sig, err)
+	}
+	if err := store.PutSums(ids[0], []byte("sums"), nil); err != nil {
+		t.Fatalf("PutSums failed: %v", err)
+	}
+	if _, sig, err := store.GetSums(ids[0]); err != nil || sig != nil {
+		t.Fatalf("GetSums after an unsigned put = %q, %v, want no signature", sig, err)
+	}
+	if ids, err := store.ListManifests(); err != nil || len(ids) != 2 {
+		t.Fatalf("ListManifests should ignore integrity manifests: %v, %v", ids, err)
+	}
+
+	if err := store.DeleteManifest(ids[0]); err != nil {
+		t.Fatalf("DeleteManifest failed: %v", err)
+	}
+	if _, err := store.GetManifest(ids[0]); err == nil {
+		t.Fatal("GetManifest should fail for a deleted snapshot")
+	}
+	if _, _, err := store.GetSums(ids[0]); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("GetSums after DeleteManifest = %v, want fs.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,94 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ObjectPath is the path of the object with the given hash in a bundle.
+func ObjectPath(hash string) string { return "objects/" + hash[:2] + "/" + hash }
+
+// ManifestPath is the path of the manifest with the given id in a bundle.
+func ManifestPath(id string) string { return "snapshots/" + id + ".json" }
+
+// Sums is the integrity manifest of a snapshot: the SHA-256 of every file
+// it consists of, by path in the bundle. It is written in the format of
+// sha256sum, so an unencrypted bundle can also be checked with
+// "sha256sum -c snapshots/<id>.sums" from its root.
+type Sums map[string]string
+
+// Add records the SHA-256 of data under path.
+func (s Sums) Add(path string, data []byte) {
+	sum := sha256.Sum256(data)
+	s[path] = hex.EncodeToString(sum[:])
+}
+
+// Marshal encodes s one file per line, sorted by path.
+func (s Sums) Marshal() []byte {
+	var buf bytes.Buffer
+	for _, path := range slices.Sorted(maps.Keys(s)) {
+		fmt.Fprintf(&buf, "%s  %s\n", s[path], path)
+	}
+	return buf.Bytes()
+}
+
+// ParseSums decodes an integrity manifest written by Sums.Marshal.
+func ParseSums(data []byte) (Sums, error) {
+	sums := make(Sums)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		hash, path, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok || len(hash) != sha256.Size*2 || path == "" {
+			return nil, fmt.Errorf("invalid integrity manifest: line %d is not \"<sha256>  <path>\"", n)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("invalid integrity manifest: line %d: %w", n, err)
+		}
+		sums[path] = hash
+	}
+	return sums, scanner.Err()
+}
+
+// SumsDiff lists the files of a bundle that do not match its integrity
+// manifest, by path.
+type SumsDiff struct {
+	// Changed files have a different SHA-256 than recorded.
+	Changed []string
+	// Missing files are recorded but absent.
+	Missing []string
+	// Unlisted files are part of the snapshot but not recorded.
+	Unlisted []string
+}
+
+// Empty reports whether the bundle matches its integrity manifest.
+func (d SumsDiff) Empty() bool {
+	return len(d.Changed) == 0 && len(d.Missing) == 0 && len(d.Unlisted) == 0
+}
+
+// Compare lists how actual, the sums of the files found in a bundle, differ
+// from s, the sums recorded for it. Files absent from the bundle are left
+// out of actual.
+func (s Sums) Compare(actual Sums) SumsDiff {
+	var diff SumsDiff
+	for _, path := range slices.Sorted(maps.Keys(s)) {
+		got, ok := actual[path]
+		switch {
+		case !ok:
+			diff.Missing = append(diff.Missing, path)
+		case got != s[path]:
+			diff.Changed = append(diff.Changed, path)
+		}
+	}
+	for _, path := range slices.Sorted(maps.Keys(actual)) {
+		if _, ok := s[path]; !ok {
+			diff.Unlisted = append(diff.Unlisted, path)
+		}
+	}
+	return diff
+}
@@ -0,0 +1,95 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSums(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	sums := make(Sums)
+	sums.Add(ManifestPath("20260101T000000.000Z"), []byte("{}\n"))
+	sums[ObjectPath(hash)] = hash
+
+	manifestSum := sha256.Sum256([]byte("{}\n"))
+	data := sums.Marshal()
+	want := hash + "  objects/ab/" + hash + "\n" +
+		hex.EncodeToString(manifestSum[:]) + "  snapshots/20260101T000000.000Z.json\n"
+	if string(data) != want {
+		t.Fatalf("Marshal = %q, want %q", data, want)
+	}
+	parsed, err := ParseSums(data)
+	if err != nil || !bytes.Equal(parsed.Marshal(), data) {
+		t.Fatalf("ParseSums round trip = %v, %v", parsed, err)
+	}
+	for _, bad := range []string{"nothash  path\n", hash + " single-space\n", hash + "  \n"} {
+		if _, err := ParseSums([]byte(bad)); err == nil {
+			t.Errorf("ParseSums(%q) should fail", bad)
+		}
+	}
+
+	actual := make(Sums)
+	actual.Add(ManifestPath("20260101T000000.000Z"), []byte("{\"changed\":true}\n"))
+	actual["objects/cd/cdcd"] = "cdcd"
+	diff := sums.Compare(actual)
+	if !slices.Equal(diff.Changed, []string{"snapshots/20260101T000000.000Z.json"}) ||
+		!slices.Equal(diff.Missing, []string{ObjectPath(hash)}) ||
+		!slices.Equal(diff.Unlisted, []string{"objects/cd/cdcd"}) {
+		t.Fatalf("Compare = %+v", diff)
+	}
+	if diff := sums.Compare(parsed); !diff.Empty() {
+		t.Fatalf("Compare of equal sums = %+v, want empty", diff)
+	}
+}
+
+func TestSignSSH(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen is not installed")
+	}
+	dir := t.TempDir()
+	newKey := func(name, principal string) string {
+		key := filepath.Join(dir, name)
+		if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", principal, "-f", key).CombinedOutput(); err != nil {
+			t.Fatalf("ssh-keygen failed: %v: %s", err, out)
+		}
+		return key
+	}
+	allowed := func(name, principal, key string) string {
+		pub, err := exec.Command("ssh-keygen", "-y", "-f", key).Output()
+		if err != nil {
+			t.Fatalf("ssh-keygen -y failed: %v", err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(principal+" "+string(pub)), 0o600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		return path
+	}
+	ctx := context.Background()
+	key := newKey("id", "alice")
+	signers := allowed("allowed", "alice@example.com", key)
+	others := allowed("others", "bob@example.com", newKey("other", "bob"))
+
+	data := []byte("sums\n")
+	sig, err := SignSSH(ctx, key, data)
+	if err != nil {
+		t.Fatalf("SignSSH failed: %v", err)
+	}
+	if principal, err := VerifySSH(ctx, signers, data, sig); err != nil || principal != "alice@example.com" {
+		t.Fatalf("VerifySSH = %q, %v", principal, err)
+	}
+	if _, err := VerifySSH(ctx, signers, []byte("altered\n"), sig); err == nil {
+		t.Fatal("VerifySSH should reject altered data")
+	}
+	if _, err := VerifySSH(ctx, others, data, sig); err == nil {
+		t.Fatal("VerifySSH should reject signatures from keys not in the file")
+	}
+}
@@ -0,0 +1,111 @@
+// Package summarizer generates short summaries of entry content by running
+// a user-configured command, so that tools can skim long entries without
+// reading them in full.
+package summarizer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/choplin/vault.md/internal/config"
+)
+
+// MaxLength is the longest summary stored, in bytes. Longer output is cut
+// at a character boundary.
+const MaxLength = 1000
+
+// DefaultTimeout bounds how long one summarizer run may take.
+const DefaultTimeout = 60 * time.Second
+
+// ErrEmpty is returned when the command printed nothing.
+var ErrEmpty = errors.New("summarizer produced no output")
+
+// Command summarizes content by running an external program with the
+// content on stdin and VAULT_KEY set to the entry's key.
+type Command struct {
+	// Line is the command line as configured, recorded with each summary.
+	Line    string
+	MinSize int
+	Timeout time.Duration
+	argv    []string
+}
+
+// New parses line into a Command that summarizes content of at least
+// minSize bytes.
+func New(line string, minSize int) (*Command, error) {
+	argv, err := config.SplitCommandLine(line)
+	if err != nil {
+		return nil, fmt.Errorf("invalid summarizer command %q: %w", line, err)
+	}
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("summarizer command is empty")
+	}
+	return &Command{Line: line, argv: argv, MinSize: minSize, Timeout: DefaultTimeout}, nil
+}
+
+// FromSettings returns the Command configured in settings, or nil when
+// summaries are disabled.
+func FromSettings(settings *config.Settings) (*Command, error) {
+	line := settings.SummarizerCommand()
+	if line == "" {
+		return nil, nil
+	}
+	return New(line, settings.SummarizeMinSize())
+}
+
+// Wants reports whether content is long enough to be summarized.
+func (c *Command) Wants(content string) bool {
+	return c != nil && len(content) >= c.MinSize
+}
+
+// Summarize runs the command and returns its trimmed output.
+func (c *Command) Summarize(ctx context.Context, key, content string) (string, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	//nolint:gosec // G204: the summarizer command comes from the user's config file
+	cmd := exec.CommandContext(ctx, c.argv[0], c.argv[1:]...)
+	cmd.Stdin = strings.NewReader(content)
+	cmd.Env = append(os.Environ(), "VAULT_KEY="+key)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("summarizer %s timed out after %s", c.argv[0], c.Timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("summarizer %s failed: %w: %s", c.argv[0], err, msg)
+		}
+		return "", fmt.Errorf("summarizer %s failed: %w", c.argv[0], err)
+	}
+
+	summary := strings.TrimSpace(stdout.String())
+	if summary == "" {
+		return "", ErrEmpty
+	}
+	return truncate(summary, MaxLength), nil
+}
+
+// truncate shortens s to at most n bytes without splitting a character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[:n]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,80 @@
+package summarizer
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func requireShell(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("summarizer tests use sh")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	requireShell(t)
+	cmd, err := New(`sh -c 'printf "%s: " "$VAULT_KEY"; head -c 5; echo'`, 0)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+
+	got, err := cmd.Summarize(context.Background(), "notes", "hello world")
+	if err != nil {
+		t.Fatalf("Summarize error: %v", err)
+	}
+	if got != "notes: hello" {
+		t.Fatalf("unexpected summary %q", got)
+	}
+}
+
+func TestSummarizeErrors(t *testing.T) {
+	requireShell(t)
+
+	empty, _ := New("true", 0)
+	if _, err := empty.Summarize(context.Background(), "k", "content"); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("expected ErrEmpty, got %v", err)
+	}
+
+	failing, _ := New(`sh -c 'echo quota exceeded >&2; exit 3'`, 0)
+	_, err := failing.Summarize(context.Background(), "k", "content")
+	if err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Fatalf("expected stderr in error, got %v", err)
+	}
+
+	slow, _ := New("sleep 5", 0)
+	slow.Timeout = 50 * time.Millisecond
+	if _, err := slow.Summarize(context.Background(), "k", "content"); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+}
+
+func TestWants(t *testing.T) {
+	cmd, err := New("cat", 10)
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	if cmd.Wants("short") {
+		t.Fatalf("expected short content to be skipped")
+	}
+	if !cmd.Wants(strings.Repeat("x", 10)) {
+		t.Fatalf("expected content at MinSize to be summarized")
+	}
+	var disabled *Command
+	if disabled.Wants(strings.Repeat("x", 100)) {
+		t.Fatalf("expected nil command to summarize nothing")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("héllo", 2); got != "h" {
+		t.Fatalf("expected cut before multi-byte rune, got %q", got)
+	}
+	if got := truncate("short", 10); got != "short" {
+		t.Fatalf("expected unchanged string, got %q", got)
+	}
+}
@@ -0,0 +1,83 @@
+package textpatch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines Unified shows around each
+// change, as diff -u does.
+const diffContext = 3
+
+// Unified returns a unified diff that turns a into b, with labelA and labelB
+// as the file names in its header, or "" if the texts are equal. The result
+// applies to a with ApplyUnified.
+func Unified(a, b, labelA, labelB string) string {
+	if a == b {
+		return ""
+	}
+	// Lines keep their newline so that a missing one at the end of the
+	// text counts as a change.
+	aLines, bLines := splitKeepEOL(a), splitKeepEOL(b)
+	hunks := diffLines(aLines, bLines)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", labelA, labelB)
+	for start := 0; start < len(hunks); {
+		end := start + 1
+		for end < len(hunks) && hunks[end].aStart-hunks[end-1].aEnd <= 2*diffContext {
+			end++
+		}
+		writeHunk(&sb, aLines, bLines, hunks[start:end])
+		start = end
+	}
+	return sb.String()
+}
+
+// writeHunk writes one "@@" block covering group, a run of changes close
+// enough to share their context lines.
+func writeHunk(sb *strings.Builder, a, b []string, group []diffHunk) {
+	first, last := group[0], group[len(group)-1]
+	aStart := max(first.aStart-diffContext, 0)
+	aEnd := min(last.aEnd+diffContext, len(a))
+	bStart := first.bStart - (first.aStart - aStart)
+	bEnd := last.bEnd + (aEnd - last.aEnd)
+
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aStart, aEnd-aStart), hunkRange(bStart, bEnd-bStart))
+	pos := aStart
+	for _, h := range group {
+		writeLines(sb, " ", a[pos:h.aStart])
+		writeLines(sb, "-", a[h.aStart:h.aEnd])
+		writeLines(sb, "+", b[h.bStart:h.bEnd])
+		pos = h.aEnd
+	}
+	writeLines(sb, " ", a[pos:aEnd])
+}
+
+// hunkRange formats the "start,count" of a hunk header. An empty range
+// names the line before it, as diff -u does.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func writeLines(sb *strings.Builder, prefix string, lines []string) {
+	for _, line := range lines {
+		sb.WriteString(prefix)
+		sb.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// splitKeepEOL splits text into lines that keep their trailing newline.
+func splitKeepEOL(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
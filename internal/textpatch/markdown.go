@@ -0,0 +1,139 @@
+package textpatch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// heading is an ATX heading ("## Title") found outside fenced code blocks.
+type heading struct {
+	line  int
+	level int
+	text  string
+}
+
+// ExtractSection returns the content under a markdown heading: the lines
+// after the heading up to the next heading of the same or a higher level.
+// See ReplaceSection for how the heading is matched.
+func ExtractSection(text, name string) (string, error) {
+	lines, hasEOL := splitLines(text)
+	start, end, err := findSection(lines, name)
+	if err != nil {
+		return "", err
+	}
+	return joinLines(lines[start:end], hasEOL || end < len(lines)), nil
+}
+
+// ReplaceSection replaces the content under a markdown heading, keeping the
+// heading line itself. name is either a full heading such as "## Decisions",
+// which must match level and title, or a bare title matched at any level.
+// The heading must occur exactly once.
+func ReplaceSection(text, name, content string) (string, error) {
+	lines, hasEOL := splitLines(text)
+	start, end, err := findSection(lines, name)
+	if err != nil {
+		return "", err
+	}
+
+	body, bodyEOL := splitLines(content)
+	if end == len(lines) && len(body) > 0 {
+		// The section runs to the end of the document, so the replacement
+		// decides whether it ends with a newline.
+		hasEOL = bodyEOL
+	}
+
+	result := make([]string, 0, len(lines)-(end-start)+len(body))
+	result = append(result, lines[:start]...)
+	result = append(result, body...)
+	result = append(result, lines[end:]...)
+	return joinLines(result, hasEOL), nil
+}
+
+// findSection returns the line range [start, end) of the body under the
+// heading called name.
+func findSection(lines []string, name string) (int, int, error) {
+	level, title := parseHeadingName(name)
+	if title == "" {
+		return 0, 0, fmt.Errorf("section heading is empty")
+	}
+
+	headings := scanHeadings(lines)
+	match := -1
+	for i, h := range headings {
+		if h.text != title || (level > 0 && h.level != level) {
+			continue
+		}
+		if match >= 0 {
+			return 0, 0, fmt.Errorf("section %q occurs more than once", name)
+		}
+		match = i
+	}
+	if match < 0 {
+		return 0, 0, fmt.Errorf("section %q not found", name)
+	}
+
+	h := headings[match]
+	end := len(lines)
+	for _, next := range headings[match+1:] {
+		if next.level <= h.level {
+			end = next.line
+			break
+		}
+	}
+	return h.line + 1, end, nil
+}
+
+// parseHeadingName splits "## Title" into level 2 and "Title". A name
+// without leading '#' characters has level 0, meaning any level.
+func parseHeadingName(name string) (int, string) {
+	name = strings.TrimSpace(name)
+	if level, text, ok := parseATXHeading(name); ok {
+		return level, text
+	}
+	return 0, name
+}
+
+func scanHeadings(lines []string) []heading {
+	var (
+		headings []heading
+		fence    string
+	)
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+		if level, text, ok := parseATXHeading(trimmed); ok {
+			headings = append(headings, heading{line: i, level: level, text: text})
+		}
+	}
+	return headings
+}
+
+// parseATXHeading recognises "#"-style headings of level 1 to 6, dropping
+// an optional closing sequence of '#' characters.
+func parseATXHeading(line string) (int, string, bool) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, "", false
+	}
+	rest := line[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, "", false
+	}
+	text := strings.TrimSpace(rest)
+	if trimmed := strings.TrimRight(text, "#"); trimmed == "" || strings.HasSuffix(trimmed, " ") {
+		text = strings.TrimSpace(trimmed)
+	}
+	return level, text, true
+}
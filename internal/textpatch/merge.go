@@ -0,0 +1,209 @@
+package textpatch
+
+import (
+	"slices"
+	"strings"
+)
+
+// MergeLabels names the sides of a three-way merge in conflict markers.
+type MergeLabels struct {
+	Ours   string
+	Theirs string
+}
+
+// Merge3 merges the changes ours and theirs each made to base, line by line.
+// Regions changed on only one side take that side's lines; regions both sides
+// changed identically are taken once. Any other overlapping change becomes a
+// conflict delimited by "<<<<<<<", "=======" and ">>>>>>>" lines. Merge3
+// returns the merged text and the number of conflicts.
+func Merge3(base, ours, theirs string, labels MergeLabels) (string, int) {
+	baseLines, baseEOL := splitLines(base)
+	oursLines, oursEOL := splitLines(ours)
+	theirsLines, theirsEOL := splitLines(theirs)
+
+	type sideHunk struct {
+		diffHunk
+		theirs bool
+	}
+	var hunks []sideHunk
+	for _, h := range diffLines(baseLines, oursLines) {
+		hunks = append(hunks, sideHunk{diffHunk: h})
+	}
+	for _, h := range diffLines(baseLines, theirsLines) {
+		hunks = append(hunks, sideHunk{diffHunk: h, theirs: true})
+	}
+	slices.SortStableFunc(hunks, func(a, b sideHunk) int {
+		return a.aStart - b.aStart
+	})
+
+	var (
+		result    []string
+		conflicts int
+		cursor    int
+	)
+	for i := 0; i < len(hunks); {
+		// Group hunks whose base ranges overlap or touch.
+		lo, hi := hunks[i].aStart, hunks[i].aEnd
+		j := i + 1
+		for j < len(hunks) && hunks[j].aStart <= hi {
+			hi = max(hi, hunks[j].aEnd)
+			j++
+		}
+		group := hunks[i:j]
+		i = j
+
+		var oursHunks, theirsHunks []diffHunk
+		for _, h := range group {
+			if h.theirs {
+				theirsHunks = append(theirsHunks, h.diffHunk)
+			} else {
+				oursHunks = append(oursHunks, h.diffHunk)
+			}
+		}
+
+		result = append(result, baseLines[cursor:lo]...)
+		cursor = hi
+
+		oursRegion := sideRegion(baseLines, oursLines, oursHunks, lo, hi)
+		theirsRegion := sideRegion(baseLines, theirsLines, theirsHunks, lo, hi)
+		switch {
+		case len(theirsHunks) == 0:
+			result = append(result, oursRegion...)
+		case len(oursHunks) == 0:
+			result = append(result, theirsRegion...)
+		case slices.Equal(oursRegion, theirsRegion):
+			result = append(result, oursRegion...)
+		default:
+			conflicts++
+			result = append(result, strings.TrimSpace("<<<<<<< "+labels.Ours))
+			result = append(result, oursRegion...)
+			result = append(result, "=======")
+			result = append(result, theirsRegion...)
+			result = append(result, strings.TrimSpace(">>>>>>> "+labels.Theirs))
+		}
+	}
+	result = append(result, baseLines[cursor:]...)
+
+	hasEOL := oursEOL
+	if oursEOL == baseEOL {
+		hasEOL = theirsEOL
+	}
+	if conflicts > 0 {
+		hasEOL = true
+	}
+	return joinLines(result, hasEOL), conflicts
+}
+
+// sideRegion returns one side's lines covering base[lo:hi], given that side's
+// hunks within that range.
+func sideRegion(base, side []string, hunks []diffHunk, lo, hi int) []string {
+	if len(hunks) == 0 {
+		return base[lo:hi]
+	}
+	first, last := hunks[0], hunks[len(hunks)-1]
+	return side[first.bStart-(first.aStart-lo) : last.bEnd+(hi-last.aEnd)]
+}
+
+// diffHunk maps the lines a[aStart:aEnd] to b[bStart:bEnd].
+type diffHunk struct {
+	aStart, aEnd int
+	bStart, bEnd int
+}
+
+// diffLines returns the hunks that turn a into b.
+func diffLines(a, b []string) []diffHunk {
+	var hunks []diffHunk
+	ai, bi := 0, 0
+	for _, m := range matchLines(a, b) {
+		if m[0] > ai || m[1] > bi {
+			hunks = append(hunks, diffHunk{aStart: ai, aEnd: m[0], bStart: bi, bEnd: m[1]})
+		}
+		ai, bi = m[0]+1, m[1]+1
+	}
+	if ai < len(a) || bi < len(b) {
+		hunks = append(hunks, diffHunk{aStart: ai, aEnd: len(a), bStart: bi, bEnd: len(b)})
+	}
+	return hunks
+}
+
+// matchLines returns the index pairs of a longest common subsequence of a
+// and b, in increasing order, using Myers' O(ND) algorithm. Common leading
+// and trailing lines are matched up front to keep the search small.
+func matchLines(a, b []string) [][2]int {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	matches := make([][2]int, 0, prefix+suffix)
+	for i := range prefix {
+		matches = append(matches, [2]int{i, i})
+	}
+	for _, m := range myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		matches = append(matches, [2]int{m[0] + prefix, m[1] + prefix})
+	}
+	for i := suffix; i > 0; i-- {
+		matches = append(matches, [2]int{len(a) - i, len(b) - i})
+	}
+	return matches
+}
+
+func myers(a, b []string) [][2]int {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return nil
+	}
+
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	var trace [][]int
+
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, slices.Clone(v))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	var matches [][2]int
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			matches = append(matches, [2]int{x, y})
+		}
+		x, y = prevX, prevY
+	}
+	slices.Reverse(matches)
+	return matches
+}
@@ -0,0 +1,78 @@
+package textpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SectionEdit replaces everything between the line containing Start and the
+// next line containing End. The marker lines themselves are kept.
+type SectionEdit struct {
+	Start   string `json:"start"`
+	End     string `json:"end"`
+	Content string `json:"content"`
+}
+
+// ParseSectionEdits decodes a JSON section patch: a single edit object or
+// an array of them.
+func ParseSectionEdits(data []byte) ([]SectionEdit, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var edit SectionEdit
+		if err := json.Unmarshal(trimmed, &edit); err != nil {
+			return nil, fmt.Errorf("invalid section patch: %w", err)
+		}
+		return []SectionEdit{edit}, nil
+	}
+
+	var edits []SectionEdit
+	if err := json.Unmarshal(trimmed, &edits); err != nil {
+		return nil, fmt.Errorf("invalid section patch: %w", err)
+	}
+	return edits, nil
+}
+
+// ApplySections applies edits in order. Each start marker must occur exactly
+// once so that an edit cannot silently land in the wrong place.
+func ApplySections(original string, edits []SectionEdit) (string, error) {
+	if len(edits) == 0 {
+		return "", fmt.Errorf("patch contains no sections")
+	}
+
+	text := original
+	for i, edit := range edits {
+		if edit.Start == "" || edit.End == "" {
+			return "", fmt.Errorf("section %d: start and end markers are required", i+1)
+		}
+
+		switch n := strings.Count(text, edit.Start); n {
+		case 0:
+			return "", fmt.Errorf("section %d: start marker %q not found", i+1, edit.Start)
+		case 1:
+		default:
+			return "", fmt.Errorf("section %d: start marker %q occurs %d times", i+1, edit.Start, n)
+		}
+
+		startLine := strings.Index(text, edit.Start)
+		bodyStart := len(text)
+		if nl := strings.IndexByte(text[startLine:], '\n'); nl >= 0 {
+			bodyStart = startLine + nl + 1
+		}
+
+		endMarker := strings.Index(text[bodyStart:], edit.End)
+		if endMarker < 0 {
+			return "", fmt.Errorf("section %d: end marker %q not found after start marker", i+1, edit.End)
+		}
+		// Replace up to the beginning of the end marker's line.
+		bodyEnd := bodyStart + strings.LastIndexByte(text[bodyStart:bodyStart+endMarker], '\n') + 1
+
+		content := edit.Content
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		text = text[:bodyStart] + content + text[bodyEnd:]
+	}
+	return text, nil
+}
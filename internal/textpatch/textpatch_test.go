@@ -0,0 +1,211 @@
+package textpatch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyUnifiedReplacesLines(t *testing.T) {
+	original := "# Notes\n\nalpha\nbeta\ngamma\n"
+	diff := `--- a/notes.md
++++ b/notes.md
+@@ -3,3 +3,3 @@
+ alpha
+-beta
++BETA
+ gamma
+`
+	got, err := ApplyUnified(original, diff)
+	if err != nil {
+		t.Fatalf("ApplyUnified error: %v", err)
+	}
+	if want := "# Notes\n\nalpha\nBETA\ngamma\n"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestApplyUnifiedToleratesOffset(t *testing.T) {
+	// The diff was made before two lines were added at the top.
+	original := "new 1\nnew 2\none\ntwo\nthree\n"
+	diff := "@@ -1,2 +1,3 @@\n one\n+one and a half\n two\n"
+
+	got, err := ApplyUnified(original, diff)
+	if err != nil {
+		t.Fatalf("ApplyUnified error: %v", err)
+	}
+	if want := "new 1\nnew 2\none\none and a half\ntwo\nthree\n"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestApplyUnifiedHandlesMissingFinalNewline(t *testing.T) {
+	original := "one\ntwo"
+	diff := "@@ -1,2 +1,2 @@\n one\n-two\n\\ No newline at end of file\n+TWO\n"
+
+	got, err := ApplyUnified(original, diff)
+	if err != nil {
+		t.Fatalf("ApplyUnified error: %v", err)
+	}
+	if want := "one\nTWO\n"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestApplyUnifiedRejectsMismatch(t *testing.T) {
+	_, err := ApplyUnified("one\ntwo\n", "@@ -1,1 +1,1 @@\n-uno\n+ONE\n")
+	if err == nil || !strings.Contains(err.Error(), "does not apply") {
+		t.Fatalf("expected hunk mismatch error, got %v", err)
+	}
+}
+
+func TestApplySectionsReplacesBetweenMarkers(t *testing.T) {
+	original := "intro\n<!-- begin:todo -->\n- old\n<!-- end:todo -->\noutro\n"
+	edits, err := ParseSectionEdits([]byte(`{"start": "<!-- begin:todo -->", "end": "<!-- end:todo -->", "content": "- new\n- newer"}`))
+	if err != nil {
+		t.Fatalf("ParseSectionEdits error: %v", err)
+	}
+
+	got, err := ApplySections(original, edits)
+	if err != nil {
+		t.Fatalf("ApplySections error: %v", err)
+	}
+	if want := "intro\n<!-- begin:todo -->\n- new\n- newer\n<!-- end:todo -->\noutro\n"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestApplySectionsRejectsAmbiguousMarker(t *testing.T) {
+	original := "[a]\nx\n[/a]\n[a]\ny\n[/a]\n"
+	_, err := ApplySections(original, []SectionEdit{{Start: "[a]", End: "[/a]", Content: "z"}})
+	if err == nil || !strings.Contains(err.Error(), "occurs 2 times") {
+		t.Fatalf("expected ambiguity error, got %v", err)
+	}
+}
+
+func TestExtractSection(t *testing.T) {
+	doc := "# Notes\nintro\n## Decisions\nuse sqlite\n### Details\nwal mode\n## Open\n```\n## not a heading\n```\n"
+
+	got, err := ExtractSection(doc, "## Decisions")
+	if err != nil {
+		t.Fatalf("ExtractSection: %v", err)
+	}
+	if want := "use sqlite\n### Details\nwal mode\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	got, err = ExtractSection(doc, "Open")
+	if err != nil {
+		t.Fatalf("ExtractSection by title: %v", err)
+	}
+	if want := "```\n## not a heading\n```\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if _, err := ExtractSection(doc, "### Decisions"); err == nil {
+		t.Fatal("expected an error for a heading at the wrong level")
+	}
+	if _, err := ExtractSection(doc, "not a heading"); err == nil {
+		t.Fatal("expected headings inside code fences to be ignored")
+	}
+}
+
+func TestReplaceSection(t *testing.T) {
+	doc := "# Notes\n## Decisions\nold\n## Open\nquestions"
+
+	got, err := ReplaceSection(doc, "## Decisions", "new\nlines")
+	if err != nil {
+		t.Fatalf("ReplaceSection: %v", err)
+	}
+	if want := "# Notes\n## Decisions\nnew\nlines\n## Open\nquestions"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	got, err = ReplaceSection(doc, "Open", "answers\n")
+	if err != nil {
+		t.Fatalf("ReplaceSection last section: %v", err)
+	}
+	if want := "# Notes\n## Decisions\nold\n## Open\nanswers\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if _, err := ReplaceSection("## A\nx\n## A\ny\n", "A", "z"); err == nil {
+		t.Fatal("expected an error for an ambiguous heading")
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+	labels := MergeLabels{Ours: "v2", Theirs: "v3"}
+
+	got, conflicts := Merge3(base, "a\nB\nc\nd\ne\n", "a\nb\nc\nd\nE\nf\n", labels)
+	if conflicts != 0 {
+		t.Fatalf("expected a clean merge, got %d conflicts:\n%s", conflicts, got)
+	}
+	if want := "a\nB\nc\nd\nE\nf\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	got, conflicts = Merge3(base, "a\nX\nc\nd\ne\n", "a\nY\nc\nd\ne\n", labels)
+	if conflicts != 1 {
+		t.Fatalf("expected 1 conflict, got %d:\n%s", conflicts, got)
+	}
+	if want := "a\n<<<<<<< v2\nX\n=======\nY\n>>>>>>> v3\nc\nd\ne\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	got, conflicts = Merge3(base, "a\nb\nZ\nd\ne\n", "a\nb\nZ\nd\ne\n", labels)
+	if conflicts != 0 || got != "a\nb\nZ\nd\ne\n" {
+		t.Fatalf("identical changes should merge cleanly, got %d conflicts: %q", conflicts, got)
+	}
+}
+
+func TestMatchLines(t *testing.T) {
+	a := strings.Split("a b c a b b a", " ")
+	b := strings.Split("c b a b a c", " ")
+	matches := matchLines(a, b)
+	if len(matches) != 4 {
+		t.Fatalf("expected an LCS of length 4, got %v", matches)
+	}
+	for i, m := range matches {
+		if a[m[0]] != b[m[1]] {
+			t.Fatalf("match %v pairs %q with %q", m, a[m[0]], b[m[1]])
+		}
+		if i > 0 && (m[0] <= matches[i-1][0] || m[1] <= matches[i-1][1]) {
+			t.Fatalf("matches are not increasing: %v", matches)
+		}
+	}
+}
+
+func TestUnifiedRoundTrips(t *testing.T) {
+	long := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	cases := []struct{ a, b string }{
+		{"one\ntwo\nthree\n", "one\n2\nthree\n"},
+		{long, strings.Replace(strings.Replace(long, "2\n", "two\n", 1), "14\n", "", 1)},
+		{"", "new\n"},
+		{"old\n", ""},
+		{"same\nno newline", "same\nno newline\n"},
+		{"a\nb\n", "a\nb\nc"},
+	}
+	for _, c := range cases {
+		diff := Unified(c.a, c.b, "a/notes", "b/notes")
+		if !strings.HasPrefix(diff, "--- a/notes\n+++ b/notes\n@@ ") {
+			t.Fatalf("unexpected diff header for %q -> %q:\n%s", c.a, c.b, diff)
+		}
+		got, err := ApplyUnified(c.a, diff)
+		if err != nil {
+			t.Fatalf("ApplyUnified error for %q -> %q: %v\n%s", c.a, c.b, err, diff)
+		}
+		if got != c.b {
+			t.Fatalf("round trip of %q gave %q, want %q\n%s", c.a, got, c.b, diff)
+		}
+	}
+
+	if got := Unified("x\n", "x\n", "a", "b"); got != "" {
+		t.Fatalf("expected no diff for equal texts, got %q", got)
+	}
+	// Distant changes get separate hunks.
+	diff := Unified(long, strings.Replace(strings.Replace(long, "1\n", "one\n", 1), "15\n", "fifteen\n", 1), "a", "b")
+	if n := strings.Count(diff, "@@ -"); n != 2 {
+		t.Fatalf("expected 2 hunks, got %d:\n%s", n, diff)
+	}
+}
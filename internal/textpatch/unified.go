@@ -0,0 +1,180 @@
+// Package textpatch applies partial edits to stored text: unified diffs,
+// replacements of the text between marker lines, and markdown sections.
+package textpatch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// hunk is one "@@ -a,b +c,d @@" block of a unified diff.
+type hunk struct {
+	oldStart int
+	oldLines []string
+	newLines []string
+	// oldNoEOL and newNoEOL record "\ No newline at end of file" markers.
+	oldNoEOL bool
+	newNoEOL bool
+}
+
+// ApplyUnified applies a unified diff (as produced by `diff -u` or
+// `git diff`) to original. File headers are ignored, so the diff must
+// describe a single file. Hunks may apply at an offset from the line
+// numbers in their headers, but every context and removed line must match.
+func ApplyUnified(original, diff string) (string, error) {
+	hunks, err := parseUnified(diff)
+	if err != nil {
+		return "", err
+	}
+	if len(hunks) == 0 {
+		return "", fmt.Errorf("patch contains no hunks")
+	}
+
+	lines, hasEOL := splitLines(original)
+	var result []string
+	pos := 0
+	for i, h := range hunks {
+		at, ok := locate(lines, h, pos)
+		if !ok {
+			return "", fmt.Errorf("hunk %d (@@ -%d) does not apply", i+1, h.oldStart)
+		}
+		result = append(result, lines[pos:at]...)
+		result = append(result, h.newLines...)
+		pos = at + len(h.oldLines)
+
+		if pos == len(lines) {
+			hasEOL = !h.newNoEOL
+		}
+	}
+	result = append(result, lines[pos:]...)
+
+	return joinLines(result, hasEOL), nil
+}
+
+func parseUnified(diff string) ([]hunk, error) {
+	var (
+		hunks   []hunk
+		current *hunk
+		last    byte
+	)
+	lines := strings.Split(diff, "\n")
+	for n, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			oldStart, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			hunks = append(hunks, hunk{oldStart: oldStart})
+			current = &hunks[len(hunks)-1]
+			last = 0
+		case current == nil:
+			// Headers (diff --git, index, ---, +++) precede the first hunk.
+			continue
+		case strings.HasPrefix(line, `\`):
+			switch last {
+			case '-':
+				current.oldNoEOL = true
+			case '+':
+				current.newNoEOL = true
+			case ' ':
+				current.oldNoEOL = true
+				current.newNoEOL = true
+			}
+		case line == "":
+			// Blank context lines are sometimes emitted without the leading
+			// space; a trailing empty string is just the final newline.
+			if n == len(lines)-1 {
+				continue
+			}
+			current.oldLines = append(current.oldLines, "")
+			current.newLines = append(current.newLines, "")
+			last = ' '
+		case line[0] == ' ':
+			current.oldLines = append(current.oldLines, line[1:])
+			current.newLines = append(current.newLines, line[1:])
+			last = ' '
+		case line[0] == '-':
+			current.oldLines = append(current.oldLines, line[1:])
+			last = '-'
+		case line[0] == '+':
+			current.newLines = append(current.newLines, line[1:])
+			last = '+'
+		default:
+			// Anything else ends the hunk, e.g. the next file's header.
+			current = nil
+		}
+	}
+	return hunks, nil
+}
+
+// parseHunkHeader returns the 1-based start line of the old side.
+func parseHunkHeader(line string) (int, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") {
+		return 0, fmt.Errorf("malformed hunk header: %q", line)
+	}
+	start, _, _ := strings.Cut(fields[1][1:], ",")
+	n, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, fmt.Errorf("malformed hunk header: %q", line)
+	}
+	return n, nil
+}
+
+// locate finds where h's old lines occur at or after from, preferring the
+// position named in the hunk header and searching outwards from it.
+func locate(lines []string, h hunk, from int) (int, bool) {
+	want := h.oldStart - 1
+	if len(h.oldLines) == 0 {
+		// Pure insertion: "-N,0" means after line N.
+		want = h.oldStart
+	}
+	want = max(want, from)
+
+	for delta := 0; ; delta++ {
+		below, above := want+delta, want-delta
+		if below > len(lines) && above < from {
+			return 0, false
+		}
+		if below <= len(lines) && matchesAt(lines, h.oldLines, below) {
+			return below, true
+		}
+		if delta > 0 && above >= from && matchesAt(lines, h.oldLines, above) {
+			return above, true
+		}
+	}
+}
+
+func matchesAt(lines, want []string, at int) bool {
+	if at+len(want) > len(lines) {
+		return false
+	}
+	for i, line := range want {
+		if lines[at+i] != line {
+			return false
+		}
+	}
+	return true
+}
+
+// splitLines splits text into lines and reports whether it ended in a newline.
+func splitLines(text string) ([]string, bool) {
+	if text == "" {
+		return nil, false
+	}
+	hasEOL := strings.HasSuffix(text, "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n"), hasEOL
+}
+
+func joinLines(lines []string, hasEOL bool) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	text := strings.Join(lines, "\n")
+	if hasEOL {
+		text += "\n"
+	}
+	return text
+}
@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// StorageInfo describes where the vault lives and what it holds, for bug
+// reports.
+type StorageInfo struct {
+	VaultDir            string `json:"vaultDir"`
+	DatabasePath        string `json:"databasePath"`
+	SchemaVersion       int    `json:"schemaVersion"`
+	LatestSchemaVersion int    `json:"latestSchemaVersion"`
+	Entries             int64  `json:"entries"`
+	Versions            int64  `json:"versions"`
+	ObjectFiles         int64  `json:"objectFiles"`
+	ObjectBytes         int64  `json:"objectBytes"`
+	// ObjectLayout is the layout new objects are written in.
+	ObjectLayout filesystem.Layout `json:"objectLayout"`
+}
+
+// CollectStorageInfo reports the vault's location, schema version, and the
+// size of its object store. Object files are counted on disk, so they
+// include files no version refers to.
+func CollectStorageInfo(ctx context.Context, dbCtx *database.Context) (*StorageInfo, error) {
+	info := &StorageInfo{
+		VaultDir:     config.GetVaultDir(),
+		DatabasePath: config.GetDBPath(),
+	}
+	if config.IsEphemeral() {
+		info.DatabasePath = "in memory (ephemeral)"
+	}
+
+	var err error
+	info.SchemaVersion, info.LatestSchemaVersion, err = database.SchemaVersion(dbCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := services.NewIntegrityService(dbCtx).Usage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info.Entries = usage.EntryCount
+	info.Versions = usage.VersionCount
+
+	if info.ObjectLayout, err = filesystem.CurrentLayout(); err != nil {
+		return nil, err
+	}
+	err = filepath.WalkDir(config.GetObjectsDir(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || filesystem.IsLayoutFile(path) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		info.ObjectFiles++
+		info.ObjectBytes += fi.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
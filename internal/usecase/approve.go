@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// ApproveResult describes the outcome of Approve.
+type ApproveResult struct {
+	Version int64
+	// AlreadyApproved is true when the version had been approved before; the
+	// original approval is kept.
+	AlreadyApproved bool
+}
+
+// Approve marks a version of key (the latest when version is nil) as
+// approved by actor; see ResolveActor. Versions start out as drafts, and
+// Get with GetOptions.Approved reads the newest approved one.
+func (u *Entry) Approve(ctx context.Context, sc scope.Scope, key string, version *int, actor string) (*ApproveResult, error) {
+	if err := scope.Validate(sc); err != nil {
+		return nil, err
+	}
+
+	scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	if key, err = u.lookupKey(ctx, scopeID, key); err != nil {
+		return nil, err
+	}
+
+	var target int64
+	if version != nil {
+		target = int64(*version)
+	} else {
+		latest, err := u.entryService.GetLatest(ctx, scopeID, key)
+		if err != nil {
+			return nil, err
+		}
+		target = latest.Version
+	}
+
+	approved, err := u.entryService.Approve(ctx, scopeID, key, target, ResolveActor(actor))
+	if err != nil {
+		return nil, err
+	}
+	return &ApproveResult{Version: target, AlreadyApproved: !approved}, nil
+}
+
+// latestApprovedVersion resolves GetOptions.Approved to a version number.
+func (u *Entry) latestApprovedVersion(ctx context.Context, scopeID int64, key string) (int64, error) {
+	version, err := u.entryService.LatestApprovedVersion(ctx, scopeID, key)
+	if errors.Is(err, services.ErrNotFound) {
+		if _, latestErr := u.entryService.GetLatest(ctx, scopeID, key); latestErr != nil {
+			return 0, latestErr
+		}
+		return 0, fmt.Errorf("%s has no approved version", key)
+	}
+	return version, err
+}
@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/choplin/vault.md/internal/scope"
+)
+
+// ArchiveFilter selects the entries ArchiveMatching archives. Entries must
+// match every filter that is set.
+type ArchiveFilter struct {
+	// Patterns are keys or path.Match globs, as in PackOptions.
+	Patterns []string
+	// Prefix keeps keys starting with it, such as "tmp/".
+	Prefix string
+	// Before keeps entries whose latest version was written before it.
+	Before time.Time
+	// DryRun reports the matching entries without archiving them.
+	DryRun bool
+}
+
+// ArchiveMatching archives the unarchived entries of sc that match filter in
+// one transaction and returns them. At least one filter must be set so that
+// a missing flag cannot archive a whole scope.
+func (u *Entry) ArchiveMatching(ctx context.Context, sc scope.Scope, filter ArchiveFilter) ([]ListEntry, error) {
+	if len(filter.Patterns) == 0 && filter.Prefix == "" && filter.Before.IsZero() {
+		return nil, fmt.Errorf("archive needs keys, a prefix, or a cutoff time")
+	}
+	for _, pattern := range filter.Patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid key pattern %q: %w", pattern, err)
+		}
+	}
+
+	var matched []ListEntry
+	err := u.WithTransaction(ctx, func(tx *Entry) error {
+		list, err := tx.List(ctx, sc, &ListOptions{Until: filter.Before})
+		if err != nil {
+			return err
+		}
+		records, err := selectPackRecords(list.Entries, filter.Patterns)
+		if err != nil {
+			return err
+		}
+
+		matched = make([]ListEntry, 0, len(records))
+		for _, record := range records {
+			if !strings.HasPrefix(record.Key, filter.Prefix) {
+				continue
+			}
+			if !filter.DryRun {
+				if _, err := tx.Archive(ctx, sc, record.Key); err != nil {
+					return fmt.Errorf("archive %s: %w", record.Key, err)
+				}
+			}
+			matched = append(matched, ListEntry{
+				Record:     record,
+				Scope:      sc,
+				ScopeType:  sc.Type,
+				ScopeShort: scope.FormatScopeShort(sc),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matched, nil
+}
+
+// Restore brings back an archived key so list and stale show it again. It
+// returns false if the key does not exist or is not archived.
+func (u *Entry) Restore(ctx context.Context, sc scope.Scope, key string) (bool, error) {
+	if err := scope.Validate(sc); err != nil {
+		return false, err
+	}
+	scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
+	if err != nil {
+		return false, err
+	}
+	if key, err = u.lookupKey(ctx, scopeID, key); err != nil {
+		return false, err
+	}
+	restored, err := u.entryService.Restore(ctx, scopeID, key)
+	if restored {
+		u.emit(ctx, newEvent(EventRestore, sc, key, 0))
+	}
+	return restored, err
+}
@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// versionAsOf returns the newest version of key created at or before asOf.
+// Deleted versions cannot be seen, so this is the version that was latest at
+// asOf only if none was deleted since.
+func (u *Entry) versionAsOf(ctx context.Context, scopeID int64, key string, asOf time.Time) (int64, error) {
+	versions, err := u.entryService.ListVersions(ctx, scopeID, key)
+	if err != nil {
+		return 0, err
+	}
+	var version int64
+	for _, v := range versions {
+		if !v.CreatedAt.After(asOf) && v.Version > version {
+			version = v.Version
+		}
+	}
+	if version == 0 {
+		return 0, fmt.Errorf("%w: %s had no version at %s", services.ErrNotFound, key, asOf.UTC().Format(time.RFC3339))
+	}
+	return version, nil
+}
+
+// asOfLatest reports whether List picks a version of each key by AsOf.
+func (o *ListOptions) asOfLatest() bool {
+	return !o.AsOf.IsZero() && !o.AllVersions
+}
+
+// eachEntryAsOf lists, for every key, the newest version created at or before
+// opts.AsOf. The other filters apply to that version rather than to the
+// versions it is picked from, so a key whose description changed is not
+// matched by an older description.
+func (u *Entry) eachEntryAsOf(ctx context.Context, sc scope.Scope, opts *ListOptions, fn func(ListEntry) error) error {
+	candidates := ListOptions{
+		IncludeArchived: opts.IncludeArchived,
+		AllVersions:     true,
+		AllScopes:       opts.AllScopes,
+		Until:           opts.AsOf,
+		IncludeInternal: opts.IncludeInternal,
+	}
+
+	type scopedKey struct {
+		scopeID int64
+		key     string
+	}
+	index := make(map[scopedKey]int)
+	var latest []ListEntry
+	err := u.eachEntry(ctx, sc, &candidates, func(entry ListEntry) error {
+		k := scopedKey{entry.Record.ScopeID, entry.Record.Key}
+		i, ok := index[k]
+		switch {
+		case !ok:
+			index[k] = len(latest)
+			latest = append(latest, entry)
+		case entry.Record.Version > latest[i].Record.Version:
+			latest[i] = entry
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var entries []ListEntry
+	for _, entry := range latest {
+		if matchesAsOfFilter(entry, opts) {
+			entries = append(entries, entry)
+		}
+	}
+	if opts.SortBy != "" || !opts.AllScopes {
+		sortListEntries(entries, opts.SortBy, opts.Reverse)
+	}
+	for _, entry := range entries {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchesAsOfFilter applies the filters of opts other than AsOf the way the
+// list queries do.
+func matchesAsOfFilter(entry ListEntry, opts *ListOptions) bool {
+	r := entry.Record
+	if !opts.Since.IsZero() && r.UpdatedAt.Before(opts.Since) {
+		return false
+	}
+	if !opts.Until.IsZero() && r.UpdatedAt.After(opts.Until) {
+		return false
+	}
+	if opts.DescriptionContains != "" {
+		if r.Description == nil || !strings.Contains(strings.ToLower(*r.Description), strings.ToLower(opts.DescriptionContains)) {
+			return false
+		}
+	}
+	if opts.Language != "" && r.Language != opts.Language {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,242 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// DefaultBackupSample is how many versions VerifyBackup hashes by default.
+const DefaultBackupSample = 20
+
+// backupLayout locates the parts of a backup.
+type backupLayout struct {
+	dbPath string
+	// objectsDir is the backup's copy of the objects directory, or empty
+	// when the backup holds only the database and its versions point at
+	// the live objects.
+	objectsDir string
+}
+
+// resolveBackup accepts either a copy of the vault directory or a copy of
+// its index.db, with the objects directory next to it or not at all.
+func resolveBackup(path string) (backupLayout, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return backupLayout{}, err
+	}
+	dir := filepath.Dir(path)
+	layout := backupLayout{dbPath: path}
+	if info.IsDir() {
+		dir = path
+		layout.dbPath = filepath.Join(path, filepath.Base(config.GetDBPath()))
+	}
+	objects := filepath.Join(dir, filepath.Base(config.GetObjectsDir()))
+	if info, err := os.Stat(objects); err == nil && info.IsDir() {
+		layout.objectsDir = objects
+	}
+	return layout, nil
+}
+
+// objectPath maps the file path recorded for a version to the backup's copy
+// of it.
+func (l backupLayout) objectPath(recorded string) string {
+	if l.objectsDir == "" {
+		return recorded
+	}
+	if rel, err := filepath.Rel(config.GetObjectsDir(), recorded); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.Join(l.objectsDir, rel)
+	}
+	// The backup may come from a vault at another location.
+	marker := string(filepath.Separator) + filepath.Base(config.GetObjectsDir()) + string(filepath.Separator)
+	if _, rel, ok := strings.Cut(recorded, marker); ok {
+		return filepath.Join(l.objectsDir, rel)
+	}
+	return recorded
+}
+
+// VerifyBackup checks that the backup at path could be restored from: the
+// database opens and passes SQLite's integrity check, its rows are
+// consistent, its counts are compared with the live vault, and sample
+// versions are read back and hashed. sample caps how many versions are
+// hashed; 0 hashes all of them. The backup is opened read-only.
+func VerifyBackup(ctx context.Context, path string, sample int) *DoctorReport {
+	report := &DoctorReport{Status: CheckOK}
+
+	layout, err := resolveBackup(path)
+	if err != nil {
+		report.add(DoctorCheck{Name: "backup", Status: CheckFail, Message: err.Error()})
+		return report
+	}
+	backup, err := database.OpenReadOnly(layout.dbPath)
+	if err != nil {
+		report.add(DoctorCheck{
+			Name:    "backup",
+			Status:  CheckFail,
+			Message: err.Error(),
+			Hint:    "pass a copy of the vault directory or of its index.db",
+		})
+		return report
+	}
+	defer func() {
+		_ = database.CloseDatabase(backup)
+	}()
+	report.add(DoctorCheck{Name: "backup", Status: CheckOK, Message: layout.dbPath})
+
+	schema := checkBackupSchema(ctx, backup)
+	report.add(schema)
+	if schema.Status == CheckFail {
+		return report
+	}
+	report.add(checkBackupIntegrity(ctx, backup))
+
+	d := &Doctor{dbCtx: backup, integrity: services.NewIntegrityService(backup)}
+	report.add(d.checkDanglingRows(ctx))
+	report.add(checkBackupCounts(ctx, d.integrity))
+
+	versionFiles, err := d.integrity.VersionFiles(ctx)
+	if err != nil {
+		report.add(DoctorCheck{Name: "object hashes", Status: CheckFail, Message: err.Error()})
+		return report
+	}
+	report.add(checkBackupObjects(ctx, layout, versionFiles, sample))
+	return report
+}
+
+func checkBackupSchema(ctx context.Context, backup *database.Context) DoctorCheck {
+	current, latest, err := backup.SchemaVersion(ctx)
+	if err != nil {
+		return DoctorCheck{Name: "schema", Status: CheckFail, Message: err.Error()}
+	}
+	switch {
+	case current == 0:
+		return DoctorCheck{
+			Name:    "schema",
+			Status:  CheckFail,
+			Message: "not a vault database",
+			Hint:    "pass a copy of the vault directory or of its index.db",
+		}
+	case current > latest:
+		return DoctorCheck{
+			Name:    "schema",
+			Status:  CheckWarn,
+			Message: fmt.Sprintf("backup schema v%d is newer than this binary (v%d)", current, latest),
+			Hint:    "restore it with a vault at least as new as the one that wrote it",
+		}
+	case current < latest:
+		return DoctorCheck{Name: "schema", Status: CheckOK, Message: fmt.Sprintf("v%d, migrated to v%d when restored", current, latest)}
+	default:
+		return DoctorCheck{Name: "schema", Status: CheckOK, Message: fmt.Sprintf("v%d", current)}
+	}
+}
+
+func checkBackupIntegrity(ctx context.Context, backup *database.Context) DoctorCheck {
+	problems, err := backup.IntegrityCheck(ctx)
+	if err != nil {
+		return DoctorCheck{Name: "integrity", Status: CheckFail, Message: err.Error()}
+	}
+	if len(problems) == 0 {
+		return DoctorCheck{Name: "integrity", Status: CheckOK, Message: "ok"}
+	}
+	return DoctorCheck{
+		Name:    "integrity",
+		Status:  CheckFail,
+		Message: fmt.Sprintf("%d problems: %s", len(problems), summarizePaths(problems)),
+		Hint:    "the backup file is corrupt; take a new backup",
+	}
+}
+
+// checkBackupCounts compares the backup's size with the live vault's. A
+// backup that differs is not broken, only out of date, so it warns.
+func checkBackupCounts(ctx context.Context, backup *services.IntegrityService) DoctorCheck {
+	backupUsage, err := backup.Usage(ctx)
+	if err != nil {
+		return DoctorCheck{Name: "counts", Status: CheckFail, Message: err.Error()}
+	}
+	counts := fmt.Sprintf("%d entries, %d versions", backupUsage.EntryCount, backupUsage.VersionCount)
+
+	live, err := database.CreateDatabase("")
+	if err != nil {
+		return DoctorCheck{Name: "counts", Status: CheckSkip, Message: counts + "; live vault unavailable: " + err.Error()}
+	}
+	defer func() {
+		_ = database.CloseDatabase(live)
+	}()
+	liveUsage, err := services.NewIntegrityService(live).Usage(ctx)
+	if err != nil {
+		return DoctorCheck{Name: "counts", Status: CheckSkip, Message: counts + "; live vault unavailable: " + err.Error()}
+	}
+
+	if backupUsage.EntryCount == liveUsage.EntryCount && backupUsage.VersionCount == liveUsage.VersionCount {
+		return DoctorCheck{Name: "counts", Status: CheckOK, Message: counts + ", same as the live vault"}
+	}
+	return DoctorCheck{
+		Name:    "counts",
+		Status:  CheckWarn,
+		Message: fmt.Sprintf("%s; the live vault has %d entries, %d versions", counts, liveUsage.EntryCount, liveUsage.VersionCount),
+		Hint:    "the backup is out of date; take a new one to cover recent changes",
+	}
+}
+
+func checkBackupObjects(ctx context.Context, layout backupLayout, versionFiles []database.VersionFileRecord, sample int) DoctorCheck {
+	where := "in the backup"
+	if layout.objectsDir == "" {
+		where = "at their recorded paths, as the backup has no objects directory"
+	}
+	if len(versionFiles) == 0 {
+		return DoctorCheck{Name: "object hashes", Status: CheckOK, Message: "no versions to check"}
+	}
+
+	checked := versionFiles
+	if sample > 0 && sample < len(versionFiles) {
+		checked = make([]database.VersionFileRecord, 0, sample)
+		for _, i := range rand.Perm(len(versionFiles))[:sample] { //nolint:gosec // G404: sampling, not security
+			checked = append(checked, versionFiles[i])
+		}
+	}
+
+	var missing, mismatched []string
+	for _, vf := range checked {
+		if err := ctx.Err(); err != nil {
+			return DoctorCheck{Name: "object hashes", Status: CheckFail, Message: err.Error()}
+		}
+		path := layout.objectPath(vf.FilePath)
+		ok, err := filesystem.VerifyFile(path, vf.Hash)
+		switch {
+		case err != nil:
+			mismatched = append(mismatched, fmt.Sprintf("%s (%v)", path, err))
+		case !filesystem.FileExists(path):
+			missing = append(missing, path)
+		case !ok:
+			mismatched = append(mismatched, path)
+		}
+	}
+
+	message := fmt.Sprintf("%d of %d versions hashed %s", len(checked), len(versionFiles), where)
+	if len(missing) == 0 && len(mismatched) == 0 {
+		return DoctorCheck{Name: "object hashes", Status: CheckOK, Message: message}
+	}
+	var problems []string
+	hint := "copy the objects directory together with index.db when taking backups"
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("%d missing: %s", len(missing), summarizePaths(missing)))
+	}
+	if len(mismatched) > 0 {
+		problems = append(problems, fmt.Sprintf("%d do not match their hash: %s", len(mismatched), summarizePaths(mismatched)))
+		hint = "the files were corrupted or changed after the backup was taken; take a new backup"
+	}
+	return DoctorCheck{
+		Name:    "object hashes",
+		Status:  CheckFail,
+		Message: message + "; " + strings.Join(problems, "; "),
+		Hint:    hint,
+	}
+}
@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// ContextEntry is the latest version of a key in one scope.
+type ContextEntry struct {
+	GetResult
+	Content string
+}
+
+// ContextResult is what LoadContext found.
+type ContextResult struct {
+	// Entries are ordered by key, in the order given, and then by scope,
+	// most specific first. A key may be found in several scopes.
+	Entries []ContextEntry
+	// Missing lists the keys found in no scope.
+	Missing []string
+}
+
+// LoadContext reads the latest version of each key from each of scopes, so
+// an agent can pick up its plan, conventions, and decisions in one call.
+func (u *Entry) LoadContext(ctx context.Context, scopes []scope.Scope, keys []string, opts *GetOptions) (*ContextResult, error) {
+	if opts != nil {
+		// Only the latest version of each key is read.
+		readOpts := *opts
+		readOpts.Version = nil
+		opts = &readOpts
+	}
+
+	result := &ContextResult{}
+	for _, key := range keys {
+		found := false
+		for _, sc := range scopes {
+			got, err := u.Get(ctx, sc, key, opts)
+			if errors.Is(err, services.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			content, err := filesystem.ReadFile(got.Record.FilePath)
+			if err != nil {
+				return nil, err
+			}
+			result.Entries = append(result.Entries, ContextEntry{GetResult: *got, Content: content})
+			found = true
+		}
+		if !found {
+			result.Missing = append(result.Missing, key)
+		}
+	}
+	return result, nil
+}
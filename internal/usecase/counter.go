@@ -0,0 +1,167 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/language"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// Update retries for as long as it keeps losing to other writers, up to
+// maxUpdateAttempts, waiting a random time of up to updateBackoff times the
+// attempt number in between so that contenders spread out.
+const (
+	maxUpdateAttempts = 50
+	updateBackoff     = 10 * time.Millisecond
+)
+
+// UpdateFunc computes new content from the content of the latest version,
+// which is "" with exists false when key has no version yet.
+type UpdateFunc func(content string, exists bool) (string, error)
+
+// Update stores the result of update as a new version of key. Unlike Patch,
+// it starts over from the new latest version when another writer stores one
+// in the meantime, so concurrent updates are applied one after the other and
+// none is lost. update may therefore run more than once.
+func (u *Entry) Update(ctx context.Context, sc scope.Scope, key string, update UpdateFunc, opts *SetOptions) (*SetResult, error) {
+	if err := scope.Validate(sc); err != nil {
+		return nil, err
+	}
+	scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	// Read the key Set will write, so that a key given unnormalized does not
+	// conflict with itself.
+	if key, err = u.storedKey(ctx, scopeID, key, opts != nil && opts.Internal); err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; attempt <= maxUpdateAttempts; attempt++ {
+		var result *SetResult
+		result, err = u.updateOnce(ctx, sc, key, update, opts)
+		if !errors.Is(err, services.ErrVersionConflict) {
+			return result, err
+		}
+		wait := rand.N(time.Duration(attempt) * updateBackoff) //nolint:gosec // G404: jitter, not security
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return nil, fmt.Errorf("failed to update %s after %d attempts: %w", key, maxUpdateAttempts, err)
+}
+
+func (u *Entry) updateOnce(ctx context.Context, sc scope.Scope, key string, update UpdateFunc, opts *SetOptions) (*SetResult, error) {
+	setOpts := SetOptions{}
+	if opts != nil {
+		setOpts = *opts
+	}
+
+	var (
+		content string
+		base    int64
+	)
+	current, err := u.Get(ctx, sc, key, nil)
+	switch {
+	case err == nil:
+		if content, err = filesystem.ReadFile(current.Record.FilePath); err != nil {
+			return nil, err
+		}
+		base = current.Record.Version
+		if setOpts.Language == "" {
+			setOpts.Language = current.Record.Language
+		}
+	case !errors.Is(err, services.ErrNotFound):
+		return nil, err
+	}
+
+	updated, err := update(content, base != 0)
+	if err != nil {
+		return nil, err
+	}
+	setOpts.BaseVersion = &base
+	return u.Set(ctx, sc, key, updated, &setOpts)
+}
+
+// CounterResult describes an Increment.
+type CounterResult struct {
+	Value   int64
+	Version int64
+}
+
+// Increment adds delta to the integer stored under key, starting from 0 for
+// a new key, and returns the new value. Concurrent increments all count.
+func (u *Entry) Increment(ctx context.Context, sc scope.Scope, key string, delta int64, opts *SetOptions) (*CounterResult, error) {
+	var value int64
+	setOpts := SetOptions{}
+	if opts != nil {
+		setOpts = *opts
+	}
+	setOpts.Language = language.Text
+	result, err := u.Update(ctx, sc, key, func(content string, exists bool) (string, error) {
+		value = 0
+		if exists {
+			n, err := parseCounter(content)
+			if err != nil {
+				return "", fmt.Errorf("%s is not a counter: %w", key, err)
+			}
+			value = n
+		}
+		value += delta
+		return strconv.FormatInt(value, 10), nil
+	}, &setOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &CounterResult{Value: value, Version: result.Version}, nil
+}
+
+// parseCounter parses the content of a counter.
+func parseCounter(content string) (int64, error) {
+	return strconv.ParseInt(strings.TrimSpace(content), 10, 64)
+}
+
+// AppendLine adds line to the end of key, creating it if needed.
+// Concurrent appends all land, each on its own line.
+func (u *Entry) AppendLine(ctx context.Context, sc scope.Scope, key, line string, opts *SetOptions) (*SetResult, error) {
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("line is empty")
+	}
+	return u.Update(ctx, sc, key, func(content string, _ bool) (string, error) {
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		return content + line + "\n", nil
+	}, opts)
+}
+
+// Counter returns the integer stored under key, or 0 if key has no version.
+func (u *Entry) Counter(ctx context.Context, sc scope.Scope, key string) (int64, error) {
+	current, err := u.Get(ctx, sc, key, nil)
+	if errors.Is(err, services.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	content, err := filesystem.ReadFile(current.Record.FilePath)
+	if err != nil {
+		return 0, err
+	}
+	value, err := parseCounter(content)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a counter: %w", key, err)
+	}
+	return value, nil
+}
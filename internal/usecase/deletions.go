@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/filesystem"
+)
+
+// Deleting an entry removes its version rows first and its object files
+// after the transaction commits. The rows' file paths are queued for
+// deletion in that same transaction, so a file that cannot be removed, or
+// a process that dies in between, leaves a queue record rather than an
+// orphan nobody knows about. The queue is retried before each delete and
+// by vault db gc.
+
+// PendingDeletionResult reports a pass over the deletion queue.
+type PendingDeletionResult struct {
+	// Removed counts the files that were removed, or found already gone.
+	Removed int
+	// Failed lists the files that are still queued, with the error of this
+	// attempt.
+	Failed []database.PendingDeletionRecord
+}
+
+// ProcessPendingDeletions retries removing every file in the deletion queue.
+func (u *Entry) ProcessPendingDeletions(ctx context.Context) (*PendingDeletionResult, error) {
+	if err := u.requireDatabase(); err != nil {
+		return nil, err
+	}
+	if _, err := u.integrityService.DropReusedDeletions(ctx); err != nil {
+		return nil, err
+	}
+	pending, err := u.integrityService.PendingDeletions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PendingDeletionResult{}
+	for _, p := range pending {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if err := u.removeObject(ctx, p.FilePath); err != nil {
+			p.Attempts++
+			p.LastError = err.Error()
+			result.Failed = append(result.Failed, p)
+			continue
+		}
+		result.Removed++
+	}
+	return result, nil
+}
+
+// retryPendingDeletions makes a quiet pass over the deletion queue; files
+// that still cannot be removed stay queued for the next one.
+func (u *Entry) retryPendingDeletions(ctx context.Context) {
+	if u.integrityService == nil {
+		return
+	}
+	_, _ = u.ProcessPendingDeletions(ctx)
+}
+
+// removeObjects removes the object files of deleted versions, carrying on
+// past failures, and returns the first error.
+func (u *Entry) removeObjects(ctx context.Context, paths []string) error {
+	var first error
+	for _, path := range paths {
+		if err := u.removeObject(ctx, path); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// removeObject removes one object file and settles its deletion queue
+// record. Without a database nothing was queued, so the file is just
+// removed.
+func (u *Entry) removeObject(ctx context.Context, path string) error {
+	err := filesystem.DeleteFile(path)
+	if u.integrityService == nil {
+		return err
+	}
+	if err != nil {
+		_ = u.integrityService.DeletionFailed(ctx, path, err)
+		return err
+	}
+	return u.integrityService.DeletionDone(ctx, path)
+}
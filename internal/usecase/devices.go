@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/database"
+)
+
+// ErrDeviceRevoked is returned when a revoked device tries to push to a
+// shared remote.
+var ErrDeviceRevoked = errors.New("this device has been revoked")
+
+// Devices lists the devices that have written to the vault, including
+// revoked ones.
+func (u *Entry) Devices(ctx context.Context) ([]database.DeviceRecord, error) {
+	if err := u.requireDatabase(); err != nil {
+		return nil, err
+	}
+	return u.deviceService.List(ctx)
+}
+
+// RevokeDevice stops versions written by deviceID from being accepted by
+// sync, and stops that device from pushing once it has seen the revocation.
+// Revocations travel with snapshots, so other vaults pick them up on their
+// next restore.
+func (u *Entry) RevokeDevice(ctx context.Context, deviceID string) error {
+	if err := u.requireDatabase(); err != nil {
+		return err
+	}
+	devices, err := u.deviceService.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, d := range devices {
+		if d.DeviceID == deviceID {
+			return u.deviceService.Revoke(ctx, deviceID)
+		}
+	}
+	return fmt.Errorf("unknown device: %s", deviceID)
+}
+
+// adoptRevocations records the revocations found in a remote manifest and
+// fails if they include this device.
+func (u *Entry) adoptRevocations(ctx context.Context, revoked []string) error {
+	if err := u.requireDatabase(); err != nil {
+		return err
+	}
+	if len(revoked) > 0 {
+		if err := u.deviceService.Revoke(ctx, revoked...); err != nil {
+			return err
+		}
+	}
+	return u.checkDeviceNotRevoked(ctx)
+}
+
+// checkDeviceNotRevoked fails with ErrDeviceRevoked when this installation's
+// device has been revoked.
+func (u *Entry) checkDeviceNotRevoked(ctx context.Context) error {
+	if err := u.requireDatabase(); err != nil {
+		return err
+	}
+	self, err := config.DeviceID()
+	if err != nil {
+		return err
+	}
+	revoked, err := u.deviceService.Revoked(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range revoked {
+		if id == self {
+			return fmt.Errorf("%w: %s", ErrDeviceRevoked, self)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,517 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// CheckStatus is the outcome of a single doctor check.
+type CheckStatus string
+
+// Check outcomes, ordered from best to worst.
+const (
+	CheckOK   CheckStatus = "ok"
+	CheckSkip CheckStatus = "skip"
+	CheckWarn CheckStatus = "warn"
+	CheckFail CheckStatus = "fail"
+)
+
+func (s CheckStatus) severity() int {
+	switch s {
+	case CheckWarn:
+		return 1
+	case CheckFail:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// lowDiskSpaceBytes is the free space below which doctor warns.
+const lowDiskSpaceBytes = 100 << 20
+
+// maxListedPaths caps how many offending paths a finding lists.
+const maxListedPaths = 5
+
+// DoctorCheck is one finding of the doctor command.
+type DoctorCheck struct {
+	Name    string      `json:"name"`
+	Status  CheckStatus `json:"status"`
+	Message string      `json:"message"`
+	Hint    string      `json:"hint,omitempty"`
+}
+
+// DoctorReport collects all findings of a doctor run.
+type DoctorReport struct {
+	Status CheckStatus   `json:"status"`
+	Checks []DoctorCheck `json:"checks"`
+}
+
+func (r *DoctorReport) add(check DoctorCheck) {
+	r.Checks = append(r.Checks, check)
+	if check.Status.severity() > r.Status.severity() {
+		r.Status = check.Status
+	}
+}
+
+// Doctor diagnoses the vault environment.
+type Doctor struct {
+	dbCtx     *database.Context
+	integrity *services.IntegrityService
+}
+
+// NewDoctor creates a Doctor.
+func NewDoctor() *Doctor {
+	return &Doctor{}
+}
+
+// Run executes every check. Environment checks run before the database is
+// opened so that problems with the vault directory are reported as found
+// rather than masked by the directory being created.
+func (d *Doctor) Run(ctx context.Context) *DoctorReport {
+	report := &DoctorReport{Status: CheckOK}
+
+	report.add(checkConfig())
+	report.add(checkVaultDir())
+	report.add(checkCloudSync())
+	report.add(checkDiskSpace())
+	report.add(checkGit())
+
+	dbCtx, err := database.CreateDatabase("")
+	if err != nil {
+		report.add(DoctorCheck{
+			Name:    "database",
+			Status:  CheckFail,
+			Message: err.Error(),
+			Hint:    fmt.Sprintf("check that %s is a readable SQLite database", databaseLocation()),
+		})
+		return report
+	}
+	defer func() {
+		_ = database.CloseDatabase(dbCtx)
+	}()
+	d.dbCtx = dbCtx
+	d.integrity = services.NewIntegrityService(dbCtx)
+
+	report.add(DoctorCheck{Name: "database", Status: CheckOK, Message: databaseLocation()})
+	report.add(d.checkSchema(ctx))
+	report.add(d.checkJournal(ctx))
+	report.add(d.checkWriteLock())
+	report.add(d.checkDanglingRows(ctx))
+	report.add(d.checkRetention(ctx))
+
+	versionFiles, err := d.integrity.VersionFiles(ctx)
+	if err != nil {
+		report.add(DoctorCheck{Name: "objects", Status: CheckFail, Message: err.Error()})
+		return report
+	}
+	report.add(checkMissingObjects(ctx, versionFiles))
+	report.add(d.checkPendingDeletions(ctx))
+	report.add(checkOrphanedObjects(ctx, versionFiles))
+
+	return report
+}
+
+// CheckConsistency runs the checks of Run that compare the index with the
+// object files: dangling rows, missing objects, and orphaned objects.
+func CheckConsistency(ctx context.Context, dbCtx *database.Context) []DoctorCheck {
+	d := &Doctor{dbCtx: dbCtx, integrity: services.NewIntegrityService(dbCtx)}
+	checks := []DoctorCheck{d.checkDanglingRows(ctx)}
+
+	versionFiles, err := d.integrity.VersionFiles(ctx)
+	if err != nil {
+		return append(checks, DoctorCheck{Name: "objects", Status: CheckFail, Message: err.Error()})
+	}
+	return append(checks, checkMissingObjects(ctx, versionFiles), checkOrphanedObjects(ctx, versionFiles))
+}
+
+// databaseLocation describes where the index lives: the local database
+// file, or the configured remote URL without credentials.
+func databaseLocation() string {
+	if config.IsEphemeral() {
+		return "in memory (ephemeral)"
+	}
+	settings, err := config.Load()
+	if err != nil || settings.RemoteDatabaseURL() == "" {
+		return config.GetDBPath()
+	}
+	u, err := url.Parse(settings.RemoteDatabaseURL())
+	if err != nil {
+		return "remote database"
+	}
+	u.User = nil
+	u.RawQuery = ""
+	return u.String()
+}
+
+func checkConfig() DoctorCheck {
+	path := config.GetConfigPath()
+	if _, err := config.Load(); err != nil {
+		return DoctorCheck{
+			Name:    "config",
+			Status:  CheckFail,
+			Message: err.Error(),
+			Hint:    fmt.Sprintf("fix or remove %s", path),
+		}
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return DoctorCheck{Name: "config", Status: CheckOK, Message: "no config file, using defaults"}
+	}
+	return DoctorCheck{Name: "config", Status: CheckOK, Message: path}
+}
+
+func checkVaultDir() DoctorCheck {
+	dir := config.GetVaultDir()
+	info, err := os.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return DoctorCheck{
+			Name:    "vault dir",
+			Status:  CheckWarn,
+			Message: fmt.Sprintf("%s does not exist", dir),
+			Hint:    "it is created on first write; set VAULT_DIR if this is not the intended location",
+		}
+	}
+	if err != nil {
+		return DoctorCheck{Name: "vault dir", Status: CheckFail, Message: err.Error()}
+	}
+	if !info.IsDir() {
+		return DoctorCheck{
+			Name:    "vault dir",
+			Status:  CheckFail,
+			Message: fmt.Sprintf("%s is not a directory", dir),
+			Hint:    "move the file away or point VAULT_DIR elsewhere",
+		}
+	}
+
+	probe, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return DoctorCheck{
+			Name:    "vault dir",
+			Status:  CheckFail,
+			Message: fmt.Sprintf("%s is not writable: %v", dir, err),
+			Hint:    fmt.Sprintf("fix permissions, e.g. chmod u+rwx %s", dir),
+		}
+	}
+	_ = probe.Close()
+	_ = os.Remove(probe.Name())
+
+	return DoctorCheck{Name: "vault dir", Status: CheckOK, Message: dir}
+}
+
+func checkCloudSync() DoctorCheck {
+	dir := config.GetVaultDir()
+	service := config.CloudSyncService(dir)
+	if service == "" {
+		return DoctorCheck{Name: "cloud sync", Status: CheckOK, Message: "vault dir is not in a synced folder"}
+	}
+
+	settings, err := config.Load()
+	if err != nil {
+		return DoctorCheck{Name: "cloud sync", Status: CheckSkip, Message: "config could not be loaded"}
+	}
+	if settings.RemoteDatabaseURL() != "" {
+		return DoctorCheck{Name: "cloud sync", Status: CheckOK, Message: fmt.Sprintf("vault dir is in a %s folder, but the database is remote", service)}
+	}
+	if !settings.IsSharedStorage() {
+		return DoctorCheck{
+			Name:    "cloud sync",
+			Status:  CheckFail,
+			Message: fmt.Sprintf("vault dir is in a %s folder and sharedStorage is off", service),
+			Hint:    "syncing copies the database mid-write and corrupts it; move the vault with VAULT_DIR and share it with vault sync-git",
+		}
+	}
+	return DoctorCheck{
+		Name:    "cloud sync",
+		Status:  CheckWarn,
+		Message: fmt.Sprintf("vault dir is in a %s folder; shared storage mode is on", service),
+		Hint:    "the lockfile does not stop two devices from writing their own copies; move the vault with VAULT_DIR and share it with vault sync-git",
+	}
+}
+
+func checkDiskSpace() DoctorCheck {
+	dir := config.GetVaultDir()
+	if _, err := os.Stat(dir); err != nil {
+		dir = filepath.Dir(dir)
+	}
+
+	free, err := filesystem.FreeSpace(dir)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return DoctorCheck{Name: "disk space", Status: CheckSkip, Message: "not supported on this platform"}
+	}
+	if err != nil {
+		return DoctorCheck{Name: "disk space", Status: CheckWarn, Message: err.Error()}
+	}
+	if free < lowDiskSpaceBytes {
+		return DoctorCheck{
+			Name:    "disk space",
+			Status:  CheckWarn,
+			Message: fmt.Sprintf("only %s free", formatBytes(int64(free))), //nolint:gosec // G115: free space fits in int64
+			Hint:    "free up space; writes fail once the disk is full",
+		}
+	}
+	return DoctorCheck{Name: "disk space", Status: CheckOK, Message: fmt.Sprintf("%s free", formatBytes(int64(free)))} //nolint:gosec // G115: free space fits in int64
+}
+
+func checkGit() DoctorCheck {
+	path, err := exec.LookPath("git")
+	if err != nil {
+		return DoctorCheck{
+			Name:    "git",
+			Status:  CheckWarn,
+			Message: "git not found in PATH",
+			Hint:    "install git; without it only the global scope is available",
+		}
+	}
+	return DoctorCheck{Name: "git", Status: CheckOK, Message: path}
+}
+
+func (d *Doctor) checkSchema(ctx context.Context) DoctorCheck {
+	current, latest, err := d.dbCtx.SchemaVersion(ctx)
+	if err != nil {
+		return DoctorCheck{Name: "schema", Status: CheckFail, Message: err.Error()}
+	}
+	switch {
+	case current > latest:
+		return DoctorCheck{
+			Name:    "schema",
+			Status:  CheckWarn,
+			Message: fmt.Sprintf("database schema v%d is newer than this binary (v%d)", current, latest),
+			Hint:    "upgrade vault; older binaries may misread newer data",
+		}
+	case current < latest:
+		return DoctorCheck{
+			Name:    "schema",
+			Status:  CheckFail,
+			Message: fmt.Sprintf("database schema v%d, expected v%d", current, latest),
+			Hint:    "migrations did not complete; check the vault dir is writable and re-run any command",
+		}
+	default:
+		return DoctorCheck{Name: "schema", Status: CheckOK, Message: fmt.Sprintf("v%d", current)}
+	}
+}
+
+func (d *Doctor) checkJournal(ctx context.Context) DoctorCheck {
+	mode, err := d.dbCtx.JournalMode(ctx)
+	if err != nil {
+		return DoctorCheck{Name: "journal", Status: CheckFail, Message: err.Error()}
+	}
+
+	if strings.EqualFold(mode, "wal") {
+		if d.dbCtx.SharedStorage() {
+			return DoctorCheck{
+				Name:    "journal",
+				Status:  CheckFail,
+				Message: "WAL mode is enabled on shared storage",
+				Hint:    "WAL does not work on network filesystems; run `sqlite3 index.db 'PRAGMA journal_mode=DELETE'` with all writers stopped",
+			}
+		}
+		if info, err := os.Stat(config.GetDBPath() + "-wal"); err == nil && info.Size() > 64<<20 {
+			return DoctorCheck{
+				Name:    "journal",
+				Status:  CheckWarn,
+				Message: fmt.Sprintf("WAL file is %s", formatBytes(info.Size())),
+				Hint:    "a long-running reader may be blocking checkpoints; restart MCP servers using this vault",
+			}
+		}
+	}
+
+	return DoctorCheck{Name: "journal", Status: CheckOK, Message: mode}
+}
+
+func (d *Doctor) checkWriteLock() DoctorCheck {
+	path := d.dbCtx.WriteLockPath()
+	if path == "" {
+		return DoctorCheck{Name: "write lock", Status: CheckSkip, Message: "shared storage mode is off"}
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return DoctorCheck{Name: "write lock", Status: CheckOK, Message: "not held"}
+	}
+	if err != nil {
+		return DoctorCheck{Name: "write lock", Status: CheckWarn, Message: err.Error()}
+	}
+
+	age := time.Since(info.ModTime()).Round(time.Second)
+	if age > database.WriteLockStaleAfter {
+		return DoctorCheck{
+			Name:    "write lock",
+			Status:  CheckWarn,
+			Message: fmt.Sprintf("%s held for %s", path, age),
+			Hint:    "the holder probably crashed; the next write breaks the lock automatically",
+		}
+	}
+	return DoctorCheck{Name: "write lock", Status: CheckOK, Message: fmt.Sprintf("held for %s", age)}
+}
+
+func (d *Doctor) checkDanglingRows(ctx context.Context) DoctorCheck {
+	counts, err := d.integrity.DanglingRows(ctx)
+	if err != nil {
+		return DoctorCheck{Name: "dangling rows", Status: CheckFail, Message: err.Error()}
+	}
+	if counts.Total() == 0 {
+		return DoctorCheck{Name: "dangling rows", Status: CheckOK, Message: "none"}
+	}
+	return DoctorCheck{
+		Name:   "dangling rows",
+		Status: CheckWarn,
+		Message: fmt.Sprintf("%d entries without versions, %d entries without status, %d statuses pointing at missing versions",
+			counts.EntriesWithoutVersions, counts.EntriesWithoutStatus, counts.StatusWithMissingVersion),
+		Hint: "delete and re-create the affected keys with `vault delete`",
+	}
+}
+
+func (d *Doctor) checkRetention(ctx context.Context) DoctorCheck {
+	// An unreadable config is already reported by checkConfig.
+	settings, _ := config.Load()
+
+	stats, err := CollectStats(ctx, d.dbCtx, settings.RetentionKeepVersions(), false)
+	if err != nil {
+		return DoctorCheck{Name: "retention", Status: CheckFail, Message: err.Error()}
+	}
+
+	usage := fmt.Sprintf("%d versions (%s)", stats.Versions, formatBytes(stats.TotalSize))
+	if stats.Retention == nil {
+		return DoctorCheck{
+			Name:    "retention",
+			Status:  CheckOK,
+			Message: usage + ", no retention policy",
+			Hint:    "set retention.keepVersions in the config file to track prunable history",
+		}
+	}
+	if stats.Retention.ReclaimableVersions == 0 {
+		return DoctorCheck{
+			Name:    "retention",
+			Status:  CheckOK,
+			Message: fmt.Sprintf("%s, all within keepVersions=%d", usage, stats.Retention.KeepVersions),
+		}
+	}
+	return DoctorCheck{
+		Name:   "retention",
+		Status: CheckWarn,
+		Message: fmt.Sprintf("%d of %s reclaimable (%s) under keepVersions=%d",
+			stats.Retention.ReclaimableVersions, usage, formatBytes(stats.Retention.ReclaimableSize), stats.Retention.KeepVersions),
+		Hint: "prune old versions with `vault delete --version`",
+	}
+}
+
+func checkMissingObjects(ctx context.Context, versionFiles []database.VersionFileRecord) DoctorCheck {
+	var missing []string
+	for _, vf := range versionFiles {
+		if err := ctx.Err(); err != nil {
+			return DoctorCheck{Name: "missing objects", Status: CheckFail, Message: err.Error()}
+		}
+		if !filesystem.FileExists(vf.FilePath) {
+			missing = append(missing, vf.FilePath)
+		}
+	}
+	if len(missing) == 0 {
+		return DoctorCheck{Name: "missing objects", Status: CheckOK, Message: fmt.Sprintf("%d versions checked", len(versionFiles))}
+	}
+	return DoctorCheck{
+		Name:    "missing objects",
+		Status:  CheckFail,
+		Message: fmt.Sprintf("%d versions reference missing files: %s", len(missing), summarizePaths(missing)),
+		Hint:    "restore the files from backup, or delete the affected versions with `vault delete --version`",
+	}
+}
+
+func (d *Doctor) checkPendingDeletions(ctx context.Context) DoctorCheck {
+	pending, err := d.integrity.PendingDeletions(ctx)
+	if err != nil {
+		return DoctorCheck{Name: "pending deletions", Status: CheckWarn, Message: err.Error()}
+	}
+	if len(pending) == 0 {
+		return DoctorCheck{Name: "pending deletions", Status: CheckOK, Message: "none"}
+	}
+	paths := make([]string, len(pending))
+	for i, p := range pending {
+		paths[i] = p.FilePath
+	}
+	return DoctorCheck{
+		Name:    "pending deletions",
+		Status:  CheckWarn,
+		Message: fmt.Sprintf("%d files of deleted versions could not be removed: %s", len(pending), summarizePaths(paths)),
+		Hint:    "run `vault db gc` to retry removing them",
+	}
+}
+
+func checkOrphanedObjects(ctx context.Context, versionFiles []database.VersionFileRecord) DoctorCheck {
+	referenced := make(map[string]struct{}, len(versionFiles))
+	for _, vf := range versionFiles {
+		referenced[filepath.Clean(vf.FilePath)] = struct{}{}
+	}
+
+	var (
+		orphans []string
+		bytes   int64
+	)
+	err := filepath.WalkDir(config.GetObjectsDir(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || filesystem.IsLayoutFile(path) {
+			return nil
+		}
+		if _, ok := referenced[filepath.Clean(path)]; ok {
+			return nil
+		}
+		orphans = append(orphans, path)
+		if info, err := d.Info(); err == nil {
+			bytes += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return DoctorCheck{Name: "orphaned objects", Status: CheckWarn, Message: err.Error()}
+	}
+
+	if len(orphans) == 0 {
+		return DoctorCheck{Name: "orphaned objects", Status: CheckOK, Message: "none"}
+	}
+	return DoctorCheck{
+		Name:    "orphaned objects",
+		Status:  CheckWarn,
+		Message: fmt.Sprintf("%d files (%s) not referenced by any version: %s", len(orphans), formatBytes(bytes), summarizePaths(orphans)),
+		Hint:    "left over from interrupted writes or manual edits; safe to delete once backed up",
+	}
+}
+
+func summarizePaths(paths []string) string {
+	if len(paths) <= maxListedPaths {
+		return strings.Join(paths, ", ")
+	}
+	return fmt.Sprintf("%s, and %d more", strings.Join(paths[:maxListedPaths], ", "), len(paths)-maxListedPaths)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
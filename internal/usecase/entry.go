@@ -2,78 +2,389 @@
 package usecase
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/entrykey"
 	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/jsonquery"
+	"github.com/choplin/vault.md/internal/language"
+	"github.com/choplin/vault.md/internal/notify"
 	"github.com/choplin/vault.md/internal/scope"
 	"github.com/choplin/vault.md/internal/services"
+	"github.com/choplin/vault.md/internal/summarizer"
 )
 
+// ErrIntegrity is returned when stored content no longer matches its hash.
+var ErrIntegrity = errors.New("file integrity check failed")
+
 // Entry provides use case operations for vault entries.
 type Entry struct {
-	scopeService *services.ScopeService
-	entryService *services.EntryService
+	db               *database.Context
+	scopeService     ScopeRepository
+	entryService     EntryRepository
+	deviceService    *services.DeviceService
+	integrityService *services.IntegrityService
+	lockService      *services.LockService
+	relationService  *services.RelationService
+	snapshotService  *services.ScopeSnapshotService
+
+	// inTx is set on the Entry WithTransaction passes to fn; events it
+	// emits wait in pending until the transaction commits.
+	inTx    bool
+	pending []notify.Event
+
+	// notifiers are loaded from the config file once, when the Entry is
+	// created.
+	notifiers []*notify.Webhook
 }
 
 // NewEntry creates a new Entry use case.
 func NewEntry(dbCtx *database.Context) *Entry {
+	u := newEntry(dbCtx)
+	u.notifiers = loadNotifiers()
+	return u
+}
+
+// newEntry creates an Entry on dbCtx without notifiers.
+func newEntry(dbCtx *database.Context) *Entry {
 	scopeSvc := services.NewScopeService(dbCtx)
 	entrySvc := services.NewEntryService(dbCtx)
 	return &Entry{
-		scopeService: scopeSvc,
-		entryService: entrySvc,
+		db:               dbCtx,
+		scopeService:     scopeSvc,
+		entryService:     entrySvc,
+		deviceService:    services.NewDeviceService(dbCtx),
+		integrityService: services.NewIntegrityService(dbCtx),
+		lockService:      services.NewLockService(dbCtx),
+		relationService:  services.NewRelationService(dbCtx),
+		snapshotService:  services.NewScopeSnapshotService(dbCtx),
 	}
 }
 
+// WithTransaction runs fn with an Entry whose operations all share one
+// database transaction, committed when fn returns nil and rolled back
+// otherwise, so multi-step changes such as imports land in full or not at
+// all. Object files fn wrote are not removed on rollback; callers that care
+// delete them, and doctor reports any left behind as orphaned. tx must not
+// be used after fn returns or from other goroutines.
+//
+// An Entry without a database, see NewEntryFromRepositories, runs fn on
+// itself without atomicity.
+func (u *Entry) WithTransaction(ctx context.Context, fn func(tx *Entry) error) error {
+	if u.db == nil {
+		return fn(u)
+	}
+	var pending []notify.Event
+	err := u.db.RunInTx(ctx, func(txCtx *database.Context) error {
+		// Events are announced through u once the transaction commits.
+		tx := newEntry(txCtx)
+		tx.inTx = true
+		if err := fn(tx); err != nil {
+			return err
+		}
+		pending = tx.pending
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, ev := range pending {
+		u.emit(ctx, ev)
+	}
+	return nil
+}
+
 // SetOptions contains options for the Set operation.
 type SetOptions struct {
 	Description *string
+	// Provenance is stored with the new version; see CaptureProvenance.
+	Provenance *database.VersionProvenance
+	// IdempotencyKey identifies a logical write across retries. Repeating
+	// a Set with the same key and content returns the version created the
+	// first time instead of adding another one.
+	IdempotencyKey string
+	// BaseVersion, when set, makes Set fail with services.ErrVersionConflict
+	// unless it is still the latest version, instead of writing on top of a
+	// concurrent change. Read-modify-write operations such as Patch use it.
+	BaseVersion *int64
+	// Language is the content language hint, such as "markdown" or "go".
+	// It is detected from the key and content when empty. Content given
+	// as "json" must be a single valid JSON value.
+	Language string
+	// Summarizer, when set, generates a summary of content that is at
+	// least its MinSize. Failing to summarize does not fail the write; the
+	// error is reported in SetResult.SummaryErr.
+	Summarizer *summarizer.Command
+	// IgnoreLock writes even if another owner holds a lock on the key; see
+	// Lock.
+	IgnoreLock bool
+	// Internal admits keys under entrykey.ReservedPrefix, for entries the
+	// vault keeps for itself. It must not be set from user input.
+	Internal bool
+
+	// skipKeyTemplates stops the writes that seed key templates from
+	// seeding them again.
+	skipKeyTemplates bool
+}
+
+// resolveLanguage normalises an explicit language hint, or detects one from
+// the key and content when none is given.
+func resolveLanguage(lang, key, content string) (string, error) {
+	if lang == "" {
+		return language.Detect(key, content), nil
+	}
+	return language.Normalize(lang)
+}
+
+// maxSetAttempts bounds how often Set picks a new version number after
+// losing a race with a concurrent writer.
+const maxSetAttempts = 10
+
+// SetResult describes the version written by Set.
+type SetResult struct {
+	// Key is the key the content was stored under, which differs from the
+	// one given when that was normalized; see entrykey.Normalize.
+	Key string
+	// Path is the object file holding the content.
+	Path string
+	// Version is the version number that was written.
+	Version int64
+	// PreviousVersion is the latest version before this write, or 0 if the
+	// key was new.
+	PreviousVersion int64
+	// ConcurrentWrite reports that another writer claimed the version Set
+	// first chose, so the content was written as a later version.
+	ConcurrentWrite bool
+	// Replayed reports that the idempotency key had already been used and
+	// the result describes that earlier write; PreviousVersion is then
+	// Version-1.
+	Replayed bool
+	// Summary is the summary generated for the new version, if any.
+	Summary string
+	// SummaryErr is why no summary could be generated although
+	// SetOptions.Summarizer asked for one.
+	SummaryErr error
+	// Pruned is how many superseded versions of the scope were deleted to
+	// stay within the quota; see Quota.Prune.
+	Pruned int
+	// PruneErr is why pruning stopped early; the content was stored
+	// regardless, so the scope may be over its quota until the next write.
+	PruneErr error
+	// Templated lists the keys created from the keyTemplates config
+	// because this was the first write to the scope. They are created
+	// after the write succeeds, so a rejected write creates none.
+	Templated []string
+	// TemplateErr is why creating the template keys stopped early; the
+	// content was stored regardless.
+	TemplateErr error
+	// Warnings lists the validators from the config file that the content
+	// failed without being rejected.
+	Warnings []string
 }
 
 // Set stores content in the vault.
-func (u *Entry) Set(ctx context.Context, sc scope.Scope, key, content string, opts *SetOptions) (string, error) {
+//
+// Both the object file and the versions row are claimed exclusively, so two
+// simultaneous writers can never share a version number; the loser retries
+// with the next one and the final number is reported in the result.
+func (u *Entry) Set(ctx context.Context, sc scope.Scope, key, content string, opts *SetOptions) (*SetResult, error) {
+	if err := scope.Validate(sc); err != nil {
+		return nil, err
+	}
 	scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if key, err = u.storedKey(ctx, scopeID, key, opts != nil && opts.Internal); err != nil {
+		return nil, err
 	}
 
-	nextVersion, err := u.entryService.GetNextVersion(ctx, scopeID, key)
+	var (
+		description    *string
+		provenance     *database.VersionProvenance
+		idempotencyKey string
+		baseVersion    *int64
+		lang           string
+		summarize      *summarizer.Command
+		skipTemplates  bool
+		ignoreLock     bool
+	)
+	if opts != nil {
+		description = opts.Description
+		provenance = opts.Provenance
+		idempotencyKey = opts.IdempotencyKey
+		baseVersion = opts.BaseVersion
+		lang = opts.Language
+		summarize = opts.Summarizer
+		skipTemplates = opts.skipKeyTemplates
+		ignoreLock = opts.IgnoreLock
+	}
+	explicitLang := lang != ""
+	lang, err = resolveLanguage(lang, key, content)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if explicitLang && lang == language.JSON {
+		if _, err := jsonquery.Decode(content); err != nil {
+			return nil, fmt.Errorf("%w: %s is not valid JSON: %v", ErrInvalidContent, key, err)
+		}
 	}
 
-	scopeKey := scope.GetScopeStorageKey(sc)
-	path, hash, err := filesystem.SaveFile(scopeKey, key, int(nextVersion), content)
+	if idempotencyKey != "" {
+		result, err := u.replay(ctx, scopeID, key, content, idempotencyKey)
+		if !errors.Is(err, services.ErrNotFound) {
+			return result, err
+		}
+	}
+
+	warnings, err := validateContent(key, content)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	var description *string
-	if opts != nil {
-		description = opts.Description
+	if !ignoreLock {
+		if err := u.checkLock(ctx, scopeID, key, provenance); err != nil {
+			return nil, err
+		}
 	}
 
-	if _, err := u.entryService.Create(ctx, database.ScopedEntryRecord{
-		ScopeID:     scopeID,
-		Key:         key,
-		Version:     nextVersion,
-		FilePath:    path,
-		Hash:        hash,
-		Description: description,
-		IsArchived:  false,
-	}); err != nil {
-		return "", err
+	var templates []keyTemplate
+	if !skipTemplates && baseVersion == nil {
+		if templates, err = u.pendingKeyTemplates(ctx, sc, scopeID, key); err != nil {
+			return nil, err
+		}
 	}
 
-	return path, nil
+	prunable, err := u.checkQuota(ctx, sc, scopeID, key, int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+
+	scopeKey := scope.GetScopeStorageKey(sc)
+	result := &SetResult{Key: key, Warnings: warnings}
+	var version int64
+	for attempt := 1; attempt <= maxSetAttempts; attempt++ {
+		nextVersion, err := u.entryService.GetNextVersion(ctx, scopeID, key)
+		if err != nil {
+			return nil, err
+		}
+		if baseVersion != nil && nextVersion != *baseVersion+1 {
+			return nil, fmt.Errorf("%w: %s changed from version %d to %d", services.ErrVersionConflict, key, *baseVersion, nextVersion-1)
+		}
+		// A file left for the version tried last (by an in-flight writer or a
+		// crashed one) means it is taken even though the index lags behind.
+		version = max(nextVersion, version+1)
+
+		path, hash, err := filesystem.SaveNewFile(scopeKey, key, int(version), content)
+		if errors.Is(err, fs.ErrExist) {
+			if baseVersion != nil {
+				return nil, fmt.Errorf("%w: %s version %d is being written concurrently", services.ErrVersionConflict, key, version)
+			}
+			result.ConcurrentWrite = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = u.entryService.Create(ctx, database.ScopedEntryRecord{
+			ScopeID:        scopeID,
+			Key:            key,
+			Version:        version,
+			FilePath:       path,
+			Hash:           hash,
+			Description:    description,
+			Size:           int64(len(content)),
+			IsArchived:     false,
+			Language:       lang,
+			Provenance:     provenance,
+			IdempotencyKey: idempotencyKey,
+		})
+		if errors.Is(err, services.ErrIdempotencyKeyUsed) {
+			// A concurrent retry of the same write won.
+			_ = filesystem.DeleteFile(path)
+			return u.replay(ctx, scopeID, key, content, idempotencyKey)
+		}
+		if errors.Is(err, services.ErrVersionConflict) && baseVersion == nil {
+			// The file was created exclusively above, so it is ours to remove.
+			_ = filesystem.DeleteFile(path)
+			result.ConcurrentWrite = true
+			continue
+		}
+		if err != nil {
+			_ = filesystem.DeleteFile(path)
+			return nil, err
+		}
+
+		result.Path = path
+		result.Version = version
+		result.PreviousVersion = nextVersion - 1
+		result.Pruned, result.PruneErr = u.prune(ctx, sc, scopeID, prunable)
+		if summarize.Wants(content) {
+			result.Summary, result.SummaryErr = u.storeSummary(ctx, scopeID, key, version, content, summarize)
+		}
+		ev := newEvent(EventSet, sc, key, version)
+		if description != nil {
+			ev.Description = *description
+		}
+		if provenance != nil && provenance.Actor != "" {
+			ev.Actor = provenance.Actor
+		}
+		u.emit(ctx, ev)
+		result.Templated, result.TemplateErr = u.seedKeyTemplates(ctx, sc, templates)
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("failed to store %s: version still taken after %d attempts (last tried %d): %w",
+		key, maxSetAttempts, version, services.ErrVersionConflict)
+}
+
+// replay returns the result of the earlier write made with token, or
+// services.ErrNotFound if there is none. Reusing a token for a different
+// key or content is an error.
+func (u *Entry) replay(ctx context.Context, scopeID int64, key, content, token string) (*SetResult, error) {
+	prior, err := u.entryService.FindByIdempotencyKey(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if prior.ScopeID != scopeID || prior.Key != key || prior.Hash != filesystem.HashContent(content) {
+		return nil, fmt.Errorf("idempotency key %q was already used for a different write", token)
+	}
+	return &SetResult{
+		Key:             prior.Key,
+		Path:            prior.FilePath,
+		Version:         prior.Version,
+		PreviousVersion: prior.Version - 1,
+		Replayed:        true,
+	}, nil
 }
 
 // GetOptions contains options for the Get operation.
 type GetOptions struct {
 	Version *int
+	// SkipVerify disables the content hash check on read.
+	SkipVerify bool
+	// Approved reads the newest approved version instead of the latest one.
+	// It is ignored when Version is set.
+	Approved bool
+	// Snapshot reads the version pinned by the named scope snapshot; see
+	// CreateScopeSnapshot. It is ignored when Version is set.
+	Snapshot string
+	// AsOf, when non-zero, reads the newest version created at or before it.
+	// It is ignored when Version or Snapshot is set.
+	AsOf time.Time
+	// TrackRead records the read of the entry for vault stats and vault
+	// stale; see config.Settings.TrackReads.
+	TrackRead bool
 }
 
 // GetResult contains the result of a Get operation.
@@ -92,23 +403,48 @@ func (u *Entry) Get(ctx context.Context, sc scope.Scope, key string, opts *GetOp
 	if err != nil {
 		return nil, err
 	}
+	if key, err = u.lookupKey(ctx, scopeID, key); err != nil {
+		return nil, err
+	}
 
 	var entry *database.ScopedEntryRecord
-	if opts != nil && opts.Version != nil {
+	switch {
+	case opts != nil && opts.Version != nil:
 		entry, err = u.entryService.GetByVersion(ctx, scopeID, key, int64(*opts.Version))
-	} else {
+	case opts != nil && opts.Snapshot != "":
+		var version int64
+		if version, err = u.pinnedVersion(ctx, scopeID, opts.Snapshot, key); err == nil {
+			entry, err = u.entryService.GetByVersion(ctx, scopeID, key, version)
+			if errors.Is(err, services.ErrNotFound) {
+				err = fmt.Errorf("%w: %s version %d, pinned by scope snapshot %s, was deleted", services.ErrNotFound, key, version, opts.Snapshot)
+			}
+		}
+	case opts != nil && !opts.AsOf.IsZero():
+		var version int64
+		if version, err = u.versionAsOf(ctx, scopeID, key, opts.AsOf); err == nil {
+			entry, err = u.entryService.GetByVersion(ctx, scopeID, key, version)
+		}
+	case opts != nil && opts.Approved:
+		var version int64
+		if version, err = u.latestApprovedVersion(ctx, scopeID, key); err == nil {
+			entry, err = u.entryService.GetByVersion(ctx, scopeID, key, version)
+		}
+	default:
 		entry, err = u.entryService.GetLatest(ctx, scopeID, key)
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	ok, err := filesystem.VerifyFile(entry.FilePath, entry.Hash)
-	if err != nil {
-		return nil, err
+	if opts == nil || !opts.SkipVerify {
+		if err := u.verify(ctx, entry); err != nil {
+			return nil, err
+		}
 	}
-	if !ok {
-		return nil, fmt.Errorf("file integrity check failed for %s", key)
+
+	if opts != nil && opts.TrackRead {
+		// Bookkeeping only: failing to record a read does not fail it.
+		_ = u.entryService.RecordRead(ctx, entry.EntryID)
 	}
 
 	return &GetResult{
@@ -117,11 +453,96 @@ func (u *Entry) Get(ctx context.Context, sc scope.Scope, key string, opts *GetOp
 	}, nil
 }
 
+// verify checks entry content against its stored hash. Hashing is skipped when
+// the file's mtime and size match the stamp recorded at the last successful
+// verification, so repeated reads of large unchanged entries stay cheap.
+func (u *Entry) verify(ctx context.Context, entry *database.ScopedEntryRecord) error {
+	info, err := os.Stat(entry.FilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w for %s", ErrIntegrity, entry.Key)
+		}
+		return err
+	}
+	stamp := database.VersionVerification{
+		MtimeNs: info.ModTime().UnixNano(),
+		Size:    info.Size(),
+	}
+
+	cached, err := u.entryService.GetVerification(ctx, entry.EntryID, entry.Version)
+	if err != nil {
+		return err
+	}
+	if cached != nil && *cached == stamp {
+		return nil
+	}
+
+	ok, err := filesystem.VerifyFile(entry.FilePath, entry.Hash)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w for %s", ErrIntegrity, entry.Key)
+	}
+
+	// Caching is an optimisation only; a read-only database must not turn a
+	// successful verification into a failed read.
+	_ = u.entryService.RecordVerification(ctx, entry.EntryID, entry.Version, stamp)
+	return nil
+}
+
+// InfoResult contains the result of an Info operation.
+type InfoResult struct {
+	GetResult
+	Summary database.EntryVersionSummary
+}
+
+// Info retrieves metadata for one version of an entry together with a summary
+// across all of its versions.
+func (u *Entry) Info(ctx context.Context, sc scope.Scope, key string, opts *GetOptions) (*InfoResult, error) {
+	result, err := u.Get(ctx, sc, key, opts)
+	if err != nil {
+		return nil, err
+	}
+	return u.Describe(ctx, result)
+}
+
+// Describe adds the summary across all versions to a version already read,
+// as Info returns it, without reading the entry again.
+func (u *Entry) Describe(ctx context.Context, result *GetResult) (*InfoResult, error) {
+	summary, err := u.entryService.GetVersionSummary(ctx, result.Record.EntryID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InfoResult{
+		GetResult: *result,
+		Summary:   *summary,
+	}, nil
+}
+
 // ListOptions contains options for the List operation.
 type ListOptions struct {
 	IncludeArchived bool
 	AllVersions     bool
 	AllScopes       bool
+	// Since and Until bound the version creation time; zero values are unbounded.
+	Since               time.Time
+	Until               time.Time
+	DescriptionContains string
+	// Language keeps versions with this language hint.
+	Language string
+	// SortBy orders results in SQL; when listing all scopes an explicit SortBy
+	// also orders across scopes instead of grouping by scope.
+	SortBy  services.SortField
+	Reverse bool
+	// AsOf, when non-zero, lists the newest version of each key created at
+	// or before it instead of the latest one. With AllVersions it only
+	// bounds the version creation time like Until.
+	AsOf time.Time
+	// IncludeInternal also lists the vault's own entries, whose keys start
+	// with entrykey.ReservedPrefix.
+	IncludeInternal bool
 }
 
 // ListResult contains the result of a List operation.
@@ -141,55 +562,146 @@ type ListEntry struct {
 func (u *Entry) List(ctx context.Context, sc scope.Scope, opts *ListOptions) (*ListResult, error) {
 	var allEntries []ListEntry
 
+	each := u.eachEntry
+	if opts != nil && opts.asOfLatest() {
+		each = u.eachEntryAsOf
+	}
+	err := each(ctx, sc, opts, func(entry ListEntry) error {
+		allEntries = append(allEntries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if opts != nil && opts.AllScopes && opts.SortBy != "" {
+		sortListEntries(allEntries, opts.SortBy, opts.Reverse)
+	}
+
+	return &ListResult{Entries: allEntries}, nil
+}
+
+// ListEach calls fn for every entry List would return, streaming rows from the
+// database instead of collecting them first. Sorting across all scopes needs
+// the complete result set, so that case falls back to List, as does AsOf.
+func (u *Entry) ListEach(ctx context.Context, sc scope.Scope, opts *ListOptions, fn func(ListEntry) error) error {
+	if opts != nil && (opts.AllScopes && opts.SortBy != "" || opts.asOfLatest()) {
+		result, err := u.List(ctx, sc, opts)
+		if err != nil {
+			return err
+		}
+		for _, entry := range result.Entries {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return u.eachEntry(ctx, sc, opts, fn)
+}
+
+func (u *Entry) eachEntry(ctx context.Context, sc scope.Scope, opts *ListOptions, fn func(ListEntry) error) error {
 	includeArchived := opts != nil && opts.IncludeArchived
 	allVersions := opts != nil && opts.AllVersions
 	allScopes := opts != nil && opts.AllScopes
 
+	if opts == nil || !opts.IncludeInternal {
+		next := fn
+		fn = func(entry ListEntry) error {
+			if entrykey.IsReserved(entry.Record.Key) {
+				return nil
+			}
+			return next(entry)
+		}
+	}
+
+	var filter services.ListFilter
+	if opts != nil {
+		until := opts.Until
+		if !opts.AsOf.IsZero() && (until.IsZero() || opts.AsOf.Before(until)) {
+			until = opts.AsOf
+		}
+		filter = services.ListFilter{
+			Since:               opts.Since,
+			Until:               until,
+			DescriptionContains: opts.DescriptionContains,
+			Language:            opts.Language,
+			SortBy:              opts.SortBy,
+			Reverse:             opts.Reverse,
+		}
+	}
+
 	if allScopes {
 		// Get all scopes from database
 		scopes, err := u.scopeService.GetAll(ctx)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		for _, scopeRecord := range scopes {
-			entries, err := u.entryService.List(ctx, scopeRecord.ID, includeArchived, allVersions)
-			if err != nil {
-				return nil, err
-			}
-
-			for _, entry := range entries {
-				allEntries = append(allEntries, ListEntry{
+			err := u.entryService.EachWithFilter(ctx, scopeRecord.ID, includeArchived, allVersions, filter, func(entry database.ScopedEntryRecord) error {
+				return fn(ListEntry{
 					Record:     entry,
 					Scope:      scopeRecord.Scope,
 					ScopeType:  scopeRecord.Scope.Type,
 					ScopeShort: scope.FormatScopeShort(scopeRecord.Scope),
 				})
+			})
+			if err != nil {
+				return err
 			}
 		}
-	} else {
-		// List from single scope
-		scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
-		if err != nil {
-			return nil, err
-		}
+		return nil
+	}
 
-		entries, err := u.entryService.List(ctx, scopeID, includeArchived, allVersions)
-		if err != nil {
-			return nil, err
-		}
+	// List from single scope
+	scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
+	if err != nil {
+		return err
+	}
 
-		for _, entry := range entries {
-			allEntries = append(allEntries, ListEntry{
-				Record:     entry,
-				Scope:      sc,
-				ScopeType:  sc.Type,
-				ScopeShort: scope.FormatScopeShort(sc),
-			})
+	return u.entryService.EachWithFilter(ctx, scopeID, includeArchived, allVersions, filter, func(entry database.ScopedEntryRecord) error {
+		return fn(ListEntry{
+			Record:     entry,
+			Scope:      sc,
+			ScopeType:  sc.Type,
+			ScopeShort: scope.FormatScopeShort(sc),
+		})
+	})
+}
+
+// sortListEntries orders entries gathered from several scopes using the same
+// rules the list queries apply within a single scope.
+func sortListEntries(entries []ListEntry, by services.SortField, reverse bool) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].Record, entries[j].Record
+
+		var order int
+		switch by {
+		case services.SortByCreated:
+			order = a.CreatedAt.Compare(b.CreatedAt)
+		case services.SortByUpdated:
+			order = a.UpdatedAt.Compare(b.UpdatedAt)
+		case services.SortByVersion:
+			order = cmp.Compare(a.Version, b.Version)
+		case services.SortBySize:
+			order = cmp.Compare(a.Size, b.Size)
+		default:
+			order = strings.Compare(a.Key, b.Key)
+		}
+		if reverse {
+			order = -order
+		}
+		if order != 0 {
+			return order < 0
 		}
-	}
 
-	return &ListResult{Entries: allEntries}, nil
+		if keyCmp := strings.Compare(a.Key, b.Key); keyCmp != 0 {
+			return keyCmp < 0
+		}
+		return a.Version > b.Version
+	})
 }
 
 // DeleteVersion deletes a specific version of an entry.
@@ -199,10 +711,16 @@ func (u *Entry) DeleteVersion(ctx context.Context, sc scope.Scope, key string, v
 		return false, err
 	}
 
+	// Files an earlier delete failed to remove get another chance first.
+	u.retryPendingDeletions(ctx)
+
 	scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
 	if err != nil {
 		return false, err
 	}
+	if key, err = u.lookupKey(ctx, scopeID, key); err != nil {
+		return false, err
+	}
 
 	// Get the entry before deleting to get the file path
 	entry, err := u.entryService.GetByVersion(ctx, scopeID, key, int64(version))
@@ -221,9 +739,10 @@ func (u *Entry) DeleteVersion(ctx context.Context, sc scope.Scope, key string, v
 
 	// Delete file from filesystem
 	if deleted {
-		if err := filesystem.DeleteFile(entry.FilePath); err != nil {
-			// Log error but don't fail - DB is already updated
-			return true, fmt.Errorf("deleted from database but failed to delete file %s: %w", entry.FilePath, err)
+		u.emit(ctx, newEvent(EventDelete, sc, key, int64(version)))
+		if err := u.removeObjects(ctx, []string{entry.FilePath}); err != nil {
+			// DB is already updated; the file stays queued for retry
+			return true, fmt.Errorf("deleted from database but failed to delete file %s (queued for retry; run vault db gc): %w", entry.FilePath, err)
 		}
 	}
 
@@ -237,10 +756,15 @@ func (u *Entry) DeleteKey(ctx context.Context, sc scope.Scope, key string) (int,
 		return 0, err
 	}
 
+	u.retryPendingDeletions(ctx)
+
 	scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
 	if err != nil {
 		return 0, err
 	}
+	if key, err = u.lookupKey(ctx, scopeID, key); err != nil {
+		return 0, err
+	}
 
 	// Get all versions before deleting to get file paths
 	entries, err := u.entryService.List(ctx, scopeID, true, true)
@@ -269,14 +793,13 @@ func (u *Entry) DeleteKey(ctx context.Context, sc scope.Scope, key string) (int,
 	if !deleted {
 		return 0, nil
 	}
+	u.emit(ctx, newEvent(EventDelete, sc, key, 0))
 
 	// Delete all files from filesystem
 	deletedCount := len(filePaths)
-	for _, filePath := range filePaths {
-		if err := filesystem.DeleteFile(filePath); err != nil {
-			// Log error but continue with other files
-			return deletedCount, fmt.Errorf("deleted from database but failed to delete some files: %w", err)
-		}
+	if err := u.removeObjects(ctx, filePaths); err != nil {
+		// DB is already updated; the files stay queued for retry
+		return deletedCount, fmt.Errorf("deleted from database but failed to delete some files (queued for retry; run vault db gc): %w", err)
 	}
 
 	return deletedCount, nil
@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/notify"
+	"github.com/choplin/vault.md/internal/scope"
+)
+
+// Event types passed to the configured notifiers.
+const (
+	EventSet     = "set"
+	EventDelete  = "delete"
+	EventArchive = "archive"
+	EventRestore = "restore"
+)
+
+// newEvent describes a change to key in sc.
+func newEvent(eventType string, sc scope.Scope, key string, version int64) notify.Event {
+	return notify.Event{
+		Type:      eventType,
+		Scope:     scope.FormatScope(sc),
+		ScopeType: string(sc.Type),
+		Key:       key,
+		Version:   version,
+		Actor:     ResolveActor(""),
+		Time:      time.Now().UTC(),
+	}
+}
+
+// notifyQueueSize is how many notifications may wait to be sent before
+// further ones are dropped.
+const notifyQueueSize = 256
+
+// notifications sends the events of every Entry in the process in the
+// background; FlushNotifications drains it before exit.
+var notifications = notify.NewQueue(notifyQueueSize)
+
+// loadNotifiers returns the notifiers in the config file, or none when
+// notifications are disabled (see config.NotificationsEnabled). Notifications
+// are best effort: a config that cannot be loaded sends none rather than
+// failing the use case.
+func loadNotifiers() []*notify.Webhook {
+	if !config.NotificationsEnabled() {
+		return nil
+	}
+	settings, err := config.Load()
+	if err != nil {
+		return nil
+	}
+	return notify.FromSettings(settings)
+}
+
+// emit publishes ev once the change it describes is committed: right away
+// outside a transaction, and when WithTransaction commits inside one, so
+// rolled-back changes are never announced.
+func (u *Entry) emit(_ context.Context, ev notify.Event) {
+	if u.inTx {
+		u.pending = append(u.pending, ev)
+		return
+	}
+	if len(u.notifiers) == 0 {
+		return
+	}
+	// A full queue drops the event; the change itself is stored regardless.
+	_ = notifications.Publish(u.notifiers, ev)
+}
+
+// FlushNotifications waits until the notifications queued by this process
+// are sent, or until timeout passes, and stops accepting new ones. Call it
+// once before the process exits.
+func FlushNotifications(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return notifications.Close(ctx)
+}
@@ -0,0 +1,145 @@
+package usecase
+
+import "fmt"
+
+// Snapshot manifests and key exports share one compatibility policy:
+//
+//   - Every release reads all format versions up to its own.
+//   - Each document records in readerVersion the oldest format version whose
+//     readers can import it by ignoring the fields they do not know. A
+//     reader accepts a document newer than itself when readerVersion allows
+//     it, so new optional fields do not lock out releases that predate them.
+//   - A document without readerVersion needs a reader of its formatVersion.
+//     Releases that predate readerVersion also apply this rule.
+//   - Writers can convert a document down to an older format version, leaving
+//     out what that version cannot hold, so maintained older releases can
+//     import it; see SnapshotManifest.ConvertTo and KeyExport.ConvertTo.
+
+// documentFormat describes the versions of one document format this build
+// handles.
+type documentFormat struct {
+	name string
+	// current is the version written by default.
+	current int
+	// readerVersion is the oldest version whose readers can import
+	// documents written in the current version.
+	readerVersion int
+}
+
+var (
+	snapshotFormat  = documentFormat{name: "snapshot manifest", current: SnapshotFormatVersion, readerVersion: 1}
+	keyExportFormat = documentFormat{name: "key export", current: KeyExportFormatVersion, readerVersion: 1}
+)
+
+// check reports whether a document with the given versions can be read.
+func (f documentFormat) check(version, readerVersion int) error {
+	switch {
+	case version < 1:
+		return fmt.Errorf("unsupported %s version: %d", f.name, version)
+	case version <= f.current:
+		return nil
+	case readerVersion >= 1 && readerVersion <= f.current:
+		return nil
+	case readerVersion > f.current:
+		return fmt.Errorf("%s version %d needs a reader of version %d, newer than this vault supports (%d); upgrade vault to read it", f.name, version, readerVersion, f.current)
+	default:
+		return fmt.Errorf("%s version %d is newer than this vault supports (%d); upgrade vault to read it", f.name, version, f.current)
+	}
+}
+
+// target resolves the version to write: version itself, or the current
+// version when it is 0.
+func (f documentFormat) target(version int) (int, error) {
+	if version == 0 {
+		return f.current, nil
+	}
+	if version < 1 || version > f.current {
+		return 0, fmt.Errorf("invalid %s format version: %d (valid values: 1 to %d)", f.name, version, f.current)
+	}
+	return version, nil
+}
+
+// readerFor returns the readerVersion to record for a document written in
+// version.
+func (f documentFormat) readerFor(version int) int {
+	if version < f.current {
+		// Older versions predate readerVersion.
+		return 0
+	}
+	return f.readerVersion
+}
+
+// ValidateSnapshotFormatVersion checks a snapshot manifest format version
+// requested by the user; 0 selects the current version.
+func ValidateSnapshotFormatVersion(version int) error {
+	_, err := snapshotFormat.target(version)
+	return err
+}
+
+// ValidateKeyExportFormatVersion checks a key export format version
+// requested by the user; 0 selects the current version.
+func ValidateKeyExportFormatVersion(version int) error {
+	_, err := keyExportFormat.target(version)
+	return err
+}
+
+// ConvertTo returns m in format version, or in the current version when
+// version is 0. Converting down to version 1 leaves out the version
+// metadata, parent links, and scope information; converting up only
+// relabels the manifest, since later versions add fields rather than change
+// them. m is not modified.
+func (m *SnapshotManifest) ConvertTo(version int) (*SnapshotManifest, error) {
+	version, err := snapshotFormat.target(version)
+	if err != nil {
+		return nil, err
+	}
+	converted := *m
+	converted.FormatVersion = version
+	converted.ReaderVersion = snapshotFormat.readerFor(version)
+	if version >= 2 {
+		return &converted, nil
+	}
+
+	converted.Scopes = nil
+	converted.Entries = make([]SnapshotEntry, len(m.Entries))
+	for i, e := range m.Entries {
+		e.Parent = nil
+		versions := make([]SnapshotVersion, len(e.Versions))
+		for j, v := range e.Versions {
+			v.VersionMetadata = VersionMetadata{}
+			versions[j] = v
+		}
+		e.Versions = versions
+		converted.Entries[i] = e
+	}
+	return &converted, nil
+}
+
+// ConvertTo returns e in format version, or in the current version when
+// version is 0. Converting down to version 1 leaves out the version
+// metadata. e is not modified.
+func (e *KeyExport) ConvertTo(version int) (*KeyExport, error) {
+	version, err := keyExportFormat.target(version)
+	if err != nil {
+		return nil, err
+	}
+	converted := *e
+	converted.FormatVersion = version
+	converted.ReaderVersion = keyExportFormat.readerFor(version)
+	if version >= 2 {
+		return &converted, nil
+	}
+
+	converted.Versions = make([]KeyExportVersion, len(e.Versions))
+	for i, v := range e.Versions {
+		v.VersionMetadata = VersionMetadata{}
+		converted.Versions[i] = v
+	}
+	return &converted, nil
+}
+
+// IsNewer reports whether e was written in a format version newer than this
+// vault's, so fields this vault does not know were ignored.
+func (e *KeyExport) IsNewer() bool {
+	return e.FormatVersion > KeyExportFormatVersion
+}
@@ -0,0 +1,220 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/choplin/vault.md/internal/git"
+	"github.com/choplin/vault.md/internal/snapshot"
+)
+
+const gitSyncManifestPath = "manifest.json"
+
+// GitSyncOptions selects the repository and branch used by PushToGit and
+// RestoreFromGit.
+type GitSyncOptions struct {
+	RepoDir string
+	Branch  string
+	// Remote, when set, is fetched before and pushed after a snapshot.
+	Remote string
+	// Cipher, when set, encrypts the manifest and every object on the
+	// branch; see snapshot.Cipher.
+	Cipher *snapshot.Cipher
+	// FormatVersion selects the manifest format PushToGit writes, 0 for
+	// the current one; see SnapshotManifest.ConvertTo.
+	FormatVersion int
+}
+
+// GitSyncResult describes the outcome of PushToGit.
+type GitSyncResult struct {
+	Commit   string
+	Entries  int
+	Versions int
+	// Changed is false when the branch already held the same snapshot.
+	Changed bool
+}
+
+// GitRestoreResult describes the outcome of RestoreFromGit.
+type GitRestoreResult struct {
+	RestoreResult
+	Commit string
+}
+
+// gitSyncObjectPath returns where the object with hash is kept on the
+// branch: under its hash, or its snapshot.Cipher.ObjectID when encrypted.
+func gitSyncObjectPath(hash string, c *snapshot.Cipher) string {
+	if c != nil {
+		hash = c.ObjectID(hash)
+	}
+	return "objects/" + hash[:2] + "/" + hash
+}
+
+// PushToGit commits a snapshot of the whole vault to a dedicated branch: a
+// manifest plus one file per distinct content hash. Each snapshot is a new
+// commit, so the branch history doubles as a backup log. If the remote
+// branch holds versions that are missing locally, PushToGit refuses to drop
+// them and asks for a restore first. A device revoked locally or on the
+// branch cannot push; see RevokeDevice.
+func (u *Entry) PushToGit(ctx context.Context, opts GitSyncOptions) (*GitSyncResult, error) {
+	if err := ValidateSnapshotFormatVersion(opts.FormatVersion); err != nil {
+		return nil, err
+	}
+	ref := "refs/heads/" + opts.Branch
+	parent := git.ResolveRef(ctx, opts.RepoDir, ref)
+	if opts.Remote != "" {
+		remoteHead, err := git.FetchBranch(ctx, opts.RepoDir, opts.Remote, opts.Branch)
+		if err != nil {
+			return nil, err
+		}
+		if remoteHead != "" {
+			parent = remoteHead
+		}
+	}
+
+	var previous *SnapshotManifest
+	var revoked []string
+	if parent != "" {
+		var err error
+		if previous, err = readGitSyncManifest(ctx, opts.RepoDir, parent, opts.Cipher); err != nil {
+			return nil, err
+		}
+		revoked = previous.RevokedDevices
+	}
+	if err := u.adoptRevocations(ctx, revoked); err != nil {
+		return nil, err
+	}
+
+	manifest, objects, err := u.Snapshot(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if previous != nil {
+		if missing := manifest.Missing(previous); missing > 0 {
+			return nil, fmt.Errorf("branch %s has %d version(s) missing from this vault; restore them first", opts.Branch, missing)
+		}
+		if previous.Missing(manifest) == 0 {
+			// Same versions as the branch: keep the old timestamp so the
+			// tree, and therefore the commit, stays unchanged.
+			manifest.CreatedAt = previous.CreatedAt
+		}
+	}
+
+	if opts.Cipher != nil {
+		sealedDir, err := os.MkdirTemp("", "vault-sync-git-*")
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = os.RemoveAll(sealedDir)
+		}()
+		if objects, err = sealSnapshotObjects(objects, opts.Cipher, sealedDir); err != nil {
+			return nil, err
+		}
+	}
+
+	files := make([]git.SnapshotFile, 0, len(objects)+1)
+	for _, obj := range objects {
+		files = append(files, git.SnapshotFile{Path: gitSyncObjectPath(obj.Hash, opts.Cipher), Source: obj.Path})
+	}
+	written, err := manifest.ConvertTo(opts.FormatVersion)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(written, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, '\n')
+	if opts.Cipher != nil {
+		data = opts.Cipher.Seal(snapshot.ManifestName(gitSyncManifestPath), data)
+	}
+	files = append(files, git.SnapshotFile{Path: gitSyncManifestPath, Data: data})
+
+	result := &GitSyncResult{
+		Entries:  len(manifest.Entries),
+		Versions: manifest.Versions(),
+	}
+	message := fmt.Sprintf("vault snapshot: %d keys, %d versions", result.Entries, result.Versions)
+	result.Commit, result.Changed, err = git.CommitSnapshot(ctx, opts.RepoDir, ref, parent, files, message)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Remote != "" && result.Changed {
+		if err := git.PushBranch(ctx, opts.RepoDir, opts.Remote, opts.Branch); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// RestoreFromGit imports the versions recorded on the sync branch that are
+// missing from the vault; see RestoreSnapshot.
+func (u *Entry) RestoreFromGit(ctx context.Context, opts GitSyncOptions) (*GitRestoreResult, error) {
+	rev := git.ResolveRef(ctx, opts.RepoDir, "refs/heads/"+opts.Branch)
+	if opts.Remote != "" {
+		remoteHead, err := git.FetchBranch(ctx, opts.RepoDir, opts.Remote, opts.Branch)
+		if err != nil {
+			return nil, err
+		}
+		if remoteHead != "" {
+			rev = remoteHead
+		}
+	}
+	if rev == "" {
+		return nil, fmt.Errorf("branch %s not found", opts.Branch)
+	}
+
+	manifest, err := readGitSyncManifest(ctx, opts.RepoDir, rev, opts.Cipher)
+	if err != nil {
+		return nil, err
+	}
+
+	restored, err := u.RestoreSnapshot(ctx, manifest, func(hash string) ([]byte, error) {
+		data, err := git.ReadBlob(ctx, opts.RepoDir, rev, gitSyncObjectPath(hash, opts.Cipher))
+		if err != nil || opts.Cipher == nil {
+			return data, err
+		}
+		return opts.Cipher.Open(snapshot.ObjectName(hash), data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &GitRestoreResult{RestoreResult: *restored, Commit: rev}, nil
+}
+
+func readGitSyncManifest(ctx context.Context, repoDir, rev string, c *snapshot.Cipher) (*SnapshotManifest, error) {
+	data, err := git.ReadBlob(ctx, repoDir, rev, gitSyncManifestPath)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		if data, err = c.Open(snapshot.ManifestName(gitSyncManifestPath), data); err != nil {
+			return nil, err
+		}
+	}
+	return ParseSnapshotManifest(data)
+}
+
+// sealSnapshotObjects writes an encrypted copy of each object to dir and
+// returns the objects pointing at the copies.
+func sealSnapshotObjects(objects []SnapshotObject, c *snapshot.Cipher, dir string) ([]SnapshotObject, error) {
+	sealed := make([]SnapshotObject, 0, len(objects))
+	for _, obj := range objects {
+		//nolint:gosec // G304: path is an object file recorded in the vault database
+		data, err := os.ReadFile(obj.Path)
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(dir, obj.Hash)
+		if err := os.WriteFile(path, c.Seal(snapshot.ObjectName(obj.Hash), data), 0o600); err != nil {
+			return nil, err
+		}
+		sealed = append(sealed, SnapshotObject{Hash: obj.Hash, Path: path})
+	}
+	return sealed, nil
+}
@@ -0,0 +1,225 @@
+package usecase
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/scope"
+)
+
+// DefaultImportBatchSize is the number of records ImportStream commits per
+// transaction when no batch size is given.
+const DefaultImportBatchSize = 500
+
+// ImportRecord is one line of an NDJSON import stream. Each record becomes
+// the next version of its key.
+type ImportRecord struct {
+	Key         string  `json:"key"`
+	Content     string  `json:"content"`
+	Description *string `json:"description,omitempty"`
+	// Language is the content language hint; detected when empty.
+	Language string `json:"language,omitempty"`
+	// Scope overrides the stream's default scope for this record.
+	Scope *SnapshotScope `json:"scope,omitempty"`
+}
+
+// StreamImportOptions contains options for the ImportStream operation.
+type StreamImportOptions struct {
+	// Scope is used for records that do not name their own scope.
+	Scope scope.Scope
+	// BatchSize is the number of records committed per transaction;
+	// DefaultImportBatchSize when zero.
+	BatchSize int
+	// Provenance is stored with every imported version.
+	Provenance *database.VersionProvenance
+	// Progress, when set, is called after each committed batch with the
+	// number of versions imported so far.
+	Progress func(imported int)
+}
+
+// StreamImportResult describes the outcome of ImportStream.
+type StreamImportResult struct {
+	Imported int
+	Batches  int
+}
+
+// ImportStream reads newline-delimited ImportRecords from r and stores each
+// one as a new version. Records are committed in batches, one transaction
+// per batch, and r is only read as fast as batches are committed, so a
+// producer piping a large corpus is held back instead of buffered in memory.
+//
+// When a record is malformed or a batch fails, ImportStream stops and returns
+// the result so far together with the error; every batch before the failing
+// one stays committed.
+func (u *Entry) ImportStream(ctx context.Context, r io.Reader, opts *StreamImportOptions) (_ *StreamImportResult, err error) {
+	if opts == nil {
+		opts = &StreamImportOptions{}
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultImportBatchSize
+	}
+
+	imp := &streamImporter{
+		entry:        u,
+		provenance:   opts.Provenance,
+		scopeIDs:     map[SnapshotScope]int64{},
+		nextVersions: map[versionKey]int64{},
+	}
+	defer func() {
+		if err != nil {
+			imp.discard()
+		}
+	}()
+	defaultScope := newSnapshotScope(opts.Scope)
+	result := &StreamImportResult{}
+
+	flush := func() error {
+		if len(imp.batch) == 0 {
+			return nil
+		}
+		if err := imp.entry.entryService.CreateBatch(ctx, imp.batch); err != nil {
+			return err
+		}
+		result.Imported += len(imp.batch)
+		result.Batches++
+		imp.batch = imp.batch[:0]
+		if opts.Progress != nil {
+			opts.Progress(result.Imported)
+		}
+		return nil
+	}
+
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return result, readErr
+		}
+
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			var record ImportRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				return result, fmt.Errorf("line %d: %w", line, err)
+			}
+			if record.Key == "" {
+				return result, fmt.Errorf("line %d: record has no key", line)
+			}
+			lang, err := resolveLanguage(record.Language, record.Key, record.Content)
+			if err != nil {
+				return result, fmt.Errorf("line %d: %w", line, err)
+			}
+			record.Language = lang
+			sc := defaultScope
+			if record.Scope != nil {
+				sc = *record.Scope
+			}
+			if err := imp.add(ctx, sc, record); err != nil {
+				return result, fmt.Errorf("line %d: %w", line, err)
+			}
+			if len(imp.batch) >= batchSize {
+				if err := flush(); err != nil {
+					return result, err
+				}
+			}
+		}
+
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+	}
+
+	if err := flush(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+type versionKey struct {
+	scopeID int64
+	key     string
+}
+
+// streamImporter holds the state ImportStream carries across batches.
+type streamImporter struct {
+	entry        *Entry
+	provenance   *database.VersionProvenance
+	scopeIDs     map[SnapshotScope]int64
+	nextVersions map[versionKey]int64
+	batch        []database.ScopedEntryRecord
+}
+
+// add writes the record's object file and queues its version for the next
+// commit.
+func (imp *streamImporter) add(ctx context.Context, ss SnapshotScope, record ImportRecord) error {
+	sc := ss.scope()
+	scopeID, ok := imp.scopeIDs[ss]
+	if !ok {
+		if err := scope.Validate(sc); err != nil {
+			return err
+		}
+		var err error
+		if scopeID, err = imp.entry.scopeService.GetOrCreate(ctx, sc); err != nil {
+			return err
+		}
+		imp.scopeIDs[ss] = scopeID
+	}
+
+	key, err := imp.entry.storedKey(ctx, scopeID, record.Key, false)
+	if err != nil {
+		return err
+	}
+	record.Key = key
+
+	vk := versionKey{scopeID: scopeID, key: record.Key}
+	version, ok := imp.nextVersions[vk]
+	if !ok {
+		if version, err = imp.entry.entryService.GetNextVersion(ctx, scopeID, record.Key); err != nil {
+			return err
+		}
+	}
+
+	scopeKey := scope.GetScopeStorageKey(sc)
+	for attempt := 1; ; attempt++ {
+		path, hash, err := filesystem.SaveNewFile(scopeKey, record.Key, int(version), record.Content)
+		if errors.Is(err, fs.ErrExist) && attempt < maxSetAttempts {
+			// Left by a concurrent or crashed writer; see Set.
+			version++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		imp.batch = append(imp.batch, database.ScopedEntryRecord{
+			ScopeID:     scopeID,
+			Key:         record.Key,
+			Version:     version,
+			FilePath:    path,
+			Hash:        hash,
+			Description: record.Description,
+			Size:        int64(len(record.Content)),
+			Language:    record.Language,
+			Provenance:  imp.provenance,
+		})
+		imp.nextVersions[vk] = version + 1
+		return nil
+	}
+}
+
+// discard removes the object files of versions that were queued but not
+// committed.
+func (imp *streamImporter) discard() {
+	for _, record := range imp.batch {
+		_ = filesystem.DeleteFile(record.FilePath)
+	}
+	imp.batch = nil
+}
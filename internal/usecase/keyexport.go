@@ -0,0 +1,231 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/entrykey"
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/language"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// KeyExportFormat identifies documents produced by ExportKey.
+const KeyExportFormat = "vault.md/key-export"
+
+// KeyExportFormatVersion is the current version of the key export document.
+// Version 2 added the metadata of versions; version 1 documents are still
+// read. See format.go for the compatibility policy.
+const KeyExportFormatVersion = 2
+
+// KeyExport is a self-contained document holding every version of one key.
+type KeyExport struct {
+	Format        string `json:"format"`
+	FormatVersion int    `json:"formatVersion"`
+	// ReaderVersion is the oldest format version whose readers can import
+	// the document by ignoring fields they do not know.
+	ReaderVersion int                `json:"readerVersion,omitempty"`
+	ExportedAt    time.Time          `json:"exportedAt"`
+	Scope         string             `json:"scope"`
+	Key           string             `json:"key"`
+	IsArchived    bool               `json:"isArchived"`
+	Versions      []KeyExportVersion `json:"versions"`
+}
+
+// KeyExportVersion is a single version inside a KeyExport, oldest first.
+type KeyExportVersion struct {
+	Version     int64     `json:"version"`
+	Hash        string    `json:"hash"`
+	Description *string   `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Content     string    `json:"content"`
+	VersionMetadata
+}
+
+// ExportKey collects content and metadata for every version of key.
+func (u *Entry) ExportKey(ctx context.Context, sc scope.Scope, key string) (*KeyExport, error) {
+	if err := scope.Validate(sc); err != nil {
+		return nil, err
+	}
+
+	scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	if key, err = u.lookupKey(ctx, scopeID, key); err != nil {
+		return nil, err
+	}
+
+	latest, err := u.entryService.GetLatest(ctx, scopeID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := u.entryService.ListVersions(ctx, scopeID, key)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	history, err := u.versionHistory(ctx, scopeID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &KeyExport{
+		Format:        KeyExportFormat,
+		FormatVersion: KeyExportFormatVersion,
+		ReaderVersion: keyExportFormat.readerVersion,
+		ExportedAt:    time.Now().UTC(),
+		Scope:         scope.FormatScope(sc),
+		Key:           key,
+		IsArchived:    latest.IsArchived,
+		Versions:      make([]KeyExportVersion, 0, len(versions)),
+	}
+
+	for _, v := range versions {
+		content, err := filesystem.ReadFile(v.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read version %d: %w", v.Version, err)
+		}
+		if filesystem.HashContent(content) != v.Hash {
+			return nil, fmt.Errorf("%w for %s version %d", ErrIntegrity, key, v.Version)
+		}
+		export.Versions = append(export.Versions, KeyExportVersion{
+			Version:         v.Version,
+			Hash:            v.Hash,
+			Description:     v.Description,
+			CreatedAt:       v.CreatedAt,
+			Content:         content,
+			VersionMetadata: newVersionMetadata(history[v.Version]),
+		})
+	}
+
+	return export, nil
+}
+
+// ImportKeyOptions contains options for the ImportKey operation.
+type ImportKeyOptions struct {
+	// Key imports the history under a different key than the exported one.
+	Key string
+}
+
+// ImportKey recreates an exported key's history in sc, keeping version
+// numbers, descriptions, and write times. The target key must not exist.
+// The history is imported in one transaction, so a failure leaves no
+// partial key behind. It returns the key the history was imported under.
+func (u *Entry) ImportKey(ctx context.Context, sc scope.Scope, export *KeyExport, opts *ImportKeyOptions) (string, error) {
+	if err := scope.Validate(sc); err != nil {
+		return "", err
+	}
+	if err := export.validate(); err != nil {
+		return "", err
+	}
+
+	key := export.Key
+	if opts != nil && opts.Key != "" {
+		key = opts.Key
+	}
+	key, err := entrykey.Clean(key)
+	if err != nil {
+		return "", err
+	}
+
+	versions := append([]KeyExportVersion(nil), export.Versions...)
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+
+	var written []string
+	err = u.WithTransaction(ctx, func(tx *Entry) error {
+		return tx.importVersions(ctx, sc, key, export.IsArchived, versions, &written)
+	})
+	if err != nil {
+		for _, path := range written {
+			_ = filesystem.DeleteFile(path)
+		}
+		return "", err
+	}
+	return key, nil
+}
+
+// importVersions stores versions of a key that must not exist yet,
+// appending each object file it writes to written.
+func (u *Entry) importVersions(ctx context.Context, sc scope.Scope, key string, archived bool, versions []KeyExportVersion, written *[]string) error {
+	scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
+	if err != nil {
+		return err
+	}
+
+	if _, err := u.entryService.GetEntryByKey(ctx, scopeID, key); err == nil {
+		return fmt.Errorf("key already exists: %s", key)
+	} else if !errors.Is(err, services.ErrNotFound) {
+		return err
+	}
+
+	scopeKey := scope.GetScopeStorageKey(sc)
+	for _, v := range versions {
+		path, hash, err := filesystem.SaveFile(scopeKey, key, int(v.Version), v.Content)
+		if err != nil {
+			return err
+		}
+		*written = append(*written, path)
+		if hash != v.Hash {
+			return fmt.Errorf("hash mismatch for version %d: export may be corrupted", v.Version)
+		}
+
+		lang := v.Language
+		if lang == "" {
+			lang = language.Detect(key, v.Content)
+		}
+		if _, err := u.entryService.Create(ctx, database.ScopedEntryRecord{
+			ScopeID:     scopeID,
+			Key:         key,
+			Version:     v.Version,
+			FilePath:    path,
+			Hash:        hash,
+			Description: v.Description,
+			UpdatedAt:   v.CreatedAt,
+			Size:        int64(len(v.Content)),
+			IsArchived:  archived,
+			Language:    lang,
+			Provenance:  v.provenance(""),
+		}); err != nil {
+			return err
+		}
+		if err := u.applyVersionMetadata(ctx, scopeID, key, v.Version, v.VersionMetadata, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *KeyExport) validate() error {
+	if e.Format != KeyExportFormat {
+		return fmt.Errorf("not a key export: unexpected format %q", e.Format)
+	}
+	if err := keyExportFormat.check(e.FormatVersion, e.ReaderVersion); err != nil {
+		return err
+	}
+	if e.Key == "" {
+		return fmt.Errorf("key export is missing the key")
+	}
+	if len(e.Versions) == 0 {
+		return fmt.Errorf("key export contains no versions")
+	}
+
+	seen := make(map[int64]struct{}, len(e.Versions))
+	for _, v := range e.Versions {
+		if v.Version < 1 {
+			return fmt.Errorf("invalid version number in key export: %d", v.Version)
+		}
+		if _, ok := seen[v.Version]; ok {
+			return fmt.Errorf("duplicate version in key export: %d", v.Version)
+		}
+		seen[v.Version] = struct{}{}
+	}
+	return nil
+}
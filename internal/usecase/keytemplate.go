@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/scope"
+)
+
+// defaultTemplateDescription is stored with keys created from a template
+// that has no description of its own.
+const defaultTemplateDescription = "Created from key template"
+
+// keyTemplate is an entry to create from the keyTemplates config.
+type keyTemplate struct {
+	key         string
+	content     string
+	description string
+}
+
+// pendingKeyTemplates returns the configured template keys for sc's type
+// when the scope holds no entries yet, skipping key, which the caller is
+// about to write. Their content is loaded here, so a template that cannot
+// be read fails the write before anything is stored; seedKeyTemplates
+// creates them once the write has succeeded.
+func (u *Entry) pendingKeyTemplates(ctx context.Context, sc scope.Scope, scopeID int64, key string) ([]keyTemplate, error) {
+	settings, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	templates := settings.KeyTemplatesFor(string(sc.Type))
+	if len(templates) == 0 {
+		return nil, nil
+	}
+
+	existing, err := u.entryService.List(ctx, scopeID, true, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(templates))
+	for k := range templates {
+		if k != key {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	pending := make([]keyTemplate, 0, len(keys))
+	for _, k := range keys {
+		t := templates[k]
+		content, err := t.Load()
+		if err != nil {
+			return nil, fmt.Errorf("key template %s: %w", k, err)
+		}
+		description := defaultTemplateDescription
+		if t.Description != nil {
+			description = *t.Description
+		}
+		pending = append(pending, keyTemplate{key: k, content: content, description: description})
+	}
+	return pending, nil
+}
+
+// seedKeyTemplates creates the entries returned by pendingKeyTemplates and
+// returns the keys it created.
+func (u *Entry) seedKeyTemplates(ctx context.Context, sc scope.Scope, pending []keyTemplate) ([]string, error) {
+	var keys []string
+	for _, t := range pending {
+		if _, err := u.Set(ctx, sc, t.key, t.content, &SetOptions{Description: &t.description, skipKeyTemplates: true}); err != nil {
+			return keys, fmt.Errorf("key template %s: %w", t.key, err)
+		}
+		keys = append(keys, t.key)
+	}
+	return keys, nil
+}
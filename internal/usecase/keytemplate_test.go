@@ -0,0 +1,42 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func TestRejectedFirstWriteSeedsNoKeyTemplates(t *testing.T) {
+	uc, dir := openTestEntry(t)
+	config := `{"quota": {"maxBytes": 50}, "keyTemplates": {"branch": {"progress": {"content": "# Progress\n"}}}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	branch := scope.NewBranch("/repo", "feature")
+
+	if _, err := uc.Set(ctx, branch, "plan", strings.Repeat("x", 100), nil); !errors.Is(err, usecase.ErrQuotaExceeded) {
+		t.Fatalf("Set over the quota = %v, want ErrQuotaExceeded", err)
+	}
+	list, err := uc.List(ctx, branch, nil)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list.Entries) != 0 {
+		t.Fatalf("a rejected write left %d entries in the scope: %+v", len(list.Entries), list.Entries)
+	}
+
+	result, err := uc.Set(ctx, branch, "plan", "# Plan\n", nil)
+	if err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if len(result.Templated) != 1 || result.Templated[0] != "progress" || result.TemplateErr != nil {
+		t.Fatalf("Templated = %v (%v), want progress", result.Templated, result.TemplateErr)
+	}
+}
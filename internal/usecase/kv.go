@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/language"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// KVKeyPrefix starts the entry key of every value stored with KVSet, so that
+// the name "agent.last-id" is kept as the entry "kv/agent.last-id".
+const KVKeyPrefix = "kv/"
+
+// MaxKVValueSize bounds a value stored with KVSet. Larger content belongs in
+// an ordinary entry.
+const MaxKVValueSize = 4096
+
+// KVKey returns the entry key of the value called name.
+func KVKey(name string) string {
+	return KVKeyPrefix + name
+}
+
+// KVPair is a stored value and its name.
+type KVPair struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Version int64  `json:"version"`
+}
+
+// KVSetResult describes a KVSet.
+type KVSetResult struct {
+	Version int64
+	// Unchanged is true when the value was already stored and no version
+	// was added.
+	Unchanged bool
+}
+
+func validateKVName(name string) error {
+	if name == "" {
+		return fmt.Errorf("kv name is empty")
+	}
+	if strings.ContainsAny(name, " \t\r\n") {
+		return fmt.Errorf("kv name %q contains whitespace", name)
+	}
+	return nil
+}
+
+// KVSet stores value under name. Storing the value that is already stored
+// adds no version, so agents can checkpoint on every step without growing
+// the history.
+func (u *Entry) KVSet(ctx context.Context, sc scope.Scope, name, value string, opts *SetOptions) (*KVSetResult, error) {
+	if err := validateKVName(name); err != nil {
+		return nil, err
+	}
+	if len(value) > MaxKVValueSize {
+		return nil, fmt.Errorf("kv value is %d bytes, over the limit of %d; store it with vault set instead", len(value), MaxKVValueSize)
+	}
+
+	current, err := u.KVGet(ctx, sc, name, &GetOptions{SkipVerify: true})
+	switch {
+	case err == nil && current.Value == value:
+		return &KVSetResult{Version: current.Version, Unchanged: true}, nil
+	case err != nil && !errors.Is(err, services.ErrNotFound):
+		return nil, err
+	}
+
+	setOpts := SetOptions{}
+	if opts != nil {
+		setOpts = *opts
+	}
+	setOpts.Language = language.Text
+	setOpts.Summarizer = nil
+	result, err := u.Set(ctx, sc, KVKey(name), value, &setOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &KVSetResult{Version: result.Version}, nil
+}
+
+// KVGet returns the value stored under name, or services.ErrNotFound.
+func (u *Entry) KVGet(ctx context.Context, sc scope.Scope, name string, opts *GetOptions) (*KVPair, error) {
+	if err := validateKVName(name); err != nil {
+		return nil, err
+	}
+	result, err := u.Get(ctx, sc, KVKey(name), opts)
+	if err != nil {
+		return nil, err
+	}
+	value, err := filesystem.ReadFile(result.Record.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	return &KVPair{Name: name, Value: value, Version: result.Record.Version}, nil
+}
+
+// KVList returns the values in sc whose names start with prefix, sorted by
+// name.
+func (u *Entry) KVList(ctx context.Context, sc scope.Scope, prefix string) ([]KVPair, error) {
+	list, err := u.List(ctx, sc, nil)
+	if err != nil {
+		return nil, err
+	}
+	var pairs []KVPair
+	for _, e := range list.Entries {
+		name, ok := strings.CutPrefix(e.Record.Key, KVKeyPrefix)
+		if !ok || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		value, err := filesystem.ReadFile(e.Record.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, KVPair{Name: name, Value: value, Version: e.Record.Version})
+	}
+	return pairs, nil
+}
+
+// KVDelete removes the value stored under name with all its versions. It
+// reports whether there was one.
+func (u *Entry) KVDelete(ctx context.Context, sc scope.Scope, name string) (bool, error) {
+	if err := validateKVName(name); err != nil {
+		return false, err
+	}
+	deleted, err := u.DeleteKey(ctx, sc, KVKey(name))
+	return deleted > 0, err
+}
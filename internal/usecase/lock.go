@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+)
+
+// Lock claims key in sc for owner for ttl, so that Set fails for everyone
+// else until the lock is released or expires. The owner can renew its lock
+// by locking again; force takes over a lock held by someone else. Locks are
+// advisory: writes with SetOptions.IgnoreLock pass.
+func (u *Entry) Lock(ctx context.Context, sc scope.Scope, key, owner string, ttl time.Duration, force bool) (*database.LockRecord, error) {
+	if u.lockService == nil {
+		return nil, ErrNoDatabase
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("lock duration must be positive, got %s", ttl)
+	}
+	if err := scope.Validate(sc); err != nil {
+		return nil, err
+	}
+	scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	if key, err = u.lookupKey(ctx, scopeID, key); err != nil {
+		return nil, err
+	}
+	return u.lockService.Acquire(ctx, scopeID, key, owner, ttl, force, time.Now())
+}
+
+// Unlock releases owner's lock on key and returns false if the key was not
+// locked. Releasing someone else's unexpired lock needs force.
+func (u *Entry) Unlock(ctx context.Context, sc scope.Scope, key, owner string, force bool) (bool, error) {
+	if u.lockService == nil {
+		return false, ErrNoDatabase
+	}
+	if err := scope.Validate(sc); err != nil {
+		return false, err
+	}
+	scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
+	if err != nil {
+		return false, err
+	}
+	if key, err = u.lookupKey(ctx, scopeID, key); err != nil {
+		return false, err
+	}
+	return u.lockService.Release(ctx, scopeID, key, owner, force, time.Now())
+}
+
+// checkLock fails with services.ErrLocked if someone other than the writer
+// holds a lock on key. The writer is the actor recorded in provenance, or
+// ResolveActor's default.
+func (u *Entry) checkLock(ctx context.Context, scopeID int64, key string, provenance *database.VersionProvenance) error {
+	if u.lockService == nil {
+		return nil
+	}
+	owner := ResolveActor("")
+	if provenance != nil && provenance.Actor != "" {
+		owner = provenance.Actor
+	}
+	return u.lockService.Check(ctx, scopeID, key, owner, time.Now())
+}
+
+// CheckLock fails with services.ErrLocked if someone other than owner holds
+// a lock on key, so that callers such as edit can refuse before the user
+// starts working instead of when saving.
+func (u *Entry) CheckLock(ctx context.Context, sc scope.Scope, key, owner string) error {
+	if u.lockService == nil {
+		return nil
+	}
+	scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
+	if err != nil {
+		return err
+	}
+	if key, err = u.lookupKey(ctx, scopeID, key); err != nil {
+		return err
+	}
+	return u.lockService.Check(ctx, scopeID, key, owner, time.Now())
+}
@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/textpatch"
+)
+
+// MergeOptions selects the versions of a three-way merge.
+type MergeOptions struct {
+	Ours   int64
+	Theirs int64
+	// Base is the common ancestor. When nil, the version before the older
+	// of Ours and Theirs is used; version 0 stands for empty content.
+	Base *int64
+	// Set configures the version stored for a clean merge.
+	Set *SetOptions
+}
+
+// MergeResult describes the outcome of Merge.
+type MergeResult struct {
+	Base      int64
+	Content   string
+	Conflicts int
+	// Stored is the new version, nil when the merge has conflicts.
+	Stored *SetResult
+}
+
+// Merge combines the changes two versions of key made to their common
+// ancestor. A clean merge is stored as a new version; a merge with
+// conflicts is returned with conflict markers and nothing is stored.
+func (u *Entry) Merge(ctx context.Context, sc scope.Scope, key string, opts MergeOptions) (*MergeResult, error) {
+	base := min(opts.Ours, opts.Theirs) - 1
+	if opts.Base != nil {
+		base = *opts.Base
+	}
+	if base < 0 {
+		return nil, fmt.Errorf("invalid base version: %d", base)
+	}
+
+	ours, err := u.readVersion(ctx, sc, key, opts.Ours)
+	if err != nil {
+		return nil, err
+	}
+	theirs, err := u.readVersion(ctx, sc, key, opts.Theirs)
+	if err != nil {
+		return nil, err
+	}
+	var ancestor string
+	if base > 0 {
+		ancestor, err = u.readVersion(ctx, sc, key, base)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	merged, conflicts := textpatch.Merge3(ancestor, ours, theirs, textpatch.MergeLabels{
+		Ours:   fmt.Sprintf("%s v%d", key, opts.Ours),
+		Theirs: fmt.Sprintf("%s v%d", key, opts.Theirs),
+	})
+	result := &MergeResult{
+		Base:      base,
+		Content:   merged,
+		Conflicts: conflicts,
+	}
+	if conflicts > 0 {
+		return result, nil
+	}
+
+	result.Stored, err = u.Set(ctx, sc, key, merged, opts.Set)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (u *Entry) readVersion(ctx context.Context, sc scope.Scope, key string, version int64) (string, error) {
+	v := int(version)
+	entry, err := u.Get(ctx, sc, key, &GetOptions{Version: &v})
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s version %d: %w", key, version, err)
+	}
+	return filesystem.ReadFile(entry.Record.FilePath)
+}
@@ -0,0 +1,124 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/choplin/vault.md/internal/contextpack"
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/scope"
+)
+
+// PackOptions selects the entries Pack assembles and how.
+type PackOptions struct {
+	// Patterns are keys or path.Match globs such as "adr/*", packed in the
+	// order given; keys matched by a glob follow in key order. No patterns
+	// selects every entry in the scope.
+	Patterns []string
+	// Language and DescriptionContains narrow the entries further, as in
+	// ListOptions.
+	Language            string
+	DescriptionContains string
+	// Budget is the approximate token limit; zero means no limit.
+	Budget   int
+	Strategy contextpack.Strategy
+	// SkipVerify disables the content hash check.
+	SkipVerify bool
+	// Progress, when set, is called as each entry has been read.
+	Progress ProgressFunc
+}
+
+// Pack assembles the latest versions of the selected entries into one
+// document that fits opts.Budget; see contextpack.Build.
+func (u *Entry) Pack(ctx context.Context, sc scope.Scope, opts PackOptions) (*contextpack.Result, error) {
+	for _, pattern := range opts.Patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid key pattern %q: %w", pattern, err)
+		}
+	}
+
+	list, err := u.List(ctx, sc, &ListOptions{
+		Language:            opts.Language,
+		DescriptionContains: opts.DescriptionContains,
+	})
+	if err != nil {
+		return nil, err
+	}
+	records, err := selectPackRecords(list.Entries, opts.Patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	scopeName := scope.FormatScopeShort(sc)
+	docs := make([]contextpack.Document, 0, len(records))
+	for i, record := range records {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !opts.SkipVerify {
+			if err := u.verify(ctx, &record); err != nil {
+				return nil, err
+			}
+		}
+		content, err := filesystem.ReadFile(record.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		doc := contextpack.Document{
+			Key:      record.Key,
+			Scope:    scopeName,
+			Version:  record.Version,
+			Language: record.Language,
+			Summary:  record.Summary,
+			Content:  content,
+		}
+		if record.Description != nil {
+			doc.Description = *record.Description
+		}
+		docs = append(docs, doc)
+		opts.Progress.report(i+1, len(records))
+	}
+
+	return contextpack.Build(docs, opts.Budget, opts.Strategy), nil
+}
+
+// selectPackRecords picks the listed entries matching patterns, in pattern
+// order and without duplicates. A plain key that matches nothing is an
+// error; a glob may match nothing.
+func selectPackRecords(entries []ListEntry, patterns []string) ([]database.ScopedEntryRecord, error) {
+	if len(patterns) == 0 {
+		records := make([]database.ScopedEntryRecord, 0, len(entries))
+		for _, e := range entries {
+			records = append(records, e.Record)
+		}
+		return records, nil
+	}
+
+	var records []database.ScopedEntryRecord
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		isGlob := strings.ContainsAny(pattern, `*?[\`)
+		matched := false
+		for _, e := range entries {
+			ok := e.Record.Key == pattern
+			if isGlob {
+				ok, _ = path.Match(pattern, e.Record.Key)
+			}
+			if !ok {
+				continue
+			}
+			matched = true
+			if !seen[e.Record.Key] {
+				seen[e.Record.Key] = true
+				records = append(records, e.Record)
+			}
+		}
+		if !matched && !isGlob {
+			return nil, fmt.Errorf("key not found: %s", pattern)
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/textpatch"
+)
+
+// PatchFunc computes new content from the content of the latest version.
+type PatchFunc func(content string) (string, error)
+
+// Patch applies patch to the latest version of key and stores the result as
+// a new version. If another writer stores a version in the meantime, Patch
+// fails with services.ErrVersionConflict rather than discarding that change.
+func (u *Entry) Patch(ctx context.Context, sc scope.Scope, key string, patch PatchFunc, opts *SetOptions) (*SetResult, error) {
+	current, err := u.Get(ctx, sc, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := filesystem.ReadFile(current.Record.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := patch(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to patch %s version %d: %w", key, current.Record.Version, err)
+	}
+	if updated == content {
+		return nil, fmt.Errorf("patch leaves %s unchanged", key)
+	}
+
+	setOpts := SetOptions{}
+	if opts != nil {
+		setOpts = *opts
+	}
+	base := current.Record.Version
+	setOpts.BaseVersion = &base
+	if setOpts.Language == "" {
+		setOpts.Language = current.Record.Language
+	}
+
+	return u.Set(ctx, sc, key, updated, &setOpts)
+}
+
+// NewPatchFunc builds a PatchFunc from a unified diff ("unified") or a JSON
+// list of section edits ("sections"). With "auto", input that starts with a
+// JSON object or array is treated as section edits.
+func NewPatchFunc(patch, patchType string) (PatchFunc, error) {
+	if patchType == "auto" {
+		patchType = "unified"
+		if trimmed := strings.TrimSpace(patch); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			patchType = "sections"
+		}
+	}
+
+	switch patchType {
+	case "unified":
+		if strings.TrimSpace(patch) == "" {
+			return nil, fmt.Errorf("patch is empty")
+		}
+		return func(content string) (string, error) {
+			return textpatch.ApplyUnified(content, patch)
+		}, nil
+	case "sections":
+		edits, err := textpatch.ParseSectionEdits([]byte(patch))
+		if err != nil {
+			return nil, err
+		}
+		return func(content string) (string, error) {
+			return textpatch.ApplySections(content, edits)
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid patch type: %s (valid values: auto, unified, sections)", patchType)
+	}
+}
+
+// SectionPatch returns a PatchFunc that replaces the content under the
+// markdown heading section with content.
+func SectionPatch(section, content string) PatchFunc {
+	return func(text string) (string, error) {
+		return textpatch.ReplaceSection(text, section, content)
+	}
+}
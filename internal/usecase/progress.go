@@ -0,0 +1,12 @@
+package usecase
+
+// ProgressFunc is told how many of total items a long-running operation has
+// processed, so that interfaces such as MCP can report progress. It is
+// called from the goroutine running the operation.
+type ProgressFunc func(done, total int)
+
+func (f ProgressFunc) report(done, total int) {
+	if f != nil {
+		f(done, total)
+	}
+}
@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// PromoteOptions selects what Promote copies.
+type PromoteOptions struct {
+	// Patterns are keys or path.Match globs, as in PackOptions. No
+	// patterns selects every entry of the source scope.
+	Patterns []string
+	// DryRun reports what would be copied and archived without writing.
+	DryRun bool
+	// Set is applied to each version written to the target scope; the
+	// source version's description is used when it has none.
+	Set *SetOptions
+}
+
+// PromotedKey is one key Promote copied, or would copy.
+type PromotedKey struct {
+	Key string
+	// FromVersion is the latest version in the source scope.
+	FromVersion int64
+	// ToVersion is the version written to the target scope, or the version
+	// that already holds the same content when Unchanged is set. It is 0
+	// in a dry run for keys that would be written.
+	ToVersion int64
+	// Unchanged reports that the target's latest version already had the
+	// content, so nothing was written.
+	Unchanged bool
+}
+
+// PromoteResult describes what Promote did.
+type PromoteResult struct {
+	Keys []PromotedKey
+	// Archived are the keys of the source scope that were archived, or
+	// would be in a dry run.
+	Archived []string
+}
+
+// Promote copies the latest versions of the selected keys from one scope to
+// another, typically a finished branch to its repository, and then archives
+// every key of the source scope. Keys whose content the target already has
+// are not written again, so an interrupted promote can simply be rerun.
+func (u *Entry) Promote(ctx context.Context, from, to scope.Scope, opts PromoteOptions) (*PromoteResult, error) {
+	for _, pattern := range opts.Patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid key pattern %q: %w", pattern, err)
+		}
+	}
+	if scope.GetScopeStorageKey(from) == scope.GetScopeStorageKey(to) {
+		return nil, fmt.Errorf("cannot promote %s to itself", scope.FormatScope(from))
+	}
+
+	list, err := u.List(ctx, from, nil)
+	if err != nil {
+		return nil, err
+	}
+	records, err := selectPackRecords(list.Entries, opts.Patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PromoteResult{Keys: make([]PromotedKey, 0, len(records))}
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		promoted := PromotedKey{Key: record.Key, FromVersion: record.Version}
+
+		current, err := u.Get(ctx, to, record.Key, &GetOptions{SkipVerify: true})
+		switch {
+		case err == nil && current.Record.Hash == record.Hash:
+			promoted.ToVersion = current.Record.Version
+			promoted.Unchanged = true
+		case err != nil && !errors.Is(err, services.ErrNotFound):
+			return nil, err
+		case !opts.DryRun:
+			if err := u.verify(ctx, &record); err != nil {
+				return nil, err
+			}
+			content, err := filesystem.ReadFile(record.FilePath)
+			if err != nil {
+				return nil, err
+			}
+			setOpts := SetOptions{}
+			if opts.Set != nil {
+				setOpts = *opts.Set
+			}
+			if setOpts.Description == nil {
+				setOpts.Description = record.Description
+			}
+			setOpts.Language = record.Language
+			stored, err := u.Set(ctx, to, record.Key, content, &setOpts)
+			if err != nil {
+				return nil, fmt.Errorf("promote %s: %w", record.Key, err)
+			}
+			promoted.ToVersion = stored.Version
+		}
+		result.Keys = append(result.Keys, promoted)
+	}
+
+	for _, e := range list.Entries {
+		if !opts.DryRun {
+			if _, err := u.Archive(ctx, from, e.Record.Key); err != nil {
+				return nil, fmt.Errorf("archive %s: %w", e.Record.Key, err)
+			}
+		}
+		result.Archived = append(result.Archived, e.Record.Key)
+	}
+	return result, nil
+}
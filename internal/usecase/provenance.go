@@ -0,0 +1,106 @@
+package usecase
+
+import (
+	"context"
+	"os"
+	"os/user"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/git"
+	"github.com/choplin/vault.md/internal/scope"
+)
+
+// Tool names recorded as the interface a version was written through.
+const (
+	ToolCLI = "cli"
+	ToolMCP = "mcp"
+)
+
+// CaptureProvenance describes a write made by actor through tool. The actor,
+// tool, and device ID are always recorded; when captureEnv is set, the
+// hostname and the git state of dir (the current directory if empty) are
+// recorded as well. See ResolveActor for how the actor is chosen.
+func CaptureProvenance(ctx context.Context, tool, actor, dir string, captureEnv bool) *database.VersionProvenance {
+	p := &database.VersionProvenance{
+		Actor: ResolveActor(actor),
+		Tool:  tool,
+	}
+	if deviceID, err := config.DeviceID(); err == nil {
+		p.DeviceID = deviceID
+	}
+	if !captureEnv {
+		return p
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		p.Hostname = hostname
+	}
+	if state := git.GetWorkingState(ctx, dir); state != nil {
+		dirty := state.Dirty
+		p.GitBranch = state.Branch
+		p.GitCommit = state.Commit
+		p.GitDirty = &dirty
+	}
+	return p
+}
+
+// ResolveActor picks who a write is attributed to: VAULT_ACTOR when set,
+// otherwise the actor the interface identified (such as an MCP client name),
+// otherwise the login name of the current OS user.
+func ResolveActor(actor string) string {
+	if explicit := os.Getenv("VAULT_ACTOR"); explicit != "" {
+		return explicit
+	}
+	if actor != "" {
+		return actor
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// HistoryOptions narrows the versions returned by History.
+type HistoryOptions struct {
+	// Actor, when set, keeps only versions written by this actor.
+	Actor string
+	// DeviceID, when set, keeps only versions written from this device.
+	DeviceID string
+}
+
+// History returns every version of key, newest first, with its provenance.
+func (u *Entry) History(ctx context.Context, sc scope.Scope, key string, opts *HistoryOptions) ([]database.VersionHistoryRecord, error) {
+	if err := scope.Validate(sc); err != nil {
+		return nil, err
+	}
+
+	scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	if key, err = u.lookupKey(ctx, scopeID, key); err != nil {
+		return nil, err
+	}
+
+	history, err := u.entryService.ListHistory(ctx, scopeID, key)
+	if err != nil || opts == nil || (opts.Actor == "" && opts.DeviceID == "") {
+		return history, err
+	}
+
+	filtered := make([]database.VersionHistoryRecord, 0, len(history))
+	for _, record := range history {
+		p := record.Provenance
+		if p == nil {
+			continue
+		}
+		if opts.Actor != "" && p.Actor != opts.Actor {
+			continue
+		}
+		if opts.DeviceID != "" && p.DeviceID != opts.DeviceID {
+			continue
+		}
+		filtered = append(filtered, record)
+	}
+	return filtered, nil
+}
@@ -0,0 +1,191 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// ErrQuotaExceeded is returned by Set when a write would take a scope past
+// a limit in the quota section of the config file.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quota holds the per-scope limits of config.QuotaSettings. Zero limits are
+// unlimited.
+type Quota struct {
+	MaxEntries int
+	MaxBytes   int64
+	// Prune makes room under MaxBytes by deleting the oldest superseded
+	// versions of the scope instead of failing the write.
+	Prune bool
+}
+
+// QuotaFromSettings returns the configured quota, or nil when none is set.
+func QuotaFromSettings(settings *config.Settings) *Quota {
+	q := &Quota{
+		MaxEntries: settings.QuotaMaxEntries(),
+		MaxBytes:   settings.QuotaMaxBytes(),
+		Prune:      settings.QuotaPrunes(),
+	}
+	if q.MaxEntries == 0 && q.MaxBytes == 0 {
+		return nil
+	}
+	return q
+}
+
+// ScopeUsage is how much one scope stores, against its quota.
+type ScopeUsage struct {
+	Scope      scope.Scope
+	ScopeShort string
+	// Entries includes archived entries.
+	Entries  int64
+	Versions int64
+	Bytes    int64
+	// Quota is nil when no quota is configured.
+	Quota *Quota
+}
+
+// Usage reports what sc stores, or every scope when allScopes is set.
+func (u *Entry) Usage(ctx context.Context, sc scope.Scope, allScopes bool) ([]ScopeUsage, error) {
+	if err := u.requireDatabase(); err != nil {
+		return nil, err
+	}
+	quota, err := loadQuota()
+	if err != nil {
+		return nil, err
+	}
+
+	type target struct {
+		id int64
+		sc scope.Scope
+	}
+	var targets []target
+	if allScopes {
+		scopes, err := u.scopeService.GetAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range scopes {
+			targets = append(targets, target{record.ID, record.Scope})
+		}
+	} else {
+		if err := scope.Validate(sc); err != nil {
+			return nil, err
+		}
+		id, err := u.scopeService.GetOrCreate(ctx, sc)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target{id, sc})
+	}
+
+	usages := make([]ScopeUsage, 0, len(targets))
+	for _, t := range targets {
+		usage, err := u.integrityService.ScopeUsage(ctx, t.id)
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, ScopeUsage{
+			Scope:      t.sc,
+			ScopeShort: scope.FormatScopeShort(t.sc),
+			Entries:    usage.EntryCount,
+			Versions:   usage.VersionCount,
+			Bytes:      usage.TotalSize,
+			Quota:      quota,
+		})
+	}
+	return usages, nil
+}
+
+// checkQuota checks that writing size bytes to key keeps the scope within
+// its quota. When the quota prunes, it returns the oldest superseded
+// versions that must go to make room; they are deleted by prune once the
+// write has succeeded, so a failed write never costs history. The check is
+// not atomic with the write, so concurrent writers can overshoot a limit
+// slightly.
+func (u *Entry) checkQuota(ctx context.Context, sc scope.Scope, scopeID int64, key string, size int64) ([]database.SupersededVersion, error) {
+	quota, err := loadQuota()
+	if err != nil || quota == nil {
+		return nil, err
+	}
+	if err := u.requireDatabase(); err != nil {
+		return nil, err
+	}
+
+	usage, err := u.integrityService.ScopeUsage(ctx, scopeID)
+	if err != nil {
+		return nil, err
+	}
+	name := scope.FormatScopeShort(sc)
+
+	if quota.MaxEntries > 0 && usage.EntryCount >= int64(quota.MaxEntries) {
+		_, err := u.entryService.GetLatest(ctx, scopeID, key)
+		if errors.Is(err, services.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %s already holds %d keys (quota.maxEntries is %d)", ErrQuotaExceeded, name, usage.EntryCount, quota.MaxEntries)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if quota.MaxBytes == 0 {
+		return nil, nil
+	}
+	excess := usage.TotalSize + size - quota.MaxBytes
+	if excess <= 0 {
+		return nil, nil
+	}
+	overErr := fmt.Errorf("%w: %s would hold %d bytes (quota.maxBytes is %d)", ErrQuotaExceeded, name, usage.TotalSize+size, quota.MaxBytes)
+	if !quota.Prune {
+		return nil, overErr
+	}
+
+	// Prune only when it frees enough; otherwise keep the history and fail.
+	superseded, err := u.entryService.ListSuperseded(ctx, scopeID)
+	if err != nil {
+		return nil, err
+	}
+	var freed int64
+	n := 0
+	for n < len(superseded) && freed < excess {
+		freed += superseded[n].Size
+		n++
+	}
+	if freed < excess {
+		return nil, overErr
+	}
+	return superseded[:n], nil
+}
+
+// prune deletes the versions checkQuota chose to make room, emitting a
+// delete event for each. It returns how many were deleted from the index.
+func (u *Entry) prune(ctx context.Context, sc scope.Scope, scopeID int64, versions []database.SupersededVersion) (int, error) {
+	for i, v := range versions {
+		deleted, err := u.entryService.DeleteVersion(ctx, scopeID, v.Key, v.Version)
+		if err != nil {
+			return i, err
+		}
+		if !deleted {
+			// Already gone, deleted by another writer or by hand.
+			continue
+		}
+		u.emit(ctx, newEvent(EventDelete, sc, v.Key, v.Version))
+		if err := u.removeObjects(ctx, []string{v.FilePath}); err != nil {
+			return i + 1, fmt.Errorf("pruned %s version %d but failed to delete file %s (queued for retry; run vault db gc): %w", v.Key, v.Version, v.FilePath, err)
+		}
+	}
+	return len(versions), nil
+}
+
+func loadQuota() (*Quota, error) {
+	settings, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	return QuotaFromSettings(settings), nil
+}
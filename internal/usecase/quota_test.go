@@ -0,0 +1,73 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+// openTestEntry opens an empty vault in a temporary directory and returns
+// the use case on it with the directory, where config.json is read from.
+func openTestEntry(t *testing.T) (*usecase.Entry, string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("VAULT_DIR", dir)
+	t.Setenv("VAULT_CONFIG", filepath.Join(dir, "config.json"))
+	dbCtx, err := database.CreateDatabase("")
+	if err != nil {
+		t.Fatalf("CreateDatabase failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = database.CloseDatabase(dbCtx)
+	})
+	return usecase.NewEntry(dbCtx), dir
+}
+
+func TestQuotaPrunesOnlyAfterTheWrite(t *testing.T) {
+	uc, dir := openTestEntry(t)
+	ctx := context.Background()
+	sc := scope.NewGlobal()
+
+	content := strings.Repeat("x", 100)
+	for range 3 {
+		if _, err := uc.Set(ctx, sc, "notes", content, nil); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	config := `{"quota": {"maxBytes": 350, "onExceed": "prune"}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := int64(1)
+	_, err := uc.Set(ctx, sc, "notes", content, &usecase.SetOptions{BaseVersion: &stale})
+	if !errors.Is(err, services.ErrVersionConflict) {
+		t.Fatalf("Set with a stale base version = %v, want ErrVersionConflict", err)
+	}
+	for version := 1; version <= 3; version++ {
+		v := version
+		if _, err := uc.Get(ctx, sc, "notes", &usecase.GetOptions{Version: &v}); err != nil {
+			t.Errorf("version %d is gone after a rejected write: %v", version, err)
+		}
+	}
+
+	result, err := uc.Set(ctx, sc, "notes", content, nil)
+	if err != nil {
+		t.Fatalf("Set under quota failed: %v", err)
+	}
+	if result.Version != 4 || result.Pruned != 1 || result.PruneErr != nil {
+		t.Fatalf("Set = version %d, pruned %d (%v), want version 4 with 1 pruned", result.Version, result.Pruned, result.PruneErr)
+	}
+	first := 1
+	if _, err := uc.Get(ctx, sc, "notes", &usecase.GetOptions{Version: &first}); !errors.Is(err, services.ErrNotFound) {
+		t.Errorf("oldest version after pruning: %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,169 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// SetParent declares child a child of parent in sc, such as a chapter of a
+// large document; see services.RelationService.SetParent. Either key may
+// have no version yet.
+func (u *Entry) SetParent(ctx context.Context, sc scope.Scope, child, parent string, position *int64) (*database.ChildRecord, error) {
+	scopeID, err := u.relationScope(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	if child, err = u.lookupKey(ctx, scopeID, child); err != nil {
+		return nil, err
+	}
+	if parent, err = u.lookupKey(ctx, scopeID, parent); err != nil {
+		return nil, err
+	}
+	return u.relationService.SetParent(ctx, scopeID, child, parent, position)
+}
+
+// ClearParent removes child's parent and returns false if it had none.
+func (u *Entry) ClearParent(ctx context.Context, sc scope.Scope, child string) (bool, error) {
+	scopeID, err := u.relationScope(ctx, sc)
+	if err != nil {
+		return false, err
+	}
+	if child, err = u.lookupKey(ctx, scopeID, child); err != nil {
+		return false, err
+	}
+	return u.relationService.ClearParent(ctx, scopeID, child)
+}
+
+// Parent returns the relation declaring child's parent, or
+// services.ErrNotFound.
+func (u *Entry) Parent(ctx context.Context, sc scope.Scope, child string) (*database.ChildRecord, error) {
+	scopeID, err := u.relationScope(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	if child, err = u.lookupKey(ctx, scopeID, child); err != nil {
+		return nil, err
+	}
+	return u.relationService.Parent(ctx, scopeID, child)
+}
+
+// Children returns the children of parent in order.
+func (u *Entry) Children(ctx context.Context, sc scope.Scope, parent string) ([]database.ChildRecord, error) {
+	scopeID, err := u.relationScope(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	if parent, err = u.lookupKey(ctx, scopeID, parent); err != nil {
+		return nil, err
+	}
+	return u.relationService.Children(ctx, scopeID, parent)
+}
+
+func (u *Entry) relationScope(ctx context.Context, sc scope.Scope) (int64, error) {
+	if u.relationService == nil {
+		return 0, ErrNoDatabase
+	}
+	if err := scope.Validate(sc); err != nil {
+		return 0, err
+	}
+	return u.scopeService.GetOrCreate(ctx, sc)
+}
+
+// DocumentPart is one entry of an assembled document.
+type DocumentPart struct {
+	Key     string
+	Version int64
+	// Depth is 0 for the parent, 1 for its children, and so on.
+	Depth   int
+	Content string
+}
+
+// Document is an entry assembled with its descendants.
+type Document struct {
+	// Root is the read of the entry itself; see Entry.Describe.
+	Root  GetResult
+	Parts []DocumentPart
+	// Missing lists declared children that have no version, which are
+	// left out.
+	Missing []string
+}
+
+// Content joins the parts, separated by blank lines.
+func (d *Document) Content() string {
+	var b strings.Builder
+	for i, part := range d.Parts {
+		if i > 0 {
+			if !strings.HasSuffix(d.Parts[i-1].Content, "\n") {
+				b.WriteString("\n")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(part.Content)
+	}
+	return b.String()
+}
+
+// Assemble reads key followed by its children in order, each followed by
+// its own children. opts selects the version of key; children are read at
+// their latest version, or the one pinned by the same scope snapshot, with
+// the same verification.
+func (u *Entry) Assemble(ctx context.Context, sc scope.Scope, key string, opts *GetOptions) (*Document, error) {
+	scopeID, err := u.relationScope(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	root, err := u.Get(ctx, sc, key, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	childOpts := &GetOptions{}
+	if opts != nil {
+		childOpts.SkipVerify = opts.SkipVerify
+		childOpts.TrackRead = opts.TrackRead
+		childOpts.Snapshot = opts.Snapshot
+	}
+	doc := &Document{Root: *root}
+	visited := map[string]bool{}
+	var walk func(record database.ScopedEntryRecord, depth int) error
+	walk = func(record database.ScopedEntryRecord, depth int) error {
+		visited[record.Key] = true
+		content, err := filesystem.ReadFile(record.FilePath)
+		if err != nil {
+			return err
+		}
+		doc.Parts = append(doc.Parts, DocumentPart{Key: record.Key, Version: record.Version, Depth: depth, Content: content})
+
+		children, err := u.relationService.Children(ctx, scopeID, record.Key)
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			if visited[child.ChildKey] {
+				continue
+			}
+			result, err := u.Get(ctx, sc, child.ChildKey, childOpts)
+			if errors.Is(err, services.ErrNotFound) {
+				doc.Missing = append(doc.Missing, child.ChildKey)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := walk(result.Record, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(root.Record, 0); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
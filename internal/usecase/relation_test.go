@@ -0,0 +1,47 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+func TestAssembleDescribeRecordsOneRead(t *testing.T) {
+	uc, _ := openTestEntry(t)
+	ctx := context.Background()
+	sc := scope.NewGlobal()
+
+	for _, key := range []string{"book", "chapter"} {
+		if _, err := uc.Set(ctx, sc, key, key+"\n", nil); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	if _, err := uc.SetParent(ctx, sc, "chapter", "book", nil); err != nil {
+		t.Fatalf("SetParent failed: %v", err)
+	}
+
+	doc, err := uc.Assemble(ctx, sc, "book", &usecase.GetOptions{TrackRead: true})
+	if err != nil {
+		t.Fatalf("Assemble failed: %v", err)
+	}
+	if got := doc.Content(); got != "book\n\nchapter\n" {
+		t.Errorf("Assemble content = %q", got)
+	}
+	info, err := uc.Describe(ctx, &doc.Root)
+	if err != nil {
+		t.Fatalf("Describe failed: %v", err)
+	}
+	if info.Record.Key != "book" || info.Summary.VersionCount != 1 {
+		t.Errorf("Describe = %+v, want book with one version", info)
+	}
+
+	after, err := uc.Info(ctx, sc, "book", nil)
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if after.Record.ReadCount != 1 {
+		t.Errorf("read count = %d, want 1 for a single assembled read", after.Record.ReadCount)
+	}
+}
@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// RelayoutResult describes a RelayoutObjects run.
+type RelayoutResult struct {
+	Layout filesystem.Layout `json:"layout"`
+	// Moved counts the objects moved into the layout.
+	Moved int `json:"moved"`
+	// Skipped counts versions whose object is missing or lies outside the
+	// objects directory; they keep pointing where they did.
+	Skipped int `json:"skipped"`
+}
+
+// RelayoutObjects makes layout the layout of the vault and moves every
+// object into it. New objects are written in layout from the start, so the
+// vault stays usable meanwhile; objects written in the old layout while the
+// move runs stay readable and are moved by running it again. Each object is
+// copied, hard-linked where possible, and its version pointed at the copy
+// before the old file is removed, so an interruption leaves at worst a stray
+// file that vault doctor reports.
+func RelayoutObjects(ctx context.Context, dbCtx *database.Context, layout filesystem.Layout, progress ProgressFunc) (*RelayoutResult, error) {
+	if err := filesystem.SetLayout(layout); err != nil {
+		return nil, err
+	}
+
+	integrity := services.NewIntegrityService(dbCtx)
+	files, err := integrity.VersionFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RelayoutResult{Layout: layout}
+	for i, vf := range files {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		progress.report(i, len(files))
+
+		target, ok := filesystem.RelayoutPath(vf.FilePath, layout)
+		if !ok || !filesystem.FileExists(vf.FilePath) {
+			result.Skipped++
+			continue
+		}
+		if target == vf.FilePath {
+			continue
+		}
+
+		if err := filesystem.CopyObject(vf.FilePath, target); err != nil {
+			return result, err
+		}
+		moved, err := integrity.MoveVersionFile(ctx, vf.VersionID, vf.FilePath, target)
+		if err != nil {
+			return result, err
+		}
+		if !moved {
+			// The version was deleted meanwhile.
+			if err := filesystem.RemoveObject(target); err != nil {
+				return result, err
+			}
+			continue
+		}
+		if err := filesystem.RemoveObject(vf.FilePath); err != nil {
+			return result, err
+		}
+		result.Moved++
+	}
+	progress.report(len(files), len(files))
+	return result, nil
+}
@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// EntryRepository stores entry versions and their metadata.
+// services.EntryService implements it on top of the database; package
+// services/memory provides an in-memory implementation for tests.
+// Lookups of a missing entry or version return services.ErrNotFound.
+type EntryRepository interface {
+	GetLatest(ctx context.Context, scopeID int64, key string) (*database.ScopedEntryRecord, error)
+	GetByVersion(ctx context.Context, scopeID int64, key string, version int64) (*database.ScopedEntryRecord, error)
+	GetNextVersion(ctx context.Context, scopeID int64, key string) (int64, error)
+	GetEntryByKey(ctx context.Context, scopeID int64, key string) (*database.EntryRecord, error)
+	Create(ctx context.Context, entry database.ScopedEntryRecord) (versionID int64, err error)
+	CreateBatch(ctx context.Context, entries []database.ScopedEntryRecord) error
+	List(ctx context.Context, scopeID int64, includeArchived, allVersions bool) ([]database.ScopedEntryRecord, error)
+	ListWithFilter(ctx context.Context, scopeID int64, includeArchived, allVersions bool, filter services.ListFilter) ([]database.ScopedEntryRecord, error)
+	EachWithFilter(ctx context.Context, scopeID int64, includeArchived, allVersions bool, filter services.ListFilter, fn func(database.ScopedEntryRecord) error) error
+	ListVersions(ctx context.Context, scopeID int64, key string) ([]database.VersionRecord, error)
+	ListHistory(ctx context.Context, scopeID int64, key string) ([]database.VersionHistoryRecord, error)
+	ListSuperseded(ctx context.Context, scopeID int64) ([]database.SupersededVersion, error)
+	DeleteVersion(ctx context.Context, scopeID int64, key string, version int64) (bool, error)
+	DeleteAll(ctx context.Context, scopeID int64, key string) (bool, error)
+	Archive(ctx context.Context, scopeID int64, key string) (bool, error)
+	Restore(ctx context.Context, scopeID int64, key string) (bool, error)
+	RecordRead(ctx context.Context, entryID int64) error
+	Approve(ctx context.Context, scopeID int64, key string, version int64, approvedBy string) (bool, error)
+	ImportApproval(ctx context.Context, scopeID int64, key string, version int64, approval database.VersionApproval) (bool, error)
+	LatestApprovedVersion(ctx context.Context, scopeID int64, key string) (int64, error)
+	SetSummary(ctx context.Context, scopeID int64, key string, version int64, summary, summarizer string) error
+	FindByIdempotencyKey(ctx context.Context, token string) (*database.IdempotentWrite, error)
+	GetVersionSummary(ctx context.Context, entryID int64) (*database.EntryVersionSummary, error)
+	GetVerification(ctx context.Context, entryID, version int64) (*database.VersionVerification, error)
+	RecordVerification(ctx context.Context, entryID, version int64, stamp database.VersionVerification) error
+}
+
+// ScopeRepository resolves scopes to the IDs entries are stored under and
+// keeps their descriptions. Lookups of a missing scope return
+// services.ErrNotFound.
+type ScopeRepository interface {
+	GetOrCreate(ctx context.Context, sc scope.Scope) (int64, error)
+	GetByID(ctx context.Context, id int64) (*database.ScopeRecord, error)
+	GetAll(ctx context.Context) ([]database.ScopeRecord, error)
+	Describe(ctx context.Context, id int64, description string, metadata map[string]string) error
+}
+
+// ErrNoDatabase is returned by operations that need the database when the
+// Entry was built from repositories alone.
+var ErrNoDatabase = errors.New("operation requires a database")
+
+var (
+	_ EntryRepository = (*services.EntryService)(nil)
+	_ ScopeRepository = (*services.ScopeService)(nil)
+)
+
+// NewEntryFromRepositories creates an Entry use case over the given
+// repositories instead of a database, so callers can run it against
+// in-memory fakes. Operations that need the database directly, such as
+// devices, snapshots, and quotas, fail with ErrNoDatabase; WithTransaction
+// is not atomic on it.
+func NewEntryFromRepositories(scopes ScopeRepository, entries EntryRepository) *Entry {
+	return &Entry{
+		scopeService: scopes,
+		entryService: entries,
+		notifiers:    loadNotifiers(),
+	}
+}
+
+// requireDatabase fails with ErrNoDatabase unless the device and integrity
+// services are available.
+func (u *Entry) requireDatabase() error {
+	if u.deviceService == nil || u.integrityService == nil {
+		return ErrNoDatabase
+	}
+	return nil
+}
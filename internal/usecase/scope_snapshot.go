@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+)
+
+// ScopeSnapshot is a scope snapshot with the versions it pins.
+type ScopeSnapshot struct {
+	database.ScopeSnapshotRecord
+	Versions []database.PinnedVersion
+}
+
+// CreateScopeSnapshot records the latest version of every key in sc under
+// name, so that Get with GetOptions.Snapshot reads exactly those versions
+// however the keys change afterwards.
+func (u *Entry) CreateScopeSnapshot(ctx context.Context, sc scope.Scope, name string) (*database.ScopeSnapshotRecord, error) {
+	scopeID, err := u.scopeSnapshotScope(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	return u.snapshotService.Create(ctx, scopeID, name)
+}
+
+// ScopeSnapshots returns the scope snapshots of sc, oldest first.
+func (u *Entry) ScopeSnapshots(ctx context.Context, sc scope.Scope) ([]database.ScopeSnapshotRecord, error) {
+	scopeID, err := u.scopeSnapshotScope(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	return u.snapshotService.List(ctx, scopeID)
+}
+
+// ScopeSnapshot returns the scope snapshot called name with its versions,
+// or services.ErrNotFound.
+func (u *Entry) ScopeSnapshot(ctx context.Context, sc scope.Scope, name string) (*ScopeSnapshot, error) {
+	scopeID, err := u.scopeSnapshotScope(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	record, err := u.snapshotService.Get(ctx, scopeID, name)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := u.snapshotService.Versions(ctx, record.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &ScopeSnapshot{ScopeSnapshotRecord: *record, Versions: versions}, nil
+}
+
+// DeleteScopeSnapshot removes the scope snapshot called name and returns
+// false if there was none. The versions it pinned are kept.
+func (u *Entry) DeleteScopeSnapshot(ctx context.Context, sc scope.Scope, name string) (bool, error) {
+	scopeID, err := u.scopeSnapshotScope(ctx, sc)
+	if err != nil {
+		return false, err
+	}
+	return u.snapshotService.Delete(ctx, scopeID, name)
+}
+
+func (u *Entry) scopeSnapshotScope(ctx context.Context, sc scope.Scope) (int64, error) {
+	if u.snapshotService == nil {
+		return 0, ErrNoDatabase
+	}
+	if err := scope.Validate(sc); err != nil {
+		return 0, err
+	}
+	return u.scopeService.GetOrCreate(ctx, sc)
+}
+
+// pinnedVersion resolves GetOptions.Snapshot to a version number.
+func (u *Entry) pinnedVersion(ctx context.Context, scopeID int64, name, key string) (int64, error) {
+	if u.snapshotService == nil {
+		return 0, ErrNoDatabase
+	}
+	return u.snapshotService.PinnedVersion(ctx, scopeID, name, key)
+}
@@ -0,0 +1,195 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/textpatch"
+)
+
+// ScopeInfo is a scope with its description and how many keys it holds.
+type ScopeInfo struct {
+	Record database.ScopeRecord
+	// Keys counts the keys that are not archived.
+	Keys int
+}
+
+// Scopes lists every scope the vault knows, ordered by type, repository,
+// and branch. progress, if not nil, is called as each scope's keys have been
+// counted.
+func (u *Entry) Scopes(ctx context.Context, progress ProgressFunc) ([]ScopeInfo, error) {
+	records, err := u.scopeService.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ScopeInfo, 0, len(records))
+	for i, record := range records {
+		entries, err := u.entryService.List(ctx, record.ID, false, false)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ScopeInfo{Record: record, Keys: len(entries)})
+		progress.report(i+1, len(records))
+	}
+	return result, nil
+}
+
+// ScopeDescription is a change to a scope's description and metadata.
+// The zero value changes nothing.
+type ScopeDescription struct {
+	// Description replaces the description when set; an empty string
+	// clears it.
+	Description *string
+	// ClearMetadata drops all metadata before Set is applied.
+	ClearMetadata bool
+	// Set adds or replaces metadata values.
+	Set map[string]string
+	// Unset removes metadata keys.
+	Unset []string
+}
+
+// ParseScopeMetadata parses key=value pairs as given to scope describe.
+func ParseScopeMetadata(pairs []string) (map[string]string, error) {
+	metadata := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid metadata %q: expected key=value", pair)
+		}
+		metadata[key] = value
+	}
+	return metadata, nil
+}
+
+// DescribeScope applies change to the scope, creating the scope if it does
+// not exist yet, and returns the updated record.
+func (u *Entry) DescribeScope(ctx context.Context, sc scope.Scope, change ScopeDescription) (*database.ScopeRecord, error) {
+	var result *database.ScopeRecord
+	err := u.WithTransaction(ctx, func(tx *Entry) error {
+		id, err := tx.scopeService.GetOrCreate(ctx, sc)
+		if err != nil {
+			return err
+		}
+		record, err := tx.scopeService.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		description := record.Description
+		if change.Description != nil {
+			description = strings.TrimSpace(*change.Description)
+		}
+		metadata := maps.Clone(record.Metadata)
+		if change.ClearMetadata || metadata == nil {
+			metadata = map[string]string{}
+		}
+		maps.Copy(metadata, change.Set)
+		for _, key := range change.Unset {
+			delete(metadata, key)
+		}
+
+		if err := tx.scopeService.Describe(ctx, id, description, metadata); err != nil {
+			return err
+		}
+		result, err = tx.scopeService.GetByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ScopeKeyDiff is a key whose latest content differs between two scopes.
+type ScopeKeyDiff struct {
+	Key string
+	// A and B are the latest versions in each scope.
+	A, B database.ScopedEntryRecord
+	// Diff is a unified diff from A's content to B's.
+	Diff string
+}
+
+// ScopeDiff compares the keys of two scopes.
+type ScopeDiff struct {
+	// Changed lists the keys in both scopes with different content, in
+	// key order.
+	Changed []ScopeKeyDiff
+	// OnlyA and OnlyB list the keys that exist in just one scope.
+	OnlyA []string
+	OnlyB []string
+	// Same counts the keys whose content is identical.
+	Same int
+}
+
+// DiffScopes compares the latest versions of the keys in scopes a and b,
+// so branch context can be reviewed before it is merged into another
+// scope. Archived keys are left out.
+func (u *Entry) DiffScopes(ctx context.Context, a, b scope.Scope) (*ScopeDiff, error) {
+	listA, err := u.List(ctx, a, nil)
+	if err != nil {
+		return nil, err
+	}
+	listB, err := u.List(ctx, b, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	inB := make(map[string]database.ScopedEntryRecord, len(listB.Entries))
+	for _, e := range listB.Entries {
+		inB[e.Record.Key] = e.Record
+	}
+
+	result := &ScopeDiff{}
+	inA := make(map[string]bool, len(listA.Entries))
+	for _, e := range listA.Entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		recordA := e.Record
+		inA[recordA.Key] = true
+		recordB, ok := inB[recordA.Key]
+		switch {
+		case !ok:
+			result.OnlyA = append(result.OnlyA, recordA.Key)
+		case recordA.Hash == recordB.Hash:
+			result.Same++
+		default:
+			diff, err := u.diffVersions(ctx, a, b, recordA, recordB)
+			if err != nil {
+				return nil, err
+			}
+			result.Changed = append(result.Changed, ScopeKeyDiff{Key: recordA.Key, A: recordA, B: recordB, Diff: diff})
+		}
+	}
+	for _, e := range listB.Entries {
+		if !inA[e.Record.Key] {
+			result.OnlyB = append(result.OnlyB, e.Record.Key)
+		}
+	}
+	return result, nil
+}
+
+func (u *Entry) diffVersions(ctx context.Context, a, b scope.Scope, recordA, recordB database.ScopedEntryRecord) (string, error) {
+	contents := make([]string, 2)
+	for i, record := range []*database.ScopedEntryRecord{&recordA, &recordB} {
+		if err := u.verify(ctx, record); err != nil {
+			return "", err
+		}
+		content, err := filesystem.ReadFile(record.FilePath)
+		if err != nil {
+			return "", err
+		}
+		contents[i] = content
+	}
+	return textpatch.Unified(contents[0], contents[1],
+		fmt.Sprintf("%s (%s v%d)", recordA.Key, scope.FormatScope(a), recordA.Version),
+		fmt.Sprintf("%s (%s v%d)", recordB.Key, scope.FormatScope(b), recordB.Version),
+	), nil
+}
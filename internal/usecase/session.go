@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/language"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// SessionKeyPrefix starts the key of every session log. A log is named
+// after the day its session started, such as "session/2025-06-01".
+const SessionKeyPrefix = "session/"
+
+// SessionKey returns the key of the session log started on the day of t.
+func SessionKey(t time.Time) string {
+	return SessionKeyPrefix + t.Format("2006-01-02")
+}
+
+// ErrNoOpenSession is returned when appending to or ending a session while
+// none is open in the scope.
+var ErrNoOpenSession = errors.New("no open session")
+
+// sessionEvent matches the start and end lines of a session log.
+var sessionEvent = regexp.MustCompile(`(?m)^- \d{2}:\d{2} (started|ended)\b`)
+
+// SessionResult describes a write to a session log.
+type SessionResult struct {
+	Key     string
+	Version int64
+	// Resumed is true when StartSession added to a log that already
+	// existed for the day instead of creating it.
+	Resumed bool
+}
+
+// StartSession opens a session in the log for the day of now, creating the
+// log if needed. note, if not empty, is recorded with the start.
+func (u *Entry) StartSession(ctx context.Context, sc scope.Scope, now time.Time, note string, opts *SetOptions) (*SessionResult, error) {
+	key := SessionKey(now)
+	line := sessionLine(now, "started", note)
+
+	setOpts := sessionSetOptions(opts, "Session started")
+	_, err := u.Get(ctx, sc, key, &GetOptions{SkipVerify: true})
+	if errors.Is(err, services.ErrNotFound) {
+		content := fmt.Sprintf("# Session %s\n\n%s", now.Format("2006-01-02"), line)
+		result, err := u.Set(ctx, sc, key, content, setOpts)
+		if err != nil {
+			return nil, err
+		}
+		return &SessionResult{Key: key, Version: result.Version}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := u.Patch(ctx, sc, key, appendSessionLine(line), setOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionResult{Key: key, Version: result.Version, Resumed: true}, nil
+}
+
+// AppendSession adds text to the open session.
+func (u *Entry) AppendSession(ctx context.Context, sc scope.Scope, now time.Time, text string, opts *SetOptions) (*SessionResult, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("session note is empty")
+	}
+	return u.writeOpenSession(ctx, sc, sessionLine(now, "", text), sessionSetOptions(opts, "Session note"))
+}
+
+// EndSession closes the open session, recording summary if not empty.
+func (u *Entry) EndSession(ctx context.Context, sc scope.Scope, now time.Time, summary string, opts *SetOptions) (*SessionResult, error) {
+	return u.writeOpenSession(ctx, sc, sessionLine(now, "ended", summary), sessionSetOptions(opts, "Session ended"))
+}
+
+// OpenSession returns the key of the open session log in sc, or
+// ErrNoOpenSession. Only the most recent log can be open.
+func (u *Entry) OpenSession(ctx context.Context, sc scope.Scope) (string, error) {
+	list, err := u.List(ctx, sc, nil)
+	if err != nil {
+		return "", err
+	}
+	var latest *ListEntry
+	for i, e := range list.Entries {
+		if strings.HasPrefix(e.Record.Key, SessionKeyPrefix) && (latest == nil || e.Record.Key > latest.Record.Key) {
+			latest = &list.Entries[i]
+		}
+	}
+	if latest == nil {
+		return "", ErrNoOpenSession
+	}
+
+	content, err := filesystem.ReadFile(latest.Record.FilePath)
+	if err != nil {
+		return "", err
+	}
+	events := sessionEvent.FindAllStringSubmatch(content, -1)
+	if len(events) == 0 || events[len(events)-1][1] != "started" {
+		return "", ErrNoOpenSession
+	}
+	return latest.Record.Key, nil
+}
+
+func (u *Entry) writeOpenSession(ctx context.Context, sc scope.Scope, line string, opts *SetOptions) (*SessionResult, error) {
+	key, err := u.OpenSession(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	result, err := u.Patch(ctx, sc, key, appendSessionLine(line), opts)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionResult{Key: key, Version: result.Version}, nil
+}
+
+// sessionLine formats one list item of a session log. Continuation lines
+// of a multi-line text are indented under the item.
+func sessionLine(now time.Time, event, text string) string {
+	text = strings.TrimSpace(text)
+	var b strings.Builder
+	b.WriteString("- ")
+	b.WriteString(now.Format("15:04"))
+	if event != "" {
+		b.WriteString(" ")
+		b.WriteString(event)
+		if text != "" {
+			b.WriteString(":")
+		}
+	}
+	if text != "" {
+		b.WriteString(" ")
+		b.WriteString(strings.ReplaceAll(text, "\n", "\n  "))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func appendSessionLine(line string) PatchFunc {
+	return func(content string) (string, error) {
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		return content + line, nil
+	}
+}
+
+func sessionSetOptions(opts *SetOptions, description string) *SetOptions {
+	setOpts := SetOptions{}
+	if opts != nil {
+		setOpts = *opts
+	}
+	if setOpts.Description == nil {
+		setOpts.Description = &description
+	}
+	setOpts.Language = language.Markdown
+	return &setOpts
+}
@@ -0,0 +1,784 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/language"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+	"github.com/choplin/vault.md/internal/snapshot"
+)
+
+// SnapshotFormat identifies a vault snapshot manifest.
+const SnapshotFormat = "vault.md/snapshot"
+
+// SnapshotFormatVersion is the current version of the snapshot manifest.
+// Version 2 added the metadata of versions, parent links, and scope
+// descriptions and snapshots; version 1 manifests carry none of them and
+// are still read. See format.go for the compatibility policy.
+const SnapshotFormatVersion = 2
+
+// SnapshotManifest lists every version in a snapshot of the whole vault.
+// Content is stored separately, addressed by hash, so unchanged content is
+// shared between versions and between snapshots.
+type SnapshotManifest struct {
+	Format        string `json:"format"`
+	FormatVersion int    `json:"formatVersion"`
+	// ReaderVersion is the oldest format version whose readers can import
+	// the manifest by ignoring fields they do not know.
+	ReaderVersion int             `json:"readerVersion,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	Entries       []SnapshotEntry `json:"entries"`
+	// RevokedDevices lists devices whose versions are no longer accepted;
+	// see DeviceService.Revoke.
+	RevokedDevices []string `json:"revokedDevices,omitempty"`
+	// Scopes describes the scopes that have a description or scope
+	// snapshots.
+	Scopes []SnapshotScopeInfo `json:"scopes,omitempty"`
+}
+
+// SnapshotEntry is one key of one scope in a SnapshotManifest.
+type SnapshotEntry struct {
+	Scope      SnapshotScope     `json:"scope"`
+	Key        string            `json:"key"`
+	IsArchived bool              `json:"isArchived"`
+	Versions   []SnapshotVersion `json:"versions"`
+	// Parent is the key the entry is a child of, if any.
+	Parent *SnapshotParent `json:"parent,omitempty"`
+}
+
+// SnapshotVersion is one version of a SnapshotEntry, oldest first. Its
+// content is stored as an object named by Hash.
+type SnapshotVersion struct {
+	Version     int64     `json:"version"`
+	Hash        string    `json:"hash"`
+	Description *string   `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// DeviceID is the device that wrote the version, when it was recorded.
+	DeviceID string `json:"deviceId,omitempty"`
+	VersionMetadata
+}
+
+// SnapshotScope is the serialised form of a scope.Scope.
+type SnapshotScope struct {
+	Type         string `json:"type"`
+	PrimaryPath  string `json:"primaryPath,omitempty"`
+	BranchName   string `json:"branchName,omitempty"`
+	WorktreeID   string `json:"worktreeId,omitempty"`
+	WorktreePath string `json:"worktreePath,omitempty"`
+}
+
+// SnapshotObject is a content file referenced by a SnapshotManifest.
+type SnapshotObject struct {
+	Hash string
+	Path string
+}
+
+// RestoreResult describes the outcome of restoring a snapshot.
+type RestoreResult struct {
+	Imported int
+	Present  int
+	// FormatVersion is the format version of the restored manifest. When
+	// it exceeds SnapshotFormatVersion, fields this vault does not know
+	// were ignored.
+	FormatVersion int
+	// Integrity describes the integrity check of a snapshot restored with
+	// RestoreFrom, or is nil when the snapshot has no integrity manifest.
+	Integrity *SnapshotIntegrity
+	// ScopeSnapshots counts the scope snapshots created.
+	ScopeSnapshots int
+	// Conflicts lists "scope key vN" for versions that could not be
+	// imported because the number is taken locally by different content or
+	// lies below the local latest version.
+	Conflicts []string
+	// Rejected lists "scope key vN" for versions written by a revoked
+	// device, which are never imported.
+	Rejected []string
+}
+
+// Versions returns the number of versions in the manifest.
+func (m *SnapshotManifest) Versions() int {
+	n := 0
+	for _, e := range m.Entries {
+		n += len(e.Versions)
+	}
+	return n
+}
+
+// Hashes returns the set of content hashes the manifest references.
+func (m *SnapshotManifest) Hashes() map[string]bool {
+	hashes := make(map[string]bool)
+	for _, e := range m.Entries {
+		for _, v := range e.Versions {
+			hashes[v.Hash] = true
+		}
+	}
+	return hashes
+}
+
+// Missing counts the versions in other that m does not contain, ignoring
+// versions written by devices m lists as revoked.
+func (m *SnapshotManifest) Missing(other *SnapshotManifest) int {
+	revoked := make(map[string]bool, len(m.RevokedDevices))
+	for _, id := range m.RevokedDevices {
+		revoked[id] = true
+	}
+	have := make(map[string]bool)
+	for _, e := range m.Entries {
+		for _, v := range e.Versions {
+			have[snapshotVersionID(e.Scope, e.Key, v.Version, v.Hash)] = true
+		}
+	}
+	missing := 0
+	for _, e := range other.Entries {
+		for _, v := range e.Versions {
+			if !have[snapshotVersionID(e.Scope, e.Key, v.Version, v.Hash)] && (v.DeviceID == "" || !revoked[v.DeviceID]) {
+				missing++
+			}
+		}
+	}
+	return missing
+}
+
+// SnapshotChange is a key with versions that an earlier snapshot lacks.
+type SnapshotChange struct {
+	Scope scope.Scope
+	Key   string
+	// Versions lists the new version numbers, oldest first.
+	Versions []int64
+}
+
+// Changes lists the keys of m with versions that prev does not contain, in
+// manifest order. Every version is new when prev is nil.
+func (m *SnapshotManifest) Changes(prev *SnapshotManifest) []SnapshotChange {
+	have := make(map[string]bool)
+	if prev != nil {
+		for _, e := range prev.Entries {
+			for _, v := range e.Versions {
+				have[snapshotVersionID(e.Scope, e.Key, v.Version, v.Hash)] = true
+			}
+		}
+	}
+	var changes []SnapshotChange
+	for _, e := range m.Entries {
+		var versions []int64
+		for _, v := range e.Versions {
+			if !have[snapshotVersionID(e.Scope, e.Key, v.Version, v.Hash)] {
+				versions = append(versions, v.Version)
+			}
+		}
+		if len(versions) > 0 {
+			changes = append(changes, SnapshotChange{Scope: e.Scope.scope(), Key: e.Key, Versions: versions})
+		}
+	}
+	return changes
+}
+
+func snapshotVersionID(sc SnapshotScope, key string, version int64, hash string) string {
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%s\x00%s\x00%d\x00%s",
+		sc.Type, sc.PrimaryPath, sc.BranchName, sc.WorktreeID, sc.WorktreePath, key, version, hash)
+}
+
+// ParseSnapshotManifest decodes and checks a manifest.
+func ParseSnapshotManifest(data []byte) (*SnapshotManifest, error) {
+	if snapshot.IsEncrypted(data) {
+		return nil, fmt.Errorf("snapshot manifest is encrypted; set syncKeyFile in the config to read it")
+	}
+	var manifest SnapshotManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid snapshot manifest: %w", err)
+	}
+	if manifest.Format != SnapshotFormat {
+		return nil, fmt.Errorf("not a vault snapshot: unexpected format %q", manifest.Format)
+	}
+	if err := snapshotFormat.check(manifest.FormatVersion, manifest.ReaderVersion); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// SnapshotFilter narrows a snapshot to part of the vault. The zero value
+// selects every version.
+type SnapshotFilter struct {
+	// KeyGlob keeps keys matching a path.Match pattern such as "adr/*".
+	KeyGlob string
+	// Since keeps versions written at or after this time.
+	Since time.Time
+	// ScopeType keeps scopes of this type.
+	ScopeType scope.ScopeType
+	// Scope keeps only this scope.
+	Scope *scope.Scope
+}
+
+// Validate checks the glob pattern and scope type.
+func (f *SnapshotFilter) Validate() error {
+	if f == nil {
+		return nil
+	}
+	if _, err := path.Match(f.KeyGlob, ""); err != nil {
+		return fmt.Errorf("invalid key glob %q: %w", f.KeyGlob, err)
+	}
+	switch f.ScopeType {
+	case "", scope.ScopeGlobal, scope.ScopeRepository, scope.ScopeBranch, scope.ScopeWorktree:
+		return nil
+	default:
+		return fmt.Errorf("invalid scope type: %s (valid values: global, repository, branch, worktree)", f.ScopeType)
+	}
+}
+
+func (f *SnapshotFilter) matchScope(sc scope.Scope) bool {
+	if f != nil && f.Scope != nil && (sc.Type != f.Scope.Type || scope.FormatScope(sc) != scope.FormatScope(*f.Scope)) {
+		return false
+	}
+	return f == nil || f.ScopeType == "" || sc.Type == f.ScopeType
+}
+
+func (f *SnapshotFilter) matchKey(key string) bool {
+	if f == nil || f.KeyGlob == "" {
+		return true
+	}
+	ok, _ := path.Match(f.KeyGlob, key)
+	return ok
+}
+
+// Snapshot builds the manifest for every version in the vault selected by
+// filter (all of them when nil), oldest version first, together with the
+// content files it references. Each distinct hash is listed once; content
+// is checked against its hash.
+func (u *Entry) Snapshot(ctx context.Context, filter *SnapshotFilter) (*SnapshotManifest, []SnapshotObject, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := u.requireDatabase(); err != nil {
+		return nil, nil, err
+	}
+
+	scopes, err := u.scopeService.GetAll(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	revoked, err := u.deviceService.Revoked(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	writers, err := u.versionDevices(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manifest := &SnapshotManifest{
+		Format:         SnapshotFormat,
+		FormatVersion:  SnapshotFormatVersion,
+		ReaderVersion:  snapshotFormat.readerVersion,
+		CreatedAt:      time.Now().UTC(),
+		Entries:        []SnapshotEntry{},
+		RevokedDevices: revoked,
+	}
+	var objects []SnapshotObject
+	seen := make(map[string]bool)
+
+	for _, sr := range scopes {
+		if !filter.matchScope(sr.Scope) {
+			continue
+		}
+		info, err := u.snapshotScopeInfo(ctx, sr, filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		if info != nil {
+			manifest.Scopes = append(manifest.Scopes, *info)
+		}
+
+		var listFilter services.ListFilter
+		if filter != nil {
+			listFilter.Since = filter.Since
+		}
+		records, err := u.entryService.ListWithFilter(ctx, sr.ID, true, true, listFilter)
+		if err != nil {
+			return nil, nil, err
+		}
+		sort.Slice(records, func(i, j int) bool {
+			if records[i].Key != records[j].Key {
+				return records[i].Key < records[j].Key
+			}
+			return records[i].Version < records[j].Version
+		})
+
+		sc := newSnapshotScope(sr.Scope)
+		var history map[int64]database.VersionHistoryRecord
+		for _, r := range records {
+			if !filter.matchKey(r.Key) {
+				continue
+			}
+			last := len(manifest.Entries) - 1
+			if last < 0 || manifest.Entries[last].Key != r.Key || manifest.Entries[last].Scope != sc {
+				parent, err := u.snapshotParent(ctx, sr.ID, r.Key)
+				if err != nil {
+					return nil, nil, err
+				}
+				if history, err = u.versionHistory(ctx, sr.ID, r.Key); err != nil {
+					return nil, nil, err
+				}
+				manifest.Entries = append(manifest.Entries, SnapshotEntry{Scope: sc, Key: r.Key, Parent: parent})
+				last++
+			}
+
+			if err := ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+			ok, err := filesystem.VerifyFile(r.FilePath, r.Hash)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !ok {
+				return nil, nil, fmt.Errorf("%w for %s version %d", ErrIntegrity, r.Key, r.Version)
+			}
+
+			entry := &manifest.Entries[last]
+			entry.IsArchived = r.IsArchived
+			entry.Versions = append(entry.Versions, SnapshotVersion{
+				Version:         r.Version,
+				Hash:            r.Hash,
+				Description:     r.Description,
+				CreatedAt:       r.UpdatedAt,
+				DeviceID:        writers[versionRef{r.EntryID, r.Version}],
+				VersionMetadata: newVersionMetadata(history[r.Version]),
+			})
+			if !seen[r.Hash] {
+				seen[r.Hash] = true
+				objects = append(objects, SnapshotObject{Hash: r.Hash, Path: r.FilePath})
+			}
+		}
+	}
+	return manifest, objects, nil
+}
+
+type versionRef struct {
+	entryID int64
+	version int64
+}
+
+// versionDevices maps each version to the device that wrote it.
+func (u *Entry) versionDevices(ctx context.Context) (map[versionRef]string, error) {
+	records, err := u.deviceService.VersionDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	writers := make(map[versionRef]string, len(records))
+	for _, r := range records {
+		writers[versionRef{r.EntryID, r.Version}] = r.DeviceID
+	}
+	return writers, nil
+}
+
+// RestoreSnapshot imports the versions in manifest that are missing from
+// the vault, keeping their version numbers, descriptions, write times, and
+// writing device. read returns the content for a hash. Versions already
+// present are left as they are; versions that would not fit into the local
+// history are reported as conflicts. Device revocations in the manifest are
+// adopted first, and versions written by any revoked device are rejected.
+func (u *Entry) RestoreSnapshot(ctx context.Context, manifest *SnapshotManifest, read func(hash string) ([]byte, error)) (*RestoreResult, error) {
+	if err := u.requireDatabase(); err != nil {
+		return nil, err
+	}
+	if len(manifest.RevokedDevices) > 0 {
+		if err := u.deviceService.Revoke(ctx, manifest.RevokedDevices...); err != nil {
+			return nil, err
+		}
+	}
+	revokedIDs, err := u.deviceService.Revoked(ctx)
+	if err != nil {
+		return nil, err
+	}
+	revoked := make(map[string]bool, len(revokedIDs))
+	for _, id := range revokedIDs {
+		revoked[id] = true
+	}
+
+	result := &RestoreResult{FormatVersion: manifest.FormatVersion}
+	for _, e := range manifest.Entries {
+		sc := e.Scope.scope()
+		if err := scope.Validate(sc); err != nil {
+			return nil, fmt.Errorf("invalid scope for %s in manifest: %w", e.Key, err)
+		}
+		scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
+		if err != nil {
+			return nil, err
+		}
+		// Snapshots carry the vault's own entries along with the user's.
+		key, err := u.storedKey(ctx, scopeID, e.Key, true)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key in manifest: %w", err)
+		}
+
+		for _, v := range e.Versions {
+			conflict := fmt.Sprintf("%s %s v%d", scope.FormatScope(sc), key, v.Version)
+
+			existing, err := u.entryService.GetByVersion(ctx, scopeID, key, v.Version)
+			switch {
+			case err == nil && existing.Hash == v.Hash:
+				if err := u.applyVersionMetadata(ctx, scopeID, key, v.Version, v.VersionMetadata, false); err != nil {
+					return nil, err
+				}
+				result.Present++
+				continue
+			case err == nil:
+				result.Conflicts = append(result.Conflicts, conflict)
+				continue
+			case !errors.Is(err, services.ErrNotFound):
+				return nil, err
+			}
+			if v.DeviceID != "" && revoked[v.DeviceID] {
+				result.Rejected = append(result.Rejected, conflict)
+				continue
+			}
+
+			content, err := read(v.Hash)
+			if err != nil {
+				return nil, err
+			}
+			err = u.importVersion(ctx, sc, scopeID, key, e.IsArchived, v, string(content))
+			if errors.Is(err, services.ErrVersionConflict) || errors.Is(err, fs.ErrExist) {
+				// A newer local version (or a leftover object) already holds
+				// the number; versions can only be appended.
+				result.Conflicts = append(result.Conflicts, conflict)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if err := u.applyVersionMetadata(ctx, scopeID, key, v.Version, v.VersionMetadata, true); err != nil {
+				return nil, err
+			}
+			result.Imported++
+		}
+
+		if err := u.restoreParent(ctx, scopeID, key, e.Parent); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, info := range manifest.Scopes {
+		if err := scope.Validate(info.Scope.scope()); err != nil {
+			return nil, fmt.Errorf("invalid scope in manifest: %w", err)
+		}
+		created, err := u.restoreScopeInfo(ctx, info)
+		if err != nil {
+			return nil, err
+		}
+		result.ScopeSnapshots += created
+	}
+	return result, nil
+}
+
+// importVersion stores one version under its original number.
+func (u *Entry) importVersion(ctx context.Context, sc scope.Scope, scopeID int64, key string, archived bool, v SnapshotVersion, content string) error {
+	path, hash, err := filesystem.SaveNewFile(scope.GetScopeStorageKey(sc), key, int(v.Version), content)
+	if err != nil {
+		return err
+	}
+	if hash != v.Hash {
+		_ = filesystem.DeleteFile(path)
+		return fmt.Errorf("hash mismatch for %s version %d: snapshot may be corrupted", key, v.Version)
+	}
+
+	lang := v.Language
+	if lang == "" {
+		lang = language.Detect(key, content)
+	}
+
+	if _, err := u.entryService.Create(ctx, database.ScopedEntryRecord{
+		ScopeID:     scopeID,
+		Key:         key,
+		Version:     v.Version,
+		FilePath:    path,
+		Hash:        hash,
+		Description: v.Description,
+		UpdatedAt:   v.CreatedAt,
+		Size:        int64(len(content)),
+		IsArchived:  archived,
+		Language:    lang,
+		Provenance:  v.provenance(v.DeviceID),
+	}); err != nil {
+		_ = filesystem.DeleteFile(path)
+		return err
+	}
+	return nil
+}
+
+func newSnapshotScope(sc scope.Scope) SnapshotScope {
+	return SnapshotScope{
+		Type:         string(sc.Type),
+		PrimaryPath:  sc.PrimaryPath,
+		BranchName:   sc.BranchName,
+		WorktreeID:   sc.WorktreeID,
+		WorktreePath: sc.WorktreePath,
+	}
+}
+
+func (s SnapshotScope) scope() scope.Scope {
+	return scope.Scope{
+		Type:         scope.ScopeType(s.Type),
+		PrimaryPath:  s.PrimaryPath,
+		BranchName:   s.BranchName,
+		WorktreeID:   s.WorktreeID,
+		WorktreePath: s.WorktreePath,
+	}
+}
+
+// snapshotIDLayout names snapshots after their creation time, so ids sort
+// by age.
+const snapshotIDLayout = "20060102T150405.000Z"
+
+// SnapshotResult describes the outcome of SnapshotTo.
+type SnapshotResult struct {
+	ID       string
+	Entries  int
+	Versions int
+	// Uploaded counts the objects that were not in the store yet.
+	Uploaded int
+	// Rotated lists the snapshots removed by the keep policy.
+	Rotated []string
+	// Pruned counts the objects no remaining snapshot referenced.
+	Pruned int
+	// Previous is the snapshot that was newest before this one, or empty
+	// for the first snapshot in the store.
+	Previous string
+	// Changed lists the keys with versions Previous lacks.
+	Changed []SnapshotChange
+}
+
+// SnapshotToOptions controls SnapshotTo.
+type SnapshotToOptions struct {
+	// Keep, when positive, removes all but the newest Keep snapshots
+	// afterwards, along with the objects only they referenced.
+	Keep int
+	// Filter selects the part of the vault to snapshot; nil selects all
+	// of it.
+	Filter *SnapshotFilter
+	// FormatVersion selects the manifest format, 0 for the current one;
+	// see SnapshotManifest.ConvertTo.
+	FormatVersion int
+	// SigningKey, when set, is the SSH key the integrity manifest is
+	// signed with; see snapshot.SignSSH.
+	SigningKey string
+}
+
+// SnapshotTo writes a snapshot of the vault to store, with an integrity
+// manifest listing the SHA-256 of its manifest and every object it
+// references. It fails with ErrDeviceRevoked if this device is revoked
+// locally or in the newest snapshot of store. Only objects the store does
+// not hold yet are copied.
+func (u *Entry) SnapshotTo(ctx context.Context, store snapshot.Store, opts SnapshotToOptions) (*SnapshotResult, error) {
+	if err := ValidateSnapshotFormatVersion(opts.FormatVersion); err != nil {
+		return nil, err
+	}
+	ids, err := store.ListManifests()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		previous *SnapshotManifest
+		revoked  []string
+	)
+	if len(ids) > 0 {
+		data, err := store.GetManifest(ids[len(ids)-1])
+		if err != nil {
+			return nil, err
+		}
+		previous, err = ParseSnapshotManifest(data)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %s: %w", ids[len(ids)-1], err)
+		}
+		revoked = previous.RevokedDevices
+	}
+	if err := u.adoptRevocations(ctx, revoked); err != nil {
+		return nil, err
+	}
+
+	manifest, objects, err := u.Snapshot(ctx, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SnapshotResult{
+		ID:       manifest.CreatedAt.Format(snapshotIDLayout),
+		Entries:  len(manifest.Entries),
+		Versions: manifest.Versions(),
+		Changed:  manifest.Changes(previous),
+	}
+	if previous != nil {
+		result.Previous = ids[len(ids)-1]
+	}
+	for _, obj := range objects {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ok, err := store.HasObject(obj.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			continue
+		}
+		if err := putSnapshotObject(store, obj); err != nil {
+			return nil, err
+		}
+		result.Uploaded++
+	}
+
+	// The manifest goes last, so a snapshot never references missing
+	// objects. It is signed first, so a key that cannot sign leaves no
+	// unsigned snapshot behind.
+	written, err := manifest.ConvertTo(opts.FormatVersion)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(written, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, '\n')
+	sums := snapshotSums(result.ID, data, objects).Marshal()
+	var sig []byte
+	if opts.SigningKey != "" {
+		if sig, err = snapshot.SignSSH(ctx, opts.SigningKey, sums); err != nil {
+			return nil, err
+		}
+	}
+	if err := store.PutManifest(result.ID, data); err != nil {
+		return nil, err
+	}
+	if err := store.PutSums(result.ID, sums, sig); err != nil {
+		return nil, err
+	}
+
+	if opts.Keep > 0 {
+		result.Rotated, result.Pruned, err = rotateSnapshots(store, opts.Keep)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func putSnapshotObject(store snapshot.Store, obj SnapshotObject) error {
+	//nolint:gosec // G304: path is an object file recorded in the vault database
+	f, err := os.Open(obj.Path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return store.PutObject(obj.Hash, f)
+}
+
+// rotateSnapshots removes all but the newest keep snapshots and then the
+// objects the remaining ones do not reference.
+func rotateSnapshots(store snapshot.Store, keep int) ([]string, int, error) {
+	ids, err := store.ListManifests()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(ids) <= keep {
+		return nil, 0, nil
+	}
+
+	rotated := ids[:len(ids)-keep]
+	for _, id := range rotated {
+		if err := store.DeleteManifest(id); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	referenced := make(map[string]bool)
+	for _, id := range ids[len(ids)-keep:] {
+		data, err := store.GetManifest(id)
+		if err != nil {
+			return nil, 0, err
+		}
+		manifest, err := ParseSnapshotManifest(data)
+		if err != nil {
+			return nil, 0, fmt.Errorf("snapshot %s: %w", id, err)
+		}
+		for hash := range manifest.Hashes() {
+			referenced[snapshot.StoredName(store, hash)] = true
+		}
+	}
+
+	names, err := store.ListObjects()
+	if err != nil {
+		return nil, 0, err
+	}
+	pruned := 0
+	for _, name := range names {
+		if referenced[name] {
+			continue
+		}
+		if err := store.DeleteObject(name); err != nil {
+			return nil, 0, err
+		}
+		pruned++
+	}
+	return rotated, pruned, nil
+}
+
+// RestoreFromOptions controls RestoreFrom.
+type RestoreFromOptions struct {
+	// ID is the snapshot to restore, or empty for the newest.
+	ID string
+	// AllowedSigners, when set, is an ssh-keygen allowed signers file; the
+	// snapshot must then be signed by one of its keys.
+	AllowedSigners string
+}
+
+// RestoreFrom restores a snapshot from store and returns the id it used;
+// see RestoreSnapshot. The snapshot is first checked against its integrity
+// manifest, and nothing is imported if any file differs; the error is a
+// *SnapshotIntegrityError listing them.
+func (u *Entry) RestoreFrom(ctx context.Context, store snapshot.Store, opts RestoreFromOptions) (*RestoreResult, string, error) {
+	id := opts.ID
+	if id == "" {
+		ids, err := store.ListManifests()
+		if err != nil {
+			return nil, "", err
+		}
+		if len(ids) == 0 {
+			return nil, "", fmt.Errorf("no snapshots found")
+		}
+		id = ids[len(ids)-1]
+	}
+
+	data, err := store.GetManifest(id)
+	if err != nil {
+		return nil, "", err
+	}
+	manifest, err := ParseSnapshotManifest(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("snapshot %s: %w", id, err)
+	}
+
+	integrity, err := verifySnapshot(ctx, store, id, data, manifest, opts.AllowedSigners)
+	if err != nil {
+		return nil, "", err
+	}
+
+	result, err := u.RestoreSnapshot(ctx, manifest, store.GetObject)
+	if err != nil {
+		return nil, "", err
+	}
+	result.Integrity = integrity
+	return result, id, nil
+}
@@ -0,0 +1,130 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/choplin/vault.md/internal/snapshot"
+)
+
+// SnapshotIntegrity describes how a restored snapshot was checked against
+// its integrity manifest.
+type SnapshotIntegrity struct {
+	// Files counts the files that matched the integrity manifest.
+	Files int
+	// Signed reports whether the integrity manifest carries a signature.
+	Signed bool
+	// Signer is the principal whose signature was verified, or empty when
+	// the signature was not checked.
+	Signer string
+}
+
+// SnapshotIntegrityError reports the files of a snapshot that do not match
+// its integrity manifest. It wraps ErrIntegrity.
+type SnapshotIntegrityError struct {
+	ID string
+	snapshot.SumsDiff
+}
+
+func (e *SnapshotIntegrityError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "snapshot %s does not match its integrity manifest; it may have been tampered with", e.ID)
+	for _, p := range e.Changed {
+		fmt.Fprintf(&b, "\n  changed:    %s", p)
+	}
+	for _, p := range e.Missing {
+		fmt.Fprintf(&b, "\n  missing:    %s", p)
+	}
+	for _, p := range e.Unlisted {
+		fmt.Fprintf(&b, "\n  not listed: %s", p)
+	}
+	return b.String()
+}
+
+func (e *SnapshotIntegrityError) Unwrap() error { return ErrIntegrity }
+
+// snapshotSums builds the integrity manifest of a snapshot from the bytes
+// of its manifest and the objects it references, whose names are already
+// their SHA-256.
+func snapshotSums(id string, manifestData []byte, objects []SnapshotObject) snapshot.Sums {
+	sums := make(snapshot.Sums, len(objects)+1)
+	sums.Add(snapshot.ManifestPath(id), manifestData)
+	for _, obj := range objects {
+		sums[snapshot.ObjectPath(obj.Hash)] = obj.Hash
+	}
+	return sums
+}
+
+// verifySnapshot checks the snapshot id in store against its integrity
+// manifest before anything is imported, reading every object it references.
+// With allowedSigners set, the integrity manifest must also carry a valid
+// signature from one of them. Snapshots written without an integrity
+// manifest pass unchecked, with a nil result, unless a signature is
+// required.
+func verifySnapshot(ctx context.Context, store snapshot.Store, id string, manifestData []byte, manifest *SnapshotManifest, allowedSigners string) (*SnapshotIntegrity, error) {
+	sumsData, sig, err := store.GetSums(id)
+	if errors.Is(err, fs.ErrNotExist) {
+		if allowedSigners != "" {
+			return nil, fmt.Errorf("snapshot %s has no integrity manifest, so it cannot be verified against %s", id, allowedSigners)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", id, err)
+	}
+
+	integrity := &SnapshotIntegrity{Signed: sig != nil}
+	if allowedSigners != "" {
+		if sig == nil {
+			return nil, fmt.Errorf("snapshot %s is not signed", id)
+		}
+		if integrity.Signer, err = snapshot.VerifySSH(ctx, allowedSigners, sumsData, sig); err != nil {
+			return nil, fmt.Errorf("snapshot %s: %w", id, err)
+		}
+	}
+	recorded, err := snapshot.ParseSums(sumsData)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", id, err)
+	}
+
+	// Check the objects the manifest references and those the integrity
+	// manifest lists, so an altered manifest shows up as such rather than
+	// as missing objects.
+	hashes := manifest.Hashes()
+	for p := range recorded {
+		if strings.HasPrefix(p, "objects/") {
+			hashes[path.Base(p)] = true
+		}
+	}
+
+	actual := make(snapshot.Sums, len(hashes)+1)
+	actual.Add(snapshot.ManifestPath(id), manifestData)
+	for hash := range hashes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		content, err := store.GetObject(hash)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case errors.Is(err, snapshot.ErrUndecryptable), errors.Is(err, snapshot.ErrNotEncrypted):
+			// Altered or replaced ciphertext: it cannot match any recorded
+			// sum.
+			actual[snapshot.ObjectPath(hash)] = ""
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("snapshot %s: %w", id, err)
+		}
+		actual.Add(snapshot.ObjectPath(hash), content)
+	}
+
+	if diff := recorded.Compare(actual); !diff.Empty() {
+		return nil, &SnapshotIntegrityError{ID: id, SumsDiff: diff}
+	}
+	integrity.Files = len(actual)
+	return integrity, nil
+}
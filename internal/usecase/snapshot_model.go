@@ -0,0 +1,239 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"time"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// VersionMetadata is what snapshots and key exports record about a version
+// besides its content and description. It was added in format version 2 of
+// both; older documents leave it empty.
+type VersionMetadata struct {
+	Language   string              `json:"language,omitempty"`
+	Provenance *ExportedProvenance `json:"provenance,omitempty"`
+	Approval   *ExportedApproval   `json:"approval,omitempty"`
+	Summary    string              `json:"summary,omitempty"`
+}
+
+// ExportedProvenance is the serialised form of the provenance of a version,
+// apart from the device, which snapshots record separately.
+type ExportedProvenance struct {
+	Actor     string `json:"actor,omitempty"`
+	Tool      string `json:"tool,omitempty"`
+	Hostname  string `json:"hostname,omitempty"`
+	GitBranch string `json:"gitBranch,omitempty"`
+	GitCommit string `json:"gitCommit,omitempty"`
+	GitDirty  *bool  `json:"gitDirty,omitempty"`
+}
+
+// ExportedApproval is the serialised form of a version approval.
+type ExportedApproval struct {
+	ApprovedBy string    `json:"approvedBy,omitempty"`
+	ApprovedAt time.Time `json:"approvedAt"`
+}
+
+// SnapshotParent places a SnapshotEntry under another key; see SetParent.
+type SnapshotParent struct {
+	Key      string `json:"key"`
+	Position int64  `json:"position"`
+}
+
+// SnapshotScopeInfo carries what a snapshot records about a scope rather
+// than its entries: its description and its scope snapshots.
+type SnapshotScopeInfo struct {
+	Scope       SnapshotScope       `json:"scope"`
+	Description string              `json:"description,omitempty"`
+	Metadata    map[string]string   `json:"metadata,omitempty"`
+	Snapshots   []SnapshotPinnedSet `json:"snapshots,omitempty"`
+}
+
+// SnapshotPinnedSet is a scope snapshot: the version it pins for each key.
+type SnapshotPinnedSet struct {
+	Name      string           `json:"name"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Versions  map[string]int64 `json:"versions"`
+}
+
+// newVersionMetadata collects the metadata of a version from its history.
+func newVersionMetadata(h database.VersionHistoryRecord) VersionMetadata {
+	meta := VersionMetadata{Language: h.Language, Summary: h.Summary}
+	if p := h.Provenance; p != nil {
+		exported := ExportedProvenance{
+			Actor:     p.Actor,
+			Tool:      p.Tool,
+			Hostname:  p.Hostname,
+			GitBranch: p.GitBranch,
+			GitCommit: p.GitCommit,
+			GitDirty:  p.GitDirty,
+		}
+		if exported != (ExportedProvenance{}) {
+			meta.Provenance = &exported
+		}
+	}
+	if a := h.Approval; a != nil {
+		meta.Approval = &ExportedApproval{ApprovedBy: a.ApprovedBy, ApprovedAt: a.ApprovedAt}
+	}
+	return meta
+}
+
+// provenance returns the provenance to store with an imported version, or
+// nil if neither it nor deviceID is known.
+func (m VersionMetadata) provenance(deviceID string) *database.VersionProvenance {
+	if m.Provenance == nil && deviceID == "" {
+		return nil
+	}
+	p := &database.VersionProvenance{DeviceID: deviceID}
+	if e := m.Provenance; e != nil {
+		p.Actor = e.Actor
+		p.Tool = e.Tool
+		p.Hostname = e.Hostname
+		p.GitBranch = e.GitBranch
+		p.GitCommit = e.GitCommit
+		p.GitDirty = e.GitDirty
+	}
+	return p
+}
+
+// versionHistory returns the history of key by version number.
+func (u *Entry) versionHistory(ctx context.Context, scopeID int64, key string) (map[int64]database.VersionHistoryRecord, error) {
+	history, err := u.entryService.ListHistory(ctx, scopeID, key)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]database.VersionHistoryRecord, len(history))
+	for _, h := range history {
+		byVersion[h.Version] = h
+	}
+	return byVersion, nil
+}
+
+// applyVersionMetadata records the approval of meta for a version present in
+// the vault, and its summary when imported reports that the version was
+// just written. Approvals and summaries already there are kept.
+func (u *Entry) applyVersionMetadata(ctx context.Context, scopeID int64, key string, version int64, meta VersionMetadata, imported bool) error {
+	if a := meta.Approval; a != nil {
+		approval := database.VersionApproval{ApprovedBy: a.ApprovedBy, ApprovedAt: a.ApprovedAt}
+		if _, err := u.entryService.ImportApproval(ctx, scopeID, key, version, approval); err != nil {
+			return err
+		}
+	}
+	if imported && meta.Summary != "" {
+		if err := u.entryService.SetSummary(ctx, scopeID, key, version, meta.Summary, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotParent returns the parent of key, or nil if it has none.
+func (u *Entry) snapshotParent(ctx context.Context, scopeID int64, key string) (*SnapshotParent, error) {
+	relation, err := u.relationService.Parent(ctx, scopeID, key)
+	if errors.Is(err, services.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SnapshotParent{Key: relation.ParentKey, Position: relation.Position}, nil
+}
+
+// restoreParent declares the parent recorded in a snapshot unless key has
+// one already, or has no versions because none could be restored.
+func (u *Entry) restoreParent(ctx context.Context, scopeID int64, key string, parent *SnapshotParent) error {
+	if parent == nil {
+		return nil
+	}
+	if _, err := u.entryService.GetEntryByKey(ctx, scopeID, key); errors.Is(err, services.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	_, err := u.relationService.Parent(ctx, scopeID, key)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, services.ErrNotFound) {
+		return err
+	}
+	position := parent.Position
+	_, err = u.relationService.SetParent(ctx, scopeID, key, parent.Key, &position)
+	return err
+}
+
+// snapshotScopeInfo describes sr for a snapshot, keeping only the pins of
+// keys filter selects. It returns nil when there is nothing to record.
+func (u *Entry) snapshotScopeInfo(ctx context.Context, sr database.ScopeRecord, filter *SnapshotFilter) (*SnapshotScopeInfo, error) {
+	info := &SnapshotScopeInfo{
+		Scope:       newSnapshotScope(sr.Scope),
+		Description: sr.Description,
+	}
+	if len(sr.Metadata) > 0 {
+		info.Metadata = maps.Clone(sr.Metadata)
+	}
+
+	records, err := u.snapshotService.List(ctx, sr.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		pinned, err := u.snapshotService.Versions(ctx, r.ID)
+		if err != nil {
+			return nil, err
+		}
+		set := SnapshotPinnedSet{Name: r.Name, CreatedAt: r.CreatedAt, Versions: make(map[string]int64, len(pinned))}
+		for _, p := range pinned {
+			if filter.matchKey(p.Key) {
+				set.Versions[p.Key] = p.Version
+			}
+		}
+		info.Snapshots = append(info.Snapshots, set)
+	}
+
+	if info.Description == "" && info.Metadata == nil && info.Snapshots == nil {
+		return nil, nil
+	}
+	return info, nil
+}
+
+// restoreScopeInfo adopts the description of info unless the scope has one,
+// and creates the scope snapshots it does not have yet. It returns how many
+// scope snapshots it created.
+func (u *Entry) restoreScopeInfo(ctx context.Context, info SnapshotScopeInfo) (int, error) {
+	scopeID, err := u.scopeService.GetOrCreate(ctx, info.Scope.scope())
+	if err != nil {
+		return 0, err
+	}
+
+	if info.Description != "" || len(info.Metadata) > 0 {
+		current, err := u.scopeService.GetByID(ctx, scopeID)
+		if err != nil {
+			return 0, err
+		}
+		if current.Description == "" && len(current.Metadata) == 0 {
+			if err := u.scopeService.Describe(ctx, scopeID, info.Description, info.Metadata); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	created := 0
+	for _, set := range info.Snapshots {
+		pins := make([]database.PinnedVersion, 0, len(set.Versions))
+		for key, version := range set.Versions {
+			pins = append(pins, database.PinnedVersion{Key: key, Version: version})
+		}
+		ok, err := u.snapshotService.Import(ctx, scopeID, set.Name, set.CreatedAt, pins)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+		}
+	}
+	return created, nil
+}
@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// StaleOptions selects the entries Stale reports.
+type StaleOptions struct {
+	// Before is the cutoff: entries last written and last read before it
+	// are stale.
+	Before time.Time
+	// AllScopes looks in every scope instead of sc.
+	AllScopes bool
+}
+
+// Stale returns the unarchived entries whose latest version was written
+// before opts.Before and that have not been read since, least recently
+// written first, so outdated context can be refreshed or archived before an
+// agent trusts it. Reads only count when read tracking is enabled.
+func (u *Entry) Stale(ctx context.Context, sc scope.Scope, opts StaleOptions) ([]ListEntry, error) {
+	result, err := u.List(ctx, sc, &ListOptions{
+		AllScopes: opts.AllScopes,
+		Until:     opts.Before,
+		SortBy:    services.SortByUpdated,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stale := result.Entries[:0]
+	for _, e := range result.Entries {
+		if e.Record.LastReadAt.Before(opts.Before) {
+			stale = append(stale, e)
+		}
+	}
+	return stale, nil
+}
+
+// Archive hides key from list and stale until it is written again or
+// restored. It returns false if the key does not exist or is already
+// archived.
+func (u *Entry) Archive(ctx context.Context, sc scope.Scope, key string) (bool, error) {
+	if err := scope.Validate(sc); err != nil {
+		return false, err
+	}
+	scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
+	if err != nil {
+		return false, err
+	}
+	if key, err = u.lookupKey(ctx, scopeID, key); err != nil {
+		return false, err
+	}
+	archived, err := u.entryService.Archive(ctx, scopeID, key)
+	if archived {
+		u.emit(ctx, newEvent(EventArchive, sc, key, 0))
+	}
+	return archived, err
+}
@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// mostReadLimit is how many keys ReadStats.MostRead lists.
+const mostReadLimit = 5
+
+// VaultStats summarises what the vault stores across all scopes.
+type VaultStats struct {
+	Entries   int64           `json:"entries"`
+	Versions  int64           `json:"versions"`
+	TotalSize int64           `json:"totalSize"`
+	Retention *RetentionStats `json:"retention,omitempty"`
+	Reads     *ReadStats      `json:"reads,omitempty"`
+}
+
+// RetentionStats reports how much history falls outside the configured
+// retention policy and could be pruned.
+type RetentionStats struct {
+	KeepVersions        int   `json:"keepVersions"`
+	ReclaimableVersions int64 `json:"reclaimableVersions"`
+	ReclaimableSize     int64 `json:"reclaimableSize"`
+}
+
+// ReadStats reports how much of the vault is read, as recorded by read
+// tracking.
+type ReadStats struct {
+	ReadEntries   int64       `json:"readEntries"`
+	UnreadEntries int64       `json:"unreadEntries"`
+	TotalReads    int64       `json:"totalReads"`
+	MostRead      []ReadCount `json:"mostRead"`
+}
+
+// ReadCount is how often one key has been read.
+type ReadCount struct {
+	Scope    string    `json:"scope"`
+	Key      string    `json:"key"`
+	Reads    int64     `json:"reads"`
+	LastRead time.Time `json:"lastRead"`
+}
+
+// CollectStats gathers vault-wide usage. keepVersions is the retention
+// policy's versions-per-key limit; Retention is left nil when it is 0.
+// Reads is left nil unless trackReads is set.
+func CollectStats(ctx context.Context, dbCtx *database.Context, keepVersions int, trackReads bool) (*VaultStats, error) {
+	integrity := services.NewIntegrityService(dbCtx)
+
+	usage, err := integrity.Usage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := &VaultStats{
+		Entries:   usage.EntryCount,
+		Versions:  usage.VersionCount,
+		TotalSize: usage.TotalSize,
+	}
+
+	if keepVersions > 0 {
+		reclaimable, err := integrity.Reclaimable(ctx, keepVersions)
+		if err != nil {
+			return nil, err
+		}
+		stats.Retention = &RetentionStats{
+			KeepVersions:        keepVersions,
+			ReclaimableVersions: reclaimable.VersionCount,
+			ReclaimableSize:     reclaimable.TotalSize,
+		}
+	}
+
+	if trackReads {
+		reads, err := collectReadStats(ctx, NewEntry(dbCtx))
+		if err != nil {
+			return nil, err
+		}
+		stats.Reads = reads
+	}
+
+	return stats, nil
+}
+
+func collectReadStats(ctx context.Context, uc *Entry) (*ReadStats, error) {
+	list, err := uc.List(ctx, scope.Scope{}, &ListOptions{AllScopes: true, IncludeArchived: true})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &ReadStats{MostRead: []ReadCount{}}
+	for _, e := range list.Entries {
+		if e.Record.ReadCount == 0 {
+			stats.UnreadEntries++
+			continue
+		}
+		stats.ReadEntries++
+		stats.TotalReads += e.Record.ReadCount
+		stats.MostRead = append(stats.MostRead, ReadCount{
+			Scope:    e.ScopeShort,
+			Key:      e.Record.Key,
+			Reads:    e.Record.ReadCount,
+			LastRead: e.Record.LastReadAt,
+		})
+	}
+
+	sort.SliceStable(stats.MostRead, func(i, j int) bool {
+		return stats.MostRead[i].Reads > stats.MostRead[j].Reads
+	})
+	if len(stats.MostRead) > mostReadLimit {
+		stats.MostRead = stats.MostRead[:mostReadLimit]
+	}
+	return stats, nil
+}
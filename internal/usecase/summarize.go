@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/summarizer"
+)
+
+// SummarizeResult describes the summary stored by Summarize.
+type SummarizeResult struct {
+	Key     string
+	Version int64
+	Summary string
+}
+
+// Summarize runs cmd over a version of key (the latest when opts has no
+// Version) and stores its output as that version's summary, replacing any
+// earlier one. Unlike summaries generated on Set, it ignores cmd.MinSize.
+func (u *Entry) Summarize(ctx context.Context, sc scope.Scope, key string, opts *GetOptions, cmd *summarizer.Command) (*SummarizeResult, error) {
+	entry, err := u.Get(ctx, sc, key, opts)
+	if err != nil {
+		return nil, err
+	}
+	content, err := filesystem.ReadFile(entry.Record.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := u.storeSummary(ctx, entry.Record.ScopeID, entry.Record.Key, entry.Record.Version, content, cmd)
+	if err != nil {
+		return nil, err
+	}
+	return &SummarizeResult{Key: entry.Record.Key, Version: entry.Record.Version, Summary: summary}, nil
+}
+
+// storeSummary generates the summary of content and stores it for version.
+func (u *Entry) storeSummary(ctx context.Context, scopeID int64, key string, version int64, content string, cmd *summarizer.Command) (string, error) {
+	summary, err := cmd.Summarize(ctx, key, content)
+	if err != nil {
+		return "", err
+	}
+	if err := u.entryService.SetSummary(ctx, scopeID, key, version, summary, cmd.Line); err != nil {
+		return "", err
+	}
+	return summary, nil
+}
@@ -0,0 +1,255 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+)
+
+// Operations recorded in the undo journal.
+const (
+	UndoSet     = "set"
+	UndoDelete  = "delete"
+	UndoArchive = "archive"
+)
+
+// undoJournalFile is the journal in the vault directory. It holds only the
+// last mutating CLI operation.
+const undoJournalFile = "undo.json"
+
+// ErrNothingToUndo is returned by Undo when the journal is empty.
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// UndoRecord describes the last mutating operation well enough to revert
+// it.
+type UndoRecord struct {
+	Operation string        `json:"operation"`
+	Scope     SnapshotScope `json:"scope"`
+	Time      time.Time     `json:"time"`
+	// Keys are the keys the operation changed.
+	Keys []string `json:"keys"`
+	// Version is the version a set wrote.
+	Version int64 `json:"version,omitempty"`
+	// Deleted holds the versions a delete removed, with their content, so
+	// they can be written back.
+	Deleted []UndoVersion `json:"deleted,omitempty"`
+}
+
+// UndoVersion is a deleted version kept in the journal.
+type UndoVersion struct {
+	Key string `json:"key"`
+	SnapshotVersion
+	Archived bool   `json:"archived,omitempty"`
+	Content  string `json:"content"`
+}
+
+// Describe summarises the operation for messages such as "undid ...".
+func (r *UndoRecord) Describe() string {
+	sc := scope.FormatScopeShort(r.Scope.scope())
+	switch r.Operation {
+	case UndoSet:
+		return fmt.Sprintf("set of %s version %d in %s", r.Keys[0], r.Version, sc)
+	case UndoDelete:
+		return fmt.Sprintf("delete of %d version(s) of %s in %s", len(r.Deleted), r.Keys[0], sc)
+	default:
+		return fmt.Sprintf("%s of %d key(s) in %s", r.Operation, len(r.Keys), sc)
+	}
+}
+
+// NewSetUndo records a set that wrote version of key.
+func NewSetUndo(sc scope.Scope, key string, version int64) *UndoRecord {
+	return &UndoRecord{Operation: UndoSet, Scope: newSnapshotScope(sc), Time: time.Now(), Keys: []string{key}, Version: version}
+}
+
+// NewArchiveUndo records that keys were archived.
+func NewArchiveUndo(sc scope.Scope, keys []string) *UndoRecord {
+	return &UndoRecord{Operation: UndoArchive, Scope: newSnapshotScope(sc), Time: time.Now(), Keys: keys}
+}
+
+// CaptureDelete reads the versions a delete of key is about to remove, all
+// of them when version is 0, so the delete can be undone. Call it before
+// deleting and record the result with RecordUndo once the delete succeeded.
+func (u *Entry) CaptureDelete(ctx context.Context, sc scope.Scope, key string, version int64) (*UndoRecord, error) {
+	scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	if key, err = u.lookupKey(ctx, scopeID, key); err != nil {
+		return nil, err
+	}
+	entries, err := u.entryService.List(ctx, scopeID, true, true)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &UndoRecord{Operation: UndoDelete, Scope: newSnapshotScope(sc), Time: time.Now(), Keys: []string{key}}
+	for _, e := range entries {
+		if e.Key != key || (version != 0 && e.Version != version) {
+			continue
+		}
+		content, err := filesystem.ReadFile(e.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		record.Deleted = append(record.Deleted, UndoVersion{
+			Key: key,
+			SnapshotVersion: SnapshotVersion{
+				Version:     e.Version,
+				Hash:        e.Hash,
+				Description: e.Description,
+				CreatedAt:   e.UpdatedAt,
+			},
+			Archived: e.IsArchived,
+			Content:  content,
+		})
+	}
+	slices.SortFunc(record.Deleted, func(a, b UndoVersion) int { return int(a.Version - b.Version) })
+	return record, nil
+}
+
+// RecordUndo replaces the journal with record.
+func RecordUndo(record *UndoRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	dir := config.GetVaultDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, undoJournalFile+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), undoJournalPath())
+}
+
+// LastUndo returns the journaled operation, or ErrNothingToUndo.
+func LastUndo() (*UndoRecord, error) {
+	data, err := os.ReadFile(undoJournalPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNothingToUndo
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record UndoRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("read undo journal: %w", err)
+	}
+	return &record, nil
+}
+
+func undoJournalPath() string {
+	return filepath.Join(config.GetVaultDir(), undoJournalFile)
+}
+
+// Undo reverts the journaled operation and clears the journal: a set's
+// version is deleted, deleted versions are written back under their
+// numbers, and archived keys are restored. A set is not undone once the key
+// has a newer version, nor a delete of a version that is no longer the
+// latest; the journal is then kept.
+func (u *Entry) Undo(ctx context.Context) (*UndoRecord, error) {
+	record, err := LastUndo()
+	if err != nil {
+		return nil, err
+	}
+	sc := record.Scope.scope()
+	if err := scope.Validate(sc); err != nil {
+		return nil, fmt.Errorf("invalid scope in undo journal: %w", err)
+	}
+
+	err = u.WithTransaction(ctx, func(tx *Entry) error {
+		switch record.Operation {
+		case UndoSet:
+			return tx.undoSet(ctx, sc, record.Keys[0], record.Version)
+		case UndoDelete:
+			return tx.undoDelete(ctx, sc, record.Deleted)
+		case UndoArchive:
+			for _, key := range record.Keys {
+				if _, err := tx.Restore(ctx, sc, key); err != nil {
+					return fmt.Errorf("restore %s: %w", key, err)
+				}
+			}
+			return nil
+		default:
+			return fmt.Errorf("unknown operation in undo journal: %s", record.Operation)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Remove(undoJournalPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (u *Entry) undoSet(ctx context.Context, sc scope.Scope, key string, version int64) error {
+	scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
+	if err != nil {
+		return err
+	}
+	latest, err := u.entryService.GetLatest(ctx, scopeID, key)
+	if errors.Is(err, services.ErrNotFound) {
+		return fmt.Errorf("%s no longer exists", key)
+	}
+	if err != nil {
+		return err
+	}
+	if latest.Version != version {
+		return fmt.Errorf("%s has changed since version %d (now version %d); not undoing", key, version, latest.Version)
+	}
+
+	if version == 1 {
+		_, err = u.DeleteKey(ctx, sc, key)
+		return err
+	}
+	_, err = u.DeleteVersion(ctx, sc, key, int(version))
+	return err
+}
+
+func (u *Entry) undoDelete(ctx context.Context, sc scope.Scope, versions []UndoVersion) error {
+	scopeID, err := u.scopeService.GetOrCreate(ctx, sc)
+	if err != nil {
+		return err
+	}
+	for _, v := range versions {
+		if _, err := u.entryService.GetByVersion(ctx, scopeID, v.Key, v.Version); err == nil {
+			return fmt.Errorf("%s version %d exists again; not undoing", v.Key, v.Version)
+		} else if !errors.Is(err, services.ErrNotFound) {
+			return err
+		}
+		err := u.importVersion(ctx, sc, scopeID, v.Key, v.Archived, v.SnapshotVersion, v.Content)
+		if errors.Is(err, services.ErrVersionConflict) {
+			// Versions can only be appended, so a version deleted from the
+			// middle of the history cannot take its number back.
+			return fmt.Errorf("cannot restore %s version %d: a later version exists", v.Key, v.Version)
+		}
+		if err != nil {
+			return fmt.Errorf("restore %s version %d: %w", v.Key, v.Version, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/entrykey"
+	"github.com/choplin/vault.md/internal/services"
+	"github.com/choplin/vault.md/internal/validator"
+)
+
+// ErrInvalidContent is returned by Set when content fails a validator from
+// the config file that rejects writes.
+var ErrInvalidContent = errors.New("content failed validation")
+
+// validateContent runs the configured validators for key. It fails with
+// ErrInvalidContent when a rejecting validator fails, and otherwise returns
+// the problems of the warning ones.
+func validateContent(key, content string) ([]string, error) {
+	settings, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	validators, err := validator.FromSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var rejected, warnings []string
+	for _, problem := range validator.Check(validators, key, content) {
+		if problem.Reject {
+			rejected = append(rejected, problem.Err.Error())
+		} else {
+			warnings = append(warnings, problem.Err.Error())
+		}
+	}
+	if len(rejected) > 0 {
+		return nil, fmt.Errorf("%w: %s: %s", ErrInvalidContent, key, strings.Join(rejected, "; "))
+	}
+	return warnings, nil
+}
+
+// storedKey returns the key a write of key is stored under: key run through
+// entrykey.Clean, or key itself when an entry already has it, so keys
+// written before they were validated stay writable. It fails with
+// entrykey.ErrInvalid for a new key the vault does not accept. Keys under
+// entrykey.ReservedPrefix are refused even when an entry has them, unless
+// internal is set.
+func (u *Entry) storedKey(ctx context.Context, scopeID int64, key string, internal bool) (string, error) {
+	if normalized := entrykey.Normalize(key); !internal && entrykey.IsReserved(normalized) {
+		return "", entrykey.Validate(normalized)
+	}
+	clean := entrykey.Clean
+	if internal {
+		clean = entrykey.CleanInternal
+	}
+	cleaned, err := clean(key)
+	if err == nil && cleaned == key {
+		return key, nil
+	}
+	if _, getErr := u.entryService.GetEntryByKey(ctx, scopeID, key); getErr == nil {
+		return key, nil
+	} else if !errors.Is(getErr, services.ErrNotFound) {
+		return "", getErr
+	}
+	return cleaned, err
+}
+
+// lookupKey returns the key an entry given as key is stored under, for
+// reads, deletes, and other lookups: key itself when an entry has it, and
+// otherwise its entrykey.Normalize form, which is what writes store. A
+// key typed with surrounding spaces or in decomposed Unicode thus finds the
+// entry that writing it created.
+func (u *Entry) lookupKey(ctx context.Context, scopeID int64, key string) (string, error) {
+	normalized := entrykey.Normalize(key)
+	if normalized == key {
+		return key, nil
+	}
+	if _, err := u.entryService.GetEntryByKey(ctx, scopeID, key); err == nil {
+		return key, nil
+	} else if !errors.Is(err, services.ErrNotFound) {
+		return "", err
+	}
+	return normalized, nil
+}
@@ -0,0 +1,127 @@
+// Package validator checks entry content against the validators section of
+// the config file, so that entries consumed by scripts and agents keep the
+// structure they expect.
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+
+	"github.com/choplin/vault.md/internal/config"
+)
+
+// Validator is a compiled config.ValidatorSettings.
+type Validator struct {
+	settings config.ValidatorSettings
+	pattern  *regexp.Regexp
+	schema   *jsonschema.Resolved
+}
+
+// New compiles settings, reading the schema file if it names one.
+func New(settings config.ValidatorSettings) (*Validator, error) {
+	v := &Validator{settings: settings}
+	if settings.Pattern != nil {
+		pattern, err := regexp.Compile(*settings.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("validator for %q: %w", settings.Prefix, err)
+		}
+		v.pattern = pattern
+		return v, nil
+	}
+
+	data := []byte(settings.Schema)
+	if settings.SchemaFile != nil {
+		var err error
+		data, err = os.ReadFile(*settings.SchemaFile) //nolint:gosec // G304: schema path is chosen by the user
+		if err != nil {
+			return nil, fmt.Errorf("validator for %q: failed to read schema: %w", settings.Prefix, err)
+		}
+	}
+	var schema jsonschema.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("validator for %q: invalid JSON Schema: %w", settings.Prefix, err)
+	}
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("validator for %q: invalid JSON Schema: %w", settings.Prefix, err)
+	}
+	v.schema = resolved
+	return v, nil
+}
+
+// FromSettings compiles the validators in settings.
+func FromSettings(settings *config.Settings) ([]*Validator, error) {
+	if settings == nil {
+		return nil, nil
+	}
+	validators := make([]*Validator, 0, len(settings.Validators))
+	for _, s := range settings.Validators {
+		v, err := New(s)
+		if err != nil {
+			return nil, err
+		}
+		validators = append(validators, v)
+	}
+	return validators, nil
+}
+
+// Applies reports whether the validator checks key.
+func (v *Validator) Applies(key string) bool {
+	return strings.HasPrefix(key, v.settings.Prefix)
+}
+
+// Check returns why content fails the validator, or nil.
+func (v *Validator) Check(content string) error {
+	err := v.check(content)
+	if err == nil || v.settings.Message == nil {
+		return err
+	}
+	return fmt.Errorf("%s (%w)", *v.settings.Message, err)
+}
+
+func (v *Validator) check(content string) error {
+	if v.pattern != nil {
+		if !v.pattern.MatchString(content) {
+			return fmt.Errorf("content does not match %s", v.pattern)
+		}
+		return nil
+	}
+	var instance any
+	if err := json.Unmarshal([]byte(content), &instance); err != nil {
+		return fmt.Errorf("content is not JSON: %w", err)
+	}
+	if err := v.schema.Validate(instance); err != nil {
+		return fmt.Errorf("content does not match the schema: %w", err)
+	}
+	return nil
+}
+
+// Problem is a validator that content failed.
+type Problem struct {
+	// Prefix is the key prefix of the validator.
+	Prefix string
+	// Reject is set when the validator rejects the write rather than
+	// warning about it.
+	Reject bool
+	Err    error
+}
+
+// Check runs the validators that apply to key against content and returns
+// the ones it fails, in configuration order.
+func Check(validators []*Validator, key, content string) []Problem {
+	var problems []Problem
+	for _, v := range validators {
+		if !v.Applies(key) {
+			continue
+		}
+		if err := v.Check(content); err != nil {
+			problems = append(problems, Problem{Prefix: v.settings.Prefix, Reject: v.settings.Rejects(), Err: err})
+		}
+	}
+	return problems
+}
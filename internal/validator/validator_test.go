@@ -0,0 +1,81 @@
+package validator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/choplin/vault.md/internal/config"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestCheckPattern(t *testing.T) {
+	validators, err := FromSettings(&config.Settings{Validators: []config.ValidatorSettings{{
+		Prefix:  "adr/",
+		Pattern: ptr(`(?m)^## Decision$`),
+		Message: ptr("ADRs need a Decision heading"),
+	}}})
+	if err != nil {
+		t.Fatalf("FromSettings error: %v", err)
+	}
+
+	if problems := Check(validators, "adr/0001", "# Use SQLite\n\n## Decision\n\nYes.\n"); len(problems) != 0 {
+		t.Fatalf("expected valid ADR to pass, got %v", problems)
+	}
+	if problems := Check(validators, "notes", "anything"); len(problems) != 0 {
+		t.Fatalf("expected keys outside the prefix to be skipped, got %v", problems)
+	}
+
+	problems := Check(validators, "adr/0002", "# Use SQLite\n\nWe decided.\n")
+	if len(problems) != 1 || !problems[0].Reject {
+		t.Fatalf("expected one rejecting problem, got %v", problems)
+	}
+	if msg := problems[0].Err.Error(); !strings.HasPrefix(msg, "ADRs need a Decision heading (") {
+		t.Fatalf("expected the configured message first, got %q", msg)
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	schema := `{"type": "object", "required": ["status"], "properties": {"status": {"enum": ["open", "done"]}}}`
+	schemaFile := filepath.Join(t.TempDir(), "task.json")
+	if err := os.WriteFile(schemaFile, []byte(schema), 0o600); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	validators, err := FromSettings(&config.Settings{Validators: []config.ValidatorSettings{
+		{Prefix: "tasks/", Schema: json.RawMessage(schema)},
+		{Prefix: "tasks/", SchemaFile: &schemaFile, OnFail: ptr(config.ValidateWarn)},
+	}})
+	if err != nil {
+		t.Fatalf("FromSettings error: %v", err)
+	}
+
+	if problems := Check(validators, "tasks/1", `{"status": "open"}`); len(problems) != 0 {
+		t.Fatalf("expected valid task to pass, got %v", problems)
+	}
+
+	for _, content := range []string{`{"status": "later"}`, `{}`, `not json`} {
+		problems := Check(validators, "tasks/1", content)
+		if len(problems) != 2 {
+			t.Fatalf("expected both validators to fail %q, got %v", content, problems)
+		}
+		if !problems[0].Reject || problems[1].Reject {
+			t.Fatalf("expected reject then warn, got %v", problems)
+		}
+	}
+}
+
+func TestNewRejectsInvalidSchema(t *testing.T) {
+	if _, err := New(config.ValidatorSettings{Prefix: "x", Schema: json.RawMessage(`{"type": 5}`)}); err == nil {
+		t.Fatal("expected an invalid schema to fail")
+	}
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	if _, err := New(config.ValidatorSettings{Prefix: "x", SchemaFile: &missing}); err == nil {
+		t.Fatal("expected a missing schema file to fail")
+	}
+}
@@ -0,0 +1,279 @@
+// Package vaulttest runs integration tests against an isolated vault. New
+// creates an empty vault for one test, Seed and Set fill it, the Assert
+// methods check its state, and AssertGolden compares a rendering of it with
+// a golden file.
+//
+// Scopes are named as vault scope list prints them: "global", a repository
+// path, "path:branch", or "path@worktree-id". New points the process at the
+// vault through environment variables, so tests using it cannot run in
+// parallel.
+package vaulttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/choplin/vault.md/internal/config"
+	"github.com/choplin/vault.md/internal/database"
+	"github.com/choplin/vault.md/internal/filesystem"
+	"github.com/choplin/vault.md/internal/scope"
+	"github.com/choplin/vault.md/internal/services"
+	"github.com/choplin/vault.md/internal/usecase"
+)
+
+// UpdateEnv is the environment variable that, when set, makes AssertGolden
+// write the golden files instead of comparing with them.
+const UpdateEnv = "VAULTTEST_UPDATE"
+
+// Vault is an isolated vault in a temporary directory, removed when the test
+// ends.
+type Vault struct {
+	tb    testing.TB
+	dir   string
+	dbCtx *database.Context
+	entry *usecase.Entry
+}
+
+// New creates an empty vault for the test and points VAULT_DIR and
+// VAULT_CONFIG at it until the test ends, so code under test and vault
+// subprocesses started with the inherited environment use it too. The
+// config file starts out absent; see WriteConfig.
+func New(tb testing.TB) *Vault {
+	tb.Helper()
+
+	dir := tb.TempDir()
+	tb.Setenv("VAULT_DIR", dir)
+	tb.Setenv("VAULT_CONFIG", filepath.Join(dir, "config.json"))
+	for _, env := range []string{config.EphemeralEnv, "VAULT_SCOPE", "VAULT_REPO", "VAULT_BRANCH"} {
+		tb.Setenv(env, "")
+	}
+
+	dbCtx, err := database.CreateDatabase("")
+	if err != nil {
+		tb.Fatalf("vaulttest: create database: %v", err)
+	}
+	tb.Cleanup(func() {
+		if err := database.CloseDatabase(dbCtx); err != nil {
+			tb.Errorf("vaulttest: close database: %v", err)
+		}
+	})
+
+	return &Vault{tb: tb, dir: dir, dbCtx: dbCtx, entry: usecase.NewEntry(dbCtx)}
+}
+
+// Dir returns the vault directory.
+func (v *Vault) Dir() string {
+	return v.dir
+}
+
+// Env returns the environment variables that point a vault subprocess at v,
+// for commands run with an explicit environment.
+func (v *Vault) Env() []string {
+	return []string{"VAULT_DIR=" + v.dir, "VAULT_CONFIG=" + filepath.Join(v.dir, "config.json")}
+}
+
+// WriteConfig replaces the config file of the vault with settings, given
+// as the JSON of the config file.
+func (v *Vault) WriteConfig(settings string) {
+	v.tb.Helper()
+	if err := os.WriteFile(filepath.Join(v.dir, "config.json"), []byte(settings), 0o600); err != nil {
+		v.tb.Fatalf("vaulttest: write config: %v", err)
+	}
+}
+
+func (v *Vault) scope(name string) scope.Scope {
+	v.tb.Helper()
+	sc, err := scope.ParseScope(name)
+	if err != nil {
+		v.tb.Fatalf("vaulttest: %v", err)
+	}
+	return sc
+}
+
+// Set stores content as a new version of key and returns the version.
+func (v *Vault) Set(scopeName, key, content string) int64 {
+	v.tb.Helper()
+	result, err := v.entry.Set(context.Background(), v.scope(scopeName), key, content, nil)
+	if err != nil {
+		v.tb.Fatalf("vaulttest: set %s in %s: %v", key, scopeName, err)
+	}
+	return result.Version
+}
+
+// Seed stores each of entries, keyed by key, in key order.
+func (v *Vault) Seed(scopeName string, entries map[string]string) {
+	v.tb.Helper()
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		v.Set(scopeName, key, entries[key])
+	}
+}
+
+// lookup returns the latest version of key and its content, or ok false if
+// key has none.
+func (v *Vault) lookup(scopeName, key string) (version int64, content string, ok bool) {
+	v.tb.Helper()
+	result, err := v.entry.Get(context.Background(), v.scope(scopeName), key, nil)
+	if errors.Is(err, services.ErrNotFound) {
+		return 0, "", false
+	}
+	if err != nil {
+		v.tb.Fatalf("vaulttest: get %s in %s: %v", key, scopeName, err)
+	}
+	content, err = filesystem.ReadFile(result.Record.FilePath)
+	if err != nil {
+		v.tb.Fatalf("vaulttest: read %s in %s: %v", key, scopeName, err)
+	}
+	return result.Record.Version, content, true
+}
+
+// Get returns the content of the latest version of key, failing the test if
+// there is none.
+func (v *Vault) Get(scopeName, key string) string {
+	v.tb.Helper()
+	_, content, ok := v.lookup(scopeName, key)
+	if !ok {
+		v.tb.Fatalf("vaulttest: %s not found in %s", key, scopeName)
+	}
+	return content
+}
+
+// Keys returns the keys of the scope, archived ones excluded, sorted.
+func (v *Vault) Keys(scopeName string) []string {
+	v.tb.Helper()
+	list, err := v.entry.List(context.Background(), v.scope(scopeName), nil)
+	if err != nil {
+		v.tb.Fatalf("vaulttest: list %s: %v", scopeName, err)
+	}
+	keys := make([]string, 0, len(list.Entries))
+	for _, e := range list.Entries {
+		keys = append(keys, e.Record.Key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// AssertContent checks the content of the latest version of key.
+func (v *Vault) AssertContent(scopeName, key, want string) {
+	v.tb.Helper()
+	if got := v.Get(scopeName, key); got != want {
+		v.tb.Errorf("vaulttest: content of %s in %s = %q, want %q", key, scopeName, got, want)
+	}
+}
+
+// AssertVersion checks the number of the latest version of key.
+func (v *Vault) AssertVersion(scopeName, key string, want int64) {
+	v.tb.Helper()
+	got, _, ok := v.lookup(scopeName, key)
+	if !ok {
+		v.tb.Errorf("vaulttest: %s not found in %s, want version %d", key, scopeName, want)
+		return
+	}
+	if got != want {
+		v.tb.Errorf("vaulttest: version of %s in %s = %d, want %d", key, scopeName, got, want)
+	}
+}
+
+// AssertMissing checks that key has no version.
+func (v *Vault) AssertMissing(scopeName, key string) {
+	v.tb.Helper()
+	if version, _, ok := v.lookup(scopeName, key); ok {
+		v.tb.Errorf("vaulttest: %s in %s has version %d, want none", key, scopeName, version)
+	}
+}
+
+// AssertKeys checks that the scope holds exactly the keys want, in any
+// order.
+func (v *Vault) AssertKeys(scopeName string, want ...string) {
+	v.tb.Helper()
+	want = slices.Sorted(slices.Values(want))
+	if got := v.Keys(scopeName); !slices.Equal(got, want) {
+		v.tb.Errorf("vaulttest: keys of %s = %q, want %q", scopeName, got, want)
+	}
+}
+
+// Dump renders the latest version of every key in every scope, in scope and
+// key order, as text for golden files:
+//
+//	== global
+//	-- notes (v2)
+//	content of version 2
+func (v *Vault) Dump() string {
+	v.tb.Helper()
+	list, err := v.entry.List(context.Background(), scope.NewGlobal(), &usecase.ListOptions{AllScopes: true, IncludeArchived: true, IncludeInternal: true})
+	if err != nil {
+		v.tb.Fatalf("vaulttest: list: %v", err)
+	}
+	entries := list.Entries
+	slices.SortFunc(entries, func(a, b usecase.ListEntry) int {
+		if c := strings.Compare(scope.FormatScope(a.Scope), scope.FormatScope(b.Scope)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Record.Key, b.Record.Key)
+	})
+
+	var b strings.Builder
+	current := ""
+	for i, e := range entries {
+		name := scope.FormatScope(e.Scope)
+		if i == 0 || name != current {
+			current = name
+			fmt.Fprintf(&b, "== %s\n", name)
+		}
+		content, err := filesystem.ReadFile(e.Record.FilePath)
+		if err != nil {
+			v.tb.Fatalf("vaulttest: read %s in %s: %v", e.Record.Key, name, err)
+		}
+		archived := ""
+		if e.Record.IsArchived {
+			archived = ", archived"
+		}
+		fmt.Fprintf(&b, "-- %s (v%d%s)\n%s", e.Record.Key, e.Record.Version, archived, content)
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// AssertGolden compares Dump with the golden file testdata/<name>.golden.
+func (v *Vault) AssertGolden(name string) {
+	v.tb.Helper()
+	AssertGolden(v.tb, name, v.Dump())
+}
+
+// AssertGolden compares got with the golden file testdata/<name>.golden,
+// relative to the package under test. With UpdateEnv set, it writes got to
+// the file instead.
+func AssertGolden(tb testing.TB, name, got string) {
+	tb.Helper()
+	path := filepath.Join("testdata", name+".golden")
+
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			tb.Fatalf("vaulttest: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o600); err != nil {
+			tb.Fatalf("vaulttest: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path) //nolint:gosec // G304: golden file of the test
+	if err != nil {
+		tb.Fatalf("vaulttest: %v (run with %s=1 to create it)", err, UpdateEnv)
+	}
+	if got != string(want) {
+		tb.Errorf("vaulttest: output differs from %s (run with %s=1 to update it)\n--- got\n%s--- want\n%s", path, UpdateEnv, got, want)
+	}
+}
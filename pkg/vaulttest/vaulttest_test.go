@@ -0,0 +1,41 @@
+package vaulttest
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestVault(t *testing.T) {
+	v := New(t)
+	if os.Getenv("VAULT_DIR") != v.Dir() {
+		t.Fatalf("expected VAULT_DIR to point at the test vault")
+	}
+
+	v.Seed("global", map[string]string{"plan": "draft", "notes": "first"})
+	if version := v.Set("global", "plan", "final"); version != 2 {
+		t.Fatalf("expected version 2, got %d", version)
+	}
+	v.Set("/src/app:main", "progress", "half done\n")
+
+	v.AssertContent("global", "plan", "final")
+	v.AssertVersion("global", "plan", 2)
+	v.AssertMissing("global", "missing")
+	v.AssertKeys("global", "plan", "notes")
+	v.AssertKeys("/src/app:main", "progress")
+	v.AssertGolden("seeded")
+
+	for _, env := range v.Env() {
+		if !strings.HasPrefix(env, "VAULT_") {
+			t.Fatalf("unexpected environment variable %q", env)
+		}
+	}
+}
+
+func TestVaultsAreIsolated(t *testing.T) {
+	for range 2 {
+		v := New(t)
+		v.AssertKeys("global")
+		v.Set("global", "plan", "content")
+	}
+}